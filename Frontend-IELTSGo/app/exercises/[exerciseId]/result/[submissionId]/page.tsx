@@ -13,7 +13,7 @@ import { CheckCircle2, XCircle, Clock, Target, TrendingUp, Home, RotateCcw, Aler
 import { PageLoading } from "@/components/ui/page-loading"
 import { EmptyState } from "@/components/ui/empty-state"
 import { exercisesApi } from "@/lib/api/exercises"
-import type { SubmissionResult } from "@/types"
+import type { ScoreConfidence, SubmissionResult, TextAnalysis } from "@/types"
 import { usePreferences } from "@/lib/contexts/preferences-context"
 import { useTranslations } from '@/lib/i18n'
 import { useI18nStore } from '@/lib/i18n/client'
@@ -113,6 +113,10 @@ export default function ExerciseResultPage() {
     }
   }
   // Note: detailed_scores is only available for AI exercises (Writing/Speaking)
+  const scoreConfidence: ScoreConfidence | undefined = detailedScores.confidence
+  const textAnalysis: TextAnalysis | undefined = detailedScores.text_analysis
+  // Scored by the offline heuristic evaluator while no AI model was reachable
+  const isEstimatedScore = detailedScores.provider === "heuristic"
 
   // Helper function to get step info based on evaluation status
   const getEvaluationStepInfo = (status: string, type: "writing" | "speaking") => {
@@ -360,6 +364,28 @@ export default function ExerciseResultPage() {
                     <div className="text-6xl font-bold text-primary mb-4">
                       {submission.band_score?.toFixed(1) || "N/A"}
                     </div>
+                    {isEstimatedScore && (
+                      <Badge variant="outline" className="border-amber-500 text-amber-600 mb-2">
+                        <AlertCircle className="w-3 h-3 mr-1" />
+                        {tAI("score_estimated")}
+                      </Badge>
+                    )}
+                    {scoreConfidence && (
+                      <div className="space-y-2">
+                        <p className="text-sm text-muted-foreground">
+                          {tAI("score_confidence", {
+                            samples: scoreConfidence.samples,
+                            agreement: Math.round(scoreConfidence.agreement * 100),
+                          })}
+                        </p>
+                        {scoreConfidence.needs_review && (
+                          <Badge variant="outline" className="border-amber-500 text-amber-600">
+                            <AlertCircle className="w-3 h-3 mr-1" />
+                            {tAI("score_needs_review", { spread: scoreConfidence.overall_spread.toFixed(1) })}
+                          </Badge>
+                        )}
+                      </div>
+                    )}
                   </div>
                 )}
               </CardContent>
@@ -474,6 +500,32 @@ export default function ExerciseResultPage() {
                     {t('word_count')?.replace('{count}', submission.word_count.toString()) || `Word count: ${submission.word_count}`}
                   </p>
                 )}
+                {textAnalysis && (
+                  <div className="mt-4 space-y-1 text-sm text-muted-foreground">
+                    <h4 className="font-semibold text-foreground">{tAI("text_analysis")}</h4>
+                    <p>
+                      {tAI("text_analysis_structure", {
+                        sentences: textAnalysis.sentence_count,
+                        paragraphs: textAnalysis.paragraph_count,
+                        mean: textAnalysis.sentence_length.mean,
+                      })}
+                    </p>
+                    <p>
+                      {tAI("text_analysis_vocabulary", {
+                        ttr: textAnalysis.type_token_ratio.toFixed(2),
+                        awl: Math.round(textAnalysis.academic_word_coverage * 1000) / 10,
+                      })}
+                    </p>
+                    <p>{tAI("text_analysis_linking", { count: textAnalysis.linking_device_count })}</p>
+                    {textAnalysis.repeated_phrases.length > 0 && (
+                      <p>
+                        {tAI("text_analysis_repeated", {
+                          phrases: textAnalysis.repeated_phrases.map((p) => `"${p.phrase}" ×${p.count}`).join(", "),
+                        })}
+                      </p>
+                    )}
+                  </div>
+                )}
               </CardContent>
             </Card>
           )}
@@ -1,6 +1,6 @@
 import { apiClient } from "./apiClient"
 import { apiCache } from "@/lib/utils/api-cache"
-import type { Exercise, ExerciseSubmission, ExerciseResult } from "@/types"
+import type { Exercise, ExerciseSubmission, ExerciseResult, AutosaveResult, Submission, TestSession, TestSessionReport, ReviewItem, ReviewSession, ReviewAnswerResult, ReviewSummary } from "@/types"
 
 export interface SubmissionFilters {
   skill?: string[]
@@ -109,7 +109,9 @@ export const exercisesApi = {
   submitAnswers: async (submissionId: string, answers: Array<{
     question_id: string
     selected_option_id?: string
+    selected_option_ids?: string[]
     text_answer?: string
+    matches?: Record<string, string>
     time_spent_seconds?: number
   }>, totalTimeSpentSeconds?: number): Promise<void> => {
     await apiClient.put(`/submissions/${submissionId}/answers`, { 
@@ -118,6 +120,31 @@ export const exercisesApi = {
     })
   },
 
+  // Autosave partial answers without submitting; returns the server-side time left.
+  // Fails with ATTEMPT_EXPIRED (409) once time is up and saved answers have been submitted.
+  autosave: async (submissionId: string, data: {
+    answers?: Array<{
+      question_id: string
+      selected_option_id?: string
+      selected_option_ids?: string[]
+      text_answer?: string
+      matches?: Record<string, string>
+      time_spent_seconds?: number
+    }>
+    writing_data?: {
+      essay_text: string
+      word_count: number
+      task_type: string
+      prompt_text: string
+    }
+  }): Promise<AutosaveResult> => {
+    const response = await apiClient.put<{ success: boolean; data: AutosaveResult }>(
+      `/submissions/${submissionId}/autosave`,
+      data
+    )
+    return response.data.data
+  },
+
   // Submit exercise (unified for all skills - Writing/Speaking use this)
   submitExercise: async (submissionId: string, data: {
     // For Writing
@@ -187,4 +214,86 @@ export const exercisesApi = {
     }>(`/submissions/my?${params.toString()}`)
     return response.data.data
   },
+
+  // Full mock tests: one exercise per part, taken in order L -> R -> W -> S
+  createTestSession: async (exerciseIds: string[], testType: "mock_test" | "full_test" = "mock_test"): Promise<TestSession> => {
+    const response = await apiClient.post<{ success: boolean; data: TestSession }>("/test-sessions", {
+      exercise_ids: exerciseIds,
+      test_type: testType,
+    })
+    return response.data.data
+  },
+
+  getMyTestSessions: async (page = 1, limit = 20): Promise<{ test_sessions: TestSession[]; pagination: { page: number; limit: number; total: number; total_pages: number } }> => {
+    const response = await apiClient.get(`/test-sessions?page=${page}&limit=${limit}`)
+    return response.data.data
+  },
+
+  getTestSession: async (sessionId: string): Promise<TestSession> => {
+    const response = await apiClient.get<{ success: boolean; data: TestSession }>(`/test-sessions/${sessionId}`)
+    return response.data.data
+  },
+
+  // Starts the attempt of a part; answer and submit it with the regular submission endpoints
+  startTestSessionPart: async (sessionId: string, partNumber: number): Promise<Submission> => {
+    const response = await apiClient.post<{ success: boolean; data: Submission }>(
+      `/test-sessions/${sessionId}/parts/${partNumber}/start`
+    )
+    return response.data.data
+  },
+
+  getTestSessionReport: async (sessionId: string): Promise<TestSessionReport> => {
+    const response = await apiClient.get<{ success: boolean; data: TestSessionReport }>(`/test-sessions/${sessionId}/report`)
+    return response.data.data
+  },
+
+  // Mistake notebook: wrong answers come back for review on a spaced-repetition schedule
+  getReviewSummary: async (): Promise<ReviewSummary> => {
+    const response = await apiClient.get<{ success: boolean; data: ReviewSummary }>("/reviews/summary")
+    return response.data.data
+  },
+
+  getReviewItems: async (status: ReviewItem["status"] = "active", page = 1, limit = 20): Promise<{ items: ReviewItem[]; pagination: { page: number; limit: number; total: number; total_pages: number } }> => {
+    const response = await apiClient.get(`/reviews/items?status=${status}&page=${page}&limit=${limit}`)
+    return response.data.data
+  },
+
+  archiveReviewItem: async (itemId: string): Promise<void> => {
+    await apiClient.delete(`/reviews/items/${itemId}`)
+  },
+
+  // Returns the open session if there is one
+  startReviewSession: async (options: { limit?: number; skill_type?: "listening" | "reading" } = {}): Promise<ReviewSession> => {
+    const response = await apiClient.post<{ success: boolean; data: ReviewSession }>("/reviews/sessions", options)
+    return response.data.data
+  },
+
+  getReviewSession: async (sessionId: string): Promise<ReviewSession> => {
+    const response = await apiClient.get<{ success: boolean; data: ReviewSession }>(`/reviews/sessions/${sessionId}`)
+    return response.data.data
+  },
+
+  answerReviewItem: async (
+    sessionId: string,
+    itemId: string,
+    answer: {
+      selected_option_id?: string
+      selected_option_ids?: string[]
+      text_answer?: string
+      matches?: Record<string, string>
+      time_spent_seconds?: number
+      confidence?: "easy" | "good" | "hard"
+    }
+  ): Promise<ReviewAnswerResult> => {
+    const response = await apiClient.post<{ success: boolean; data: ReviewAnswerResult }>(
+      `/reviews/sessions/${sessionId}/items/${itemId}/answer`,
+      answer
+    )
+    return response.data.data
+  },
+
+  completeReviewSession: async (sessionId: string): Promise<ReviewSession> => {
+    const response = await apiClient.post<{ success: boolean; data: ReviewSession }>(`/reviews/sessions/${sessionId}/complete`)
+    return response.data.data
+  },
 }
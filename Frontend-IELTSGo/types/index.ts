@@ -353,7 +353,7 @@ export interface QuestionOption {
   option_label: string  // A, B, C, D
   option_text: string
   option_image_url?: string
-  is_correct: boolean
+  is_correct?: boolean  // Author view only (hidden from students)
   display_order: number
   created_at: string
 }
@@ -407,7 +407,7 @@ export interface Submission {
   user_id: string
   exercise_id: string
   attempt_number: number
-  status: 'in_progress' | 'completed' | 'abandoned'
+  status: 'in_progress' | 'submitted' | 'completed' | 'abandoned'
   total_questions: number
   questions_answered: number
   correct_answers: number
@@ -417,6 +417,10 @@ export interface Submission {
   time_spent_seconds: number
   started_at: string
   completed_at?: string
+  last_activity_at?: string
+  auto_submitted?: boolean // Submitted by the server when time ran out
+  deadline_at?: string // Server-side deadline of in-progress timed attempts
+  remaining_seconds?: number
   device_type?: 'web' | 'android' | 'ios'
   created_at: string
   updated_at: string
@@ -435,6 +439,176 @@ export interface Submission {
   detailed_scores?: string | Record<string, any> // JSONB field - can be string or parsed object
 }
 
+// Agreement between independent AI evaluations, stored as detailed_scores.confidence
+// when the AI service scores with more than one sample
+export interface ScoreConfidence {
+  samples: number
+  agreement: number // Share of samples within half a band of the final band (0-1)
+  overall_spread: number // Highest minus lowest overall band among samples
+  criteria_spread: Record<string, number>
+  needs_review: boolean
+}
+
+// Essay measures computed by the AI service, stored as detailed_scores.text_analysis
+export interface TextAnalysis {
+  word_count: number
+  sentence_count: number
+  paragraph_count: number
+  type_token_ratio: number // Distinct words / words (0-1)
+  lexical_density: number // Content words / words (0-1)
+  academic_word_coverage: number // Share of Academic Word List words (0-1)
+  academic_words: string[]
+  repeated_phrases: { phrase: string; count: number }[]
+  linking_devices: Record<string, number>
+  linking_device_count: number
+  sentence_length: { mean: number; std_dev: number; variation: number; shortest: number; longest: number }
+}
+
+export interface AutosaveResult {
+  submission_id: string
+  status: Submission['status']
+  saved_answers: number
+  server_time: string
+  deadline_at?: string
+  remaining_seconds?: number // Undefined for untimed attempts
+  grace_seconds: number
+}
+
+// Full mock test: Listening, Reading, Writing and Speaking taken in order
+export interface TestSessionPart {
+  part_number: number
+  skill_type: "listening" | "reading" | "writing" | "speaking"
+  exercise_id: string
+  exercise_title: string
+  time_limit_minutes?: number
+  writing_task_type?: "task1" | "task2"
+  attempt_id?: string
+  status: "pending" | "in_progress" | "submitted" | "graded" | "evaluation_failed" | "skipped"
+  evaluation_status?: string
+  band_score?: number
+  correct_answers?: number
+  total_questions?: number
+  time_spent_seconds?: number
+  started_at?: string
+  completed_at?: string
+  deadline_at?: string
+  remaining_seconds?: number
+  detailed_scores?: string
+  ai_feedback?: string
+}
+
+export interface TestSession {
+  id: string
+  user_id: string
+  test_type: "mock_test" | "full_test"
+  ielts_variant: "academic" | "general_training"
+  status: "in_progress" | "awaiting_evaluation" | "completed" | "incomplete"
+  listening_band?: number
+  reading_band?: number
+  writing_band?: number
+  speaking_band?: number
+  overall_band?: number
+  started_at: string
+  last_activity_at: string
+  completed_at?: string
+  user_service_sync_status: string
+  created_at: string
+  updated_at: string
+  parts?: TestSessionPart[]
+}
+
+export interface SkillScoreReport {
+  skill_type: TestSessionPart["skill_type"]
+  band_score?: number
+  parts: number
+  parts_graded: number
+  parts_skipped: number
+  correct_answers?: number
+  total_questions?: number
+  time_spent_seconds: number
+}
+
+export interface TestSessionReport {
+  session: TestSession
+  skills: SkillScoreReport[]
+  overall_band?: number
+  completion_status: "completed" | "incomplete" | "pending"
+  time_spent_seconds: number
+}
+
+// Mistake notebook: missed objective questions scheduled with SM-2 spaced repetition
+export interface ReviewItem {
+  id: string
+  user_id: string
+  question_id: string
+  exercise_id: string
+  source_attempt_id?: string
+  status: "active" | "mastered" | "archived"
+  ease_factor: number
+  interval_days: number
+  repetitions: number
+  lapses: number
+  due_at: string
+  review_count: number
+  last_reviewed_at?: string
+  last_result?: boolean
+  created_at: string
+  exercise_title: string
+  skill_type: "listening" | "reading"
+  question_number: number
+  question_type: string
+  question_text: string
+}
+
+export interface ReviewSessionItem {
+  review_item_id: string
+  position: number
+  question_id: string
+  exercise_id: string
+  exercise_title: string
+  skill_type: "listening" | "reading"
+  is_correct?: boolean
+  score?: number
+  quality?: number
+  time_spent_seconds?: number
+  answered_at?: string
+  section?: ExerciseSection["section"]
+  question?: QuestionWithOptions
+}
+
+export interface ReviewSession {
+  id: string
+  user_id: string
+  status: "in_progress" | "completed"
+  started_at: string
+  completed_at?: string
+  user_service_sync_status: string
+  items: ReviewSessionItem[]
+}
+
+export interface ReviewAnswerResult {
+  review_item_id: string
+  result: {
+    correct: boolean
+    score: number
+    points_earned: number
+    items: GradingItemResult[]
+  }
+  quality: number
+  explanation?: string
+  status: "active" | "mastered"
+  interval_days: number
+  next_due_at: string
+  session_completed: boolean
+}
+
+export interface ReviewSummary {
+  due: number
+  active: number
+  mastered: number
+  next_due_at?: string
+}
+
 export interface SubmissionWithExercise {
   submission: Submission
   exercise: Exercise
@@ -447,12 +621,23 @@ export interface SubmissionAnswer {
   user_id: string
   answer_text?: string
   selected_option_id?: string
+  selected_option_ids?: string[]
+  matches?: Record<string, string>
   is_correct?: boolean
   points_earned?: number
+  grading_details?: GradingItemResult[]
   time_spent_seconds?: number
   answered_at: string
 }
 
+export interface GradingItemResult {
+  item?: string
+  given?: string
+  expected?: string
+  correct: boolean
+  reason?: "unanswered" | "word_limit_exceeded" | "too_many_options" | "no_answer_key"
+}
+
 export interface SubmissionResult {
   submission: Submission
   exercise: Exercise
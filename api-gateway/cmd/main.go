@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/bisosad1501/ielts-platform/api-gateway/internal/config"
 	"github.com/bisosad1501/ielts-platform/api-gateway/internal/middleware"
@@ -31,16 +32,40 @@ func main() {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 
+	// Only believe forwarding headers from configured proxies; by default gin trusts them
+	// from anyone, which would let clients pick their own IP for the per-IP rate limits
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("❌ Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// Global middleware
 	r.Use(gin.Recovery()) // Panic recovery
 	r.Use(middleware.CORS())
 	r.Use(middleware.RequestLogger())
 
+	// Revoked access tokens are loaded from the auth service, then synced in the background
+	revocations := middleware.NewRevocationCache(cfg.Services.AuthService, cfg.InternalAPIKey, time.Duration(cfg.RevocationSyncSeconds)*time.Second)
+	go revocations.Start()
+
 	// Initialize auth middleware
-	authMiddleware := middleware.NewAuthMiddleware(cfg.JWTSecret)
+	authMiddleware := middleware.NewAuthMiddleware(cfg.JWTSecret, revocations)
+
+	// Rate limit counters: Redis shares limits between gateway replicas, memory is per instance
+	var rateLimitStore middleware.RateLimitStore = middleware.NewMemoryRateLimitStore()
+	if cfg.RateLimit.Store == "redis" {
+		redisStore, err := middleware.NewRedisRateLimitStore(cfg.RateLimit.RedisURL)
+		if err != nil {
+			log.Printf("⚠️  Redis rate limit store unavailable, using in-memory store: %v", err)
+		} else {
+			defer redisStore.Close()
+			rateLimitStore = redisStore
+		}
+	}
+	rateLimiter := middleware.NewRateLimiter(rateLimitStore, cfg.RateLimit.Enabled)
+	log.Printf("📝 Rate limiting: enabled=%v store=%s (%d req/min per IP)", cfg.RateLimit.Enabled, cfg.RateLimit.Store, cfg.RateLimit.RequestsPerMinute)
 
 	// Setup all routes
-	routes.SetupRoutes(r, cfg, authMiddleware)
+	routes.SetupRoutes(r, cfg, authMiddleware, rateLimiter)
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -57,4 +82,5 @@ func main() {
 
 	<-quit
 	log.Println("🛑 Shutting down API Gateway...")
+	revocations.Stop()
 }
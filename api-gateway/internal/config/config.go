@@ -3,13 +3,22 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 )
 
 type Config struct {
-	ServerPort string
-	JWTSecret  string
-	Services   ServiceURLs
-	RateLimit  RateLimitConfig
+	ServerPort     string
+	JWTSecret      string
+	InternalAPIKey string
+	Services       ServiceURLs
+	RateLimit      RateLimitConfig
+
+	// How often revoked access tokens are synced from the auth service
+	RevocationSyncSeconds int
+
+	// Proxies (IPs or CIDRs) whose X-Forwarded-For / X-Real-IP headers are believed.
+	// Empty: the client IP is the connection's remote address, so it cannot be spoofed.
+	TrustedProxies []string
 }
 
 type ServiceURLs struct {
@@ -23,14 +32,22 @@ type ServiceURLs struct {
 }
 
 type RateLimitConfig struct {
-	RequestsPerMinute int
+	RequestsPerMinute int // Per client IP across all /api/v1 routes
 	Enabled           bool
+
+	Store    string // "memory" (per instance) or "redis" (shared between replicas)
+	RedisURL string
+
+	UserRequestsPerMinute   int // Per authenticated user
+	AuthRequestsPerMinute   int // Per client IP and route on login, registration, password reset and 2FA
+	SubmitRequestsPerMinute int // Per user on exercise submission and AI grading
 }
 
 func LoadConfig() (*Config, error) {
 	config := &Config{
-		ServerPort: getEnv("SERVER_PORT", "8080"),
-		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key"),
+		ServerPort:     getEnv("SERVER_PORT", "8080"),
+		JWTSecret:      getEnv("JWT_SECRET", "your-secret-key"),
+		InternalAPIKey: getEnv("INTERNAL_API_KEY", "internal_secret_key_ielts_2025_change_in_production"),
 		Services: ServiceURLs{
 			AuthService:         getEnv("AUTH_SERVICE_URL", "http://auth-service:8081"),
 			UserService:         getEnv("USER_SERVICE_URL", "http://user-service:8082"),
@@ -43,7 +60,16 @@ func LoadConfig() (*Config, error) {
 		RateLimit: RateLimitConfig{
 			RequestsPerMinute: getEnvAsInt("RATE_LIMIT_RPM", 100),
 			Enabled:           getEnvAsBool("RATE_LIMIT_ENABLED", true),
+
+			Store:    getEnv("RATE_LIMIT_STORE", "memory"),
+			RedisURL: getEnv("REDIS_URL", "redis://localhost:6379"),
+
+			UserRequestsPerMinute:   getEnvAsInt("RATE_LIMIT_USER_RPM", 300),
+			AuthRequestsPerMinute:   getEnvAsInt("RATE_LIMIT_AUTH_RPM", 10),
+			SubmitRequestsPerMinute: getEnvAsInt("RATE_LIMIT_SUBMIT_RPM", 10),
 		},
+		RevocationSyncSeconds: getEnvAsInt("TOKEN_REVOCATION_SYNC_SECONDS", 5),
+		TrustedProxies:        getEnvAsList("TRUSTED_PROXIES"),
 	}
 
 	if config.JWTSecret == "" {
@@ -70,6 +96,17 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+// getEnvAsList splits a comma-separated variable, returning nil when it is unset
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// RateLimitStore counts hits per key in fixed windows. Implementations must be safe for
+// concurrent use; a shared store (Redis) keeps limits consistent across gateway replicas.
+type RateLimitStore interface {
+	// Increment records one hit for key and returns the hit count in the current window
+	// and the time the window resets
+	Increment(ctx context.Context, key string, window time.Duration) (int64, time.Time, error)
+}
+
+// RateLimitKey selects what a rule counts hits against
+type RateLimitKey int
+
+const (
+	KeyByIP   RateLimitKey = iota // Client IP
+	KeyByUser                     // Authenticated user ID, falling back to client IP
+)
+
+// RateLimitRule limits hits per key to Limit per Window. Name identifies the route group:
+// routes sharing a rule share its budget.
+type RateLimitRule struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+	KeyBy  RateLimitKey
+}
+
+// RateLimiter builds rate limiting middleware backed by a RateLimitStore
+type RateLimiter struct {
+	store   RateLimitStore
+	enabled bool
+}
+
+func NewRateLimiter(store RateLimitStore, enabled bool) *RateLimiter {
+	return &RateLimiter{store: store, enabled: enabled}
+}
+
+// Limit enforces rule and reports it in the RateLimit-* response headers.
+// KeyByUser rules must run after ValidateToken or OptionalAuth, which set the verified user ID.
+// Store errors are logged and the request is allowed.
+func (rl *RateLimiter) Limit(rule RateLimitRule) gin.HandlerFunc {
+	policy := fmt.Sprintf("%d;w=%d", rule.Limit, int(rule.Window.Seconds()))
+
+	return func(c *gin.Context) {
+		if !rl.enabled || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		key := "ip:" + c.ClientIP()
+		if rule.KeyBy == KeyByUser {
+			if userID := c.GetString("user_id"); userID != "" {
+				key = "user:" + userID
+			}
+		}
+
+		count, resetAt, err := rl.store.Increment(c.Request.Context(), "ratelimit:"+rule.Name+":"+key, rule.Window)
+		if err != nil {
+			log.Printf("⚠️  Rate limit check failed for %s: %v", rule.Name, err)
+			c.Next()
+			return
+		}
+
+		remaining := int64(rule.Limit) - count
+		if remaining < 0 {
+			remaining = 0
+		}
+		resetSeconds := int64(time.Until(resetAt).Round(time.Second).Seconds())
+		if resetSeconds < 0 {
+			resetSeconds = 0
+		}
+		setRateLimitHeaders(c, rule.Limit, remaining, resetSeconds, policy)
+
+		if count > int64(rule.Limit) {
+			c.Header("Retry-After", strconv.FormatInt(resetSeconds, 10))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":   "rate_limit_exceeded",
+				"message": fmt.Sprintf("Too many requests. Please try again in %d seconds.", resetSeconds),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// setRateLimitHeaders writes the RateLimit-* headers unless a rule that already ran for this
+// request has fewer requests remaining, so clients always see the most restrictive limit
+func setRateLimitHeaders(c *gin.Context, limit int, remaining, resetSeconds int64, policy string) {
+	if current := c.Writer.Header().Get("RateLimit-Remaining"); current != "" {
+		if value, err := strconv.ParseInt(current, 10, 64); err == nil && value < remaining {
+			return
+		}
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(limit))
+	c.Header("RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+	c.Header("RateLimit-Reset", strconv.FormatInt(resetSeconds, 10))
+	c.Header("RateLimit-Policy", policy)
+}
+
+// MemoryRateLimitStore keeps counters in process memory. Limits are per gateway instance.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	windows   map[string]*rateLimitWindow
+	nextSweep time.Time
+}
+
+type rateLimitWindow struct {
+	count   int64
+	resetAt time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{windows: make(map[string]*rateLimitWindow)}
+}
+
+func (s *MemoryRateLimitStore) Increment(_ context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop expired windows at most once a minute so the map doesn't grow with every client seen
+	if now.After(s.nextSweep) {
+		for k, w := range s.windows {
+			if !now.Before(w.resetAt) {
+				delete(s.windows, k)
+			}
+		}
+		s.nextSweep = now.Add(time.Minute)
+	}
+
+	w, ok := s.windows[key]
+	if !ok || !now.Before(w.resetAt) {
+		w = &rateLimitWindow{resetAt: now.Add(window)}
+		s.windows[key] = w
+	}
+	w.count++
+
+	return w.count, w.resetAt, nil
+}
+
+// RedisRateLimitStore keeps counters in Redis (or any server speaking its protocol),
+// sharing limits between gateway replicas
+type RedisRateLimitStore struct {
+	client *redis.Client
+}
+
+// Starts the window on the first hit and returns the count with the milliseconds left
+var incrementScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+func NewRedisRateLimitStore(redisURL string) (*RedisRateLimitStore, error) {
+	opt, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis URL: %w", err)
+	}
+
+	client := redis.NewClient(opt)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisRateLimitStore{client: client}, nil
+}
+
+func (s *RedisRateLimitStore) Increment(ctx context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	result, err := incrementScript.Run(ctx, s.client, []string{key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if len(result) != 2 {
+		return 0, time.Time{}, fmt.Errorf("unexpected rate limit script result: %v", result)
+	}
+
+	return result[0], time.Now().Add(time.Duration(result[1]) * time.Millisecond), nil
+}
+
+func (s *RedisRateLimitStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMemoryRateLimitStoreWindow(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	ctx := context.Background()
+
+	for want := int64(1); want <= 3; want++ {
+		count, _, err := store.Increment(ctx, "k", 20*time.Millisecond)
+		if err != nil || count != want {
+			t.Fatalf("Increment = %d, %v; want %d", count, err, want)
+		}
+	}
+	if count, _, _ := store.Increment(ctx, "other", 20*time.Millisecond); count != 1 {
+		t.Errorf("keys should be counted separately, got %d", count)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if count, _, _ := store.Increment(ctx, "k", 20*time.Millisecond); count != 1 {
+		t.Errorf("count should restart after the window, got %d", count)
+	}
+}
+
+func TestRateLimiterLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(), true)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if userID := c.GetHeader("X-Test-User"); userID != "" {
+			c.Set("user_id", userID)
+		}
+	})
+	r.GET("/", limiter.Limit(RateLimitRule{Name: "test", Limit: 2, Window: time.Minute, KeyBy: KeyByUser}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(userID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Test-User", userID)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request("a"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "1" {
+		t.Fatalf("first request: status %d, remaining %q", w.Code, w.Header().Get("RateLimit-Remaining"))
+	}
+	request("a")
+
+	w := request("a")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request: status %d, want 429", w.Code)
+	}
+	if w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("Retry-After") == "" {
+		t.Errorf("missing limit headers on 429: %v", w.Header())
+	}
+	if got := w.Header().Get("RateLimit-Policy"); got != "2;w=60" {
+		t.Errorf("RateLimit-Policy = %q", got)
+	}
+
+	if w := request("b"); w.Code != http.StatusOK {
+		t.Errorf("another user should have its own budget, got %d", w.Code)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(), false)
+	r := gin.New()
+	r.GET("/", limiter.Limit(RateLimitRule{Name: "test", Limit: 1, Window: time.Minute}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d with limiting disabled", i, w.Code)
+		}
+	}
+}
+
+func TestRateLimiterIgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(), true)
+	r := gin.New()
+	if err := r.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	r.POST("/login", limiter.Limit(RateLimitRule{Name: "auth:login", Limit: 2, Window: time.Minute, KeyBy: KeyByIP}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	codes := make([]int, 3)
+	for i := range codes {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = "203.0.113.7:40000"
+		req.Header.Set("X-Forwarded-For", "198.51.100."+string(rune('1'+i)))
+		r.ServeHTTP(w, req)
+		codes[i] = w.Code
+	}
+	if codes[2] != http.StatusTooManyRequests {
+		t.Errorf("statuses %v: a new X-Forwarded-For on each request must not reset the budget", codes)
+	}
+}
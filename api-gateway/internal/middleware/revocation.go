@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RevocationCache keeps a local copy of auth-service's access token revocations so that
+// ValidateToken can reject revoked tokens without a call per request. It is refreshed
+// incrementally in the background. Until the first sync succeeds the cache cannot tell
+// which tokens were revoked, so Synced is false and authenticated requests are refused.
+type RevocationCache struct {
+	authServiceURL string
+	internalAPIKey string
+	interval       time.Duration
+	client         *http.Client
+
+	mu            sync.RWMutex
+	revokedTokens map[string]time.Time    // jti -> token expiry
+	invalidBefore map[uuid.UUID]time.Time // user -> tokens issued before this are rejected
+	syncedAt      time.Time               // auth-service clock at the last successful sync
+	synced        bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type revocationsResponse struct {
+	Success bool `json:"success"`
+	Data    struct {
+		ServerTime    time.Time `json:"server_time"`
+		RevokedTokens []struct {
+			JTI       string    `json:"jti"`
+			ExpiresAt time.Time `json:"expires_at"`
+		} `json:"revoked_tokens"`
+		TokenCutoffs []struct {
+			UserID        uuid.UUID `json:"user_id"`
+			InvalidBefore time.Time `json:"invalid_before"`
+		} `json:"token_cutoffs"`
+	} `json:"data"`
+}
+
+// NewRevocationCache loads the current revocations before returning, so a gateway that
+// can reach auth-service accepts tokens from its first request
+func NewRevocationCache(authServiceURL, internalAPIKey string, interval time.Duration) *RevocationCache {
+	rc := &RevocationCache{
+		authServiceURL: authServiceURL,
+		internalAPIKey: internalAPIKey,
+		interval:       interval,
+		client:         &http.Client{Timeout: 5 * time.Second},
+		revokedTokens:  make(map[string]time.Time),
+		invalidBefore:  make(map[uuid.UUID]time.Time),
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	if err := rc.refresh(); err != nil {
+		log.Printf("⚠️  Initial token revocation sync failed, refusing authenticated requests until it succeeds: %v", err)
+	}
+	return rc
+}
+
+// Start syncs with auth-service until Stop is called
+func (rc *RevocationCache) Start() {
+	defer close(rc.done)
+
+	ticker := time.NewTicker(rc.interval)
+	defer ticker.Stop()
+
+	log.Printf("🔒 Token revocation sync started (every %v)", rc.interval)
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-rc.stop:
+			return
+		}
+
+		if err := rc.refresh(); err != nil {
+			log.Printf("⚠️  Token revocation sync failed: %v", err)
+		}
+	}
+}
+
+// Stop ends the background sync
+func (rc *RevocationCache) Stop() {
+	close(rc.stop)
+	<-rc.done
+}
+
+// Synced reports whether revocations have been loaded at least once
+func (rc *RevocationCache) Synced() bool {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.synced
+}
+
+// IsRevoked reports whether the token was revoked by ID or issued before the user's cutoff
+func (rc *RevocationCache) IsRevoked(claims *Claims) bool {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	if claims.ID != "" {
+		if _, ok := rc.revokedTokens[claims.ID]; ok {
+			return true
+		}
+	}
+
+	cutoff, ok := rc.invalidBefore[claims.UserID]
+	if !ok {
+		return false
+	}
+	if claims.IssuedAt == nil {
+		return true
+	}
+	return claims.IssuedAt.Time.Before(cutoff)
+}
+
+// refresh fetches the revocations recorded since the last sync and drops expired ones
+func (rc *RevocationCache) refresh() error {
+	rc.mu.RLock()
+	since := rc.syncedAt
+	rc.mu.RUnlock()
+
+	endpoint := rc.authServiceURL + "/api/v1/auth/internal/revocations"
+	if !since.IsZero() {
+		endpoint += "?since=" + url.QueryEscape(since.Format(time.RFC3339Nano))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Internal-API-Key", rc.internalAPIKey)
+
+	resp, err := rc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth service returned status %d", resp.StatusCode)
+	}
+
+	var body revocationsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode revocations: %w", err)
+	}
+
+	now := time.Now()
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	for _, token := range body.Data.RevokedTokens {
+		rc.revokedTokens[token.JTI] = token.ExpiresAt
+	}
+	for _, cutoff := range body.Data.TokenCutoffs {
+		if cutoff.InvalidBefore.After(rc.invalidBefore[cutoff.UserID]) {
+			rc.invalidBefore[cutoff.UserID] = cutoff.InvalidBefore
+		}
+	}
+	for jti, expiresAt := range rc.revokedTokens {
+		if expiresAt.Before(now) {
+			delete(rc.revokedTokens, jti)
+		}
+	}
+	rc.syncedAt = body.Data.ServerTime
+	rc.synced = true
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func signedToken(t *testing.T, secret, jti string) string {
+	t.Helper()
+	claims := &Claims{
+		UserID: uuid.New(),
+		Role:   "student",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestValidateTokenWaitsForRevocationSync(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var available atomic.Bool
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"success": true,
+			"data": map[string]any{
+				"server_time":    time.Now(),
+				"revoked_tokens": []map[string]any{{"jti": "revoked", "expires_at": time.Now().Add(time.Hour)}},
+			},
+		})
+	}))
+	defer auth.Close()
+
+	revocations := NewRevocationCache(auth.URL, "key", time.Hour)
+	r := gin.New()
+	r.GET("/me", NewAuthMiddleware("secret", revocations).ValidateToken(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	call := func(jti string) int {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+signedToken(t, "secret", jti))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if revocations.Synced() {
+		t.Fatal("cache reports synced after a failed first sync")
+	}
+	if code := call("revoked"); code != http.StatusServiceUnavailable {
+		t.Errorf("before the first sync: status %d, want 503", code)
+	}
+
+	available.Store(true)
+	if err := revocations.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if code := call("revoked"); code != http.StatusUnauthorized {
+		t.Errorf("revoked token: status %d, want 401", code)
+	}
+	if code := call("live"); code != http.StatusOK {
+		t.Errorf("live token: status %d, want 200", code)
+	}
+}
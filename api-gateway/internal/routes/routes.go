@@ -2,6 +2,7 @@ package routes
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/bisosad1501/ielts-platform/api-gateway/internal/config"
 	"github.com/bisosad1501/ielts-platform/api-gateway/internal/middleware"
@@ -9,7 +10,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.AuthMiddleware) {
+func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.AuthMiddleware, rateLimiter *middleware.RateLimiter) {
 	// Health check for gateway itself
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -47,8 +48,20 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 		})
 	})
 
+	// Rate limits: every client IP, every signed-in user, plus stricter budgets for
+	// credential endpoints (brute force, email flooding) and grading submissions.
+	// Each credential endpoint has its own budget, so requesting codes does not use up
+	// login attempts and vice versa.
+	ipLimit := rateLimiter.Limit(middleware.RateLimitRule{Name: "ip", Limit: cfg.RateLimit.RequestsPerMinute, Window: time.Minute, KeyBy: middleware.KeyByIP})
+	userLimit := rateLimiter.Limit(middleware.RateLimitRule{Name: "user", Limit: cfg.RateLimit.UserRequestsPerMinute, Window: time.Minute, KeyBy: middleware.KeyByUser})
+	authLimit := func(route string) gin.HandlerFunc {
+		return rateLimiter.Limit(middleware.RateLimitRule{Name: "auth:" + route, Limit: cfg.RateLimit.AuthRequestsPerMinute, Window: time.Minute, KeyBy: middleware.KeyByIP})
+	}
+	submitLimit := rateLimiter.Limit(middleware.RateLimitRule{Name: "submit", Limit: cfg.RateLimit.SubmitRequestsPerMinute, Window: time.Minute, KeyBy: middleware.KeyByUser})
+
 	// API v1 routes
 	v1 := r.Group("/api/v1")
+	v1.Use(ipLimit)
 
 	// ============================================
 	// AUTH SERVICE - No auth required (public routes)
@@ -56,20 +69,20 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 	authGroup := v1.Group("/auth")
 	{
 		// Public auth endpoints (no token required)
-		authGroup.POST("/register", proxy.ReverseProxy(cfg.Services.AuthService))
-		authGroup.POST("/login", proxy.ReverseProxy(cfg.Services.AuthService))
+		authGroup.POST("/register", authLimit("register"), proxy.ReverseProxy(cfg.Services.AuthService))
+		authGroup.POST("/login", authLimit("login"), proxy.ReverseProxy(cfg.Services.AuthService))
 		authGroup.POST("/refresh", proxy.ReverseProxy(cfg.Services.AuthService))
 		authGroup.POST("/logout", proxy.ReverseProxy(cfg.Services.AuthService))
 
 		// Email verification
-		authGroup.GET("/verify-email", proxy.ReverseProxy(cfg.Services.AuthService))          // Legacy token-based verification
-		authGroup.POST("/verify-email-by-code", proxy.ReverseProxy(cfg.Services.AuthService)) // New 6-digit code verification
-		authGroup.POST("/resend-verification", proxy.ReverseProxy(cfg.Services.AuthService))
+		authGroup.GET("/verify-email", proxy.ReverseProxy(cfg.Services.AuthService))                     // Legacy token-based verification
+		authGroup.POST("/verify-email-by-code", authLimit("verify-email-by-code"), proxy.ReverseProxy(cfg.Services.AuthService)) // New 6-digit code verification
+		authGroup.POST("/resend-verification", authLimit("resend-verification"), proxy.ReverseProxy(cfg.Services.AuthService))
 
 		// Password reset
-		authGroup.POST("/forgot-password", proxy.ReverseProxy(cfg.Services.AuthService))        // Request reset (sends 6-digit code)
-		authGroup.POST("/reset-password", proxy.ReverseProxy(cfg.Services.AuthService))         // Legacy token-based reset
-		authGroup.POST("/reset-password-by-code", proxy.ReverseProxy(cfg.Services.AuthService)) // New 6-digit code reset
+		authGroup.POST("/forgot-password", authLimit("forgot-password"), proxy.ReverseProxy(cfg.Services.AuthService))        // Request reset (sends 6-digit code)
+		authGroup.POST("/reset-password", authLimit("reset-password"), proxy.ReverseProxy(cfg.Services.AuthService))         // Legacy token-based reset
+		authGroup.POST("/reset-password-by-code", authLimit("reset-password-by-code"), proxy.ReverseProxy(cfg.Services.AuthService)) // New 6-digit code reset
 
 		// Google OAuth
 		authGroup.GET("/google/url", proxy.ReverseProxy(cfg.Services.AuthService))      // Get OAuth URL (Mobile/Web)
@@ -77,13 +90,25 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 		authGroup.GET("/google/callback", proxy.ReverseProxy(cfg.Services.AuthService)) // Web flow: Handle callback
 		authGroup.POST("/google/token", proxy.ReverseProxy(cfg.Services.AuthService))   // Mobile flow: Exchange code
 
+		// Two-factor login (second step after /login)
+		authGroup.POST("/2fa/verify", authLimit("2fa/verify"), proxy.ReverseProxy(cfg.Services.AuthService))
+		authGroup.POST("/2fa/email/send", authLimit("2fa/email/send"), proxy.ReverseProxy(cfg.Services.AuthService))
+
 		// Protected auth endpoints (require token)
 		authProtected := authGroup.Group("")
-		authProtected.Use(authMiddleware.ValidateToken())
+		authProtected.Use(authMiddleware.ValidateToken(), userLimit)
 		{
 			authProtected.GET("/validate", proxy.ReverseProxy(cfg.Services.AuthService))
 			authProtected.POST("/change-password", proxy.ReverseProxy(cfg.Services.AuthService))
 			authProtected.GET("/me", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.GET("/sessions", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.DELETE("/sessions/:id", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.POST("/sessions/revoke-others", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.GET("/2fa/status", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.POST("/2fa/totp/setup", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.POST("/2fa/totp/enable", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.POST("/2fa/totp/disable", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.POST("/2fa/recovery-codes", proxy.ReverseProxy(cfg.Services.AuthService))
 		}
 	}
 
@@ -92,7 +117,7 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 	// ============================================
 	// Public user profile route (optional auth)
 	usersGroup := v1.Group("/users")
-	usersGroup.Use(authMiddleware.OptionalAuth()) // Optional auth for visibility check
+	usersGroup.Use(authMiddleware.OptionalAuth(), userLimit) // Optional auth for visibility check
 	{
 		usersGroup.GET("/:id/profile", proxy.ReverseProxy(cfg.Services.UserService))
 		usersGroup.GET("/:id/achievements", proxy.ReverseProxy(cfg.Services.UserService))
@@ -102,14 +127,14 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 
 	// Protected social routes (auth required)
 	usersProtected := v1.Group("/users")
-	usersProtected.Use(authMiddleware.ValidateToken())
+	usersProtected.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		usersProtected.POST("/:id/follow", proxy.ReverseProxy(cfg.Services.UserService))
 		usersProtected.DELETE("/:id/follow", proxy.ReverseProxy(cfg.Services.UserService))
 	}
 
 	userGroup := v1.Group("/user")
-	userGroup.Use(authMiddleware.ValidateToken())
+	userGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		userGroup.GET("/profile", proxy.ReverseProxy(cfg.Services.UserService))
 		userGroup.PUT("/profile", proxy.ReverseProxy(cfg.Services.UserService))
@@ -162,7 +187,7 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 
 		// Protected review endpoints
 		courseProtected := courseGroup.Group("")
-		courseProtected.Use(authMiddleware.ValidateToken())
+		courseProtected.Use(authMiddleware.ValidateToken(), userLimit)
 		{
 			courseProtected.POST("/:id/enroll", proxy.ReverseProxy(cfg.Services.CourseService))
 			courseProtected.GET("/my-courses", proxy.ReverseProxy(cfg.Services.CourseService))
@@ -183,7 +208,7 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 
 	// Video endpoints (protected)
 	videoGroup := v1.Group("/videos")
-	videoGroup.Use(authMiddleware.ValidateToken())
+	videoGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		videoGroup.POST("/track", proxy.ReverseProxy(cfg.Services.CourseService))        // Track video watch progress
 		videoGroup.GET("/history", proxy.ReverseProxy(cfg.Services.CourseService))       // Get watch history
@@ -192,14 +217,14 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 
 	// Materials endpoints (protected)
 	materialGroup := v1.Group("/materials")
-	materialGroup.Use(authMiddleware.ValidateToken())
+	materialGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		materialGroup.POST("/:id/download", proxy.ReverseProxy(cfg.Services.CourseService)) // Record material download
 	}
 
 	// Enrollments endpoints (from Course Service)
 	enrollmentGroup := v1.Group("/enrollments")
-	enrollmentGroup.Use(authMiddleware.ValidateToken())
+	enrollmentGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		enrollmentGroup.POST("", proxy.ReverseProxy(cfg.Services.CourseService))
 		enrollmentGroup.GET("/my", proxy.ReverseProxy(cfg.Services.CourseService))
@@ -208,7 +233,7 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 
 	// Progress endpoints (from Course Service)
 	progressGroup := v1.Group("/progress")
-	progressGroup.Use(authMiddleware.ValidateToken())
+	progressGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		progressGroup.GET("/lessons/:id", proxy.ReverseProxy(cfg.Services.CourseService)) // Get lesson progress (for resume watching)
 		progressGroup.PUT("/lessons/:id", proxy.ReverseProxy(cfg.Services.CourseService)) // Update lesson progress
@@ -226,7 +251,7 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 
 		// Protected (requires login)
 		exerciseProtected := exerciseGroup.Group("")
-		exerciseProtected.Use(authMiddleware.ValidateToken())
+		exerciseProtected.Use(authMiddleware.ValidateToken(), userLimit)
 		{
 			exerciseProtected.POST("/:id/start", proxy.ReverseProxy(cfg.Services.ExerciseService))
 		}
@@ -240,16 +265,41 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 
 	// Submissions (all protected)
 	submissionGroup := v1.Group("/submissions")
-	submissionGroup.Use(authMiddleware.ValidateToken())
+	submissionGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
-		submissionGroup.POST("", proxy.ReverseProxy(cfg.Services.ExerciseService))            // Start new submission
-		submissionGroup.POST("/:id/submit", proxy.ReverseProxy(cfg.Services.ExerciseService)) // Unified submission (Phase 4)
-		submissionGroup.PUT("/:id/answers", proxy.ReverseProxy(cfg.Services.ExerciseService)) // Deprecated, use /submit
+		submissionGroup.POST("", proxy.ReverseProxy(cfg.Services.ExerciseService))                         // Start new submission
+		submissionGroup.POST("/:id/submit", submitLimit, proxy.ReverseProxy(cfg.Services.ExerciseService)) // Unified submission (Phase 4)
+		submissionGroup.PUT("/:id/answers", submitLimit, proxy.ReverseProxy(cfg.Services.ExerciseService)) // Deprecated, use /submit
+		submissionGroup.PUT("/:id/autosave", proxy.ReverseProxy(cfg.Services.ExerciseService))             // Save partial answers
 		submissionGroup.GET("/:id/result", proxy.ReverseProxy(cfg.Services.ExerciseService))
 		submissionGroup.GET("/my", proxy.ReverseProxy(cfg.Services.ExerciseService))
 		submissionGroup.GET("", proxy.ReverseProxy(cfg.Services.ExerciseService)) // List my submissions (duplicate of /my)
 	}
 
+	// Full mock test sessions (all protected)
+	testSessionGroup := v1.Group("/test-sessions")
+	testSessionGroup.Use(authMiddleware.ValidateToken(), userLimit)
+	{
+		testSessionGroup.POST("", proxy.ReverseProxy(cfg.Services.ExerciseService))                       // Create a test session
+		testSessionGroup.GET("", proxy.ReverseProxy(cfg.Services.ExerciseService))                        // My test sessions
+		testSessionGroup.GET("/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))                    // Session progress
+		testSessionGroup.POST("/:id/parts/:part/start", proxy.ReverseProxy(cfg.Services.ExerciseService)) // Start the next part
+		testSessionGroup.GET("/:id/report", proxy.ReverseProxy(cfg.Services.ExerciseService))             // Full score report
+	}
+
+	// Mistake notebook and spaced-repetition reviews (all protected)
+	reviewGroup := v1.Group("/reviews")
+	reviewGroup.Use(authMiddleware.ValidateToken(), userLimit)
+	{
+		reviewGroup.GET("/summary", proxy.ReverseProxy(cfg.Services.ExerciseService))                           // Due review counts
+		reviewGroup.GET("/items", proxy.ReverseProxy(cfg.Services.ExerciseService))                             // My review items
+		reviewGroup.DELETE("/items/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))                      // Stop reviewing a question
+		reviewGroup.POST("/sessions", proxy.ReverseProxy(cfg.Services.ExerciseService))                         // Start a review session
+		reviewGroup.GET("/sessions/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))                      // Session with questions
+		reviewGroup.POST("/sessions/:id/items/:item_id/answer", proxy.ReverseProxy(cfg.Services.ExerciseService)) // Answer a review item
+		reviewGroup.POST("/sessions/:id/complete", proxy.ReverseProxy(cfg.Services.ExerciseService))            // Finish early
+	}
+
 	// ============================================
 		// STORAGE SERVICE
 		// ============================================
@@ -258,9 +308,9 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 			audio := storageGroup.Group("/audio")
 			{
 				// Protected routes (require auth)
-				audio.POST("/upload", authMiddleware.ValidateToken(), proxy.ReverseProxy(cfg.Services.ExerciseService))              // Upload audio (proxy to Storage Service)
-				audio.GET("/info/*object_name", authMiddleware.ValidateToken(), proxy.ReverseProxy(cfg.Services.ExerciseService))    // Get audio info
-				audio.GET("/presigned-url/*object_name", authMiddleware.ValidateToken(), proxy.ReverseProxy(cfg.Services.StorageService)) // Get presigned URL (direct to Storage Service)
+				audio.POST("/upload", authMiddleware.ValidateToken(), userLimit, proxy.ReverseProxy(cfg.Services.ExerciseService))              // Upload audio (proxy to Storage Service)
+				audio.GET("/info/*object_name", authMiddleware.ValidateToken(), userLimit, proxy.ReverseProxy(cfg.Services.ExerciseService))    // Get audio info
+				audio.GET("/presigned-url/*object_name", authMiddleware.ValidateToken(), userLimit, proxy.ReverseProxy(cfg.Services.StorageService)) // Get presigned URL (direct to Storage Service)
 				
 				// Public route (no auth required) - for HTML5 audio player
 				// NOTE: This is safe because audio files are already protected during upload
@@ -273,7 +323,7 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 	// NOTIFICATION SERVICE - All protected
 	// ============================================
 	notificationGroup := v1.Group("/notifications")
-	notificationGroup.Use(authMiddleware.ValidateToken())
+	notificationGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		// SSE stream (must be before /:id to avoid route conflict)
 		notificationGroup.GET("/stream", proxy.ReverseProxy(cfg.Services.NotificationService))
@@ -309,70 +359,102 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 	}
 
 	// ============================================
-	// ADMIN ROUTES - Require admin/instructor role
+	// ADMIN ROUTES - Require a per-endpoint permission
 	// ============================================
 	adminGroup := v1.Group("/admin")
-	adminGroup.Use(authMiddleware.ValidateToken())
-	adminGroup.Use(authMiddleware.RequireRole("instructor", "admin"))
+	adminGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		// Course management
-		adminGroup.POST("/courses", proxy.ReverseProxy(cfg.Services.CourseService))
-		adminGroup.PUT("/courses/:id", proxy.ReverseProxy(cfg.Services.CourseService))
-		adminGroup.DELETE("/courses/:id", proxy.ReverseProxy(cfg.Services.CourseService))
-		adminGroup.POST("/courses/:id/publish", proxy.ReverseProxy(cfg.Services.CourseService))
+		adminGroup.POST("/courses", authMiddleware.RequirePermission("course:create"), proxy.ReverseProxy(cfg.Services.CourseService))
+		adminGroup.PUT("/courses/:id", authMiddleware.RequirePermission("course:update"), proxy.ReverseProxy(cfg.Services.CourseService))
+		adminGroup.DELETE("/courses/:id", authMiddleware.RequirePermission("course:delete"), proxy.ReverseProxy(cfg.Services.CourseService))
+		adminGroup.POST("/courses/:id/publish", authMiddleware.RequirePermission("course:publish"), proxy.ReverseProxy(cfg.Services.CourseService))
 
 		// Module and lesson management
-		adminGroup.POST("/modules", proxy.ReverseProxy(cfg.Services.CourseService))
-		adminGroup.POST("/lessons", proxy.ReverseProxy(cfg.Services.CourseService))
+		adminGroup.POST("/modules", authMiddleware.RequirePermission("course:create"), proxy.ReverseProxy(cfg.Services.CourseService))
+		adminGroup.POST("/lessons", authMiddleware.RequirePermission("course:create"), proxy.ReverseProxy(cfg.Services.CourseService))
 
 		// Video management
-		adminGroup.POST("/lessons/:lesson_id/videos", proxy.ReverseProxy(cfg.Services.CourseService))
+		adminGroup.POST("/lessons/:lesson_id/videos", authMiddleware.RequirePermission("course:create"), proxy.ReverseProxy(cfg.Services.CourseService))
 
 		// Exercise management
-		adminGroup.POST("/exercises", proxy.ReverseProxy(cfg.Services.ExerciseService))
-		adminGroup.PUT("/exercises/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))
-		adminGroup.DELETE("/exercises/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))
-		adminGroup.POST("/exercises/:id/publish", proxy.ReverseProxy(cfg.Services.ExerciseService))
-		adminGroup.POST("/exercises/:id/unpublish", proxy.ReverseProxy(cfg.Services.ExerciseService))
-		adminGroup.POST("/exercises/:id/sections", proxy.ReverseProxy(cfg.Services.ExerciseService))
-		adminGroup.GET("/exercises/:id/analytics", proxy.ReverseProxy(cfg.Services.ExerciseService))
-		adminGroup.POST("/exercises/:id/tags", proxy.ReverseProxy(cfg.Services.ExerciseService))
-		adminGroup.DELETE("/exercises/:id/tags/:tag_id", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/exercises", authMiddleware.RequirePermission("exercise:create"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/exercises/import", authMiddleware.RequirePermission("exercise:create"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.GET("/exercises/:id", authMiddleware.RequirePermission("exercise:update"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.GET("/exercises/:id/export", authMiddleware.RequirePermission("exercise:update"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.PUT("/exercises/:id", authMiddleware.RequirePermission("exercise:update"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.DELETE("/exercises/:id", authMiddleware.RequirePermission("exercise:delete"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/exercises/:id/publish", authMiddleware.RequirePermission("exercise:publish"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/exercises/:id/unpublish", authMiddleware.RequirePermission("exercise:publish"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/exercises/:id/sections", authMiddleware.RequirePermission("exercise:create"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.GET("/exercises/:id/analytics", authMiddleware.RequirePermission("exercise:view_analytics"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.GET("/exercises/:id/analytics/items", authMiddleware.RequirePermission("exercise:view_analytics"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/exercises/:id/tags", authMiddleware.RequirePermission("exercise:update"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.DELETE("/exercises/:id/tags/:tag_id", authMiddleware.RequirePermission("exercise:update"), proxy.ReverseProxy(cfg.Services.ExerciseService))
 
 		// Question management
-		adminGroup.POST("/questions", proxy.ReverseProxy(cfg.Services.ExerciseService))
-		adminGroup.POST("/questions/:id/options", proxy.ReverseProxy(cfg.Services.ExerciseService))
-		adminGroup.POST("/questions/:id/answer", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/questions", authMiddleware.RequirePermission("exercise:create"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/questions/:id/options", authMiddleware.RequirePermission("exercise:create"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/questions/:id/answer", authMiddleware.RequirePermission("exercise:create"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/questions/:id/preview-grading", authMiddleware.RequirePermission("exercise:update"), proxy.ReverseProxy(cfg.Services.ExerciseService))
 
 		// Question Bank management
-		adminGroup.GET("/question-bank", proxy.ReverseProxy(cfg.Services.ExerciseService))
-		adminGroup.POST("/question-bank", proxy.ReverseProxy(cfg.Services.ExerciseService))
-		adminGroup.PUT("/question-bank/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))
-		adminGroup.DELETE("/question-bank/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.GET("/question-bank", authMiddleware.RequirePermission("question_bank:manage"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/question-bank", authMiddleware.RequirePermission("question_bank:manage"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/question-bank/assemble", authMiddleware.RequirePermission("question_bank:manage"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.PUT("/question-bank/:id", authMiddleware.RequirePermission("question_bank:manage"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.DELETE("/question-bank/:id", authMiddleware.RequirePermission("question_bank:manage"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+
+		// AI evaluation queue
+		adminGroup.GET("/evaluation-jobs/dead", authMiddleware.RequirePermission("evaluation:manage"), proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/evaluation-jobs/:id/requeue", authMiddleware.RequirePermission("evaluation:manage"), proxy.ReverseProxy(cfg.Services.ExerciseService))
 
 		// Tag management
-		adminGroup.POST("/tags", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/tags", authMiddleware.RequirePermission("exercise:create"), proxy.ReverseProxy(cfg.Services.ExerciseService))
 
 		// Notification management
-		adminGroup.POST("/notifications", proxy.ReverseProxy(cfg.Services.NotificationService))
-		adminGroup.POST("/notifications/bulk", proxy.ReverseProxy(cfg.Services.NotificationService))
+		adminGroup.POST("/notifications", authMiddleware.RequirePermission("notification:send"), proxy.ReverseProxy(cfg.Services.NotificationService))
+		adminGroup.POST("/notifications/bulk", authMiddleware.RequirePermission("notification:bulk_send"), proxy.ReverseProxy(cfg.Services.NotificationService))
+
+		// Account bans (Auth Service)
+		adminGroup.POST("/users/:id/ban", authMiddleware.RequirePermission("user:ban"), proxy.ReverseProxy(cfg.Services.AuthService))
+		adminGroup.POST("/users/:id/unban", authMiddleware.RequirePermission("user:ban"), proxy.ReverseProxy(cfg.Services.AuthService))
+
+		// Roles, permissions and user role assignments (Auth Service)
+		rbac := adminGroup.Group("")
+		rbac.Use(authMiddleware.RequirePermission("rbac:manage"))
+		{
+			rbac.GET("/roles", proxy.ReverseProxy(cfg.Services.AuthService))
+			rbac.POST("/roles", proxy.ReverseProxy(cfg.Services.AuthService))
+			rbac.PUT("/roles/:id", proxy.ReverseProxy(cfg.Services.AuthService))
+			rbac.DELETE("/roles/:id", proxy.ReverseProxy(cfg.Services.AuthService))
+			rbac.GET("/roles/:id/permissions", proxy.ReverseProxy(cfg.Services.AuthService))
+			rbac.POST("/roles/:id/permissions", proxy.ReverseProxy(cfg.Services.AuthService))
+			rbac.DELETE("/roles/:id/permissions/:permission_id", proxy.ReverseProxy(cfg.Services.AuthService))
+			rbac.GET("/permissions", proxy.ReverseProxy(cfg.Services.AuthService))
+			rbac.POST("/permissions", proxy.ReverseProxy(cfg.Services.AuthService))
+			rbac.DELETE("/permissions/:id", proxy.ReverseProxy(cfg.Services.AuthService))
+			rbac.GET("/users/:id/roles", proxy.ReverseProxy(cfg.Services.AuthService))
+			rbac.POST("/users/:id/roles", proxy.ReverseProxy(cfg.Services.AuthService))
+			rbac.DELETE("/users/:id/roles/:role_id", proxy.ReverseProxy(cfg.Services.AuthService))
+		}
 	}
 
 	// ============================================
 	// AI SERVICE - Protected (auth required)
 	// ============================================
 	aiGroup := v1.Group("/ai")
-	aiGroup.Use(authMiddleware.ValidateToken())
+	aiGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		// Writing endpoints
-		aiGroup.POST("/writing/submit", proxy.ReverseProxy(cfg.Services.AIService))
+		aiGroup.POST("/writing/submit", submitLimit, proxy.ReverseProxy(cfg.Services.AIService))
 		aiGroup.GET("/writing/submissions", proxy.ReverseProxy(cfg.Services.AIService))
 		aiGroup.GET("/writing/submissions/:id", proxy.ReverseProxy(cfg.Services.AIService))
 		aiGroup.GET("/writing/prompts", proxy.ReverseProxy(cfg.Services.AIService))
 		aiGroup.GET("/writing/prompts/:id", proxy.ReverseProxy(cfg.Services.AIService))
 
 		// Speaking endpoints
-		aiGroup.POST("/speaking/submit", proxy.ReverseProxy(cfg.Services.AIService))
+		aiGroup.POST("/speaking/submit", submitLimit, proxy.ReverseProxy(cfg.Services.AIService))
 		aiGroup.GET("/speaking/submissions", proxy.ReverseProxy(cfg.Services.AIService))
 		aiGroup.GET("/speaking/submissions/:id", proxy.ReverseProxy(cfg.Services.AIService))
 		aiGroup.GET("/speaking/prompts", proxy.ReverseProxy(cfg.Services.AIService))
@@ -380,11 +462,11 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 	}
 
 	// ============================================
-	// ADMIN AI ROUTES - Require admin role
+	// ADMIN AI ROUTES - Require ai:manage
 	// ============================================
 	adminAIGroup := v1.Group("/admin/ai")
-	adminAIGroup.Use(authMiddleware.ValidateToken())
-	adminAIGroup.Use(authMiddleware.RequireRole("admin"))
+	adminAIGroup.Use(authMiddleware.ValidateToken(), userLimit)
+	adminAIGroup.Use(authMiddleware.RequirePermission("ai:manage"))
 	{
 		// Writing prompts management
 		adminAIGroup.POST("/writing/prompts", proxy.ReverseProxy(cfg.Services.AIService))
@@ -54,6 +54,7 @@ CREATE TABLE users (
     locked_until TIMESTAMP, -- Account lockout timestamp
     last_login_at TIMESTAMP,
     last_login_ip VARCHAR(45), -- Supports both IPv4 and IPv6
+    tokens_invalid_before TIMESTAMP, -- Access tokens issued before this are rejected (ban, password change)
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     deleted_at TIMESTAMP -- Soft delete support
@@ -137,6 +138,7 @@ CREATE TABLE refresh_tokens (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     token_hash VARCHAR(255) NOT NULL, -- SHA-256 hash of the refresh token
+    family_id UUID NOT NULL, -- Session: shared by every token rotated from the same login
     device_id VARCHAR(255), -- Unique device identifier
     device_name VARCHAR(100), -- User-friendly device name
     device_type VARCHAR(50), -- 'mobile', 'desktop', 'tablet'
@@ -153,6 +155,65 @@ CREATE TABLE refresh_tokens (
 CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
 CREATE INDEX idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
 CREATE INDEX idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+
+-- ----------------------------------------------------------------------------
+-- Revoked Access Tokens Table
+-- ----------------------------------------------------------------------------
+-- Access tokens (by JWT ID) revoked before expiry, e.g. on logout.
+-- Synced to the API gateway; rows can be dropped once expires_at has passed.
+CREATE TABLE revoked_access_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL, -- Expiry of the revoked token
+    reason VARCHAR(50), -- 'logout', ...
+    revoked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_revoked_access_tokens_revoked_at ON revoked_access_tokens(revoked_at);
+CREATE INDEX idx_revoked_access_tokens_expires_at ON revoked_access_tokens(expires_at);
+
+-- ----------------------------------------------------------------------------
+-- Two-Factor Authentication Tables
+-- ----------------------------------------------------------------------------
+-- TOTP enrollment per user. The secret is encrypted (AES-GCM) by auth-service.
+CREATE TABLE user_two_factor (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    totp_secret TEXT NOT NULL, -- Encrypted base32 secret
+    totp_enabled BOOLEAN NOT NULL DEFAULT false, -- false until the first code is confirmed
+    totp_last_used_step BIGINT NOT NULL DEFAULT 0, -- Last accepted 30s time step (replay protection)
+    enabled_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Single-use recovery codes, stored hashed
+CREATE TABLE two_factor_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(255) NOT NULL, -- SHA-256 hash of the recovery code
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_two_factor_recovery_codes_user_id ON two_factor_recovery_codes(user_id);
+
+-- Second step of a login: issued after the password check, exchanged for tokens
+-- once a TOTP, email or recovery code is verified
+CREATE TABLE two_factor_challenges (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(255) NOT NULL, -- SHA-256 hash of the challenge token
+    email_code_hash VARCHAR(255), -- SHA-256 hash of the emailed code, if one was sent
+    email_code_sent_at TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0, -- Failed verification attempts
+    expires_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_two_factor_challenges_token_hash ON two_factor_challenges(token_hash);
+CREATE INDEX idx_two_factor_challenges_expires_at ON two_factor_challenges(expires_at);
 
 -- ----------------------------------------------------------------------------
 -- Password Reset Tokens Table
@@ -307,6 +368,45 @@ INSERT INTO role_permissions (role_id, permission_id) VALUES
 INSERT INTO role_permissions (role_id, permission_id) VALUES
     (3, 1), (3, 2), (3, 3), (3, 4), (3, 5), (3, 6), (3, 7), (3, 8), (3, 9), (3, 10);
 
+-- ----------------------------------------------------------------------------
+-- Endpoint Permissions
+-- ----------------------------------------------------------------------------
+-- Fine-grained '<resource>:<action>' permissions checked by RequirePermission in the
+-- gateway and services. Access tokens carry the union of the user's role permissions.
+INSERT INTO permissions (name, resource, action, description) VALUES
+    ('course:create', 'courses', 'create', 'Create courses, modules, lessons and lesson videos'),
+    ('course:update', 'courses', 'update', 'Update courses'),
+    ('course:publish', 'courses', 'publish', 'Publish courses'),
+    ('course:delete', 'courses', 'delete', 'Delete courses'),
+    ('course:sync_videos', 'courses', 'sync', 'Re-sync video durations'),
+    ('exercise:create', 'exercises', 'create', 'Create exercises, sections, questions and tags'),
+    ('exercise:update', 'exercises', 'update', 'Update exercises and their tags'),
+    ('exercise:publish', 'exercises', 'publish', 'Publish and unpublish exercises'),
+    ('exercise:delete', 'exercises', 'delete', 'Delete exercises'),
+    ('exercise:view_analytics', 'exercises', 'read', 'View exercise analytics'),
+    ('question_bank:manage', 'question_bank', 'manage', 'Manage the question bank'),
+    ('evaluation:manage', 'evaluations', 'manage', 'Inspect and requeue AI evaluation jobs'),
+    ('notification:send', 'notifications', 'create', 'Send a notification to a user'),
+    ('notification:bulk_send', 'notifications', 'bulk_create', 'Send notifications to many users'),
+    ('ai:manage', 'ai', 'manage', 'Manage AI prompts and caches'),
+    ('rbac:manage', 'roles', 'manage', 'Manage roles, permissions and user role assignments'),
+    ('user:ban', 'users', 'ban', 'Ban and unban user accounts');
+
+-- Instructors: content authoring and notifications
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'instructor' AND p.name IN (
+    'course:create', 'course:update', 'course:publish',
+    'exercise:create', 'exercise:update', 'exercise:publish', 'exercise:delete', 'exercise:view_analytics',
+    'question_bank:manage', 'evaluation:manage',
+    'notification:send', 'notification:bulk_send'
+);
+
+-- Admins: every endpoint permission
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.name LIKE '%:%';
+
 -- ============================================================================
 -- SCHEMA MIGRATIONS TRACKING
 -- ============================================================================
@@ -210,25 +210,32 @@ CREATE TABLE official_test_results (
     notes TEXT,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    skill_type VARCHAR(20) NOT NULL CHECK (skill_type IN ('listening', 'reading', 'writing', 'speaking')),
+    skill_type VARCHAR(20) NOT NULL CHECK (skill_type IN ('listening', 'reading', 'writing', 'speaking', 'overall')), -- 'overall' = full four-skill test
     
     -- Scoring
     band_score NUMERIC(3,1) NOT NULL CHECK (band_score >= 0 AND band_score <= 9),
     raw_score INTEGER CHECK (raw_score IS NULL OR (raw_score >= 0 AND raw_score <= 40)),
     total_questions INTEGER,
     
+    -- Skill bands of a full test (skill_type = 'overall' only)
+    listening_band_score NUMERIC(3,1) CHECK (listening_band_score IS NULL OR (listening_band_score >= 0 AND listening_band_score <= 9)),
+    reading_band_score NUMERIC(3,1) CHECK (reading_band_score IS NULL OR (reading_band_score >= 0 AND reading_band_score <= 9)),
+    writing_band_score NUMERIC(3,1) CHECK (writing_band_score IS NULL OR (writing_band_score >= 0 AND writing_band_score <= 9)),
+    speaking_band_score NUMERIC(3,1) CHECK (speaking_band_score IS NULL OR (speaking_band_score >= 0 AND speaking_band_score <= 9)),
+    
     -- Source tracking (from which service/table this result came)
     source_service VARCHAR(50), -- 'exercise-service', 'ai-service'
     source_table VARCHAR(50), -- 'user_exercise_attempts', 'ai_evaluations'
     source_id UUID, -- Original record ID
     
-    -- IELTS variant (for reading only)
+    -- IELTS variant (for reading and full tests only)
     ielts_variant VARCHAR(20) CHECK (ielts_variant IS NULL OR ielts_variant IN ('academic', 'general_training')),
     
     -- Ensure reading tests have IELTS variant specified
     CONSTRAINT official_test_results_reading_variant_rule CHECK (
         (skill_type = 'reading' AND ielts_variant IS NOT NULL) OR
-        (skill_type != 'reading' AND ielts_variant IS NULL)
+        (skill_type = 'overall') OR
+        (skill_type NOT IN ('reading', 'overall') AND ielts_variant IS NULL)
     )
 );
 
@@ -402,6 +409,17 @@ CREATE TABLE user_preferences (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- ----------------------------------------------------------------------------
+-- Weekly Report Deliveries Table
+-- ----------------------------------------------------------------------------
+-- One row per user per reported week; guarantees a weekly progress email is sent at most once
+CREATE TABLE weekly_report_deliveries (
+    user_id UUID NOT NULL REFERENCES user_profiles(user_id) ON DELETE CASCADE,
+    week_start DATE NOT NULL, -- Monday of the reported week, in the user's timezone
+    sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, week_start)
+);
+
 -- ============================================================================
 -- FUNCTIONS AND TRIGGERS
 -- ============================================================================
@@ -176,7 +176,7 @@ CREATE TABLE questions (
     section_id UUID REFERENCES exercise_sections(id) ON DELETE CASCADE,
     question_number INTEGER NOT NULL,
     question_text TEXT NOT NULL,
-    question_type VARCHAR(50) NOT NULL, -- 'multiple_choice', 'fill_in_blank', 'matching', 'true_false', 'short_answer', 'essay'
+    question_type VARCHAR(50) NOT NULL, -- 'multiple_choice', 'multiple_choice_multiple', 'true_false_not_given', 'yes_no_not_given', 'matching_headings', 'map_labeling', 'sentence_completion', 'short_answer', ... (see exercise-service grading package)
     
     -- Media
     audio_url TEXT,
@@ -193,6 +193,7 @@ CREATE TABLE questions (
     
     -- Metadata
     display_order INTEGER DEFAULT 0,
+    bank_question_id UUID, -- question_bank item the question was assembled from
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -201,6 +202,7 @@ CREATE INDEX idx_questions_exercise_id ON questions(exercise_id);
 CREATE INDEX idx_questions_section_id ON questions(section_id);
 CREATE INDEX idx_questions_question_type ON questions(question_type);
 CREATE INDEX idx_questions_number ON questions(exercise_id, question_number);
+CREATE INDEX idx_questions_bank_question_id ON questions(bank_question_id) WHERE bank_question_id IS NOT NULL;
 
 -- ----------------------------------------------------------------------------
 -- Question Options Table (for multiple choice)
@@ -226,8 +228,8 @@ CREATE TABLE question_answers (
     question_id UUID NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
     answer_text TEXT NOT NULL,
     answer_variations TEXT[], -- Alternative accepted answers
-    match_left TEXT, -- For matching questions: left side
-    match_right TEXT, -- For matching questions: right side
+    match_left TEXT, -- For matching questions: left side (item label, e.g. 'Paragraph A'); for multi-blank completion: the blank number
+    match_right TEXT, -- For matching questions: right side (the matched letter or numeral)
     is_primary_answer BOOLEAN DEFAULT true,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -246,12 +248,13 @@ CREATE TABLE user_exercise_attempts (
     user_id UUID NOT NULL,
     exercise_id UUID REFERENCES exercises(id) ON DELETE CASCADE,
     attempt_number INTEGER DEFAULT 1,
-    status VARCHAR(20) DEFAULT 'in_progress', -- 'in_progress', 'completed', 'abandoned'
+    status VARCHAR(20) DEFAULT 'in_progress', -- 'in_progress', 'submitted', 'completed', 'abandoned'
     
     -- Scoring
     total_questions INTEGER NOT NULL,
     questions_answered INTEGER DEFAULT 0,
     correct_answers INTEGER DEFAULT 0,
+    raw_score INTEGER, -- L/R marks the band is computed from: partial credit, rounded down to whole marks
     score NUMERIC(5,2),
     band_score NUMERIC(3,1) CHECK (band_score IS NULL OR (band_score >= 0 AND band_score <= 9)),
     
@@ -260,6 +263,9 @@ CREATE TABLE user_exercise_attempts (
     time_spent_seconds INTEGER DEFAULT 0,
     started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     completed_at TIMESTAMP,
+    last_activity_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- Last autosave or submit, for idle detection
+    auto_submitted BOOLEAN DEFAULT false, -- Submitted by the server when the time limit ran out
+    auto_submit_claimed_at TIMESTAMP, -- When a sweeper claimed the auto-submit; stale claims are taken over
     
     -- Metadata
     device_type VARCHAR(20),
@@ -283,6 +289,7 @@ CREATE TABLE user_exercise_attempts (
     audio_format VARCHAR(20),
     transcript_text TEXT,
     transcript_word_count INTEGER,
+    speech_metrics JSONB, -- Fluency metrics from the transcription's word timings, kept for evaluation retries
     speaking_part_number INTEGER,
     
     -- AI Evaluation
@@ -297,11 +304,13 @@ CREATE TABLE user_exercise_attempts (
     user_service_sync_status VARCHAR(20) DEFAULT 'pending', -- 'pending', 'synced', 'failed'
     user_service_sync_attempts INTEGER DEFAULT 0,
     user_service_last_sync_attempt TIMESTAMP,
-    user_service_sync_error TEXT
+    user_service_sync_error TEXT,
+    result_recorded_at TIMESTAMP -- When the result was first sent to user-service and notified; never sent twice
 );
 
 CREATE INDEX idx_user_exercise_attempts_user_id ON user_exercise_attempts(user_id);
 CREATE INDEX idx_user_exercise_attempts_exercise_id ON user_exercise_attempts(exercise_id);
+CREATE INDEX idx_user_exercise_attempts_in_progress ON user_exercise_attempts(started_at) WHERE status = 'in_progress';
 CREATE INDEX idx_user_exercise_attempts_completed_at ON user_exercise_attempts(completed_at);
 CREATE INDEX idx_user_exercise_attempts_status ON user_exercise_attempts(status);
 CREATE INDEX idx_user_exercise_attempts_sync_status ON user_exercise_attempts(user_service_sync_status);
@@ -325,10 +334,12 @@ CREATE TABLE user_answers (
     answer_text TEXT, -- For text-based answers
     selected_option_id UUID, -- For single choice (references question_options.id)
     selected_options UUID[], -- For multiple choice
+    answer_matches JSONB, -- For matching and multi-blank questions: item label -> answer
     
     -- Grading
     is_correct BOOLEAN,
     points_earned NUMERIC(5,2) DEFAULT 0,
+    grading_details JSONB, -- Per-item results from the grader
     time_spent_seconds INTEGER,
     
     -- Metadata
@@ -346,6 +357,177 @@ CREATE INDEX idx_user_answers_attempt_id ON user_answers(attempt_id);
 CREATE INDEX idx_user_answers_question_id ON user_answers(question_id);
 CREATE INDEX idx_user_answers_user_id ON user_answers(user_id);
 
+-- ----------------------------------------------------------------------------
+-- Evaluation Jobs Table (durable queue for Writing/Speaking AI evaluation)
+-- ----------------------------------------------------------------------------
+-- One job per attempt. Workers lease jobs with FOR UPDATE SKIP LOCKED, so a job
+-- is only processed by one replica at a time. Expired leases are picked up again
+-- after a crash; jobs that exhaust max_attempts are moved to 'dead'. Once the
+-- result is saved the job stays leased as 'recording' while the attempt is
+-- recorded to user-service, so a crash at that point resumes the recording
+-- instead of losing it.
+CREATE TABLE evaluation_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    attempt_id UUID NOT NULL UNIQUE REFERENCES user_exercise_attempts(id) ON DELETE CASCADE,
+    skill_type VARCHAR(20) NOT NULL CHECK (skill_type IN ('writing', 'speaking')),
+    status VARCHAR(20) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'leased', 'recording', 'completed', 'dead')),
+    record_result BOOLEAN NOT NULL DEFAULT false, -- Set with the result: record the attempt to user-service
+
+    -- Retry tracking
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    next_run_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+
+    -- Leasing
+    lease_owner VARCHAR(100),
+    lease_expires_at TIMESTAMP,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX idx_evaluation_jobs_runnable ON evaluation_jobs(next_run_at)
+    WHERE status = 'queued' OR (status = 'recording' AND lease_owner IS NULL);
+CREATE INDEX idx_evaluation_jobs_lease_expires ON evaluation_jobs(lease_expires_at)
+    WHERE status IN ('leased', 'recording');
+CREATE INDEX idx_evaluation_jobs_dead ON evaluation_jobs(updated_at)
+    WHERE status = 'dead';
+
+-- ----------------------------------------------------------------------------
+-- Test Sessions Table (full four-skill mock tests)
+-- ----------------------------------------------------------------------------
+-- A session chains Listening, Reading, Writing and Speaking exercises, taken in
+-- order, each part with its own timer (the exercise's time limit). The overall
+-- band is computed once every part is graded, and recorded in user-service as
+-- one combined official test result.
+CREATE TABLE test_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    test_type VARCHAR(20) NOT NULL DEFAULT 'mock_test' CHECK (test_type IN ('mock_test', 'full_test')),
+    ielts_variant VARCHAR(20) NOT NULL DEFAULT 'academic' CHECK (ielts_variant IN ('academic', 'general_training')),
+    status VARCHAR(20) NOT NULL DEFAULT 'in_progress'
+        CHECK (status IN ('in_progress', 'awaiting_evaluation', 'completed', 'incomplete')), -- incomplete = finished with skipped parts
+    
+    -- Scores (set when the session is finished)
+    listening_band NUMERIC(3,1),
+    reading_band NUMERIC(3,1),
+    writing_band NUMERIC(3,1),
+    speaking_band NUMERIC(3,1),
+    overall_band NUMERIC(3,1),
+    
+    -- Timing
+    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_activity_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    
+    -- Service sync status
+    user_service_sync_status VARCHAR(20) DEFAULT 'pending', -- 'pending', 'synced', 'failed'
+    user_service_sync_attempts INTEGER DEFAULT 0,
+    user_service_sync_error TEXT,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_test_sessions_user_id ON test_sessions(user_id, created_at DESC);
+CREATE INDEX idx_test_sessions_open ON test_sessions(last_activity_at)
+    WHERE status IN ('in_progress', 'awaiting_evaluation');
+CREATE INDEX idx_test_sessions_sync ON test_sessions(user_service_sync_status)
+    WHERE status IN ('completed', 'incomplete');
+
+-- ----------------------------------------------------------------------------
+-- Test Session Parts Table
+-- ----------------------------------------------------------------------------
+-- One row per exercise of a session. A part's progress comes from its attempt.
+CREATE TABLE test_session_parts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id UUID NOT NULL REFERENCES test_sessions(id) ON DELETE CASCADE,
+    part_number INTEGER NOT NULL CHECK (part_number >= 1),
+    skill_type VARCHAR(20) NOT NULL CHECK (skill_type IN ('listening', 'reading', 'writing', 'speaking')),
+    exercise_id UUID NOT NULL REFERENCES exercises(id),
+    attempt_id UUID UNIQUE REFERENCES user_exercise_attempts(id) ON DELETE SET NULL, -- Set when the part is started
+    skipped BOOLEAN DEFAULT false, -- Never started before the session was closed
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    UNIQUE(session_id, part_number)
+);
+
+CREATE INDEX idx_test_session_parts_session_id ON test_session_parts(session_id);
+
+-- ----------------------------------------------------------------------------
+-- Review Items Table (mistake notebook)
+-- ----------------------------------------------------------------------------
+-- Questions a student answered wrongly in a completed attempt, scheduled for
+-- review with SM-2 spaced repetition. Missing the question again in a later
+-- attempt puts it back at the start of the schedule.
+CREATE TABLE review_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    question_id UUID NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+    exercise_id UUID NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    source_attempt_id UUID REFERENCES user_exercise_attempts(id) ON DELETE SET NULL, -- Attempt it was last missed in
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'mastered', 'archived')),
+    
+    -- SM-2 schedule
+    ease_factor NUMERIC(4,2) NOT NULL DEFAULT 2.5,
+    interval_days INTEGER NOT NULL DEFAULT 0,
+    repetitions INTEGER NOT NULL DEFAULT 0, -- Correct reviews in a row
+    lapses INTEGER NOT NULL DEFAULT 0,
+    due_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    
+    review_count INTEGER NOT NULL DEFAULT 0,
+    last_reviewed_at TIMESTAMP,
+    last_result BOOLEAN,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    UNIQUE(user_id, question_id)
+);
+
+CREATE INDEX idx_review_items_due ON review_items(user_id, due_at) WHERE status = 'active';
+
+-- ----------------------------------------------------------------------------
+-- Review Sessions Table
+-- ----------------------------------------------------------------------------
+-- A mixed set of due review items. Each finished session is recorded in
+-- user-service as a practice activity per skill.
+CREATE TABLE review_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'in_progress' CHECK (status IN ('in_progress', 'completed')),
+    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    
+    -- Service sync status
+    user_service_sync_status VARCHAR(20) DEFAULT 'pending', -- 'pending', 'synced', 'failed'
+    user_service_sync_attempts INTEGER DEFAULT 0,
+    user_service_sync_error TEXT,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_review_sessions_user_id ON review_sessions(user_id, created_at DESC);
+CREATE INDEX idx_review_sessions_sync ON review_sessions(user_service_sync_status)
+    WHERE status = 'completed';
+
+CREATE TABLE review_session_items (
+    session_id UUID NOT NULL REFERENCES review_sessions(id) ON DELETE CASCADE,
+    review_item_id UUID NOT NULL REFERENCES review_items(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    
+    -- Outcome, set when answered
+    is_correct BOOLEAN,
+    score NUMERIC(4,3),
+    quality INTEGER CHECK (quality BETWEEN 0 AND 5), -- SM-2 response quality
+    time_spent_seconds INTEGER,
+    answered_at TIMESTAMP,
+    
+    PRIMARY KEY (session_id, review_item_id)
+);
+
 -- ============================================================================
 -- ANALYTICS AND METADATA
 -- ============================================================================
@@ -396,6 +578,19 @@ CREATE TABLE exercise_tag_mapping (
 
 CREATE INDEX idx_exercise_tag_mapping_tag_id ON exercise_tag_mapping(tag_id);
 
+-- ----------------------------------------------------------------------------
+-- Exercise Package Media Table
+-- ----------------------------------------------------------------------------
+-- Audio uploaded to storage by package imports, with the hash of the bundled file,
+-- so that re-importing the same package is recognised as unchanged
+CREATE TABLE exercise_package_media (
+    exercise_id UUID NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    stored_url TEXT NOT NULL,
+    content_sha256 VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (exercise_id, stored_url)
+);
+
 -- ============================================================================
 -- QUESTION BANK (Reusable Questions)
 -- ============================================================================
@@ -517,6 +712,18 @@ CREATE TRIGGER update_user_answers_updated_at
     BEFORE UPDATE ON user_answers
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+CREATE TRIGGER update_evaluation_jobs_updated_at
+    BEFORE UPDATE ON evaluation_jobs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_test_sessions_updated_at
+    BEFORE UPDATE ON test_sessions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_review_items_updated_at
+    BEFORE UPDATE ON review_items
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
 -- ----------------------------------------------------------------------------
 -- Auto-grade answer function
 -- ----------------------------------------------------------------------------
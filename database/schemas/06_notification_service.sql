@@ -46,6 +46,7 @@ CREATE TABLE notifications (
     is_sent BOOLEAN DEFAULT false,
     sent_at TIMESTAMP,
     scheduled_for TIMESTAMP,
+    deferred_reason VARCHAR(20), -- 'quiet_hours', 'daily_limit' (held back by user preferences)
     expires_at TIMESTAMP,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
@@ -55,6 +56,7 @@ CREATE INDEX idx_notifications_user_id ON notifications(user_id);
 CREATE INDEX idx_notifications_is_read ON notifications(is_read);
 CREATE INDEX idx_notifications_created_at ON notifications(created_at DESC);
 CREATE INDEX idx_notifications_scheduled_for ON notifications(scheduled_for) WHERE is_sent = false;
+CREATE INDEX idx_notifications_user_sent_at ON notifications(user_id, sent_at) WHERE is_sent = true;
 
 -- ----------------------------------------------------------------------------
 -- Email Notifications Table
@@ -69,7 +71,7 @@ CREATE TABLE email_notifications (
     body_text TEXT,
     template_name VARCHAR(100), -- Reference to notification_templates
     template_data JSONB, -- Variables for template
-    status VARCHAR(20) DEFAULT 'pending', -- 'pending', 'sent', 'delivered', 'failed', 'bounced'
+    status VARCHAR(20) DEFAULT 'pending', -- 'pending', 'sending', 'sent', 'delivered', 'failed', 'bounced'
     sent_at TIMESTAMP,
     delivered_at TIMESTAMP,
     opened_at TIMESTAMP, -- Email opened tracking
@@ -77,12 +79,15 @@ CREATE TABLE email_notifications (
     external_id VARCHAR(255), -- ID from email service provider
     error_message TEXT,
     retry_count INTEGER DEFAULT 0,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- When the sender may (re)try; also the claim expiry while 'sending'
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE INDEX idx_email_notifications_user_id ON email_notifications(user_id);
 CREATE INDEX idx_email_notifications_to_email ON email_notifications(to_email);
 CREATE INDEX idx_email_notifications_status ON email_notifications(status);
+CREATE INDEX idx_email_notifications_next_attempt ON email_notifications(next_attempt_at) WHERE status IN ('pending', 'sending');
 
 -- ----------------------------------------------------------------------------
 -- Push Notifications Table
@@ -208,9 +213,9 @@ CREATE TABLE scheduled_notifications (
     user_id UUID NOT NULL,
     title VARCHAR(200) NOT NULL,
     message TEXT NOT NULL,
-    schedule_type VARCHAR(20) NOT NULL, -- 'once', 'daily', 'weekly', 'custom'
+    schedule_type VARCHAR(20) NOT NULL, -- 'once', 'daily', 'weekly', 'monthly', 'custom'
     scheduled_time TIME NOT NULL,
-    days_of_week INTEGER[], -- [0-6] where 0=Monday, 6=Sunday (for weekly)
+    days_of_week INTEGER[], -- [1-7] where 1=Monday, 7=Sunday (for weekly/custom)
     timezone VARCHAR(50) DEFAULT 'Asia/Ho_Chi_Minh',
     is_active BOOLEAN DEFAULT true,
     last_sent_at TIMESTAMP,
@@ -386,7 +391,88 @@ INSERT INTO notification_templates (template_code, name, notification_type, cate
      'Chào mừng đến với khóa học',
      'Chào mừng bạn đến với khóa học "{{course_title}}". Chúc bạn học tập hiệu quả!',
      NULL,
-     ARRAY['course_title']);
+     ARRAY['course_title'],
+
+    ('weekly_progress_report', 'Weekly Progress Report', 'email', 'info',
+     'Báo cáo học tập tuần {{week_range}}',
+     'Chào {{full_name}}, đây là kết quả học tập tuần {{week_range}} của bạn.
+Thời gian học: {{study_minutes}} phút ({{study_minutes_change}})
+Bài luyện tập đã hoàn thành: {{exercises_total}}
+Listening: {{listening_exercises}} bài, band {{listening_band}} ({{listening_trend}})
+Reading: {{reading_exercises}} bài, band {{reading_band}} ({{reading_trend}})
+Writing: {{writing_exercises}} bài, band {{writing_band}} ({{writing_trend}})
+Speaking: {{speaking_exercises}} bài, band {{speaking_band}} ({{speaking_trend}})
+Chuỗi ngày học: {{current_streak}} ngày (kỷ lục {{longest_streak}} ngày)
+Mục tiêu:
+{{goals_summary}}',
+     '📊 Báo cáo học tập tuần {{week_range}} - IELTSGo',
+     ARRAY['full_name', 'week_range', 'study_minutes', 'study_minutes_change', 'exercises_total',
+           'listening_exercises', 'listening_band', 'listening_trend',
+           'reading_exercises', 'reading_band', 'reading_trend',
+           'writing_exercises', 'writing_band', 'writing_trend',
+           'speaking_exercises', 'speaking_band', 'speaking_trend',
+           'current_streak', 'longest_streak', 'goals_summary']);
+
+-- HTML body for the weekly progress email (variables are HTML-escaped when rendered)
+UPDATE notification_templates SET html_template = '<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"></head>
+<body style="margin:0;padding:24px;background:#FFF7F5;font-family:Arial,Helvetica,sans-serif;">
+  <table role="presentation" width="100%" cellspacing="0" cellpadding="0" style="max-width:600px;margin:0 auto;background:#FFFFFF;border-radius:10px;border:1px solid #FAD8D6;">
+    <tr>
+      <td style="padding:24px;border-bottom:1px solid #FAD8D6;">
+        <div style="font-size:22px;line-height:1.2;color:#111827;font-weight:700;letter-spacing:-0.3px">
+          <span>IELTS</span><span style="color:#E53935">Go</span>
+        </div>
+      </td>
+    </tr>
+    <tr>
+      <td style="padding:24px;color:#374151;font-size:14px;line-height:1.7">
+        <h1 style="margin:0 0 12px 0;font-size:18px;color:#111827">Báo cáo học tập tuần {{week_range}}</h1>
+        <p style="margin:0 0 16px 0">Chào {{full_name}}, đây là tóm tắt quá trình học của bạn trong tuần qua.</p>
+        <table role="presentation" width="100%" cellspacing="0" cellpadding="0" style="margin-bottom:16px">
+          <tr>
+            <td align="center" style="padding:12px;background:#FFF7F5;border-radius:8px">
+              <div style="font-size:22px;font-weight:700;color:#E53935">{{study_minutes}}</div>
+              <div style="font-size:12px;color:#6B7280">phút học ({{study_minutes_change}})</div>
+            </td>
+            <td width="12"></td>
+            <td align="center" style="padding:12px;background:#FFF7F5;border-radius:8px">
+              <div style="font-size:22px;font-weight:700;color:#E53935">{{exercises_total}}</div>
+              <div style="font-size:12px;color:#6B7280">bài luyện tập</div>
+            </td>
+            <td width="12"></td>
+            <td align="center" style="padding:12px;background:#FFF7F5;border-radius:8px">
+              <div style="font-size:22px;font-weight:700;color:#E53935">{{current_streak}}</div>
+              <div style="font-size:12px;color:#6B7280">ngày liên tiếp (kỷ lục {{longest_streak}})</div>
+            </td>
+          </tr>
+        </table>
+        <table role="presentation" width="100%" cellspacing="0" cellpadding="0" style="margin-bottom:16px;font-size:13px">
+          <tr style="color:#6B7280">
+            <td style="padding:8px;border-bottom:1px solid #FAD8D6">Kỹ năng</td>
+            <td align="center" style="padding:8px;border-bottom:1px solid #FAD8D6">Số bài</td>
+            <td align="center" style="padding:8px;border-bottom:1px solid #FAD8D6">Band TB</td>
+            <td align="center" style="padding:8px;border-bottom:1px solid #FAD8D6">So với tuần trước</td>
+          </tr>
+              <tr><td style="padding:8px;border-bottom:1px solid #F3F4F6">Listening</td><td align="center" style="padding:8px;border-bottom:1px solid #F3F4F6">{{listening_exercises}}</td><td align="center" style="padding:8px;border-bottom:1px solid #F3F4F6">{{listening_band}}</td><td align="center" style="padding:8px;border-bottom:1px solid #F3F4F6">{{listening_trend}}</td></tr>
+              <tr><td style="padding:8px;border-bottom:1px solid #F3F4F6">Reading</td><td align="center" style="padding:8px;border-bottom:1px solid #F3F4F6">{{reading_exercises}}</td><td align="center" style="padding:8px;border-bottom:1px solid #F3F4F6">{{reading_band}}</td><td align="center" style="padding:8px;border-bottom:1px solid #F3F4F6">{{reading_trend}}</td></tr>
+              <tr><td style="padding:8px;border-bottom:1px solid #F3F4F6">Writing</td><td align="center" style="padding:8px;border-bottom:1px solid #F3F4F6">{{writing_exercises}}</td><td align="center" style="padding:8px;border-bottom:1px solid #F3F4F6">{{writing_band}}</td><td align="center" style="padding:8px;border-bottom:1px solid #F3F4F6">{{writing_trend}}</td></tr>
+              <tr><td style="padding:8px;border-bottom:1px solid #F3F4F6">Speaking</td><td align="center" style="padding:8px;border-bottom:1px solid #F3F4F6">{{speaking_exercises}}</td><td align="center" style="padding:8px;border-bottom:1px solid #F3F4F6">{{speaking_band}}</td><td align="center" style="padding:8px;border-bottom:1px solid #F3F4F6">{{speaking_trend}}</td></tr>
+        </table>
+        <h2 style="margin:0 0 8px 0;font-size:15px;color:#111827">Mục tiêu</h2>
+        <p style="margin:0;white-space:pre-line">{{goals_summary}}</p>
+      </td>
+    </tr>
+    <tr>
+      <td style="padding:20px 24px 24px 24px;color:#9CA3AF;font-size:12px;border-top:1px solid #FAD8D6;">
+        Bạn nhận email này vì đã bật báo cáo học tập hằng tuần. Bạn có thể tắt trong phần cài đặt thông báo.
+      </td>
+    </tr>
+  </table>
+</body>
+</html>'
+WHERE template_code = 'weekly_progress_report';
 
 -- ============================================================================
 -- SCHEMA MIGRATIONS TRACKING
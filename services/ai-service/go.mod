@@ -4,8 +4,10 @@ go 1.23
 
 toolchain go1.23.0
 
+replace github.com/bisosad1501/DATN/shared => ../../shared
+
 require (
-	github.com/bisosad1501/DATN/shared v0.0.0-20251103121442-79c5877c523c
+	github.com/bisosad1501/DATN/shared v0.0.0-00010101000000-000000000000
 	github.com/gin-gonic/gin v1.9.1
 	github.com/golang-jwt/jwt/v5 v5.3.0
 	github.com/google/uuid v1.5.0
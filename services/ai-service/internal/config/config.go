@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -24,7 +26,28 @@ type Config struct {
 	InternalAPIKey string
 
 	// OpenAI API
-	OpenAIAPIKey string
+	OpenAIAPIKey             string
+	OpenAIChatModel          string
+	OpenAITranscriptionModel string
+
+	// OpenAI-compatible server (Ollama, vLLM, ...), used as provider "openai_compatible"
+	LLMBaseURL            string
+	LLMAPIKey             string
+	LLMChatModel          string
+	LLMTranscriptionModel string
+
+	// Provider order per task, e.g. "openai,openai_compatible,heuristic"; the next one is
+	// tried when a provider fails. Empty means every configured provider except heuristic.
+	TranscriptionProviders []string
+	WritingProviders       []string
+	SpeakingProviders      []string
+
+	// Independent evaluations per submission, keyed by skill ("writing") or skill and task
+	// ("writing_task1", "speaking_part2"), from AI_EVALUATION_SAMPLES="writing=3,speaking_part2=3".
+	// Their scores are combined by median; a spread of ReviewSpreadThreshold bands or more
+	// between samples flags the result for human review.
+	EvaluationSamples     map[string]int
+	ReviewSpreadThreshold float64
 
 	// Service URLs
 	UserServiceURL        string
@@ -51,7 +74,24 @@ func LoadConfig() *Config {
 		InternalAPIKey: getEnv("INTERNAL_API_KEY", "internal_secret_key_ielts_2025_change_in_production"),
 
 		// OpenAI API
-		OpenAIAPIKey: getEnv("OPENAI_API_KEY", ""),
+		OpenAIAPIKey:             getEnv("OPENAI_API_KEY", ""),
+		OpenAIChatModel:          getEnv("OPENAI_CHAT_MODEL", "gpt-4o"),
+		OpenAITranscriptionModel: getEnv("OPENAI_TRANSCRIPTION_MODEL", "whisper-1"),
+
+		// OpenAI-compatible server
+		LLMBaseURL:            getEnv("LLM_BASE_URL", ""),
+		LLMAPIKey:             getEnv("LLM_API_KEY", ""),
+		LLMChatModel:          getEnv("LLM_CHAT_MODEL", "llama3.1"),
+		LLMTranscriptionModel: getEnv("LLM_TRANSCRIPTION_MODEL", "whisper-1"),
+
+		// AI providers
+		TranscriptionProviders: getEnvList("AI_TRANSCRIPTION_PROVIDERS"),
+		WritingProviders:       getEnvList("AI_WRITING_PROVIDERS"),
+		SpeakingProviders:      getEnvList("AI_SPEAKING_PROVIDERS"),
+
+		// Multi-sample scoring
+		EvaluationSamples:     getEnvSamples("AI_EVALUATION_SAMPLES"),
+		ReviewSpreadThreshold: getEnvFloat("AI_REVIEW_SPREAD_THRESHOLD", 1.0),
 
 		// Service URLs
 		UserServiceURL:        getEnv("USER_SERVICE_URL", "http://user-service:8082"),
@@ -59,8 +99,8 @@ func LoadConfig() *Config {
 		NotificationServiceURL: getEnv("NOTIFICATION_SERVICE_URL", "http://notification-service:8086"),
 	}
 
-	if config.OpenAIAPIKey == "" {
-		log.Printf("⚠️  WARNING: OPENAI_API_KEY not set. AI features will not work.")
+	if config.OpenAIAPIKey == "" && config.LLMBaseURL == "" {
+		log.Printf("⚠️  WARNING: neither OPENAI_API_KEY nor LLM_BASE_URL is set. Transcription will not work and evaluations use the heuristic scorer.")
 	}
 
 	log.Printf("✅ Configuration loaded successfully")
@@ -68,6 +108,9 @@ func LoadConfig() *Config {
 	log.Printf("🗄️  Database: %s@%s:%s/%s", config.DBUser, config.DBHost, config.DBPort, config.DBName)
 	log.Printf("🔐 Auth Service: %s", config.AuthServiceURL)
 	log.Printf("🤖 OpenAI API: %s", maskAPIKey(config.OpenAIAPIKey))
+	if config.LLMBaseURL != "" {
+		log.Printf("🤖 OpenAI-compatible API: %s (model %s)", config.LLMBaseURL, config.LLMChatModel)
+	}
 
 	return config
 }
@@ -80,6 +123,47 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// getEnvList reads a comma-separated list, ignoring blanks
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// MaxEvaluationSamples bounds AI_EVALUATION_SAMPLES, as every sample is a paid model call
+const MaxEvaluationSamples = 5
+
+// getEnvSamples reads "key=count" pairs, ignoring malformed entries
+func getEnvSamples(key string) map[string]int {
+	samples := make(map[string]int)
+	for _, item := range getEnvList(key) {
+		name, value, _ := strings.Cut(item, "=")
+		count, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || count < 1 {
+			log.Printf("⚠️  Ignoring invalid %s entry %q", key, item)
+			continue
+		}
+		if count > MaxEvaluationSamples {
+			log.Printf("⚠️  %s entry %q is capped at %d samples", key, item, MaxEvaluationSamples)
+			count = MaxEvaluationSamples
+		}
+		samples[strings.ToLower(strings.TrimSpace(name))] = count
+	}
+	return samples
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 func maskAPIKey(key string) string {
 	if len(key) == 0 {
 		return "not set"
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"math"
 	"net/http"
 	"strings"
 
+	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
 	"github.com/bisosad1501/DATN/services/ai-service/internal/service"
 	"github.com/gin-gonic/gin"
 )
@@ -59,7 +61,7 @@ func (h *AIHandler) TranscribeSpeaking(c *gin.Context) {
 		return
 	}
 
-	transcript, err := h.service.TranscribeSpeakingPure(req.AudioURL)
+	transcript, metrics, err := h.service.TranscribeSpeakingPure(req.AudioURL)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -68,7 +70,9 @@ func (h *AIHandler) TranscribeSpeaking(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"transcript_text": transcript,
+			"transcript_text":        transcript.Text,
+			"audio_duration_seconds": int(math.Round(transcript.Duration)),
+			"speech_metrics":         metrics,
 		},
 	})
 }
@@ -82,6 +86,8 @@ func (h *AIHandler) EvaluateSpeaking(c *gin.Context) {
 		PartNumber     int     `json:"part_number"`
 		WordCount      int     `json:"word_count"`
 		Duration       float64 `json:"duration"`
+		// Returned by /speaking/transcribe; measured from the transcript alone when missing
+		SpeechMetrics *models.SpeechMetrics `json:"speech_metrics"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -95,7 +101,7 @@ func (h *AIHandler) EvaluateSpeaking(c *gin.Context) {
 		wordCount = len(strings.Fields(req.TranscriptText))
 	}
 
-	result, err := h.service.EvaluateSpeakingPure(req.AudioURL, req.TranscriptText, req.PromptText, req.PartNumber, wordCount, req.Duration)
+	result, err := h.service.EvaluateSpeakingPure(req.AudioURL, req.TranscriptText, req.PromptText, req.PartNumber, wordCount, req.Duration, req.SpeechMetrics)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
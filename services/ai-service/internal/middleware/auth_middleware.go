@@ -62,6 +62,7 @@ func (m *AuthMiddleware) AuthRequired() gin.HandlerFunc {
 		c.Set("user_id", claims["user_id"])
 		c.Set("email", claims["email"])
 		c.Set("role", claims["role"])
+		c.Set("permissions", claimPermissions(claims))
 		c.Next()
 	}
 }
@@ -110,6 +111,7 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 					if role, ok := claims["role"].(string); ok {
 						c.Set("role", role)
 					}
+					c.Set("permissions", claimPermissions(claims))
 				}
 			}
 		}
@@ -117,3 +119,34 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 	}
 }
 
+// RequirePermission checks that the token grants at least one of the given permissions
+func (m *AuthMiddleware) RequirePermission(requiredPermissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, _ := c.Get("permissions")
+		permissions, _ := granted.([]string)
+
+		for _, required := range requiredPermissions {
+			for _, p := range permissions {
+				if p == required {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Requires one of permissions: %v", requiredPermissions)})
+		c.Abort()
+	}
+}
+
+// claimPermissions reads the permissions claim issued by auth-service
+func claimPermissions(claims jwt.MapClaims) []string {
+	raw, _ := claims["permissions"].([]interface{})
+	permissions := make([]string, 0, len(raw))
+	for _, p := range raw {
+		if name, ok := p.(string); ok {
+			permissions = append(permissions, name)
+		}
+	}
+	return permissions
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,6 +20,8 @@ type RateLimitMiddleware struct {
 	globalLimiter *limiter.Limiter
 	// Submission limiters (per user, for submissions)
 	submissionLimiters map[string]*limiter.Limiter
+	// Guards the limiter maps, which are filled on demand from concurrent requests
+	mu sync.Mutex
 }
 
 // Rate limit configurations
@@ -120,13 +123,7 @@ func (r *RateLimitMiddleware) SubmissionRateLimit() gin.HandlerFunc {
 		userIDKey := userID.String()
 
 		// Get or create submission limiter for this user (hourly limit)
-		hourlyLimiter, exists := r.submissionLimiters[userIDKey+"_hour"]
-		if !exists {
-			store := memory.NewStore()
-			hourlyLimit, _ := limiter.NewRateFromFormatted(UserSubmissionLimitPerHour)
-			hourlyLimiter = limiter.New(store, hourlyLimit)
-			r.submissionLimiters[userIDKey+"_hour"] = hourlyLimiter
-		}
+		hourlyLimiter := r.submissionLimiter(userIDKey+"_hour", UserSubmissionLimitPerHour)
 
 		// Check hourly limit
 		hourlyContext, err := hourlyLimiter.Get(c, userIDKey)
@@ -137,13 +134,7 @@ func (r *RateLimitMiddleware) SubmissionRateLimit() gin.HandlerFunc {
 		}
 
 		// Check daily limit
-		dailyLimiter, exists := r.submissionLimiters[userIDKey+"_day"]
-		if !exists {
-			store := memory.NewStore()
-			dailyLimit, _ := limiter.NewRateFromFormatted(UserSubmissionLimitPerDay)
-			dailyLimiter = limiter.New(store, dailyLimit)
-			r.submissionLimiters[userIDKey+"_day"] = dailyLimiter
-		}
+		dailyLimiter := r.submissionLimiter(userIDKey+"_day", UserSubmissionLimitPerDay)
 
 		dailyContext, err := dailyLimiter.Get(c, userIDKey)
 		if err != nil {
@@ -185,3 +176,16 @@ func (r *RateLimitMiddleware) SubmissionRateLimit() gin.HandlerFunc {
 	}
 }
 
+// submissionLimiter returns the limiter for key, creating it on first use
+func (r *RateLimitMiddleware) submissionLimiter(key, rate string) *limiter.Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if l, exists := r.submissionLimiters[key]; exists {
+		return l
+	}
+	parsed, _ := limiter.NewRateFromFormatted(rate)
+	l := limiter.New(memory.NewStore(), parsed)
+	r.submissionLimiters[key] = l
+	return l
+}
@@ -1,5 +1,7 @@
 package models
 
+import "github.com/bisosad1501/DATN/shared/pkg/textanalysis"
+
 // FeedbackBilingual contains feedback in both Vietnamese and English
 type FeedbackBilingual struct {
 	VI string `json:"vi"`
@@ -21,9 +23,12 @@ type OpenAIWritingEvaluation struct {
 		LexicalResource   FeedbackBilingual `json:"lexical_resource"`
 		GrammaticalRange  FeedbackBilingual `json:"grammatical_range"`
 	} `json:"detailed_feedback"`
-	ExaminerFeedback    string   `json:"examiner_feedback"`
-	Strengths           []string `json:"strengths"`
-	AreasForImprovement []string `json:"areas_for_improvement"`
+	ExaminerFeedback    string                 `json:"examiner_feedback"`
+	Strengths           []string               `json:"strengths"`
+	AreasForImprovement []string               `json:"areas_for_improvement"`
+	Confidence          *ScoreConfidence       `json:"confidence,omitempty"`    // Only for multi-sample scoring
+	TextAnalysis        *textanalysis.Analysis `json:"text_analysis,omitempty"` // Measured from the essay, not by the evaluator
+	Provider            string                 `json:"provider,omitempty"`      // Evaluator that scored it; "heuristic" is an estimate
 }
 
 // OpenAI Evaluation Response (Speaking)
@@ -53,18 +58,69 @@ type OpenAISpeakingEvaluation struct {
 			Analysis string  `json:"analysis"`
 		} `json:"pronunciation"`
 	} `json:"detailed_feedback"`
-	ExaminerFeedback    string   `json:"examiner_feedback"`
-	Strengths           []string `json:"strengths"`
-	AreasForImprovement []string `json:"areas_for_improvement"`
+	ExaminerFeedback    string           `json:"examiner_feedback"`
+	Strengths           []string         `json:"strengths"`
+	AreasForImprovement []string         `json:"areas_for_improvement"`
+	Confidence          *ScoreConfidence `json:"confidence,omitempty"`     // Only for multi-sample scoring
+	SpeechMetrics       *SpeechMetrics   `json:"speech_metrics,omitempty"` // Measured, not judged by the model
+	Provider            string           `json:"provider,omitempty"`       // Evaluator that scored it; "heuristic" is an estimate
+}
+
+// ScoreConfidence describes how far independent evaluations of the same answer agreed
+type ScoreConfidence struct {
+	Samples        int                `json:"samples"`
+	Agreement      float64            `json:"agreement"`       // Share of samples within half a band of the overall band (0-1)
+	OverallSpread  float64            `json:"overall_spread"`  // Highest minus lowest overall band among samples
+	CriteriaSpread map[string]float64 `json:"criteria_spread"` // Same, per criterion
+	NeedsReview    bool               `json:"needs_review"`    // Samples disagree enough for a teacher to check
 }
 
 // OpenAI Transcription Response
 type OpenAITranscription struct {
-	Text     string  `json:"text"`
-	Duration float64 `json:"duration"`
-	Words    []struct {
-		Word  string  `json:"word"`
-		Start float64 `json:"start"`
-		End   float64 `json:"end"`
-	} `json:"words"`
+	Text     string              `json:"text"`
+	Duration float64             `json:"duration"`
+	Words    []TranscriptWord    `json:"words"`
+	Segments []TranscriptSegment `json:"segments"`
+}
+
+// TranscriptWord is a recognised word with its start and end time in seconds
+type TranscriptWord struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// TranscriptSegment is a stretch of speech recognised together, with the recogniser's
+// average log probability for its tokens
+type TranscriptSegment struct {
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	AvgLogprob float64 `json:"avg_logprob"`
+}
+
+// SpeechMetrics are fluency measures computed from a transcript and its word timings. The
+// pause figures and articulation rate need word timings and are zero without them.
+type SpeechMetrics struct {
+	WordCount          int               `json:"word_count"`
+	DurationSeconds    float64           `json:"duration_seconds"`
+	SpeechRate         float64           `json:"speech_rate"`       // Words per minute over the whole answer
+	ArticulationRate   float64           `json:"articulation_rate"` // Words per minute of speaking, pauses excluded
+	PauseCount         int               `json:"pause_count"`       // Silences of 0.25 s or more between words
+	Pauses             PauseDistribution `json:"pauses"`
+	LongPauseRatio     float64           `json:"long_pause_ratio"` // Share of the answer spent in pauses of 1 s or more
+	FillerCount        int               `json:"filler_count"`     // um, uh, er, filler "like", ...
+	FillersPer100Words float64           `json:"fillers_per_100_words"`
+	SelfRepetitions    int               `json:"self_repetitions"` // Immediately repeated words or word pairs
+	HasWordTimings     bool              `json:"has_word_timings"`
+	// Pronunciation proxy: how sure the recogniser was of what it heard (0-1), when known
+	RecognitionConfidence *float64 `json:"recognition_confidence,omitempty"`
+}
+
+// PauseDistribution groups the pauses between words by length
+type PauseDistribution struct {
+	Short          int     `json:"short"`  // 0.25-0.5 s
+	Medium         int     `json:"medium"` // 0.5-1 s
+	Long           int     `json:"long"`   // 1 s or more
+	MeanSeconds    float64 `json:"mean_seconds"`
+	LongestSeconds float64 `json:"longest_seconds"`
 }
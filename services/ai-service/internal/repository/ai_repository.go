@@ -39,7 +39,8 @@ func (r *AIRepository) GetCachedEvaluation(hash string) (*CacheEntry, error) {
                     'detailed_feedback', feedback,
                     'examiner_feedback', feedback->>'examiner_feedback',
                     'strengths', feedback->'strengths',
-                    'areas_for_improvement', feedback->'areas_for_improvement'
+                    'areas_for_improvement', feedback->'areas_for_improvement',
+                    'confidence', feedback->'confidence'
                 )::text,
                 '{}'
             ) as content,
@@ -26,10 +26,10 @@ func SetupRoutes(handler *handlers.AIHandler, authMiddleware *middleware.AuthMid
 			internal.POST("/speaking/evaluate", handler.EvaluateSpeaking)
 		}
 
-		// Admin endpoints (protected + role check)
+		// Admin endpoints (protected + permission check)
 		admin := v1.Group("/admin/ai")
 		admin.Use(authMiddleware.AuthRequired())
-		admin.Use(authMiddleware.RequireRole("admin"))
+		admin.Use(authMiddleware.RequirePermission("ai:manage"))
 		{
 			// Cache management
 			admin.GET("/cache/stats", handler.GetCacheStatistics)
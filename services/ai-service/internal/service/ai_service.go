@@ -12,12 +12,15 @@ import (
 	"github.com/bisosad1501/DATN/services/ai-service/internal/config"
 	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
 	"github.com/bisosad1501/DATN/services/ai-service/internal/repository"
+	"github.com/bisosad1501/DATN/services/ai-service/internal/validation"
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+	"github.com/bisosad1501/DATN/shared/pkg/textanalysis"
 )
 
 type AIService struct {
 	repo         *repository.AIRepository
 	config       *config.Config
-	openAIClient *OpenAIClient
+	providers    *Providers
 	cacheService *CacheService
 }
 
@@ -25,7 +28,7 @@ func NewAIService(repo *repository.AIRepository, cfg *config.Config) *AIService
 	return &AIService{
 		repo:         repo,
 		config:       cfg,
-		openAIClient: NewOpenAIClient(cfg.OpenAIAPIKey),
+		providers:    NewProviders(cfg),
 		cacheService: NewCacheService(repo),
 	}
 }
@@ -39,30 +42,52 @@ func (s *AIService) EvaluateWritingPure(essayText, taskType, promptText string)
 	if essayText == "" {
 		return nil, fmt.Errorf("essay text is required")
 	}
+	if taskType == "" {
+		taskType = "task2"
+	}
+	if err := validation.ValidateWritingSubmission(taskType, essayText); err != nil {
+		return nil, err
+	}
+
+	analysis := textanalysis.Analyze(essayText)
 
 	// Check cache first
 	if cached, hit := s.cacheService.CheckWritingCache(essayText, taskType, promptText); hit {
+		cached.TextAnalysis = analysis
 		return cached, nil
 	}
 
-	wordCount := len(strings.Fields(essayText))
-
-	// Call OpenAI for evaluation (cache miss)
-	evalResult, err := s.openAIClient.EvaluateWriting(promptText, essayText, wordCount, 0)
+	// Evaluate with the first provider that answers (cache miss), as many times as configured
+	samples, provider, err := s.providers.Writing.SampleWriting(WritingInput{
+		TaskType:   taskType,
+		PromptText: promptText,
+		EssayText:  essayText,
+		WordCount:  analysis.WordCount,
+		Analysis:   analysis,
+	}, sampleCount(s.config, "writing", taskType))
 	if err != nil {
 		return nil, fmt.Errorf("evaluation failed: %w", err)
 	}
-
-	// Save to cache (async, don't block on cache errors)
-	go s.cacheService.SaveWritingCache(essayText, taskType, promptText, evalResult)
+	evalResult := writingConsensus(samples, s.config.ReviewSpreadThreshold)
+	applyLengthPenalty(evalResult, taskType, analysis.WordCount)
+	evalResult.TextAnalysis = analysis
+	evalResult.Provider = provider
+	log.Printf("✅ [AI Service] Writing evaluated by %s: %.1f band (%d words)", provider, evalResult.OverallBand, analysis.WordCount)
+
+	// Save to cache (async, don't block on cache errors). Heuristic estimates are not cached,
+	// so the essay is evaluated properly once a model is reachable again.
+	if provider != ProviderHeuristic {
+		go s.cacheService.SaveWritingCache(essayText, taskType, promptText, evalResult)
+	}
 
 	return evalResult, nil
 }
 
-// TranscribeSpeakingPure transcribes audio without database operations (stateless)
-func (s *AIService) TranscribeSpeakingPure(audioURL string) (string, error) {
+// TranscribeSpeakingPure transcribes audio without database operations (stateless). Besides
+// the transcript it returns speech metrics measured from the word timings.
+func (s *AIService) TranscribeSpeakingPure(audioURL string) (*models.OpenAITranscription, *models.SpeechMetrics, error) {
 	if audioURL == "" {
-		return "", fmt.Errorf("audio URL is required")
+		return nil, nil, fmt.Errorf("audio URL is required")
 	}
 
 	log.Printf("🎤 [AI Service] Transcribing audio from URL: %s", audioURL)
@@ -71,47 +96,58 @@ func (s *AIService) TranscribeSpeakingPure(audioURL string) (string, error) {
 	audioData, err := downloadAudio(audioURL)
 	if err != nil {
 		log.Printf("❌ [AI Service] Failed to download audio from URL %s: %v", audioURL, err)
-		return "", fmt.Errorf("failed to download audio: %w", err)
+		return nil, nil, fmt.Errorf("failed to download audio: %w", err)
 	}
 
 	log.Printf("✅ [AI Service] Downloaded audio: %d bytes", len(audioData))
 
-	// Transcribe with OpenAI Whisper
-	log.Printf("🎤 [AI Service] Calling OpenAI Whisper API to transcribe audio...")
-	transcript, err := s.openAIClient.TranscribeAudio("audio.mp3", audioData)
+	// Transcribe with the first provider that answers
+	transcript, provider, err := s.providers.Transcription.TranscribeAudio("audio.mp3", audioData)
 	if err != nil {
 		log.Printf("❌ [AI Service] Transcription failed: %v", err)
-		return "", fmt.Errorf("transcription failed: %w", err)
+		return nil, nil, fmt.Errorf("transcription failed: %w", err)
 	}
 
 	if transcript == nil || transcript.Text == "" {
 		log.Printf("⚠️ [AI Service] Transcription returned empty result")
-		return "", fmt.Errorf("transcription returned empty result")
+		return nil, nil, fmt.Errorf("transcription returned empty result")
 	}
 
-	log.Printf("✅ [AI Service] Transcription successful. Transcript length: %d characters", len(transcript.Text))
+	log.Printf("✅ [AI Service] Transcription by %s successful. Transcript length: %d characters", provider, len(transcript.Text))
 	if len(transcript.Text) > 200 {
 		log.Printf("📝 [AI Service] Transcript preview: %s...", transcript.Text[:200])
 	} else {
 		log.Printf("📝 [AI Service] Full transcript: %s", transcript.Text)
 	}
 
-	return transcript.Text, nil
+	metrics := AnalyzeSpeech(transcript.Text, transcript.Words, transcript.Segments, transcript.Duration)
+	log.Printf("📊 [AI Service] Speech metrics: %.0f wpm, %d pauses (%d long), %d fillers, %d repetitions",
+		metrics.SpeechRate, metrics.PauseCount, metrics.Pauses.Long, metrics.FillerCount, metrics.SelfRepetitions)
+
+	return transcript, metrics, nil
 }
 
-// EvaluateSpeakingPure evaluates speaking without database operations (stateless with cache)
-func (s *AIService) EvaluateSpeakingPure(audioURL, transcriptText, promptText string, partNumber int, wordCount int, duration float64) (*models.OpenAISpeakingEvaluation, error) {
+// EvaluateSpeakingPure evaluates speaking without database operations (stateless with cache).
+// metrics are the speech metrics returned with the transcript; when missing they are
+// measured from the transcript and duration alone.
+func (s *AIService) EvaluateSpeakingPure(audioURL, transcriptText, promptText string, partNumber int, wordCount int, duration float64, metrics *models.SpeechMetrics) (*models.OpenAISpeakingEvaluation, error) {
 	if audioURL == "" {
 		return nil, fmt.Errorf("audio URL is required")
 	}
 
 	// If transcript not provided, transcribe first
 	if transcriptText == "" {
-		var err error
-		transcriptText, err = s.TranscribeSpeakingPure(audioURL)
+		transcript, transcriptMetrics, err := s.TranscribeSpeakingPure(audioURL)
 		if err != nil {
 			return nil, fmt.Errorf("transcription failed: %w", err)
 		}
+		transcriptText, metrics = transcript.Text, transcriptMetrics
+		if duration == 0 {
+			duration = transcript.Duration
+		}
+	}
+	if metrics == nil {
+		metrics = AnalyzeSpeech(transcriptText, nil, nil, duration)
 	}
 
 	// Calculate word count if not provided
@@ -124,21 +160,34 @@ func (s *AIService) EvaluateSpeakingPure(audioURL, transcriptText, promptText st
 
 	// Check cache first
 	if cached, hit := s.cacheService.CheckSpeakingCache(audioURL, transcriptText, partNumber); hit {
+		cached.SpeechMetrics = metrics
 		return cached, nil
 	}
 
-	// Evaluate speaking with OpenAI (cache miss)
-	// Correct parameter order: part, promptText, transcriptText, wordCount, duration
-	evalResult, err := s.openAIClient.EvaluateSpeaking(partStr, promptText, transcriptText, wordCount, duration)
+	// Evaluate with the first provider that answers (cache miss), as many times as configured
+	samples, provider, err := s.providers.Speaking.SampleSpeaking(SpeakingInput{
+		Part:            partStr,
+		PromptText:      promptText,
+		Transcript:      transcriptText,
+		WordCount:       wordCount,
+		DurationSeconds: duration,
+		Metrics:         metrics,
+	}, sampleCount(s.config, "speaking", partStr))
 	if err != nil {
 		return nil, fmt.Errorf("evaluation failed: %w", err)
 	}
+	evalResult := speakingConsensus(samples, s.config.ReviewSpreadThreshold)
+	log.Printf("✅ [AI Service] Speaking evaluated by %s", provider)
 
 	// Post-processing: Validate and adjust scores if necessary
 	evalResult = s.validateAndAdjustSpeakingScores(evalResult, transcriptText, wordCount)
+	evalResult.SpeechMetrics = metrics
+	evalResult.Provider = provider
 
-	// Save to cache (async, don't block on cache errors)
-	go s.cacheService.SaveSpeakingCache(audioURL, transcriptText, partNumber, evalResult)
+	// Save to cache (async, don't block on cache errors); heuristic estimates are not cached
+	if provider != ProviderHeuristic {
+		go s.cacheService.SaveSpeakingCache(audioURL, transcriptText, partNumber, evalResult)
+	}
 
 	return evalResult, nil
 }
@@ -172,10 +221,8 @@ func (s *AIService) validateAndAdjustSpeakingScores(result *models.OpenAISpeakin
 	result.CriteriaScores.GrammaticalRange = grammarScore
 	result.CriteriaScores.Pronunciation = pronunciationScore
 
-	// Calculate overall band from criteria average (round to nearest 0.5)
-	avgScore := (fluencyScore + lexicalScore + grammarScore + pronunciationScore) / 4.0
-	roundedBand := math.Round(avgScore*2) / 2.0
-	result.OverallBand = roundedBand
+	// Calculate overall band from criteria average (IELTS rounding)
+	result.OverallBand = ielts.CalculateSpeakingBand(fluencyScore, lexicalScore, grammarScore, pronunciationScore)
 
 	// Log warning if all scores are 0.0 but transcript has meaningful content
 	allZero := fluencyScore == 0.0 && lexicalScore == 0.0 && grammarScore == 0.0 && pronunciationScore == 0.0
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"log"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/bisosad1501/DATN/services/ai-service/internal/config"
+	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+)
+
+var (
+	writingCriteriaNames  = []string{"task_achievement", "coherence_cohesion", "lexical_resource", "grammatical_range"}
+	speakingCriteriaNames = []string{"fluency_coherence", "lexical_resource", "grammatical_range", "pronunciation"}
+)
+
+// sampleCount returns how many independent evaluations to run for a skill and task type
+// ("task2", "part1", ...). A setting for the task wins over one for the whole skill.
+func sampleCount(cfg *config.Config, skill, task string) int {
+	if n, ok := cfg.EvaluationSamples[skill+"_"+task]; ok {
+		return n
+	}
+	if n, ok := cfg.EvaluationSamples[skill]; ok {
+		return n
+	}
+	return 1
+}
+
+// SampleWriting evaluates the essay up to n times with the first provider that answers.
+// Extra samples that fail are dropped, so fewer than n may come back.
+func (chain WritingChain) SampleWriting(in WritingInput, n int) ([]*models.OpenAIWritingEvaluation, string, error) {
+	first, provider, err := chain.EvaluateWriting(in)
+	if err != nil {
+		return nil, "", err
+	}
+	p := findProvider(chain, provider)
+	return collectSamples(first, n, provider, func() (*models.OpenAIWritingEvaluation, error) {
+		return p.EvaluateWriting(in)
+	}), provider, nil
+}
+
+// SampleSpeaking evaluates the answer up to n times with the first provider that answers.
+// Extra samples that fail are dropped, so fewer than n may come back.
+func (chain SpeakingChain) SampleSpeaking(in SpeakingInput, n int) ([]*models.OpenAISpeakingEvaluation, string, error) {
+	first, provider, err := chain.EvaluateSpeaking(in)
+	if err != nil {
+		return nil, "", err
+	}
+	p := findProvider(chain, provider)
+	return collectSamples(first, n, provider, func() (*models.OpenAISpeakingEvaluation, error) {
+		return p.EvaluateSpeaking(in)
+	}), provider, nil
+}
+
+func findProvider[P interface{ Name() string }](chain []P, name string) P {
+	for _, p := range chain {
+		if p.Name() == name {
+			return p
+		}
+	}
+	var none P
+	return none
+}
+
+// collectSamples runs the remaining n-1 evaluations concurrently. The heuristic provider is
+// deterministic, so asking it again would only repeat the first sample.
+func collectSamples[T any](first *T, n int, provider string, evaluate func() (*T, error)) []*T {
+	samples := []*T{first}
+	if n <= 1 || provider == ProviderHeuristic {
+		return samples
+	}
+
+	extra := make([]*T, n-1)
+	var wg sync.WaitGroup
+	for i := range extra {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			eval, err := evaluate()
+			if err != nil {
+				log.Printf("⚠️ [AI Service] %s evaluation sample failed, continuing without it: %v", provider, err)
+				return
+			}
+			extra[i] = eval
+		}(i)
+	}
+	wg.Wait()
+
+	for _, eval := range extra {
+		if eval != nil {
+			samples = append(samples, eval)
+		}
+	}
+	return samples
+}
+
+// bandSample is the overall band and criteria bands of one evaluation, criteria in a fixed order
+type bandSample struct {
+	overall  float64
+	criteria []float64
+}
+
+// consensus takes the median of each criterion over the samples, rounded to a half band.
+// It also returns the sample closest to those medians, whose feedback is kept, and how far
+// the samples agreed. overallBand computes the overall band from criteria bands.
+func consensus(names []string, samples []bandSample, overallBand func([]float64) float64, reviewSpread float64) ([]float64, int, *models.ScoreConfidence) {
+	medians := make([]float64, len(names))
+	confidence := &models.ScoreConfidence{
+		Samples:        len(samples),
+		CriteriaSpread: make(map[string]float64, len(names)),
+	}
+	for c, name := range names {
+		values := make([]float64, len(samples))
+		for i, s := range samples {
+			values[i] = s.criteria[c]
+		}
+		medians[c] = ielts.RoundToIELTSBand(median(values))
+		confidence.CriteriaSpread[name] = spread(values)
+	}
+
+	overall := overallBand(medians)
+	overalls := make([]float64, len(samples))
+	agreeing := 0
+	for i, s := range samples {
+		overalls[i] = s.overall
+		if math.Abs(s.overall-overall) <= 0.5 {
+			agreeing++
+		}
+	}
+	confidence.Agreement = math.Round(float64(agreeing)/float64(len(samples))*100) / 100
+	confidence.OverallSpread = spread(overalls)
+	confidence.NeedsReview = confidence.OverallSpread >= reviewSpread
+
+	closest, closestDistance := 0, math.Inf(1)
+	for i, s := range samples {
+		distance := 0.0
+		for c := range names {
+			distance += math.Abs(s.criteria[c] - medians[c])
+		}
+		if distance < closestDistance {
+			closest, closestDistance = i, distance
+		}
+	}
+	return medians, closest, confidence
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func spread(values []float64) float64 {
+	lowest, highest := values[0], values[0]
+	for _, v := range values[1:] {
+		lowest, highest = math.Min(lowest, v), math.Max(highest, v)
+	}
+	return highest - lowest
+}
+
+// writingConsensus combines writing samples into one evaluation with a confidence attached.
+// A single sample is returned as it is.
+func writingConsensus(samples []*models.OpenAIWritingEvaluation, reviewSpread float64) *models.OpenAIWritingEvaluation {
+	if len(samples) == 1 {
+		return samples[0]
+	}
+
+	bands := make([]bandSample, len(samples))
+	for i, e := range samples {
+		c := e.CriteriaScores
+		bands[i] = bandSample{e.OverallBand, []float64{c.TaskAchievement, c.CoherenceCohesion, c.LexicalResource, c.GrammaticalRange}}
+	}
+	medians, closest, confidence := consensus(writingCriteriaNames, bands, func(c []float64) float64 {
+		return ielts.CalculateWritingBand(c[0], c[1], c[2], c[3])
+	}, reviewSpread)
+
+	result := *samples[closest]
+	c := &result.CriteriaScores
+	c.TaskAchievement, c.CoherenceCohesion, c.LexicalResource, c.GrammaticalRange = medians[0], medians[1], medians[2], medians[3]
+	result.OverallBand = ielts.CalculateWritingBand(medians[0], medians[1], medians[2], medians[3])
+	result.Confidence = confidence
+
+	logConsensus("Writing", result.OverallBand, confidence)
+	return &result
+}
+
+// speakingConsensus combines speaking samples into one evaluation with a confidence attached.
+// A single sample is returned as it is.
+func speakingConsensus(samples []*models.OpenAISpeakingEvaluation, reviewSpread float64) *models.OpenAISpeakingEvaluation {
+	if len(samples) == 1 {
+		return samples[0]
+	}
+
+	bands := make([]bandSample, len(samples))
+	for i, e := range samples {
+		c := e.CriteriaScores
+		bands[i] = bandSample{e.OverallBand, []float64{c.FluencyCoherence, c.LexicalResource, c.GrammaticalRange, c.Pronunciation}}
+	}
+	medians, closest, confidence := consensus(speakingCriteriaNames, bands, func(c []float64) float64 {
+		return ielts.CalculateSpeakingBand(c[0], c[1], c[2], c[3])
+	}, reviewSpread)
+
+	result := *samples[closest]
+	c := &result.CriteriaScores
+	c.FluencyCoherence, c.LexicalResource, c.GrammaticalRange, c.Pronunciation = medians[0], medians[1], medians[2], medians[3]
+	d := &result.DetailedFeedback
+	d.FluencyCoherence.Score, d.LexicalResource.Score = medians[0], medians[1]
+	d.GrammaticalRange.Score, d.Pronunciation.Score = medians[2], medians[3]
+	result.OverallBand = ielts.CalculateSpeakingBand(medians[0], medians[1], medians[2], medians[3])
+	result.Confidence = confidence
+
+	logConsensus("Speaking", result.OverallBand, confidence)
+	return &result
+}
+
+func logConsensus(skill string, overall float64, confidence *models.ScoreConfidence) {
+	log.Printf("📊 [AI Service] %s consensus of %d samples: %.1f band, agreement %.2f, spread %.1f",
+		skill, confidence.Samples, overall, confidence.Agreement, confidence.OverallSpread)
+	if confidence.NeedsReview {
+		log.Printf("🚩 [AI Service] %s samples differ by %.1f bands, flagged for human review", skill, confidence.OverallSpread)
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/bisosad1501/DATN/services/ai-service/internal/config"
+	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
+)
+
+func writingSample(overall, ta, cc, lr, gr float64, feedback string) *models.OpenAIWritingEvaluation {
+	e := &models.OpenAIWritingEvaluation{OverallBand: overall, ExaminerFeedback: feedback}
+	e.CriteriaScores.TaskAchievement, e.CriteriaScores.CoherenceCohesion = ta, cc
+	e.CriteriaScores.LexicalResource, e.CriteriaScores.GrammaticalRange = lr, gr
+	return e
+}
+
+func TestWritingConsensus(t *testing.T) {
+	samples := []*models.OpenAIWritingEvaluation{
+		writingSample(6.5, 6.5, 6.5, 6.0, 6.5, "first"),
+		writingSample(7.5, 7.5, 7.5, 7.0, 7.5, "outlier"),
+		writingSample(6.5, 6.5, 6.0, 6.5, 6.0, "third"),
+	}
+	result := writingConsensus(samples, 1.0)
+
+	c := result.CriteriaScores
+	if c.TaskAchievement != 6.5 || c.CoherenceCohesion != 6.5 || c.LexicalResource != 6.5 || c.GrammaticalRange != 6.5 {
+		t.Errorf("median criteria = %+v", c)
+	}
+	if result.OverallBand != 6.5 || result.ExaminerFeedback != "first" {
+		t.Errorf("overall %.1f with feedback from %q", result.OverallBand, result.ExaminerFeedback)
+	}
+
+	conf := result.Confidence
+	if conf == nil || conf.Samples != 3 || conf.Agreement != 0.67 || conf.OverallSpread != 1.0 || !conf.NeedsReview {
+		t.Fatalf("confidence = %+v", conf)
+	}
+	if conf.CriteriaSpread["task_achievement"] != 1.0 || conf.CriteriaSpread["grammatical_range"] != 1.5 {
+		t.Errorf("criteria spread = %v", conf.CriteriaSpread)
+	}
+	if samples[0].Confidence != nil || samples[0].CriteriaScores.LexicalResource != 6.0 {
+		t.Error("consensus modified a sample")
+	}
+
+	if single := writingConsensus(samples[:1], 1.0); single != samples[0] || single.Confidence != nil {
+		t.Error("a single sample should be returned unchanged")
+	}
+}
+
+func TestSampleWriting(t *testing.T) {
+	cfg := &config.Config{EvaluationSamples: map[string]int{"writing": 3, "writing_task1": 1}}
+	if n := sampleCount(cfg, "writing", "task2"); n != 3 {
+		t.Errorf("task2 samples = %d", n)
+	}
+	if n := sampleCount(cfg, "writing", "task1"); n != 1 {
+		t.Errorf("task1 samples = %d", n)
+	}
+	if n := sampleCount(cfg, "speaking", "part1"); n != 1 {
+		t.Errorf("speaking samples = %d", n)
+	}
+
+	// The heuristic evaluator always gives the same answer, so it is only asked once
+	failing := &failingEvaluator{}
+	samples, provider, err := WritingChain{failing, NewHeuristicEvaluator()}.SampleWriting(WritingInput{EssayText: "Short essay text."}, 3)
+	if err != nil || provider != ProviderHeuristic || len(samples) != 1 || failing.calls != 1 {
+		t.Errorf("got %d samples from %q, err %v, failing calls %d", len(samples), provider, err, failing.calls)
+	}
+}
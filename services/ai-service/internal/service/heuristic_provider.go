@@ -0,0 +1,323 @@
+package service
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+	"github.com/bisosad1501/DATN/shared/pkg/textanalysis"
+)
+
+// Heuristic scores stay below the top bands; counting features cannot justify an 8 or 9
+const heuristicMaxBand = 7.5
+
+var (
+	subordinators    = toSet("because", "although", "though", "which", "who", "whom", "whose", "whereas", "while", "unless", "since", "if", "when", "whether", "that")
+	fillerWords      = toSet("um", "uh", "er", "erm", "ah", "hmm", "mm")
+	promptStopwords  = toSet("about", "above", "their", "there", "these", "those", "which", "would", "should", "could", "other", "write", "words", "least", "agree", "disagree", "extent", "opinion", "discuss", "views", "reasons", "include", "relevant", "examples", "knowledge", "experience", "summarise", "information", "selecting", "reporting", "features", "comparisons", "where")
+	speakingTargetWC = map[string]int{"part1": 40, "part2": 200, "part3": 100}
+)
+
+// HeuristicEvaluator estimates writing and speaking bands from countable features of the
+// text: length, vocabulary range, sentence complexity and linking. It needs no network and
+// always gives the same result for the same input, for development, tests and as the last
+// provider when no model is reachable. It cannot transcribe audio.
+type HeuristicEvaluator struct{}
+
+func NewHeuristicEvaluator() *HeuristicEvaluator {
+	return &HeuristicEvaluator{}
+}
+
+// Name identifies the provider in logs and configuration
+func (h *HeuristicEvaluator) Name() string {
+	return ProviderHeuristic
+}
+
+// textFeatures are the counts the heuristic scores are built from
+type textFeatures struct {
+	wordCount      int      // As IELTS counts words, numbers included
+	words          []string // Lower case, numbers left out
+	sentences      int
+	paragraphs     int
+	distinct       int
+	longWords      int // 7+ letters
+	subordinators  int
+	linkingDevices int
+	fillers        int
+}
+
+// analyzeText takes length, sentences, paragraphs and linking from the shared text
+// analysis, measuring the text when a is nil
+func analyzeText(text string, a *textanalysis.Analysis) textFeatures {
+	if a == nil {
+		a = textanalysis.Analyze(text)
+	}
+	f := textFeatures{
+		wordCount:      a.WordCount,
+		words:          textanalysis.Words(text),
+		sentences:      a.SentenceCount,
+		paragraphs:     a.ParagraphCount,
+		linkingDevices: a.LinkingDeviceCount,
+	}
+
+	seen := make(map[string]bool)
+	for _, w := range f.words {
+		if !seen[w] {
+			seen[w] = true
+			f.distinct++
+		}
+		if len(w) >= 7 {
+			f.longWords++
+		}
+		if subordinators[w] {
+			f.subordinators++
+		}
+		if fillerWords[w] {
+			f.fillers++
+		}
+	}
+	return f
+}
+
+// guiraud is vocabulary range corrected for length (distinct words / √words)
+func (f textFeatures) guiraud() float64 {
+	if len(f.words) == 0 {
+		return 0
+	}
+	return float64(f.distinct) / math.Sqrt(float64(len(f.words)))
+}
+
+func (f textFeatures) per100Words(count int) float64 {
+	if len(f.words) == 0 {
+		return 0
+	}
+	return float64(count) * 100 / float64(len(f.words))
+}
+
+// EvaluateWriting estimates the four Writing criteria
+func (h *HeuristicEvaluator) EvaluateWriting(in WritingInput) (*models.OpenAIWritingEvaluation, error) {
+	f := analyzeText(in.EssayText, in.Analysis)
+	wordCount := f.wordCount
+	minWords := ielts.MinimumWritingWords(in.TaskType)
+
+	eval := &models.OpenAIWritingEvaluation{}
+	if wordCount < 20 {
+		c := &eval.CriteriaScores
+		c.TaskAchievement, c.CoherenceCohesion, c.LexicalResource, c.GrammaticalRange = 1, 1, 1, 1
+	} else {
+		// Task: length against the minimum, and whether the prompt's key words are used
+		task := interpolate(float64(wordCount)/float64(minWords), []float64{0.4, 0.8, 1.0, 1.3}, []float64{3, 4.5, 5.5, 6.5})
+		if overlap, ok := promptOverlap(in.PromptText, f); ok {
+			task += interpolate(overlap, []float64{0.1, 0.3, 0.6}, []float64{-1.5, 0, 0.5})
+		}
+
+		// Coherence: paragraphing and linking, overused linkers read as mechanical
+		coherence := interpolate(float64(f.paragraphs), []float64{1, 2, 3, 4}, []float64{4, 5, 6, 6.5})
+		coherence += interpolate(f.per100Words(f.linkingDevices), []float64{0, 1, 4, 8}, []float64{-0.5, 0.5, 0.5, -0.5})
+
+		eval.CriteriaScores.TaskAchievement = heuristicBand(task)
+		eval.CriteriaScores.CoherenceCohesion = heuristicBand(coherence)
+		eval.CriteriaScores.LexicalResource = heuristicBand(lexicalScore(f))
+		eval.CriteriaScores.GrammaticalRange = heuristicBand(grammarScore(f))
+	}
+
+	c := eval.CriteriaScores
+	eval.OverallBand = ielts.CalculateWritingBand(c.TaskAchievement, c.CoherenceCohesion, c.LexicalResource, c.GrammaticalRange)
+
+	d := &eval.DetailedFeedback
+	d.TaskAchievement = bilingual(
+		fmt.Sprintf("Bài viết có %d từ (yêu cầu tối thiểu %d).", wordCount, minWords),
+		fmt.Sprintf("The essay has %d words (minimum %d).", wordCount, minWords))
+	d.CoherenceCohesion = bilingual(
+		fmt.Sprintf("%d đoạn văn, %d từ nối.", f.paragraphs, f.linkingDevices),
+		fmt.Sprintf("%d paragraphs, %d linking devices.", f.paragraphs, f.linkingDevices))
+	d.LexicalResource = bilingual(
+		fmt.Sprintf("%d từ khác nhau trên tổng %d từ; %d từ dài (7 chữ cái trở lên).", f.distinct, wordCount, f.longWords),
+		fmt.Sprintf("%d different words out of %d; %d long words (7+ letters).", f.distinct, wordCount, f.longWords))
+	d.GrammaticalRange = bilingual(
+		fmt.Sprintf("%d câu, %d liên từ phụ thuộc (câu phức).", f.sentences, f.subordinators),
+		fmt.Sprintf("%d sentences, %d subordinating words (complex sentences).", f.sentences, f.subordinators))
+
+	scores := map[string]float64{
+		"task":      c.TaskAchievement,
+		"coherence": c.CoherenceCohesion,
+		"lexical":   c.LexicalResource,
+		"grammar":   c.GrammaticalRange,
+	}
+	eval.ExaminerFeedback = heuristicNotice
+	eval.Strengths, eval.AreasForImprovement = heuristicAdvice(scores, writingAdvice)
+	return eval, nil
+}
+
+// EvaluateSpeaking estimates the four Speaking criteria from the transcript and its duration
+func (h *HeuristicEvaluator) EvaluateSpeaking(in SpeakingInput) (*models.OpenAISpeakingEvaluation, error) {
+	f := analyzeText(in.Transcript, nil)
+	wordCount := f.wordCount
+
+	eval := &models.OpenAISpeakingEvaluation{}
+	var wpm float64
+	if wordCount >= 5 {
+		// Fluency: speaking rate when the duration is known, otherwise length for the part
+		var fluency float64
+		if in.DurationSeconds > 0 {
+			wpm = float64(wordCount) / (in.DurationSeconds / 60)
+			fluency = interpolate(wpm, []float64{50, 80, 110, 150, 200}, []float64{4, 5, 6, 7, 6})
+		} else {
+			target, ok := speakingTargetWC[in.Part]
+			if !ok {
+				target = speakingTargetWC["part3"]
+			}
+			fluency = interpolate(float64(wordCount)/float64(target), []float64{0.3, 0.7, 1.0}, []float64{4, 5.5, 6.5})
+		}
+		fluency -= math.Min(1.5, f.per100Words(f.fillers)*0.25)
+
+		// Speech is rarely punctuated reliably, so complexity is measured per 100 words
+		grammar := interpolate(f.per100Words(f.subordinators), []float64{0, 3, 6}, []float64{4.5, 6, 7})
+
+		c := &eval.CriteriaScores
+		c.FluencyCoherence = heuristicBand(fluency)
+		c.LexicalResource = heuristicBand(lexicalScore(f))
+		c.GrammaticalRange = heuristicBand(grammar)
+		// Pronunciation cannot be heard in a transcript; the other criteria stand in for it
+		c.Pronunciation = heuristicBand((c.FluencyCoherence + c.LexicalResource + c.GrammaticalRange) / 3)
+	}
+
+	c := eval.CriteriaScores
+	eval.OverallBand = ielts.CalculateSpeakingBand(c.FluencyCoherence, c.LexicalResource, c.GrammaticalRange, c.Pronunciation)
+
+	d := &eval.DetailedFeedback
+	d.FluencyCoherence.Score = c.FluencyCoherence
+	d.FluencyCoherence.Analysis = fmt.Sprintf("%d từ, %d từ đệm (um, uh). / %d words, %d fillers.", wordCount, f.fillers, wordCount, f.fillers)
+	if wpm > 0 {
+		d.FluencyCoherence.Analysis = fmt.Sprintf("%d từ trong %.0f giây (%.0f từ/phút), %d từ đệm (um, uh). / %d words in %.0f seconds (%.0f words per minute), %d fillers.",
+			wordCount, in.DurationSeconds, wpm, f.fillers, wordCount, in.DurationSeconds, wpm, f.fillers)
+	}
+	d.LexicalResource.Score, d.LexicalResource.Analysis = c.LexicalResource,
+		fmt.Sprintf("%d từ khác nhau trên tổng %d từ. / %d different words out of %d.", f.distinct, wordCount, f.distinct, wordCount)
+	d.GrammaticalRange.Score, d.GrammaticalRange.Analysis = c.GrammaticalRange,
+		fmt.Sprintf("%d liên từ phụ thuộc (câu phức). / %d subordinating words (complex sentences).", f.subordinators, f.subordinators)
+	d.Pronunciation.Score, d.Pronunciation.Analysis = c.Pronunciation,
+		"Không đánh giá được phát âm từ bản ghi; điểm ước lượng từ các tiêu chí khác. / Pronunciation cannot be assessed from a transcript; estimated from the other criteria."
+
+	scores := map[string]float64{
+		"fluency": c.FluencyCoherence,
+		"lexical": c.LexicalResource,
+		"grammar": c.GrammaticalRange,
+	}
+	eval.ExaminerFeedback = heuristicNotice
+	eval.Strengths, eval.AreasForImprovement = heuristicAdvice(scores, speakingAdvice)
+	return eval, nil
+}
+
+func lexicalScore(f textFeatures) float64 {
+	longShare := float64(f.longWords) / math.Max(1, float64(len(f.words)))
+	return 0.7*interpolate(f.guiraud(), []float64{4, 6, 8, 10}, []float64{4, 5, 6.5, 8}) +
+		0.3*interpolate(longShare, []float64{0.1, 0.2, 0.3}, []float64{4, 6, 8})
+}
+
+func grammarScore(f textFeatures) float64 {
+	perSentence := float64(f.subordinators) / math.Max(1, float64(f.sentences))
+	score := interpolate(perSentence, []float64{0, 0.5, 1}, []float64{4.5, 6, 7.5})
+	avgLength := float64(f.wordCount) / math.Max(1, float64(f.sentences))
+	if avgLength < 8 || avgLength > 35 {
+		score -= 1 // Fragments or run-on sentences
+	}
+	return score
+}
+
+// promptOverlap is the share of the prompt's content words used in the text
+func promptOverlap(prompt string, f textFeatures) (float64, bool) {
+	used := make(map[string]bool, len(f.words))
+	for _, w := range f.words {
+		used[w] = true
+	}
+	keywords, found := 0, 0
+	seen := make(map[string]bool)
+	for _, w := range textanalysis.Words(prompt) {
+		if len(w) < 5 || promptStopwords[w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		keywords++
+		if used[w] {
+			found++
+		}
+	}
+	if keywords == 0 {
+		return 0, false
+	}
+	return float64(found) / float64(keywords), true
+}
+
+// interpolate maps x onto ys piecewise linearly, flat outside the ends of xs
+func interpolate(x float64, xs, ys []float64) float64 {
+	if x <= xs[0] {
+		return ys[0]
+	}
+	for i := 1; i < len(xs); i++ {
+		if x <= xs[i] {
+			return ys[i-1] + (ys[i]-ys[i-1])*(x-xs[i-1])/(xs[i]-xs[i-1])
+		}
+	}
+	return ys[len(ys)-1]
+}
+
+// heuristicBand rounds to a half band between 1 and heuristicMaxBand
+func heuristicBand(score float64) float64 {
+	return math.Max(1, math.Min(heuristicMaxBand, math.Round(score*2)/2))
+}
+
+func bilingual(vi, en string) models.FeedbackBilingual {
+	return models.FeedbackBilingual{VI: vi, EN: en}
+}
+
+const heuristicNotice = "Điểm ước tính tự động từ độ dài, từ vựng, cấu trúc câu và từ nối, chưa được giám khảo AI chấm. / Automatic estimate from length, vocabulary, sentence structure and linking; not marked by an AI examiner."
+
+var writingAdvice = map[string][2]string{
+	"task":      {"Bài viết đủ độ dài và bám sát đề bài.", "Viết đủ số từ tối thiểu và trả lời trực tiếp mọi phần của đề bài."},
+	"coherence": {"Bố cục đoạn văn rõ ràng, có sử dụng từ nối.", "Chia bài thành các đoạn rõ ràng và dùng từ nối đa dạng, tự nhiên hơn."},
+	"lexical":   {"Vốn từ vựng khá đa dạng.", "Mở rộng vốn từ, tránh lặp lại cùng một từ."},
+	"grammar":   {"Sử dụng được câu phức.", "Kết hợp nhiều câu phức (because, although, which) với câu đơn."},
+}
+
+var speakingAdvice = map[string][2]string{
+	"fluency": {"Nói với tốc độ tự nhiên.", "Nói trôi chảy hơn, hạn chế từ đệm như um, uh."},
+	"lexical": {"Vốn từ vựng khá đa dạng.", "Dùng từ vựng đa dạng hơn, tránh lặp từ."},
+	"grammar": {"Sử dụng được câu phức.", "Dùng thêm câu phức để phát triển ý."},
+}
+
+// heuristicAdvice names the best criterion as a strength, if it is good enough, and the
+// weakest as the area to work on
+func heuristicAdvice(scores map[string]float64, advice map[string][2]string) ([]string, []string) {
+	best, worst := "", ""
+	for _, key := range []string{"task", "fluency", "coherence", "lexical", "grammar"} {
+		score, ok := scores[key]
+		if !ok {
+			continue
+		}
+		if best == "" || score > scores[best] {
+			best = key
+		}
+		if worst == "" || score < scores[worst] {
+			worst = key
+		}
+	}
+	strengths := []string{}
+	if best != "" && scores[best] >= 6 {
+		strengths = append(strengths, advice[best][0])
+	}
+	areas := []string{}
+	if worst != "" {
+		areas = append(areas, advice[worst][1])
+	}
+	return strengths, areas
+}
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
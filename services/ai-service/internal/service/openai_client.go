@@ -8,32 +8,57 @@ import (
 	"log"
 	"mime/multipart"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
 )
 
+// OpenAIClient talks to the OpenAI API, or to any server implementing its chat completions
+// and audio transcription endpoints (Ollama, vLLM, LocalAI, ...)
 type OpenAIClient struct {
-	APIKey     string
-	BaseURL    string
-	HTTPClient *http.Client
+	ProviderName       string
+	APIKey             string // Optional for local servers
+	BaseURL            string
+	ChatModel          string
+	TranscriptionModel string
+	HTTPClient         *http.Client
 }
 
-func NewOpenAIClient(apiKey string) *OpenAIClient {
+func NewOpenAIClient(apiKey, chatModel, transcriptionModel string) *OpenAIClient {
 	if apiKey == "" {
 		return nil
 	}
+	return NewOpenAICompatibleClient(ProviderOpenAI, "https://api.openai.com/v1", apiKey, chatModel, transcriptionModel)
+}
+
+// NewOpenAICompatibleClient creates a client for an OpenAI-compatible server at baseURL
+func NewOpenAICompatibleClient(name, baseURL, apiKey, chatModel, transcriptionModel string) *OpenAIClient {
 	return &OpenAIClient{
-		APIKey:  apiKey,
-		BaseURL: "https://api.openai.com/v1",
+		ProviderName:       name,
+		APIKey:             apiKey,
+		BaseURL:            strings.TrimRight(baseURL, "/"),
+		ChatModel:          chatModel,
+		TranscriptionModel: transcriptionModel,
 		HTTPClient: &http.Client{
 			Timeout: 120 * time.Second, // Long timeout for AI processing
 		},
 	}
 }
 
-// TranscribeAudio transcribes audio using Whisper API
-func (c *OpenAIClient) TranscribeAudio(audioURL string, audioData []byte) (*models.OpenAITranscription, error) {
+// Name identifies the provider in logs and configuration
+func (c *OpenAIClient) Name() string {
+	return c.ProviderName
+}
+
+func (c *OpenAIClient) setAuthorization(req *http.Request) {
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+}
+
+// TranscribeAudio transcribes audio using the Whisper API
+func (c *OpenAIClient) TranscribeAudio(fileName string, audioData []byte) (*models.OpenAITranscription, error) {
 	if c == nil {
 		return nil, fmt.Errorf("OpenAI client not initialized (missing API key)")
 	}
@@ -43,7 +68,7 @@ func (c *OpenAIClient) TranscribeAudio(audioURL string, audioData []byte) (*mode
 	writer := multipart.NewWriter(body)
 
 	// Add audio file
-	part, err := writer.CreateFormFile("file", "audio.mp3")
+	part, err := writer.CreateFormFile("file", fileName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create form file: %w", err)
 	}
@@ -52,10 +77,11 @@ func (c *OpenAIClient) TranscribeAudio(audioURL string, audioData []byte) (*mode
 	}
 
 	// Add form fields
-	writer.WriteField("model", "whisper-1")
+	writer.WriteField("model", c.TranscriptionModel)
 	writer.WriteField("language", "en")
 	writer.WriteField("response_format", "verbose_json")
 	writer.WriteField("timestamp_granularities[]", "word")
+	writer.WriteField("timestamp_granularities[]", "segment")
 
 	writer.Close()
 
@@ -65,7 +91,7 @@ func (c *OpenAIClient) TranscribeAudio(audioURL string, audioData []byte) (*mode
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	c.setAuthorization(req)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	// Send request
@@ -77,43 +103,20 @@ func (c *OpenAIClient) TranscribeAudio(audioURL string, audioData []byte) (*mode
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("OpenAI API error: %s - %s", resp.Status, string(bodyBytes))
-	}
-
-	// Parse response - OpenAI returns verbose_json format
-	var transcriptResponse struct {
-		Text     string  `json:"text"`
-		Duration float64 `json:"duration"`
-		Words    []struct {
-			Word  string  `json:"word"`
-			Start float64 `json:"start"`
-			End   float64 `json:"end"`
-		} `json:"words"`
+		return nil, fmt.Errorf("%s API error: %s - %s", c.ProviderName, resp.Status, string(bodyBytes))
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&transcriptResponse); err != nil {
+	// Parse response - OpenAI returns verbose_json format with word and segment timings
+	var transcript models.OpenAITranscription
+	if err := json.NewDecoder(resp.Body).Decode(&transcript); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Convert to our model
-	transcript := &models.OpenAITranscription{
-		Text:     transcriptResponse.Text,
-		Duration: transcriptResponse.Duration,
-	}
-
-	// Convert words
-	transcript.Words = make([]struct {
-		Word  string  `json:"word"`
-		Start float64 `json:"start"`
-		End   float64 `json:"end"`
-	}, len(transcriptResponse.Words))
-	copy(transcript.Words, transcriptResponse.Words)
-
-	return transcript, nil
+	return &transcript, nil
 }
 
-// EvaluateWriting evaluates writing using GPT-4
-func (c *OpenAIClient) EvaluateWriting(taskPromptText, essayText string, wordCount, timeSpent int) (*models.OpenAIWritingEvaluation, error) {
+// EvaluateWriting evaluates writing with the chat model
+func (c *OpenAIClient) EvaluateWriting(in WritingInput) (*models.OpenAIWritingEvaluation, error) {
 	if c == nil {
 		return nil, fmt.Errorf("OpenAI client not initialized (missing API key)")
 	}
@@ -125,7 +128,8 @@ func (c *OpenAIClient) EvaluateWriting(taskPromptText, essayText string, wordCou
 <Student's Essay>
 %s
 
-[Word count: %d | Time taken: %ds]`, taskPromptText, essayText, wordCount, timeSpent)
+[Word count: %d | Time taken: %ds]
+%s`, in.PromptText, in.EssayText, in.WordCount, in.TimeSpentSeconds, textAnalysisPrompt(in.TaskType, in.Analysis))
 
 	systemPrompt := `You are an official IELTS Writing examiner.
 You will be given a writing task and a student's essay.
@@ -201,33 +205,11 @@ Guidelines:
 - Examiner feedback should be natural and encouraging but honest
 - Overall band = average of 4 criteria, rounded to nearest 0.5`
 
-	// Prepare request payload
-	payload := map[string]interface{}{
-		"model": "gpt-4o",
-		"messages": []map[string]interface{}{
-			{
-				"role":    "system",
-				"content": systemPrompt,
-			},
-			{
-				"role":    "user",
-				"content": evaluationPrompt,
-			},
-		},
-		"temperature":      0.3,
-		"response_format": map[string]string{"type": "json_object"},
-	}
-
-	eval := &models.OpenAIWritingEvaluation{}
-	_, err := c.callChatAPI(payload, eval)
-	if err != nil {
-		return nil, err
-	}
-	return eval, nil
+	return requestEvaluation(c, systemPrompt, evaluationPrompt, writingContract)
 }
 
-// EvaluateSpeaking evaluates speaking using GPT-4
-func (c *OpenAIClient) EvaluateSpeaking(part string, promptText, transcriptText string, wordCount int, duration float64) (*models.OpenAISpeakingEvaluation, error) {
+// EvaluateSpeaking evaluates speaking with the chat model
+func (c *OpenAIClient) EvaluateSpeaking(in SpeakingInput) (*models.OpenAISpeakingEvaluation, error) {
 	if c == nil {
 		return nil, fmt.Errorf("OpenAI client not initialized (missing API key)")
 	}
@@ -237,7 +219,7 @@ func (c *OpenAIClient) EvaluateSpeaking(part string, promptText, transcriptText
 		"part2": "Part 2 (Long Turn)",
 		"part3": "Part 3 (Two-way Discussion)",
 	}
-	partName := partNames[part]
+	partName := partNames[in.Part]
 	if partName == "" {
 		partName = "a section"
 	}
@@ -255,7 +237,7 @@ EVALUATION METADATA:
 - Part: %s
 - Duration: %.1f seconds
 - Word count: %d words
-
+%s
 EVALUATION TASK (Follow these steps carefully):
 
 STEP 1: TOPIC RELEVANCE ANALYSIS
@@ -311,7 +293,7 @@ IMPORTANT REMINDERS:
 1. Even if the answer is completely off-topic, you MUST still evaluate language skills fairly
 2. Topic relevance affects ONLY "Fluency & Coherence - Topic Development", not other criteria
 3. Always cite specific examples from the transcript for each criterion
-4. Be fair, accurate, and constructive in your evaluation`, part, promptText, transcriptText, partName, duration, wordCount)
+4. Be fair, accurate, and constructive in your evaluation`, in.Part, in.PromptText, in.Transcript, partName, in.DurationSeconds, in.WordCount, speechMetricsPrompt(in.Metrics))
 
 	systemPrompt := `You are an official IELTS Speaking examiner. 
 You will receive a student's spoken answers (converted to text) and the questions they were responding to. 
@@ -461,110 +443,85 @@ EVALUATION GUIDELINES:
    - Double-check that off-topic answers still receive fair language evaluation
    - Verify that all examples cited actually exist in the transcript`
 
-	// Prepare request payload
-	payload := map[string]interface{}{
-		"model": "gpt-4o",
-		"messages": []map[string]interface{}{
-			{
-				"role":    "system",
-				"content": systemPrompt,
-			},
-			{
-				"role":    "user",
-				"content": evaluationPrompt,
-			},
-		},
-		"temperature":      0.3,
-		"response_format": map[string]string{"type": "json_object"},
-	}
+	return requestEvaluation(c, systemPrompt, evaluationPrompt, speakingContract)
+}
 
-	eval := &models.OpenAISpeakingEvaluation{}
-	_, err := c.callChatAPI(payload, eval)
-	if err != nil {
-		return nil, err
-	}
-	return eval, nil
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
 }
 
-// callChatAPI is a helper to call OpenAI Chat API
-func (c *OpenAIClient) callChatAPI(payload interface{}, result interface{}) (interface{}, error) {
+// chatCompletion sends the conversation to the chat API and returns the reply and why the
+// model stopped ("stop", or "length" when the reply was cut off)
+func (c *OpenAIClient) chatCompletion(messages []chatMessage, responseFormat interface{}) (string, string, error) {
+	payload := map[string]interface{}{
+		"model":           c.ChatModel,
+		"messages":        messages,
+		"temperature":     0.3,
+		"response_format": responseFormat,
+	}
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		return "", "", fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	log.Printf("📤 OpenAI API Request: POST %s/chat/completions", c.BaseURL)
-	log.Printf("📊 Request Payload (first 500 chars): %s", string(jsonData[:min(500, len(jsonData))]))
+	log.Printf("📤 %s API Request: POST %s/chat/completions", c.ProviderName, c.BaseURL)
 
 	req, err := http.NewRequest("POST", c.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return "", "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	c.setAuthorization(req)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		log.Printf("❌ OpenAI API Request Failed: %v", err)
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		log.Printf("❌ %s API Request Failed: %v", c.ProviderName, err)
+		return "", "", fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	log.Printf("📥 OpenAI API Response Status: %d (%s)", resp.StatusCode, resp.Status)
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		log.Printf("❌ OpenAI API Error Response: %s", string(bodyBytes))
-		return nil, fmt.Errorf("OpenAI API error: %s - %s", resp.Status, string(bodyBytes))
-	}
+	log.Printf("📥 %s API Response Status: %d (%s)", c.ProviderName, resp.StatusCode, resp.Status)
 
-	// Read body for debugging
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Printf("❌ Error reading response body: %v", err)
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return "", "", fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	log.Printf("📥 Full OpenAI API Response Body: %s", string(bodyBytes))
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("❌ %s API Error Response: %s", c.ProviderName, string(bodyBytes))
+		return "", "", fmt.Errorf("%s API error: %s - %s", c.ProviderName, resp.Status, string(bodyBytes))
+	}
 
 	var response struct {
 		Choices []struct {
 			Message struct {
 				Content string `json:"content"`
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
 		Error map[string]interface{} `json:"error"`
 	}
 
 	if err := json.Unmarshal(bodyBytes, &response); err != nil {
-		log.Printf("❌ Failed to decode OpenAI response: %v", err)
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		log.Printf("❌ Failed to decode %s response: %v", c.ProviderName, err)
+		return "", "", fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	// Check for API error
 	if response.Error != nil {
-		log.Printf("❌ OpenAI API returned error: %v", response.Error)
-		return nil, fmt.Errorf("OpenAI API error: %v", response.Error)
+		log.Printf("❌ %s API returned error: %v", c.ProviderName, response.Error)
+		return "", "", fmt.Errorf("%s API error: %v", c.ProviderName, response.Error)
 	}
 
 	if len(response.Choices) == 0 {
-		log.Printf("❌ No choices in OpenAI response")
-		return nil, fmt.Errorf("no choices in response")
+		log.Printf("❌ No choices in %s response", c.ProviderName)
+		return "", "", fmt.Errorf("no choices in response")
 	}
 
-	log.Printf("✅ OpenAI API Response received (first 300 chars of content): %s", response.Choices[0].Message.Content[:min(300, len(response.Choices[0].Message.Content))])
-
-	// Parse JSON content to result type
-	contentBytes := []byte(response.Choices[0].Message.Content)
-	if err := json.Unmarshal(contentBytes, result); err != nil {
-		log.Printf("❌ Failed to unmarshal evaluation result: %v", err)
-		log.Printf("Raw content: %s", string(contentBytes))
-		return nil, fmt.Errorf("failed to unmarshal evaluation: %w", err)
-	}
-
-	log.Printf("✅ Successfully parsed OpenAI evaluation result")
-	log.Printf("Overall Band Score: %v", result)
-	return result, nil
+	choice := response.Choices[0]
+	log.Printf("✅ %s API Response received (first 300 chars of content): %s", c.ProviderName, choice.Message.Content[:min(300, len(choice.Message.Content))])
+	return choice.Message.Content, choice.FinishReason, nil
 }
-
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bisosad1501/DATN/services/ai-service/internal/config"
+	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
+	"github.com/bisosad1501/DATN/shared/pkg/textanalysis"
+)
+
+// Provider names used in AI_*_PROVIDERS
+const (
+	ProviderOpenAI           = "openai"            // api.openai.com
+	ProviderOpenAICompatible = "openai_compatible" // Any server speaking the OpenAI API at LLM_BASE_URL (Ollama, vLLM, ...)
+	ProviderHeuristic        = "heuristic"         // Deterministic local scoring, no network
+)
+
+// WritingInput is what a writing evaluator is given
+type WritingInput struct {
+	TaskType         string // task1, task2
+	PromptText       string
+	EssayText        string
+	WordCount        int
+	TimeSpentSeconds int
+	Analysis         *textanalysis.Analysis // Measured text analytics, nil if unavailable
+}
+
+// SpeakingInput is what a speaking evaluator is given
+type SpeakingInput struct {
+	Part            string // part1, part2, part3
+	PromptText      string
+	Transcript      string
+	WordCount       int
+	DurationSeconds float64
+	Metrics         *models.SpeechMetrics // Measured fluency, nil if unavailable
+}
+
+// Transcriber turns recorded speech into text
+type Transcriber interface {
+	Name() string
+	TranscribeAudio(fileName string, audioData []byte) (*models.OpenAITranscription, error)
+}
+
+// WritingEvaluator scores an essay against the IELTS Writing band descriptors
+type WritingEvaluator interface {
+	Name() string
+	EvaluateWriting(in WritingInput) (*models.OpenAIWritingEvaluation, error)
+}
+
+// SpeakingEvaluator scores a transcribed answer against the IELTS Speaking band descriptors
+type SpeakingEvaluator interface {
+	Name() string
+	EvaluateSpeaking(in SpeakingInput) (*models.OpenAISpeakingEvaluation, error)
+}
+
+// errNoProvider is returned by a chain with nothing configured
+var errNoProvider = errors.New("no AI provider configured")
+
+// TranscriberChain tries each transcriber in order until one succeeds
+type TranscriberChain []Transcriber
+
+// TranscribeAudio returns the first successful transcription and the provider that made it
+func (chain TranscriberChain) TranscribeAudio(fileName string, audioData []byte) (*models.OpenAITranscription, string, error) {
+	var errs []error
+	for _, p := range chain {
+		transcript, err := p.TranscribeAudio(fileName, audioData)
+		if err == nil {
+			return transcript, p.Name(), nil
+		}
+		log.Printf("⚠️ [AI Service] %s transcription failed, trying next provider: %v", p.Name(), err)
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+	}
+	return nil, "", chainError(errs)
+}
+
+// WritingChain tries each writing evaluator in order until one succeeds
+type WritingChain []WritingEvaluator
+
+// EvaluateWriting returns the first successful evaluation and the provider that made it
+func (chain WritingChain) EvaluateWriting(in WritingInput) (*models.OpenAIWritingEvaluation, string, error) {
+	var errs []error
+	for _, p := range chain {
+		eval, err := p.EvaluateWriting(in)
+		if err == nil {
+			return eval, p.Name(), nil
+		}
+		log.Printf("⚠️ [AI Service] %s writing evaluation failed, trying next provider: %v", p.Name(), err)
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+	}
+	return nil, "", chainError(errs)
+}
+
+// SpeakingChain tries each speaking evaluator in order until one succeeds
+type SpeakingChain []SpeakingEvaluator
+
+// EvaluateSpeaking returns the first successful evaluation and the provider that made it
+func (chain SpeakingChain) EvaluateSpeaking(in SpeakingInput) (*models.OpenAISpeakingEvaluation, string, error) {
+	var errs []error
+	for _, p := range chain {
+		eval, err := p.EvaluateSpeaking(in)
+		if err == nil {
+			return eval, p.Name(), nil
+		}
+		log.Printf("⚠️ [AI Service] %s speaking evaluation failed, trying next provider: %v", p.Name(), err)
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+	}
+	return nil, "", chainError(errs)
+}
+
+func chainError(errs []error) error {
+	if len(errs) == 0 {
+		return errNoProvider
+	}
+	return errors.Join(errs...)
+}
+
+// Providers holds the provider chain of each AI task
+type Providers struct {
+	Transcription TranscriberChain
+	Writing       WritingChain
+	Speaking      SpeakingChain
+}
+
+// NewProviders builds the provider chains from configuration. Providers that are listed but
+// not usable (e.g. openai without an API key) are skipped with a warning. When a task lists
+// no providers, every configured remote provider is used. The heuristic evaluator is only
+// used when listed: its estimates are not examiner bands, and a failed evaluation is retried
+// later rather than silently replaced by one.
+func NewProviders(cfg *config.Config) *Providers {
+	type backend struct {
+		transcriber Transcriber // nil when the provider cannot transcribe
+		writing     WritingEvaluator
+		speaking    SpeakingEvaluator
+	}
+	available := make(map[string]backend)
+	if cfg.OpenAIAPIKey != "" {
+		c := NewOpenAIClient(cfg.OpenAIAPIKey, cfg.OpenAIChatModel, cfg.OpenAITranscriptionModel)
+		available[ProviderOpenAI] = backend{c, c, c}
+	}
+	if cfg.LLMBaseURL != "" {
+		c := NewOpenAICompatibleClient(ProviderOpenAICompatible, cfg.LLMBaseURL, cfg.LLMAPIKey, cfg.LLMChatModel, cfg.LLMTranscriptionModel)
+		available[ProviderOpenAICompatible] = backend{c, c, c}
+	}
+	heuristic := NewHeuristicEvaluator()
+	available[ProviderHeuristic] = backend{nil, heuristic, heuristic}
+
+	pick := func(task string, names []string, defaults ...string) []backend {
+		explicit := len(names) > 0
+		if !explicit {
+			names = defaults
+		}
+		var picked []backend
+		for _, name := range names {
+			b, ok := available[name]
+			if !ok {
+				if explicit {
+					log.Printf("⚠️  %s provider %q is not configured, skipping", task, name)
+				}
+				continue
+			}
+			picked = append(picked, b)
+		}
+		return picked
+	}
+
+	providers := &Providers{}
+	for _, b := range pick("Transcription", cfg.TranscriptionProviders, ProviderOpenAI, ProviderOpenAICompatible) {
+		if b.transcriber == nil {
+			log.Printf("⚠️  %s provider cannot transcribe audio, skipping", b.writing.Name())
+			continue
+		}
+		providers.Transcription = append(providers.Transcription, b.transcriber)
+	}
+	for _, b := range pick("Writing", cfg.WritingProviders, ProviderOpenAI, ProviderOpenAICompatible) {
+		providers.Writing = append(providers.Writing, b.writing)
+	}
+	for _, b := range pick("Speaking", cfg.SpeakingProviders, ProviderOpenAI, ProviderOpenAICompatible) {
+		providers.Speaking = append(providers.Speaking, b.speaking)
+	}
+
+	log.Printf("🤖 AI providers: transcription=[%s] writing=[%s] speaking=[%s]",
+		providerNames(providers.Transcription), providerNames(providers.Writing), providerNames(providers.Speaking))
+	return providers
+}
+
+func providerNames[P interface{ Name() string }](chain []P) string {
+	names := make([]string, len(chain))
+	for i, p := range chain {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, ",")
+}
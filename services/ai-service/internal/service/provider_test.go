@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bisosad1501/DATN/services/ai-service/internal/config"
+	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
+)
+
+type failingEvaluator struct{ calls int }
+
+func (f *failingEvaluator) Name() string { return "failing" }
+
+func (f *failingEvaluator) EvaluateWriting(WritingInput) (*models.OpenAIWritingEvaluation, error) {
+	f.calls++
+	return nil, errors.New("connection refused")
+}
+
+func TestWritingChainFailover(t *testing.T) {
+	failing := &failingEvaluator{}
+	chain := WritingChain{failing, NewHeuristicEvaluator()}
+
+	eval, provider, err := chain.EvaluateWriting(WritingInput{TaskType: "task2", EssayText: strings.Repeat("Students learn better with teachers. ", 10)})
+	if err != nil || eval == nil || provider != ProviderHeuristic || failing.calls != 1 {
+		t.Fatalf("got provider %q, err %v, failing calls %d", provider, err, failing.calls)
+	}
+
+	_, _, err = WritingChain{failing}.EvaluateWriting(WritingInput{EssayText: "x"})
+	if err == nil || !strings.Contains(err.Error(), "failing: connection refused") {
+		t.Errorf("all providers failing: err = %v", err)
+	}
+	if _, _, err := (WritingChain{}).EvaluateWriting(WritingInput{}); !errors.Is(err, errNoProvider) {
+		t.Errorf("empty chain: err = %v", err)
+	}
+}
+
+func TestHeuristicWriting(t *testing.T) {
+	prompt := "Some people believe that university education should be free for all students. To what extent do you agree or disagree?"
+	paragraph := "Many people argue that university education should be free, because talented students from poor families " +
+		"would otherwise never reach higher education. However, governments have limited budgets, which means that " +
+		"funding universities completely could reduce spending on hospitals and schools. In addition, graduates usually " +
+		"earn considerably higher salaries, so it seems reasonable that they contribute something towards their studies. "
+	essay := strings.TrimSpace(strings.Repeat(paragraph+"\n\n", 3))
+
+	h := NewHeuristicEvaluator()
+	full, err := h.EvaluateWriting(WritingInput{TaskType: "task2", PromptText: prompt, EssayText: essay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := h.EvaluateWriting(WritingInput{TaskType: "task2", PromptText: prompt, EssayText: essay})
+	if !reflect.DeepEqual(full, again) {
+		t.Error("heuristic evaluation is not deterministic")
+	}
+
+	short, _ := h.EvaluateWriting(WritingInput{TaskType: "task2", PromptText: prompt, EssayText: paragraph})
+	offTopic, _ := h.EvaluateWriting(WritingInput{TaskType: "task2", PromptText: "Describe the chart showing coffee exports from Brazil and Vietnam.", EssayText: essay})
+	tiny, _ := h.EvaluateWriting(WritingInput{TaskType: "task2", EssayText: "I agree."})
+
+	if full.CriteriaScores.TaskAchievement <= short.CriteriaScores.TaskAchievement {
+		t.Errorf("full essay task %.1f, short %.1f", full.CriteriaScores.TaskAchievement, short.CriteriaScores.TaskAchievement)
+	}
+	if full.CriteriaScores.TaskAchievement <= offTopic.CriteriaScores.TaskAchievement {
+		t.Errorf("on-topic task %.1f, off-topic %.1f", full.CriteriaScores.TaskAchievement, offTopic.CriteriaScores.TaskAchievement)
+	}
+	if tiny.OverallBand != 1 || len(tiny.Strengths) != 0 {
+		t.Errorf("tiny essay = %.1f, strengths %v", tiny.OverallBand, tiny.Strengths)
+	}
+	for _, band := range []float64{full.OverallBand, full.CriteriaScores.LexicalResource, full.CriteriaScores.GrammaticalRange} {
+		if band < 1 || band > heuristicMaxBand || band*2 != float64(int(band*2)) {
+			t.Errorf("band %.2f is not a half band within range", band)
+		}
+	}
+}
+
+func TestHeuristicSpeaking(t *testing.T) {
+	h := NewHeuristicEvaluator()
+	answer := "I really enjoy reading novels because they let me escape from my busy routine, and when I have free time " +
+		"I usually visit the library which is close to my house."
+
+	fluent, _ := h.EvaluateSpeaking(SpeakingInput{Part: "part1", Transcript: answer, DurationSeconds: 12})
+	hesitant, _ := h.EvaluateSpeaking(SpeakingInput{Part: "part1", Transcript: "um I uh " + answer + " um er uh um", DurationSeconds: 30})
+	if fluent.CriteriaScores.FluencyCoherence <= hesitant.CriteriaScores.FluencyCoherence {
+		t.Errorf("fluent %.1f, hesitant %.1f", fluent.CriteriaScores.FluencyCoherence, hesitant.CriteriaScores.FluencyCoherence)
+	}
+
+	silent, _ := h.EvaluateSpeaking(SpeakingInput{Part: "part2", Transcript: "yes"})
+	if silent.OverallBand != 0 {
+		t.Errorf("near-empty answer = %.1f", silent.OverallBand)
+	}
+}
+
+func TestNewProvidersHeuristicOnlyWhenListed(t *testing.T) {
+	cfg := &config.Config{OpenAIAPIKey: "sk-test"}
+	providers := NewProviders(cfg)
+	if got := providerNames(providers.Writing); got != ProviderOpenAI {
+		t.Errorf("default writing chain = %q, want only %q", got, ProviderOpenAI)
+	}
+	if got := providerNames(providers.Speaking); got != ProviderOpenAI {
+		t.Errorf("default speaking chain = %q, want only %q", got, ProviderOpenAI)
+	}
+
+	cfg.WritingProviders = []string{ProviderOpenAI, ProviderHeuristic}
+	if got := providerNames(NewProviders(cfg).Writing); got != "openai,heuristic" {
+		t.Errorf("explicit writing chain = %q", got)
+	}
+}
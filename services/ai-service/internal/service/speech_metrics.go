@@ -0,0 +1,220 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
+	"github.com/bisosad1501/DATN/shared/pkg/textanalysis"
+)
+
+const (
+	pauseThreshold  = 0.25 // Seconds of silence between words that count as a pause
+	mediumPause     = 0.5
+	longPause       = 1.0
+	fillerWordLike  = "like"
+	minTimedSeconds = 1.0 // Below this the speaking time is too short for a rate
+)
+
+// likeAsVerb are words after which "like" is the verb ("I like", "would like") unless a pause
+// or comma separates them
+var likeAsVerb = toSet("i", "you", "we", "they", "would", "don't", "didn't", "really", "also", "to", "people", "who", "not")
+
+// spokenToken is a word of the answer with its timing when known
+type spokenToken struct {
+	word       string // lower case, punctuation removed
+	start, end float64
+	commaAfter bool
+}
+
+// AnalyzeSpeech measures fluency from the answer. With word timings (from the transcription)
+// it also measures pauses and articulation rate; without them only the transcript and the
+// recording length are used. durationSeconds is the recording length, 0 if unknown.
+func AnalyzeSpeech(transcript string, words []models.TranscriptWord, segments []models.TranscriptSegment, durationSeconds float64) *models.SpeechMetrics {
+	tokens := timedTokens(words)
+	timed := len(tokens) > 0
+	if !timed {
+		tokens = textTokens(transcript)
+	}
+
+	m := &models.SpeechMetrics{WordCount: len(tokens), HasWordTimings: timed}
+	if timed && durationSeconds <= 0 {
+		durationSeconds = tokens[len(tokens)-1].end
+	}
+	m.DurationSeconds = round(durationSeconds, 1)
+	if durationSeconds >= minTimedSeconds {
+		m.SpeechRate = round(float64(len(tokens))/durationSeconds*60, 1)
+	}
+
+	if timed {
+		measurePauses(m, tokens, durationSeconds)
+	}
+
+	content := make([]string, 0, len(tokens))
+	for i, t := range tokens {
+		if isFiller(tokens, i, timed) {
+			m.FillerCount++
+			continue
+		}
+		content = append(content, t.word)
+	}
+	if len(tokens) > 0 {
+		m.FillersPer100Words = round(float64(m.FillerCount)/float64(len(tokens))*100, 1)
+	}
+	m.SelfRepetitions = countRepetitions(content)
+	m.RecognitionConfidence = recognitionConfidence(segments)
+	return m
+}
+
+func timedTokens(words []models.TranscriptWord) []spokenToken {
+	var tokens []spokenToken
+	for _, w := range words {
+		for _, part := range textanalysis.Words(w.Word) {
+			tokens = append(tokens, spokenToken{word: part, start: w.Start, end: w.End})
+		}
+		if len(tokens) > 0 && strings.HasSuffix(strings.TrimSpace(w.Word), ",") {
+			tokens[len(tokens)-1].commaAfter = true
+		}
+	}
+	return tokens
+}
+
+func textTokens(transcript string) []spokenToken {
+	var tokens []spokenToken
+	for _, field := range strings.Fields(transcript) {
+		for _, part := range textanalysis.Words(field) {
+			tokens = append(tokens, spokenToken{word: part})
+		}
+		if len(tokens) > 0 && strings.HasSuffix(field, ",") {
+			tokens[len(tokens)-1].commaAfter = true
+		}
+	}
+	return tokens
+}
+
+// measurePauses fills the pause figures and articulation rate. Silence before the first and
+// after the last word is not a pause: it is the recording starting and stopping.
+func measurePauses(m *models.SpeechMetrics, tokens []spokenToken, durationSeconds float64) {
+	var total, long float64
+	for i := 1; i < len(tokens); i++ {
+		gap := tokens[i].start - tokens[i-1].end
+		if gap < pauseThreshold {
+			continue
+		}
+		m.PauseCount++
+		total += gap
+		m.Pauses.LongestSeconds = math.Max(m.Pauses.LongestSeconds, gap)
+		switch {
+		case gap >= longPause:
+			m.Pauses.Long++
+			long += gap
+		case gap >= mediumPause:
+			m.Pauses.Medium++
+		default:
+			m.Pauses.Short++
+		}
+	}
+	if m.PauseCount > 0 {
+		m.Pauses.MeanSeconds = round(total/float64(m.PauseCount), 2)
+	}
+	m.Pauses.LongestSeconds = round(m.Pauses.LongestSeconds, 2)
+	if durationSeconds > 0 {
+		m.LongPauseRatio = round(long/durationSeconds, 2)
+	}
+
+	speaking := tokens[len(tokens)-1].end - tokens[0].start - total
+	if speaking >= minTimedSeconds {
+		m.ArticulationRate = round(float64(len(tokens))/speaking*60, 1)
+	}
+}
+
+// isFiller reports whether the token is a hesitation. "like" only counts when it is set off
+// by a pause or comma or sits next to another filler, so "I like reading" is not a filler.
+func isFiller(tokens []spokenToken, i int, timed bool) bool {
+	t := tokens[i]
+	if fillerWords[t.word] {
+		return true
+	}
+	if t.word != fillerWordLike {
+		return false
+	}
+
+	pausedBefore := i > 0 && (tokens[i-1].commaAfter || (timed && t.start-tokens[i-1].end >= pauseThreshold))
+	if i > 0 && likeAsVerb[tokens[i-1].word] && !pausedBefore {
+		return false
+	}
+	pausedAfter := t.commaAfter || (timed && i+1 < len(tokens) && tokens[i+1].start-t.end >= pauseThreshold)
+	nextToFiller := (i > 0 && fillerWords[tokens[i-1].word]) || (i+1 < len(tokens) && fillerWords[tokens[i+1].word])
+	return pausedBefore || pausedAfter || nextToFiller
+}
+
+// countRepetitions counts immediately repeated words ("the the") and word pairs
+// ("I went I went"). Fillers are removed first, so "I um I think" is a repetition.
+func countRepetitions(words []string) int {
+	count := 0
+	for i := 1; i < len(words); i++ {
+		if i >= 3 && words[i] != words[i-1] && words[i-1] == words[i-3] && words[i] == words[i-2] {
+			count++
+			continue
+		}
+		if words[i] == words[i-1] {
+			count++
+		}
+	}
+	return count
+}
+
+// recognitionConfidence is the recogniser's mean token probability, weighted by segment
+// length. Unclear pronunciation makes recognition less certain, so it serves as a proxy.
+func recognitionConfidence(segments []models.TranscriptSegment) *float64 {
+	var weighted, total float64
+	for _, s := range segments {
+		length := s.End - s.Start
+		if length <= 0 {
+			continue
+		}
+		weighted += math.Exp(s.AvgLogprob) * length
+		total += length
+	}
+	if total == 0 {
+		return nil
+	}
+	confidence := round(weighted/total, 2)
+	return &confidence
+}
+
+func round(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
+
+// speechMetricsPrompt describes the measured metrics for the evaluation prompt
+func speechMetricsPrompt(m *models.SpeechMetrics) string {
+	if m == nil || m.WordCount == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\nSPEECH METRICS (measured from the recording, not estimated):\n")
+	if m.SpeechRate > 0 {
+		fmt.Fprintf(&b, "- Speech rate: %.0f words per minute", m.SpeechRate)
+		if m.ArticulationRate > 0 {
+			fmt.Fprintf(&b, " (%.0f words per minute while speaking, pauses excluded)", m.ArticulationRate)
+		}
+		b.WriteString("\n")
+	}
+	if m.HasWordTimings {
+		fmt.Fprintf(&b, "- Pauses of 0.25 s or more: %d (%d under 0.5 s, %d of 0.5-1 s, %d of 1 s or more; longest %.1f s)\n",
+			m.PauseCount, m.Pauses.Short, m.Pauses.Medium, m.Pauses.Long, m.Pauses.LongestSeconds)
+		fmt.Fprintf(&b, "- Time spent in long pauses: %.0f%% of the answer\n", m.LongPauseRatio*100)
+	}
+	fmt.Fprintf(&b, "- Filler words: %d (%.1f per 100 words)\n", m.FillerCount, m.FillersPer100Words)
+	fmt.Fprintf(&b, "- Self-repetitions: %d\n", m.SelfRepetitions)
+	if m.RecognitionConfidence != nil {
+		fmt.Fprintf(&b, "- Speech recognition confidence: %.2f (0-1; low values suggest unclear pronunciation)\n", *m.RecognitionConfidence)
+	}
+	b.WriteString("Base Fluency & Coherence on these figures rather than guessing pace from the text, and use them as indirect evidence for Pronunciation. ")
+	b.WriteString("Fluent speakers typically manage 120-160 words per minute with few long pauses. The transcriber may drop some fillers, so treat the filler count as a minimum.\n")
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
+)
+
+// timeline builds word timings from "word start end" triples
+func timeline(spec ...interface{}) []models.TranscriptWord {
+	var words []models.TranscriptWord
+	for i := 0; i+2 < len(spec); i += 3 {
+		words = append(words, models.TranscriptWord{Word: spec[i].(string), Start: spec[i+1].(float64), End: spec[i+2].(float64)})
+	}
+	return words
+}
+
+func TestAnalyzeSpeechTimeline(t *testing.T) {
+	words := timeline(
+		"I", 0.0, 0.2, "think", 0.2, 0.5,
+		"um", 0.8, 1.0, // 0.3 s pause before
+		"the", 2.2, 2.4, "the", 2.4, 2.6, "city", 2.6, 3.0, // 1.2 s pause before
+		"is", 3.6, 3.8, "like", 3.8, 4.0, // 0.6 s pause before
+		"really", 4.3, 4.6, "big.", 4.6, 5.0, // 0.3 s pause before
+	)
+	m := AnalyzeSpeech("", words, nil, 6)
+
+	if m.WordCount != 10 || !m.HasWordTimings || m.SpeechRate != 100 {
+		t.Errorf("words %d, timed %v, speech rate %.1f", m.WordCount, m.HasWordTimings, m.SpeechRate)
+	}
+	// 10 words in 5 s of speech minus 2.4 s of pauses
+	if m.ArticulationRate != 230.8 {
+		t.Errorf("articulation rate = %.1f", m.ArticulationRate)
+	}
+	want := models.PauseDistribution{Short: 2, Medium: 1, Long: 1, MeanSeconds: 0.6, LongestSeconds: 1.2}
+	if m.PauseCount != 4 || m.Pauses != want {
+		t.Errorf("pauses %d %+v, want 4 %+v", m.PauseCount, m.Pauses, want)
+	}
+	if m.LongPauseRatio != 0.2 {
+		t.Errorf("long pause ratio = %.2f", m.LongPauseRatio)
+	}
+	// "um", and "like" followed by a pause
+	if m.FillerCount != 2 || m.FillersPer100Words != 20 || m.SelfRepetitions != 1 {
+		t.Errorf("fillers %d (%.1f per 100), repetitions %d", m.FillerCount, m.FillersPer100Words, m.SelfRepetitions)
+	}
+	if m.RecognitionConfidence != nil {
+		t.Errorf("recognition confidence without segments = %v", *m.RecognitionConfidence)
+	}
+}
+
+func TestAnalyzeSpeechWithoutTimings(t *testing.T) {
+	m := AnalyzeSpeech("Well, um, I I like it.", nil, nil, 30)
+	if m.WordCount != 6 || m.HasWordTimings || m.SpeechRate != 12 || m.PauseCount != 0 || m.ArticulationRate != 0 {
+		t.Errorf("metrics = %+v", m)
+	}
+	if m.FillerCount != 1 || m.SelfRepetitions != 1 {
+		t.Errorf("fillers %d, repetitions %d", m.FillerCount, m.SelfRepetitions)
+	}
+
+	// Without a recording length the last word's end time stands in for it
+	if m := AnalyzeSpeech("", timeline("Hello", 0.5, 1.0, "there", 1.0, 2.0), nil, 0); m.DurationSeconds != 2 || m.SpeechRate != 60 {
+		t.Errorf("duration %.1f, rate %.1f", m.DurationSeconds, m.SpeechRate)
+	}
+}
+
+func TestFillerLike(t *testing.T) {
+	tests := []struct {
+		text    string
+		fillers int
+	}{
+		{"I like reading books", 0},
+		{"I would like a coffee", 0},
+		{"It was, like, really huge", 1},
+		{"and like um the park", 2},
+		{"They look like their father", 0},
+	}
+	for _, tt := range tests {
+		if m := AnalyzeSpeech(tt.text, nil, nil, 0); m.FillerCount != tt.fillers {
+			t.Errorf("%q: %d fillers, want %d", tt.text, m.FillerCount, tt.fillers)
+		}
+	}
+
+	// A pause before "like" marks it as a filler even after a subject
+	paused := timeline("I", 0.0, 0.2, "like", 0.8, 1.0, "enjoy", 1.0, 1.4, "it", 1.4, 1.6)
+	if m := AnalyzeSpeech("", paused, nil, 2); m.FillerCount != 1 {
+		t.Errorf("paused like: %d fillers", m.FillerCount)
+	}
+}
+
+func TestCountRepetitions(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"I went I went to the shop", 1},
+		{"the the the cat", 2},
+		{"I um I think so", 1},
+		{"we saw a bird and a dog", 0},
+	}
+	for _, tt := range tests {
+		if m := AnalyzeSpeech(tt.text, nil, nil, 0); m.SelfRepetitions != tt.want {
+			t.Errorf("%q: %d repetitions, want %d", tt.text, m.SelfRepetitions, tt.want)
+		}
+	}
+}
+
+func TestRecognitionConfidence(t *testing.T) {
+	segments := []models.TranscriptSegment{
+		{Start: 0, End: 2, AvgLogprob: math.Log(0.9)},
+		{Start: 2, End: 3, AvgLogprob: math.Log(0.6)},
+	}
+	if c := recognitionConfidence(segments); c == nil || *c != 0.8 {
+		t.Errorf("confidence = %v", c)
+	}
+}
+
+func TestSpeechMetricsPrompt(t *testing.T) {
+	timed := speechMetricsPrompt(AnalyzeSpeech("", timeline("Hello", 0.0, 0.5, "world", 1.6, 2.0), nil, 2))
+	if !strings.Contains(timed, "Pauses of 0.25 s or more: 1") || !strings.Contains(timed, "Speech rate: 60 words per minute") {
+		t.Errorf("timed prompt:\n%s", timed)
+	}
+	if text := speechMetricsPrompt(AnalyzeSpeech("Hello world", nil, nil, 0)); strings.Contains(text, "Pauses") || strings.Contains(text, "Speech rate") {
+		t.Errorf("untimed prompt:\n%s", text)
+	}
+	if speechMetricsPrompt(nil) != "" {
+		t.Error("nil metrics should add nothing to the prompt")
+	}
+}
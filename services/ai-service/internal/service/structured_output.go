@@ -0,0 +1,275 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+)
+
+// maxRepairAttempts bounds how often the model is asked to fix an invalid evaluation
+const maxRepairAttempts = 2
+
+// evaluationContract is what an evaluation from the model must look like: the JSON schema
+// sent as the response format, and the band checks the decoded result must pass
+type evaluationContract[T any] struct {
+	name   string
+	schema map[string]interface{}
+	// check validates the criteria bands and recomputes the overall band from them
+	check func(*T) error
+}
+
+var writingContract = evaluationContract[models.OpenAIWritingEvaluation]{
+	name: "ielts_writing_evaluation",
+	schema: objectSchema(map[string]interface{}{
+		"overall_band": bandSchema,
+		"criteria_scores": objectSchema(map[string]interface{}{
+			"task_achievement":   bandSchema,
+			"coherence_cohesion": bandSchema,
+			"lexical_resource":   bandSchema,
+			"grammatical_range":  bandSchema,
+		}),
+		"detailed_feedback": objectSchema(map[string]interface{}{
+			"task_achievement":   bilingualSchema,
+			"coherence_cohesion": bilingualSchema,
+			"lexical_resource":   bilingualSchema,
+			"grammatical_range":  bilingualSchema,
+		}),
+		"examiner_feedback":     stringSchema,
+		"strengths":             stringListSchema,
+		"areas_for_improvement": stringListSchema,
+	}),
+	check: func(e *models.OpenAIWritingEvaluation) error {
+		c := e.CriteriaScores
+		if err := ielts.ValidateWritingCriteria(c.TaskAchievement, c.CoherenceCohesion, c.LexicalResource, c.GrammaticalRange); err != nil {
+			return err
+		}
+		overall := ielts.CalculateWritingBand(c.TaskAchievement, c.CoherenceCohesion, c.LexicalResource, c.GrammaticalRange)
+		if overall != e.OverallBand {
+			log.Printf("⚠️ Model gave writing overall band %.1f, criteria average to %.1f; using %.1f", e.OverallBand, overall, overall)
+		}
+		e.OverallBand = overall
+		return nil
+	},
+}
+
+var speakingContract = evaluationContract[models.OpenAISpeakingEvaluation]{
+	name: "ielts_speaking_evaluation",
+	schema: objectSchema(map[string]interface{}{
+		"overall_band": bandSchema,
+		"criteria_scores": objectSchema(map[string]interface{}{
+			"fluency_coherence": bandSchema,
+			"lexical_resource":  bandSchema,
+			"grammatical_range": bandSchema,
+			"pronunciation":     bandSchema,
+		}),
+		"detailed_feedback": objectSchema(map[string]interface{}{
+			"fluency_coherence": criterionAnalysisSchema,
+			"lexical_resource":  criterionAnalysisSchema,
+			"grammatical_range": criterionAnalysisSchema,
+			"pronunciation":     criterionAnalysisSchema,
+		}),
+		"examiner_feedback":     stringSchema,
+		"strengths":             stringListSchema,
+		"areas_for_improvement": stringListSchema,
+	}),
+	check: func(e *models.OpenAISpeakingEvaluation) error {
+		c := e.CriteriaScores
+		if err := ielts.ValidateSpeakingCriteria(c.FluencyCoherence, c.LexicalResource, c.GrammaticalRange, c.Pronunciation); err != nil {
+			return err
+		}
+		// The per-criterion feedback repeats the score; keep it consistent with the criteria
+		d := &e.DetailedFeedback
+		d.FluencyCoherence.Score, d.LexicalResource.Score = c.FluencyCoherence, c.LexicalResource
+		d.GrammaticalRange.Score, d.Pronunciation.Score = c.GrammaticalRange, c.Pronunciation
+
+		overall := ielts.CalculateSpeakingBand(c.FluencyCoherence, c.LexicalResource, c.GrammaticalRange, c.Pronunciation)
+		if overall != e.OverallBand {
+			log.Printf("⚠️ Model gave speaking overall band %.1f, criteria average to %.1f; using %.1f", e.OverallBand, overall, overall)
+		}
+		e.OverallBand = overall
+		return nil
+	},
+}
+
+var (
+	bandSchema       = map[string]interface{}{"type": "number", "description": "IELTS band from 0 to 9 in steps of 0.5"}
+	stringSchema     = map[string]interface{}{"type": "string"}
+	stringListSchema = map[string]interface{}{"type": "array", "items": stringSchema}
+	bilingualSchema  = objectSchema(map[string]interface{}{
+		"vi": stringSchema,
+		"en": stringSchema,
+	})
+	criterionAnalysisSchema = objectSchema(map[string]interface{}{
+		"score":    bandSchema,
+		"analysis": stringSchema,
+	})
+)
+
+// objectSchema is an object that requires all of its properties and allows no others
+func objectSchema(properties map[string]interface{}) map[string]interface{} {
+	required := make([]string, 0, len(properties))
+	for name := range properties {
+		required = append(required, name)
+	}
+	sort.Strings(required)
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// responseFormat asks the server to constrain output to the contract's schema
+func (c evaluationContract[T]) responseFormat() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "json_schema",
+		"json_schema": map[string]interface{}{
+			"name":   c.name,
+			"schema": c.schema,
+			"strict": true,
+		},
+	}
+}
+
+// parse decodes model output into an evaluation, checking it against the schema and the
+// band rules. Code fences and text around the JSON object are ignored.
+func (c evaluationContract[T]) parse(content string) (*T, error) {
+	raw := extractJSONObject(content)
+	if raw == "" {
+		return nil, errors.New("response contains no JSON object")
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	if problems := validateSchema(value, c.schema, ""); len(problems) > 0 {
+		return nil, fmt.Errorf("response does not match the schema: %s", strings.Join(problems, "; "))
+	}
+
+	result := new(T)
+	if err := json.Unmarshal([]byte(raw), result); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	if err := c.check(result); err != nil {
+		return nil, fmt.Errorf("invalid band scores: %w", err)
+	}
+	return result, nil
+}
+
+// extractJSONObject returns the outermost {...} of the content, or "" if there is none
+func extractJSONObject(content string) string {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return ""
+	}
+	return content[start : end+1]
+}
+
+// validateSchema checks types and required properties, the subset of JSON Schema the
+// evaluation contracts use. Returns one problem per mismatch, with its path.
+func validateSchema(value interface{}, schema map[string]interface{}, path string) []string {
+	where := path
+	if where == "" {
+		where = "response"
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{where + " must be an object"}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		var problems []string
+		for _, name := range schema["required"].([]string) {
+			if _, ok := obj[name]; !ok {
+				problems = append(problems, joinPath(path, name)+" is missing")
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if sub, ok := properties[name].(map[string]interface{}); ok {
+				problems = append(problems, validateSchema(obj[name], sub, joinPath(path, name))...)
+			}
+		}
+		return problems
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{where + " must be an array"}
+		}
+		var problems []string
+		for i, item := range items {
+			problems = append(problems, validateSchema(item, schema["items"].(map[string]interface{}), fmt.Sprintf("%s[%d]", where, i))...)
+		}
+		return problems
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return []string{where + " must be a number"}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return []string{where + " must be a string"}
+		}
+	}
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// requestEvaluation asks the chat model for an evaluation matching the contract. An answer
+// that fails validation is sent back with the problems found, up to maxRepairAttempts times.
+func requestEvaluation[T any](c *OpenAIClient, systemPrompt, userPrompt string, contract evaluationContract[T]) (*T, error) {
+	messages := []chatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: userPrompt},
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRepairAttempts; attempt++ {
+		content, finishReason, err := c.chatCompletion(messages, contract.responseFormat())
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := contract.parse(content)
+		if err == nil {
+			if attempt > 0 {
+				log.Printf("✅ %s evaluation repaired after %d attempt(s)", c.ProviderName, attempt)
+			}
+			return result, nil
+		}
+		if finishReason == "length" {
+			err = fmt.Errorf("%w (the response was cut off; keep the feedback shorter)", err)
+		}
+		lastErr = err
+		log.Printf("⚠️ %s returned an invalid evaluation (attempt %d/%d): %v", c.ProviderName, attempt+1, maxRepairAttempts+1, err)
+
+		messages = append(messages,
+			chatMessage{Role: "assistant", Content: content},
+			chatMessage{Role: "user", Content: fmt.Sprintf(
+				"Your previous response was invalid: %v.\nReturn only the corrected JSON object, following the required structure exactly. Every band must be between 0 and 9 in steps of 0.5.", err)},
+		)
+	}
+	return nil, fmt.Errorf("invalid evaluation after %d attempts: %w", maxRepairAttempts+1, lastErr)
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readRecorded(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "evaluations", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseRecordedWritingResponses(t *testing.T) {
+	tests := []struct {
+		file        string
+		wantErr     string
+		wantOverall float64
+	}{
+		{"writing_wrong_overall.json", "", 6.5}, // Model said 7.0; the criteria average to 6.375
+		{"writing_fenced.txt", "", 6.5},
+		{"writing_truncated.json", "not valid JSON", 0},
+		{"writing_off_grid.json", "task achievement", 0},
+		{"writing_out_of_range.json", "lexical resource", 0},
+		{"writing_missing_criterion.json", "criteria_scores.grammatical_range is missing", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			eval, err := writingContract.parse(readRecorded(t, tt.file))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if eval.OverallBand != tt.wantOverall {
+				t.Errorf("overall band = %.1f, want %.1f", eval.OverallBand, tt.wantOverall)
+			}
+		})
+	}
+}
+
+func TestParseRecordedSpeakingResponses(t *testing.T) {
+	if _, err := speakingContract.parse(readRecorded(t, "speaking_string_band.json")); err == nil ||
+		!strings.Contains(err.Error(), "criteria_scores.fluency_coherence must be a number") {
+		t.Errorf("string band: err = %v", err)
+	}
+
+	eval, err := speakingContract.parse(readRecorded(t, "speaking_valid.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 6.5, 6.0, 5.5, 6.0 average to exactly 6.0; the feedback's stray 5.0 follows the criterion
+	if eval.OverallBand != 6.0 || eval.DetailedFeedback.GrammaticalRange.Score != 5.5 {
+		t.Errorf("overall = %.1f, grammar feedback score = %.1f", eval.OverallBand, eval.DetailedFeedback.GrammaticalRange.Score)
+	}
+}
+
+// chatServer replies to each chat completion with the next recorded response
+func chatServer(t *testing.T, replies ...string) (*httptest.Server, *[][]chatMessage) {
+	t.Helper()
+	var requests [][]chatMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []chatMessage `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		requests = append(requests, body.Messages)
+
+		reply := replies[min(len(requests), len(replies))-1]
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": reply}, "finish_reason": "stop"},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestEvaluationRepair(t *testing.T) {
+	server, requests := chatServer(t, readRecorded(t, "writing_off_grid.json"), readRecorded(t, "writing_wrong_overall.json"))
+	client := NewOpenAICompatibleClient("test", server.URL, "", "test-model", "")
+
+	eval, err := client.EvaluateWriting(WritingInput{TaskType: "task2", PromptText: "Prompt", EssayText: "Essay"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eval.OverallBand != 6.5 || len(*requests) != 2 {
+		t.Fatalf("overall = %.1f after %d requests", eval.OverallBand, len(*requests))
+	}
+	// The repair request carries the bad answer and what was wrong with it
+	repair := (*requests)[1]
+	if len(repair) != 4 || repair[2].Role != "assistant" || !strings.Contains(repair[3].Content, "task achievement") {
+		t.Errorf("repair conversation = %+v", repair)
+	}
+}
+
+func TestEvaluationRepairGivesUp(t *testing.T) {
+	server, requests := chatServer(t, readRecorded(t, "writing_truncated.json"))
+	client := NewOpenAICompatibleClient("test", server.URL, "", "test-model", "")
+
+	_, err := client.EvaluateWriting(WritingInput{EssayText: "Essay"})
+	if err == nil || !strings.Contains(err.Error(), "invalid evaluation after 3 attempts") {
+		t.Fatalf("err = %v", err)
+	}
+	if len(*requests) != maxRepairAttempts+1 {
+		t.Errorf("requests = %d, want %d", len(*requests), maxRepairAttempts+1)
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+	"github.com/bisosad1501/DATN/shared/pkg/textanalysis"
+)
+
+// textAnalysisPrompt presents the measured text analytics to the examiner model, so that
+// length and range judgements rest on counts rather than impressions
+func textAnalysisPrompt(taskType string, a *textanalysis.Analysis) string {
+	if a == nil || a.WordCount == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\nTEXT ANALYTICS (measured from the essay, not estimated):\n")
+	fmt.Fprintf(&b, "- Words: %d (minimum for this task: %d); sentences: %d; paragraphs: %d\n",
+		a.WordCount, ielts.MinimumWritingWords(taskType), a.SentenceCount, a.ParagraphCount)
+	fmt.Fprintf(&b, "- Sentence length: mean %.1f words, shortest %d, longest %d (variation %.2f)\n",
+		a.SentenceLength.Mean, a.SentenceLength.Shortest, a.SentenceLength.Longest, a.SentenceLength.Variation)
+	fmt.Fprintf(&b, "- Type-token ratio: %.2f; lexical density: %.2f\n", a.TypeTokenRatio, a.LexicalDensity)
+	fmt.Fprintf(&b, "- Academic Word List coverage: %.1f%% (%d families)\n", a.AcademicWordCoverage*100, len(a.AcademicWords))
+	if a.LinkingDeviceCount > 0 {
+		fmt.Fprintf(&b, "- Linking devices: %d uses (%s)\n", a.LinkingDeviceCount, linkingDeviceSummary(a.LinkingDevices))
+	}
+	if len(a.RepeatedPhrases) > 0 {
+		phrases := make([]string, len(a.RepeatedPhrases))
+		for i, p := range a.RepeatedPhrases {
+			phrases[i] = fmt.Sprintf("%q ×%d", p.Phrase, p.Count)
+		}
+		fmt.Fprintf(&b, "- Repeated phrases: %s\n", strings.Join(phrases, ", "))
+	}
+	b.WriteString("Use the word count as given. Essays under the minimum are penalised in Task Achievement / Task Response. ")
+	b.WriteString("Treat the other figures as evidence for Lexical Resource, Coherence and Cohesion and Grammatical Range; overuse of the same linking devices or phrases is a weakness.\n")
+	return b.String()
+}
+
+// linkingDeviceSummary lists devices by use, most used first
+func linkingDeviceSummary(devices map[string]int) string {
+	names := make([]string, 0, len(devices))
+	for name := range devices {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if devices[names[i]] != devices[names[j]] {
+			return devices[names[i]] > devices[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s ×%d", name, devices[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// applyLengthPenalty caps Task Achievement for essays under the minimum length, whatever
+// the evaluator gave, and recomputes the overall band
+func applyLengthPenalty(eval *models.OpenAIWritingEvaluation, taskType string, wordCount int) {
+	maxBand := ielts.MaxTaskBandForLength(taskType, wordCount)
+	c := &eval.CriteriaScores
+	if c.TaskAchievement <= maxBand {
+		return
+	}
+
+	log.Printf("✂️ [AI Service] Essay has %d words (minimum %d): Task Achievement capped %.1f → %.1f",
+		wordCount, ielts.MinimumWritingWords(taskType), c.TaskAchievement, maxBand)
+	c.TaskAchievement = maxBand
+	eval.OverallBand = ielts.CalculateWritingBand(c.TaskAchievement, c.CoherenceCohesion, c.LexicalResource, c.GrammaticalRange)
+	eval.AreasForImprovement = append(eval.AreasForImprovement, fmt.Sprintf(
+		"Bài viết chỉ có %d từ, dưới mức tối thiểu %d từ, nên điểm Task Achievement bị giới hạn ở %.1f. Hãy viết đủ số từ yêu cầu.",
+		wordCount, ielts.MinimumWritingWords(taskType), maxBand))
+}
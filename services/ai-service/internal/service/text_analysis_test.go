@@ -0,0 +1,44 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
+	"github.com/bisosad1501/DATN/shared/pkg/textanalysis"
+)
+
+func TestApplyLengthPenalty(t *testing.T) {
+	eval := &models.OpenAIWritingEvaluation{OverallBand: 7.0}
+	c := &eval.CriteriaScores
+	c.TaskAchievement, c.CoherenceCohesion, c.LexicalResource, c.GrammaticalRange = 7, 7, 7, 7
+
+	applyLengthPenalty(eval, "task2", 260)
+	if c.TaskAchievement != 7 || eval.OverallBand != 7 || len(eval.AreasForImprovement) != 0 {
+		t.Fatalf("full-length essay changed: %+v", eval)
+	}
+
+	// 200 words is 80% of the Task 2 minimum: Task Response is capped at 6.0
+	applyLengthPenalty(eval, "task2", 200)
+	if c.TaskAchievement != 6 || eval.OverallBand != 7 || len(eval.AreasForImprovement) != 1 {
+		t.Errorf("after cap: task %.1f, overall %.1f, advice %v", c.TaskAchievement, eval.OverallBand, eval.AreasForImprovement)
+	}
+
+	applyLengthPenalty(eval, "task2", 90)
+	if c.TaskAchievement != 3 || eval.OverallBand != 6 {
+		t.Errorf("very short essay: task %.1f, overall %.1f", c.TaskAchievement, eval.OverallBand)
+	}
+}
+
+func TestTextAnalysisPrompt(t *testing.T) {
+	a := textanalysis.Analyze("However, cities grow. However, towns shrink. Moreover, villages vanish.")
+	prompt := textAnalysisPrompt("task1", a)
+	for _, want := range []string{"Words: 9 (minimum for this task: 150)", "sentences: 3", "Linking devices: 3 uses (however ×2, moreover ×1)"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if textAnalysisPrompt("task2", nil) != "" {
+		t.Error("nil analysis should add nothing to the prompt")
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"mime"
 	"strings"
+
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+	"github.com/bisosad1501/DATN/shared/pkg/textanalysis"
 )
 
 // Audio validation constants
@@ -37,8 +40,8 @@ var AllowedExtensions = []string{
 
 // Writing validation constants
 const (
-	MinWordCountTask1 = 150 // IELTS Writing Task 1 minimum
-	MinWordCountTask2 = 250 // IELTS Writing Task 2 minimum
+	MinWordCountTask1 = ielts.MinWordsWritingTask1 // IELTS Writing Task 1 minimum
+	MinWordCountTask2 = ielts.MinWordsWritingTask2 // IELTS Writing Task 2 minimum
 	MaxWordCount      = 10000 // Maximum to prevent spam
 )
 
@@ -74,7 +77,8 @@ func ValidateAudioFile(fileSizeBytes int64, durationSeconds int, fileName string
 	return nil
 }
 
-// ValidateWritingSubmission validates writing submission text and word count
+// ValidateWritingSubmission validates writing submission text and word count. Essays under
+// the task minimum are accepted: the length is penalised when scoring, as in the real test.
 func ValidateWritingSubmission(taskType string, essayText string) error {
 	// Validate essay length
 	if len(essayText) > MaxEssayLength {
@@ -82,24 +86,16 @@ func ValidateWritingSubmission(taskType string, essayText string) error {
 			len(essayText), MaxEssayLength)
 	}
 
-	// Calculate word count
-	words := strings.Fields(essayText)
-	wordCount := len(words)
-
-	// Validate word count based on task type
-	var minWords int
-	switch taskType {
-	case "task1":
-		minWords = MinWordCountTask1
-	case "task2":
-		minWords = MinWordCountTask2
-	default:
+	// Calculate word count the way IELTS counts it
+	wordCount := textanalysis.CountWords(essayText)
+
+	// Validate task type
+	if taskType != "task1" && taskType != "task2" {
 		return fmt.Errorf("invalid task type: %s. Must be 'task1' or 'task2'", taskType)
 	}
 
-	if wordCount < minWords {
-		return fmt.Errorf("essay word count (%d) is below minimum required for %s (%d words)", 
-			wordCount, taskType, minWords)
+	if wordCount == 0 {
+		return fmt.Errorf("essay contains no words")
 	}
 
 	if wordCount > MaxWordCount {
@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/bisosad1501/DATN/services/auth-service/internal/config"
 	"github.com/bisosad1501/DATN/services/auth-service/internal/database"
@@ -23,6 +24,9 @@ func main() {
 
 	// Load configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	// Initialize database connection
 	db, err := database.NewPostgresConnection(cfg)
@@ -42,6 +46,8 @@ func main() {
 	userRepo := repository.NewUserRepository(db)
 	roleRepo := repository.NewRoleRepository(db)
 	tokenRepo := repository.NewTokenRepository(db)
+	revocationRepo := repository.NewRevocationRepository(db)
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
 	auditRepo := repository.NewAuditLogRepository(db)
 	passwordResetRepo := repository.NewPasswordResetRepository(db)
 	emailVerificationRepo := repository.NewEmailVerificationRepository(db)
@@ -60,11 +66,33 @@ func main() {
 	userServiceClient := client.NewUserServiceClient(cfg.UserServiceURL, cfg.InternalAPIKey)
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, roleRepo, tokenRepo, auditRepo, passwordResetRepo, emailVerificationRepo, emailService, redisClient, cfg)
+	authService := service.NewAuthService(userRepo, roleRepo, tokenRepo, revocationRepo, twoFactorRepo, auditRepo, passwordResetRepo, emailVerificationRepo, emailService, redisClient, cfg)
 	googleOAuthService := service.NewGoogleOAuthService(cfg, userRepo, roleRepo, tokenRepo, auditRepo, authService, userServiceClient)
+	rbacService := service.NewRBACService(roleRepo, userRepo, auditRepo, authService)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService, googleOAuthService)
+	adminHandler := handlers.NewAdminHandler(rbacService, authService)
+
+	// Keep ValidateToken's revocation cache in step with other instances
+	go authService.StartRevocationSync()
+
+	// Drop expired refresh tokens and access token revocations
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := tokenRepo.CleanupExpiredTokens(); err != nil {
+				log.Printf("Token cleanup failed: %v", err)
+			}
+			if err := revocationRepo.DeleteExpiredRevocations(); err != nil {
+				log.Printf("Revocation cleanup failed: %v", err)
+			}
+			if err := twoFactorRepo.DeleteExpiredChallenges(); err != nil {
+				log.Printf("Two-factor challenge cleanup failed: %v", err)
+			}
+		}
+	}()
 
 	// Setup Gin router
 	if cfg.AppEnv == "production" {
@@ -73,7 +101,7 @@ func main() {
 	router := gin.Default()
 
 	// Setup routes
-	routes.SetupRoutes(router, authHandler, authService)
+	routes.SetupRoutes(router, authHandler, adminHandler, authService, cfg.InternalAPIKey)
 
 	// Start server
 	port := os.Getenv("PORT")
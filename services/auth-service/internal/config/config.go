@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -30,6 +32,12 @@ type Config struct {
 	MaxLoginAttempts    int
 	AccountLockDuration int // minutes
 
+	// Two-factor authentication
+	TwoFactorRequiredRoles []string // Roles that must pass a second factor on login
+	TwoFactorEncryptionKey string   // Encrypts TOTP secrets at rest; required and separate from JWTSecret
+	TwoFactorChallengeTTL  string
+	TwoFactorIssuer        string // Shown in authenticator apps
+
 	// Google OAuth
 	GoogleClientID     string
 	GoogleClientSecret string
@@ -74,6 +82,11 @@ func Load() *Config {
 		MaxLoginAttempts:    maxLoginAttempts,
 		AccountLockDuration: lockDuration,
 
+		TwoFactorRequiredRoles: strings.Split(getEnv("TWO_FACTOR_REQUIRED_ROLES", "admin,instructor"), ","),
+		TwoFactorEncryptionKey: getEnv("TWO_FACTOR_ENCRYPTION_KEY", ""),
+		TwoFactorChallengeTTL:  getEnv("TWO_FACTOR_CHALLENGE_TTL", "5m"),
+		TwoFactorIssuer:        getEnv("TWO_FACTOR_ISSUER", "IELTSGo"),
+
 		GoogleClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
 		GoogleClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
 		GoogleRedirectURL:  getEnv("GOOGLE_REDIRECT_URL", "http://localhost:8080/api/v1/auth/google/callback"),
@@ -91,6 +104,18 @@ func Load() *Config {
 	}
 }
 
+// Validate rejects configuration the service must not start with
+func (c *Config) Validate() error {
+	// A leaked signing key must not also expose every user's TOTP secret
+	if c.TwoFactorEncryptionKey == "" {
+		return fmt.Errorf("TWO_FACTOR_ENCRYPTION_KEY is required")
+	}
+	if c.TwoFactorEncryptionKey == c.JWTSecret {
+		return fmt.Errorf("TWO_FACTOR_ENCRYPTION_KEY must differ from JWT_SECRET")
+	}
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AdminHandler exposes role, permission and user role management (requires rbac:manage)
+// and account bans (requires user:ban)
+type AdminHandler struct {
+	rbacService service.RBACService
+	authService service.AuthService
+}
+
+func NewAdminHandler(rbacService service.RBACService, authService service.AuthService) *AdminHandler {
+	return &AdminHandler{rbacService: rbacService, authService: authService}
+}
+
+// ListRoles lists all roles
+func (h *AdminHandler) ListRoles(c *gin.Context) {
+	roles, err := h.rbacService.ListRoles()
+	if err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Success: true, Data: roles})
+}
+
+// CreateRole creates a role
+func (h *AdminHandler) CreateRole(c *gin.Context) {
+	var req models.CreateRoleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	role, err := h.rbacService.CreateRole(&req)
+	if err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, models.SuccessResponse{Success: true, Data: role, Message: "Role created"})
+}
+
+// UpdateRole updates a role's display name and description
+func (h *AdminHandler) UpdateRole(c *gin.Context) {
+	roleID, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	var req models.UpdateRoleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	role, err := h.rbacService.UpdateRole(roleID, &req)
+	if err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Success: true, Data: role, Message: "Role updated"})
+}
+
+// DeleteRole deletes a custom role
+func (h *AdminHandler) DeleteRole(c *gin.Context) {
+	roleID, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.rbacService.DeleteRole(roleID); err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Success: true, Message: "Role deleted"})
+}
+
+// ListPermissions lists all permissions
+func (h *AdminHandler) ListPermissions(c *gin.Context) {
+	permissions, err := h.rbacService.ListPermissions()
+	if err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Success: true, Data: permissions})
+}
+
+// CreatePermission creates a permission
+func (h *AdminHandler) CreatePermission(c *gin.Context) {
+	var req models.CreatePermissionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	permission, err := h.rbacService.CreatePermission(&req)
+	if err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, models.SuccessResponse{Success: true, Data: permission, Message: "Permission created"})
+}
+
+// DeletePermission deletes a permission and its role grants
+func (h *AdminHandler) DeletePermission(c *gin.Context) {
+	permissionID, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.rbacService.DeletePermission(permissionID); err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Success: true, Message: "Permission deleted"})
+}
+
+// GetRolePermissions lists the permissions granted to a role
+func (h *AdminHandler) GetRolePermissions(c *gin.Context) {
+	roleID, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+
+	permissions, err := h.rbacService.GetRolePermissions(roleID)
+	if err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Success: true, Data: permissions})
+}
+
+// AddPermissionToRole grants a permission to a role
+func (h *AdminHandler) AddPermissionToRole(c *gin.Context) {
+	roleID, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	var req models.RolePermissionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.rbacService.AddPermissionToRole(roleID, req.PermissionID); err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Success: true, Message: "Permission granted"})
+}
+
+// RemovePermissionFromRole revokes a permission from a role
+func (h *AdminHandler) RemovePermissionFromRole(c *gin.Context) {
+	roleID, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	permissionID, ok := intParam(c, "permission_id")
+	if !ok {
+		return
+	}
+
+	if err := h.rbacService.RemovePermissionFromRole(roleID, permissionID); err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Success: true, Message: "Permission revoked"})
+}
+
+// GetUserRoles lists a user's roles
+func (h *AdminHandler) GetUserRoles(c *gin.Context) {
+	userID, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	roles, err := h.rbacService.GetUserRoles(userID)
+	if err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Success: true, Data: roles})
+}
+
+// AssignRoleToUser assigns a role to a user
+func (h *AdminHandler) AssignRoleToUser(c *gin.Context) {
+	userID, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+	var req models.UserRoleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.rbacService.AssignRoleToUser(userID, req.RoleID, currentUserID(c)); err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Success: true, Message: "Role assigned"})
+}
+
+// RemoveRoleFromUser removes a role from a user
+func (h *AdminHandler) RemoveRoleFromUser(c *gin.Context) {
+	userID, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+	roleID, ok := intParam(c, "role_id")
+	if !ok {
+		return
+	}
+
+	if err := h.rbacService.RemoveRoleFromUser(userID, roleID, currentUserID(c)); err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Success: true, Message: "Role removed"})
+}
+
+// BanUser deactivates a user account and revokes all of its tokens
+func (h *AdminHandler) BanUser(c *gin.Context) {
+	userID, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+	var req models.BanUserRequest
+	if c.Request.ContentLength > 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.authService.BanUser(userID, currentUserID(c), req.Reason); err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Success: true, Message: "User banned"})
+}
+
+// UnbanUser reactivates a banned user account
+func (h *AdminHandler) UnbanUser(c *gin.Context) {
+	userID, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.authService.UnbanUser(userID, currentUserID(c)); err != nil {
+		respondRBACError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Success: true, Message: "User unbanned"})
+}
+
+func bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "VALIDATION_ERROR",
+				Message: err.Error(),
+			},
+		})
+		return false
+	}
+	return true
+}
+
+func intParam(c *gin.Context, name string) (int, bool) {
+	value, err := strconv.Atoi(c.Param(name))
+	if err != nil || value < 1 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INVALID_ID",
+				Message: "Invalid " + name,
+			},
+		})
+		return 0, false
+	}
+	return value, true
+}
+
+func uuidParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	value, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INVALID_USER_ID",
+				Message: "Invalid user ID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return value, true
+}
+
+// currentUserID returns the authenticated admin's ID (set by AuthMiddleware)
+func currentUserID(c *gin.Context) uuid.UUID {
+	userID, _ := c.Get("user_id")
+	id, _ := uuid.Parse(userID.(string))
+	return id
+}
+
+// respondRBACError maps RBAC service errors to responses. Other errors are logged, and the
+// client gets a generic message rather than database error text.
+func respondRBACError(c *gin.Context, err error) {
+	status, code, message := http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to update roles and permissions"
+	switch {
+	case errors.Is(err, service.ErrRoleNotFound), errors.Is(err, service.ErrPermissionNotFound), errors.Is(err, service.ErrUserNotFound):
+		status, code, message = http.StatusNotFound, "NOT_FOUND", err.Error()
+	case errors.Is(err, service.ErrBuiltInRole), errors.Is(err, service.ErrLastUserRole):
+		status, code, message = http.StatusConflict, "CONFLICT", err.Error()
+	case strings.Contains(err.Error(), "duplicate key"):
+		status, code, message = http.StatusConflict, "ALREADY_EXISTS", "A role or permission with this name already exists"
+	default:
+		log.Printf("[Admin] ERROR: %s %s: %v", c.Request.Method, c.FullPath(), err)
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
 	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
@@ -228,8 +231,10 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	}
 
 	userUUID, _ := uuid.Parse(userID.(string))
+	claims, _ := c.Get("claims")
+	accessToken, _ := claims.(*service.TokenClaims)
 
-	if err := h.authService.Logout(userUUID, req.RefreshToken); err != nil {
+	if err := h.authService.Logout(userUUID, req.RefreshToken, accessToken); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Success: false,
 			Error: &models.ErrorData{
@@ -538,7 +543,7 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 	log.Printf("[GoogleCallback] ✅ User authenticated successfully")
 	log.Printf("[GoogleCallback] 🔍 authResp.Success = %v", authResp.Success)
 	log.Printf("[GoogleCallback] 🔍 authResp.Data != nil = %v", authResp.Data != nil)
-	if authResp.Data != nil {
+	if authResp.Data != nil && len(authResp.Data.AccessToken) >= 20 {
 		log.Printf("[GoogleCallback] 🔍 authResp.Data.AccessToken = %s...", authResp.Data.AccessToken[:20])
 		log.Printf("[GoogleCallback] 🔍 authResp.Data.UserID = %s", authResp.Data.UserID)
 	}
@@ -550,7 +555,18 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 		frontendURL = "http://localhost:3000"
 	}
 
-	if authResp.Success && authResp.Data != nil {
+	if authResp.Success && authResp.Data != nil && authResp.Data.TwoFactorRequired {
+		// Second factor required: frontend completes the login with POST /auth/2fa/verify
+		redirectURL := fmt.Sprintf(
+			"%s/auth/google/callback?two_factor_required=true&challenge_token=%s&methods=%s&email=%s",
+			frontendURL,
+			authResp.Data.ChallengeToken,
+			strings.Join(authResp.Data.TwoFactorMethods, ","),
+			authResp.Data.Email,
+		)
+		log.Printf("[GoogleCallback] 🔐 Two-factor authentication required, redirecting to frontend")
+		c.Redirect(http.StatusTemporaryRedirect, redirectURL)
+	} else if authResp.Success && authResp.Data != nil {
 		// Success: Redirect to frontend callback with tokens
 		redirectURL := fmt.Sprintf(
 			"%s/auth/google/callback?success=true&access_token=%s&refresh_token=%s&user_id=%s&email=%s&role=%s",
@@ -915,3 +931,197 @@ func (h *AuthHandler) VerifyEmailByCode(c *gin.Context) {
 		Message: "Email verified successfully",
 	})
 }
+
+// GetUserContactInternal returns the email address of a user for other services
+// (e.g. user-service weekly reports). Internal API key required.
+func (h *AuthHandler) GetUserContactInternal(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INVALID_USER_ID",
+				Message: "Invalid user ID",
+			},
+		})
+		return
+	}
+
+	user, err := h.authService.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "USER_NOT_FOUND",
+				Message: "User not found",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"user_id":     user.ID,
+			"email":       user.Email,
+			"is_active":   user.IsActive,
+			"is_verified": user.IsVerified,
+		},
+	})
+}
+
+// ListSessions godoc
+// @Summary List sessions
+// @Description List the devices the user is signed in on
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse
+// @Router /auth/sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	sessions, err := h.authService.ListSessions(userID, currentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to list sessions",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    sessions,
+	})
+}
+
+// RevokeSession godoc
+// @Summary Revoke session
+// @Description Sign out one device
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INVALID_SESSION_ID",
+				Message: "Invalid session ID",
+			},
+		})
+		return
+	}
+
+	if err := h.authService.RevokeSession(userID, sessionID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Success: false,
+				Error: &models.ErrorData{
+					Code:    "SESSION_NOT_FOUND",
+					Message: "Session not found",
+				},
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to revoke session",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Session revoked",
+	})
+}
+
+// RevokeOtherSessions godoc
+// @Summary Log out all other devices
+// @Description Sign out every session except the current one
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse
+// @Router /auth/sessions/revoke-others [post]
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	count, err := h.authService.RevokeOtherSessions(userID, currentSessionID(c), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to revoke sessions",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"revoked_sessions": count,
+		},
+		Message: "Signed out of all other devices",
+	})
+}
+
+// currentSessionID returns the session the access token was issued for (uuid.Nil for older tokens)
+func currentSessionID(c *gin.Context) uuid.UUID {
+	sessionID, _ := uuid.Parse(c.GetString("session_id"))
+	return sessionID
+}
+
+// GetRevocationsInternal returns access token revocations recorded after the `since` query
+// parameter (RFC 3339, empty for a full sync). Polled by the API gateway. Internal API key required.
+func (h *AuthHandler) GetRevocationsInternal(c *gin.Context) {
+	var since time.Time
+	if value := c.Query("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Success: false,
+				Error: &models.ErrorData{
+					Code:    "INVALID_SINCE",
+					Message: "since must be an RFC 3339 timestamp",
+				},
+			})
+			return
+		}
+		since = parsed
+	}
+
+	revocations, err := h.authService.GetRevocationsSince(since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to load revocations",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    revocations,
+	})
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// VerifyTwoFactor godoc
+// @Summary Complete login with a second factor
+// @Description Exchange the challenge token returned by login and a TOTP, email or recovery code for tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorVerifyRequest true "Verification request"
+// @Success 200 {object} models.AuthResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/2fa/verify [post]
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req models.TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	response, err := h.authService.VerifyTwoFactor(&req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		log.Printf("[VerifyTwoFactor] ERROR: %v", err)
+		respondTwoFactorInternalError(c, "Failed to verify code")
+		return
+	}
+
+	if !response.Success {
+		statusCode := http.StatusUnauthorized
+		if response.Error.Code == "ACCOUNT_INACTIVE" {
+			statusCode = http.StatusForbidden
+		}
+		c.JSON(statusCode, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SendTwoFactorEmailCode godoc
+// @Summary Email a login code
+// @Description Send a one-time code to the account email for a pending two-factor challenge
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorChallengeRequest true "Challenge"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /auth/2fa/email/send [post]
+func (h *AuthHandler) SendTwoFactorEmailCode(c *gin.Context) {
+	var req models.TwoFactorChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	if err := h.authService.SendTwoFactorEmailCode(req.ChallengeToken, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		respondTwoFactorError(c, err, "Failed to send code")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "A sign-in code has been sent to your email",
+	})
+}
+
+// GetTwoFactorStatus godoc
+// @Summary Get two-factor status
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse{data=models.TwoFactorStatusResponse}
+// @Router /auth/2fa/status [get]
+func (h *AuthHandler) GetTwoFactorStatus(c *gin.Context) {
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	status, err := h.authService.GetTwoFactorStatus(userID)
+	if err != nil {
+		respondTwoFactorInternalError(c, "Failed to load two-factor status")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    status,
+	})
+}
+
+// SetupTOTP godoc
+// @Summary Start authenticator app enrollment
+// @Description Generate a TOTP secret and the otpauth:// URI to render as a QR code. Confirm with /auth/2fa/totp/enable.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse{data=models.TOTPSetupResponse}
+// @Failure 409 {object} models.ErrorResponse
+// @Router /auth/2fa/totp/setup [post]
+func (h *AuthHandler) SetupTOTP(c *gin.Context) {
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	setup, err := h.authService.SetupTOTP(userID)
+	if err != nil {
+		respondTwoFactorError(c, err, "Failed to start TOTP setup")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    setup,
+	})
+}
+
+// EnableTOTP godoc
+// @Summary Confirm authenticator app enrollment
+// @Description Verify the first code from the authenticator app. Recovery codes are returned once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} models.SuccessResponse{data=models.RecoveryCodesResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /auth/2fa/totp/enable [post]
+func (h *AuthHandler) EnableTOTP(c *gin.Context) {
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	codes, err := h.authService.EnableTOTP(userID, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    models.RecoveryCodesResponse{RecoveryCodes: codes},
+		Message: "Two-factor authentication enabled. Store your recovery codes somewhere safe.",
+	})
+}
+
+// DisableTOTP godoc
+// @Summary Disable authenticator app
+// @Description Requires the account password and a current TOTP or recovery code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.DisableTOTPRequest true "Password and code"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /auth/2fa/totp/disable [post]
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	var req models.DisableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	if err := h.authService.DisableTOTP(userID, &req); err != nil {
+		respondTwoFactorError(c, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes. Requires a current TOTP code.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} models.SuccessResponse{data=models.RecoveryCodesResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /auth/2fa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    models.RecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+func respondValidationError(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    "VALIDATION_ERROR",
+			Message: "Invalid request format",
+			Details: map[string]interface{}{
+				"error": err.Error(),
+			},
+		},
+	})
+}
+
+// respondTwoFactorError maps two-factor service errors to status codes
+func respondTwoFactorError(c *gin.Context, err error, fallback string) {
+	var status int
+	var code string
+	switch {
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		status, code = http.StatusBadRequest, "INVALID_2FA_CODE"
+	case errors.Is(err, service.ErrInvalidPassword):
+		status, code = http.StatusBadRequest, "INVALID_PASSWORD"
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		status, code = http.StatusBadRequest, "2FA_NOT_ENABLED"
+	case errors.Is(err, service.ErrTwoFactorSetupMissing):
+		status, code = http.StatusBadRequest, "2FA_SETUP_REQUIRED"
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		status, code = http.StatusConflict, "2FA_ALREADY_ENABLED"
+	case errors.Is(err, service.ErrChallengeNotFound):
+		status, code = http.StatusUnauthorized, "INVALID_CHALLENGE"
+	case errors.Is(err, service.ErrEmailCodeRecentlySent):
+		status, code = http.StatusTooManyRequests, "CODE_RECENTLY_SENT"
+	case errors.Is(err, service.ErrUserNotFound):
+		status, code = http.StatusNotFound, "USER_NOT_FOUND"
+	default:
+		log.Printf("[TwoFactor] ERROR: %v", err)
+		respondTwoFactorInternalError(c, fallback)
+		return
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    code,
+			Message: err.Error(),
+		},
+	})
+}
+
+func respondTwoFactorInternalError(c *gin.Context, message string) {
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    "INTERNAL_ERROR",
+			Message: message,
+		},
+	})
+}
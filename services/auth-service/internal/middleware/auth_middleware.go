@@ -57,6 +57,9 @@ func AuthMiddleware(authService service.AuthService) gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
 		c.Set("role", claims.Role)
+		c.Set("permissions", claims.Permissions)
+		c.Set("session_id", claims.SessionID)
+		c.Set("claims", claims)
 
 		c.Next()
 	}
@@ -102,3 +105,61 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequirePermission checks that the token grants at least one of the given permissions
+func RequirePermission(requiredPermissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, _ := c.Get("permissions")
+		permissions, _ := granted.([]string)
+
+		for _, required := range requiredPermissions {
+			for _, p := range permissions {
+				if p == required {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "FORBIDDEN",
+				Message: "Insufficient permissions",
+			},
+		})
+		c.Abort()
+	}
+}
+
+// InternalAuth validates the internal API key for service-to-service calls
+func InternalAuth(internalAPIKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader("X-Internal-API-Key")
+		if apiKey == "" {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Success: false,
+				Error: &models.ErrorData{
+					Code:    "MISSING_API_KEY",
+					Message: "Internal API key required",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		if apiKey != internalAPIKey {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Success: false,
+				Error: &models.ErrorData{
+					Code:    "INVALID_API_KEY",
+					Message: "Invalid internal API key",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		permissions interface{} // nil leaves the context key unset
+		required    []string
+		wantStatus  int
+	}{
+		{"granted", []string{"exercises:read", "users:manage"}, []string{"users:manage"}, http.StatusOK},
+		{"any of several", []string{"exercises:read"}, []string{"users:manage", "exercises:read"}, http.StatusOK},
+		{"missing", []string{"exercises:read"}, []string{"users:manage"}, http.StatusForbidden},
+		{"no permissions in token", []string{}, []string{"users:manage"}, http.StatusForbidden},
+		{"unauthenticated", nil, []string{"users:manage"}, http.StatusForbidden},
+		{"wrong type", "users:manage", []string{"users:manage"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/admin",
+				func(c *gin.Context) {
+					if tt.permissions != nil {
+						c.Set("permissions", tt.permissions)
+					}
+				},
+				RequirePermission(tt.required...),
+				func(c *gin.Context) { c.Status(http.StatusOK) },
+			)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
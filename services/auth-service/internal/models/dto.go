@@ -1,11 +1,13 @@
 package models
 
+import "time"
+
 // RegisterRequest represents a registration request
 type RegisterRequest struct {
 	Email           string  `json:"email" binding:"required,email"`
 	Password        string  `json:"password" binding:"required,min=8"`
 	Phone           string  `json:"phone" binding:"omitempty"`
-	Role            string  `json:"role" binding:"required,oneof=student instructor"`
+	Role            string  `json:"role" binding:"omitempty,oneof=student"` // Sign-up creates students; other roles are granted by an admin
 	FullName        string  `json:"fullName" binding:"omitempty"`
 	TargetBandScore float64 `json:"targetBandScore" binding:"omitempty,min=0,max=9"`
 }
@@ -56,6 +58,37 @@ type AuthResponse struct {
 	Error   *ErrorData `json:"error,omitempty"`
 }
 
+// CreateRoleRequest represents a request to create a role
+type CreateRoleRequest struct {
+	Name        string `json:"name" binding:"required,min=2,max=50"`
+	DisplayName string `json:"display_name" binding:"required,max=100"`
+	Description string `json:"description" binding:"omitempty"`
+}
+
+// UpdateRoleRequest represents a request to update a role (the name is immutable)
+type UpdateRoleRequest struct {
+	DisplayName string `json:"display_name" binding:"required,max=100"`
+	Description string `json:"description" binding:"omitempty"`
+}
+
+// CreatePermissionRequest represents a request to create a permission, e.g. name "exercise:publish"
+type CreatePermissionRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Resource    string `json:"resource" binding:"required,max=50"`
+	Action      string `json:"action" binding:"required,max=50"`
+	Description string `json:"description" binding:"omitempty"`
+}
+
+// RolePermissionRequest grants a permission to a role
+type RolePermissionRequest struct {
+	PermissionID int `json:"permission_id" binding:"required,min=1"`
+}
+
+// UserRoleRequest assigns a role to a user
+type UserRoleRequest struct {
+	RoleID int `json:"role_id" binding:"required,min=1"`
+}
+
 // AuthData represents authentication data in response
 type AuthData struct {
 	UserID       string `json:"user_id"`
@@ -64,6 +97,11 @@ type AuthData struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 	ExpiresIn    int64  `json:"expires_in"` // seconds
+
+	// Set instead of the tokens when the login needs a second factor (POST /auth/2fa/verify)
+	TwoFactorRequired bool     `json:"two_factor_required,omitempty"`
+	ChallengeToken    string   `json:"challenge_token,omitempty"`
+	TwoFactorMethods  []string `json:"two_factor_methods,omitempty"` // 'totp', 'email', 'recovery_code'
 }
 
 // ErrorData represents error data in response
@@ -85,3 +123,68 @@ type ErrorResponse struct {
 	Success bool       `json:"success"`
 	Error   *ErrorData `json:"error"`
 }
+
+// SessionResponse represents one signed-in device (a refresh token family)
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceName *string   `json:"device_name,omitempty"`
+	DeviceType *string   `json:"device_type,omitempty"`
+	IPAddress  *string   `json:"ip_address,omitempty"`
+	SignedInAt time.Time `json:"signed_in_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// RevocationsResponse is the access token revocation state synced by the API gateway
+type RevocationsResponse struct {
+	ServerTime    time.Time            `json:"server_time"`
+	RevokedTokens []RevokedAccessToken `json:"revoked_tokens"`
+	TokenCutoffs  []TokenCutoff        `json:"token_cutoffs"`
+}
+
+// BanUserRequest represents a request to ban a user account
+type BanUserRequest struct {
+	Reason string `json:"reason" binding:"omitempty,max=255"`
+}
+
+// TwoFactorVerifyRequest completes a login challenge with a TOTP, email or recovery code
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Method         string `json:"method" binding:"required,oneof=totp email recovery_code"`
+	Code           string `json:"code" binding:"required,max=32"`
+}
+
+// TwoFactorChallengeRequest identifies a login challenge
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+// TwoFactorCodeRequest carries a TOTP code for enrollment changes
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required,len=6"`
+}
+
+// DisableTOTPRequest requires the password and a current code (TOTP or recovery code)
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required,max=32"`
+}
+
+// TOTPSetupResponse is returned when enrollment starts; the URI is rendered as a QR code
+type TOTPSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// TwoFactorStatusResponse describes a user's 2FA state
+type TwoFactorStatusResponse struct {
+	TOTPEnabled            bool `json:"totp_enabled"`
+	Required               bool `json:"required"` // Enforced for the user's role
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// RecoveryCodesResponse returns freshly generated recovery codes (shown once)
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -65,6 +65,7 @@ type RefreshToken struct {
 	ID        uuid.UUID `db:"id" json:"id"`
 	UserID    uuid.UUID `db:"user_id" json:"user_id"`
 	TokenHash string    `db:"token_hash" json:"-"`
+	FamilyID  uuid.UUID `db:"family_id" json:"-"`
 
 	DeviceID   *string `db:"device_id" json:"device_id,omitempty"`
 	DeviceName *string `db:"device_name" json:"device_name,omitempty"`
@@ -122,3 +123,39 @@ type UserWithRoles struct {
 	User
 	Roles []Role `json:"roles"`
 }
+
+// RevokedAccessToken is an access token revoked before it expired
+type RevokedAccessToken struct {
+	JTI       string    `db:"jti" json:"jti"`
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+}
+
+// TokenCutoff rejects every access token of a user issued before InvalidBefore
+type TokenCutoff struct {
+	UserID        uuid.UUID `db:"user_id" json:"user_id"`
+	InvalidBefore time.Time `db:"tokens_invalid_before" json:"invalid_before"`
+}
+
+// UserTwoFactor holds a user's TOTP enrollment
+type UserTwoFactor struct {
+	UserID           uuid.UUID  `db:"user_id" json:"user_id"`
+	TOTPSecret       string     `db:"totp_secret" json:"-"` // Encrypted
+	TOTPEnabled      bool       `db:"totp_enabled" json:"totp_enabled"`
+	TOTPLastUsedStep int64      `db:"totp_last_used_step" json:"-"`
+	EnabledAt        *time.Time `db:"enabled_at" json:"enabled_at,omitempty"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// TwoFactorChallenge is a pending second login step
+type TwoFactorChallenge struct {
+	ID              uuid.UUID  `db:"id" json:"id"`
+	UserID          uuid.UUID  `db:"user_id" json:"user_id"`
+	TokenHash       string     `db:"token_hash" json:"-"`
+	EmailCodeHash   *string    `db:"email_code_hash" json:"-"`
+	EmailCodeSentAt *time.Time `db:"email_code_sent_at" json:"-"`
+	Attempts        int        `db:"attempts" json:"attempts"`
+	ExpiresAt       time.Time  `db:"expires_at" json:"expires_at"`
+	CompletedAt     *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// RevocationRepository stores access token revocations: single tokens by JWT ID and
+// per-user cutoffs that reject every token issued before a point in time
+type RevocationRepository interface {
+	RevokeAccessToken(jti string, userID uuid.UUID, expiresAt time.Time, reason string) error
+	SetTokensInvalidBefore(userID uuid.UUID, at time.Time) error
+	IsAccessTokenRevoked(jti string, userID uuid.UUID, issuedAt time.Time) (bool, error)
+	FindRevokedAccessTokensSince(since time.Time) ([]models.RevokedAccessToken, error)
+	FindTokenCutoffsSince(since time.Time) ([]models.TokenCutoff, error)
+	DeleteExpiredRevocations() error
+}
+
+type revocationRepository struct {
+	db *sqlx.DB
+}
+
+func NewRevocationRepository(db *sqlx.DB) RevocationRepository {
+	return &revocationRepository{db: db}
+}
+
+func (r *revocationRepository) RevokeAccessToken(jti string, userID uuid.UUID, expiresAt time.Time, reason string) error {
+	query := `
+		INSERT INTO revoked_access_tokens (jti, user_id, expires_at, reason, revoked_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	_, err := r.db.Exec(query, jti, userID, expiresAt, reason, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *revocationRepository) SetTokensInvalidBefore(userID uuid.UUID, at time.Time) error {
+	query := `UPDATE users SET tokens_invalid_before = $2, updated_at = $3 WHERE id = $1`
+
+	_, err := r.db.Exec(query, userID, at, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to set token cutoff: %w", err)
+	}
+
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether the token was revoked by ID or issued before the user's cutoff
+func (r *revocationRepository) IsAccessTokenRevoked(jti string, userID uuid.UUID, issuedAt time.Time) (bool, error) {
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM revoked_access_tokens WHERE jti = $1),
+			COALESCE((SELECT tokens_invalid_before > $3 FROM users WHERE id = $2), false)
+	`
+
+	var revoked, cutOff bool
+	err := r.db.QueryRow(query, jti, userID, issuedAt).Scan(&revoked, &cutOff)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+
+	return revoked || cutOff, nil
+}
+
+// FindRevokedAccessTokensSince returns unexpired tokens revoked after since
+func (r *revocationRepository) FindRevokedAccessTokensSince(since time.Time) ([]models.RevokedAccessToken, error) {
+	query := `
+		SELECT jti, expires_at
+		FROM revoked_access_tokens
+		WHERE revoked_at > $1 AND expires_at > $2
+	`
+
+	tokens := []models.RevokedAccessToken{}
+	if err := r.db.Select(&tokens, query, since, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to find revoked access tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// FindTokenCutoffsSince returns user cutoffs set after since
+func (r *revocationRepository) FindTokenCutoffsSince(since time.Time) ([]models.TokenCutoff, error) {
+	query := `
+		SELECT id AS user_id, tokens_invalid_before
+		FROM users
+		WHERE tokens_invalid_before > $1
+	`
+
+	cutoffs := []models.TokenCutoff{}
+	if err := r.db.Select(&cutoffs, query, since); err != nil {
+		return nil, fmt.Errorf("failed to find token cutoffs: %w", err)
+	}
+
+	return cutoffs, nil
+}
+
+func (r *revocationRepository) DeleteExpiredRevocations() error {
+	query := `DELETE FROM revoked_access_tokens WHERE expires_at < $1`
+
+	_, err := r.db.Exec(query, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to cleanup revoked access tokens: %w", err)
+	}
+
+	return nil
+}
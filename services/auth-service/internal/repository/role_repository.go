@@ -11,9 +11,26 @@ import (
 
 type RoleRepository interface {
 	FindByName(name string) (*models.Role, error)
+	FindByID(id int) (*models.Role, error)
 	FindByUserID(userID uuid.UUID) ([]models.Role, error)
+	List() ([]models.Role, error)
+	Create(role *models.Role) error
+	Update(role *models.Role) error
+	Delete(id int) error
 	AssignRoleToUser(userID uuid.UUID, roleID int, assignedBy *uuid.UUID) error
 	RemoveRoleFromUser(userID uuid.UUID, roleID int) error
+
+	// Permissions
+	FindPermissionByID(id int) (*models.Permission, error)
+	ListPermissions() ([]models.Permission, error)
+	CreatePermission(permission *models.Permission) error
+	DeletePermission(id int) error
+	FindPermissionsByRoleID(roleID int) ([]models.Permission, error)
+	AddPermissionToRole(roleID, permissionID int) error
+	RemovePermissionFromRole(roleID, permissionID int) error
+	FindPermissionNamesByUserID(userID uuid.UUID) ([]string, error)
+	FindUserIDsByRoleID(roleID int) ([]uuid.UUID, error)
+	FindUserIDsByPermissionID(permissionID int) ([]uuid.UUID, error)
 }
 
 type roleRepository struct {
@@ -45,6 +62,7 @@ func (r *roleRepository) FindByUserID(userID uuid.UUID) ([]models.Role, error) {
 		FROM roles r
 		INNER JOIN user_roles ur ON r.id = ur.role_id
 		WHERE ur.user_id = $1
+		ORDER BY r.id
 	`
 
 	var roles []models.Role
@@ -81,3 +99,225 @@ func (r *roleRepository) RemoveRoleFromUser(userID uuid.UUID, roleID int) error
 
 	return nil
 }
+
+func (r *roleRepository) FindByID(id int) (*models.Role, error) {
+	query := `SELECT id, name, display_name, description, created_at, updated_at FROM roles WHERE id = $1`
+
+	var role models.Role
+	err := r.db.Get(&role, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("role not found")
+		}
+		return nil, fmt.Errorf("failed to find role: %w", err)
+	}
+
+	return &role, nil
+}
+
+func (r *roleRepository) List() ([]models.Role, error) {
+	query := `SELECT id, name, display_name, description, created_at, updated_at FROM roles ORDER BY id`
+
+	roles := []models.Role{}
+	if err := r.db.Select(&roles, query); err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+func (r *roleRepository) Create(role *models.Role) error {
+	query := `
+		INSERT INTO roles (name, display_name, description)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(query, role.Name, role.DisplayName, role.Description).
+		Scan(&role.ID, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+
+	return nil
+}
+
+func (r *roleRepository) Update(role *models.Role) error {
+	query := `
+		UPDATE roles
+		SET display_name = $2, description = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(query, role.ID, role.DisplayName, role.Description).Scan(&role.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("role not found")
+		}
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	return nil
+}
+
+func (r *roleRepository) Delete(id int) error {
+	query := `DELETE FROM roles WHERE id = $1`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("role not found")
+	}
+
+	return nil
+}
+
+func (r *roleRepository) FindPermissionByID(id int) (*models.Permission, error) {
+	query := `SELECT id, name, resource, action, description, created_at FROM permissions WHERE id = $1`
+
+	var permission models.Permission
+	err := r.db.Get(&permission, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("permission not found")
+		}
+		return nil, fmt.Errorf("failed to find permission: %w", err)
+	}
+
+	return &permission, nil
+}
+
+func (r *roleRepository) ListPermissions() ([]models.Permission, error) {
+	query := `SELECT id, name, resource, action, description, created_at FROM permissions ORDER BY resource, name`
+
+	permissions := []models.Permission{}
+	if err := r.db.Select(&permissions, query); err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+
+	return permissions, nil
+}
+
+func (r *roleRepository) CreatePermission(permission *models.Permission) error {
+	query := `
+		INSERT INTO permissions (name, resource, action, description)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(query, permission.Name, permission.Resource, permission.Action, permission.Description).
+		Scan(&permission.ID, &permission.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create permission: %w", err)
+	}
+
+	return nil
+}
+
+func (r *roleRepository) DeletePermission(id int) error {
+	query := `DELETE FROM permissions WHERE id = $1`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete permission: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("permission not found")
+	}
+
+	return nil
+}
+
+func (r *roleRepository) FindPermissionsByRoleID(roleID int) ([]models.Permission, error) {
+	query := `
+		SELECT p.id, p.name, p.resource, p.action, p.description, p.created_at
+		FROM permissions p
+		INNER JOIN role_permissions rp ON p.id = rp.permission_id
+		WHERE rp.role_id = $1
+		ORDER BY p.resource, p.name
+	`
+
+	permissions := []models.Permission{}
+	if err := r.db.Select(&permissions, query, roleID); err != nil {
+		return nil, fmt.Errorf("failed to find role permissions: %w", err)
+	}
+
+	return permissions, nil
+}
+
+func (r *roleRepository) AddPermissionToRole(roleID, permissionID int) error {
+	query := `
+		INSERT INTO role_permissions (role_id, permission_id)
+		VALUES ($1, $2)
+		ON CONFLICT (role_id, permission_id) DO NOTHING
+	`
+
+	_, err := r.db.Exec(query, roleID, permissionID)
+	if err != nil {
+		return fmt.Errorf("failed to add permission to role: %w", err)
+	}
+
+	return nil
+}
+
+func (r *roleRepository) RemovePermissionFromRole(roleID, permissionID int) error {
+	query := `DELETE FROM role_permissions WHERE role_id = $1 AND permission_id = $2`
+
+	_, err := r.db.Exec(query, roleID, permissionID)
+	if err != nil {
+		return fmt.Errorf("failed to remove permission from role: %w", err)
+	}
+
+	return nil
+}
+
+// FindPermissionNamesByUserID resolves the union of permissions granted by all of the user's roles
+func (r *roleRepository) FindPermissionNamesByUserID(userID uuid.UUID) ([]string, error) {
+	query := `
+		SELECT DISTINCT p.name
+		FROM permissions p
+		INNER JOIN role_permissions rp ON p.id = rp.permission_id
+		INNER JOIN user_roles ur ON ur.role_id = rp.role_id
+		WHERE ur.user_id = $1
+		ORDER BY p.name
+	`
+
+	names := []string{}
+	if err := r.db.Select(&names, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to find user permissions: %w", err)
+	}
+
+	return names, nil
+}
+
+// FindUserIDsByRoleID returns the users holding a role
+func (r *roleRepository) FindUserIDsByRoleID(roleID int) ([]uuid.UUID, error) {
+	query := `SELECT user_id FROM user_roles WHERE role_id = $1`
+
+	userIDs := []uuid.UUID{}
+	if err := r.db.Select(&userIDs, query, roleID); err != nil {
+		return nil, fmt.Errorf("failed to find role users: %w", err)
+	}
+
+	return userIDs, nil
+}
+
+// FindUserIDsByPermissionID returns the users granted a permission by any of their roles
+func (r *roleRepository) FindUserIDsByPermissionID(permissionID int) ([]uuid.UUID, error) {
+	query := `
+		SELECT DISTINCT ur.user_id
+		FROM user_roles ur
+		INNER JOIN role_permissions rp ON rp.role_id = ur.role_id
+		WHERE rp.permission_id = $1
+	`
+
+	userIDs := []uuid.UUID{}
+	if err := r.db.Select(&userIDs, query, permissionID); err != nil {
+		return nil, fmt.Errorf("failed to find permission users: %w", err)
+	}
+
+	return userIDs, nil
+}
@@ -13,9 +13,14 @@ import (
 type TokenRepository interface {
 	CreateRefreshToken(token *models.RefreshToken) error
 	FindRefreshToken(tokenHash string) (*models.RefreshToken, error)
+	FindRefreshTokenIncludingRevoked(tokenHash string) (*models.RefreshToken, error)
 	UpdateLastUsed(tokenID uuid.UUID) error
 	RevokeToken(tokenID uuid.UUID, revokedBy uuid.UUID, reason string) error
 	RevokeAllUserTokens(userID uuid.UUID) error
+	RotateToken(tokenID uuid.UUID) (bool, error)
+	FindActiveSessions(userID uuid.UUID) ([]models.RefreshToken, error)
+	RevokeFamily(userID, familyID uuid.UUID, revokedBy *uuid.UUID, reason string) (bool, error)
+	RevokeUserSessions(userID, exceptFamilyID uuid.UUID, revokedBy *uuid.UUID, reason string) ([]uuid.UUID, error)
 	CleanupExpiredTokens() error
 }
 
@@ -30,10 +35,10 @@ func NewTokenRepository(db *sqlx.DB) TokenRepository {
 func (r *tokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
 	query := `
 		INSERT INTO refresh_tokens (
-			id, user_id, token_hash, device_id, device_name, device_type,
+			id, user_id, token_hash, family_id, device_id, device_name, device_type,
 			user_agent, ip_address, expires_at, created_at, last_used_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at
 	`
 
@@ -41,11 +46,16 @@ func (r *tokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
 	token.ID = uuid.New()
 	token.CreatedAt = now
 	token.LastUsedAt = now
+	// A token without a family starts a new session
+	if token.FamilyID == uuid.Nil {
+		token.FamilyID = token.ID
+	}
 
 	err := r.db.QueryRowx(query,
 		token.ID,
 		token.UserID,
 		token.TokenHash,
+		token.FamilyID,
 		token.DeviceID,
 		token.DeviceName,
 		token.DeviceType,
@@ -65,7 +75,7 @@ func (r *tokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
 
 func (r *tokenRepository) FindRefreshToken(tokenHash string) (*models.RefreshToken, error) {
 	query := `
-		SELECT id, user_id, token_hash, device_id, device_name, device_type,
+		SELECT id, user_id, token_hash, family_id, device_id, device_name, device_type,
 		       user_agent, ip_address, expires_at, revoked_at, revoked_by,
 		       revoked_reason, created_at, last_used_at
 		FROM refresh_tokens
@@ -84,6 +94,28 @@ func (r *tokenRepository) FindRefreshToken(tokenHash string) (*models.RefreshTok
 	return &token, nil
 }
 
+// FindRefreshTokenIncludingRevoked also returns revoked tokens, so a replayed token can be recognised
+func (r *tokenRepository) FindRefreshTokenIncludingRevoked(tokenHash string) (*models.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, family_id, device_id, device_name, device_type,
+		       user_agent, ip_address, expires_at, revoked_at, revoked_by,
+		       revoked_reason, created_at, last_used_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	var token models.RefreshToken
+	err := r.db.Get(&token, query, tokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("token not found")
+		}
+		return nil, fmt.Errorf("failed to find token: %w", err)
+	}
+
+	return &token, nil
+}
+
 func (r *tokenRepository) UpdateLastUsed(tokenID uuid.UUID) error {
 	query := `UPDATE refresh_tokens SET last_used_at = $2 WHERE id = $1`
 
@@ -125,8 +157,93 @@ func (r *tokenRepository) RevokeAllUserTokens(userID uuid.UUID) error {
 	return nil
 }
 
+// RotateToken marks a token as used up by rotation. Returns false when it was already revoked,
+// e.g. by a concurrent refresh with the same token.
+func (r *tokenRepository) RotateToken(tokenID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $2, revoked_reason = $3
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, tokenID, time.Now(), "rotated")
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate token: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate token: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+// FindActiveSessions returns the live token of each of the user's sessions, most recently used first.
+// CreatedAt is set to when the session signed in rather than when the token was rotated.
+func (r *tokenRepository) FindActiveSessions(userID uuid.UUID) ([]models.RefreshToken, error) {
+	query := `
+		SELECT rt.id, rt.user_id, rt.token_hash, rt.family_id, rt.device_id, rt.device_name, rt.device_type,
+		       rt.user_agent, rt.ip_address, rt.expires_at, rt.revoked_at, rt.revoked_by,
+		       rt.revoked_reason, rt.last_used_at,
+		       (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = rt.family_id) AS created_at
+		FROM refresh_tokens rt
+		WHERE rt.user_id = $1 AND rt.revoked_at IS NULL AND rt.expires_at > $2
+		ORDER BY rt.last_used_at DESC
+	`
+
+	var tokens []models.RefreshToken
+	if err := r.db.Select(&tokens, query, userID, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to find sessions: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// RevokeFamily revokes every live token of one of the user's sessions.
+// Returns false when the session does not exist or is already signed out.
+func (r *tokenRepository) RevokeFamily(userID, familyID uuid.UUID, revokedBy *uuid.UUID, reason string) (bool, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $3, revoked_by = $4, revoked_reason = $5
+		WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, userID, familyID, time.Now(), revokedBy, reason)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+// RevokeUserSessions revokes all of the user's sessions except exceptFamilyID (uuid.Nil keeps none)
+// and returns the IDs of the sessions it signed out
+func (r *tokenRepository) RevokeUserSessions(userID, exceptFamilyID uuid.UUID, revokedBy *uuid.UUID, reason string) ([]uuid.UUID, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $3, revoked_by = $4, revoked_reason = $5
+		WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL
+		RETURNING family_id
+	`
+
+	var familyIDs []uuid.UUID
+	if err := r.db.Select(&familyIDs, query, userID, exceptFamilyID, time.Now(), revokedBy, reason); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return familyIDs, nil
+}
+
+// CleanupExpiredTokens deletes expired tokens. Revoked tokens are kept until they expire
+// so that replaying a rotated token is still detected.
 func (r *tokenRepository) CleanupExpiredTokens() error {
-	query := `DELETE FROM refresh_tokens WHERE expires_at < $1 OR revoked_at IS NOT NULL`
+	query := `DELETE FROM refresh_tokens WHERE expires_at < $1`
 
 	_, err := r.db.Exec(query, time.Now())
 	if err != nil {
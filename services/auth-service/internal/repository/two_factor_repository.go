@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type TwoFactorRepository interface {
+	// TOTP enrollment
+	FindByUserID(userID uuid.UUID) (*models.UserTwoFactor, error)
+	UpsertPendingTOTP(userID uuid.UUID, encryptedSecret string) error
+	EnableTOTP(userID uuid.UUID, step int64) error
+	DisableTOTP(userID uuid.UUID) error
+	UseTOTPStep(userID uuid.UUID, step int64) (bool, error)
+
+	// Recovery codes
+	ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error
+	UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error)
+	CountRecoveryCodes(userID uuid.UUID) (int, error)
+
+	// Login challenges
+	CreateChallenge(challenge *models.TwoFactorChallenge) error
+	FindChallenge(tokenHash string) (*models.TwoFactorChallenge, error)
+	SetChallengeEmailCode(challengeID uuid.UUID, codeHash string) error
+	UseChallengeAttempt(challengeID uuid.UUID, maxAttempts int) (int, bool, error)
+	CompleteChallenge(challengeID uuid.UUID) (bool, error)
+	DeleteExpiredChallenges() error
+}
+
+type twoFactorRepository struct {
+	db *sqlx.DB
+}
+
+func NewTwoFactorRepository(db *sqlx.DB) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+func (r *twoFactorRepository) FindByUserID(userID uuid.UUID) (*models.UserTwoFactor, error) {
+	query := `
+		SELECT user_id, totp_secret, totp_enabled, totp_last_used_step, enabled_at, created_at, updated_at
+		FROM user_two_factor
+		WHERE user_id = $1
+	`
+
+	var twoFactor models.UserTwoFactor
+	err := r.db.Get(&twoFactor, query, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find two-factor settings: %w", err)
+	}
+
+	return &twoFactor, nil
+}
+
+// UpsertPendingTOTP stores a new secret awaiting confirmation. An enabled enrollment is left untouched.
+func (r *twoFactorRepository) UpsertPendingTOTP(userID uuid.UUID, encryptedSecret string) error {
+	query := `
+		INSERT INTO user_two_factor (user_id, totp_secret, totp_enabled, created_at, updated_at)
+		VALUES ($1, $2, false, $3, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET totp_secret = EXCLUDED.totp_secret, totp_last_used_step = 0, updated_at = EXCLUDED.updated_at
+		WHERE user_two_factor.totp_enabled = false
+	`
+
+	_, err := r.db.Exec(query, userID, encryptedSecret, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to store totp secret: %w", err)
+	}
+
+	return nil
+}
+
+func (r *twoFactorRepository) EnableTOTP(userID uuid.UUID, step int64) error {
+	query := `
+		UPDATE user_two_factor
+		SET totp_enabled = true, totp_last_used_step = $2, enabled_at = $3, updated_at = $3
+		WHERE user_id = $1
+	`
+
+	_, err := r.db.Exec(query, userID, step, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+
+	return nil
+}
+
+// DisableTOTP removes the enrollment and its recovery codes
+func (r *twoFactorRepository) DisableTOTP(userID uuid.UUID) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_two_factor WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM two_factor_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep records an accepted time step. Returns false when that step (or a later one)
+// was already used, so a code cannot be replayed.
+func (r *twoFactorRepository) UseTOTPStep(userID uuid.UUID, step int64) (bool, error) {
+	query := `
+		UPDATE user_two_factor
+		SET totp_last_used_step = $2, updated_at = $3
+		WHERE user_id = $1 AND totp_last_used_step < $2
+	`
+
+	result, err := r.db.Exec(query, userID, step, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to record totp step: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record totp step: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM two_factor_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	now := time.Now()
+	for _, codeHash := range codeHashes {
+		_, err := tx.Exec(`
+			INSERT INTO two_factor_recovery_codes (id, user_id, code_hash, created_at)
+			VALUES ($1, $2, $3, $4)
+		`, uuid.New(), userID, codeHash, now)
+		if err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseRecoveryCode marks an unused recovery code as used. Returns false when no such code exists.
+func (r *twoFactorRepository) UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE two_factor_recovery_codes
+		SET used_at = $3
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, userID, codeHash, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+func (r *twoFactorRepository) CountRecoveryCodes(userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM two_factor_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+
+	var count int
+	if err := r.db.Get(&count, query, userID); err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *twoFactorRepository) CreateChallenge(challenge *models.TwoFactorChallenge) error {
+	query := `
+		INSERT INTO two_factor_challenges (id, user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	challenge.ID = uuid.New()
+	challenge.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(query, challenge.ID, challenge.UserID, challenge.TokenHash, challenge.ExpiresAt, challenge.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create two-factor challenge: %w", err)
+	}
+
+	return nil
+}
+
+// FindChallenge returns a challenge that has not been completed or expired
+func (r *twoFactorRepository) FindChallenge(tokenHash string) (*models.TwoFactorChallenge, error) {
+	query := `
+		SELECT id, user_id, token_hash, email_code_hash, email_code_sent_at, attempts, expires_at, completed_at, created_at
+		FROM two_factor_challenges
+		WHERE token_hash = $1 AND completed_at IS NULL AND expires_at > $2
+	`
+
+	var challenge models.TwoFactorChallenge
+	err := r.db.Get(&challenge, query, tokenHash, time.Now())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("challenge not found")
+		}
+		return nil, fmt.Errorf("failed to find two-factor challenge: %w", err)
+	}
+
+	return &challenge, nil
+}
+
+func (r *twoFactorRepository) SetChallengeEmailCode(challengeID uuid.UUID, codeHash string) error {
+	query := `UPDATE two_factor_challenges SET email_code_hash = $2, email_code_sent_at = $3 WHERE id = $1`
+
+	_, err := r.db.Exec(query, challengeID, codeHash, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to store email code: %w", err)
+	}
+
+	return nil
+}
+
+// UseChallengeAttempt counts a verification attempt before the code is checked and returns
+// the new attempt count. Returns false once maxAttempts are used up, so parallel requests
+// cannot try more codes than the limit.
+func (r *twoFactorRepository) UseChallengeAttempt(challengeID uuid.UUID, maxAttempts int) (int, bool, error) {
+	query := `
+		UPDATE two_factor_challenges SET attempts = attempts + 1
+		WHERE id = $1 AND attempts < $2 AND completed_at IS NULL
+		RETURNING attempts
+	`
+
+	var attempts int
+	if err := r.db.Get(&attempts, query, challengeID, maxAttempts); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to record attempt: %w", err)
+	}
+
+	return attempts, true, nil
+}
+
+// CompleteChallenge consumes a challenge. Returns false if it was already consumed.
+func (r *twoFactorRepository) CompleteChallenge(challengeID uuid.UUID) (bool, error) {
+	query := `UPDATE two_factor_challenges SET completed_at = $2 WHERE id = $1 AND completed_at IS NULL`
+
+	result, err := r.db.Exec(query, challengeID, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to complete challenge: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to complete challenge: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+func (r *twoFactorRepository) DeleteExpiredChallenges() error {
+	query := `DELETE FROM two_factor_challenges WHERE expires_at < $1`
+
+	_, err := r.db.Exec(query, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to cleanup two-factor challenges: %w", err)
+	}
+
+	return nil
+}
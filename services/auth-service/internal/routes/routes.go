@@ -7,7 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine, authHandler *handlers.AuthHandler, authService service.AuthService) {
+func SetupRoutes(router *gin.Engine, authHandler *handlers.AuthHandler, adminHandler *handlers.AdminHandler, authService service.AuthService, internalAPIKey string) {
 	// Health check
 	router.GET("/health", authHandler.HealthCheck)
 
@@ -38,6 +38,10 @@ func SetupRoutes(router *gin.Engine, authHandler *handlers.AuthHandler, authServ
 			auth.POST("/verify-email-by-code", authHandler.VerifyEmailByCode) // Verify email with 6-digit code
 			auth.POST("/resend-verification", authHandler.ResendVerification) // Resend verification email (sends 6-digit code)
 
+			// Two-factor login (second step after /login returns a challenge token)
+			auth.POST("/2fa/verify", authHandler.VerifyTwoFactor)            // Complete login with TOTP, email or recovery code
+			auth.POST("/2fa/email/send", authHandler.SendTwoFactorEmailCode) // Email a login code (fallback)
+
 			// Protected endpoints (require authentication)
 			protected := auth.Group("")
 			protected.Use(middleware.AuthMiddleware(authService))
@@ -45,7 +49,58 @@ func SetupRoutes(router *gin.Engine, authHandler *handlers.AuthHandler, authServ
 				protected.GET("/validate", authHandler.ValidateToken)
 				protected.POST("/logout", authHandler.Logout)
 				protected.POST("/change-password", authHandler.ChangePassword)
+
+				// Sessions (signed-in devices)
+				protected.GET("/sessions", authHandler.ListSessions)
+				protected.DELETE("/sessions/:id", authHandler.RevokeSession)
+				protected.POST("/sessions/revoke-others", authHandler.RevokeOtherSessions)
+
+				// Two-factor authentication
+				protected.GET("/2fa/status", authHandler.GetTwoFactorStatus)
+				protected.POST("/2fa/totp/setup", authHandler.SetupTOTP)
+				protected.POST("/2fa/totp/enable", authHandler.EnableTOTP)
+				protected.POST("/2fa/totp/disable", authHandler.DisableTOTP)
+				protected.POST("/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
 			}
+
+			// Internal endpoints (service-to-service only)
+			internal := auth.Group("/internal")
+			internal.Use(middleware.InternalAuth(internalAPIKey))
+			{
+				internal.GET("/users/:id", authHandler.GetUserContactInternal)
+				internal.GET("/revocations", authHandler.GetRevocationsInternal)
+			}
+		}
+
+		// User administration
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(authService))
+		{
+			// Account bans
+			admin.POST("/users/:id/ban", middleware.RequirePermission("user:ban"), adminHandler.BanUser)
+			admin.POST("/users/:id/unban", middleware.RequirePermission("user:ban"), adminHandler.UnbanUser)
+		}
+
+		// Role and permission management
+		rbac := v1.Group("/admin")
+		rbac.Use(middleware.AuthMiddleware(authService))
+		rbac.Use(middleware.RequirePermission("rbac:manage"))
+		{
+			rbac.GET("/roles", adminHandler.ListRoles)
+			rbac.POST("/roles", adminHandler.CreateRole)
+			rbac.PUT("/roles/:id", adminHandler.UpdateRole)
+			rbac.DELETE("/roles/:id", adminHandler.DeleteRole)
+			rbac.GET("/roles/:id/permissions", adminHandler.GetRolePermissions)
+			rbac.POST("/roles/:id/permissions", adminHandler.AddPermissionToRole)
+			rbac.DELETE("/roles/:id/permissions/:permission_id", adminHandler.RemovePermissionFromRole)
+
+			rbac.GET("/permissions", adminHandler.ListPermissions)
+			rbac.POST("/permissions", adminHandler.CreatePermission)
+			rbac.DELETE("/permissions/:id", adminHandler.DeletePermission)
+
+			rbac.GET("/users/:id/roles", adminHandler.GetUserRoles)
+			rbac.POST("/users/:id/roles", adminHandler.AssignRoleToUser)
+			rbac.DELETE("/users/:id/roles/:role_id", adminHandler.RemoveRoleFromUser)
 		}
 	}
 }
@@ -23,7 +23,7 @@ type AuthService interface {
 	Register(req *models.RegisterRequest, ip, userAgent string) (*models.AuthResponse, error)
 	Login(req *models.LoginRequest, ip, userAgent string) (*models.AuthResponse, error)
 	RefreshToken(req *models.RefreshTokenRequest, ip, userAgent string) (*models.AuthResponse, error)
-	Logout(userID uuid.UUID, refreshToken string) error
+	Logout(userID uuid.UUID, refreshToken string, accessToken *TokenClaims) error
 	ChangePassword(userID uuid.UUID, req *models.ChangePasswordRequest) error
 	ValidateToken(tokenString string) (*TokenClaims, error)
 
@@ -38,12 +38,40 @@ type AuthService interface {
 
 	// Reset password with code
 	ResetPasswordByCode(code, newPassword, ip string) error
+
+	// Internal lookups for other services
+	GetUserByID(userID uuid.UUID) (*models.User, error)
+
+	// Sessions (one per refresh token family)
+	ListSessions(userID, currentSessionID uuid.UUID) ([]models.SessionResponse, error)
+	RevokeSession(userID, sessionID uuid.UUID, ip, userAgent string) error
+	RevokeOtherSessions(userID, currentSessionID uuid.UUID, ip, userAgent string) (int, error)
+
+	// Access token revocation
+	BanUser(userID, bannedBy uuid.UUID, reason string) error
+	UnbanUser(userID, unbannedBy uuid.UUID) error
+	GetRevocationsSince(since time.Time) (*models.RevocationsResponse, error)
+	InvalidateAccessTokens(userID uuid.UUID) error
+	StartRevocationSync()
+
+	// Two-factor authentication
+	StartTwoFactorChallenge(user *models.User, roleName, ip, userAgent string) (*models.AuthResponse, error)
+	VerifyTwoFactor(req *models.TwoFactorVerifyRequest, ip, userAgent string) (*models.AuthResponse, error)
+	SendTwoFactorEmailCode(challengeToken, ip, userAgent string) error
+	GetTwoFactorStatus(userID uuid.UUID) (*models.TwoFactorStatusResponse, error)
+	SetupTOTP(userID uuid.UUID) (*models.TOTPSetupResponse, error)
+	EnableTOTP(userID uuid.UUID, code string) ([]string, error)
+	DisableTOTP(userID uuid.UUID, req *models.DisableTOTPRequest) error
+	RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error)
 }
 
 type authService struct {
 	userRepo              repository.UserRepository
 	roleRepo              repository.RoleRepository
 	tokenRepo             repository.TokenRepository
+	revocationRepo        repository.RevocationRepository
+	revocations           *revocationCache
+	twoFactorRepo         repository.TwoFactorRepository
 	auditRepo             repository.AuditLogRepository
 	passwordResetRepo     repository.PasswordResetRepository
 	emailVerificationRepo repository.EmailVerificationRepository
@@ -55,9 +83,11 @@ type authService struct {
 }
 
 type TokenClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID      string   `json:"user_id"`
+	Email       string   `json:"email"`
+	Role        string   `json:"role"`                  // Primary role, kept for clients and legacy checks
+	Permissions []string `json:"permissions,omitempty"` // Union of permissions from all of the user's roles
+	SessionID   string   `json:"sid,omitempty"`         // Refresh token family the access token was issued for
 	jwt.RegisteredClaims
 }
 
@@ -65,6 +95,8 @@ func NewAuthService(
 	userRepo repository.UserRepository,
 	roleRepo repository.RoleRepository,
 	tokenRepo repository.TokenRepository,
+	revocationRepo repository.RevocationRepository,
+	twoFactorRepo repository.TwoFactorRepository,
 	auditRepo repository.AuditLogRepository,
 	passwordResetRepo repository.PasswordResetRepository,
 	emailVerificationRepo repository.EmailVerificationRepository,
@@ -80,6 +112,9 @@ func NewAuthService(
 		userRepo:              userRepo,
 		roleRepo:              roleRepo,
 		tokenRepo:             tokenRepo,
+		revocationRepo:        revocationRepo,
+		revocations:           newRevocationCache(),
+		twoFactorRepo:         twoFactorRepo,
 		auditRepo:             auditRepo,
 		passwordResetRepo:     passwordResetRepo,
 		emailVerificationRepo: emailVerificationRepo,
@@ -167,23 +202,18 @@ func (s *authService) Register(req *models.RegisterRequest, ip, userAgent string
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	// Assign role
-	role, err := s.roleRepo.FindByName(req.Role)
+	// Assign role: everyone signs up as a student, instructors are promoted by an admin
+	roleName := registrationRole
+	role, err := s.roleRepo.FindByName(roleName)
 	if err != nil {
 		s.logAudit(&user.ID, "register", "failed", ip, userAgent, fmt.Sprintf("role not found: %v", err))
-		return nil, fmt.Errorf("failed to find role '%s': %w", req.Role, err)
+		return nil, fmt.Errorf("failed to find role '%s': %w", roleName, err)
 	}
 
 	if err := s.roleRepo.AssignRoleToUser(user.ID, role.ID, nil); err != nil {
 		return nil, fmt.Errorf("failed to assign role: %w", err)
 	}
 
-	// Generate tokens
-	accessToken, refreshToken, expiresIn, err := s.generateTokens(user.ID, req.Email, req.Role, ip, userAgent)
-	if err != nil {
-		return nil, err
-	}
-
 	log.Printf("[Auth-Service] Starting post-registration tasks for user %s (%s)", user.ID, user.Email)
 
 	// Call User Service to create profile - CRITICAL: Must succeed for data consistency
@@ -193,7 +223,7 @@ func (s *authService) Register(req *models.RegisterRequest, ip, userAgent string
 	profileReq := client.CreateProfileRequest{
 		UserID:          user.ID.String(),
 		Email:           user.Email,
-		Role:            req.Role,
+		Role:            roleName,
 		FullName:        req.FullName,
 		TargetBandScore: req.TargetBandScore,
 	}
@@ -228,12 +258,24 @@ func (s *authService) Register(req *models.RegisterRequest, ip, userAgent string
 		log.Printf("[Auth-Service] SUCCESS: Sent welcome notification to %s", user.Email)
 	}
 
+	// A role that requires a second factor gets a challenge instead of tokens, as on login
+	challenge, err := s.StartTwoFactorChallenge(user, roleName, ip, userAgent)
+	if err != nil || challenge != nil {
+		return challenge, err
+	}
+
+	// Generate tokens
+	accessToken, refreshToken, expiresIn, err := s.generateTokens(user.ID, req.Email, roleName, ip, userAgent, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	return &models.AuthResponse{
 		Success: true,
 		Data: &models.AuthData{
 			UserID:       user.ID.String(),
 			Email:        user.Email,
-			Role:         req.Role,
+			Role:         roleName,
 			AccessToken:  accessToken,
 			RefreshToken: refreshToken,
 			ExpiresIn:    expiresIn,
@@ -329,16 +371,25 @@ func (s *authService) Login(req *models.LoginRequest, ip, userAgent string) (*mo
 		return nil, fmt.Errorf("failed to find user roles: %w", err)
 	}
 
-	roleName := roles[0].Name
+	roleName := PrimaryRoleName(roles)
 
 	// Reset failed attempts
 	s.userRepo.ResetFailedAttempts(user.ID)
 
+	// Second factor: tokens are issued by VerifyTwoFactor once the challenge is passed
+	challenge, err := s.StartTwoFactorChallenge(user, roleName, ip, userAgent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start two-factor challenge: %w", err)
+	}
+	if challenge != nil {
+		return challenge, nil
+	}
+
 	// Update login info
 	s.userRepo.UpdateLoginInfo(user.ID, ip)
 
 	// Generate tokens
-	accessToken, refreshToken, expiresIn, err := s.generateTokens(user.ID, user.Email, roleName, ip, userAgent)
+	accessToken, refreshToken, expiresIn, err := s.generateTokens(user.ID, user.Email, roleName, ip, userAgent, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -362,16 +413,18 @@ func (s *authService) RefreshToken(req *models.RefreshTokenRequest, ip, userAgen
 	// Hash the refresh token
 	tokenHash := s.hashToken(req.RefreshToken)
 
-	// Find token in database
-	token, err := s.tokenRepo.FindRefreshToken(tokenHash)
+	// Find token in database (revoked ones too, so a replayed token can be detected)
+	token, err := s.tokenRepo.FindRefreshTokenIncludingRevoked(tokenHash)
 	if err != nil {
-		return &models.AuthResponse{
-			Success: false,
-			Error: &models.ErrorData{
-				Code:    "INVALID_TOKEN",
-				Message: "Invalid or expired refresh token",
-			},
-		}, nil
+		return invalidRefreshTokenResponse(), nil
+	}
+
+	if token.RevokedAt != nil {
+		// A rotated token presented again has leaked: sign the whole session out
+		if token.RevokedReason != nil && *token.RevokedReason == "rotated" {
+			s.handleRefreshTokenReuse(token, ip, userAgent)
+		}
+		return invalidRefreshTokenResponse(), nil
 	}
 
 	// Check if token is expired
@@ -397,13 +450,31 @@ func (s *authService) RefreshToken(req *models.RefreshTokenRequest, ip, userAgen
 		return nil, fmt.Errorf("failed to find user roles: %w", err)
 	}
 
-	roleName := roles[0].Name
+	roleName := PrimaryRoleName(roles)
 
-	// Update last used
-	s.tokenRepo.UpdateLastUsed(token.ID)
+	if !user.IsActive {
+		return &models.AuthResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "ACCOUNT_INACTIVE",
+				Message: "Account is inactive",
+			},
+		}, nil
+	}
 
-	// Generate new access token
-	accessToken, _, expiresIn, err := s.generateTokens(user.ID, user.Email, roleName, ip, userAgent)
+	// Rotate: each refresh token can be exchanged only once. Losing the race means another
+	// request already used it, which is treated as reuse.
+	rotated, err := s.tokenRepo.RotateToken(token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		s.handleRefreshTokenReuse(token, ip, userAgent)
+		return invalidRefreshTokenResponse(), nil
+	}
+
+	// Generate new token pair in the same session
+	accessToken, refreshToken, expiresIn, err := s.generateTokens(user.ID, user.Email, roleName, ip, userAgent, token)
 	if err != nil {
 		return nil, err
 	}
@@ -417,21 +488,36 @@ func (s *authService) RefreshToken(req *models.RefreshTokenRequest, ip, userAgen
 			Email:        user.Email,
 			Role:         roleName,
 			AccessToken:  accessToken,
-			RefreshToken: req.RefreshToken,
+			RefreshToken: refreshToken,
 			ExpiresIn:    expiresIn,
 		},
 	}, nil
 }
 
-func (s *authService) Logout(userID uuid.UUID, refreshToken string) error {
+func (s *authService) Logout(userID uuid.UUID, refreshToken string, accessToken *TokenClaims) error {
+	// Stop the access token used to log out from working until it expires
+	if accessToken != nil && accessToken.ID != "" && accessToken.ExpiresAt != nil {
+		if err := s.revocationRepo.RevokeAccessToken(accessToken.ID, userID, accessToken.ExpiresAt.Time, "logout"); err != nil {
+			return err
+		}
+		s.revocations.revokeToken(accessToken.ID, accessToken.ExpiresAt.Time)
+	}
+
 	tokenHash := s.hashToken(refreshToken)
 
 	token, err := s.tokenRepo.FindRefreshToken(tokenHash)
-	if err != nil {
+	if err != nil || token.UserID != userID {
 		return nil // Token not found, already logged out
 	}
 
-	return s.tokenRepo.RevokeToken(token.ID, userID, "logout")
+	revoked, err := s.tokenRepo.RevokeFamily(userID, token.FamilyID, &userID, "logout")
+	if err != nil {
+		return err
+	}
+	if revoked {
+		s.logSessionRevoked(userID, token.FamilyID, "logout", "", "")
+	}
+	return nil
 }
 
 func (s *authService) ChangePassword(userID uuid.UUID, req *models.ChangePasswordRequest) error {
@@ -459,7 +545,7 @@ func (s *authService) ChangePassword(userID uuid.UUID, req *models.ChangePasswor
 	}
 
 	// Revoke all refresh tokens
-	s.tokenRepo.RevokeAllUserTokens(userID)
+	s.revokeAllSessions(userID, &userID, "password_changed", "")
 
 	s.logAudit(&userID, "change_password", "success", "", "", "")
 
@@ -478,26 +564,83 @@ func (s *authService) ValidateToken(tokenString string) (*TokenClaims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*TokenClaims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*TokenClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	// Reject tokens revoked on logout, or issued before a ban or password change
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token")
+	}
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revoked, synced := s.revocations.check(claims.ID, userID, issuedAt)
+	if !synced {
+		// The cache has not loaded yet, ask the database
+		if revoked, err = s.revocationRepo.IsAccessTokenRevoked(claims.ID, userID, issuedAt); err != nil {
+			return nil, err
+		}
+	}
+	if revoked {
+		return nil, fmt.Errorf("token has been revoked")
 	}
 
-	return nil, fmt.Errorf("invalid token")
+	return claims, nil
 }
 
 // Helper functions
 
-func (s *authService) generateTokens(userID uuid.UUID, email, role, ip, userAgent string) (string, string, int64, error) {
+// registrationRole is the role every self-registered account starts with
+const registrationRole = "student"
+
+// rolePriority orders the built-in roles; the highest one becomes the token's primary role
+var rolePriority = map[string]int{"student": 1, "instructor": 2, "admin": 3}
+
+// PrimaryRoleName picks the role reported in the token's `role` claim when a user has several
+func PrimaryRoleName(roles []models.Role) string {
+	if len(roles) == 0 {
+		return ""
+	}
+	primary := roles[0]
+	for _, role := range roles[1:] {
+		if rolePriority[role.Name] > rolePriority[primary.Name] {
+			primary = role
+		}
+	}
+	return primary.Name
+}
+
+// generateTokens issues an access/refresh token pair. A nil previous token starts a new session;
+// otherwise the pair continues previous's session (token rotation).
+func (s *authService) generateTokens(userID uuid.UUID, email, role, ip, userAgent string, previous *models.RefreshToken) (string, string, int64, error) {
 	// Parse JWT expiry
 	expiryDuration, _ := time.ParseDuration(s.config.JWTExpiry)
 	expiresAt := time.Now().Add(expiryDuration)
 
+	// Permissions are resolved from role_permissions at issue time
+	permissions, err := s.roleRepo.FindPermissionNamesByUserID(userID)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	familyID := uuid.New()
+	if previous != nil {
+		familyID = previous.FamilyID
+	}
+
 	// Create access token
 	claims := TokenClaims{
-		UserID: userID.String(),
-		Email:  email,
-		Role:   role,
+		UserID:      userID.String(),
+		Email:       email,
+		Role:        role,
+		Permissions: permissions,
+		SessionID:   familyID.String(),
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
@@ -520,9 +663,17 @@ func (s *authService) generateTokens(userID uuid.UUID, email, role, ip, userAgen
 	refreshToken := &models.RefreshToken{
 		UserID:    userID,
 		TokenHash: refreshTokenHash,
+		FamilyID:  familyID,
 		ExpiresAt: refreshExpiresAt,
 	}
 
+	if previous != nil {
+		refreshToken.DeviceID = previous.DeviceID
+		refreshToken.DeviceName = previous.DeviceName
+		refreshToken.DeviceType = previous.DeviceType
+	} else {
+		applyDeviceInfo(refreshToken, userAgent)
+	}
 	if ip != "" {
 		refreshToken.IPAddress = &ip
 	}
@@ -650,7 +801,7 @@ func (s *authService) ResetPassword(req *models.ResetPasswordRequest, ip string)
 	s.passwordResetRepo.MarkAsUsed(token.ID)
 
 	// Revoke all refresh tokens for security
-	s.tokenRepo.RevokeAllUserTokens(token.UserID)
+	s.revokeAllSessions(token.UserID, nil, "password_reset", ip)
 
 	s.logAudit(&token.UserID, "reset_password", "success", ip, "", "")
 
@@ -806,9 +957,14 @@ func (s *authService) ResetPasswordByCode(code, newPassword, ip string) error {
 	s.passwordResetRepo.MarkAsUsed(token.ID)
 
 	// Revoke all refresh tokens for security
-	s.tokenRepo.RevokeAllUserTokens(token.UserID)
+	s.revokeAllSessions(token.UserID, nil, "password_reset", ip)
 
 	s.logAudit(&token.UserID, "reset_password_by_code", "success", ip, "", "")
 
 	return nil
 }
+
+// GetUserByID returns an active (not deleted) user account
+func (s *authService) GetUserByID(userID uuid.UUID) (*models.User, error) {
+	return s.userRepo.FindByID(userID)
+}
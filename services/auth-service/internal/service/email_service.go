@@ -18,6 +18,7 @@ const (
 type EmailService interface {
 	SendPasswordResetEmail(toEmail, resetCode string) error
 	SendVerificationEmail(toEmail, verificationCode string) error
+	SendTwoFactorCodeEmail(toEmail, code string) error
 }
 
 type emailService struct {
@@ -109,6 +110,23 @@ If you didn’t create this account, you can safely ignore this email.`
 	return s.sendEmail(toEmail, subject, body)
 }
 
+// ---- Two-factor sign-in code (en) – simple, elegant ----
+func (s *emailService) SendTwoFactorCodeEmail(toEmail, code string) error {
+	subject := "IELTSGo – Your sign-in code"
+	intro := `Someone is signing in to your <strong>IELTSGo</strong> account. 
+Enter the code below to finish signing in.`
+	note := `This code expires in <strong>5 minutes</strong>. 
+If you didn’t try to sign in, change your password right away.`
+	body := minimalTemplate(
+		"Sign-in verification",
+		"Your sign-in code",
+		intro,
+		code,
+		note,
+	)
+	return s.sendEmail(toEmail, subject, body)
+}
+
 // ---- Core send ----
 func (s *emailService) sendEmail(to, subject, body string) error {
 	auth := smtp.PlainAuth("", s.smtpUsername, s.smtpPassword, s.smtpHost)
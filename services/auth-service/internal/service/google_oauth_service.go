@@ -198,18 +198,36 @@ func (s *googleOAuthService) AuthenticateUser(googleUser *GoogleUserInfo, ip, us
 		}
 		role = studentRole
 	} else {
-		role = &roles[0]
+		role = &models.Role{Name: PrimaryRoleName(roles)}
+	}
+
+	// Google sign-in does not bypass two-factor authentication
+	challenge, err := s.authService.StartTwoFactorChallenge(user, role.Name, ip, userAgent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start two-factor challenge: %w", err)
+	}
+	if challenge != nil {
+		return challenge, nil
+	}
+
+	permissions, err := s.roleRepo.FindPermissionNamesByUserID(user.ID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Generate JWT access token
 	expiryDuration, _ := time.ParseDuration(s.appConfig.JWTExpiry)
 	expiresAt := time.Now().Add(expiryDuration)
 
+	familyID := uuid.New()
 	claims := TokenClaims{
-		UserID: user.ID.String(),
-		Email:  user.Email,
-		Role:   role.Name,
+		UserID:      user.ID.String(),
+		Email:       user.Email,
+		Role:        role.Name,
+		Permissions: permissions,
+		SessionID:   familyID.String(),
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
@@ -234,10 +252,12 @@ func (s *googleOAuthService) AuthenticateUser(googleUser *GoogleUserInfo, ip, us
 	refreshToken := &models.RefreshToken{
 		UserID:    user.ID,
 		TokenHash: refreshTokenHash,
+		FamilyID:  familyID,
 		ExpiresAt: refreshExpiresAt,
 		IPAddress: ipPtr,
 		UserAgent: uaPtr,
 	}
+	applyDeviceInfo(refreshToken, userAgent)
 
 	if err := s.tokenRepo.CreateRefreshToken(refreshToken); err != nil {
 		return nil, fmt.Errorf("failed to create refresh token: %w", err)
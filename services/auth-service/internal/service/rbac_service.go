@@ -0,0 +1,283 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrPermissionNotFound = errors.New("permission not found")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrBuiltInRole        = errors.New("built-in roles cannot be deleted")
+	ErrLastUserRole       = errors.New("user must keep at least one role")
+)
+
+// RBACService manages roles, permissions and user role assignments.
+// Permissions are embedded in access tokens, so granting one takes effect the next time a
+// token is issued, while removing one invalidates the tokens of every affected user.
+type RBACService interface {
+	ListRoles() ([]models.Role, error)
+	CreateRole(req *models.CreateRoleRequest) (*models.Role, error)
+	UpdateRole(roleID int, req *models.UpdateRoleRequest) (*models.Role, error)
+	DeleteRole(roleID int) error
+
+	ListPermissions() ([]models.Permission, error)
+	CreatePermission(req *models.CreatePermissionRequest) (*models.Permission, error)
+	DeletePermission(permissionID int) error
+
+	GetRolePermissions(roleID int) ([]models.Permission, error)
+	AddPermissionToRole(roleID, permissionID int) error
+	RemovePermissionFromRole(roleID, permissionID int) error
+
+	GetUserRoles(userID uuid.UUID) ([]models.Role, error)
+	AssignRoleToUser(userID uuid.UUID, roleID int, assignedBy uuid.UUID) error
+	RemoveRoleFromUser(userID uuid.UUID, roleID int, removedBy uuid.UUID) error
+}
+
+// AccessTokenInvalidator rejects the access tokens already issued to a user (see AuthService)
+type AccessTokenInvalidator interface {
+	InvalidateAccessTokens(userID uuid.UUID) error
+}
+
+type rbacService struct {
+	roleRepo  repository.RoleRepository
+	userRepo  repository.UserRepository
+	auditRepo repository.AuditLogRepository
+	tokens    AccessTokenInvalidator
+}
+
+func NewRBACService(roleRepo repository.RoleRepository, userRepo repository.UserRepository, auditRepo repository.AuditLogRepository, tokens AccessTokenInvalidator) RBACService {
+	return &rbacService{
+		roleRepo:  roleRepo,
+		userRepo:  userRepo,
+		auditRepo: auditRepo,
+		tokens:    tokens,
+	}
+}
+
+func (s *rbacService) ListRoles() ([]models.Role, error) {
+	return s.roleRepo.List()
+}
+
+func (s *rbacService) CreateRole(req *models.CreateRoleRequest) (*models.Role, error) {
+	role := &models.Role{
+		Name:        req.Name,
+		DisplayName: req.DisplayName,
+		Description: req.Description,
+	}
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (s *rbacService) UpdateRole(roleID int, req *models.UpdateRoleRequest) (*models.Role, error) {
+	role, err := s.findRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	role.DisplayName = req.DisplayName
+	role.Description = req.Description
+	if err := s.roleRepo.Update(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (s *rbacService) DeleteRole(roleID int) error {
+	role, err := s.findRole(roleID)
+	if err != nil {
+		return err
+	}
+	if _, builtIn := rolePriority[role.Name]; builtIn {
+		return ErrBuiltInRole
+	}
+
+	affected, err := s.roleRepo.FindUserIDsByRoleID(roleID)
+	if err != nil {
+		return err
+	}
+	if err := s.roleRepo.Delete(roleID); err != nil {
+		return err
+	}
+	return s.invalidateTokens(affected)
+}
+
+func (s *rbacService) ListPermissions() ([]models.Permission, error) {
+	return s.roleRepo.ListPermissions()
+}
+
+func (s *rbacService) CreatePermission(req *models.CreatePermissionRequest) (*models.Permission, error) {
+	permission := &models.Permission{
+		Name:        req.Name,
+		Resource:    req.Resource,
+		Action:      req.Action,
+		Description: req.Description,
+	}
+	if err := s.roleRepo.CreatePermission(permission); err != nil {
+		return nil, err
+	}
+	return permission, nil
+}
+
+func (s *rbacService) DeletePermission(permissionID int) error {
+	if _, err := s.findPermission(permissionID); err != nil {
+		return err
+	}
+
+	affected, err := s.roleRepo.FindUserIDsByPermissionID(permissionID)
+	if err != nil {
+		return err
+	}
+	if err := s.roleRepo.DeletePermission(permissionID); err != nil {
+		return err
+	}
+	return s.invalidateTokens(affected)
+}
+
+func (s *rbacService) GetRolePermissions(roleID int) ([]models.Permission, error) {
+	if _, err := s.findRole(roleID); err != nil {
+		return nil, err
+	}
+	return s.roleRepo.FindPermissionsByRoleID(roleID)
+}
+
+func (s *rbacService) AddPermissionToRole(roleID, permissionID int) error {
+	if _, err := s.findRole(roleID); err != nil {
+		return err
+	}
+	if _, err := s.findPermission(permissionID); err != nil {
+		return err
+	}
+	return s.roleRepo.AddPermissionToRole(roleID, permissionID)
+}
+
+func (s *rbacService) RemovePermissionFromRole(roleID, permissionID int) error {
+	if _, err := s.findRole(roleID); err != nil {
+		return err
+	}
+
+	affected, err := s.roleRepo.FindUserIDsByRoleID(roleID)
+	if err != nil {
+		return err
+	}
+	if err := s.roleRepo.RemovePermissionFromRole(roleID, permissionID); err != nil {
+		return err
+	}
+	return s.invalidateTokens(affected)
+}
+
+func (s *rbacService) GetUserRoles(userID uuid.UUID) ([]models.Role, error) {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+	roles, err := s.roleRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if roles == nil {
+		roles = []models.Role{}
+	}
+	return roles, nil
+}
+
+func (s *rbacService) AssignRoleToUser(userID uuid.UUID, roleID int, assignedBy uuid.UUID) error {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return ErrUserNotFound
+	}
+	role, err := s.findRole(roleID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.AssignRoleToUser(userID, roleID, &assignedBy); err != nil {
+		return err
+	}
+
+	s.logRoleChange(userID, "role_assigned", role.Name, assignedBy)
+	return nil
+}
+
+func (s *rbacService) RemoveRoleFromUser(userID uuid.UUID, roleID int, removedBy uuid.UUID) error {
+	roles, err := s.GetUserRoles(userID)
+	if err != nil {
+		return err
+	}
+
+	var removed *models.Role
+	for i := range roles {
+		if roles[i].ID == roleID {
+			removed = &roles[i]
+			break
+		}
+	}
+	if removed == nil {
+		return ErrRoleNotFound
+	}
+	// Login and refresh require at least one role
+	if len(roles) == 1 {
+		return ErrLastUserRole
+	}
+
+	if err := s.roleRepo.RemoveRoleFromUser(userID, roleID); err != nil {
+		return err
+	}
+
+	s.logRoleChange(userID, "role_removed", removed.Name, removedBy)
+	return s.invalidateTokens([]uuid.UUID{userID})
+}
+
+// invalidateTokens rejects the current access tokens of users who lost a role or permission.
+// Their sessions stay open: the next refresh issues a token with their current permissions.
+func (s *rbacService) invalidateTokens(userIDs []uuid.UUID) error {
+	var failed int
+	for _, userID := range userIDs {
+		if err := s.tokens.InvalidateAccessTokens(userID); err != nil {
+			log.Printf("❌ Failed to invalidate access tokens of user %s: %v", userID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to invalidate the access tokens of %d of %d users", failed, len(userIDs))
+	}
+	return nil
+}
+
+func (s *rbacService) findRole(roleID int) (*models.Role, error) {
+	role, err := s.roleRepo.FindByID(roleID)
+	if err != nil {
+		if err.Error() == "role not found" {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+func (s *rbacService) findPermission(permissionID int) (*models.Permission, error) {
+	permission, err := s.roleRepo.FindPermissionByID(permissionID)
+	if err != nil {
+		if err.Error() == "permission not found" {
+			return nil, ErrPermissionNotFound
+		}
+		return nil, err
+	}
+	return permission, nil
+}
+
+func (s *rbacService) logRoleChange(userID uuid.UUID, eventType, roleName string, changedBy uuid.UUID) {
+	metadata := fmt.Sprintf(`{"role":%q,"changed_by":%q}`, roleName, changedBy.String())
+	s.auditRepo.Create(&models.AuditLog{
+		UserID:      &userID,
+		EventType:   eventType,
+		EventStatus: "success",
+		Metadata:    &metadata,
+	})
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+// fakeRoleRepo keeps roles, permissions and assignments in memory. Methods the RBAC service
+// does not use are left to the embedded interface.
+type fakeRoleRepo struct {
+	repository.RoleRepository
+	roles           map[int]*models.Role
+	permissions     map[int]*models.Permission
+	rolePermissions map[int]map[int]bool       // role ID -> permission IDs
+	userRoles       map[uuid.UUID]map[int]bool // user ID -> role IDs
+}
+
+func newFakeRoleRepo() *fakeRoleRepo {
+	return &fakeRoleRepo{
+		roles:           map[int]*models.Role{},
+		permissions:     map[int]*models.Permission{},
+		rolePermissions: map[int]map[int]bool{},
+		userRoles:       map[uuid.UUID]map[int]bool{},
+	}
+}
+
+func (r *fakeRoleRepo) addRole(id int, name string) {
+	r.roles[id] = &models.Role{ID: id, Name: name}
+	r.rolePermissions[id] = map[int]bool{}
+}
+
+func (r *fakeRoleRepo) grant(roleID int, permissionIDs ...int) {
+	for _, id := range permissionIDs {
+		if r.permissions[id] == nil {
+			r.permissions[id] = &models.Permission{ID: id, Name: fmt.Sprintf("permission:%d", id)}
+		}
+		r.rolePermissions[roleID][id] = true
+	}
+}
+
+func (r *fakeRoleRepo) assign(userID uuid.UUID, roleIDs ...int) {
+	if r.userRoles[userID] == nil {
+		r.userRoles[userID] = map[int]bool{}
+	}
+	for _, id := range roleIDs {
+		r.userRoles[userID][id] = true
+	}
+}
+
+func (r *fakeRoleRepo) FindByID(id int) (*models.Role, error) {
+	if role, ok := r.roles[id]; ok {
+		return role, nil
+	}
+	return nil, fmt.Errorf("role not found")
+}
+
+func (r *fakeRoleRepo) FindByUserID(userID uuid.UUID) ([]models.Role, error) {
+	var roles []models.Role
+	for id := range r.userRoles[userID] {
+		roles = append(roles, *r.roles[id])
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].ID < roles[j].ID })
+	return roles, nil
+}
+
+func (r *fakeRoleRepo) FindPermissionNamesByUserID(userID uuid.UUID) ([]string, error) {
+	var names []string
+	for roleID := range r.userRoles[userID] {
+		for permissionID := range r.rolePermissions[roleID] {
+			names = append(names, r.permissions[permissionID].Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (r *fakeRoleRepo) Delete(id int) error {
+	delete(r.roles, id)
+	delete(r.rolePermissions, id)
+	for _, roles := range r.userRoles {
+		delete(roles, id)
+	}
+	return nil
+}
+
+func (r *fakeRoleRepo) AssignRoleToUser(userID uuid.UUID, roleID int, assignedBy *uuid.UUID) error {
+	r.assign(userID, roleID)
+	return nil
+}
+
+func (r *fakeRoleRepo) RemoveRoleFromUser(userID uuid.UUID, roleID int) error {
+	delete(r.userRoles[userID], roleID)
+	return nil
+}
+
+func (r *fakeRoleRepo) FindPermissionByID(id int) (*models.Permission, error) {
+	if permission, ok := r.permissions[id]; ok {
+		return permission, nil
+	}
+	return nil, fmt.Errorf("permission not found")
+}
+
+func (r *fakeRoleRepo) DeletePermission(id int) error {
+	delete(r.permissions, id)
+	for _, permissions := range r.rolePermissions {
+		delete(permissions, id)
+	}
+	return nil
+}
+
+func (r *fakeRoleRepo) AddPermissionToRole(roleID, permissionID int) error {
+	r.grant(roleID, permissionID)
+	return nil
+}
+
+func (r *fakeRoleRepo) RemovePermissionFromRole(roleID, permissionID int) error {
+	delete(r.rolePermissions[roleID], permissionID)
+	return nil
+}
+
+func (r *fakeRoleRepo) FindUserIDsByRoleID(roleID int) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	for userID, roles := range r.userRoles {
+		if roles[roleID] {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	return userIDs, nil
+}
+
+func (r *fakeRoleRepo) FindUserIDsByPermissionID(permissionID int) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	for userID, roles := range r.userRoles {
+		for roleID := range roles {
+			if r.rolePermissions[roleID][permissionID] {
+				userIDs = append(userIDs, userID)
+				break
+			}
+		}
+	}
+	return userIDs, nil
+}
+
+type fakeUserRepo struct {
+	repository.UserRepository
+}
+
+func (fakeUserRepo) FindByID(id uuid.UUID) (*models.User, error) {
+	return &models.User{ID: id, Email: id.String() + "@example.com", IsActive: true}, nil
+}
+
+type fakeAuditRepo struct {
+	repository.AuditLogRepository
+	events []string
+}
+
+func (r *fakeAuditRepo) Create(log *models.AuditLog) error {
+	r.events = append(r.events, log.EventType)
+	return nil
+}
+
+// fakeInvalidator records the users whose access tokens were invalidated
+type fakeInvalidator struct {
+	users []uuid.UUID
+	err   error
+}
+
+func (f *fakeInvalidator) InvalidateAccessTokens(userID uuid.UUID) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.users = append(f.users, userID)
+	return nil
+}
+
+func sortedUsers(users []uuid.UUID) []uuid.UUID {
+	sorted := append([]uuid.UUID{}, users...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].String() < sorted[j].String() })
+	return sorted
+}
+
+const (
+	testStudentRole    = 1
+	testInstructorRole = 2
+	testReviewerRole   = 10
+	testGradePerm      = 100
+	testExportPerm     = 101
+)
+
+// newTestRBAC sets up a reviewer role (custom) with grade and export permissions, held by
+// two users, and an instructor (built-in) with the grade permission, held by a third
+func newTestRBAC() (*rbacService, *fakeRoleRepo, *fakeInvalidator, []uuid.UUID) {
+	roles := newFakeRoleRepo()
+	roles.addRole(testStudentRole, "student")
+	roles.addRole(testInstructorRole, "instructor")
+	roles.addRole(testReviewerRole, "reviewer")
+	roles.grant(testReviewerRole, testGradePerm, testExportPerm)
+	roles.grant(testInstructorRole, testGradePerm)
+
+	users := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	roles.assign(users[0], testStudentRole, testReviewerRole)
+	roles.assign(users[1], testStudentRole, testReviewerRole)
+	roles.assign(users[2], testInstructorRole)
+
+	tokens := &fakeInvalidator{}
+	svc := NewRBACService(roles, fakeUserRepo{}, &fakeAuditRepo{}, tokens).(*rbacService)
+	return svc, roles, tokens, users
+}
+
+func TestRBACRemovalsInvalidateAffectedTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		remove   func(s RBACService, users []uuid.UUID) error
+		affected func(users []uuid.UUID) []uuid.UUID
+	}{
+		{
+			name: "role removed from user",
+			remove: func(s RBACService, users []uuid.UUID) error {
+				return s.RemoveRoleFromUser(users[0], testReviewerRole, uuid.New())
+			},
+			affected: func(users []uuid.UUID) []uuid.UUID { return users[:1] },
+		},
+		{
+			name: "permission removed from role",
+			remove: func(s RBACService, users []uuid.UUID) error {
+				return s.RemovePermissionFromRole(testReviewerRole, testExportPerm)
+			},
+			affected: func(users []uuid.UUID) []uuid.UUID { return users[:2] },
+		},
+		{
+			name: "permission deleted",
+			remove: func(s RBACService, users []uuid.UUID) error {
+				return s.DeletePermission(testGradePerm)
+			},
+			affected: func(users []uuid.UUID) []uuid.UUID { return users },
+		},
+		{
+			name: "role deleted",
+			remove: func(s RBACService, users []uuid.UUID) error {
+				return s.DeleteRole(testReviewerRole)
+			},
+			affected: func(users []uuid.UUID) []uuid.UUID { return users[:2] },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, tokens, users := newTestRBAC()
+			if err := tt.remove(svc, users); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := sortedUsers(tokens.users), sortedUsers(tt.affected(users)); !reflect.DeepEqual(got, want) {
+				t.Errorf("invalidated %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRBACGrantsDoNotInvalidateTokens(t *testing.T) {
+	svc, _, tokens, users := newTestRBAC()
+
+	if err := svc.AssignRoleToUser(users[2], testReviewerRole, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.AddPermissionToRole(testInstructorRole, testExportPerm); err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens.users) != 0 {
+		t.Errorf("grants invalidated tokens of %v", tokens.users)
+	}
+}
+
+func TestRBACRemovalRules(t *testing.T) {
+	svc, roles, tokens, users := newTestRBAC()
+
+	if err := svc.DeleteRole(testInstructorRole); !errors.Is(err, ErrBuiltInRole) {
+		t.Errorf("delete built-in role = %v, want ErrBuiltInRole", err)
+	}
+	if err := svc.RemoveRoleFromUser(users[2], testInstructorRole, uuid.New()); !errors.Is(err, ErrLastUserRole) {
+		t.Errorf("remove last role = %v, want ErrLastUserRole", err)
+	}
+	if err := svc.RemoveRoleFromUser(users[2], testReviewerRole, uuid.New()); !errors.Is(err, ErrRoleNotFound) {
+		t.Errorf("remove role the user lacks = %v, want ErrRoleNotFound", err)
+	}
+	if err := svc.DeletePermission(999); !errors.Is(err, ErrPermissionNotFound) {
+		t.Errorf("delete unknown permission = %v, want ErrPermissionNotFound", err)
+	}
+	if len(tokens.users) != 0 {
+		t.Errorf("refused removals invalidated tokens of %v", tokens.users)
+	}
+	if _, ok := roles.roles[testInstructorRole]; !ok {
+		t.Error("built-in role was deleted")
+	}
+}
+
+func TestRBACRemovalReportsInvalidationFailure(t *testing.T) {
+	svc, roles, tokens, users := newTestRBAC()
+	tokens.err = errors.New("database unavailable")
+
+	if err := svc.RemoveRoleFromUser(users[0], testReviewerRole, uuid.New()); err == nil {
+		t.Fatal("removal succeeded although the tokens were not invalidated")
+	}
+	if roles.userRoles[users[0]][testReviewerRole] {
+		t.Error("role was not removed")
+	}
+}
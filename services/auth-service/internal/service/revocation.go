@@ -0,0 +1,202 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// revocationSyncOverlap is subtracted from the caller's last sync time so rows
+// committed while the previous sync was running are not missed
+const revocationSyncOverlap = 5 * time.Second
+
+// revocationSyncInterval is how often ValidateToken's cache picks up revocations made by
+// other auth-service instances
+const revocationSyncInterval = 5 * time.Second
+
+// revocationCache is ValidateToken's copy of the access token revocations, so checking a
+// token does not query the database. Revocations made by this instance are added at once,
+// those made elsewhere with the next sync.
+type revocationCache struct {
+	mu            sync.RWMutex
+	revokedTokens map[string]time.Time    // jti -> token expiry
+	invalidBefore map[uuid.UUID]time.Time // user -> tokens issued before this are rejected
+	syncedAt      time.Time               // Database clock at the last sync, zero until the first succeeds
+}
+
+func newRevocationCache() *revocationCache {
+	return &revocationCache{
+		revokedTokens: make(map[string]time.Time),
+		invalidBefore: make(map[uuid.UUID]time.Time),
+	}
+}
+
+// check reports whether the token was revoked by ID or issued before the user's cutoff.
+// synced is false until the first sync, when the cache cannot be trusted yet.
+func (rc *revocationCache) check(jti string, userID uuid.UUID, issuedAt time.Time) (revoked, synced bool) {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	if rc.syncedAt.IsZero() {
+		return false, false
+	}
+	if jti != "" {
+		if _, ok := rc.revokedTokens[jti]; ok {
+			return true, true
+		}
+	}
+	cutoff, ok := rc.invalidBefore[userID]
+	return ok && issuedAt.Before(cutoff), true
+}
+
+func (rc *revocationCache) revokeToken(jti string, expiresAt time.Time) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.revokedTokens[jti] = expiresAt
+}
+
+func (rc *revocationCache) setInvalidBefore(userID uuid.UUID, at time.Time) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if at.After(rc.invalidBefore[userID]) {
+		rc.invalidBefore[userID] = at
+	}
+}
+
+// apply merges a sync's revocations and drops tokens that have expired
+func (rc *revocationCache) apply(revocations *models.RevocationsResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	for _, token := range revocations.RevokedTokens {
+		rc.revokedTokens[token.JTI] = token.ExpiresAt
+	}
+	for _, cutoff := range revocations.TokenCutoffs {
+		if cutoff.InvalidBefore.After(rc.invalidBefore[cutoff.UserID]) {
+			rc.invalidBefore[cutoff.UserID] = cutoff.InvalidBefore
+		}
+	}
+	for jti, expiresAt := range rc.revokedTokens {
+		if expiresAt.Before(revocations.ServerTime) {
+			delete(rc.revokedTokens, jti)
+		}
+	}
+	rc.syncedAt = revocations.ServerTime
+}
+
+func (rc *revocationCache) lastSync() time.Time {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.syncedAt
+}
+
+// StartRevocationSync keeps ValidateToken's revocation cache up to date; it never returns
+func (s *authService) StartRevocationSync() {
+	ticker := time.NewTicker(revocationSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		revocations, err := s.GetRevocationsSince(s.revocations.lastSync())
+		if err != nil {
+			log.Printf("Revocation sync failed: %v", err)
+		} else {
+			s.revocations.apply(revocations)
+		}
+		<-ticker.C
+	}
+}
+
+// BanUser deactivates an account and signs it out everywhere, including access tokens already issued
+func (s *authService) BanUser(userID, bannedBy uuid.UUID, reason string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	user.IsActive = false
+	if err := s.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed to ban user: %w", err)
+	}
+
+	s.revokeAllSessions(userID, &bannedBy, "banned", "")
+
+	metadata := fmt.Sprintf(`{"banned_by":%q,"reason":%q}`, bannedBy.String(), reason)
+	s.auditRepo.Create(&models.AuditLog{
+		UserID:      &userID,
+		EventType:   "user_banned",
+		EventStatus: "success",
+		Metadata:    &metadata,
+	})
+	return nil
+}
+
+// UnbanUser reactivates an account; the user has to sign in again
+func (s *authService) UnbanUser(userID, unbannedBy uuid.UUID) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	user.IsActive = true
+	if err := s.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed to unban user: %w", err)
+	}
+
+	metadata := fmt.Sprintf(`{"unbanned_by":%q}`, unbannedBy.String())
+	s.auditRepo.Create(&models.AuditLog{
+		UserID:      &userID,
+		EventType:   "user_unbanned",
+		EventStatus: "success",
+		Metadata:    &metadata,
+	})
+	return nil
+}
+
+// GetRevocationsSince returns the revocations recorded after since, for the gateway's cache.
+// A zero since asks for everything that can still affect an unexpired access token.
+func (s *authService) GetRevocationsSince(since time.Time) (*models.RevocationsResponse, error) {
+	now := time.Now()
+	if since.IsZero() {
+		// Cutoffs older than the access token lifetime cannot match a live token
+		expiry, _ := time.ParseDuration(s.config.JWTExpiry)
+		since = now.Add(-expiry)
+	} else {
+		since = since.Add(-revocationSyncOverlap)
+	}
+
+	tokens, err := s.revocationRepo.FindRevokedAccessTokensSince(since)
+	if err != nil {
+		return nil, err
+	}
+	cutoffs, err := s.revocationRepo.FindTokenCutoffsSince(since)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RevocationsResponse{
+		ServerTime:    now,
+		RevokedTokens: tokens,
+		TokenCutoffs:  cutoffs,
+	}, nil
+}
+
+// InvalidateAccessTokens rejects every access token issued to the user so far, so that a
+// change to their roles or permissions applies now rather than when the token expires
+func (s *authService) InvalidateAccessTokens(userID uuid.UUID) error {
+	return s.invalidateAccessTokens(userID)
+}
+
+// invalidateAccessTokens rejects every access token issued to the user so far. The cutoff is
+// truncated to whole seconds like the JWT iat claim, so tokens issued right after still pass.
+func (s *authService) invalidateAccessTokens(userID uuid.UUID) error {
+	at := time.Now().Truncate(time.Second)
+	if err := s.revocationRepo.SetTokensInvalidBefore(userID, at); err != nil {
+		return err
+	}
+	s.revocations.setInvalidBefore(userID, at)
+	return nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestRevocationCache(t *testing.T) {
+	now := time.Now()
+	user, other := uuid.New(), uuid.New()
+	rc := newRevocationCache()
+
+	if _, synced := rc.check("jti-1", user, now); synced {
+		t.Fatal("cache reports synced before the first sync")
+	}
+
+	rc.apply(&models.RevocationsResponse{
+		ServerTime: now,
+		RevokedTokens: []models.RevokedAccessToken{
+			{JTI: "jti-1", ExpiresAt: now.Add(time.Minute)},
+			{JTI: "jti-expired", ExpiresAt: now.Add(-time.Minute)},
+		},
+		TokenCutoffs: []models.TokenCutoff{{UserID: user, InvalidBefore: now}},
+	})
+
+	tests := []struct {
+		name     string
+		jti      string
+		userID   uuid.UUID
+		issuedAt time.Time
+		want     bool
+	}{
+		{"revoked token", "jti-1", other, now, true},
+		{"issued before the cutoff", "jti-2", user, now.Add(-time.Second), true},
+		{"issued at the cutoff", "jti-2", user, now, false},
+		{"other user", "jti-2", other, now.Add(-time.Hour), false},
+		{"no token ID", "", other, now, false},
+	}
+	for _, tt := range tests {
+		revoked, synced := rc.check(tt.jti, tt.userID, tt.issuedAt)
+		if !synced || revoked != tt.want {
+			t.Errorf("%s: check = (%v, %v), want (%v, true)", tt.name, revoked, synced, tt.want)
+		}
+	}
+	if _, ok := rc.revokedTokens["jti-expired"]; ok {
+		t.Error("expired revocation was kept")
+	}
+
+	// Revocations made by this instance apply before the next sync
+	rc.revokeToken("jti-3", now.Add(time.Minute))
+	rc.setInvalidBefore(other, now)
+	if revoked, _ := rc.check("jti-3", uuid.New(), now); !revoked {
+		t.Error("locally revoked token accepted")
+	}
+	if revoked, _ := rc.check("jti-4", other, now.Add(-time.Second)); !revoked {
+		t.Error("local cutoff not applied")
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// ListSessions returns the user's signed-in devices. currentSessionID marks the caller's own session.
+func (s *authService) ListSessions(userID, currentSessionID uuid.UUID) ([]models.SessionResponse, error) {
+	tokens, err := s.tokenRepo.FindActiveSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]models.SessionResponse, 0, len(tokens))
+	for _, token := range tokens {
+		sessions = append(sessions, models.SessionResponse{
+			ID:         token.FamilyID.String(),
+			DeviceName: token.DeviceName,
+			DeviceType: token.DeviceType,
+			IPAddress:  token.IPAddress,
+			SignedInAt: token.CreatedAt,
+			LastUsedAt: token.LastUsedAt,
+			ExpiresAt:  token.ExpiresAt,
+			Current:    token.FamilyID == currentSessionID,
+		})
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs one of the user's devices out
+func (s *authService) RevokeSession(userID, sessionID uuid.UUID, ip, userAgent string) error {
+	revoked, err := s.tokenRepo.RevokeFamily(userID, sessionID, &userID, "session_revoked")
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+
+	s.logSessionRevoked(userID, sessionID, "session_revoked", ip, userAgent)
+	return nil
+}
+
+// RevokeOtherSessions signs out every device except the caller's and returns how many were signed out.
+// Tokens issued before sessions were tracked carry no session ID, so every session is signed out for them.
+func (s *authService) RevokeOtherSessions(userID, currentSessionID uuid.UUID, ip, userAgent string) (int, error) {
+	sessionIDs, err := s.tokenRepo.RevokeUserSessions(userID, currentSessionID, &userID, "logout_others")
+	if err != nil {
+		return 0, err
+	}
+
+	for _, sessionID := range sessionIDs {
+		s.logSessionRevoked(userID, sessionID, "logout_others", ip, userAgent)
+	}
+	return len(sessionIDs), nil
+}
+
+// handleRefreshTokenReuse revokes the session of a refresh token that was presented after rotation
+func (s *authService) handleRefreshTokenReuse(token *models.RefreshToken, ip, userAgent string) {
+	log.Printf("⚠️  Refresh token reuse detected for user %s (session %s), revoking session", token.UserID, token.FamilyID)
+	s.logAudit(&token.UserID, "refresh_token_reuse", "failed", ip, userAgent, "rotated refresh token presented again")
+
+	revoked, err := s.tokenRepo.RevokeFamily(token.UserID, token.FamilyID, nil, "reuse_detected")
+	if err != nil {
+		log.Printf("❌ Failed to revoke session %s: %v", token.FamilyID, err)
+		return
+	}
+	if revoked {
+		s.logSessionRevoked(token.UserID, token.FamilyID, "reuse_detected", ip, userAgent)
+	}
+}
+
+// revokeAllSessions signs the user out everywhere, e.g. after a password change.
+// Access tokens already issued are rejected as well.
+func (s *authService) revokeAllSessions(userID uuid.UUID, revokedBy *uuid.UUID, reason, ip string) {
+	if err := s.invalidateAccessTokens(userID); err != nil {
+		log.Printf("❌ Failed to invalidate access tokens for user %s: %v", userID, err)
+	}
+
+	sessionIDs, err := s.tokenRepo.RevokeUserSessions(userID, uuid.Nil, revokedBy, reason)
+	if err != nil {
+		log.Printf("❌ Failed to revoke sessions for user %s: %v", userID, err)
+		return
+	}
+
+	for _, sessionID := range sessionIDs {
+		s.logSessionRevoked(userID, sessionID, reason, ip, "")
+	}
+}
+
+// logSessionRevoked writes one audit entry per revoked session
+func (s *authService) logSessionRevoked(userID, sessionID uuid.UUID, reason, ip, userAgent string) {
+	metadata := fmt.Sprintf(`{"session_id":%q,"reason":%q}`, sessionID.String(), reason)
+	entry := &models.AuditLog{
+		UserID:      &userID,
+		EventType:   "session_revoked",
+		EventStatus: "success",
+		Metadata:    &metadata,
+	}
+	if ip != "" {
+		entry.IPAddress = &ip
+	}
+	if userAgent != "" {
+		entry.UserAgent = &userAgent
+	}
+
+	s.auditRepo.Create(entry)
+}
+
+func invalidRefreshTokenResponse() *models.AuthResponse {
+	return &models.AuthResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    "INVALID_TOKEN",
+			Message: "Invalid or expired refresh token",
+		},
+	}
+}
+
+// applyDeviceInfo fills a new session's device name and type from the User-Agent
+func applyDeviceInfo(token *models.RefreshToken, userAgent string) {
+	if userAgent == "" {
+		return
+	}
+	name, deviceType := describeDevice(userAgent)
+	token.DeviceName = &name
+	token.DeviceType = &deviceType
+}
+
+// describeDevice returns a display name such as "Chrome on Windows" and one of
+// 'mobile', 'tablet' or 'desktop'
+func describeDevice(userAgent string) (string, string) {
+	ua := strings.ToLower(userAgent)
+
+	deviceType := "desktop"
+	switch {
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet"):
+		deviceType = "tablet"
+	case strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		deviceType = "tablet"
+	case strings.Contains(ua, "mobile") || strings.Contains(ua, "iphone") || strings.Contains(ua, "android"):
+		deviceType = "mobile"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(ua, "iphone"):
+		os = "iPhone"
+	case strings.Contains(ua, "ipad"):
+		os = "iPad"
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "mac os"):
+		os = "macOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	// Order matters: Edge and Opera also report Chrome, and Chrome also reports Safari
+	browser := ""
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/"):
+		browser = "Opera"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "firefox/") || strings.Contains(ua, "fxios/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os, deviceType
+	case os != "":
+		return os, deviceType
+	case browser != "":
+		return browser, deviceType
+	default:
+		return "Unknown device", deviceType
+	}
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/config"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+func TestDescribeDevice(t *testing.T) {
+	tests := []struct {
+		userAgent  string
+		wantName   string
+		wantDevice string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", "Chrome on Windows", "desktop"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0", "Edge on Windows", "desktop"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15", "Safari on macOS", "desktop"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1", "Safari on iPhone", "mobile"},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36", "Chrome on Android", "mobile"},
+		{"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", "Chrome on Android", "tablet"},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", "Firefox on Linux", "desktop"},
+		{"okhttp/4.12.0", "Unknown device", "desktop"},
+	}
+
+	for _, tt := range tests {
+		name, deviceType := describeDevice(tt.userAgent)
+		if name != tt.wantName || deviceType != tt.wantDevice {
+			t.Errorf("describeDevice(%q) = (%q, %q), want (%q, %q)", tt.userAgent, name, deviceType, tt.wantName, tt.wantDevice)
+		}
+	}
+}
+
+// fakeTokenRepo keeps refresh tokens in memory, keyed by hash, with the repository's
+// rotation and revocation rules
+type fakeTokenRepo struct {
+	repository.TokenRepository
+	tokens map[string]*models.RefreshToken
+	// loseRotation makes the next RotateToken fail as if a concurrent request rotated first
+	loseRotation bool
+}
+
+func (r *fakeTokenRepo) CreateRefreshToken(token *models.RefreshToken) error {
+	stored := *token
+	stored.ID = uuid.New()
+	if stored.FamilyID == uuid.Nil {
+		stored.FamilyID = stored.ID
+	}
+	r.tokens[stored.TokenHash] = &stored
+	return nil
+}
+
+func (r *fakeTokenRepo) FindRefreshTokenIncludingRevoked(tokenHash string) (*models.RefreshToken, error) {
+	token, ok := r.tokens[tokenHash]
+	if !ok {
+		return nil, fmt.Errorf("token not found")
+	}
+	found := *token
+	return &found, nil
+}
+
+func (r *fakeTokenRepo) RotateToken(tokenID uuid.UUID) (bool, error) {
+	if r.loseRotation {
+		r.loseRotation = false
+		return false, nil
+	}
+	for _, token := range r.tokens {
+		if token.ID == tokenID && token.RevokedAt == nil {
+			r.revoke(token, "rotated")
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeTokenRepo) RevokeFamily(userID, familyID uuid.UUID, revokedBy *uuid.UUID, reason string) (bool, error) {
+	revoked := false
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.FamilyID == familyID && token.RevokedAt == nil {
+			r.revoke(token, reason)
+			revoked = true
+		}
+	}
+	return revoked, nil
+}
+
+func (r *fakeTokenRepo) revoke(token *models.RefreshToken, reason string) {
+	now := time.Now()
+	token.RevokedAt = &now
+	token.RevokedReason = &reason
+}
+
+// newSessionTestService returns an auth service with one active student
+func newSessionTestService() (*authService, *fakeTokenRepo, *fakeAuditRepo, uuid.UUID) {
+	roles := newFakeRoleRepo()
+	roles.addRole(testStudentRole, "student")
+	userID := uuid.New()
+	roles.assign(userID, testStudentRole)
+
+	tokens := &fakeTokenRepo{tokens: map[string]*models.RefreshToken{}}
+	audit := &fakeAuditRepo{}
+	svc := &authService{
+		userRepo:  fakeUserRepo{},
+		roleRepo:  roles,
+		tokenRepo: tokens,
+		auditRepo: audit,
+		config:    &config.Config{JWTSecret: "test-secret", JWTExpiry: "15m", RefreshTokenExpiry: "168h"},
+	}
+	return svc, tokens, audit, userID
+}
+
+// signIn starts a new session and returns its refresh token
+func signIn(t *testing.T, svc *authService, userID uuid.UUID, userAgent string) string {
+	t.Helper()
+	_, refreshToken, _, err := svc.generateTokens(userID, "student@example.com", "student", "10.0.0.1", userAgent, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return refreshToken
+}
+
+func refresh(t *testing.T, svc *authService, refreshToken string) *models.AuthResponse {
+	t.Helper()
+	resp, err := svc.RefreshToken(&models.RefreshTokenRequest{RefreshToken: refreshToken}, "10.0.0.2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func (r *fakeTokenRepo) get(t *testing.T, svc *authService, refreshToken string) *models.RefreshToken {
+	t.Helper()
+	token, ok := r.tokens[svc.hashToken(refreshToken)]
+	if !ok {
+		t.Fatalf("refresh token %s not stored", refreshToken)
+	}
+	return token
+}
+
+func revokedReason(token *models.RefreshToken) string {
+	if token.RevokedAt == nil || token.RevokedReason == nil {
+		return ""
+	}
+	return *token.RevokedReason
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	svc, tokens, _, userID := newSessionTestService()
+	const chromeOnWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	first := signIn(t, svc, userID, chromeOnWindows)
+
+	resp := refresh(t, svc, first)
+	if !resp.Success {
+		t.Fatalf("refresh failed: %+v", resp.Error)
+	}
+	second := resp.Data.RefreshToken
+	if second == "" || second == first {
+		t.Fatalf("refresh returned refresh token %q, want a new one", second)
+	}
+	if resp.Data.AccessToken == "" {
+		t.Error("refresh returned no access token")
+	}
+
+	old, rotated := tokens.get(t, svc, first), tokens.get(t, svc, second)
+	if reason := revokedReason(old); reason != "rotated" {
+		t.Errorf("old token revoked reason = %q, want rotated", reason)
+	}
+	if rotated.RevokedAt != nil {
+		t.Error("new token is revoked")
+	}
+	if rotated.FamilyID != old.FamilyID {
+		t.Error("new token started another session")
+	}
+	if rotated.DeviceName == nil || *rotated.DeviceName != "Chrome on Windows" {
+		t.Errorf("new token device = %v, want the session's device", rotated.DeviceName)
+	}
+
+	// The new token rotates in turn
+	if resp := refresh(t, svc, second); !resp.Success {
+		t.Fatalf("second refresh failed: %+v", resp.Error)
+	}
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	svc, tokens, audit, userID := newSessionTestService()
+	first := signIn(t, svc, userID, "")
+	other := signIn(t, svc, userID, "")
+
+	second := refresh(t, svc, first).Data.RefreshToken
+	latest := refresh(t, svc, second).Data.RefreshToken
+
+	// The first token has leaked: presenting it again signs the whole session out
+	resp := refresh(t, svc, first)
+	if resp.Success || resp.Error.Code != "INVALID_TOKEN" {
+		t.Fatalf("reused token response = %+v, want INVALID_TOKEN", resp)
+	}
+	if reason := revokedReason(tokens.get(t, svc, latest)); reason != "reuse_detected" {
+		t.Errorf("latest token of the session revoked reason = %q, want reuse_detected", reason)
+	}
+	if resp := refresh(t, svc, latest); resp.Success {
+		t.Error("the session's latest token still refreshes after reuse")
+	}
+	if tokens.get(t, svc, other).RevokedAt != nil {
+		t.Error("reuse revoked the user's other session")
+	}
+
+	wantEvents := map[string]bool{"refresh_token_reuse": false, "session_revoked": false}
+	for _, event := range audit.events {
+		if _, ok := wantEvents[event]; ok {
+			wantEvents[event] = true
+		}
+	}
+	for event, logged := range wantEvents {
+		if !logged {
+			t.Errorf("no %s audit entry", event)
+		}
+	}
+}
+
+func TestRefreshTokenLostRotationIsReuse(t *testing.T) {
+	svc, tokens, _, userID := newSessionTestService()
+	first := signIn(t, svc, userID, "")
+
+	// Two requests present the same token; this one loses the race to rotate it
+	tokens.loseRotation = true
+	resp := refresh(t, svc, first)
+	if resp.Success {
+		t.Fatal("refresh succeeded although another request rotated the token")
+	}
+	if reason := revokedReason(tokens.get(t, svc, first)); reason != "reuse_detected" {
+		t.Errorf("token revoked reason = %q, want reuse_detected", reason)
+	}
+}
+
+func TestRefreshTokenRevokedOtherwiseIsNotReuse(t *testing.T) {
+	svc, tokens, audit, userID := newSessionTestService()
+	first := signIn(t, svc, userID, "")
+	token := tokens.get(t, svc, first)
+	tokens.revoke(token, "logout")
+
+	if resp := refresh(t, svc, first); resp.Success {
+		t.Fatal("a logged-out token refreshed")
+	}
+	if reason := revokedReason(token); reason != "logout" {
+		t.Errorf("revoked reason = %q, want logout kept", reason)
+	}
+	for _, event := range audit.events {
+		if event == "refresh_token_reuse" {
+			t.Error("a logged-out token was reported as reuse")
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B secret ("12345678901234567890"), SHA-1 vectors truncated to 6 digits
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFCVectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := totpCode(rfcSecret, tt.unix/totpPeriod)
+		if err != nil {
+			t.Fatalf("totpCode(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("totpCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+
+	previous, _ := totpCode(rfcSecret, step-1)
+	if got, ok := validateTOTP(rfcSecret, previous, now); !ok || got != step-1 {
+		t.Errorf("code from previous period: got step %d ok=%v", got, ok)
+	}
+
+	stale, _ := totpCode(rfcSecret, step-2)
+	if _, ok := validateTOTP(rfcSecret, stale, now); ok {
+		t.Error("code from two periods ago should be rejected")
+	}
+
+	if _, ok := validateTOTP(rfcSecret, "12345", now); ok {
+		t.Error("short code should be rejected")
+	}
+}
+
+func TestSecretEncryptionRoundTrip(t *testing.T) {
+	encrypted, err := encryptSecret("key", rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(encrypted, rfcSecret) {
+		t.Fatal("secret stored in clear text")
+	}
+
+	decrypted, err := decryptSecret("key", encrypted)
+	if err != nil || decrypted != rfcSecret {
+		t.Fatalf("decryptSecret = %q, %v", decrypted, err)
+	}
+
+	if _, err := decryptSecret("other key", encrypted); err == nil {
+		t.Error("decrypting with the wrong key should fail")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("unexpected code format %q", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+
+	if normalizeRecoveryCode(" ABCDE-fghij ") != "abcdefghij" {
+		t.Error("normalizeRecoveryCode should ignore case, spaces and dashes")
+	}
+}
@@ -0,0 +1,431 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorSetupMissing   = errors.New("totp setup has not been started")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrInvalidPassword         = errors.New("invalid password")
+	ErrChallengeNotFound       = errors.New("two-factor challenge not found or expired")
+	ErrEmailCodeRecentlySent   = errors.New("an email code was sent recently, please wait before requesting another")
+)
+
+const (
+	maxChallengeAttempts    = 5
+	emailCodeResendInterval = time.Minute
+)
+
+// StartTwoFactorChallenge returns a challenge response when the user must pass a second factor:
+// users with TOTP enabled, and users whose role requires 2FA (who fall back to an email code
+// when not enrolled). Returns nil when the login can complete immediately.
+func (s *authService) StartTwoFactorChallenge(user *models.User, roleName, ip, userAgent string) (*models.AuthResponse, error) {
+	twoFactor, err := s.twoFactorRepo.FindByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	totpEnabled := twoFactor != nil && twoFactor.TOTPEnabled
+	if !totpEnabled && !s.twoFactorRequired(roleName) {
+		return nil, nil
+	}
+
+	challengeToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := time.ParseDuration(s.config.TwoFactorChallengeTTL)
+	if err != nil {
+		ttl = 5 * time.Minute
+	}
+
+	challenge := &models.TwoFactorChallenge{
+		UserID:    user.ID,
+		TokenHash: s.hashToken(challengeToken),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.twoFactorRepo.CreateChallenge(challenge); err != nil {
+		return nil, err
+	}
+
+	methods := []string{"email"}
+	if totpEnabled {
+		methods = []string{"totp", "recovery_code", "email"}
+	} else if err := s.sendChallengeEmailCode(challenge, user.Email, ip, userAgent); err != nil {
+		// Not enrolled in TOTP: the email code is the only factor, so send it right away
+		log.Printf("❌ Failed to send 2FA email code to user %s: %v", user.ID, err)
+	}
+
+	s.logAudit(&user.ID, "2fa_challenge_issued", "success", ip, userAgent, "")
+
+	return &models.AuthResponse{
+		Success: true,
+		Data: &models.AuthData{
+			UserID:            user.ID.String(),
+			Email:             user.Email,
+			Role:              roleName,
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+			TwoFactorMethods:  methods,
+		},
+	}, nil
+}
+
+// VerifyTwoFactor completes a login challenge and issues the tokens
+func (s *authService) VerifyTwoFactor(req *models.TwoFactorVerifyRequest, ip, userAgent string) (*models.AuthResponse, error) {
+	challenge, err := s.twoFactorRepo.FindChallenge(s.hashToken(req.ChallengeToken))
+	if err != nil {
+		return twoFactorErrorResponse("INVALID_CHALLENGE", "Two-factor challenge is invalid or expired. Please log in again."), nil
+	}
+
+	// Take an attempt before checking the code, so the limit holds for parallel requests
+	attempts, ok, err := s.twoFactorRepo.UseChallengeAttempt(challenge.ID, maxChallengeAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return twoFactorErrorResponse("INVALID_CHALLENGE", "Two-factor challenge is invalid or expired. Please log in again."), nil
+	}
+
+	valid, err := s.verifyChallengeCode(challenge, req.Method, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		s.logAudit(&challenge.UserID, "2fa_verify", "failed", ip, userAgent, fmt.Sprintf("invalid %s code (attempt %d)", req.Method, attempts))
+		if attempts >= maxChallengeAttempts {
+			return twoFactorErrorResponse("INVALID_CHALLENGE", "Too many invalid codes. Please log in again."), nil
+		}
+		return twoFactorErrorResponse("INVALID_2FA_CODE", "Invalid verification code"), nil
+	}
+
+	completed, err := s.twoFactorRepo.CompleteChallenge(challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return twoFactorErrorResponse("INVALID_CHALLENGE", "Two-factor challenge is invalid or expired. Please log in again."), nil
+	}
+
+	user, err := s.userRepo.FindByID(challenge.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if !user.IsActive {
+		return twoFactorErrorResponse("ACCOUNT_INACTIVE", "Account is inactive"), nil
+	}
+
+	roles, err := s.roleRepo.FindByUserID(user.ID)
+	if err != nil || len(roles) == 0 {
+		return nil, fmt.Errorf("failed to find user roles: %w", err)
+	}
+	roleName := PrimaryRoleName(roles)
+
+	s.userRepo.UpdateLoginInfo(user.ID, ip)
+
+	accessToken, refreshToken, expiresIn, err := s.generateTokens(user.ID, user.Email, roleName, ip, userAgent, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAudit(&user.ID, "2fa_verify", "success", ip, userAgent, "")
+	s.logAudit(&user.ID, "login", "success", ip, userAgent, "")
+
+	return &models.AuthResponse{
+		Success: true,
+		Data: &models.AuthData{
+			UserID:       user.ID.String(),
+			Email:        user.Email,
+			Role:         roleName,
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+			ExpiresIn:    expiresIn,
+		},
+	}, nil
+}
+
+// SendTwoFactorEmailCode emails a code for a pending challenge (fallback when the authenticator is unavailable)
+func (s *authService) SendTwoFactorEmailCode(challengeToken, ip, userAgent string) error {
+	challenge, err := s.twoFactorRepo.FindChallenge(s.hashToken(challengeToken))
+	if err != nil || challenge.Attempts >= maxChallengeAttempts {
+		return ErrChallengeNotFound
+	}
+	if challenge.EmailCodeSentAt != nil && time.Since(*challenge.EmailCodeSentAt) < emailCodeResendInterval {
+		return ErrEmailCodeRecentlySent
+	}
+
+	user, err := s.userRepo.FindByID(challenge.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+
+	return s.sendChallengeEmailCode(challenge, user.Email, ip, userAgent)
+}
+
+// GetTwoFactorStatus reports whether TOTP is enabled and whether the user's role requires 2FA
+func (s *authService) GetTwoFactorStatus(userID uuid.UUID) (*models.TwoFactorStatusResponse, error) {
+	twoFactor, err := s.twoFactorRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.roleRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.TwoFactorStatusResponse{
+		TOTPEnabled: twoFactor != nil && twoFactor.TOTPEnabled,
+		Required:    s.twoFactorRequired(PrimaryRoleName(roles)),
+	}
+	if status.TOTPEnabled {
+		if status.RecoveryCodesRemaining, err = s.twoFactorRepo.CountRecoveryCodes(userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// SetupTOTP generates a new secret awaiting confirmation by EnableTOTP
+func (s *authService) SetupTOTP(userID uuid.UUID) (*models.TOTPSetupResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	twoFactor, err := s.twoFactorRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor != nil && twoFactor.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := encryptSecret(s.config.TwoFactorEncryptionKey, secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.UpsertPendingTOTP(userID, encrypted); err != nil {
+		return nil, err
+	}
+
+	return &models.TOTPSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(s.config.TwoFactorIssuer, user.Email, secret),
+	}, nil
+}
+
+// EnableTOTP confirms enrollment with a first code and returns the recovery codes
+func (s *authService) EnableTOTP(userID uuid.UUID, code string) ([]string, error) {
+	twoFactor, err := s.twoFactorRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor == nil {
+		return nil, ErrTwoFactorSetupMissing
+	}
+	if twoFactor.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := decryptSecret(s.config.TwoFactorEncryptionKey, twoFactor.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := validateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	if err := s.twoFactorRepo.EnableTOTP(userID, step); err != nil {
+		return nil, err
+	}
+	codes, err := s.replaceRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAudit(&userID, "2fa_enabled", "success", "", "", "")
+	return codes, nil
+}
+
+// DisableTOTP turns TOTP off after checking the password and a current TOTP or recovery code.
+// Users whose role requires 2FA fall back to email codes.
+func (s *authService) DisableTOTP(userID uuid.UUID, req *models.DisableTOTPRequest) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	// OAuth-only accounts have no password; the second factor alone is checked for them
+	if user.Password != nil && bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(req.Password)) != nil {
+		return ErrInvalidPassword
+	}
+
+	twoFactor, err := s.twoFactorRepo.FindByUserID(userID)
+	if err != nil {
+		return err
+	}
+	if twoFactor == nil || !twoFactor.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	valid, err := s.verifyTOTP(twoFactor, req.Code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		if valid, err = s.twoFactorRepo.UseRecoveryCode(userID, s.hashToken(normalizeRecoveryCode(req.Code))); err != nil {
+			return err
+		}
+	}
+	if !valid {
+		s.logAudit(&userID, "2fa_disabled", "failed", "", "", "invalid code")
+		return ErrInvalidTwoFactorCode
+	}
+
+	if err := s.twoFactorRepo.DisableTOTP(userID); err != nil {
+		return err
+	}
+
+	s.logAudit(&userID, "2fa_disabled", "success", "", "", "")
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a current TOTP code
+func (s *authService) RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error) {
+	twoFactor, err := s.twoFactorRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor == nil || !twoFactor.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	valid, err := s.verifyTOTP(twoFactor, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, err := s.replaceRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAudit(&userID, "2fa_recovery_codes_regenerated", "success", "", "", "")
+	return codes, nil
+}
+
+// verifyChallengeCode checks a login code with the requested method
+func (s *authService) verifyChallengeCode(challenge *models.TwoFactorChallenge, method, code string) (bool, error) {
+	switch method {
+	case "totp":
+		twoFactor, err := s.twoFactorRepo.FindByUserID(challenge.UserID)
+		if err != nil {
+			return false, err
+		}
+		if twoFactor == nil || !twoFactor.TOTPEnabled {
+			return false, nil
+		}
+		return s.verifyTOTP(twoFactor, code)
+	case "email":
+		if challenge.EmailCodeHash == nil {
+			return false, nil
+		}
+		return subtle.ConstantTimeCompare([]byte(*challenge.EmailCodeHash), []byte(s.hashToken(code))) == 1, nil
+	case "recovery_code":
+		used, err := s.twoFactorRepo.UseRecoveryCode(challenge.UserID, s.hashToken(normalizeRecoveryCode(code)))
+		if used {
+			log.Printf("⚠️  User %s logged in with a recovery code", challenge.UserID)
+		}
+		return used, err
+	default:
+		return false, nil
+	}
+}
+
+// verifyTOTP validates a code against an enabled enrollment, rejecting codes already used
+func (s *authService) verifyTOTP(twoFactor *models.UserTwoFactor, code string) (bool, error) {
+	secret, err := decryptSecret(s.config.TwoFactorEncryptionKey, twoFactor.TOTPSecret)
+	if err != nil {
+		return false, err
+	}
+	step, ok := validateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return s.twoFactorRepo.UseTOTPStep(twoFactor.UserID, step)
+}
+
+func (s *authService) sendChallengeEmailCode(challenge *models.TwoFactorChallenge, email, ip, userAgent string) error {
+	code := Generate6DigitCode()
+	if err := s.twoFactorRepo.SetChallengeEmailCode(challenge.ID, s.hashToken(code)); err != nil {
+		return err
+	}
+	if err := s.emailService.SendTwoFactorCodeEmail(email, code); err != nil {
+		return err
+	}
+
+	s.logAudit(&challenge.UserID, "2fa_email_code_sent", "success", ip, userAgent, "")
+	return nil
+}
+
+func (s *authService) replaceRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = s.hashToken(normalizeRecoveryCode(code))
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *authService) twoFactorRequired(roleName string) bool {
+	for _, role := range s.config.TwoFactorRequiredRoles {
+		if role == roleName {
+			return true
+		}
+	}
+	return false
+}
+
+func twoFactorErrorResponse(code, message string) *models.AuthResponse {
+	return &models.AuthResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+// randomToken returns a 256-bit random hex token
+func randomToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
@@ -100,6 +100,7 @@ func (m *AuthMiddleware) AuthRequired() gin.HandlerFunc {
 		c.Set("user_id", claims["user_id"])
 		c.Set("email", claims["email"])
 		c.Set("role", claims["role"])
+		c.Set("permissions", claimPermissions(claims))
 
 		c.Next()
 	}
@@ -127,6 +128,7 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 				c.Set("user_id", claims["user_id"])
 				c.Set("email", claims["email"])
 				c.Set("role", claims["role"])
+				c.Set("permissions", claimPermissions(claims))
 			}
 		}
 
@@ -168,3 +170,41 @@ func (m *AuthMiddleware) RequireRole(allowedRoles ...string) gin.HandlerFunc {
 		c.Abort()
 	}
 }
+
+// RequirePermission checks that the token grants at least one of the given permissions
+func (m *AuthMiddleware) RequirePermission(requiredPermissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, _ := c.Get("permissions")
+		permissions, _ := granted.([]string)
+
+		for _, required := range requiredPermissions {
+			for _, p := range permissions {
+				if p == required {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.JSON(http.StatusForbidden, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "FORBIDDEN",
+				Message: "Insufficient permissions",
+			},
+		})
+		c.Abort()
+	}
+}
+
+// claimPermissions reads the permissions claim issued by auth-service
+func claimPermissions(claims jwt.MapClaims) []string {
+	raw, _ := claims["permissions"].([]interface{})
+	permissions := make([]string, 0, len(raw))
+	for _, p := range raw {
+		if name, ok := p.(string); ok {
+			permissions = append(permissions, name)
+		}
+	}
+	return permissions
+}
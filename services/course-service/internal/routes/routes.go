@@ -85,30 +85,30 @@ func SetupRoutes(
 			progress.PUT("/lessons/:id", handler.UpdateLessonProgress) // Update lesson progress
 		}
 
-		// Admin routes (protected - instructor and admin only)
+		// Admin routes (protected - per-endpoint permissions)
 		admin := v1.Group("/admin")
 		admin.Use(authMiddleware.AuthRequired())
 		{
-			// Course management (instructor and admin)
-			admin.POST("/courses", authMiddleware.RequireRole("instructor", "admin"), handler.CreateCourse)
-			admin.PUT("/courses/:id", authMiddleware.RequireRole("instructor", "admin"), handler.UpdateCourse)
-			admin.POST("/courses/:id/publish", authMiddleware.RequireRole("instructor", "admin"), handler.PublishCourse)
-
-			// Course deletion (admin only)
-			admin.DELETE("/courses/:id", authMiddleware.RequireRole("admin"), handler.DeleteCourse)
-
-			// Module and lesson management (instructor and admin)
-			admin.POST("/modules", authMiddleware.RequireRole("instructor", "admin"), handler.CreateModule)
-			admin.POST("/lessons", authMiddleware.RequireRole("instructor", "admin"), handler.CreateLesson)
-
-			// Video management (instructor and admin)
-			admin.POST("/lessons/:lesson_id/videos", authMiddleware.RequireRole("instructor", "admin"), handler.AddVideoToLesson)
-
-			// Video duration sync (admin only)
-			admin.POST("/videos/sync-all", authMiddleware.RequireRole("admin"), handler.SyncAllVideoDurations)                      // Sync videos with missing duration
-			admin.POST("/videos/force-resync-all", authMiddleware.RequireRole("admin"), handler.ForceResyncAllVideos)               // Force re-sync ALL videos
-			admin.POST("/videos/:video_id/sync-duration", authMiddleware.RequireRole("admin"), handler.SyncSingleVideoDuration)     // Sync single video
-			admin.POST("/lessons/:lesson_id/sync-durations", authMiddleware.RequireRole("admin"), handler.SyncLessonVideoDurations) // Sync lesson videos
+			// Course management
+			admin.POST("/courses", authMiddleware.RequirePermission("course:create"), handler.CreateCourse)
+			admin.PUT("/courses/:id", authMiddleware.RequirePermission("course:update"), handler.UpdateCourse)
+			admin.POST("/courses/:id/publish", authMiddleware.RequirePermission("course:publish"), handler.PublishCourse)
+
+			// Course deletion
+			admin.DELETE("/courses/:id", authMiddleware.RequirePermission("course:delete"), handler.DeleteCourse)
+
+			// Module and lesson management
+			admin.POST("/modules", authMiddleware.RequirePermission("course:create"), handler.CreateModule)
+			admin.POST("/lessons", authMiddleware.RequirePermission("course:create"), handler.CreateLesson)
+
+			// Video management
+			admin.POST("/lessons/:lesson_id/videos", authMiddleware.RequirePermission("course:create"), handler.AddVideoToLesson)
+
+			// Video duration sync
+			admin.POST("/videos/sync-all", authMiddleware.RequirePermission("course:sync_videos"), handler.SyncAllVideoDurations)                      // Sync videos with missing duration
+			admin.POST("/videos/force-resync-all", authMiddleware.RequirePermission("course:sync_videos"), handler.ForceResyncAllVideos)               // Force re-sync ALL videos
+			admin.POST("/videos/:video_id/sync-duration", authMiddleware.RequirePermission("course:sync_videos"), handler.SyncSingleVideoDuration)     // Sync single video
+			admin.POST("/lessons/:lesson_id/sync-durations", authMiddleware.RequirePermission("course:sync_videos"), handler.SyncLessonVideoDurations) // Sync lesson videos
 		}
 	}
 }
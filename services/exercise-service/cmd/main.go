@@ -37,7 +37,7 @@ func main() {
 
 	// Initialize layers
 	exerciseRepo := repository.NewExerciseRepository(db)
-	exerciseService := service.NewExerciseService(exerciseRepo, userServiceClient, notificationClient, aiServiceClient, storageServiceClient)
+	exerciseService := service.NewExerciseService(exerciseRepo, userServiceClient, notificationClient, aiServiceClient, storageServiceClient, cfg.StorageMediaHosts)
 	exerciseHandler := handlers.NewExerciseHandler(exerciseService)
 	storageHandler := handlers.NewStorageHandler(storageServiceClient)
 	authMiddleware := middleware.NewAuthMiddleware(cfg)
@@ -55,6 +55,15 @@ func main() {
 	// FIX #8, #9: Start background sync retry worker
 	go exerciseService.StartSyncRetryWorker()
 
+	// Start durable Writing/Speaking evaluation worker (runs recovery sweep first)
+	go exerciseService.StartEvaluationWorker()
+
+	// Start sweeper that auto-submits expired timed attempts and abandons idle ones
+	go exerciseService.StartAttemptSweeper()
+
+	// Start worker that feeds item facility back into question bank difficulty
+	go exerciseService.StartBankDifficultyWorker()
+
 	// Start server
 	log.Printf("Exercise Service running on port %s", cfg.ServerPort)
 	if err := router.Run(":" + cfg.ServerPort); err != nil {
@@ -63,13 +63,26 @@ type WritingEvaluationResponse struct {
 				Feedback string  `json:"feedback"`
 			} `json:"grammatical_range"`
 		} `json:"detailed_feedback"`
-		ExaminerFeedback    string   `json:"examiner_feedback"`
-		Strengths           []string `json:"strengths"`
-		AreasForImprovement []string `json:"areas_for_improvement"`
+		ExaminerFeedback    string           `json:"examiner_feedback"`
+		Strengths           []string         `json:"strengths"`
+		AreasForImprovement []string         `json:"areas_for_improvement"`
+		Confidence          *ScoreConfidence `json:"confidence,omitempty"`
+		TextAnalysis        json.RawMessage  `json:"text_analysis,omitempty"` // Word count, vocabulary and cohesion measures
+		Provider            string           `json:"provider,omitempty"`      // Evaluator that scored it; "heuristic" is an estimate
 	} `json:"data"`
 	Message string `json:"message,omitempty"`
 }
 
+// ScoreConfidence reports how far the AI service's independent evaluations agreed.
+// Only present when the service scores with more than one sample.
+type ScoreConfidence struct {
+	Samples        int                `json:"samples"`
+	Agreement      float64            `json:"agreement"`
+	OverallSpread  float64            `json:"overall_spread"`
+	CriteriaSpread map[string]float64 `json:"criteria_spread"`
+	NeedsReview    bool               `json:"needs_review"`
+}
+
 // SpeakingTranscriptionRequest represents request to transcribe speaking
 type SpeakingTranscriptionRequest struct {
 	AudioURL string `json:"audio_url"`
@@ -79,20 +92,22 @@ type SpeakingTranscriptionRequest struct {
 type SpeakingTranscriptionResponse struct {
 	Success bool `json:"success"`
 	Data    struct {
-		TranscriptText string `json:"transcript_text"`
-		AudioDuration  int    `json:"audio_duration_seconds"`
+		TranscriptText string          `json:"transcript_text"`
+		AudioDuration  int             `json:"audio_duration_seconds"`
+		SpeechMetrics  json.RawMessage `json:"speech_metrics,omitempty"` // Measured from word timings, passed back for evaluation
 	} `json:"data"`
 	Message string `json:"message,omitempty"`
 }
 
 // SpeakingEvaluationRequest represents request to evaluate speaking
 type SpeakingEvaluationRequest struct {
-	AudioURL       string  `json:"audio_url"`
-	TranscriptText string  `json:"transcript_text"`
-	PromptText     string  `json:"prompt_text"`
-	PartNumber     int     `json:"part_number"` // 1, 2, 3
-	WordCount      int     `json:"word_count"`
-	Duration       float64 `json:"duration"`
+	AudioURL       string          `json:"audio_url"`
+	TranscriptText string          `json:"transcript_text"`
+	PromptText     string          `json:"prompt_text"`
+	PartNumber     int             `json:"part_number"` // 1, 2, 3
+	WordCount      int             `json:"word_count"`
+	Duration       float64         `json:"duration"`
+	SpeechMetrics  json.RawMessage `json:"speech_metrics,omitempty"`
 }
 
 // SpeakingEvaluationResponse represents response from speaking evaluation
@@ -124,9 +139,12 @@ type SpeakingEvaluationResponse struct {
 				Analysis string  `json:"analysis"`
 			} `json:"pronunciation"`
 		} `json:"detailed_feedback"`
-		ExaminerFeedback    string   `json:"examiner_feedback"`
-		Strengths           []string `json:"strengths"`
-		AreasForImprovement []string `json:"areas_for_improvement"`
+		ExaminerFeedback    string           `json:"examiner_feedback"`
+		Strengths           []string         `json:"strengths"`
+		AreasForImprovement []string         `json:"areas_for_improvement"`
+		Confidence          *ScoreConfidence `json:"confidence,omitempty"`
+		SpeechMetrics       json.RawMessage  `json:"speech_metrics,omitempty"`
+		Provider            string           `json:"provider,omitempty"` // Evaluator that scored it; "heuristic" is an estimate
 	} `json:"data"`
 	Message string `json:"message,omitempty"`
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 )
 
 type Config struct {
@@ -20,6 +21,9 @@ type Config struct {
 	AIServiceURL           string
 	StorageServiceURL      string
 	InternalAPIKey         string
+
+	// Hosts (host:port) that exercise media is served from; package export only downloads from these
+	StorageMediaHosts []string
 }
 
 func LoadConfig() *Config {
@@ -38,6 +42,7 @@ func LoadConfig() *Config {
 		AIServiceURL:           getEnv("AI_SERVICE_URL", "http://ai-service:8086"),
 		StorageServiceURL:      getEnv("STORAGE_SERVICE_URL", "http://storage-service:8087"),
 		InternalAPIKey:         getEnv("INTERNAL_API_KEY", "internal_secret_key_ielts_2025_change_in_production"),
+		StorageMediaHosts:      getEnvAsList("STORAGE_MEDIA_HOSTS", "localhost:9000,minio:9000"),
 	}
 
 	if config.DBPassword == "" {
@@ -58,3 +63,14 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvAsList splits a comma-separated variable
+func getEnvAsList(key, defaultValue string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
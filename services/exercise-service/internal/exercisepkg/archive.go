@@ -0,0 +1,135 @@
+package exercisepkg
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	MaxPackageSize = 200 << 20 // Whole upload, media included; also the limit on a zip's unpacked size
+	maxManifest    = 10 << 20
+	maxZipEntries  = 500
+)
+
+// ErrMalformed is returned for uploads that are neither package JSON nor a package zip
+var ErrMalformed = errors.New("malformed exercise package")
+
+// Read decodes a package from JSON or from a zip holding package.json and media files.
+// The returned files are keyed by their path in the zip.
+func Read(data []byte) (*Package, map[string][]byte, error) {
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		pkg, err := decodeManifest(data)
+		return pkg, nil, err
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if len(zr.File) > maxZipEntries {
+		return nil, nil, fmt.Errorf("%w: more than %d files", ErrMalformed, maxZipEntries)
+	}
+
+	var pkg *Package
+	files := make(map[string][]byte)
+	remaining := int64(MaxPackageSize) // Unpacked bytes left, so small entries cannot add up to a zip bomb
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		switch {
+		case f.Name == ManifestName:
+			content, err := readZipFile(f, min(maxManifest, remaining))
+			if err != nil {
+				return nil, nil, err
+			}
+			remaining -= int64(len(content))
+			if pkg, err = decodeManifest(content); err != nil {
+				return nil, nil, err
+			}
+		case ValidMediaPath(f.Name):
+			content, err := readZipFile(f, remaining)
+			if err != nil {
+				return nil, nil, err
+			}
+			remaining -= int64(len(content))
+			files[f.Name] = content
+		default:
+			return nil, nil, fmt.Errorf("%w: unexpected file %q (only %s and %s* are allowed)", ErrMalformed, f.Name, ManifestName, MediaDir)
+		}
+	}
+	if pkg == nil {
+		return nil, nil, fmt.Errorf("%w: %s is missing from the zip", ErrMalformed, ManifestName)
+	}
+	return pkg, files, nil
+}
+
+func decodeManifest(data []byte) (*Package, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields() // Typos in hand-written packages should not be silently dropped
+	var pkg Package
+	if err := dec.Decode(&pkg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return &pkg, nil
+}
+
+// readZipFile reads one entry, failing once it unpacks to more than limit bytes
+// whatever size its header declares
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
+	if f.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%w: %s is too large", ErrMalformed, f.Name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: read %s: %v", ErrMalformed, f.Name, err)
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("%w: %s is too large", ErrMalformed, f.Name)
+	}
+	return content, nil
+}
+
+// WriteZip writes the package and its media files as a zip. Files are keyed by their
+// path in the zip, as listed in pkg.Media.
+func WriteZip(w io.Writer, pkg *Package, files map[string][]byte) error {
+	zw := zip.NewWriter(w)
+
+	manifest, err := json.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return err
+	}
+	mw, err := zw.Create(ManifestName)
+	if err != nil {
+		return err
+	}
+	if _, err := mw.Write(manifest); err != nil {
+		return err
+	}
+
+	for _, m := range pkg.Media {
+		content, ok := files[m.File]
+		if !ok {
+			continue
+		}
+		fw, err := zw.Create(m.File)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(content); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
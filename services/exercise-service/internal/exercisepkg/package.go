@@ -0,0 +1,435 @@
+// Package exercisepkg defines the exercise package format used to move exercises between
+// environments and to author them offline: one exercise with its sections, questions,
+// answer key, tags and media references, as versioned JSON or as a zip with the media files.
+// Database IDs are never exported; sections and questions are identified by their numbers
+// and the exercise by its slug.
+package exercisepkg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/grading"
+)
+
+const (
+	Format  = "ielts-exercise-package"
+	Version = 1
+
+	ManifestName = "package.json" // Package JSON inside a zip
+	MediaDir     = "media/"       // Media files inside a zip
+)
+
+// Package is a portable exercise
+type Package struct {
+	Format     string     `json:"format"`
+	Version    int        `json:"version"`
+	ExportedAt *time.Time `json:"exported_at,omitempty"`
+	Exercise   Exercise   `json:"exercise"`
+	Tags       []Tag      `json:"tags,omitempty"`
+	Sections   []Section  `json:"sections"`
+	Media      []Media    `json:"media,omitempty"` // Files bundled in a zip package
+}
+
+// Exercise holds the exercise content. Statistics, publishing state and course links
+// belong to an environment and are not part of a package.
+type Exercise struct {
+	Title                string   `json:"title"`
+	Slug                 string   `json:"slug"`
+	Description          *string  `json:"description,omitempty"`
+	ExerciseType         string   `json:"exercise_type"` // practice, mock_test, full_test, mini_test
+	SkillType            string   `json:"skill_type"`    // listening, reading, writing, speaking
+	IELTSTestType        *string  `json:"ielts_test_type,omitempty"`
+	Difficulty           string   `json:"difficulty"` // easy, medium, hard
+	IELTSLevel           *string  `json:"ielts_level,omitempty"`
+	TimeLimitMinutes     *int     `json:"time_limit_minutes,omitempty"`
+	ThumbnailURL         *string  `json:"thumbnail_url,omitempty"`
+	AudioURL             *string  `json:"audio_url,omitempty"`
+	AudioDurationSeconds *int     `json:"audio_duration_seconds,omitempty"`
+	AudioTranscript      *string  `json:"audio_transcript,omitempty"`
+	PassageCount         *int     `json:"passage_count,omitempty"`
+	PassingScore         *float64 `json:"passing_score,omitempty"`
+	IsFree               bool     `json:"is_free"`
+
+	WritingTaskType        *string `json:"writing_task_type,omitempty"`
+	WritingPromptText      *string `json:"writing_prompt_text,omitempty"`
+	WritingVisualType      *string `json:"writing_visual_type,omitempty"`
+	WritingVisualURL       *string `json:"writing_visual_url,omitempty"`
+	WritingWordRequirement *int    `json:"writing_word_requirement,omitempty"`
+
+	SpeakingPartNumber        *int     `json:"speaking_part_number,omitempty"`
+	SpeakingPromptText        *string  `json:"speaking_prompt_text,omitempty"`
+	SpeakingCueCardTopic      *string  `json:"speaking_cue_card_topic,omitempty"`
+	SpeakingCueCardPoints     []string `json:"speaking_cue_card_points,omitempty"`
+	SpeakingPreparationTime   *int     `json:"speaking_preparation_time,omitempty"`
+	SpeakingResponseTime      *int     `json:"speaking_response_time,omitempty"`
+	SpeakingFollowUpQuestions []string `json:"speaking_follow_up_questions,omitempty"`
+}
+
+// Tag is matched by slug on import and created if missing
+type Tag struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// Section is a Listening part or Reading passage with its questions
+type Section struct {
+	SectionNumber    int        `json:"section_number"`
+	Title            string     `json:"title"`
+	Description      *string    `json:"description,omitempty"`
+	AudioURL         *string    `json:"audio_url,omitempty"`
+	AudioStartTime   *int       `json:"audio_start_time,omitempty"`
+	AudioEndTime     *int       `json:"audio_end_time,omitempty"`
+	Transcript       *string    `json:"transcript,omitempty"`
+	PassageTitle     *string    `json:"passage_title,omitempty"`
+	PassageContent   *string    `json:"passage_content,omitempty"`
+	PassageWordCount *int       `json:"passage_word_count,omitempty"`
+	Instructions     *string    `json:"instructions,omitempty"`
+	TimeLimitMinutes *int       `json:"time_limit_minutes,omitempty"`
+	DisplayOrder     int        `json:"display_order"`
+	Questions        []Question `json:"questions"`
+}
+
+// Question carries its own answer key: options for choice questions, answers for the rest
+type Question struct {
+	QuestionNumber int      `json:"question_number"`
+	QuestionText   string   `json:"question_text"`
+	QuestionType   string   `json:"question_type"`
+	AudioURL       *string  `json:"audio_url,omitempty"`
+	ImageURL       *string  `json:"image_url,omitempty"`
+	ContextText    *string  `json:"context_text,omitempty"`
+	Points         float64  `json:"points"`
+	Difficulty     *string  `json:"difficulty,omitempty"`
+	Explanation    *string  `json:"explanation,omitempty"`
+	Tips           *string  `json:"tips,omitempty"`
+	DisplayOrder   int      `json:"display_order"`
+	Options        []Option `json:"options,omitempty"`
+	Answers        []Answer `json:"answers,omitempty"`
+}
+
+// Option is a choice of a multiple choice (or judgement) question
+type Option struct {
+	Label        string  `json:"label"`
+	Text         string  `json:"text"`
+	ImageURL     *string `json:"image_url,omitempty"`
+	IsCorrect    bool    `json:"is_correct"`
+	DisplayOrder int     `json:"display_order"`
+}
+
+// Answer is an accepted text answer. Label names the matching item or blank it answers.
+type Answer struct {
+	Label        *string  `json:"label,omitempty"`
+	Text         string   `json:"text"`
+	Alternatives []string `json:"alternatives,omitempty"`
+}
+
+// Media maps a media URL referenced by the package to the file bundled for it
+type Media struct {
+	URL         string `json:"url"`
+	File        string `json:"file"` // Path inside the zip, under media/
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// Counts summarizes the content of a package
+type Counts struct {
+	Sections  int `json:"sections"`
+	Questions int `json:"questions"`
+	Options   int `json:"options"`
+	Answers   int `json:"answers"`
+	Tags      int `json:"tags"`
+	Media     int `json:"media"`
+}
+
+// Counts returns how many items of each kind the package holds
+func (p *Package) Counts() Counts {
+	c := Counts{Sections: len(p.Sections), Tags: len(p.Tags), Media: len(p.Media)}
+	for _, s := range p.Sections {
+		c.Questions += len(s.Questions)
+		for _, q := range s.Questions {
+			c.Options += len(q.Options)
+			c.Answers += len(q.Answers)
+		}
+	}
+	return c
+}
+
+// MediaRef is a media URL field of the package
+type MediaRef struct {
+	Path string  // Item path, e.g. sections[0].audio_url
+	URL  *string // Points into the package, so the URL can be rewritten
+}
+
+// MediaRefs returns every media URL set in the package
+func (p *Package) MediaRefs() []MediaRef {
+	var refs []MediaRef
+	add := func(path string, url *string) {
+		if url != nil && strings.TrimSpace(*url) != "" {
+			refs = append(refs, MediaRef{Path: path, URL: url})
+		}
+	}
+
+	add("exercise.thumbnail_url", p.Exercise.ThumbnailURL)
+	add("exercise.audio_url", p.Exercise.AudioURL)
+	add("exercise.writing_visual_url", p.Exercise.WritingVisualURL)
+	for i := range p.Sections {
+		s := &p.Sections[i]
+		add(fmt.Sprintf("sections[%d].audio_url", i), s.AudioURL)
+		for j := range s.Questions {
+			q := &s.Questions[j]
+			path := fmt.Sprintf("sections[%d].questions[%d]", i, j)
+			add(path+".audio_url", q.AudioURL)
+			add(path+".image_url", q.ImageURL)
+			for k := range q.Options {
+				add(fmt.Sprintf("%s.options[%d].image_url", path, k), q.Options[k].ImageURL)
+			}
+		}
+	}
+	return refs
+}
+
+// Issue is a validation problem of one item of a package
+type Issue struct {
+	Path    string `json:"path"` // e.g. sections[1].questions[3].answers
+	Message string `json:"message"`
+}
+
+var (
+	slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+	exerciseTypes = map[string]bool{"practice": true, "mock_test": true, "full_test": true, "mini_test": true}
+	skillTypes    = map[string]bool{"listening": true, "reading": true, "writing": true, "speaking": true}
+	difficulties  = map[string]bool{"easy": true, "medium": true, "hard": true}
+
+	// Question types graded against options; the rest are graded against text answers
+	choiceTypes    = map[string]bool{grading.TypeMultipleChoice: true, grading.TypeMultipleChoiceMultiple: true}
+	judgementTypes = map[string]bool{
+		grading.TypeTrueFalseNotGiven: true, grading.TypeYesNoNotGiven: true, grading.TypeTrueFalse: true,
+	}
+	textTypes = map[string]bool{
+		grading.TypeMatching: true, grading.TypeMatchingHeadings: true, grading.TypeMatchingFeatures: true,
+		grading.TypeMatchingInformation: true, grading.TypeMatchingSentenceEnds: true,
+		grading.TypeMapLabeling: true, grading.TypeDiagramLabeling: true, grading.TypePlanLabeling: true,
+		grading.TypeFillInBlank: true, grading.TypeSentenceCompletion: true, grading.TypeSummaryCompletion: true,
+		grading.TypeNoteCompletion: true, grading.TypeTableCompletion: true, grading.TypeFormCompletion: true,
+		grading.TypeFlowChartCompletion: true, grading.TypeShortAnswer: true,
+	}
+)
+
+// Validate checks the package against the format and the content rules of each skill.
+// Errors make the package unimportable; warnings are worth a look but do not block it.
+func (p *Package) Validate() (errs, warnings []Issue) {
+	v := &validator{}
+
+	if p.Format != Format {
+		v.err("format", "must be %q", Format)
+	}
+	if p.Version < 1 || p.Version > Version {
+		v.err("version", "unsupported version %d (supported: 1-%d)", p.Version, Version)
+	}
+
+	v.exercise(&p.Exercise)
+
+	tagSlugs := make(map[string]bool)
+	for i, tag := range p.Tags {
+		path := fmt.Sprintf("tags[%d]", i)
+		if strings.TrimSpace(tag.Name) == "" {
+			v.err(path+".name", "is required")
+		}
+		if !slugPattern.MatchString(tag.Slug) {
+			v.err(path+".slug", "must be lowercase letters, digits and hyphens")
+		} else if tagSlugs[tag.Slug] {
+			v.err(path+".slug", "duplicate tag %q", tag.Slug)
+		}
+		tagSlugs[tag.Slug] = true
+	}
+
+	objective := p.Exercise.SkillType == "listening" || p.Exercise.SkillType == "reading"
+	if objective && len(p.Sections) == 0 {
+		v.err("sections", "a %s exercise needs at least one section", p.Exercise.SkillType)
+	}
+	if !objective && len(p.Sections) > 0 && skillTypes[p.Exercise.SkillType] {
+		v.warn("sections", "%s exercises are graded by AI; sections and questions are kept but not graded", p.Exercise.SkillType)
+	}
+
+	sectionNumbers := make(map[int]bool)
+	questionNumbers := make(map[int]string)
+	for i := range p.Sections {
+		s := &p.Sections[i]
+		path := fmt.Sprintf("sections[%d]", i)
+		if strings.TrimSpace(s.Title) == "" {
+			v.err(path+".title", "is required")
+		}
+		if s.SectionNumber < 1 {
+			v.err(path+".section_number", "must be 1 or more")
+		} else if sectionNumbers[s.SectionNumber] {
+			v.err(path+".section_number", "duplicate section number %d", s.SectionNumber)
+		}
+		sectionNumbers[s.SectionNumber] = true
+		if objective && len(s.Questions) == 0 {
+			v.warn(path+".questions", "section has no questions")
+		}
+
+		for j := range s.Questions {
+			q := &s.Questions[j]
+			qPath := fmt.Sprintf("%s.questions[%d]", path, j)
+			if q.QuestionNumber < 1 {
+				v.err(qPath+".question_number", "must be 1 or more")
+			} else if other, ok := questionNumbers[q.QuestionNumber]; ok {
+				v.err(qPath+".question_number", "question %d is also used by %s", q.QuestionNumber, other)
+			} else {
+				questionNumbers[q.QuestionNumber] = qPath
+			}
+			v.question(qPath, q)
+		}
+	}
+
+	mediaFiles := make(map[string]bool)
+	for i, m := range p.Media {
+		path := fmt.Sprintf("media[%d]", i)
+		if strings.TrimSpace(m.URL) == "" {
+			v.err(path+".url", "is required")
+		}
+		if !ValidMediaPath(m.File) {
+			v.err(path+".file", "must be a file name under %s", MediaDir)
+		} else if mediaFiles[m.File] {
+			v.err(path+".file", "duplicate file %q", m.File)
+		}
+		mediaFiles[m.File] = true
+	}
+
+	return v.errs, v.warnings
+}
+
+func (v *validator) exercise(e *Exercise) {
+	if strings.TrimSpace(e.Title) == "" {
+		v.err("exercise.title", "is required")
+	} else if len(e.Title) > 200 {
+		v.err("exercise.title", "must be at most 200 characters")
+	}
+	if !slugPattern.MatchString(e.Slug) {
+		v.err("exercise.slug", "must be lowercase letters, digits and hyphens")
+	} else if len(e.Slug) > 250 {
+		v.err("exercise.slug", "must be at most 250 characters")
+	}
+	if !exerciseTypes[e.ExerciseType] {
+		v.err("exercise.exercise_type", "must be one of practice, mock_test, full_test, mini_test")
+	}
+	if !skillTypes[e.SkillType] {
+		v.err("exercise.skill_type", "must be one of listening, reading, writing, speaking")
+	}
+	if !difficulties[e.Difficulty] {
+		v.err("exercise.difficulty", "must be one of easy, medium, hard")
+	}
+	if e.TimeLimitMinutes != nil && *e.TimeLimitMinutes <= 0 {
+		v.err("exercise.time_limit_minutes", "must be positive")
+	}
+
+	switch e.SkillType {
+	case "reading":
+		if e.IELTSTestType == nil || (*e.IELTSTestType != "academic" && *e.IELTSTestType != "general_training") {
+			v.err("exercise.ielts_test_type", "reading exercises must be academic or general_training")
+		}
+	case "writing":
+		if e.WritingTaskType == nil || (*e.WritingTaskType != "task1" && *e.WritingTaskType != "task2") {
+			v.err("exercise.writing_task_type", "writing exercises must be task1 or task2")
+		}
+		if e.WritingPromptText == nil || strings.TrimSpace(*e.WritingPromptText) == "" {
+			v.err("exercise.writing_prompt_text", "is required for writing exercises")
+		}
+	case "speaking":
+		if e.SpeakingPartNumber == nil || *e.SpeakingPartNumber < 1 || *e.SpeakingPartNumber > 3 {
+			v.err("exercise.speaking_part_number", "speaking exercises must be part 1, 2 or 3")
+		}
+		if e.SpeakingPromptText == nil || strings.TrimSpace(*e.SpeakingPromptText) == "" {
+			v.err("exercise.speaking_prompt_text", "is required for speaking exercises")
+		}
+	}
+	if e.SkillType != "reading" && e.IELTSTestType != nil {
+		v.err("exercise.ielts_test_type", "is only set for reading exercises")
+	}
+}
+
+func (v *validator) question(path string, q *Question) {
+	if strings.TrimSpace(q.QuestionText) == "" {
+		v.err(path+".question_text", "is required")
+	}
+	if q.Points < 0 {
+		v.err(path+".points", "must not be negative")
+	}
+
+	questionType := strings.ToLower(strings.TrimSpace(q.QuestionType))
+	switch {
+	case questionType == "":
+		v.err(path+".question_type", "is required")
+	case choiceTypes[questionType]:
+		if len(q.Options) < 2 {
+			v.err(path+".options", "a %s question needs at least two options", questionType)
+		}
+	case judgementTypes[questionType]:
+		if len(q.Options) == 0 && len(q.Answers) == 0 {
+			v.err(path+".answers", "needs the correct judgement as an answer or option")
+		}
+	case textTypes[questionType]:
+		if len(q.Answers) == 0 {
+			v.err(path+".answers", "a %s question needs at least one accepted answer", questionType)
+		}
+	default:
+		v.warn(path+".question_type", "unknown question type %q will be graded as text completion", q.QuestionType)
+		if len(q.Answers) == 0 && len(q.Options) == 0 {
+			v.err(path+".answers", "needs an answer key")
+		}
+	}
+
+	labels := make(map[string]bool)
+	correct := 0
+	for k, o := range q.Options {
+		oPath := fmt.Sprintf("%s.options[%d]", path, k)
+		label := strings.ToUpper(strings.TrimSpace(o.Label))
+		if label == "" {
+			v.err(oPath+".label", "is required")
+		} else if labels[label] {
+			v.err(oPath+".label", "duplicate option %q", o.Label)
+		}
+		labels[label] = true
+		if strings.TrimSpace(o.Text) == "" && o.ImageURL == nil {
+			v.err(oPath+".text", "needs text or an image")
+		}
+		if o.IsCorrect {
+			correct++
+		}
+	}
+	if len(q.Options) > 0 && correct == 0 && len(q.Answers) == 0 {
+		v.err(path+".options", "no option is marked correct")
+	}
+	if questionType == grading.TypeMultipleChoice && correct > 1 {
+		v.warn(path+".options", "%d options are correct; use %s for \"choose TWO\" questions", correct, grading.TypeMultipleChoiceMultiple)
+	}
+
+	for k, a := range q.Answers {
+		if strings.TrimSpace(a.Text) == "" {
+			v.err(fmt.Sprintf("%s.answers[%d].text", path, k), "is required")
+		}
+	}
+}
+
+// ValidMediaPath reports whether name is a plain file name under MediaDir
+func ValidMediaPath(name string) bool {
+	file := strings.TrimPrefix(name, MediaDir)
+	return strings.HasPrefix(name, MediaDir) && file != "" &&
+		!strings.ContainsAny(file, `/\`) && file != "." && file != ".."
+}
+
+type validator struct {
+	errs     []Issue
+	warnings []Issue
+}
+
+func (v *validator) err(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warn(path, format string, args ...interface{}) {
+	v.warnings = append(v.warnings, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
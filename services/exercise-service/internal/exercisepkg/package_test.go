@@ -0,0 +1,199 @@
+package exercisepkg
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func str(s string) *string { return &s }
+
+func readingPackage() *Package {
+	return &Package{
+		Format:  Format,
+		Version: Version,
+		Exercise: Exercise{
+			Title:         "Urban Farming",
+			Slug:          "reading-urban-farming",
+			ExerciseType:  "practice",
+			SkillType:     "reading",
+			IELTSTestType: str("academic"),
+			Difficulty:    "medium",
+		},
+		Tags: []Tag{{Name: "Environment", Slug: "environment"}},
+		Sections: []Section{{
+			SectionNumber:  1,
+			Title:          "Passage 1",
+			PassageContent: str("Cities are turning rooftops into farms..."),
+			Questions: []Question{
+				{QuestionNumber: 1, QuestionText: "What is the passage mainly about?", QuestionType: "multiple_choice", Points: 1,
+					Options: []Option{{Label: "A", Text: "Rooftop farms", IsCorrect: true}, {Label: "B", Text: "Parks"}}},
+				{QuestionNumber: 2, QuestionText: "Rooftop farms reduce heat.", QuestionType: "true_false_not_given", Points: 1,
+					Answers: []Answer{{Text: "TRUE"}}},
+				{QuestionNumber: 3, QuestionText: "Farms use ____ water.", QuestionType: "sentence_completion", Points: 1,
+					ImageURL: str("https://cdn.example.com/q3.png"),
+					Answers:  []Answer{{Text: "recycled", Alternatives: []string{"re-used"}}}},
+			},
+		}},
+	}
+}
+
+func paths(issues []Issue) []string {
+	var result []string
+	for _, issue := range issues {
+		result = append(result, issue.Path)
+	}
+	return result
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		mutate    func(p *Package)
+		wantErrs  []string
+		wantWarns []string
+	}{
+		{"valid package", func(p *Package) {}, nil, nil},
+		{"wrong format and future version", func(p *Package) { p.Format = "zip"; p.Version = Version + 1 }, []string{"format", "version"}, nil},
+		{"bad slug and missing reading variant", func(p *Package) {
+			p.Exercise.Slug = "Urban Farming"
+			p.Exercise.IELTSTestType = nil
+		}, []string{"exercise.slug", "exercise.ielts_test_type"}, nil},
+		{"duplicate question number", func(p *Package) {
+			p.Sections[0].Questions[2].QuestionNumber = 1
+		}, []string{"sections[0].questions[2].question_number"}, nil},
+		{"choice question without correct option", func(p *Package) {
+			p.Sections[0].Questions[0].Options[0].IsCorrect = false
+		}, []string{"sections[0].questions[0].options"}, nil},
+		{"completion question without answers", func(p *Package) {
+			p.Sections[0].Questions[2].Answers = nil
+		}, []string{"sections[0].questions[2].answers"}, nil},
+		{"unknown question type is a warning", func(p *Package) {
+			p.Sections[0].Questions[2].QuestionType = "cloze"
+		}, nil, []string{"sections[0].questions[2].question_type"}},
+		{"reading without sections", func(p *Package) { p.Sections = nil }, []string{"sections"}, nil},
+		{"writing needs task and prompt", func(p *Package) {
+			p.Exercise.SkillType = "writing"
+			p.Exercise.IELTSTestType = nil
+			p.Sections = nil
+		}, []string{"exercise.writing_task_type", "exercise.writing_prompt_text"}, nil},
+		{"media outside media dir", func(p *Package) {
+			p.Media = []Media{{URL: "https://cdn.example.com/q3.png", File: "media/../q3.png"}}
+		}, []string{"media[0].file"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := readingPackage()
+			tt.mutate(p)
+			errs, warnings := p.Validate()
+			if got := paths(errs); !reflect.DeepEqual(got, tt.wantErrs) {
+				t.Errorf("errors = %v, want %v (%+v)", got, tt.wantErrs, errs)
+			}
+			if got := paths(warnings); !reflect.DeepEqual(got, tt.wantWarns) {
+				t.Errorf("warnings = %v, want %v", got, tt.wantWarns)
+			}
+		})
+	}
+}
+
+func TestMediaRefs(t *testing.T) {
+	p := readingPackage()
+	p.Sections[0].AudioURL = str("  ")
+	refs := p.MediaRefs()
+	if len(refs) != 1 || refs[0].Path != "sections[0].questions[2].image_url" {
+		t.Fatalf("refs = %+v", refs)
+	}
+
+	*refs[0].URL = "https://new.example.com/q3.png"
+	if got := *p.Sections[0].Questions[2].ImageURL; got != "https://new.example.com/q3.png" {
+		t.Errorf("rewritten URL = %q", got)
+	}
+}
+
+func TestReadRoundTrip(t *testing.T) {
+	p := readingPackage()
+	p.Media = []Media{{URL: "https://cdn.example.com/q3.png", File: "media/001-q3.png", ContentType: "image/png"}}
+	files := map[string][]byte{"media/001-q3.png": []byte("png-bytes")}
+
+	var buf bytes.Buffer
+	if err := WriteZip(&buf, p, files); err != nil {
+		t.Fatal(err)
+	}
+	got, gotFiles, err := Read(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("zip round trip changed the package")
+	}
+	if !reflect.DeepEqual(gotFiles, files) {
+		t.Errorf("files = %v, want %v", gotFiles, files)
+	}
+
+	manifest, _ := json.Marshal(p)
+	got, gotFiles, err = Read(manifest)
+	if err != nil || gotFiles != nil || !reflect.DeepEqual(got, p) {
+		t.Errorf("JSON read = %v, %v, %v", got, gotFiles, err)
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	zipWith := func(names ...string) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, name := range names {
+			w, _ := zw.Create(name)
+			w.Write([]byte(`{"format":"ielts-exercise-package","version":1}`))
+		}
+		zw.Close()
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"unknown field", []byte(`{"format":"ielts-exercise-package","version":1,"exercize":{}}`), "unknown field"},
+		{"not json", []byte("hello"), "invalid character"},
+		{"zip without manifest", zipWith("media/a.mp3"), "package.json is missing"},
+		{"zip with stray file", zipWith(ManifestName, "../etc/passwd"), "unexpected file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Read(tt.data)
+			if !errors.Is(err, ErrMalformed) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want ErrMalformed containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadRejectsZipBomb(t *testing.T) {
+	// Each entry is under the per-file limit, together they unpack to more than MaxPackageSize
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create(ManifestName)
+	w.Write([]byte(`{"format":"ielts-exercise-package","version":1}`))
+	chunk := make([]byte, 1<<20)
+	for i := 0; i < 3; i++ {
+		w, _ := zw.Create(fmt.Sprintf("%s%03d.mp3", MediaDir, i))
+		for written := 0; written < MaxPackageSize/3+(1<<20); written += len(chunk) {
+			w.Write(chunk)
+		}
+	}
+	zw.Close()
+
+	if buf.Len() > MaxPackageSize/100 {
+		t.Fatalf("test zip is %d bytes, expected it to compress well", buf.Len())
+	}
+	if _, _, err := Read(buf.Bytes()); !errors.Is(err, ErrMalformed) || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("err = %v, want ErrMalformed for the unpacked size", err)
+	}
+}
@@ -0,0 +1,139 @@
+package grading
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// gradeChoice grades single and multi-select multiple choice against the correct options.
+// With several correct options ("Choose TWO letters") each one is an item worth partial credit,
+// and, as on the IELTS answer sheet, choosing more letters than asked scores nothing.
+func gradeChoice(key Key, response Response) Result {
+	if len(key.Options) == 0 {
+		return gradeCompletion(key, response)
+	}
+
+	var correct []Option
+	for _, option := range key.Options {
+		if option.IsCorrect {
+			correct = append(correct, option)
+		}
+	}
+	if len(correct) == 0 {
+		return single(ItemResult{Reason: ReasonNoAnswerKey})
+	}
+
+	selected, unknown := selectedOptions(key, response)
+	given := append(optionLabels(key.Options, selected), unknown...)
+
+	if len(given) == 0 {
+		items := make([]ItemResult, len(correct))
+		for i, option := range correct {
+			items[i] = ItemResult{Item: itemName(len(correct), i), Expected: option.Label, Reason: ReasonUnanswered}
+		}
+		return newResult(items, len(correct))
+	}
+
+	if len(given) > len(correct) {
+		items := make([]ItemResult, len(correct))
+		for i, option := range correct {
+			items[i] = ItemResult{Item: itemName(len(correct), i), Given: strings.Join(given, ","), Expected: option.Label, Reason: ReasonTooManyOptions}
+		}
+		return newResult(items, len(correct))
+	}
+
+	// Letters can be given in any order: credit each correct option that was chosen,
+	// and pair the remaining slots with the wrong choices for display
+	var wrong []string
+	for _, option := range key.Options {
+		if selected[option.ID] && !option.IsCorrect {
+			wrong = append(wrong, option.Label)
+		}
+	}
+	wrong = append(wrong, unknown...)
+
+	items := make([]ItemResult, len(correct))
+	for i, option := range correct {
+		item := ItemResult{Item: itemName(len(correct), i), Expected: option.Label}
+		if selected[option.ID] {
+			item.Given = option.Label
+			item.Correct = true
+		} else if len(wrong) > 0 {
+			item.Given = wrong[0]
+			wrong = wrong[1:]
+		} else {
+			item.Reason = ReasonUnanswered
+		}
+		items[i] = item
+	}
+	return newResult(items, len(correct))
+}
+
+// selectedOptions collects the chosen options, also accepting letters typed as text ("B, D").
+// Choices that are not options of this question are counted in unknown, and always wrong.
+func selectedOptions(key Key, response Response) (selected map[uuid.UUID]bool, unknown []string) {
+	selected = make(map[uuid.UUID]bool)
+	choose := func(id uuid.UUID) {
+		for _, option := range key.Options {
+			if option.ID == id {
+				selected[id] = true
+				return
+			}
+		}
+		unknown = append(unknown, "?")
+	}
+
+	if response.SelectedOptionID != nil {
+		choose(*response.SelectedOptionID)
+	}
+	for _, id := range response.SelectedOptionIDs {
+		if !selected[id] && (response.SelectedOptionID == nil || id != *response.SelectedOptionID) {
+			choose(id)
+		}
+	}
+	if len(selected) > 0 || len(unknown) > 0 || response.Text == nil {
+		return selected, unknown
+	}
+
+	letters := strings.FieldsFunc(strings.ToUpper(*response.Text), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '/' || r == ';' || r == '&'
+	})
+	for _, letter := range letters {
+		if letter == "AND" {
+			continue
+		}
+		found := false
+		for _, option := range key.Options {
+			if strings.EqualFold(option.Label, letter) {
+				selected[option.ID] = true
+				found = true
+			}
+		}
+		if !found {
+			unknown = append(unknown, letter)
+		}
+	}
+	return selected, unknown
+}
+
+func optionLabels(options []Option, selected map[uuid.UUID]bool) []string {
+	labels := []string{}
+	for _, option := range options {
+		if selected[option.ID] {
+			labels = append(labels, option.Label)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// itemName numbers the items of multi-item questions; single items are left unnamed
+func itemName(total, index int) string {
+	if total <= 1 {
+		return ""
+	}
+	return strconv.Itoa(index + 1)
+}
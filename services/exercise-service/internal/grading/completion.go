@@ -0,0 +1,102 @@
+package grading
+
+import (
+	"strings"
+
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+)
+
+// gradeCompletion grades sentence, summary, note, table, form and flow-chart completion and
+// short answers. Answers over the word limit are wrong even when they contain the right words.
+// Keys with labelled answers have several blanks, each graded from Response.Matches.
+func gradeCompletion(key Key, response Response) Result {
+	if hasLabelledAnswers(key) {
+		return gradeItems(key, response, completionEqual)
+	}
+
+	if len(key.Answers) == 0 {
+		if len(key.Options) > 0 {
+			return gradeChoice(key, response)
+		}
+		return single(ItemResult{Reason: ReasonNoAnswerKey})
+	}
+
+	given := ""
+	if response.Text != nil {
+		given = *response.Text
+	}
+	return single(gradeItem("", given, key.Answers, key.WordLimit, completionEqual))
+}
+
+// gradeItems grades keys with several labelled items (matching pairs or blanks), one item per
+// label with equal weight. Answers sharing a label are alternatives.
+func gradeItems(key Key, response Response, equal func(string, []Answer) bool) Result {
+	var labels []string
+	byLabel := make(map[string][]Answer)
+	for _, answer := range key.Answers {
+		label := strings.TrimSpace(answer.Label)
+		if _, ok := byLabel[label]; !ok {
+			labels = append(labels, label)
+		}
+		byLabel[label] = append(byLabel[label], answer)
+	}
+
+	// Match student labels loosely: "Paragraph a", "paragraph A" and " Paragraph A " are one item
+	given := make(map[string]string, len(response.Matches))
+	for label, value := range response.Matches {
+		given[labelKey(label)] = value
+	}
+
+	items := make([]ItemResult, len(labels))
+	for i, label := range labels {
+		items[i] = gradeItem(label, given[labelKey(label)], byLabel[label], key.WordLimit, equal)
+	}
+	return newResult(items, len(labels))
+}
+
+func gradeItem(label, given string, answers []Answer, limit *ielts.WordLimit, equal func(string, []Answer) bool) ItemResult {
+	item := ItemResult{Item: label, Given: strings.TrimSpace(given), Expected: displayAnswer(answers)}
+	switch {
+	case item.Given == "":
+		item.Reason = ReasonUnanswered
+	case !limit.Allows(given):
+		item.Reason = ReasonWordLimitExceeded
+	default:
+		item.Correct = equal(given, answers)
+	}
+	return item
+}
+
+// completionEqual matches a written answer against the key's answers and variations,
+// normalized so that accepted forms of the same answer compare equal
+func completionEqual(given string, answers []Answer) bool {
+	var accepted []string
+	for _, answer := range answers {
+		accepted = append(accepted, answer.Text)
+		accepted = append(accepted, answer.Variations...)
+	}
+	return ielts.AnswerMatches(given, accepted...)
+}
+
+// displayAnswer is the key's answer as shown to students, with optional words still marked
+func displayAnswer(answers []Answer) string {
+	for _, answer := range answers {
+		if strings.TrimSpace(answer.Text) != "" {
+			return answer.Text
+		}
+	}
+	return ""
+}
+
+func labelKey(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+func hasLabelledAnswers(key Key) bool {
+	for _, answer := range key.Answers {
+		if strings.TrimSpace(answer.Label) != "" {
+			return true
+		}
+	}
+	return false
+}
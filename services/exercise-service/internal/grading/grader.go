@@ -0,0 +1,183 @@
+// Package grading scores Listening and Reading answers against a question's answer key.
+// Each question type has its own Grader; types without one fall back to text completion.
+// Text answers are compared with the shared IELTS answer normalization (ielts.NormalizeAnswer).
+package grading
+
+import (
+	"strings"
+
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+	"github.com/google/uuid"
+)
+
+// Question types understood by the default registry
+const (
+	TypeMultipleChoice         = "multiple_choice"
+	TypeMultipleChoiceMultiple = "multiple_choice_multiple" // "Choose TWO letters"
+	TypeTrueFalseNotGiven      = "true_false_not_given"
+	TypeYesNoNotGiven          = "yes_no_not_given"
+	TypeTrueFalse              = "true_false"
+	TypeMatching               = "matching"
+	TypeMatchingHeadings       = "matching_headings"
+	TypeMatchingFeatures       = "matching_features"
+	TypeMatchingInformation    = "matching_information"
+	TypeMatchingSentenceEnds   = "matching_sentence_endings"
+	TypeMapLabeling            = "map_labeling"
+	TypeDiagramLabeling        = "diagram_labeling"
+	TypePlanLabeling           = "plan_labeling"
+	TypeFillInBlank            = "fill_in_blank"
+	TypeSentenceCompletion     = "sentence_completion"
+	TypeSummaryCompletion      = "summary_completion"
+	TypeNoteCompletion         = "note_completion"
+	TypeTableCompletion        = "table_completion"
+	TypeFormCompletion         = "form_completion"
+	TypeFlowChartCompletion    = "flow_chart_completion"
+	TypeShortAnswer            = "short_answer"
+)
+
+// Option is a choice in the answer key
+type Option struct {
+	ID        uuid.UUID
+	Label     string
+	Text      string
+	IsCorrect bool
+}
+
+// Answer is an accepted text answer. Label is set for keys with several items
+// (matching pairs, or the blanks of a summary or table), and names the item it answers.
+type Answer struct {
+	Label      string
+	Text       string
+	Variations []string
+}
+
+// Key is the answer key for one question
+type Key struct {
+	QuestionType string
+	Points       float64
+	Options      []Option
+	Answers      []Answer
+	WordLimit    *ielts.WordLimit // From the question or section instructions, nil when there is none
+}
+
+// Response is a student's answer to one question
+type Response struct {
+	SelectedOptionID  *uuid.UUID
+	SelectedOptionIDs []uuid.UUID       // Multi-select
+	Text              *string           // Completion, short answer and judgement questions
+	Matches           map[string]string // Item label -> answer, for matching and multi-blank questions
+}
+
+// Reasons an item was marked wrong, besides a plain mismatch
+const (
+	ReasonUnanswered        = "unanswered"
+	ReasonWordLimitExceeded = "word_limit_exceeded"
+	ReasonTooManyOptions    = "too_many_options"
+	ReasonNoAnswerKey       = "no_answer_key"
+)
+
+// ItemResult is the outcome for one gradable item of a question: a letter of a multi-select,
+// one matching pair or one blank. Single-item questions have exactly one.
+type ItemResult struct {
+	Item     string `json:"item,omitempty"`
+	Given    string `json:"given,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Correct  bool   `json:"correct"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Result is the grade for one question. Score is the fraction of items answered correctly;
+// Correct is only true with full marks.
+type Result struct {
+	Correct      bool         `json:"correct"`
+	Score        float64      `json:"score"`
+	PointsEarned float64      `json:"points_earned"`
+	Items        []ItemResult `json:"items"`
+}
+
+// Grader grades one question type
+type Grader interface {
+	Grade(key Key, response Response) Result
+}
+
+// GraderFunc adapts a function to Grader
+type GraderFunc func(key Key, response Response) Result
+
+func (f GraderFunc) Grade(key Key, response Response) Result {
+	return f(key, response)
+}
+
+// Registry picks the grader for a question type
+type Registry struct {
+	graders  map[string]Grader
+	fallback Grader
+}
+
+// NewRegistry returns a registry with graders for all IELTS Listening and Reading formats.
+// Unknown types are graded as text completion.
+func NewRegistry() *Registry {
+	r := &Registry{
+		graders:  make(map[string]Grader),
+		fallback: GraderFunc(gradeCompletion),
+	}
+
+	for _, t := range []string{TypeMultipleChoice, TypeMultipleChoiceMultiple} {
+		r.Register(t, GraderFunc(gradeChoice))
+	}
+	for _, t := range []string{TypeTrueFalseNotGiven, TypeYesNoNotGiven, TypeTrueFalse} {
+		r.Register(t, GraderFunc(gradeJudgement))
+	}
+	for _, t := range []string{TypeMatching, TypeMatchingHeadings, TypeMatchingFeatures, TypeMatchingInformation, TypeMatchingSentenceEnds} {
+		r.Register(t, GraderFunc(gradeMatching))
+	}
+	for _, t := range []string{TypeMapLabeling, TypeDiagramLabeling, TypePlanLabeling} {
+		r.Register(t, GraderFunc(gradeLabeling))
+	}
+	for _, t := range []string{TypeFillInBlank, TypeSentenceCompletion, TypeSummaryCompletion, TypeNoteCompletion,
+		TypeTableCompletion, TypeFormCompletion, TypeFlowChartCompletion, TypeShortAnswer} {
+		r.Register(t, GraderFunc(gradeCompletion))
+	}
+	return r
+}
+
+// Register sets the grader for a question type, replacing any existing one
+func (r *Registry) Register(questionType string, grader Grader) {
+	r.graders[questionType] = grader
+}
+
+// Grade grades a response with the grader registered for the key's question type
+func (r *Registry) Grade(key Key, response Response) Result {
+	grader, ok := r.graders[strings.ToLower(strings.TrimSpace(key.QuestionType))]
+	if !ok {
+		grader = r.fallback
+	}
+
+	result := grader.Grade(key, response)
+	if result.Items == nil {
+		result.Items = []ItemResult{}
+	}
+	result.PointsEarned = key.Points * result.Score
+	return result
+}
+
+// newResult totals item results into a Result
+func newResult(items []ItemResult, total int) Result {
+	if total <= 0 {
+		return Result{Items: items}
+	}
+
+	correct := 0
+	for _, item := range items {
+		if item.Correct {
+			correct++
+		}
+	}
+
+	score := float64(correct) / float64(total)
+	return Result{Correct: correct == total, Score: score, Items: items}
+}
+
+// single wraps the outcome of a one-item question
+func single(item ItemResult) Result {
+	return newResult([]ItemResult{item}, 1)
+}
@@ -0,0 +1,130 @@
+package grading
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+	"github.com/google/uuid"
+)
+
+func text(s string) *string { return &s }
+
+func options(correct ...string) []Option {
+	var result []Option
+	for _, label := range []string{"A", "B", "C", "D", "E"} {
+		isCorrect := false
+		for _, c := range correct {
+			isCorrect = isCorrect || c == label
+		}
+		result = append(result, Option{ID: uuid.NewSHA1(uuid.Nil, []byte(label)), Label: label, Text: "Option " + label, IsCorrect: isCorrect})
+	}
+	return result
+}
+
+func optionID(label string) *uuid.UUID {
+	id := uuid.NewSHA1(uuid.Nil, []byte(label))
+	return &id
+}
+
+func TestRegistryGrade(t *testing.T) {
+	twoWordsAndNumber := ielts.ParseWordLimit("Write NO MORE THAN TWO WORDS AND/OR A NUMBER for each answer.")
+	oneWordOnly := ielts.ParseWordLimit("Write ONE WORD ONLY")
+
+	headings := []Answer{
+		{Label: "Paragraph A", Text: "iv"},
+		{Label: "Paragraph B", Text: "vii"},
+		{Label: "Paragraph C", Text: "i"},
+		{Label: "Paragraph D", Text: "ii"},
+	}
+	summary := []Answer{
+		{Label: "1", Text: "(the) harbour", Variations: []string{"harbor"}},
+		{Label: "2", Text: "1,500"},
+	}
+
+	tests := []struct {
+		name        string
+		key         Key
+		response    Response
+		wantScore   float64
+		wantCorrect bool
+		wantReason  string
+	}{
+		// Multiple choice
+		{"single choice correct", Key{QuestionType: TypeMultipleChoice, Options: options("B")}, Response{SelectedOptionID: optionID("B")}, 1, true, ""},
+		{"single choice wrong", Key{QuestionType: TypeMultipleChoice, Options: options("B")}, Response{SelectedOptionID: optionID("C")}, 0, false, ""},
+		{"single choice unanswered", Key{QuestionType: TypeMultipleChoice, Options: options("B")}, Response{}, 0, false, ReasonUnanswered},
+		{"single choice option from another question", Key{QuestionType: TypeMultipleChoice, Options: options("B")}, Response{SelectedOptionID: optionID("Z")}, 0, false, ""},
+		{"single choice typed letter", Key{QuestionType: TypeMultipleChoice, Options: options("B")}, Response{Text: text(" b ")}, 1, true, ""},
+
+		// Choose TWO letters
+		{"multi select both", Key{QuestionType: TypeMultipleChoiceMultiple, Options: options("B", "D")}, Response{SelectedOptionIDs: []uuid.UUID{*optionID("D"), *optionID("B")}}, 1, true, ""},
+		{"multi select one of two", Key{QuestionType: TypeMultipleChoiceMultiple, Options: options("B", "D")}, Response{SelectedOptionIDs: []uuid.UUID{*optionID("B"), *optionID("C")}}, 0.5, false, ""},
+		{"multi select too many letters", Key{QuestionType: TypeMultipleChoiceMultiple, Options: options("B", "D")}, Response{SelectedOptionIDs: []uuid.UUID{*optionID("B"), *optionID("C"), *optionID("D")}}, 0, false, ReasonTooManyOptions},
+		{"multi select typed letters", Key{QuestionType: TypeMultipleChoice, Options: options("A", "E")}, Response{Text: text("E, A")}, 1, true, ""},
+		{"multi select only one given", Key{QuestionType: TypeMultipleChoiceMultiple, Options: options("B", "D")}, Response{SelectedOptionID: optionID("D")}, 0.5, false, ""},
+
+		// True/False/Not Given and Yes/No/Not Given
+		{"tfng exact", Key{QuestionType: TypeTrueFalseNotGiven, Answers: []Answer{{Text: "NOT GIVEN"}}}, Response{Text: text("Not Given")}, 1, true, ""},
+		{"tfng abbreviation", Key{QuestionType: TypeTrueFalseNotGiven, Answers: []Answer{{Text: "True"}}}, Response{Text: text("t")}, 1, true, ""},
+		{"tfng ng abbreviation", Key{QuestionType: TypeTrueFalseNotGiven, Answers: []Answer{{Text: "NOT GIVEN"}}}, Response{Text: text("NG")}, 1, true, ""},
+		{"tfng wrong", Key{QuestionType: TypeTrueFalseNotGiven, Answers: []Answer{{Text: "FALSE"}}}, Response{Text: text("TRUE")}, 0, false, ""},
+		{"tfng yes is not accepted", Key{QuestionType: TypeTrueFalseNotGiven, Answers: []Answer{{Text: "TRUE"}}}, Response{Text: text("YES")}, 0, false, ""},
+		{"ynng correct", Key{QuestionType: TypeYesNoNotGiven, Answers: []Answer{{Text: "NO"}}}, Response{Text: text("no.")}, 1, true, ""},
+		{"ynng true is not accepted", Key{QuestionType: TypeYesNoNotGiven, Answers: []Answer{{Text: "YES"}}}, Response{Text: text("TRUE")}, 0, false, ""},
+		{"tfng as options", Key{QuestionType: TypeTrueFalseNotGiven, Options: options("C")}, Response{SelectedOptionID: optionID("C")}, 1, true, ""},
+
+		// Matching
+		{"matching headings all pairs", Key{QuestionType: TypeMatchingHeadings, Answers: headings}, Response{Matches: map[string]string{"Paragraph A": "IV", "paragraph b": "vii", "Paragraph C": "i", "Paragraph D": "ii"}}, 1, true, ""},
+		{"matching headings partial", Key{QuestionType: TypeMatchingHeadings, Answers: headings}, Response{Matches: map[string]string{"Paragraph A": "iv", "Paragraph B": "i", "Paragraph C": "i"}}, 0.5, false, ""},
+		{"matching features legacy option", Key{QuestionType: TypeMatching, Options: options("C")}, Response{SelectedOptionID: optionID("C")}, 1, true, ""},
+
+		// Labelling
+		{"map labelling by letter", Key{QuestionType: TypeMapLabeling, Options: options("C")}, Response{Text: text("c")}, 1, true, ""},
+		{"map labelling by option", Key{QuestionType: TypeMapLabeling, Options: options("C")}, Response{SelectedOptionID: optionID("E")}, 0, false, ""},
+		{"map labelling letter key", Key{QuestionType: TypeMapLabeling, Answers: []Answer{{Text: "G"}}}, Response{Text: text("g")}, 1, true, ""},
+		{"diagram labelling with word limit", Key{QuestionType: TypeDiagramLabeling, Answers: []Answer{{Text: "valve"}}, WordLimit: oneWordOnly}, Response{Text: text("Valve")}, 1, true, ""},
+		{"diagram labelling over limit", Key{QuestionType: TypeDiagramLabeling, Answers: []Answer{{Text: "valve"}}, WordLimit: oneWordOnly}, Response{Text: text("the valve")}, 0, false, ReasonWordLimitExceeded},
+
+		// Completion
+		{"sentence completion variation", Key{QuestionType: TypeSentenceCompletion, Answers: []Answer{{Text: "colour", Variations: []string{"color"}}}}, Response{Text: text("Color")}, 1, true, ""},
+		{"sentence completion optional word", Key{QuestionType: TypeSentenceCompletion, Answers: []Answer{{Text: "(the) city centre"}}}, Response{Text: text("the city centre")}, 1, true, ""},
+		{"sentence completion without optional word", Key{QuestionType: TypeSentenceCompletion, Answers: []Answer{{Text: "(the) city centre"}}}, Response{Text: text("city centre.")}, 1, true, ""},
+		{"word and number within limit", Key{QuestionType: TypeNoteCompletion, Answers: []Answer{{Text: "3 bedrooms"}}, WordLimit: twoWordsAndNumber}, Response{Text: text("3 bedrooms")}, 1, true, ""},
+		{"two words plus number is over two words", Key{QuestionType: TypeNoteCompletion, Answers: []Answer{{Text: "large garden"}}, WordLimit: ielts.ParseWordLimit("NO MORE THAN TWO WORDS")}, Response{Text: text("2 large gardens")}, 0, false, ReasonWordLimitExceeded},
+		{"right words over limit", Key{QuestionType: TypeSentenceCompletion, Answers: []Answer{{Text: "solar panels"}}, WordLimit: twoWordsAndNumber}, Response{Text: text("the solar panels")}, 0, false, ReasonWordLimitExceeded},
+		{"hyphenated word counts once", Key{QuestionType: TypeSentenceCompletion, Answers: []Answer{{Text: "part-time job"}}, WordLimit: ielts.ParseWordLimit("NO MORE THAN TWO WORDS")}, Response{Text: text("part-time job")}, 1, true, ""},
+		{"number only", Key{QuestionType: TypeFormCompletion, Answers: []Answer{{Text: "1500"}}, WordLimit: ielts.ParseWordLimit("Write A NUMBER")}, Response{Text: text("1,500")}, 1, true, ""},
+		{"number only rejects words", Key{QuestionType: TypeFormCompletion, Answers: []Answer{{Text: "1500"}}, WordLimit: ielts.ParseWordLimit("Write A NUMBER")}, Response{Text: text("1500 pounds")}, 0, false, ReasonWordLimitExceeded},
+		{"table completion blanks", Key{QuestionType: TypeTableCompletion, Answers: summary, WordLimit: twoWordsAndNumber}, Response{Matches: map[string]string{"1": "Harbor", "2": "1500"}}, 1, true, ""},
+		{"summary completion one blank wrong", Key{QuestionType: TypeSummaryCompletion, Answers: summary, WordLimit: twoWordsAndNumber}, Response{Matches: map[string]string{"1": "the harbour", "2": "15,000"}}, 0.5, false, ""},
+		{"date in another order", Key{QuestionType: TypeFormCompletion, Answers: []Answer{{Text: "15 March"}}, WordLimit: twoWordsAndNumber}, Response{Text: text("March 15th")}, 1, true, ""},
+		{"american spelling", Key{QuestionType: TypeNoteCompletion, Answers: []Answer{{Text: "city centre"}}}, Response{Text: text("city center")}, 1, true, ""},
+		{"number in words", Key{QuestionType: TypeShortAnswer, Answers: []Answer{{Text: "3"}}, WordLimit: twoWordsAndNumber}, Response{Text: text("three")}, 1, true, ""},
+		{"short answer case and spacing", Key{QuestionType: TypeShortAnswer, Answers: []Answer{{Text: "Marine Biology"}}}, Response{Text: text("  marine   biology ")}, 1, true, ""},
+		{"short answer empty", Key{QuestionType: TypeShortAnswer, Answers: []Answer{{Text: "Marine Biology"}}}, Response{Text: text("  ")}, 0, false, ReasonUnanswered},
+		{"unknown type falls back to completion", Key{QuestionType: "flow_chart", Answers: []Answer{{Text: "export"}}}, Response{Text: text("Export")}, 1, true, ""},
+		{"no answer key", Key{QuestionType: TypeFillInBlank}, Response{Text: text("anything")}, 0, false, ReasonNoAnswerKey},
+	}
+
+	registry := NewRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.key.Points = 2
+			result := registry.Grade(tt.key, tt.response)
+
+			if math.Abs(result.Score-tt.wantScore) > 1e-9 || result.Correct != tt.wantCorrect {
+				t.Fatalf("score = %v, correct = %v; want %v, %v (items %+v)", result.Score, result.Correct, tt.wantScore, tt.wantCorrect, result.Items)
+			}
+			if math.Abs(result.PointsEarned-2*tt.wantScore) > 1e-9 {
+				t.Errorf("points earned = %v, want %v", result.PointsEarned, 2*tt.wantScore)
+			}
+			if len(result.Items) == 0 {
+				t.Fatal("no item results")
+			}
+			if tt.wantReason != "" && result.Items[0].Reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", result.Items[0].Reason, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package grading
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Canonical judgement answers
+const (
+	judgementTrue     = "TRUE"
+	judgementFalse    = "FALSE"
+	judgementYes      = "YES"
+	judgementNo       = "NO"
+	judgementNotGiven = "NOT GIVEN"
+)
+
+var spaceRun = regexp.MustCompile(`\s+`)
+
+var judgementAliases = map[string]string{
+	"T": judgementTrue, "TRUE": judgementTrue,
+	"F": judgementFalse, "FALSE": judgementFalse,
+	"Y": judgementYes, "YES": judgementYes,
+	"N": judgementNo, "NO": judgementNo,
+	"NG": judgementNotGiven, "NOT GIVEN": judgementNotGiven, "NOTGIVEN": judgementNotGiven,
+}
+
+// gradeJudgement grades True/False/Not Given, Yes/No/Not Given and True/False questions.
+// Abbreviations are accepted, but an answer from the other family is wrong, as in the test:
+// YES is not an answer to a True/False/Not Given statement.
+func gradeJudgement(key Key, response Response) Result {
+	if len(key.Options) > 0 && (response.SelectedOptionID != nil || len(response.SelectedOptionIDs) > 0) {
+		return gradeChoice(key, response)
+	}
+
+	expected := ""
+	for _, answer := range key.Answers {
+		if expected = canonicalJudgement(answer.Text); expected != "" {
+			break
+		}
+	}
+	if expected == "" {
+		for _, option := range key.Options {
+			if option.IsCorrect {
+				expected = canonicalJudgement(option.Text)
+				if expected == "" {
+					expected = canonicalJudgement(option.Label)
+				}
+				break
+			}
+		}
+	}
+	if expected == "" {
+		return single(ItemResult{Reason: ReasonNoAnswerKey})
+	}
+
+	if response.Text == nil || strings.TrimSpace(*response.Text) == "" {
+		return single(ItemResult{Expected: expected, Reason: ReasonUnanswered})
+	}
+
+	given := canonicalJudgement(*response.Text)
+	item := ItemResult{Given: strings.TrimSpace(*response.Text), Expected: expected}
+	item.Correct = given == expected && allowedJudgement(key.QuestionType, given)
+	return single(item)
+}
+
+func canonicalJudgement(s string) string {
+	s = strings.ToUpper(strings.Trim(strings.TrimSpace(s), ".,;:!?\"'"))
+	s = strings.NewReplacer("-", " ", "_", " ").Replace(s)
+	return judgementAliases[spaceRun.ReplaceAllString(s, " ")]
+}
+
+func allowedJudgement(questionType, answer string) bool {
+	switch questionType {
+	case TypeYesNoNotGiven:
+		return answer == judgementYes || answer == judgementNo || answer == judgementNotGiven
+	case TypeTrueFalse:
+		return answer == judgementTrue || answer == judgementFalse
+	default:
+		return answer == judgementTrue || answer == judgementFalse || answer == judgementNotGiven
+	}
+}
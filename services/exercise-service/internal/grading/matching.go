@@ -0,0 +1,43 @@
+package grading
+
+import "github.com/bisosad1501/DATN/shared/pkg/ielts"
+
+// gradeMatching grades matching headings, features, information and sentence endings.
+// Keys with labelled answers are graded pair by pair from Response.Matches, one item per pair;
+// the older one-pair-per-question form is a choice between the question's options.
+func gradeMatching(key Key, response Response) Result {
+	if hasLabelledAnswers(key) {
+		key.WordLimit = nil // Letters and numerals are never over a word limit
+		return gradeItems(key, response, matchingEqual)
+	}
+	if len(key.Options) > 0 {
+		return gradeChoice(key, response)
+	}
+	return gradeCompletion(key, response)
+}
+
+// gradeLabeling grades map, plan and diagram labelling: picking letters from the map when the
+// question has options, otherwise labels written from the text under the word limit
+func gradeLabeling(key Key, response Response) Result {
+	if len(key.Options) > 0 && !hasLabelledAnswers(key) {
+		return gradeChoice(key, response)
+	}
+	return gradeCompletion(key, response)
+}
+
+// matchingEqual compares a matched letter or numeral ("iv", "C") with the key's answer
+// and its variations
+func matchingEqual(given string, answers []Answer) bool {
+	given = ielts.NormalizeAnswer(given)
+	for _, answer := range answers {
+		if given == ielts.NormalizeAnswer(answer.Text) {
+			return true
+		}
+		for _, variation := range answer.Variations {
+			if given == ielts.NormalizeAnswer(variation) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -231,6 +232,10 @@ func (h *ExerciseHandler) SubmitAnswers(c *gin.Context) {
 
 	// Pass time_spent_seconds from frontend to service
 	err = h.service.SubmitAnswers(submissionID, req.Answers, req.TimeSpentSeconds)
+	if errors.Is(err, service.ErrAttemptExpired) || errors.Is(err, service.ErrAttemptNotInProgress) {
+		c.JSON(http.StatusConflict, attemptClosedResponse(err))
+		return
+	}
 	if err != nil {
 		log.Printf("[Exercise-Handler] Error submitting answers for submission %s: %v", submissionID, err)
 		c.JSON(http.StatusInternalServerError, Response{
@@ -252,6 +257,90 @@ func (h *ExerciseHandler) SubmitAnswers(c *gin.Context) {
 	})
 }
 
+// AutosaveAnswers handles PUT /api/v1/submissions/:id/autosave
+// Saves partial answers without submitting and returns the server-side time left
+func (h *ExerciseHandler) AutosaveAnswers(c *gin.Context) {
+	submissionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_ID",
+				Message: "Invalid submission ID",
+			},
+		})
+		return
+	}
+
+	var req models.AutosaveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userUUID, _ := uuid.Parse(userID.(string))
+
+	result, err := h.service.AutosaveAnswers(submissionID, userUUID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSubmissionNotFound):
+			c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Error: &ErrorInfo{
+					Code:    "SUBMISSION_NOT_FOUND",
+					Message: "Submission not found",
+				},
+			})
+		case errors.Is(err, service.ErrAttemptExpired), errors.Is(err, service.ErrAttemptNotInProgress):
+			c.JSON(http.StatusConflict, attemptClosedResponse(err))
+		default:
+			log.Printf("[Exercise-Handler] Error autosaving submission %s: %v", submissionID, err)
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Error: &ErrorInfo{
+					Code:    "AUTOSAVE_ERROR",
+					Message: "Failed to autosave answers",
+					Details: err.Error(),
+				},
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// attemptClosedResponse is the response for answers to an attempt that no longer takes them
+func attemptClosedResponse(err error) Response {
+	if errors.Is(err, service.ErrAttemptExpired) {
+		return Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "ATTEMPT_EXPIRED",
+				Message: "Time is up. Your saved answers have been submitted",
+			},
+		}
+	}
+	return Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    "ATTEMPT_NOT_IN_PROGRESS",
+			Message: "This attempt has already been submitted",
+		},
+	}
+}
+
 // GetSubmissionResult handles GET /api/v1/submissions/:id/result
 func (h *ExerciseHandler) GetSubmissionResult(c *gin.Context) {
 	submissionID, err := uuid.Parse(c.Param("id"))
@@ -266,7 +355,10 @@ func (h *ExerciseHandler) GetSubmissionResult(c *gin.Context) {
 		return
 	}
 
-	result, err := h.service.GetSubmissionResult(submissionID)
+	userID, _ := c.Get("user_id")
+	userUUID, _ := uuid.Parse(userID.(string))
+
+	result, err := h.service.GetSubmissionResult(submissionID, userUUID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, Response{
 			Success: false,
@@ -376,6 +468,39 @@ func (h *ExerciseHandler) CreateExercise(c *gin.Context) {
 	})
 }
 
+// GetExerciseForAuthor handles GET /api/v1/admin/exercises/:id
+// Returns drafts too, with option correctness, text answers, explanations and transcripts
+func (h *ExerciseHandler) GetExerciseForAuthor(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_ID",
+				Message: "Invalid exercise ID",
+			},
+		})
+		return
+	}
+
+	exercise, err := h.service.GetExerciseForAuthor(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "EXERCISE_NOT_FOUND",
+				Message: "Exercise not found",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    exercise,
+	})
+}
+
 // UpdateExercise handles PUT /api/v1/admin/exercises/:id
 func (h *ExerciseHandler) UpdateExercise(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
@@ -653,6 +778,62 @@ func (h *ExerciseHandler) CreateQuestionAnswer(c *gin.Context) {
 	})
 }
 
+// PreviewGrading handles POST /api/v1/admin/questions/:id/preview-grading
+func (h *ExerciseHandler) PreviewGrading(c *gin.Context) {
+	questionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_ID",
+				Message: "Invalid question ID",
+			},
+		})
+		return
+	}
+
+	var req models.GradingPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	preview, err := h.service.PreviewGrading(questionID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrQuestionNotFound) {
+			c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Error: &ErrorInfo{
+					Code:    "QUESTION_NOT_FOUND",
+					Message: "Question not found",
+				},
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "PREVIEW_GRADING_ERROR",
+				Message: "Failed to preview grading",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    preview,
+	})
+}
+
 // PublishExercise handles POST /api/v1/admin/exercises/:id/publish
 func (h *ExerciseHandler) PublishExercise(c *gin.Context) {
 	exerciseID, err := uuid.Parse(c.Param("id"))
@@ -1070,6 +1251,56 @@ func (h *ExerciseHandler) DeleteBankQuestion(c *gin.Context) {
 	})
 }
 
+// AssembleExercise handles POST /api/v1/admin/question-bank/assemble
+// Builds a draft exercise from listed bank questions and/or questions drawn by criteria
+func (h *ExerciseHandler) AssembleExercise(c *gin.Context) {
+	var req models.AssembleExerciseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userUUID, _ := uuid.Parse(userID.(string))
+
+	result, err := h.service.AssembleExercise(&req, userUUID)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAssembly) {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error: &ErrorInfo{
+					Code:    "INVALID_ASSEMBLY",
+					Message: "Exercise cannot be assembled from the question bank",
+					Details: err.Error(),
+				},
+			})
+			return
+		}
+		log.Printf("[Exercise-Handler] Error assembling exercise: %v", err)
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "ASSEMBLE_FAILED",
+				Message: "Failed to assemble exercise",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    result,
+	})
+}
+
 // GetExerciseAnalytics handles GET /api/v1/admin/exercises/:id/analytics
 func (h *ExerciseHandler) GetExerciseAnalytics(c *gin.Context) {
 	exerciseID, err := uuid.Parse(c.Param("id"))
@@ -1103,6 +1334,51 @@ func (h *ExerciseHandler) GetExerciseAnalytics(c *gin.Context) {
 	})
 }
 
+// GetItemAnalysis handles GET /api/v1/admin/exercises/:id/analytics/items
+// Returns per-question facility, discrimination, distractor choices and mis-key flags
+func (h *ExerciseHandler) GetItemAnalysis(c *gin.Context) {
+	exerciseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_ID",
+				Message: "Invalid exercise ID",
+			},
+		})
+		return
+	}
+
+	analysis, err := h.service.GetItemAnalysis(exerciseID)
+	if err != nil {
+		if errors.Is(err, service.ErrExerciseNotFound) {
+			c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Error: &ErrorInfo{
+					Code:    "EXERCISE_NOT_FOUND",
+					Message: "Exercise not found",
+				},
+			})
+			return
+		}
+		log.Printf("[Exercise-Handler] Error computing item analysis for %s: %v", exerciseID, err)
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "FETCH_FAILED",
+				Message: "Failed to compute item analysis",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    analysis,
+	})
+}
+
 // SubmitExercise handles POST /api/v1/submissions/:id/submit
 // Unified submission handler for all 4 skills (Phase 4)
 func (h *ExerciseHandler) SubmitExercise(c *gin.Context) {
@@ -1132,6 +1408,10 @@ func (h *ExerciseHandler) SubmitExercise(c *gin.Context) {
 	}
 
 	err = h.service.SubmitExercise(submissionID, &req)
+	if errors.Is(err, service.ErrAttemptExpired) || errors.Is(err, service.ErrAttemptNotInProgress) {
+		c.JSON(http.StatusConflict, attemptClosedResponse(err))
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
@@ -1153,6 +1433,70 @@ func (h *ExerciseHandler) SubmitExercise(c *gin.Context) {
 	})
 }
 
+// GetDeadEvaluationJobs handles GET /api/v1/admin/evaluation-jobs/dead
+func (h *ExerciseHandler) GetDeadEvaluationJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	jobs, total, err := h.service.GetDeadEvaluationJobs(page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "FETCH_FAILED",
+				Message: "Failed to fetch dead evaluation jobs",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: gin.H{
+			"jobs":  jobs,
+			"total": total,
+			"page":  page,
+			"limit": limit,
+		},
+	})
+}
+
+// RequeueEvaluationJob handles POST /api/v1/admin/evaluation-jobs/:id/requeue
+func (h *ExerciseHandler) RequeueEvaluationJob(c *gin.Context) {
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_ID",
+				Message: "Invalid evaluation job ID",
+			},
+		})
+		return
+	}
+
+	if err := h.service.RequeueEvaluationJob(jobID); err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "REQUEUE_FAILED",
+				Message: "Failed to requeue evaluation job",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: gin.H{
+			"message": "Evaluation job requeued",
+			"job_id":  jobID,
+		},
+	})
+}
+
 // HealthCheck handles GET /health
 func (h *ExerciseHandler) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/exercisepkg"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ExportExercise handles GET /api/v1/admin/exercises/:id/export?format=json|zip
+// Downloads the exercise as a versioned package; zip also bundles the referenced media
+func (h *ExerciseHandler) ExportExercise(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_ID",
+				Message: "Invalid exercise ID",
+			},
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_FORMAT",
+				Message: "format must be json or zip",
+			},
+		})
+		return
+	}
+
+	var (
+		content     []byte
+		pkg         *exercisepkg.Package
+		contentType = "application/json"
+	)
+	if format == "zip" {
+		content, pkg, err = h.service.ExportExercisePackageZip(id)
+		contentType = "application/zip"
+	} else if pkg, err = h.service.ExportExercisePackage(id); err == nil {
+		content, err = json.MarshalIndent(pkg, "", "  ")
+	}
+	if err != nil {
+		if errors.Is(err, service.ErrExerciseNotFound) {
+			c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Error: &ErrorInfo{
+					Code:    "EXERCISE_NOT_FOUND",
+					Message: "Exercise not found",
+				},
+			})
+			return
+		}
+		log.Printf("[Exercise-Handler] Error exporting exercise %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "EXPORT_ERROR",
+				Message: "Failed to export exercise",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, pkg.Exercise.Slug, format))
+	c.Data(http.StatusOK, contentType, content)
+}
+
+// ImportExercise handles POST /api/v1/admin/exercises/import?dry_run=true
+// Accepts a package as a multipart "file" or as the raw request body (JSON or zip).
+// The response always carries the import report with per-item errors and warnings.
+func (h *ExerciseHandler) ImportExercise(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, exercisepkg.MaxPackageSize)
+
+	var (
+		data []byte
+		err  error
+	)
+	if file, header, ferr := c.Request.FormFile("file"); ferr == nil {
+		defer file.Close()
+		log.Printf("📦 Importing exercise package %s (%d bytes)", header.Filename, header.Size)
+		data, err = io.ReadAll(file)
+	} else {
+		data, err = io.ReadAll(c.Request.Body)
+	}
+	if err != nil || len(data) == 0 {
+		details := "empty request body"
+		if err != nil {
+			details = err.Error()
+		}
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Upload an exercise package (JSON or zip, up to %d MB)", exercisepkg.MaxPackageSize>>20),
+				Details: details,
+			},
+		})
+		return
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	report, err := h.service.ImportExercisePackage(data, dryRun, currentUserID(c))
+	if err != nil {
+		log.Printf("[Exercise-Handler] Error importing exercise package: %v", err)
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "IMPORT_ERROR",
+				Message: "Failed to import exercise package",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	switch {
+	case report.Action == "rejected" && report.ExerciseID != nil:
+		c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Data:    report,
+			Error: &ErrorInfo{
+				Code:    "EXERCISE_IN_USE",
+				Message: "An exercise with this slug has student attempts and cannot be replaced",
+			},
+		})
+	case !report.Valid:
+		c.JSON(http.StatusUnprocessableEntity, Response{
+			Success: false,
+			Data:    report,
+			Error: &ErrorInfo{
+				Code:    "INVALID_PACKAGE",
+				Message: fmt.Sprintf("Exercise package has %d errors", len(report.Errors)),
+			},
+		})
+	case report.Action == "create" && !dryRun:
+		c.JSON(http.StatusCreated, Response{
+			Success: true,
+			Data:    report,
+		})
+	default:
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Data:    report,
+		})
+	}
+}
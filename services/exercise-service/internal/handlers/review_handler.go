@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetReviewSummary handles GET /api/v1/reviews/summary
+func (h *ExerciseHandler) GetReviewSummary(c *gin.Context) {
+	summary, err := h.service.GetReviewSummary(currentUserID(c))
+	if err != nil {
+		status, resp := reviewErrorResponse(err, "GET_REVIEW_SUMMARY_ERROR", "Failed to get review summary")
+		c.JSON(status, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    summary,
+	})
+}
+
+// ListReviewItems handles GET /api/v1/reviews/items
+func (h *ExerciseHandler) ListReviewItems(c *gin.Context) {
+	query := &models.ReviewItemListQuery{Status: c.Query("status")}
+	query.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	query.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	items, total, err := h.service.GetReviewItems(currentUserID(c), query)
+	if err != nil {
+		status, resp := reviewErrorResponse(err, "GET_REVIEW_ITEMS_ERROR", "Failed to get review items")
+		c.JSON(status, resp)
+		return
+	}
+
+	totalPages := (total + query.Limit - 1) / query.Limit
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: gin.H{
+			"items": items,
+			"pagination": gin.H{
+				"page":        query.Page,
+				"limit":       query.Limit,
+				"total":       total,
+				"total_pages": totalPages,
+			},
+		},
+	})
+}
+
+// ArchiveReviewItem handles DELETE /api/v1/reviews/items/:id
+func (h *ExerciseHandler) ArchiveReviewItem(c *gin.Context) {
+	itemID, ok := reviewIDParam(c, "id", "review item")
+	if !ok {
+		return
+	}
+
+	if err := h.service.ArchiveReviewItem(currentUserID(c), itemID); err != nil {
+		status, resp := reviewErrorResponse(err, "ARCHIVE_REVIEW_ITEM_ERROR", "Failed to archive review item")
+		c.JSON(status, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: gin.H{
+			"message": "Review item archived",
+		},
+	})
+}
+
+// CreateReviewSession handles POST /api/v1/reviews/sessions
+func (h *ExerciseHandler) CreateReviewSession(c *gin.Context) {
+	var req models.CreateReviewSessionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error: &ErrorInfo{
+					Code:    "INVALID_REQUEST",
+					Message: "Invalid request body",
+					Details: err.Error(),
+				},
+			})
+			return
+		}
+	}
+
+	session, err := h.service.CreateReviewSession(currentUserID(c), &req)
+	if err != nil {
+		status, resp := reviewErrorResponse(err, "CREATE_REVIEW_SESSION_ERROR", "Failed to create review session")
+		c.JSON(status, resp)
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    session,
+	})
+}
+
+// GetReviewSession handles GET /api/v1/reviews/sessions/:id
+func (h *ExerciseHandler) GetReviewSession(c *gin.Context) {
+	sessionID, ok := reviewIDParam(c, "id", "review session")
+	if !ok {
+		return
+	}
+
+	session, err := h.service.GetReviewSession(currentUserID(c), sessionID)
+	if err != nil {
+		status, resp := reviewErrorResponse(err, "GET_REVIEW_SESSION_ERROR", "Failed to get review session")
+		c.JSON(status, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    session,
+	})
+}
+
+// AnswerReviewItem handles POST /api/v1/reviews/sessions/:id/items/:item_id/answer
+func (h *ExerciseHandler) AnswerReviewItem(c *gin.Context) {
+	sessionID, ok := reviewIDParam(c, "id", "review session")
+	if !ok {
+		return
+	}
+	itemID, ok := reviewIDParam(c, "item_id", "review item")
+	if !ok {
+		return
+	}
+
+	var req models.ReviewAnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	answer, err := h.service.AnswerReviewItem(currentUserID(c), sessionID, itemID, &req)
+	if err != nil {
+		status, resp := reviewErrorResponse(err, "ANSWER_REVIEW_ITEM_ERROR", "Failed to grade review answer")
+		c.JSON(status, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    answer,
+	})
+}
+
+// CompleteReviewSession handles POST /api/v1/reviews/sessions/:id/complete
+func (h *ExerciseHandler) CompleteReviewSession(c *gin.Context) {
+	sessionID, ok := reviewIDParam(c, "id", "review session")
+	if !ok {
+		return
+	}
+
+	session, err := h.service.CompleteReviewSession(currentUserID(c), sessionID)
+	if err != nil {
+		status, resp := reviewErrorResponse(err, "COMPLETE_REVIEW_SESSION_ERROR", "Failed to complete review session")
+		c.JSON(status, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    session,
+	})
+}
+
+func reviewIDParam(c *gin.Context, param, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_ID",
+				Message: "Invalid " + name + " ID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func reviewErrorResponse(err error, code, message string) (int, Response) {
+	switch {
+	case errors.Is(err, service.ErrInvalidReviewRequest):
+		return http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid review request",
+				Details: err.Error(),
+			},
+		}
+	case errors.Is(err, service.ErrReviewSessionNotFound):
+		return http.StatusNotFound, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "REVIEW_SESSION_NOT_FOUND",
+				Message: "Review session not found",
+			},
+		}
+	case errors.Is(err, service.ErrReviewItemNotFound):
+		return http.StatusNotFound, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "REVIEW_ITEM_NOT_FOUND",
+				Message: "Review item not found",
+			},
+		}
+	case errors.Is(err, service.ErrNoDueReviews):
+		return http.StatusNotFound, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "NO_DUE_REVIEWS",
+				Message: "No review items are due",
+			},
+		}
+	case errors.Is(err, service.ErrReviewSessionClosed),
+		errors.Is(err, service.ErrReviewItemAnswered):
+		return http.StatusConflict, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "REVIEW_ITEM_NOT_AVAILABLE",
+				Message: err.Error(),
+			},
+		}
+	default:
+		log.Printf("[Exercise-Handler] Review error: %v", err)
+		return http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    code,
+				Message: message,
+				Details: err.Error(),
+			},
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateTestSession handles POST /api/v1/test-sessions
+func (h *ExerciseHandler) CreateTestSession(c *gin.Context) {
+	var req models.CreateTestSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	session, err := h.service.CreateTestSession(currentUserID(c), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTestSession) {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error: &ErrorInfo{
+					Code:    "INVALID_TEST_SESSION",
+					Message: "Invalid test session",
+					Details: err.Error(),
+				},
+			})
+			return
+		}
+		log.Printf("[Exercise-Handler] Error creating test session: %v", err)
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "CREATE_TEST_SESSION_ERROR",
+				Message: "Failed to create test session",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    session,
+	})
+}
+
+// ListTestSessions handles GET /api/v1/test-sessions
+func (h *ExerciseHandler) ListTestSessions(c *gin.Context) {
+	query := &models.TestSessionListQuery{}
+	query.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	query.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	sessions, total, err := h.service.ListTestSessions(currentUserID(c), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "GET_TEST_SESSIONS_ERROR",
+				Message: "Failed to get test sessions",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	totalPages := (total + query.Limit - 1) / query.Limit
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: gin.H{
+			"test_sessions": sessions,
+			"pagination": gin.H{
+				"page":        query.Page,
+				"limit":       query.Limit,
+				"total":       total,
+				"total_pages": totalPages,
+			},
+		},
+	})
+}
+
+// GetTestSession handles GET /api/v1/test-sessions/:id
+func (h *ExerciseHandler) GetTestSession(c *gin.Context) {
+	sessionID, ok := testSessionIDParam(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.service.GetTestSession(sessionID, currentUserID(c))
+	if err != nil {
+		status, resp := testSessionErrorResponse(err, "GET_TEST_SESSION_ERROR", "Failed to get test session")
+		c.JSON(status, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    session,
+	})
+}
+
+// StartTestSessionPart handles POST /api/v1/test-sessions/:id/parts/:part/start
+func (h *ExerciseHandler) StartTestSessionPart(c *gin.Context) {
+	sessionID, ok := testSessionIDParam(c)
+	if !ok {
+		return
+	}
+
+	partNumber, err := strconv.Atoi(c.Param("part"))
+	if err != nil || partNumber < 1 {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_PART",
+				Message: "Invalid part number",
+			},
+		})
+		return
+	}
+
+	submission, err := h.service.StartTestSessionPart(sessionID, currentUserID(c), partNumber)
+	if err != nil {
+		status, resp := testSessionErrorResponse(err, "START_TEST_PART_ERROR", "Failed to start test part")
+		c.JSON(status, resp)
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    submission,
+	})
+}
+
+// GetTestSessionReport handles GET /api/v1/test-sessions/:id/report
+func (h *ExerciseHandler) GetTestSessionReport(c *gin.Context) {
+	sessionID, ok := testSessionIDParam(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetTestSessionReport(sessionID, currentUserID(c))
+	if err != nil {
+		status, resp := testSessionErrorResponse(err, "GET_TEST_REPORT_ERROR", "Failed to get test report")
+		c.JSON(status, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    report,
+	})
+}
+
+// currentUserID returns the authenticated user; routes are behind AuthRequired
+func currentUserID(c *gin.Context) uuid.UUID {
+	userID, _ := c.Get("user_id")
+	userUUID, _ := uuid.Parse(userID.(string))
+	return userUUID
+}
+
+func testSessionIDParam(c *gin.Context) (uuid.UUID, bool) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_ID",
+				Message: "Invalid test session ID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return sessionID, true
+}
+
+func testSessionErrorResponse(err error, code, message string) (int, Response) {
+	switch {
+	case errors.Is(err, service.ErrTestSessionNotFound):
+		return http.StatusNotFound, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "TEST_SESSION_NOT_FOUND",
+				Message: "Test session not found",
+			},
+		}
+	case errors.Is(err, service.ErrTestSessionClosed),
+		errors.Is(err, service.ErrTestSessionPartLocked),
+		errors.Is(err, service.ErrTestSessionPartStarted):
+		return http.StatusConflict, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "TEST_PART_NOT_AVAILABLE",
+				Message: err.Error(),
+			},
+		}
+	default:
+		log.Printf("[Exercise-Handler] Test session error: %v", err)
+		return http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    code,
+				Message: message,
+				Details: err.Error(),
+			},
+		}
+	}
+}
@@ -1,6 +1,13 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/exercisepkg"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/grading"
+	"github.com/google/uuid"
+)
 
 // ExerciseListQuery for filtering exercises
 type ExerciseListQuery struct {
@@ -30,10 +37,89 @@ type SectionWithQuestions struct {
 	Questions []QuestionWithOptions `json:"questions"`
 }
 
-// QuestionWithOptions includes question with its options
+// QuestionWithOptions includes question with its options.
+// Answers (text-based answer key) is only loaded for the author view.
 type QuestionWithOptions struct {
 	Question *Question        `json:"question"`
 	Options  []QuestionOption `json:"options,omitempty"`
+	Answers  []QuestionAnswer `json:"answers,omitempty"`
+}
+
+// StudentExerciseDetailResponse is the exercise as shown to students before they finish an attempt:
+// no option correctness, explanations, tips or transcripts
+type StudentExerciseDetailResponse struct {
+	Exercise *Exercise                     `json:"exercise"`
+	Sections []StudentSectionWithQuestions `json:"sections"`
+}
+
+// StudentSectionWithQuestions is SectionWithQuestions without the answer key
+type StudentSectionWithQuestions struct {
+	Section   *ExerciseSection             `json:"section"`
+	Questions []StudentQuestionWithOptions `json:"questions"`
+}
+
+// StudentQuestionWithOptions is QuestionWithOptions without the answer key
+type StudentQuestionWithOptions struct {
+	Question *Question               `json:"question"`
+	Options  []StudentQuestionOption `json:"options,omitempty"`
+}
+
+// StudentQuestionOption is QuestionOption without is_correct
+type StudentQuestionOption struct {
+	ID             uuid.UUID `json:"id"`
+	QuestionID     uuid.UUID `json:"question_id"`
+	OptionLabel    string    `json:"option_label"`
+	OptionText     string    `json:"option_text"`
+	OptionImageURL *string   `json:"option_image_url,omitempty"`
+	DisplayOrder   int       `json:"display_order"`
+}
+
+// StudentView projects the author view to what a student may see before finishing an attempt
+func (d *ExerciseDetailResponse) StudentView() *StudentExerciseDetailResponse {
+	exercise := *d.Exercise
+	exercise.AudioTranscript = nil
+
+	sections := make([]StudentSectionWithQuestions, 0, len(d.Sections))
+	for _, s := range d.Sections {
+		section := *s.Section
+		section.Transcript = nil
+
+		questions := make([]StudentQuestionWithOptions, 0, len(s.Questions))
+		for _, q := range s.Questions {
+			questions = append(questions, StudentQuestionWithOptions{
+				Question: q.Question.StudentView(),
+				Options:  studentOptions(q.Options),
+			})
+		}
+
+		sections = append(sections, StudentSectionWithQuestions{
+			Section:   &section,
+			Questions: questions,
+		})
+	}
+
+	return &StudentExerciseDetailResponse{
+		Exercise: &exercise,
+		Sections: sections,
+	}
+}
+
+func studentOptions(options []QuestionOption) []StudentQuestionOption {
+	if len(options) == 0 {
+		return nil
+	}
+	result := make([]StudentQuestionOption, 0, len(options))
+	for _, o := range options {
+		result = append(result, StudentQuestionOption{
+			ID:             o.ID,
+			QuestionID:     o.QuestionID,
+			OptionLabel:    o.OptionLabel,
+			OptionText:     o.OptionText,
+			OptionImageURL: o.OptionImageURL,
+			DisplayOrder:   o.DisplayOrder,
+		})
+	}
+	return result
 }
 
 // SubmitAnswersRequest for submitting exercise answers
@@ -42,15 +128,35 @@ type SubmitAnswersRequest struct {
 	TimeSpentSeconds *int               `json:"time_spent_seconds,omitempty"` // Total time spent on entire exercise (frontend tracked)
 }
 
+// AutosaveRequest saves partial answers of an in-progress attempt without submitting it
+type AutosaveRequest struct {
+	Answers     []SubmitAnswerItem     `json:"answers,omitempty"`      // Listening/Reading
+	WritingData *WritingSubmissionData `json:"writing_data,omitempty"` // Writing draft
+}
+
+// AutosaveResponse reports what was saved and the server-side time left
+type AutosaveResponse struct {
+	SubmissionID     uuid.UUID  `json:"submission_id"`
+	Status           string     `json:"status"`
+	SavedAnswers     int        `json:"saved_answers"`
+	ServerTime       time.Time  `json:"server_time"`
+	DeadlineAt       *time.Time `json:"deadline_at,omitempty"`
+	RemainingSeconds *int       `json:"remaining_seconds,omitempty"` // Nil for untimed attempts
+	GraceSeconds     int        `json:"grace_seconds"`               // Answers are accepted this long after the deadline
+}
+
 // SubmitAnswerItem represents a single answer
 type SubmitAnswerItem struct {
-	QuestionID       uuid.UUID  `json:"question_id" binding:"required"`
-	SelectedOptionID *uuid.UUID `json:"selected_option_id,omitempty"`
-	TextAnswer       *string    `json:"text_answer,omitempty"`
-	TimeSpentSeconds *int       `json:"time_spent_seconds,omitempty"` // Time spent on this specific question (optional)
+	QuestionID        uuid.UUID         `json:"question_id" binding:"required"`
+	SelectedOptionID  *uuid.UUID        `json:"selected_option_id,omitempty"`
+	SelectedOptionIDs []uuid.UUID       `json:"selected_option_ids,omitempty"` // Multi-select ("Choose TWO letters")
+	TextAnswer        *string           `json:"text_answer,omitempty"`
+	Matches           map[string]string `json:"matches,omitempty"`            // Matching pairs or multi-blank answers: item label -> answer
+	TimeSpentSeconds  *int              `json:"time_spent_seconds,omitempty"` // Time spent on this specific question (optional)
 }
 
-// SubmissionResultResponse includes detailed results for a user's exercise attempt
+// SubmissionResultResponse includes detailed results for a user's exercise attempt.
+// Correct answers, explanations and transcripts are only included once the attempt is completed.
 type SubmissionResultResponse struct {
 	Submission  *UserExerciseAttempt           `json:"submission"` // User's attempt data
 	Exercise    *Exercise                      `json:"exercise"`
@@ -58,6 +164,31 @@ type SubmissionResultResponse struct {
 	Performance *PerformanceStats              `json:"performance"`
 }
 
+// RedactAnswerKey removes correctness, correct answers, explanations and transcripts,
+// for attempts that are not completed yet
+func (r *SubmissionResultResponse) RedactAnswerKey() {
+	if r.Exercise != nil {
+		exercise := *r.Exercise
+		exercise.AudioTranscript = nil
+		r.Exercise = &exercise
+	}
+
+	for i := range r.Answers {
+		answer := &r.Answers[i]
+		answer.CorrectAnswer = nil
+		if answer.Question != nil {
+			answer.Question = answer.Question.StudentView()
+		}
+		if answer.Answer != nil {
+			redacted := *answer.Answer
+			redacted.IsCorrect = nil
+			redacted.PointsEarned = nil
+			redacted.GradingDetails = nil
+			answer.Answer = &redacted
+		}
+	}
+}
+
 // SubmissionAnswerWithQuestion includes answer with question details
 type SubmissionAnswerWithQuestion struct {
 	Answer        *SubmissionAnswer `json:"answer"`
@@ -157,12 +288,40 @@ type CreateQuestionOptionRequest struct {
 
 // CreateQuestionAnswerRequest for text-based questions
 type CreateQuestionAnswerRequest struct {
+	Label              *string  `json:"label"` // Matching item ("Paragraph A") or blank number, for questions with several
 	AnswerText         string   `json:"answer_text" binding:"required"`
 	AlternativeAnswers []string `json:"alternative_answers"`
 	IsCaseSensitive    bool     `json:"is_case_sensitive"`
 	MatchingOrder      *int     `json:"matching_order"`
 }
 
+// GradingPreviewRequest is a candidate answer to score against a question's answer key
+type GradingPreviewRequest struct {
+	SelectedOptionID  *uuid.UUID        `json:"selected_option_id,omitempty"`
+	SelectedOptionIDs []uuid.UUID       `json:"selected_option_ids,omitempty"`
+	TextAnswer        *string           `json:"text_answer,omitempty"`
+	Matches           map[string]string `json:"matches,omitempty"`
+}
+
+// GradingPreviewResponse shows how a candidate answer would be scored, with the normalized
+// forms the grader compares
+type GradingPreviewResponse struct {
+	QuestionType      string                  `json:"question_type"`
+	Points            float64                 `json:"points"`
+	WordLimit         *ielts.WordLimit        `json:"word_limit,omitempty"`
+	NormalizedAnswer  *string                 `json:"normalized_answer,omitempty"`
+	NormalizedMatches map[string]string       `json:"normalized_matches,omitempty"`
+	AcceptedAnswers   []PreviewAcceptedAnswer `json:"accepted_answers"`
+	Result            grading.Result          `json:"result"`
+}
+
+// PreviewAcceptedAnswer is one accepted form of the answer key, as written and normalized
+type PreviewAcceptedAnswer struct {
+	Label      string `json:"label,omitempty"`
+	Answer     string `json:"answer"`
+	Normalized string `json:"normalized"`
+}
+
 // MySubmissionsQuery for filtering user submissions
 type MySubmissionsQuery struct {
 	Page      int    `form:"page"`
@@ -186,3 +345,192 @@ type UserExerciseAttemptWithExercise struct {
 	Submission *UserExerciseAttempt `json:"submission"`
 	Exercise   *Exercise            `json:"exercise"`
 }
+
+// CreateTestSessionRequest starts a full mock test from one or more exercises per skill.
+// Parts are taken in IELTS order (Listening, Reading, Writing, Speaking), keeping the given
+// order within a skill, e.g. Writing Task 1 before Task 2.
+type CreateTestSessionRequest struct {
+	ExerciseIDs []uuid.UUID `json:"exercise_ids" binding:"required,min=4"`
+	TestType    string      `json:"test_type"` // mock_test (default), full_test
+}
+
+// TestSessionListQuery for paginating the user's test sessions
+type TestSessionListQuery struct {
+	Page  int `form:"page"`
+	Limit int `form:"limit"`
+}
+
+// TestSessionReport is the full score report of a test session
+type TestSessionReport struct {
+	Session          *TestSession       `json:"session"`
+	Skills           []SkillScoreReport `json:"skills"`
+	OverallBand      *float64           `json:"overall_band,omitempty"`
+	CompletionStatus string             `json:"completion_status"` // completed, incomplete, pending (still being taken or graded)
+	TimeSpentSeconds int                `json:"time_spent_seconds"`
+}
+
+// SkillScoreReport summarizes one skill of a test session
+type SkillScoreReport struct {
+	SkillType        string   `json:"skill_type"`
+	BandScore        *float64 `json:"band_score,omitempty"`
+	Parts            int      `json:"parts"`
+	PartsGraded      int      `json:"parts_graded"`
+	PartsSkipped     int      `json:"parts_skipped"`
+	CorrectAnswers   *int     `json:"correct_answers,omitempty"` // Listening/Reading
+	TotalQuestions   *int     `json:"total_questions,omitempty"` // Listening/Reading
+	TimeSpentSeconds int      `json:"time_spent_seconds"`
+}
+
+// ExercisePackageImportReport is the outcome of importing an exercise package, or of a dry run.
+// Action is what the import did (or would do): create, update, unchanged, or rejected when the
+// package has errors or would overwrite an exercise students have already attempted.
+type ExercisePackageImportReport struct {
+	DryRun        bool                `json:"dry_run"`
+	Valid         bool                `json:"valid"`
+	Action        string              `json:"action"`
+	Slug          string              `json:"slug"`
+	ExerciseID    *uuid.UUID          `json:"exercise_id,omitempty"`
+	Counts        exercisepkg.Counts  `json:"counts"`
+	MediaUploaded int                 `json:"media_uploaded"`
+	Errors        []exercisepkg.Issue `json:"errors"`
+	Warnings      []exercisepkg.Issue `json:"warnings"`
+}
+
+// AssembleExerciseRequest builds a draft exercise from question bank items. Each section takes
+// the listed bank questions first, then draws the rest by criteria.
+type AssembleExerciseRequest struct {
+	Title            string                   `json:"title" binding:"required"`
+	Slug             string                   `json:"slug" binding:"required"`
+	Description      *string                  `json:"description"`
+	ExerciseType     string                   `json:"exercise_type"` // Defaults to practice
+	SkillType        string                   `json:"skill_type" binding:"required"`
+	IELTSTestType    *string                  `json:"ielts_test_type"`
+	Difficulty       string                   `json:"difficulty"` // Defaults to medium
+	TimeLimitMinutes *int                     `json:"time_limit_minutes"`
+	AudioURL         *string                  `json:"audio_url"`
+	Sections         []AssembleSectionRequest `json:"sections" binding:"required,min=1,dive"`
+}
+
+// AssembleSectionRequest is one section of an assembled exercise
+type AssembleSectionRequest struct {
+	Title          string                 `json:"title" binding:"required"`
+	Instructions   *string                `json:"instructions"`
+	PassageTitle   *string                `json:"passage_title"`
+	PassageContent *string                `json:"passage_content"`
+	AudioURL       *string                `json:"audio_url"`
+	QuestionIDs    []uuid.UUID            `json:"question_ids"`
+	Draw           []BankQuestionCriteria `json:"draw" binding:"dive"`
+}
+
+// BankQuestionCriteria draws Count published bank questions of the exercise's skill,
+// least used first
+type BankQuestionCriteria struct {
+	QuestionType string   `json:"question_type" binding:"required"`
+	Difficulty   *string  `json:"difficulty"`
+	Topic        *string  `json:"topic"`
+	Tags         []string `json:"tags"` // Questions must have all of them
+	VerifiedOnly bool     `json:"verified_only"`
+	Count        int      `json:"count" binding:"required,min=1,max=50"`
+}
+
+// AssembleExerciseResponse is the draft exercise and the bank questions it was built from
+type AssembleExerciseResponse struct {
+	Exercise        *ExerciseDetailResponse `json:"exercise"`
+	BankQuestionIDs []uuid.UUID             `json:"bank_question_ids"`
+}
+
+// ItemAnalysis is item-level statistics for an exercise, from each student's first completed
+// attempt. Students are ranked by total score for the upper and lower groups (27% each).
+type ItemAnalysis struct {
+	ExerciseID uuid.UUID        `json:"exercise_id"`
+	Attempts   int              `json:"attempts"`
+	GroupSize  int              `json:"group_size"`
+	Items      []ItemStatistics `json:"items"`
+	ComputedAt time.Time        `json:"computed_at"`
+}
+
+// ItemStatistics describes how one question performed. Facility is the mean share of the
+// question's points earned (p-value); discrimination is upper minus lower group facility.
+// Both are omitted until enough students have attempted the exercise.
+type ItemStatistics struct {
+	QuestionID          uuid.UUID          `json:"question_id"`
+	QuestionNumber      int                `json:"question_number"`
+	QuestionType        string             `json:"question_type"`
+	BankQuestionID      *uuid.UUID         `json:"bank_question_id,omitempty"`
+	Responses           int                `json:"responses"`
+	Omitted             int                `json:"omitted"`
+	Facility            *float64           `json:"facility,omitempty"`
+	Discrimination      *float64           `json:"discrimination,omitempty"`
+	AverageTimeSeconds  *float64           `json:"average_time_seconds,omitempty"`
+	Difficulty          *string            `json:"difficulty,omitempty"`           // As authored
+	SuggestedDifficulty *string            `json:"suggested_difficulty,omitempty"` // From facility
+	Options             []OptionStatistics `json:"options,omitempty"`
+	Flags               []string           `json:"flags"` // possible_miskey, too_easy, too_hard, low_discrimination
+}
+
+// OptionStatistics is how often an option was chosen, overall and within the
+// upper and lower groups
+type OptionStatistics struct {
+	OptionID  uuid.UUID `json:"option_id"`
+	Label     string    `json:"label"`
+	IsCorrect bool      `json:"is_correct"`
+	Count     int       `json:"count"`
+	Rate      float64   `json:"rate"`
+	UpperRate *float64  `json:"upper_rate,omitempty"`
+	LowerRate *float64  `json:"lower_rate,omitempty"`
+}
+
+// ItemResponse is one student's graded answer, as used for item analysis
+type ItemResponse struct {
+	AttemptID        uuid.UUID
+	QuestionID       uuid.UUID
+	IsCorrect        bool
+	PointsEarned     float64
+	SelectedOptionID *uuid.UUID
+	SelectedOptions  []uuid.UUID
+	TimeSpentSeconds *int
+}
+
+// CreateReviewSessionRequest starts a review of the most overdue items, optionally of one skill
+type CreateReviewSessionRequest struct {
+	Limit     int     `json:"limit"`      // Default 20, at most 50
+	SkillType *string `json:"skill_type"` // listening, reading
+}
+
+// ReviewAnswerRequest answers one item of a review session. Confidence (easy, good, hard)
+// is how readily a correct answer came and adjusts how far the next review is pushed out.
+type ReviewAnswerRequest struct {
+	SelectedOptionID  *uuid.UUID        `json:"selected_option_id,omitempty"`
+	SelectedOptionIDs []uuid.UUID       `json:"selected_option_ids,omitempty"`
+	TextAnswer        *string           `json:"text_answer,omitempty"`
+	Matches           map[string]string `json:"matches,omitempty"`
+	TimeSpentSeconds  *int              `json:"time_spent_seconds,omitempty"`
+	Confidence        string            `json:"confidence,omitempty"`
+}
+
+// ReviewAnswerResponse is the grade of a review answer and the item's new schedule
+type ReviewAnswerResponse struct {
+	ReviewItemID     uuid.UUID      `json:"review_item_id"`
+	Result           grading.Result `json:"result"`
+	Quality          int            `json:"quality"`
+	Explanation      *string        `json:"explanation,omitempty"`
+	Status           string         `json:"status"` // active, mastered
+	IntervalDays     int            `json:"interval_days"`
+	NextDueAt        time.Time      `json:"next_due_at"`
+	SessionCompleted bool           `json:"session_completed"`
+}
+
+// ReviewSummary counts a student's review items
+type ReviewSummary struct {
+	Due       int        `json:"due"`
+	Active    int        `json:"active"`
+	Mastered  int        `json:"mastered"`
+	NextDueAt *time.Time `json:"next_due_at,omitempty"`
+}
+
+// ReviewItemListQuery for paginating the mistake notebook
+type ReviewItemListQuery struct {
+	Status string `form:"status"` // active (default), mastered, archived
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/grading"
+	"github.com/google/uuid"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestExerciseDetailStudentView(t *testing.T) {
+	questionID := uuid.New()
+	detail := &ExerciseDetailResponse{
+		Exercise: &Exercise{Title: "Campus Tour", AudioTranscript: strPtr("Welcome to the campus...")},
+		Sections: []SectionWithQuestions{{
+			Section: &ExerciseSection{Title: "Part 1", Transcript: strPtr("Part 1 transcript")},
+			Questions: []QuestionWithOptions{{
+				Question: &Question{ID: questionID, QuestionText: "Where does the tour start?", Explanation: strPtr("The guide says..."), Tips: strPtr("Listen for places")},
+				Options: []QuestionOption{
+					{QuestionID: questionID, OptionLabel: "A", OptionText: "Library", IsCorrect: true},
+					{QuestionID: questionID, OptionLabel: "B", OptionText: "Gym"},
+				},
+				Answers: []QuestionAnswer{{QuestionID: questionID, AnswerText: "library"}},
+			}},
+		}},
+	}
+
+	view := detail.StudentView()
+
+	if view.Exercise.AudioTranscript != nil || view.Sections[0].Section.Transcript != nil {
+		t.Error("transcripts should be removed")
+	}
+	q := view.Sections[0].Questions[0]
+	if q.Question.Explanation != nil || q.Question.Tips != nil {
+		t.Error("explanation and tips should be removed")
+	}
+	if len(q.Options) != 2 || q.Options[0].OptionText != "Library" || q.Options[1].OptionLabel != "B" {
+		t.Errorf("options = %+v, want both options without correctness", q.Options)
+	}
+
+	// The author view is left as it was
+	if detail.Exercise.AudioTranscript == nil || detail.Sections[0].Section.Transcript == nil ||
+		detail.Sections[0].Questions[0].Question.Explanation == nil {
+		t.Error("StudentView changed the author view")
+	}
+}
+
+func TestRedactAnswerKey(t *testing.T) {
+	correct, points := true, 1.0
+	result := &SubmissionResultResponse{
+		Exercise: &Exercise{AudioTranscript: strPtr("Welcome to the campus...")},
+		Answers: []SubmissionAnswerWithQuestion{
+			{
+				Answer: &SubmissionAnswer{
+					AnswerText:     strPtr("library"),
+					IsCorrect:      &correct,
+					PointsEarned:   &points,
+					GradingDetails: []grading.ItemResult{{Given: "library", Expected: "library", Correct: true}},
+				},
+				Question:      &Question{Explanation: strPtr("The guide says..."), Tips: strPtr("Listen for places")},
+				CorrectAnswer: "library",
+			},
+			{Question: &Question{QuestionText: "Unanswered"}},
+		},
+	}
+	exercise, answer, question := result.Exercise, result.Answers[0].Answer, result.Answers[0].Question
+
+	result.RedactAnswerKey()
+
+	if result.Exercise.AudioTranscript != nil {
+		t.Error("transcript should be removed")
+	}
+	got := result.Answers[0]
+	if got.CorrectAnswer != nil || got.Answer.IsCorrect != nil || got.Answer.PointsEarned != nil || got.Answer.GradingDetails != nil {
+		t.Errorf("answer key left in %+v", got.Answer)
+	}
+	if got.Answer.AnswerText == nil || *got.Answer.AnswerText != "library" {
+		t.Error("the student's own answer should be kept")
+	}
+	if got.Question.Explanation != nil || got.Question.Tips != nil {
+		t.Error("explanation and tips should be removed")
+	}
+	if result.Answers[1].Answer != nil {
+		t.Error("an unanswered question should stay unanswered")
+	}
+
+	// Redaction copies rather than changing values that may be shared
+	if exercise.AudioTranscript == nil || answer.IsCorrect == nil || question.Explanation == nil {
+		t.Error("RedactAnswerKey changed the original values")
+	}
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/grading"
 	"github.com/google/uuid"
 )
 
@@ -109,6 +110,14 @@ type Question struct {
 	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
+// StudentView returns a copy of the question without explanation and tips
+func (q *Question) StudentView() *Question {
+	question := *q
+	question.Explanation = nil
+	question.Tips = nil
+	return &question
+}
+
 // QuestionOption represents an option for multiple choice questions
 type QuestionOption struct {
 	ID             uuid.UUID `json:"id"`
@@ -125,6 +134,7 @@ type QuestionOption struct {
 type QuestionAnswer struct {
 	ID                 uuid.UUID `json:"id"`
 	QuestionID         uuid.UUID `json:"question_id"`
+	Label              *string   `json:"label,omitempty"` // Matching item or blank this answers, for questions with several
 	AnswerText         string    `json:"answer_text"`
 	AlternativeAnswers *string   `json:"alternative_answers,omitempty"` // JSON array
 	IsCaseSensitive    bool      `json:"is_case_sensitive"`
@@ -141,17 +151,24 @@ type UserExerciseAttempt struct {
 	UserID            uuid.UUID  `json:"user_id"`
 	ExerciseID        uuid.UUID  `json:"exercise_id"`
 	AttemptNumber     int        `json:"attempt_number"`
-	Status            string     `json:"status"` // in_progress, completed, abandoned
+	Status            string     `json:"status"` // in_progress, submitted, completed, abandoned
 	TotalQuestions    int        `json:"total_questions"`
 	QuestionsAnswered int        `json:"questions_answered"`
 	CorrectAnswers    int        `json:"correct_answers"`
+	RawScore          *int       `json:"raw_score,omitempty"`  // L/R marks the band is computed from, with partial credit
 	Score             *float64   `json:"score,omitempty"`      // Percentage or points
 	BandScore         *float64   `json:"band_score,omitempty"` // IELTS band score
 	TimeLimitMinutes  *int       `json:"time_limit_minutes,omitempty"`
 	TimeSpentSeconds  int        `json:"time_spent_seconds"`
 	StartedAt         time.Time  `json:"started_at"`
 	CompletedAt       *time.Time `json:"completed_at,omitempty"`
-	DeviceType        *string    `json:"device_type,omitempty"` // web, android, ios
+	LastActivityAt    *time.Time `json:"last_activity_at,omitempty"` // Last autosave or submit
+	AutoSubmitted     bool       `json:"auto_submitted"`             // Submitted by the server when time ran out
+	DeviceType        *string    `json:"device_type,omitempty"`      // web, android, ios
+
+	// Server-side timer, filled in for in-progress attempts (not stored)
+	DeadlineAt       *time.Time `json:"deadline_at,omitempty"`
+	RemainingSeconds *int       `json:"remaining_seconds,omitempty"`
 
 	// Writing-specific fields (Phase 4)
 	EssayText  *string `json:"essay_text,omitempty"`
@@ -163,6 +180,7 @@ type UserExerciseAttempt struct {
 	AudioURL             *string `json:"audio_url,omitempty"`
 	AudioDurationSeconds *int    `json:"audio_duration_seconds,omitempty"`
 	TranscriptText       *string `json:"transcript_text,omitempty"`
+	SpeechMetrics        *string `json:"-"`                              // JSONB fluency metrics saved with the transcript
 	SpeakingPartNumber   *int    `json:"speaking_part_number,omitempty"` // 1, 2, 3
 
 	// AI Evaluation fields (Phase 4)
@@ -185,18 +203,29 @@ type UserExerciseAttempt struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Deadline returns when a timed attempt runs out of time. Untimed attempts have no deadline.
+func (a *UserExerciseAttempt) Deadline() (time.Time, bool) {
+	if a.TimeLimitMinutes == nil || *a.TimeLimitMinutes <= 0 {
+		return time.Time{}, false
+	}
+	return a.StartedAt.Add(time.Duration(*a.TimeLimitMinutes) * time.Minute), true
+}
+
 // SubmissionAnswer represents an answer in a submission (maps to user_answers table)
 type SubmissionAnswer struct {
-	ID               uuid.UUID  `json:"id"`
-	AttemptID        uuid.UUID  `json:"attempt_id"` // FK to user_exercise_attempts
-	QuestionID       uuid.UUID  `json:"question_id"`
-	UserID           uuid.UUID  `json:"user_id"`
-	AnswerText       *string    `json:"answer_text,omitempty"`
-	SelectedOptionID *uuid.UUID `json:"selected_option_id,omitempty"`
-	IsCorrect        *bool      `json:"is_correct,omitempty"`
-	PointsEarned     *float64   `json:"points_earned,omitempty"`
-	TimeSpentSeconds *int       `json:"time_spent_seconds,omitempty"`
-	AnsweredAt       time.Time  `json:"answered_at"`
+	ID                uuid.UUID            `json:"id"`
+	AttemptID         uuid.UUID            `json:"attempt_id"` // FK to user_exercise_attempts
+	QuestionID        uuid.UUID            `json:"question_id"`
+	UserID            uuid.UUID            `json:"user_id"`
+	AnswerText        *string              `json:"answer_text,omitempty"`
+	SelectedOptionID  *uuid.UUID           `json:"selected_option_id,omitempty"`
+	SelectedOptionIDs []uuid.UUID          `json:"selected_option_ids,omitempty"`
+	Matches           map[string]string    `json:"matches,omitempty"`
+	IsCorrect         *bool                `json:"is_correct,omitempty"`
+	PointsEarned      *float64             `json:"points_earned,omitempty"`
+	GradingDetails    []grading.ItemResult `json:"grading_details,omitempty"` // Per-item correctness
+	TimeSpentSeconds  *int                 `json:"time_spent_seconds,omitempty"`
+	AnsweredAt        time.Time            `json:"answered_at"`
 }
 
 // ExerciseTag represents a tag for exercises
@@ -379,3 +408,140 @@ type AIEvaluationResult struct {
 	Feedback         string                 `json:"feedback"`
 	CriteriaScores   map[string]float64     `json:"criteria_scores"` // TA, CC, LR, GRA for writing; Fluency, Lexical, Grammar, Pronunciation for speaking
 }
+
+// EvaluationJob represents a queued AI evaluation for a Writing/Speaking attempt.
+// Maps to table: evaluation_jobs
+type EvaluationJob struct {
+	ID             uuid.UUID  `json:"id"`
+	AttemptID      uuid.UUID  `json:"attempt_id"`
+	SkillType      string     `json:"skill_type"` // writing, speaking
+	Status         string     `json:"status"`     // queued, leased, recording, completed, dead
+	RecordResult   bool       `json:"record_result"`
+	Attempts       int        `json:"attempts"`
+	MaxAttempts    int        `json:"max_attempts"`
+	NextRunAt      time.Time  `json:"next_run_at"`
+	LastError      *string    `json:"last_error,omitempty"`
+	LeaseOwner     *string    `json:"lease_owner,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
+
+// TestSession is a full mock test: Listening, Reading, Writing and Speaking exercises taken in order.
+// Maps to table: test_sessions
+type TestSession struct {
+	ID                    uuid.UUID  `json:"id"`
+	UserID                uuid.UUID  `json:"user_id"`
+	TestType              string     `json:"test_type"`     // mock_test, full_test
+	IELTSVariant          string     `json:"ielts_variant"` // academic, general_training
+	Status                string     `json:"status"`        // in_progress, awaiting_evaluation, completed, incomplete
+	ListeningBand         *float64   `json:"listening_band,omitempty"`
+	ReadingBand           *float64   `json:"reading_band,omitempty"`
+	WritingBand           *float64   `json:"writing_band,omitempty"`
+	SpeakingBand          *float64   `json:"speaking_band,omitempty"`
+	OverallBand           *float64   `json:"overall_band,omitempty"`
+	StartedAt             time.Time  `json:"started_at"`
+	LastActivityAt        time.Time  `json:"last_activity_at"`
+	CompletedAt           *time.Time `json:"completed_at,omitempty"`
+	UserServiceSyncStatus string     `json:"user_service_sync_status"` // pending, synced, failed
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+
+	Parts []TestSessionPart `json:"parts,omitempty"`
+}
+
+// IsFinished returns true once the session has its final scores
+func (s *TestSession) IsFinished() bool {
+	return s.Status == "completed" || s.Status == "incomplete"
+}
+
+// TestSessionPart is one exercise of a test session, with the progress of its attempt.
+// Maps to table: test_session_parts (joined with exercises and user_exercise_attempts)
+type TestSessionPart struct {
+	PartNumber       int        `json:"part_number"`
+	SkillType        string     `json:"skill_type"`
+	ExerciseID       uuid.UUID  `json:"exercise_id"`
+	ExerciseTitle    string     `json:"exercise_title"`
+	TimeLimitMinutes *int       `json:"time_limit_minutes,omitempty"`
+	WritingTaskType  *string    `json:"writing_task_type,omitempty"` // task1, task2
+	AttemptID        *uuid.UUID `json:"attempt_id,omitempty"`
+	Status           string     `json:"status"` // pending, in_progress, submitted, graded, evaluation_failed, skipped
+
+	// From the attempt
+	EvaluationStatus *string    `json:"evaluation_status,omitempty"`
+	BandScore        *float64   `json:"band_score,omitempty"`
+	CorrectAnswers   *int       `json:"correct_answers,omitempty"`
+	RawScore         *int       `json:"raw_score,omitempty"`
+	TotalQuestions   *int       `json:"total_questions,omitempty"`
+	TimeSpentSeconds *int       `json:"time_spent_seconds,omitempty"`
+	StartedAt        *time.Time `json:"started_at,omitempty"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	DeadlineAt       *time.Time `json:"deadline_at,omitempty"`
+	RemainingSeconds *int       `json:"remaining_seconds,omitempty"`
+	DetailedScores   *string    `json:"detailed_scores,omitempty"` // Writing/Speaking criteria
+	AIFeedback       *string    `json:"ai_feedback,omitempty"`
+}
+
+// IsDone returns true when the part can no longer be worked on (submitted, graded or skipped)
+func (p *TestSessionPart) IsDone() bool {
+	return p.Status == "submitted" || p.Status == "graded" || p.Status == "skipped"
+}
+
+// ReviewItem is a question in a student's mistake notebook, scheduled for review with SM-2.
+// Maps to table: review_items (joined with questions and exercises)
+type ReviewItem struct {
+	ID              uuid.UUID  `json:"id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	QuestionID      uuid.UUID  `json:"question_id"`
+	ExerciseID      uuid.UUID  `json:"exercise_id"`
+	SourceAttemptID *uuid.UUID `json:"source_attempt_id,omitempty"`
+	Status          string     `json:"status"` // active, mastered, archived
+	EaseFactor      float64    `json:"ease_factor"`
+	IntervalDays    int        `json:"interval_days"`
+	Repetitions     int        `json:"repetitions"`
+	Lapses          int        `json:"lapses"`
+	DueAt           time.Time  `json:"due_at"`
+	ReviewCount     int        `json:"review_count"`
+	LastReviewedAt  *time.Time `json:"last_reviewed_at,omitempty"`
+	LastResult      *bool      `json:"last_result,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+
+	ExerciseTitle  string `json:"exercise_title"`
+	SkillType      string `json:"skill_type"`
+	QuestionNumber int    `json:"question_number"`
+	QuestionType   string `json:"question_type"`
+	QuestionText   string `json:"question_text"`
+}
+
+// ReviewSession is a mixed set of due review items.
+// Maps to table: review_sessions
+type ReviewSession struct {
+	ID                    uuid.UUID           `json:"id"`
+	UserID                uuid.UUID           `json:"user_id"`
+	Status                string              `json:"status"` // in_progress, completed
+	StartedAt             time.Time           `json:"started_at"`
+	CompletedAt           *time.Time          `json:"completed_at,omitempty"`
+	UserServiceSyncStatus string              `json:"user_service_sync_status"` // pending, synced, failed
+	Items                 []ReviewSessionItem `json:"items"`
+}
+
+// ReviewSessionItem is one question of a review session, with its outcome once answered.
+// Maps to table: review_session_items
+type ReviewSessionItem struct {
+	ReviewItemID     uuid.UUID  `json:"review_item_id"`
+	Position         int        `json:"position"`
+	QuestionID       uuid.UUID  `json:"question_id"`
+	ExerciseID       uuid.UUID  `json:"exercise_id"`
+	ExerciseTitle    string     `json:"exercise_title"`
+	SkillType        string     `json:"skill_type"`
+	IsCorrect        *bool      `json:"is_correct,omitempty"`
+	Score            *float64   `json:"score,omitempty"`
+	Quality          *int       `json:"quality,omitempty"` // SM-2 response quality, 0-5
+	TimeSpentSeconds *int       `json:"time_spent_seconds,omitempty"`
+	AnsweredAt       *time.Time `json:"answered_at,omitempty"`
+
+	// The question as a student sees it, with its section for the passage or recording
+	Section  *ExerciseSection            `json:"section,omitempty"`
+	Question *StudentQuestionWithOptions `json:"question,omitempty"`
+}
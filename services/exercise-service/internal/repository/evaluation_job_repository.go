@@ -0,0 +1,358 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrEvaluationLeaseLost is returned when a worker tries to finish a job whose
+// lease has expired and been taken over by another worker.
+var ErrEvaluationLeaseLost = errors.New("evaluation job lease lost")
+
+// sqlExecer is satisfied by both *sql.DB and *sql.Tx
+type sqlExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+const evaluationJobColumns = `
+	id, attempt_id, skill_type, status, record_result, attempts, max_attempts, next_run_at, last_error,
+	lease_owner, lease_expires_at, created_at, updated_at, completed_at
+`
+
+func scanEvaluationJob(scanner interface{ Scan(...interface{}) error }) (*models.EvaluationJob, error) {
+	var j models.EvaluationJob
+	err := scanner.Scan(
+		&j.ID, &j.AttemptID, &j.SkillType, &j.Status, &j.RecordResult, &j.Attempts, &j.MaxAttempts, &j.NextRunAt, &j.LastError,
+		&j.LeaseOwner, &j.LeaseExpiresAt, &j.CreatedAt, &j.UpdatedAt, &j.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// EnqueueEvaluationJob queues an AI evaluation for an attempt.
+// Enqueuing the same attempt twice is a no-op (one job per attempt).
+func (r *ExerciseRepository) EnqueueEvaluationJob(attemptID uuid.UUID, skillType string, maxAttempts int) error {
+	query := `
+		INSERT INTO evaluation_jobs (attempt_id, skill_type, max_attempts)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (attempt_id) DO NOTHING
+	`
+	_, err := r.db.Exec(query, attemptID, skillType, maxAttempts)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue evaluation job: %w", err)
+	}
+	return nil
+}
+
+// LeaseEvaluationJobs claims up to limit runnable jobs for owner.
+// A job is runnable when it is queued and due, or when its previous lease expired
+// (the worker holding it crashed). A 'recording' job keeps its status, so the new owner
+// only finishes the recording; one rescheduled by RetryEvaluationJob is runnable once due.
+// SKIP LOCKED keeps replicas from claiming the same job.
+func (r *ExerciseRepository) LeaseEvaluationJobs(owner string, limit int, leaseDuration time.Duration) ([]*models.EvaluationJob, error) {
+	query := `
+		UPDATE evaluation_jobs
+		SET status = CASE WHEN status = 'recording' THEN 'recording' ELSE 'leased' END,
+		    lease_owner = $1,
+		    lease_expires_at = NOW() + ($2 * INTERVAL '1 second'),
+		    attempts = attempts + 1,
+		    updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM evaluation_jobs
+			WHERE ((status = 'queued' OR (status = 'recording' AND lease_owner IS NULL)) AND next_run_at <= NOW())
+			   OR (status IN ('leased', 'recording') AND lease_expires_at < NOW())
+			ORDER BY next_run_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + evaluationJobColumns
+
+	rows, err := r.db.Query(query, owner, int(leaseDuration.Seconds()), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease evaluation jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.EvaluationJob
+	for rows.Next() {
+		job, err := scanEvaluationJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan evaluation job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// CompleteEvaluationJob stores the AI result on the attempt and moves the job to 'recording'
+// in one transaction; FinishEvaluationJob completes it once the result is recorded. If owner
+// no longer holds the lease nothing is written and ErrEvaluationLeaseLost is returned, so a
+// result is never applied twice.
+func (r *ExerciseRepository) CompleteEvaluationJob(jobID uuid.UUID, owner string, result *models.AIEvaluationResult, recordResult bool) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var attemptID uuid.UUID
+	err = tx.QueryRow(`
+		UPDATE evaluation_jobs
+		SET status = 'recording',
+		    record_result = $3,
+		    last_error = NULL,
+		    updated_at = NOW()
+		WHERE id = $1 AND status = 'leased' AND lease_owner = $2
+		RETURNING attempt_id
+	`, jobID, owner, recordResult).Scan(&attemptID)
+	if err == sql.ErrNoRows {
+		return ErrEvaluationLeaseLost
+	}
+	if err != nil {
+		return fmt.Errorf("failed to complete evaluation job: %w", err)
+	}
+
+	if err := updateSubmissionWithAIResult(tx, attemptID, result); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FinishEvaluationJob marks a 'recording' job completed
+func (r *ExerciseRepository) FinishEvaluationJob(jobID uuid.UUID, owner string) error {
+	query := `
+		UPDATE evaluation_jobs
+		SET status = 'completed',
+		    completed_at = NOW(),
+		    lease_owner = NULL,
+		    lease_expires_at = NULL,
+		    updated_at = NOW()
+		WHERE id = $1 AND status = 'recording' AND lease_owner = $2
+	`
+	res, err := r.db.Exec(query, jobID, owner)
+	if err != nil {
+		return fmt.Errorf("failed to finish evaluation job: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrEvaluationLeaseLost
+	}
+	return nil
+}
+
+// RetryEvaluationJob releases the lease and schedules the job to run again at nextRunAt.
+// A 'recording' job stays 'recording': its result is saved, so only the recording is retried.
+func (r *ExerciseRepository) RetryEvaluationJob(jobID uuid.UUID, owner, errorMsg string, nextRunAt time.Time) error {
+	query := `
+		UPDATE evaluation_jobs
+		SET status = CASE WHEN status = 'recording' THEN 'recording' ELSE 'queued' END,
+		    next_run_at = $3,
+		    last_error = $4,
+		    lease_owner = NULL,
+		    lease_expires_at = NULL,
+		    updated_at = NOW()
+		WHERE id = $1 AND status IN ('leased', 'recording') AND lease_owner = $2
+	`
+	res, err := r.db.Exec(query, jobID, owner, nextRunAt, errorMsg)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule evaluation job: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrEvaluationLeaseLost
+	}
+	return nil
+}
+
+// DeadLetterEvaluationJob gives up on a job and marks the attempt's evaluation as failed.
+// A job that dies while recording keeps its completed evaluation.
+func (r *ExerciseRepository) DeadLetterEvaluationJob(jobID uuid.UUID, owner, errorMsg string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var attemptID uuid.UUID
+	err = tx.QueryRow(`
+		UPDATE evaluation_jobs
+		SET status = 'dead',
+		    last_error = $3,
+		    lease_owner = NULL,
+		    lease_expires_at = NULL,
+		    updated_at = NOW()
+		WHERE id = $1 AND status IN ('leased', 'recording') AND lease_owner = $2
+		RETURNING attempt_id
+	`, jobID, owner, errorMsg).Scan(&attemptID)
+	if err == sql.ErrNoRows {
+		return ErrEvaluationLeaseLost
+	}
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter evaluation job: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE user_exercise_attempts
+		SET evaluation_status = 'failed', updated_at = NOW()
+		WHERE id = $1 AND evaluation_status <> 'completed'
+	`, attemptID)
+	if err != nil {
+		return fmt.Errorf("failed to mark evaluation as failed: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// RecoverEvaluationJobs is run at startup. It requeues jobs whose lease expired and
+// creates jobs for submitted Writing/Speaking attempts that are still waiting for an
+// evaluation but have no job (e.g. submitted before the queue existed).
+func (r *ExerciseRepository) RecoverEvaluationJobs(maxAttempts int) (requeued, backfilled int64, err error) {
+	res, err := r.db.Exec(`
+		UPDATE evaluation_jobs
+		SET status = 'queued',
+		    next_run_at = NOW(),
+		    lease_owner = NULL,
+		    lease_expires_at = NULL,
+		    updated_at = NOW()
+		WHERE status = 'leased' AND lease_expires_at < NOW()
+	`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to requeue expired evaluation jobs: %w", err)
+	}
+	requeued, _ = res.RowsAffected()
+
+	res, err = r.db.Exec(`
+		INSERT INTO evaluation_jobs (attempt_id, skill_type, max_attempts)
+		SELECT a.id, e.skill_type, $1
+		FROM user_exercise_attempts a
+		JOIN exercises e ON e.id = a.exercise_id
+		WHERE e.skill_type IN ('writing', 'speaking')
+		  AND a.status = 'submitted'
+		  AND a.evaluation_status IN ('pending', 'processing')
+		ON CONFLICT (attempt_id) DO NOTHING
+	`, maxAttempts)
+	if err != nil {
+		return requeued, 0, fmt.Errorf("failed to backfill evaluation jobs: %w", err)
+	}
+	backfilled, _ = res.RowsAffected()
+
+	return requeued, backfilled, nil
+}
+
+// GetDeadEvaluationJobs returns dead-lettered jobs, most recent first
+func (r *ExerciseRepository) GetDeadEvaluationJobs(limit, offset int) ([]*models.EvaluationJob, int, error) {
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM evaluation_jobs WHERE status = 'dead'`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT `+evaluationJobColumns+`
+		FROM evaluation_jobs
+		WHERE status = 'dead'
+		ORDER BY updated_at DESC
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var jobs []*models.EvaluationJob
+	for rows.Next() {
+		job, err := scanEvaluationJob(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, total, rows.Err()
+}
+
+// RequeueDeadEvaluationJob moves a dead job back to the queue with a fresh attempt budget.
+// A job whose evaluation was saved goes back to 'recording' instead of being evaluated again.
+func (r *ExerciseRepository) RequeueDeadEvaluationJob(jobID uuid.UUID) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var attemptID uuid.UUID
+	err = tx.QueryRow(`
+		UPDATE evaluation_jobs
+		SET status = CASE WHEN EXISTS (
+		        SELECT 1 FROM user_exercise_attempts a
+		        WHERE a.id = evaluation_jobs.attempt_id AND a.evaluation_status = 'completed'
+		    ) THEN 'recording' ELSE 'queued' END,
+		    attempts = 0, next_run_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'dead'
+		RETURNING attempt_id
+	`, jobID).Scan(&attemptID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("dead evaluation job not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to requeue evaluation job: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE user_exercise_attempts
+		SET evaluation_status = 'pending', updated_at = NOW()
+		WHERE id = $1 AND evaluation_status <> 'completed'
+	`, attemptID)
+	if err != nil {
+		return fmt.Errorf("failed to reset evaluation status: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ClaimResultRecording marks an attempt's result as recorded and reports whether this call did
+// so. Only the caller that claims it sends the result to User Service and notifies the student,
+// so a job finished again by a later lease holder does not record the attempt twice.
+func (r *ExerciseRepository) ClaimResultRecording(attemptID uuid.UUID) (bool, error) {
+	res, err := r.db.Exec(`
+		UPDATE user_exercise_attempts
+		SET result_recorded_at = NOW()
+		WHERE id = $1 AND result_recorded_at IS NULL
+	`, attemptID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim result recording: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// updateSubmissionWithAIResult writes the AI result to the attempt using exec (db or tx)
+func updateSubmissionWithAIResult(exec sqlExecer, submissionID uuid.UUID, result *models.AIEvaluationResult) error {
+	detailedScoresJSON, err := json.Marshal(result.DetailedScores)
+	if err != nil {
+		return fmt.Errorf("failed to marshal detailed_scores: %w", err)
+	}
+
+	// FIX: Only set completed_at if it's not already set (to avoid violating check_attempt_sync_after_completed constraint)
+	// For Writing/Speaking, completed_at should be set when user submits, not when AI evaluation completes
+	query := `
+		UPDATE user_exercise_attempts
+		SET band_score = $1,
+		    detailed_scores = $2,
+		    ai_feedback = $3,
+		    evaluation_status = 'completed',
+		    status = 'completed',
+		    completed_at = COALESCE(completed_at, NOW()),
+		    updated_at = NOW()
+		WHERE id = $4
+	`
+	_, err = exec.Exec(query, result.OverallBandScore, string(detailedScoresJSON), result.Feedback, submissionID)
+	return err
+}
@@ -0,0 +1,363 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// The evaluation queue is SQL: these tests run against a real PostgreSQL database, given as
+// EXERCISE_TEST_DATABASE_URL (the server needs the uuid-ossp, pg_trgm and dblink extensions).
+// Each test loads the exercise schema into its own throwaway schema.
+
+const exerciseSchemaFile = "../../../../database/schemas/04_exercise_service.sql"
+
+func newDBTestRepository(t *testing.T) (*ExerciseRepository, *sql.DB) {
+	dsn := os.Getenv("EXERCISE_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("EXERCISE_TEST_DATABASE_URL not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One connection, so the search path set below applies to every query
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	schema := fmt.Sprintf("exercise_repository_test_%d", time.Now().UnixNano())
+	if _, err := db.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+	if _, err := db.Exec(`SET search_path TO ` + schema + `, public`); err != nil {
+		t.Fatal(err)
+	}
+
+	ddl, err := os.ReadFile(exerciseSchemaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(string(ddl)); err != nil {
+		t.Fatalf("load schema: %v", err)
+	}
+
+	return NewExerciseRepository(db), db
+}
+
+// insertSubmittedWriting creates a Writing attempt waiting for its evaluation
+func insertSubmittedWriting(t *testing.T, db *sql.DB) uuid.UUID {
+	var exerciseID, attemptID uuid.UUID
+	err := db.QueryRow(`
+		INSERT INTO exercises (title, slug, exercise_type, skill_type, difficulty, created_by,
+			writing_task_type, writing_prompt_text)
+		VALUES ('Task 2', $1, 'writing', 'writing', 'medium', $2, 'task2', 'Discuss both views.')
+		RETURNING id
+	`, "task-2-"+uuid.NewString(), uuid.New()).Scan(&exerciseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.QueryRow(`
+		INSERT INTO user_exercise_attempts (user_id, exercise_id, total_questions, status, evaluation_status)
+		VALUES ($1, $2, 0, 'submitted', 'pending')
+		RETURNING id
+	`, uuid.New(), exerciseID).Scan(&attemptID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return attemptID
+}
+
+func leaseOne(t *testing.T, repo *ExerciseRepository, owner string) *models.EvaluationJob {
+	t.Helper()
+	jobs, err := repo.LeaseEvaluationJobs(owner, 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("%s leased %d jobs, want 1", owner, len(jobs))
+	}
+	return jobs[0]
+}
+
+func expectNoLease(t *testing.T, repo *ExerciseRepository, owner string) {
+	t.Helper()
+	jobs, err := repo.LeaseEvaluationJobs(owner, 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 0 {
+		t.Fatalf("%s leased %d jobs, want none", owner, len(jobs))
+	}
+}
+
+func jobStatus(t *testing.T, db *sql.DB, jobID uuid.UUID) string {
+	t.Helper()
+	var status string
+	if err := db.QueryRow(`SELECT status FROM evaluation_jobs WHERE id = $1`, jobID).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func evaluationStatus(t *testing.T, db *sql.DB, attemptID uuid.UUID) string {
+	t.Helper()
+	var status string
+	if err := db.QueryRow(`SELECT evaluation_status FROM user_exercise_attempts WHERE id = $1`, attemptID).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func expireLease(t *testing.T, db *sql.DB, jobID uuid.UUID) {
+	t.Helper()
+	if _, err := db.Exec(`UPDATE evaluation_jobs SET lease_expires_at = NOW() - INTERVAL '1 second' WHERE id = $1`, jobID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func makeDue(t *testing.T, db *sql.DB, jobID uuid.UUID) {
+	t.Helper()
+	if _, err := db.Exec(`UPDATE evaluation_jobs SET next_run_at = NOW() - INTERVAL '1 second' WHERE id = $1`, jobID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var queueTestResult = &models.AIEvaluationResult{OverallBandScore: 6.5, Feedback: "Clear position"}
+
+func TestEvaluationJobLeaseExpiry(t *testing.T) {
+	repo, db := newDBTestRepository(t)
+	attemptID := insertSubmittedWriting(t, db)
+	if err := repo.EnqueueEvaluationJob(attemptID, "writing", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	job := leaseOne(t, repo, "worker-a")
+	expectNoLease(t, repo, "worker-b")
+
+	// worker-a stops; once its lease expires worker-b takes the job over
+	expireLease(t, db, job.ID)
+	takenOver := leaseOne(t, repo, "worker-b")
+	if takenOver.ID != job.ID || takenOver.Attempts != 2 {
+		t.Fatalf("worker-b leased job %s on run %d, want %s on run 2", takenOver.ID, takenOver.Attempts, job.ID)
+	}
+
+	// The old owner's late result is discarded
+	err := repo.CompleteEvaluationJob(job.ID, "worker-a", queueTestResult, true)
+	if !errors.Is(err, ErrEvaluationLeaseLost) {
+		t.Fatalf("complete by expired owner = %v, want ErrEvaluationLeaseLost", err)
+	}
+	if err := repo.CompleteEvaluationJob(job.ID, "worker-b", queueTestResult, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := jobStatus(t, db, job.ID); got != "recording" {
+		t.Errorf("status after completion = %s, want recording", got)
+	}
+
+	// A recording job whose lease expires is taken over to finish the recording only
+	expireLease(t, db, job.ID)
+	recording := leaseOne(t, repo, "worker-c")
+	if recording.Status != "recording" || !recording.RecordResult {
+		t.Errorf("re-leased job status %s record %v, want recording with record_result", recording.Status, recording.RecordResult)
+	}
+	if err := repo.FinishEvaluationJob(job.ID, "worker-c"); err != nil {
+		t.Fatal(err)
+	}
+	if got := jobStatus(t, db, job.ID); got != "completed" {
+		t.Errorf("status after finish = %s, want completed", got)
+	}
+}
+
+func TestEvaluationJobRetry(t *testing.T) {
+	repo, db := newDBTestRepository(t)
+	attemptID := insertSubmittedWriting(t, db)
+	if err := repo.EnqueueEvaluationJob(attemptID, "writing", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	job := leaseOne(t, repo, "worker-a")
+	if err := repo.RetryEvaluationJob(job.ID, "worker-b", "timeout", time.Now()); !errors.Is(err, ErrEvaluationLeaseLost) {
+		t.Fatalf("retry by another worker = %v, want ErrEvaluationLeaseLost", err)
+	}
+	if err := repo.RetryEvaluationJob(job.ID, "worker-a", "timeout", time.Now().Add(48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got := jobStatus(t, db, job.ID); got != "queued" {
+		t.Errorf("status after retry = %s, want queued", got)
+	}
+
+	// Backoff: not runnable until next_run_at (two days out, so server and client time zones
+	// cannot make it due)
+	expectNoLease(t, repo, "worker-a")
+	makeDue(t, db, job.ID)
+	job = leaseOne(t, repo, "worker-a")
+
+	// A failed recording is retried as a recording, not as a new evaluation
+	if err := repo.CompleteEvaluationJob(job.ID, "worker-a", queueTestResult, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RetryEvaluationJob(job.ID, "worker-a", "user service down", time.Now().Add(48*time.Hour)); err != nil {
+		t.Fatalf("retry while recording: %v", err)
+	}
+	if got := jobStatus(t, db, job.ID); got != "recording" {
+		t.Errorf("status after recording retry = %s, want recording", got)
+	}
+	expectNoLease(t, repo, "worker-b")
+	makeDue(t, db, job.ID)
+	if job = leaseOne(t, repo, "worker-b"); job.Status != "recording" {
+		t.Errorf("re-leased status = %s, want recording", job.Status)
+	}
+}
+
+func TestEvaluationJobDeadLetter(t *testing.T) {
+	repo, db := newDBTestRepository(t)
+
+	// An evaluation that keeps failing is dead-lettered and the attempt marked failed
+	failing := insertSubmittedWriting(t, db)
+	if err := repo.EnqueueEvaluationJob(failing, "writing", 5); err != nil {
+		t.Fatal(err)
+	}
+	job := leaseOne(t, repo, "worker-a")
+	if err := repo.DeadLetterEvaluationJob(job.ID, "worker-a", "invalid response"); err != nil {
+		t.Fatal(err)
+	}
+	if got := jobStatus(t, db, job.ID); got != "dead" {
+		t.Errorf("status = %s, want dead", got)
+	}
+	if got := evaluationStatus(t, db, failing); got != "failed" {
+		t.Errorf("evaluation status = %s, want failed", got)
+	}
+	expectNoLease(t, repo, "worker-a")
+
+	// Requeued by an admin, it is evaluated again
+	if err := repo.RequeueDeadEvaluationJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := jobStatus(t, db, job.ID); got != "queued" {
+		t.Errorf("status after requeue = %s, want queued", got)
+	}
+	if got := evaluationStatus(t, db, failing); got != "pending" {
+		t.Errorf("evaluation status after requeue = %s, want pending", got)
+	}
+
+	// A job that dies while recording keeps its evaluation, and is requeued to record only
+	recorded := insertSubmittedWriting(t, db)
+	if err := repo.EnqueueEvaluationJob(recorded, "writing", 5); err != nil {
+		t.Fatal(err)
+	}
+	job = leaseOne(t, repo, "worker-a")
+	if err := repo.CompleteEvaluationJob(job.ID, "worker-a", queueTestResult, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeadLetterEvaluationJob(job.ID, "worker-a", "user service down"); err != nil {
+		t.Fatalf("dead-letter while recording: %v", err)
+	}
+	if got := evaluationStatus(t, db, recorded); got != "completed" {
+		t.Errorf("evaluation status = %s, want completed", got)
+	}
+	if err := repo.RequeueDeadEvaluationJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if job = leaseOne(t, repo, "worker-a"); job.Status != "recording" {
+		t.Errorf("requeued status = %s, want recording", job.Status)
+	}
+}
+
+func TestRecoverEvaluationJobs(t *testing.T) {
+	repo, db := newDBTestRepository(t)
+
+	// A job whose worker crashed
+	crashed := insertSubmittedWriting(t, db)
+	if err := repo.EnqueueEvaluationJob(crashed, "writing", 5); err != nil {
+		t.Fatal(err)
+	}
+	job := leaseOne(t, repo, "worker-a")
+	expireLease(t, db, job.ID)
+
+	// A submission with no job at all
+	orphan := insertSubmittedWriting(t, db)
+
+	requeued, backfilled, err := repo.RecoverEvaluationJobs(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requeued != 1 || backfilled != 1 {
+		t.Fatalf("recovered %d requeued, %d backfilled; want 1, 1", requeued, backfilled)
+	}
+	if got := jobStatus(t, db, job.ID); got != "queued" {
+		t.Errorf("crashed job status = %s, want queued", got)
+	}
+
+	jobs, err := repo.LeaseEvaluationJobs("worker-b", 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leased := map[uuid.UUID]bool{}
+	for _, j := range jobs {
+		leased[j.AttemptID] = true
+	}
+	if len(jobs) != 2 || !leased[crashed] || !leased[orphan] {
+		t.Errorf("leased %d jobs after recovery, want the crashed and the orphaned attempt", len(jobs))
+	}
+}
+
+func TestClaimResultRecording(t *testing.T) {
+	repo, db := newDBTestRepository(t)
+	attemptID := insertSubmittedWriting(t, db)
+
+	for i, want := range []bool{true, false} {
+		claimed, err := repo.ClaimResultRecording(attemptID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claimed != want {
+			t.Errorf("claim %d = %v, want %v", i+1, claimed, want)
+		}
+	}
+}
+
+func TestTranscriptKeepsSpeechMetrics(t *testing.T) {
+	repo, db := newDBTestRepository(t)
+	attemptID := insertSubmittedWriting(t, db)
+
+	metrics := json.RawMessage(`{"words_per_minute": 132.5, "long_pauses": 2}`)
+	if err := repo.UpdateSubmissionTranscript(attemptID, "I would like to talk about my hometown.", metrics); err != nil {
+		t.Fatal(err)
+	}
+
+	// A retry reads the transcript back with the metrics measured when it was made
+	submission, err := repo.GetSubmissionByID(attemptID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if submission.SpeechMetrics == nil {
+		t.Fatal("speech metrics were not saved with the transcript")
+	}
+	var got, want map[string]float64
+	if err := json.Unmarshal([]byte(*submission.SpeechMetrics), &got); err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal(metrics, &want)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("speech metrics = %v, want %v", got, want)
+	}
+
+	if err := repo.UpdateSubmissionTranscript(attemptID, "Transcribed without word timings.", nil); err != nil {
+		t.Fatal(err)
+	}
+	if submission, err = repo.GetSubmissionByID(attemptID); err != nil {
+		t.Fatal(err)
+	}
+	if submission.SpeechMetrics != nil {
+		t.Errorf("speech metrics = %s, want none for a transcript without them", *submission.SpeechMetrics)
+	}
+}
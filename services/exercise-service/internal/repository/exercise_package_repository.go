@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/exercisepkg"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// GetExerciseIDBySlug returns the exercise with the given slug, if any
+func (r *ExerciseRepository) GetExerciseIDBySlug(slug string) (uuid.UUID, bool, error) {
+	var id uuid.UUID
+	err := r.db.QueryRow(`SELECT id FROM exercises WHERE slug = $1`, slug).Scan(&id)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	return id, true, nil
+}
+
+// CountExerciseAttempts returns how many attempts students made at an exercise
+func (r *ExerciseRepository) CountExerciseAttempts(exerciseID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM user_exercise_attempts WHERE exercise_id = $1`, exerciseID).Scan(&count)
+	return count, err
+}
+
+// GetExercisePackageMedia returns the SHA-256 of the package files that the exercise's
+// imported audio was uploaded from, keyed by the stored URL
+func (r *ExerciseRepository) GetExercisePackageMedia(exerciseID uuid.UUID) (map[string]string, error) {
+	rows, err := r.db.Query(`SELECT stored_url, content_sha256 FROM exercise_package_media WHERE exercise_id = $1`, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	media := make(map[string]string)
+	for rows.Next() {
+		var url, hash string
+		if err := rows.Scan(&url, &hash); err != nil {
+			return nil, err
+		}
+		media[url] = hash
+	}
+	return media, rows.Err()
+}
+
+// SaveExercisePackage writes an imported package in one transaction. A new exercise is
+// created unpublished; an existing one (existingID) has its content replaced and keeps
+// its publishing state, author and statistics. media (stored URL → SHA-256 of the bundled
+// file) replaces the exercise's record of uploaded package audio.
+func (r *ExerciseRepository) SaveExercisePackage(pkg *exercisepkg.Package, existingID *uuid.UUID, createdBy uuid.UUID, media map[string]string) (uuid.UUID, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	exerciseID, err := writeExercisePackage(tx, pkg, existingID, createdBy)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := saveExercisePackageMedia(tx, exerciseID, media); err != nil {
+		return uuid.Nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+	return exerciseID, nil
+}
+
+func writeExercisePackage(tx *sql.Tx, pkg *exercisepkg.Package, existingID *uuid.UUID, createdBy uuid.UUID) (uuid.UUID, error) {
+	var err error
+	e := pkg.Exercise
+	now := time.Now()
+	exerciseID := uuid.New()
+	if existingID != nil {
+		exerciseID = *existingID
+	}
+
+	// Content columns, in the same order for insert and update
+	content := []interface{}{
+		e.Title, e.Slug, e.Description, e.ExerciseType, e.SkillType, e.IELTSTestType, e.Difficulty,
+		e.IELTSLevel, e.TimeLimitMinutes, e.ThumbnailURL, e.AudioURL, e.AudioDurationSeconds,
+		e.AudioTranscript, e.PassageCount, e.PassingScore, e.IsFree,
+		e.WritingTaskType, e.WritingPromptText, e.WritingVisualType, e.WritingVisualURL, e.WritingWordRequirement,
+		e.SpeakingPartNumber, e.SpeakingPromptText, e.SpeakingCueCardTopic, pq.Array(e.SpeakingCueCardPoints),
+		e.SpeakingPreparationTime, e.SpeakingResponseTime, pq.Array(e.SpeakingFollowUpQuestions),
+	}
+
+	var links []bankLink
+	if existingID == nil {
+		_, err = tx.Exec(`
+			INSERT INTO exercises (
+				id, created_by, title, slug, description, exercise_type, skill_type, ielts_test_type, difficulty,
+				ielts_level, time_limit_minutes, thumbnail_url, audio_url, audio_duration_seconds,
+				audio_transcript, passage_count, passing_score, is_free,
+				writing_task_type, writing_prompt_text, writing_visual_type, writing_visual_url, writing_word_requirement,
+				speaking_part_number, speaking_prompt_text, speaking_cue_card_topic, speaking_cue_card_points,
+				speaking_preparation_time_seconds, speaking_response_time_seconds, speaking_follow_up_questions,
+				is_published, created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18,
+				$19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, false, $31, $31)
+		`, append(append([]interface{}{exerciseID, createdBy}, content...), now)...)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE exercises SET
+				title = $2, slug = $3, description = $4, exercise_type = $5, skill_type = $6, ielts_test_type = $7,
+				difficulty = $8, ielts_level = $9, time_limit_minutes = $10, thumbnail_url = $11, audio_url = $12,
+				audio_duration_seconds = $13, audio_transcript = $14, passage_count = $15, passing_score = $16,
+				is_free = $17, writing_task_type = $18, writing_prompt_text = $19, writing_visual_type = $20,
+				writing_visual_url = $21, writing_word_requirement = $22, speaking_part_number = $23,
+				speaking_prompt_text = $24, speaking_cue_card_topic = $25, speaking_cue_card_points = $26,
+				speaking_preparation_time_seconds = $27, speaking_response_time_seconds = $28,
+				speaking_follow_up_questions = $29, updated_at = $30
+			WHERE id = $1
+		`, append(append([]interface{}{exerciseID}, content...), now)...)
+		if err == nil {
+			// The questions are replaced; remember which ones came from the question bank
+			links, err = getBankLinks(tx, exerciseID)
+		}
+		if err == nil {
+			// Options and answers go with their questions
+			_, err = tx.Exec(`DELETE FROM questions WHERE exercise_id = $1`, exerciseID)
+		}
+		if err == nil {
+			_, err = tx.Exec(`DELETE FROM exercise_sections WHERE exercise_id = $1`, exerciseID)
+		}
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to save exercise: %w", err)
+	}
+
+	for i, s := range pkg.Sections {
+		sectionID := uuid.New()
+		_, err := tx.Exec(`
+			INSERT INTO exercise_sections (
+				id, exercise_id, title, description, section_number, audio_url,
+				audio_start_time, audio_end_time, transcript, passage_title,
+				passage_content, passage_word_count, instructions, total_questions,
+				time_limit_minutes, display_order, created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $17)
+		`, sectionID, exerciseID, s.Title, s.Description, s.SectionNumber, s.AudioURL,
+			s.AudioStartTime, s.AudioEndTime, s.Transcript, s.PassageTitle,
+			s.PassageContent, s.PassageWordCount, s.Instructions, len(s.Questions),
+			s.TimeLimitMinutes, s.DisplayOrder, now)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to save sections[%d]: %w", i, err)
+		}
+
+		for j, q := range s.Questions {
+			if err := saveQuestion(tx, exerciseID, sectionID, &q, now); err != nil {
+				return uuid.Nil, fmt.Errorf("failed to save sections[%d].questions[%d]: %w", i, j, err)
+			}
+		}
+	}
+
+	if err := restoreBankLinks(tx, exerciseID, links); err != nil {
+		return uuid.Nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE exercises SET
+			total_sections = (SELECT COUNT(*) FROM exercise_sections WHERE exercise_id = $1),
+			total_questions = (SELECT COUNT(*) FROM questions WHERE exercise_id = $1),
+			total_points = (SELECT COALESCE(SUM(points), 0) FROM questions WHERE exercise_id = $1)
+		WHERE id = $1
+	`, exerciseID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to update exercise totals: %w", err)
+	}
+
+	if err := saveExerciseTags(tx, exerciseID, pkg.Tags); err != nil {
+		return uuid.Nil, err
+	}
+	return exerciseID, nil
+}
+
+// bankLink ties a question, by number and type, to the bank question it came from
+type bankLink struct {
+	questionNumber int
+	questionType   string
+	bankID         uuid.UUID
+}
+
+func getBankLinks(tx *sql.Tx, exerciseID uuid.UUID) ([]bankLink, error) {
+	rows, err := tx.Query(`
+		SELECT question_number, question_type, bank_question_id FROM questions
+		WHERE exercise_id = $1 AND bank_question_id IS NOT NULL
+	`, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []bankLink
+	for rows.Next() {
+		var link bankLink
+		if err := rows.Scan(&link.questionNumber, &link.questionType, &link.bankID); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// restoreBankLinks links the re-inserted questions to their bank questions again. A question
+// whose number now holds a question of another type is no longer the bank question.
+func restoreBankLinks(tx *sql.Tx, exerciseID uuid.UUID, links []bankLink) error {
+	if len(links) == 0 {
+		return nil
+	}
+	numbers := make([]int64, len(links))
+	types := make([]string, len(links))
+	bankIDs := make([]uuid.UUID, len(links))
+	for i, link := range links {
+		numbers[i], types[i], bankIDs[i] = int64(link.questionNumber), link.questionType, link.bankID
+	}
+
+	_, err := tx.Exec(`
+		UPDATE questions q
+		SET bank_question_id = l.bank_id
+		FROM unnest($2::int[], $3::text[], $4::uuid[]) AS l(question_number, question_type, bank_id)
+		WHERE q.exercise_id = $1 AND q.question_number = l.question_number AND q.question_type = l.question_type
+	`, exerciseID, pq.Array(numbers), pq.Array(types), pq.Array(bankIDs))
+	if err != nil {
+		return fmt.Errorf("failed to restore bank question links: %w", err)
+	}
+	return nil
+}
+
+func saveQuestion(tx *sql.Tx, exerciseID, sectionID uuid.UUID, q *exercisepkg.Question, now time.Time) error {
+	questionID := uuid.New()
+	_, err := tx.Exec(`
+		INSERT INTO questions (
+			id, exercise_id, section_id, question_number, question_text, question_type,
+			audio_url, image_url, context_text, points, difficulty, explanation,
+			tips, display_order, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $15)
+	`, questionID, exerciseID, sectionID, q.QuestionNumber, q.QuestionText, strings.ToLower(strings.TrimSpace(q.QuestionType)),
+		q.AudioURL, q.ImageURL, q.ContextText, q.Points, q.Difficulty, q.Explanation,
+		q.Tips, q.DisplayOrder, now)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range q.Options {
+		_, err := tx.Exec(`
+			INSERT INTO question_options (
+				id, question_id, option_label, option_text, option_image_url,
+				is_correct, display_order, created_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, uuid.New(), questionID, o.Label, o.Text, o.ImageURL, o.IsCorrect, o.DisplayOrder, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	for k, a := range q.Answers {
+		var variations interface{}
+		if len(a.Alternatives) > 0 {
+			variations = pq.Array(a.Alternatives)
+		}
+		// created_at keeps the package order when answers are read back
+		_, err := tx.Exec(`
+			INSERT INTO question_answers (
+				id, question_id, match_left, answer_text, answer_variations,
+				is_primary_answer, created_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, uuid.New(), questionID, a.Label, a.Text, variations, true, now.Add(time.Duration(k)*time.Millisecond))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveExerciseTags replaces the exercise's tags, creating tags that do not exist yet
+func saveExerciseTags(tx *sql.Tx, exerciseID uuid.UUID, tags []exercisepkg.Tag) error {
+	if _, err := tx.Exec(`DELETE FROM exercise_tag_mapping WHERE exercise_id = $1`, exerciseID); err != nil {
+		return fmt.Errorf("failed to clear tags: %w", err)
+	}
+
+	for i, tag := range tags {
+		var tagID int
+		err := tx.QueryRow(`
+			INSERT INTO exercise_tags (name, slug)
+			VALUES ($1, $2)
+			ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug
+			RETURNING id
+		`, tag.Name, tag.Slug).Scan(&tagID)
+		if err != nil {
+			return fmt.Errorf("failed to save tags[%d]: %w", i, err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO exercise_tag_mapping (exercise_id, tag_id)
+			VALUES ($1, $2)
+			ON CONFLICT (exercise_id, tag_id) DO NOTHING
+		`, exerciseID, tagID)
+		if err != nil {
+			return fmt.Errorf("failed to tag exercise with tags[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// saveExercisePackageMedia replaces the exercise's record of uploaded package audio
+func saveExercisePackageMedia(tx *sql.Tx, exerciseID uuid.UUID, media map[string]string) error {
+	if _, err := tx.Exec(`DELETE FROM exercise_package_media WHERE exercise_id = $1`, exerciseID); err != nil {
+		return fmt.Errorf("failed to clear package media: %w", err)
+	}
+
+	for url, hash := range media {
+		_, err := tx.Exec(`
+			INSERT INTO exercise_package_media (exercise_id, stored_url, content_sha256)
+			VALUES ($1, $2, $3)
+		`, exerciseID, url, hash)
+		if err != nil {
+			return fmt.Errorf("failed to save package media %s: %w", url, err)
+		}
+	}
+	return nil
+}
@@ -3,11 +3,15 @@ package repository
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"strings"
 	"time"
 
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/grading"
 	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
 	"github.com/bisosad1501/ielts-platform/exercise-service/internal/utils"
 	"github.com/google/uuid"
@@ -15,11 +19,12 @@ import (
 )
 
 type ExerciseRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	graders *grading.Registry
 }
 
 func NewExerciseRepository(db *sql.DB) *ExerciseRepository {
-	return &ExerciseRepository{db: db}
+	return &ExerciseRepository{db: db, graders: grading.NewRegistry()}
 }
 
 // GetExercises returns paginated list with filters
@@ -215,6 +220,31 @@ func (r *ExerciseRepository) GetExercises(query *models.ExerciseListQuery) ([]mo
 
 // GetExerciseByID returns exercise with sections and questions
 func (r *ExerciseRepository) GetExerciseByID(id uuid.UUID) (*models.ExerciseDetailResponse, error) {
+	return r.getExerciseDetail(id, false)
+}
+
+// GetExerciseForAuthor returns the exercise, published or not, including the text answer key
+func (r *ExerciseRepository) GetExerciseForAuthor(id uuid.UUID) (*models.ExerciseDetailResponse, error) {
+	detail, err := r.getExerciseDetail(id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err := r.getExerciseAnswers(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, section := range detail.Sections {
+		for i := range section.Questions {
+			question := &section.Questions[i]
+			question.Answers = answers[question.Question.ID]
+		}
+	}
+
+	return detail, nil
+}
+
+func (r *ExerciseRepository) getExerciseDetail(id uuid.UUID, includeUnpublished bool) (*models.ExerciseDetailResponse, error) {
 	// Get exercise
 	var exercise models.Exercise
 	err := r.db.QueryRow(`
@@ -236,8 +266,8 @@ func (r *ExerciseRepository) GetExerciseByID(id uuid.UUID) (*models.ExerciseDeta
 			e.speaking_part_number, e.speaking_prompt_text, e.speaking_cue_card_topic, e.speaking_cue_card_points,
 			e.speaking_preparation_time_seconds, e.speaking_response_time_seconds, e.speaking_follow_up_questions
 		FROM exercises e
-		WHERE e.id = $1 AND e.is_published = true
-	`, id).Scan(
+		WHERE e.id = $1 AND (e.is_published = true OR $2)
+	`, id, includeUnpublished).Scan(
 		&exercise.ID, &exercise.Title, &exercise.Slug, &exercise.Description,
 		&exercise.ExerciseType, &exercise.SkillType, &exercise.IELTSTestType, &exercise.Difficulty,
 		&exercise.IELTSLevel, &exercise.TotalQuestions, &exercise.TotalSections,
@@ -268,10 +298,67 @@ func (r *ExerciseRepository) GetExerciseByID(id uuid.UUID) (*models.ExerciseDeta
 	}, nil
 }
 
-// GetSectionsWithQuestions returns sections with their questions
+// GetSectionsWithQuestions returns the exercise's sections with their questions and options.
+// The whole tree is loaded in three queries (sections, questions, options) regardless of size.
 func (r *ExerciseRepository) GetSectionsWithQuestions(exerciseID uuid.UUID) ([]models.SectionWithQuestions, error) {
-	// Get sections
-	sectionRows, err := r.db.Query(`
+	sections, err := r.getSections(exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	questions, err := r.getExerciseQuestions(exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	options, err := r.getExerciseOptions(exerciseID)
+	if err != nil {
+		return nil, err
+	}
+
+	return assembleSections(sections, questions, options), nil
+}
+
+// assembleSections groups questions under their sections and options under their questions,
+// keeping the query order. Options are only attached to choice questions and questions without
+// a section are left out, matching what the exercise detail has always shown.
+func assembleSections(sections []models.ExerciseSection, questions []models.Question, options []models.QuestionOption) []models.SectionWithQuestions {
+	optionsByQuestion := make(map[uuid.UUID][]models.QuestionOption)
+	for _, option := range options {
+		optionsByQuestion[option.QuestionID] = append(optionsByQuestion[option.QuestionID], option)
+	}
+
+	questionsBySection := make(map[uuid.UUID][]models.QuestionWithOptions)
+	for i := range questions {
+		question := &questions[i]
+		if question.SectionID == nil {
+			continue
+		}
+
+		var questionOptions []models.QuestionOption
+		if question.QuestionType == "multiple_choice" || question.QuestionType == "matching" {
+			questionOptions = optionsByQuestion[question.ID]
+		}
+		questionsBySection[*question.SectionID] = append(questionsBySection[*question.SectionID], models.QuestionWithOptions{
+			Question: question,
+			Options:  questionOptions,
+		})
+	}
+
+	result := make([]models.SectionWithQuestions, 0, len(sections))
+	for i := range sections {
+		sectionQuestions := questionsBySection[sections[i].ID]
+		if sectionQuestions == nil {
+			sectionQuestions = []models.QuestionWithOptions{}
+		}
+		result = append(result, models.SectionWithQuestions{
+			Section:   &sections[i],
+			Questions: sectionQuestions,
+		})
+	}
+	return result
+}
+
+func (r *ExerciseRepository) getSections(exerciseID uuid.UUID) ([]models.ExerciseSection, error) {
+	rows, err := r.db.Query(`
 		SELECT id, exercise_id, title, description, section_number, audio_url,
 			audio_start_time, audio_end_time, transcript, passage_title,
 			passage_content, passage_word_count, instructions, total_questions,
@@ -283,12 +370,12 @@ func (r *ExerciseRepository) GetSectionsWithQuestions(exerciseID uuid.UUID) ([]m
 	if err != nil {
 		return nil, err
 	}
-	defer sectionRows.Close()
+	defer rows.Close()
 
-	sections := []models.SectionWithQuestions{}
-	for sectionRows.Next() {
+	sections := []models.ExerciseSection{}
+	for rows.Next() {
 		var section models.ExerciseSection
-		err := sectionRows.Scan(
+		err := rows.Scan(
 			&section.ID, &section.ExerciseID, &section.Title, &section.Description,
 			&section.SectionNumber, &section.AudioURL, &section.AudioStartTime,
 			&section.AudioEndTime, &section.Transcript, &section.PassageTitle,
@@ -299,41 +386,30 @@ func (r *ExerciseRepository) GetSectionsWithQuestions(exerciseID uuid.UUID) ([]m
 		if err != nil {
 			return nil, err
 		}
-
-		// Get questions for this section
-		questions, err := r.GetQuestionsWithOptions(section.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		sections = append(sections, models.SectionWithQuestions{
-			Section:   &section,
-			Questions: questions,
-		})
+		sections = append(sections, section)
 	}
 
-	return sections, nil
+	return sections, rows.Err()
 }
 
-// GetQuestionsWithOptions returns questions with their options
-func (r *ExerciseRepository) GetQuestionsWithOptions(sectionID uuid.UUID) ([]models.QuestionWithOptions, error) {
-	questionRows, err := r.db.Query(`
+func (r *ExerciseRepository) getExerciseQuestions(exerciseID uuid.UUID) ([]models.Question, error) {
+	rows, err := r.db.Query(`
 		SELECT id, exercise_id, section_id, question_number, question_text,
 			question_type, audio_url, image_url, context_text, points,
 			difficulty, explanation, tips, display_order, created_at, updated_at
 		FROM questions 
-		WHERE section_id = $1 
+		WHERE exercise_id = $1 
 		ORDER BY display_order, question_number
-	`, sectionID)
+	`, exerciseID)
 	if err != nil {
 		return nil, err
 	}
-	defer questionRows.Close()
+	defer rows.Close()
 
-	questions := []models.QuestionWithOptions{}
-	for questionRows.Next() {
+	questions := []models.Question{}
+	for rows.Next() {
 		var question models.Question
-		err := questionRows.Scan(
+		err := rows.Scan(
 			&question.ID, &question.ExerciseID, &question.SectionID,
 			&question.QuestionNumber, &question.QuestionText, &question.QuestionType,
 			&question.AudioURL, &question.ImageURL, &question.ContextText,
@@ -344,45 +420,85 @@ func (r *ExerciseRepository) GetQuestionsWithOptions(sectionID uuid.UUID) ([]mod
 		if err != nil {
 			return nil, err
 		}
+		questions = append(questions, question)
+	}
 
-		// Get options based on question type
-		var options []models.QuestionOption
+	return questions, rows.Err()
+}
 
-		if question.QuestionType == "multiple_choice" || question.QuestionType == "matching" {
-			optionRows, err := r.db.Query(`
-				SELECT id, question_id, option_label, option_text, option_image_url,
-					is_correct, display_order, created_at
-				FROM question_options 
-				WHERE question_id = $1 
-				ORDER BY display_order
-			`, question.ID)
-			if err != nil {
-				return nil, err
-			}
-			defer optionRows.Close()
-
-			for optionRows.Next() {
-				var option models.QuestionOption
-				err := optionRows.Scan(
-					&option.ID, &option.QuestionID, &option.OptionLabel,
-					&option.OptionText, &option.OptionImageURL, &option.IsCorrect,
-					&option.DisplayOrder, &option.CreatedAt,
-				)
-				if err != nil {
-					return nil, err
-				}
-				options = append(options, option)
-			}
+func (r *ExerciseRepository) getExerciseOptions(exerciseID uuid.UUID) ([]models.QuestionOption, error) {
+	rows, err := r.db.Query(`
+		SELECT o.id, o.question_id, o.option_label, o.option_text, o.option_image_url,
+			o.is_correct, o.display_order, o.created_at
+		FROM question_options o
+		JOIN questions q ON q.id = o.question_id
+		WHERE q.exercise_id = $1
+		ORDER BY o.question_id, o.display_order
+	`, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := []models.QuestionOption{}
+	for rows.Next() {
+		var option models.QuestionOption
+		err := rows.Scan(
+			&option.ID, &option.QuestionID, &option.OptionLabel,
+			&option.OptionText, &option.OptionImageURL, &option.IsCorrect,
+			&option.DisplayOrder, &option.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
 		}
-		// Note: For text-based questions, answers are not included in public view
+		options = append(options, option)
+	}
 
-		questions = append(questions, models.QuestionWithOptions{
-			Question: &question,
-			Options:  options,
-		})
+	return options, rows.Err()
+}
+
+// getExerciseAnswers returns the accepted answers of the exercise's text-based questions, by question
+func (r *ExerciseRepository) getExerciseAnswers(exerciseID uuid.UUID) (map[uuid.UUID][]models.QuestionAnswer, error) {
+	rows, err := r.db.Query(`
+		SELECT a.id, a.question_id, a.match_left, a.answer_text, a.answer_variations, a.created_at
+		FROM question_answers a
+		JOIN questions q ON q.id = a.question_id
+		WHERE q.exercise_id = $1
+		ORDER BY a.question_id, a.is_primary_answer DESC, a.created_at
+	`, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	answers := make(map[uuid.UUID][]models.QuestionAnswer)
+	for rows.Next() {
+		var answer models.QuestionAnswer
+		var variations []string
+		if err := rows.Scan(
+			&answer.ID, &answer.QuestionID, &answer.Label, &answer.AnswerText, pq.Array(&variations), &answer.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if len(variations) > 0 {
+			jsonBytes, _ := json.Marshal(variations)
+			jsonStr := string(jsonBytes)
+			answer.AlternativeAnswers = &jsonStr
+		}
+		answers[answer.QuestionID] = append(answers[answer.QuestionID], answer)
 	}
 
-	return questions, nil
+	return answers, rows.Err()
+}
+
+// GetQuestionExerciseID returns the exercise a question belongs to
+func (r *ExerciseRepository) GetQuestionExerciseID(questionID uuid.UUID) (uuid.UUID, error) {
+	var exerciseID uuid.UUID
+	err := r.db.QueryRow(`SELECT exercise_id FROM questions WHERE id = $1`, questionID).Scan(&exerciseID)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("question not found")
+	}
+	return exerciseID, err
 }
 
 // CreateSubmission starts a new submission (uses user_exercise_attempts table)
@@ -413,14 +529,14 @@ func (r *ExerciseRepository) CreateSubmission(userID, exerciseID uuid.UUID, devi
 		INSERT INTO user_exercise_attempts (
 			id, user_id, exercise_id, attempt_number, status, 
 			total_questions, questions_answered, correct_answers, 
-			time_limit_minutes, time_spent_seconds, started_at, device_type,
+			time_limit_minutes, time_spent_seconds, started_at, last_activity_at, device_type,
 			created_at, updated_at
 		) VALUES (
 			$1, $2, $3, 
 			(SELECT COALESCE(MAX(attempt_number), 0) + 1 
 			 FROM user_exercise_attempts 
 			 WHERE user_id = $2 AND exercise_id = $3),
-			$4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+			$4, $5, $6, $7, $8, $9, $10, $10, $11, $12, $13
 		)
 		RETURNING attempt_number
 	`, submissionID, userID, exerciseID, status, totalQuestions, questionsAnswered,
@@ -442,6 +558,7 @@ func (r *ExerciseRepository) CreateSubmission(userID, exerciseID uuid.UUID, devi
 		TimeSpentSeconds:  timeSpent,
 		TimeLimitMinutes:  timeLimitMinutes,
 		StartedAt:         now,
+		LastActivityAt:    &now,
 		DeviceType:        deviceType,
 		CreatedAt:         now,
 		UpdatedAt:         now,
@@ -485,13 +602,8 @@ func (r *ExerciseRepository) SaveSubmissionAnswers(submissionID uuid.UUID, answe
 	}
 
 	for _, answer := range answers {
-		// Get question details for grading and validate it belongs to the exercise
-		var questionType string
-		var points float64
-		var questionExerciseID uuid.UUID
-		err := tx.QueryRow(`
-			SELECT question_type, points, exercise_id FROM questions WHERE id = $1
-		`, answer.QuestionID).Scan(&questionType, &points, &questionExerciseID)
+		// Load the answer key and validate the question belongs to the exercise
+		key, questionExerciseID, err := loadGradingKey(tx, answer.QuestionID)
 		if err != nil {
 			log.Printf("[Exercise-Repo] Error fetching question %s: %v", answer.QuestionID, err)
 			return fmt.Errorf("question not found: %s: %w", answer.QuestionID, err)
@@ -504,78 +616,55 @@ func (r *ExerciseRepository) SaveSubmissionAnswers(submissionID uuid.UUID, answe
 			return fmt.Errorf("question %s does not belong to exercise %s", answer.QuestionID, exerciseID)
 		}
 
-		isCorrect := false
-		pointsEarned := 0.0
-
-		// Grade based on question type
-		if questionType == "multiple_choice" || questionType == "matching" {
-			if answer.SelectedOptionID != nil {
-				var optionIsCorrect bool
-				err = tx.QueryRow(`
-					SELECT is_correct FROM question_options 
-					WHERE id = $1
-				`, *answer.SelectedOptionID).Scan(&optionIsCorrect)
-				if err == nil && optionIsCorrect {
-					isCorrect = true
-					pointsEarned = points
-				}
+		// Grade with the grader for the question type (partial credit for multi-item questions)
+		result := r.GradeAnswer(key, answer)
+		for _, item := range result.Items {
+			if item.Reason == grading.ReasonNoAnswerKey {
+				// Answer will be marked as incorrect (isCorrect = false, pointsEarned = 0)
+				log.Printf("[Exercise-Repo] ⚠️  WARNING: No answer key found for question %s (type: %s)",
+					answer.QuestionID, key.QuestionType)
+				break
 			}
-		} else {
-			// Text-based questions (fill-in-blank, short_answer)
-			if answer.TextAnswer != nil && *answer.TextAnswer != "" {
-				var answerText string
-				var answerVariations pq.StringArray
-				err = tx.QueryRow(`
-					SELECT answer_text, COALESCE(answer_variations, '{}')
-					FROM question_answers 
-					WHERE question_id = $1
-				`, answer.QuestionID).Scan(&answerText, &answerVariations)
-
-				if err == nil {
-					// Case-insensitive comparison by default
-					userAnswer := strings.ToLower(strings.TrimSpace(*answer.TextAnswer))
-					answerText = strings.ToLower(strings.TrimSpace(answerText))
-
-					// Check main answer
-					if userAnswer == answerText {
-						isCorrect = true
-						pointsEarned = points
-					} else {
-						// Check alternative answer variations
-						for _, alt := range answerVariations {
-							if strings.ToLower(strings.TrimSpace(alt)) == userAnswer {
-								isCorrect = true
-								pointsEarned = points
-								break
-							}
-						}
-					}
-				} else {
-					// Log warning when correct answer not found in database
-					log.Printf("[Exercise-Repo] ⚠️  WARNING: No correct answer found for question %s (question_id: %s). User answer: '%s'. Error: %v",
-						answer.QuestionID, answer.QuestionID, *answer.TextAnswer, err)
-					// Answer will be marked as incorrect (isCorrect = false, pointsEarned = 0)
-				}
+		}
+
+		gradingDetails, err := json.Marshal(result.Items)
+		if err != nil {
+			return fmt.Errorf("failed to encode grading details: %w", err)
+		}
+		var answerMatches interface{}
+		if len(answer.Matches) > 0 {
+			encoded, err := json.Marshal(answer.Matches)
+			if err != nil {
+				return fmt.Errorf("failed to encode answer matches: %w", err)
 			}
+			answerMatches = string(encoded)
+		}
+		var selectedOptions interface{}
+		if len(answer.SelectedOptionIDs) > 0 {
+			selectedOptions = pq.Array(answer.SelectedOptionIDs)
 		}
 
 		// UPSERT: Insert or update answer (atomic operation with unique constraint)
 		_, err = tx.Exec(`
 			INSERT INTO user_answers (
 				id, attempt_id, question_id, user_id, answer_text, selected_option_id,
-				is_correct, points_earned, time_spent_seconds, answered_at
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+				selected_options, answer_matches, is_correct, points_earned, grading_details,
+				time_spent_seconds, answered_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 			ON CONFLICT (attempt_id, question_id) DO UPDATE SET
 				answer_text = EXCLUDED.answer_text,
 				selected_option_id = EXCLUDED.selected_option_id,
+				selected_options = EXCLUDED.selected_options,
+				answer_matches = EXCLUDED.answer_matches,
 				is_correct = EXCLUDED.is_correct,
 				points_earned = EXCLUDED.points_earned,
+				grading_details = EXCLUDED.grading_details,
 				time_spent_seconds = COALESCE(EXCLUDED.time_spent_seconds, user_answers.time_spent_seconds),
 				answered_at = EXCLUDED.answered_at,
 				updated_at = CURRENT_TIMESTAMP
 		`, uuid.New(), submissionID, answer.QuestionID, userID, answer.TextAnswer,
-			answer.SelectedOptionID, isCorrect, pointsEarned, answer.TimeSpentSeconds,
-			time.Now())
+			answer.SelectedOptionID, selectedOptions, answerMatches, result.Correct, result.PointsEarned,
+			string(gradingDetails), answer.TimeSpentSeconds, time.Now())
 		if err != nil {
 			log.Printf("[Exercise-Repo] Error upserting answer for question %s: %v", answer.QuestionID, err)
 			return fmt.Errorf("failed to upsert answer: %w", err)
@@ -585,11 +674,116 @@ func (r *ExerciseRepository) SaveSubmissionAnswers(submissionID uuid.UUID, answe
 	return tx.Commit()
 }
 
+// GetGradingKey loads a question's answer key and returns the exercise it belongs to
+func (r *ExerciseRepository) GetGradingKey(questionID uuid.UUID) (grading.Key, uuid.UUID, error) {
+	return loadGradingKey(r.db, questionID)
+}
+
+// GradeAnswer grades one answer against a question's answer key
+func (r *ExerciseRepository) GradeAnswer(key grading.Key, answer models.SubmitAnswerItem) grading.Result {
+	return r.graders.Grade(key, grading.Response{
+		SelectedOptionID:  answer.SelectedOptionID,
+		SelectedOptionIDs: answer.SelectedOptionIDs,
+		Text:              answer.TextAnswer,
+		Matches:           answer.Matches,
+	})
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// loadGradingKey loads a question's answer key, with the word limit from its text or its
+// section's instructions, and returns the exercise the question belongs to
+func loadGradingKey(tx queryer, questionID uuid.UUID) (grading.Key, uuid.UUID, error) {
+	var key grading.Key
+	var exerciseID uuid.UUID
+	var questionText string
+	var contextText, instructions sql.NullString
+	err := tx.QueryRow(`
+		SELECT q.question_type, q.points, q.exercise_id, q.question_text, q.context_text, s.instructions
+		FROM questions q
+		LEFT JOIN exercise_sections s ON s.id = q.section_id
+		WHERE q.id = $1
+	`, questionID).Scan(&key.QuestionType, &key.Points, &exerciseID, &questionText, &contextText, &instructions)
+	if err != nil {
+		return key, exerciseID, err
+	}
+	key.WordLimit = ielts.ParseWordLimit(questionText, contextText.String, instructions.String)
+
+	optionRows, err := tx.Query(`
+		SELECT id, COALESCE(option_label, ''), option_text, COALESCE(is_correct, false)
+		FROM question_options
+		WHERE question_id = $1
+		ORDER BY display_order, option_label
+	`, questionID)
+	if err != nil {
+		return key, exerciseID, err
+	}
+	for optionRows.Next() {
+		var option grading.Option
+		if err := optionRows.Scan(&option.ID, &option.Label, &option.Text, &option.IsCorrect); err != nil {
+			optionRows.Close()
+			return key, exerciseID, err
+		}
+		key.Options = append(key.Options, option)
+	}
+	optionRows.Close()
+
+	// Matching pairs and multi-blank answers are labelled by match_left
+	answerRows, err := tx.Query(`
+		SELECT COALESCE(match_left, ''), COALESCE(match_right, answer_text), COALESCE(answer_variations, '{}')
+		FROM question_answers
+		WHERE question_id = $1
+		ORDER BY is_primary_answer DESC, created_at
+	`, questionID)
+	if err != nil {
+		return key, exerciseID, err
+	}
+	defer answerRows.Close()
+	for answerRows.Next() {
+		var answer grading.Answer
+		var variations pq.StringArray
+		if err := answerRows.Scan(&answer.Label, &answer.Text, &variations); err != nil {
+			return key, exerciseID, err
+		}
+		answer.Variations = variations
+		key.Answers = append(key.Answers, answer)
+	}
+
+	return key, exerciseID, answerRows.Err()
+}
+
+// ErrAttemptAlreadyCompleted is returned when completing an attempt that was already completed,
+// e.g. by the student's submit and the expiry sweeper racing. The caller must not record the
+// result again.
+var ErrAttemptAlreadyCompleted = errors.New("attempt already completed")
+
 // CompleteSubmission finalizes submission (backward compatibility)
 func (r *ExerciseRepository) CompleteSubmission(submissionID uuid.UUID) error {
 	return r.CompleteSubmissionWithTime(submissionID, nil)
 }
 
+// elapsedAttemptSeconds is the wall-clock time of an attempt, ending at the time limit for timed attempts
+func elapsedAttemptSeconds(startedAt, now time.Time, timeLimitMinutes *int) int {
+	elapsed := int(now.Sub(startedAt).Seconds())
+	if timeLimitMinutes != nil && *timeLimitMinutes > 0 && elapsed > *timeLimitMinutes*60 {
+		elapsed = *timeLimitMinutes * 60
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return elapsed
+}
+
+// wholeMarks rounds a raw score with partial credit down to whole marks, as the band
+// conversion tables count marks
+func wholeMarks(rawScore float64) int {
+	return int(math.Floor(rawScore + 1e-9)) // Sums of fractions such as 1/3 + 2/3 may fall just short
+}
+
 // CompleteSubmissionWithTime finalizes submission and calculates final score with optional frontend time
 func (r *ExerciseRepository) CompleteSubmissionWithTime(submissionID uuid.UUID, frontendTimeSpent *int) error {
 	tx, err := r.db.Begin()
@@ -604,10 +798,12 @@ func (r *ExerciseRepository) CompleteSubmissionWithTime(submissionID uuid.UUID,
 	var totalQuestions int
 	var exerciseID uuid.UUID
 	var timeLimitMinutes *int
+	// Lock the attempt: the user's submit and the expiry sweeper may complete it concurrently
 	err = tx.QueryRow(`
 		SELECT status, started_at, total_questions, exercise_id, time_limit_minutes
 		FROM user_exercise_attempts 
 		WHERE id = $1
+		FOR UPDATE
 	`, submissionID).Scan(&currentStatus, &startedAt, &totalQuestions, &exerciseID, &timeLimitMinutes)
 	if err != nil {
 		return err
@@ -616,11 +812,15 @@ func (r *ExerciseRepository) CompleteSubmissionWithTime(submissionID uuid.UUID,
 	// If already completed, skip to avoid duplicate statistics
 	if currentStatus == "completed" {
 		log.Printf("[Exercise-Repo] Submission %s already completed, skipping duplicate completion", submissionID)
-		return nil
+		return ErrAttemptAlreadyCompleted
 	}
 
-	// Calculate statistics from user_answers
+	// Calculate statistics from user_answers. The raw score gives each question the fraction
+	// of its items the grader marked correct (points_earned / points), so partial credit on
+	// multi-item questions counts towards the band; correct_answers stays fully correct questions.
+	// The raw score is stored in whole marks, and the band and user-service records use it.
 	var correctCount int
+	var rawScore float64
 	var totalPointsEarned float64
 	var totalTimeSpent int
 	var questionsAnswered int
@@ -628,12 +828,18 @@ func (r *ExerciseRepository) CompleteSubmissionWithTime(submissionID uuid.UUID,
 	err = tx.QueryRow(`
 		SELECT 
 			COUNT(*) as answered,
-			COUNT(CASE WHEN is_correct = true THEN 1 END) as correct,
-			COALESCE(SUM(points_earned), 0) as points,
-			COALESCE(SUM(time_spent_seconds), 0) as time_spent
-		FROM user_answers
-		WHERE attempt_id = $1
-	`, submissionID).Scan(&questionsAnswered, &correctCount, &totalPointsEarned, &totalTimeSpent)
+			COUNT(CASE WHEN ua.is_correct = true THEN 1 END) as correct,
+			COALESCE(SUM(CASE
+				WHEN q.points > 0 THEN LEAST(ua.points_earned / q.points, 1)
+				WHEN ua.is_correct = true THEN 1
+				ELSE 0
+			END), 0) as raw_score,
+			COALESCE(SUM(ua.points_earned), 0) as points,
+			COALESCE(SUM(ua.time_spent_seconds), 0) as time_spent
+		FROM user_answers ua
+		JOIN questions q ON q.id = ua.question_id
+		WHERE ua.attempt_id = $1
+	`, submissionID).Scan(&questionsAnswered, &correctCount, &rawScore, &totalPointsEarned, &totalTimeSpent)
 	if err != nil {
 		return err
 	}
@@ -657,8 +863,8 @@ func (r *ExerciseRepository) CompleteSubmissionWithTime(submissionID uuid.UUID,
 		// Calculate percentage from points earned
 		score = (totalPointsEarned / totalPoints) * 100
 	} else if totalQuestions > 0 {
-		// Fallback: calculate percentage from correct answers
-		score = (float64(correctCount) / float64(totalQuestions)) * 100
+		// Fallback: calculate percentage from the raw score
+		score = (rawScore / float64(totalQuestions)) * 100
 	} else {
 		score = 0.0
 	}
@@ -736,50 +942,37 @@ func (r *ExerciseRepository) CompleteSubmissionWithTime(submissionID uuid.UUID,
 
 	// Use official IELTS conversion table (raw score → band score)
 	// Pass testType for Reading exercises to use correct conversion table
+	marks := wholeMarks(rawScore)
 	var bandScore float64
 	if testType != "" {
-		bandScore = utils.ConvertRawScoreToBandScore(skillType, correctCount, totalQuestions, testType)
+		bandScore = utils.ConvertRawScoreToBandScore(skillType, marks, totalQuestions, testType)
 	} else {
-		bandScore = utils.ConvertRawScoreToBandScore(skillType, correctCount, totalQuestions)
+		bandScore = utils.ConvertRawScoreToBandScore(skillType, marks, totalQuestions)
 	}
 
-	// Calculate time spent - prioritize frontend tracked time for accuracy
-	// This ensures time represents ACTIVE study time, not just elapsed wall-clock time
+	// Calculate time spent on the server. The frontend timer only counts active time, so it is
+	// preferred, but only when it fits in the elapsed wall-clock time since started_at
 	completedAt := time.Now()
+	elapsedSeconds := elapsedAttemptSeconds(startedAt, completedAt, timeLimitMinutes)
 	var timeSpent int
 	
-	if frontendTimeSpent != nil && *frontendTimeSpent > 0 {
-		// PRIMARY: Use frontend-tracked time (most accurate - only counts active time)
-		// Frontend timer runs only when user is actively working on exercise
+	if frontendTimeSpent != nil && *frontendTimeSpent > 0 && *frontendTimeSpent <= elapsedSeconds {
+		// PRIMARY: Use frontend-tracked time (only counts active time)
 		timeSpent = *frontendTimeSpent
 		log.Printf("[Exercise-Repo] ✅ Using frontend-tracked time: %d seconds (~%.1f minutes)", 
 			timeSpent, float64(timeSpent)/60)
-	} else {
-		// FALLBACK: Calculate elapsed time from started_at to now
-		// This is less accurate as it includes time when user might be inactive
-		elapsedSeconds := int(completedAt.Sub(startedAt).Seconds())
-		log.Printf("[Exercise-Repo] ⚠️  No frontend time, calculating elapsed: %d seconds", elapsedSeconds)
-		
+	} else if totalTimeSpent > 0 && totalTimeSpent <= elapsedSeconds {
 		// Use totalTimeSpent from answers if available and reasonable
-		if totalTimeSpent > 0 && totalTimeSpent <= elapsedSeconds {
-			timeSpent = totalTimeSpent
-			log.Printf("[Exercise-Repo] Using sum of answer times: %d seconds", timeSpent)
-		} else {
-			timeSpent = elapsedSeconds
-			log.Printf("[Exercise-Repo] Using elapsed time: %d seconds", timeSpent)
-		}
-	}
-
-	// VALIDATION: Cap at time limit if exceeded (soft limit, with warning)
-	// This ensures data integrity without rejecting legitimate submissions
-	if timeLimitMinutes != nil && *timeLimitMinutes > 0 {
-		maxSeconds := *timeLimitMinutes * 60
-		if timeSpent > maxSeconds {
-			exceededBy := timeSpent - maxSeconds
-			log.Printf("[Exercise-Repo] ⚠️  WARNING: Submission %s exceeded time limit by %d seconds (spent: %d, limit: %d). Capping at limit.",
-				submissionID, exceededBy, timeSpent, maxSeconds)
-			timeSpent = maxSeconds
+		timeSpent = totalTimeSpent
+		log.Printf("[Exercise-Repo] Using sum of answer times: %d seconds", timeSpent)
+	} else {
+		// FALLBACK: Elapsed time, which includes time when user might be inactive
+		if frontendTimeSpent != nil && *frontendTimeSpent > elapsedSeconds {
+			log.Printf("[Exercise-Repo] ⚠️  WARNING: Submission %s reported %d seconds but only %d elapsed, using elapsed",
+				submissionID, *frontendTimeSpent, elapsedSeconds)
 		}
+		timeSpent = elapsedSeconds
+		log.Printf("[Exercise-Repo] Using elapsed time: %d seconds", timeSpent)
 	}
 	
 	log.Printf("[Exercise-Repo] 📊 Final time_spent_seconds: %d (~%.1f minutes)", timeSpent, float64(timeSpent)/60)
@@ -791,12 +984,14 @@ func (r *ExerciseRepository) CompleteSubmissionWithTime(submissionID uuid.UUID,
 			time_spent_seconds = $2,
 			questions_answered = $3,
 			correct_answers = $4,
-			score = $5,
-			band_score = $6,
+			raw_score = $5,
+			score = $6,
+			band_score = $7,
 			status = 'completed',
-			updated_at = $7
-		WHERE id = $8
-	`, completedAt, timeSpent, questionsAnswered, correctCount,
+			last_activity_at = $8,
+			updated_at = $8
+		WHERE id = $9
+	`, completedAt, timeSpent, questionsAnswered, correctCount, marks,
 		score, bandScore, completedAt, submissionID)
 	if err != nil {
 		return err
@@ -895,7 +1090,8 @@ func (r *ExerciseRepository) GetSubmissionResult(submissionID uuid.UUID) (*model
 	// Get answers with questions (from user_answers table)
 	rows, err := r.db.Query(`
 		SELECT ua.id, ua.attempt_id, ua.question_id, ua.user_id, ua.answer_text,
-			ua.selected_option_id, ua.is_correct, ua.points_earned, ua.time_spent_seconds,
+			ua.selected_option_id, COALESCE(ua.selected_options, '{}'), ua.answer_matches,
+			ua.is_correct, ua.points_earned, ua.grading_details, ua.time_spent_seconds,
 			ua.answered_at,
 			q.id, q.exercise_id, q.section_id, q.question_number, q.question_text,
 			q.question_type, q.audio_url, q.image_url, q.context_text, q.points,
@@ -914,11 +1110,13 @@ func (r *ExerciseRepository) GetSubmissionResult(submissionID uuid.UUID) (*model
 	for rows.Next() {
 		var submissionAnswer models.SubmissionAnswer
 		var question models.Question
+		var selectedOptions pq.StringArray
+		var answerMatches, gradingDetails []byte
 		err := rows.Scan(
 			&submissionAnswer.ID, &submissionAnswer.AttemptID, &submissionAnswer.QuestionID,
 			&submissionAnswer.UserID, &submissionAnswer.AnswerText, &submissionAnswer.SelectedOptionID,
-			&submissionAnswer.IsCorrect, &submissionAnswer.PointsEarned, &submissionAnswer.TimeSpentSeconds,
-			&submissionAnswer.AnsweredAt,
+			&selectedOptions, &answerMatches, &submissionAnswer.IsCorrect, &submissionAnswer.PointsEarned,
+			&gradingDetails, &submissionAnswer.TimeSpentSeconds, &submissionAnswer.AnsweredAt,
 			&question.ID, &question.ExerciseID, &question.SectionID, &question.QuestionNumber,
 			&question.QuestionText, &question.QuestionType, &question.AudioURL, &question.ImageURL,
 			&question.ContextText, &question.Points, &question.Difficulty, &question.Explanation,
@@ -927,6 +1125,21 @@ func (r *ExerciseRepository) GetSubmissionResult(submissionID uuid.UUID) (*model
 		if err != nil {
 			return nil, err
 		}
+		for _, id := range selectedOptions {
+			if optionID, err := uuid.Parse(id); err == nil {
+				submissionAnswer.SelectedOptionIDs = append(submissionAnswer.SelectedOptionIDs, optionID)
+			}
+		}
+		if len(answerMatches) > 0 {
+			if err := json.Unmarshal(answerMatches, &submissionAnswer.Matches); err != nil {
+				log.Printf("[Exercise-Repo] Invalid answer_matches for answer %s: %v", submissionAnswer.ID, err)
+			}
+		}
+		if len(gradingDetails) > 0 {
+			if err := json.Unmarshal(gradingDetails, &submissionAnswer.GradingDetails); err != nil {
+				log.Printf("[Exercise-Repo] Invalid grading_details for answer %s: %v", submissionAnswer.ID, err)
+			}
+		}
 
 		// Get correct answer
 		var correctAnswer interface{}
@@ -1484,6 +1697,7 @@ func (r *ExerciseRepository) CreateQuestionAnswer(questionID uuid.UUID, req *mod
 	answer := &models.QuestionAnswer{
 		ID:                 uuid.New(),
 		QuestionID:         questionID,
+		Label:              req.Label,
 		AnswerText:         req.AnswerText,
 		AlternativeAnswers: alternativeAnswersJSON,
 		IsCaseSensitive:    req.IsCaseSensitive,
@@ -1499,10 +1713,10 @@ func (r *ExerciseRepository) CreateQuestionAnswer(questionID uuid.UUID, req *mod
 
 	_, err := r.db.Exec(`
 		INSERT INTO question_answers (
-			id, question_id, answer_text, answer_variations,
+			id, question_id, match_left, answer_text, answer_variations,
 			is_primary_answer, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6)
-	`, answer.ID, answer.QuestionID, answer.AnswerText, answerVariations,
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, answer.ID, answer.QuestionID, answer.Label, answer.AnswerText, answerVariations,
 		true, answer.CreatedAt)
 
 	return answer, err
@@ -1801,10 +2015,10 @@ func (r *ExerciseRepository) GetExerciseAnalytics(exerciseID uuid.UUID) (*models
 func (r *ExerciseRepository) GetSubmissionByID(submissionID uuid.UUID) (*models.UserExerciseAttempt, error) {
 	query := `
 		SELECT id, user_id, exercise_id, attempt_number, status, total_questions, questions_answered,
-			correct_answers, score, band_score, time_limit_minutes, time_spent_seconds,
-			started_at, completed_at, device_type,
+			correct_answers, raw_score, score, band_score, time_limit_minutes, time_spent_seconds,
+			started_at, completed_at, last_activity_at, COALESCE(auto_submitted, false), device_type,
 			essay_text, word_count, task_type, prompt_text,
-			audio_url, audio_duration_seconds, transcript_text, speaking_part_number,
+			audio_url, audio_duration_seconds, transcript_text, speech_metrics, speaking_part_number,
 			evaluation_status, ai_evaluation_id, detailed_scores, ai_feedback,
 			official_test_result_id, practice_activity_id,
 			created_at, updated_at
@@ -1815,10 +2029,10 @@ func (r *ExerciseRepository) GetSubmissionByID(submissionID uuid.UUID) (*models.
 	var s models.UserExerciseAttempt
 	err := r.db.QueryRow(query, submissionID).Scan(
 		&s.ID, &s.UserID, &s.ExerciseID, &s.AttemptNumber, &s.Status, &s.TotalQuestions, &s.QuestionsAnswered,
-		&s.CorrectAnswers, &s.Score, &s.BandScore, &s.TimeLimitMinutes, &s.TimeSpentSeconds,
-		&s.StartedAt, &s.CompletedAt, &s.DeviceType,
+		&s.CorrectAnswers, &s.RawScore, &s.Score, &s.BandScore, &s.TimeLimitMinutes, &s.TimeSpentSeconds,
+		&s.StartedAt, &s.CompletedAt, &s.LastActivityAt, &s.AutoSubmitted, &s.DeviceType,
 		&s.EssayText, &s.WordCount, &s.TaskType, &s.PromptText,
-		&s.AudioURL, &s.AudioDurationSeconds, &s.TranscriptText, &s.SpeakingPartNumber,
+		&s.AudioURL, &s.AudioDurationSeconds, &s.TranscriptText, &s.SpeechMetrics, &s.SpeakingPartNumber,
 		&s.EvaluationStatus, &s.AIEvaluationID, &s.DetailedScores, &s.AIFeedback,
 		&s.OfficialTestResultID, &s.PracticeActivityID,
 		&s.CreatedAt, &s.UpdatedAt,
@@ -1830,6 +2044,118 @@ func (r *ExerciseRepository) GetSubmissionByID(submissionID uuid.UUID) (*models.
 	return &s, nil
 }
 
+// TouchSubmissionActivity records activity on an in-progress attempt (autosave)
+func (r *ExerciseRepository) TouchSubmissionActivity(submissionID uuid.UUID) error {
+	now := time.Now()
+	_, err := r.db.Exec(`
+		UPDATE user_exercise_attempts
+		SET last_activity_at = $1, updated_at = $1
+		WHERE id = $2 AND status = 'in_progress'
+	`, now, submissionID)
+	return err
+}
+
+// CountSubmissionAnswers returns how many answers are saved for an attempt
+func (r *ExerciseRepository) CountSubmissionAnswers(submissionID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM user_answers WHERE attempt_id = $1
+	`, submissionID).Scan(&count)
+	return count, err
+}
+
+// GetExpiredAttemptIDs returns in-progress timed attempts whose deadline passed before cutoff.
+// Attempts whose auto-submit was claimed at or after claimCutoff are being handled and are skipped.
+func (r *ExerciseRepository) GetExpiredAttemptIDs(cutoff, claimCutoff time.Time, limit int) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(`
+		SELECT id
+		FROM user_exercise_attempts
+		WHERE status = 'in_progress'
+		  AND time_limit_minutes > 0
+		  AND started_at + time_limit_minutes * INTERVAL '1 minute' < $1
+		  AND (NOT COALESCE(auto_submitted, false) OR auto_submit_claimed_at IS NULL OR auto_submit_claimed_at < $2)
+		ORDER BY started_at
+		LIMIT $3
+	`, cutoff, claimCutoff, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ClaimAutoSubmit flags an in-progress attempt as submitted by the server when time ran out.
+// Returns false if another sweeper (or the user) got there first. A claim made before
+// claimCutoff is stale (its sweeper died before submitting) and is taken over.
+func (r *ExerciseRepository) ClaimAutoSubmit(submissionID uuid.UUID, claimCutoff time.Time) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE user_exercise_attempts
+		SET auto_submitted = true, auto_submit_claimed_at = $1, updated_at = $1
+		WHERE id = $2 AND status = 'in_progress'
+		  AND (NOT COALESCE(auto_submitted, false) OR auto_submit_claimed_at IS NULL OR auto_submit_claimed_at < $3)
+	`, time.Now(), submissionID, claimCutoff)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ReleaseAutoSubmit undoes ClaimAutoSubmit after a failed auto-submit so the next sweep retries it
+func (r *ExerciseRepository) ReleaseAutoSubmit(submissionID uuid.UUID) error {
+	_, err := r.db.Exec(`
+		UPDATE user_exercise_attempts
+		SET auto_submitted = false, auto_submit_claimed_at = NULL, updated_at = $1
+		WHERE id = $2 AND status = 'in_progress'
+	`, time.Now(), submissionID)
+	return err
+}
+
+// AbandonSubmission marks an in-progress attempt abandoned. Returns false if it was no longer in progress.
+func (r *ExerciseRepository) AbandonSubmission(submissionID uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE user_exercise_attempts
+		SET status = 'abandoned', updated_at = $1
+		WHERE id = $2 AND status = 'in_progress'
+	`, time.Now(), submissionID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// AbandonIdleAttempts marks untimed in-progress attempts with no activity since idleBefore abandoned.
+// Timed attempts are left to the expiry sweep, which submits what was saved.
+func (r *ExerciseRepository) AbandonIdleAttempts(idleBefore time.Time, limit int) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE user_exercise_attempts
+		SET status = 'abandoned', updated_at = $1
+		WHERE id IN (
+			SELECT id
+			FROM user_exercise_attempts
+			WHERE status = 'in_progress'
+			  AND (time_limit_minutes IS NULL OR time_limit_minutes <= 0)
+			  AND COALESCE(last_activity_at, started_at) < $2
+			LIMIT $3
+		)
+		AND status = 'in_progress'
+	`, time.Now(), idleBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // GetExerciseByIDSimple retrieves basic exercise info by ID
 func (r *ExerciseRepository) GetExerciseByIDSimple(exerciseID uuid.UUID) (*models.Exercise, error) {
 	query := `
@@ -1922,7 +2248,7 @@ func (r *ExerciseRepository) MarkUserServiceSyncNotRequired(submissionID uuid.UU
 // FIX #8: Support background retry for failed/pending syncs
 func (r *ExerciseRepository) GetPendingSyncs(limit int) ([]*models.UserExerciseAttempt, error) {
 	query := `
-		SELECT id, user_id, exercise_id, status, correct_answers, total_questions,
+		SELECT id, user_id, exercise_id, status, correct_answers, raw_score, total_questions,
 		       band_score, user_service_sync_status, user_service_sync_attempts,
 		       user_service_last_sync_attempt, user_service_sync_error
 		FROM user_exercise_attempts
@@ -1943,7 +2269,7 @@ func (r *ExerciseRepository) GetPendingSyncs(limit int) ([]*models.UserExerciseA
 	for rows.Next() {
 		sub := &models.UserExerciseAttempt{}
 		err := rows.Scan(
-			&sub.ID, &sub.UserID, &sub.ExerciseID, &sub.Status, &sub.CorrectAnswers, &sub.TotalQuestions,
+			&sub.ID, &sub.UserID, &sub.ExerciseID, &sub.Status, &sub.CorrectAnswers, &sub.RawScore, &sub.TotalQuestions,
 			&sub.BandScore, &sub.UserServiceSyncStatus, &sub.UserServiceSyncAttempts,
 			&sub.UserServiceLastSyncAttempt, &sub.UserServiceSyncError,
 		)
@@ -2012,32 +2338,25 @@ func (r *ExerciseRepository) MarkSubmissionAsSubmittedWithTime(submissionID uuid
 
 	// Calculate time spent using same logic as CompleteSubmissionWithTime
 	completedAt := time.Now()
+	elapsedSeconds := elapsedAttemptSeconds(startedAt, completedAt, timeLimitMinutes)
 	var timeSpent int
 	
-	if frontendTimeSpent != nil && *frontendTimeSpent > 0 {
+	if frontendTimeSpent != nil && *frontendTimeSpent > 0 && *frontendTimeSpent <= elapsedSeconds {
 		// PRIMARY: Use frontend-tracked time
 		timeSpent = *frontendTimeSpent
 		log.Printf("[Exercise-Repo] ✅ W/S submission - using frontend time: %d seconds", timeSpent)
 	} else {
-		// FALLBACK: Calculate elapsed time
-		timeSpent = int(completedAt.Sub(startedAt).Seconds())
+		// FALLBACK: Elapsed time, capped at the time limit
+		timeSpent = elapsedSeconds
 		log.Printf("[Exercise-Repo] ⚠️  W/S submission - using elapsed time: %d seconds", timeSpent)
 	}
 
-	// VALIDATION: Cap at time limit
-	if timeLimitMinutes != nil && *timeLimitMinutes > 0 {
-		maxSeconds := *timeLimitMinutes * 60
-		if timeSpent > maxSeconds {
-			log.Printf("[Exercise-Repo] ⚠️  Capping W/S time from %d to %d seconds", timeSpent, maxSeconds)
-			timeSpent = maxSeconds
-		}
-	}
-
 	query := `
 		UPDATE user_exercise_attempts
 		SET completed_at = COALESCE(completed_at, $1),
 		    time_spent_seconds = $2,
 		    status = CASE WHEN status = 'in_progress' THEN 'submitted' ELSE status END,
+		    last_activity_at = $3,
 		    updated_at = $3
 		WHERE id = $4
 	`
@@ -2050,38 +2369,25 @@ func (r *ExerciseRepository) MarkSubmissionAsSubmittedWithTime(submissionID uuid
 	return nil
 }
 
-// UpdateSubmissionTranscript updates the transcript text
-func (r *ExerciseRepository) UpdateSubmissionTranscript(submissionID uuid.UUID, transcript string) error {
+// UpdateSubmissionTranscript updates the transcript text and the speech metrics measured
+// with it, so an evaluation retry that reuses the transcript still has them
+func (r *ExerciseRepository) UpdateSubmissionTranscript(submissionID uuid.UUID, transcript string, speechMetrics json.RawMessage) error {
+	var metrics *string
+	if len(speechMetrics) > 0 {
+		s := string(speechMetrics)
+		metrics = &s
+	}
+
 	query := `
 		UPDATE user_exercise_attempts
-		SET transcript_text = $1, updated_at = NOW()
-		WHERE id = $2
+		SET transcript_text = $1, speech_metrics = $2, updated_at = NOW()
+		WHERE id = $3
 	`
-	_, err := r.db.Exec(query, transcript, submissionID)
+	_, err := r.db.Exec(query, transcript, metrics, submissionID)
 	return err
 }
 
 // UpdateSubmissionWithAIResult updates submission with AI evaluation results
 func (r *ExerciseRepository) UpdateSubmissionWithAIResult(submissionID uuid.UUID, result *models.AIEvaluationResult) error {
-	detailedScoresJSON, err := json.Marshal(result.DetailedScores)
-	if err != nil {
-		return fmt.Errorf("failed to marshal detailed_scores: %w", err)
-	}
-	detailedScoresStr := string(detailedScoresJSON)
-
-	// FIX: Only set completed_at if it's not already set (to avoid violating check_attempt_sync_after_completed constraint)
-	// For Writing/Speaking, completed_at should be set when user submits, not when AI evaluation completes
-	query := `
-		UPDATE user_exercise_attempts
-		SET band_score = $1,
-		    detailed_scores = $2,
-		    ai_feedback = $3,
-		    evaluation_status = 'completed',
-		    status = 'completed',
-		    completed_at = COALESCE(completed_at, NOW()),
-		    updated_at = NOW()
-		WHERE id = $4
-	`
-	_, err = r.db.Exec(query, result.OverallBandScore, detailedScoresStr, result.Feedback, submissionID)
-	return err
+	return updateSubmissionWithAIResult(r.db, submissionID, result)
 }
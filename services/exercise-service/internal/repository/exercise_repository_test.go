@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/utils"
+	"github.com/google/uuid"
+)
+
+func TestElapsedAttemptSeconds(t *testing.T) {
+	start := time.Date(2026, time.March, 10, 9, 0, 0, 0, time.UTC)
+	limit := func(minutes int) *int { return &minutes }
+
+	tests := []struct {
+		name  string
+		now   time.Time
+		limit *int
+		want  int
+	}{
+		{"untimed", start.Add(90 * time.Minute), nil, 90 * 60},
+		{"zero limit is untimed", start.Add(90 * time.Minute), limit(0), 90 * 60},
+		{"within the limit", start.Add(40*time.Minute + 500*time.Millisecond), limit(60), 40 * 60},
+		{"submitted late is capped at the limit", start.Add(3 * time.Hour), limit(60), 60 * 60},
+		{"clock skew", start.Add(-time.Minute), limit(60), 0},
+	}
+	for _, tt := range tests {
+		if got := elapsedAttemptSeconds(start, tt.now, tt.limit); got != tt.want {
+			t.Errorf("%s: elapsed = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWholeMarks(t *testing.T) {
+	tests := []struct {
+		raw  float64
+		want int
+	}{
+		{0, 0},
+		{12, 12},
+		{12.5, 12},
+		{1.0/3 + 2.0/3, 1},
+		{0.1 + 0.2 + 0.7, 1},
+		{29.99, 29},
+	}
+	for _, tt := range tests {
+		if got := wholeMarks(tt.raw); got != tt.want {
+			t.Errorf("wholeMarks(%v) = %d, want %d", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestPartialCreditChangesBand(t *testing.T) {
+	// 29 fully correct questions and two multi-part answers with half their parts right
+	rawScore := 29 + 0.5 + 0.5
+	if got := utils.ConvertRawScoreToBandScore("listening", 29, 40); got != 6.5 {
+		t.Fatalf("band from fully correct questions = %.1f, want 6.5", got)
+	}
+	if got := utils.ConvertRawScoreToBandScore("listening", wholeMarks(rawScore), 40); got != 7.0 {
+		t.Errorf("band from marks with partial credit = %.1f, want 7.0", got)
+	}
+}
+
+func TestCompleteSubmissionOnlyOnce(t *testing.T) {
+	repo, db := newDBTestRepository(t)
+
+	var exerciseID, attemptID uuid.UUID
+	err := db.QueryRow(`
+		INSERT INTO exercises (title, slug, exercise_type, skill_type, difficulty, created_by)
+		VALUES ('Listening', $1, 'practice', 'listening', 'medium', $2)
+		RETURNING id
+	`, "listening-"+uuid.NewString(), uuid.New()).Scan(&exerciseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.QueryRow(`
+		INSERT INTO user_exercise_attempts (user_id, exercise_id, total_questions, status)
+		VALUES ($1, $2, 40, 'in_progress')
+		RETURNING id
+	`, uuid.New(), exerciseID).Scan(&attemptID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CompleteSubmission(attemptID); err != nil {
+		t.Fatal(err)
+	}
+	// The student's submit and the expiry sweeper may both complete the attempt; only the
+	// first may record the result
+	if err := repo.CompleteSubmission(attemptID); !errors.Is(err, ErrAttemptAlreadyCompleted) {
+		t.Errorf("second completion = %v, want ErrAttemptAlreadyCompleted", err)
+	}
+}
@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// A 40-question Listening test: 4 sections of 10 multiple choice questions with 4 options each
+const (
+	fixtureSections         = 4
+	fixtureQuestionsPerPart = 10
+	fixtureOptions          = 4
+
+	// Simulated database round trip
+	fixtureLatency = 200 * time.Microsecond
+)
+
+var fixtureExerciseID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+func TestGetSectionsWithQuestionsMatchesPerSectionLoad(t *testing.T) {
+	repo, queries := newFixtureRepository(t, 0)
+
+	batched, err := repo.GetSectionsWithQuestions(fixtureExerciseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := queries.Load(); got != 3 {
+		t.Errorf("batched load ran %d queries, want 3", got)
+	}
+
+	perSection, err := loadSectionsPerSection(repo, fixtureExerciseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(batched, perSection) {
+		t.Error("batched tree differs from the per-section tree")
+	}
+
+	if len(batched) != fixtureSections || len(batched[0].Questions) != fixtureQuestionsPerPart || len(batched[0].Questions[0].Options) != fixtureOptions {
+		t.Errorf("unexpected tree shape: %d sections", len(batched))
+	}
+}
+
+func BenchmarkExerciseTree(b *testing.B) {
+	loaders := []struct {
+		name string
+		load func(*ExerciseRepository, uuid.UUID) ([]models.SectionWithQuestions, error)
+	}{
+		{"per_section", loadSectionsPerSection},
+		{"batched", (*ExerciseRepository).GetSectionsWithQuestions},
+	}
+
+	for _, loader := range loaders {
+		b.Run(loader.name, func(b *testing.B) {
+			repo, queries := newFixtureRepository(b, fixtureLatency)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := loader.load(repo, fixtureExerciseID); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(queries.Load())/float64(b.N), "queries/op")
+		})
+	}
+}
+
+// loadSectionsPerSection is the previous loader (one query per section and per choice question),
+// kept as the baseline for the benchmark
+func loadSectionsPerSection(r *ExerciseRepository, exerciseID uuid.UUID) ([]models.SectionWithQuestions, error) {
+	sections, err := r.getSections(exerciseID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.SectionWithQuestions{}
+	for i := range sections {
+		rows, err := r.db.Query(`SELECT id FROM questions WHERE section_id = $1`, sections[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		questions := []models.QuestionWithOptions{}
+		for rows.Next() {
+			var q models.Question
+			if err := rows.Scan(
+				&q.ID, &q.ExerciseID, &q.SectionID, &q.QuestionNumber, &q.QuestionText, &q.QuestionType,
+				&q.AudioURL, &q.ImageURL, &q.ContextText, &q.Points, &q.Difficulty, &q.Explanation,
+				&q.Tips, &q.DisplayOrder, &q.CreatedAt, &q.UpdatedAt,
+			); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			var options []models.QuestionOption
+			if q.QuestionType == "multiple_choice" || q.QuestionType == "matching" {
+				optionRows, err := r.db.Query(`SELECT id FROM question_options WHERE question_id = $1`, q.ID)
+				if err != nil {
+					rows.Close()
+					return nil, err
+				}
+				for optionRows.Next() {
+					var o models.QuestionOption
+					if err := optionRows.Scan(
+						&o.ID, &o.QuestionID, &o.OptionLabel, &o.OptionText, &o.OptionImageURL,
+						&o.IsCorrect, &o.DisplayOrder, &o.CreatedAt,
+					); err != nil {
+						optionRows.Close()
+						rows.Close()
+						return nil, err
+					}
+					options = append(options, o)
+				}
+				optionRows.Close()
+			}
+
+			question := q
+			questions = append(questions, models.QuestionWithOptions{Question: &question, Options: options})
+		}
+		rows.Close()
+
+		result = append(result, models.SectionWithQuestions{Section: &sections[i], Questions: questions})
+	}
+	return result, nil
+}
+
+// ---- Fixture database ----
+
+var registerFixtureDriver sync.Once
+
+type fixtureDB struct {
+	latency  time.Duration
+	queries  *atomic.Int64
+	sections [][]driver.Value
+	qs       [][]driver.Value // questions
+	options  [][]driver.Value
+}
+
+var fixtureDBs sync.Map // DSN -> *fixtureDB
+
+func newFixtureRepository(tb testing.TB, latency time.Duration) (*ExerciseRepository, *atomic.Int64) {
+	registerFixtureDriver.Do(func() { sql.Register("exercise-fixture", fixtureDriver{}) })
+
+	fixture := buildFixture(latency)
+	dsn := tb.Name()
+	fixtureDBs.Store(dsn, fixture)
+	tb.Cleanup(func() { fixtureDBs.Delete(dsn) })
+
+	db, err := sql.Open("exercise-fixture", dsn)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	return NewExerciseRepository(db), fixture.queries
+}
+
+func buildFixture(latency time.Duration) *fixtureDB {
+	f := &fixtureDB{latency: latency, queries: &atomic.Int64{}}
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	number := 0
+	for s := 0; s < fixtureSections; s++ {
+		sectionID := uuid.New().String()
+		f.sections = append(f.sections, []driver.Value{
+			sectionID, fixtureExerciseID.String(), fmt.Sprintf("Part %d", s+1), nil, int64(s + 1), "https://cdn/audio.mp3",
+			nil, nil, "transcript", nil,
+			nil, nil, "Choose the correct letter", int64(fixtureQuestionsPerPart),
+			nil, int64(s), created, created,
+		})
+
+		for q := 0; q < fixtureQuestionsPerPart; q++ {
+			number++
+			questionID := uuid.New().String()
+			f.qs = append(f.qs, []driver.Value{
+				questionID, fixtureExerciseID.String(), sectionID, int64(number), fmt.Sprintf("Question %d", number),
+				"multiple_choice", nil, nil, nil, float64(1),
+				nil, "explanation", nil, int64(number), created, created,
+			})
+
+			for o := 0; o < fixtureOptions; o++ {
+				f.options = append(f.options, []driver.Value{
+					uuid.New().String(), questionID, string(rune('A' + o)), fmt.Sprintf("Option %d", o),
+					nil, o == 0, int64(o), created,
+				})
+			}
+		}
+	}
+	return f
+}
+
+// query returns the fixture rows for the loaders' queries, filtered by the first argument
+func (f *fixtureDB) query(query string, args []driver.Value) [][]driver.Value {
+	f.queries.Add(1)
+	if f.latency > 0 {
+		time.Sleep(f.latency)
+	}
+
+	switch {
+	case strings.Contains(query, "FROM exercise_sections"):
+		return f.sections
+	case strings.Contains(query, "FROM questions") && strings.Contains(query, "section_id = $1"):
+		return filterRows(f.qs, 2, args[0])
+	case strings.Contains(query, "FROM questions"):
+		return f.qs
+	case strings.Contains(query, "FROM question_options") && strings.Contains(query, "question_id = $1"):
+		return filterRows(f.options, 1, args[0])
+	case strings.Contains(query, "FROM question_options"):
+		return f.options
+	}
+	return nil
+}
+
+func filterRows(rows [][]driver.Value, column int, value driver.Value) [][]driver.Value {
+	want := fmt.Sprint(value)
+	var result [][]driver.Value
+	for _, row := range rows {
+		if fmt.Sprint(row[column]) == want {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+type fixtureDriver struct{}
+
+func (fixtureDriver) Open(dsn string) (driver.Conn, error) {
+	f, ok := fixtureDBs.Load(dsn)
+	if !ok {
+		return nil, fmt.Errorf("unknown fixture %q", dsn)
+	}
+	return &fixtureConn{db: f.(*fixtureDB)}, nil
+}
+
+type fixtureConn struct{ db *fixtureDB }
+
+func (c *fixtureConn) Prepare(query string) (driver.Stmt, error) {
+	return &fixtureStmt{db: c.db, query: query}, nil
+}
+func (c *fixtureConn) Close() error              { return nil }
+func (c *fixtureConn) Begin() (driver.Tx, error) { return nil, fmt.Errorf("not supported") }
+
+type fixtureStmt struct {
+	db    *fixtureDB
+	query string
+}
+
+func (s *fixtureStmt) Close() error  { return nil }
+func (s *fixtureStmt) NumInput() int { return -1 }
+func (s *fixtureStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("not supported")
+}
+func (s *fixtureStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows := s.db.query(s.query, args)
+	width := 0
+	if len(rows) > 0 {
+		width = len(rows[0])
+	}
+	return &fixtureRows{rows: rows, width: width}, nil
+}
+
+type fixtureRows struct {
+	rows  [][]driver.Value
+	width int
+}
+
+func (r *fixtureRows) Columns() []string { return make([]string, r.width) }
+func (r *fixtureRows) Close() error      { return nil }
+func (r *fixtureRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
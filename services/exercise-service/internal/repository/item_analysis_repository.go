@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Each student's first completed attempt at the exercise; retakes would reward memorised keys
+const firstCompletedAttempts = `
+	SELECT DISTINCT ON (user_id) id
+	FROM user_exercise_attempts
+	WHERE exercise_id = $1 AND status = 'completed'
+	ORDER BY user_id, started_at`
+
+// firstCompletedAttempts for every exercise at once
+const firstCompletedAttemptsPerExercise = `
+	SELECT DISTINCT ON (exercise_id, user_id) id, exercise_id
+	FROM user_exercise_attempts
+	WHERE status = 'completed'
+	ORDER BY exercise_id, user_id, started_at`
+
+// GetItemAnalysisResponses returns the attempts used for item analysis and their graded answers
+func (r *ExerciseRepository) GetItemAnalysisResponses(exerciseID uuid.UUID) ([]uuid.UUID, []models.ItemResponse, error) {
+	attemptIDs, err := r.queryIDs(firstCompletedAttempts, exerciseID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := r.db.Query(`
+		WITH first_attempts AS (`+firstCompletedAttempts+`)
+		SELECT ua.attempt_id, ua.question_id, COALESCE(ua.is_correct, false), COALESCE(ua.points_earned, 0),
+			ua.selected_option_id, COALESCE(ua.selected_options, '{}'), ua.time_spent_seconds
+		FROM user_answers ua
+		JOIN first_attempts fa ON fa.id = ua.attempt_id
+	`, exerciseID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var responses []models.ItemResponse
+	for rows.Next() {
+		var response models.ItemResponse
+		var selectedOptions pq.StringArray
+		err := rows.Scan(
+			&response.AttemptID, &response.QuestionID, &response.IsCorrect, &response.PointsEarned,
+			&response.SelectedOptionID, &selectedOptions, &response.TimeSpentSeconds,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, id := range selectedOptions {
+			if optionID, err := uuid.Parse(id); err == nil {
+				response.SelectedOptions = append(response.SelectedOptions, optionID)
+			}
+		}
+		responses = append(responses, response)
+	}
+	return attemptIDs, responses, rows.Err()
+}
+
+// GetQuestionBankLinks maps the exercise's questions to the bank questions they came from
+func (r *ExerciseRepository) GetQuestionBankLinks(exerciseID uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
+	rows, err := r.db.Query(`
+		SELECT id, bank_question_id FROM questions
+		WHERE exercise_id = $1 AND bank_question_id IS NOT NULL
+	`, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make(map[uuid.UUID]uuid.UUID)
+	for rows.Next() {
+		var questionID, bankID uuid.UUID
+		if err := rows.Scan(&questionID, &bankID); err != nil {
+			return nil, err
+		}
+		links[questionID] = bankID
+	}
+	return links, rows.Err()
+}
+
+// RefreshBankDifficulty sets each bank question's difficulty from its facility across every
+// exercise it was used in, once it has at least minResponses responses. Facility is scored as
+// in GetItemAnalysis: each student's first completed attempt only, with an omitted question
+// counting as zero. Returns how many bank questions changed.
+func (r *ExerciseRepository) RefreshBankDifficulty(minResponses int, easyFrom, hardBelow float64) (int64, error) {
+	result, err := r.db.Exec(`
+		WITH first_attempts AS (`+firstCompletedAttemptsPerExercise+`
+		), facility AS (
+			SELECT q.bank_question_id AS id,
+				AVG(CASE
+					WHEN ua.id IS NULL THEN 0
+					WHEN q.points > 0 THEN GREATEST(LEAST(COALESCE(ua.points_earned, 0) / q.points, 1), 0)
+					WHEN ua.is_correct THEN 1 ELSE 0
+				END) AS p
+			FROM first_attempts fa
+			JOIN questions q ON q.exercise_id = fa.exercise_id AND q.bank_question_id IS NOT NULL
+			LEFT JOIN user_answers ua ON ua.attempt_id = fa.id AND ua.question_id = q.id
+			GROUP BY q.bank_question_id
+			HAVING COUNT(*) >= $1
+		), suggested AS (
+			SELECT id, CASE WHEN p >= $2 THEN 'easy' WHEN p < $3 THEN 'hard' ELSE 'medium' END AS difficulty
+			FROM facility
+		)
+		UPDATE question_bank b
+		SET difficulty = s.difficulty, updated_at = CURRENT_TIMESTAMP
+		FROM suggested s
+		WHERE b.id = s.id AND b.difficulty IS DISTINCT FROM s.difficulty
+	`, minResponses, easyFrom, hardBelow)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
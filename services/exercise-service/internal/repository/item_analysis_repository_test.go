@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/exercisepkg"
+	"github.com/google/uuid"
+)
+
+// These tests use the PostgreSQL database from EXERCISE_TEST_DATABASE_URL (see
+// evaluation_job_repository_test.go) and are skipped without it.
+
+func insertBankQuestion(t *testing.T, db *sql.DB) uuid.UUID {
+	t.Helper()
+	var id uuid.UUID
+	err := db.QueryRow(`
+		INSERT INTO question_bank (skill_type, question_type, question_text)
+		VALUES ('reading', 'short_answer', 'What is kept in the library?')
+		RETURNING id
+	`).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func bankDifficulty(t *testing.T, db *sql.DB, id uuid.UUID) *string {
+	t.Helper()
+	var difficulty *string
+	if err := db.QueryRow(`SELECT difficulty FROM question_bank WHERE id = $1`, id).Scan(&difficulty); err != nil {
+		t.Fatal(err)
+	}
+	return difficulty
+}
+
+func TestRefreshBankDifficulty(t *testing.T) {
+	repo, db := newDBTestRepository(t)
+	bankQ1, bankQ2 := insertBankQuestion(t, db), insertBankQuestion(t, db)
+
+	var exerciseID uuid.UUID
+	err := db.QueryRow(`
+		INSERT INTO exercises (title, slug, exercise_type, skill_type, difficulty, created_by)
+		VALUES ('Reading', $1, 'practice', 'reading', 'medium', $2)
+		RETURNING id
+	`, "reading-"+uuid.NewString(), uuid.New()).Scan(&exerciseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	questionIDs := make(map[uuid.UUID]uuid.UUID) // bank question -> exercise question
+	for number, bankID := range []uuid.UUID{bankQ1, bankQ2} {
+		var id uuid.UUID
+		err := db.QueryRow(`
+			INSERT INTO questions (exercise_id, question_number, question_text, question_type, points, bank_question_id)
+			VALUES ($1, $2, 'Question', 'short_answer', 1, $3)
+			RETURNING id
+		`, exerciseID, number+1, bankID).Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		questionIDs[bankID] = id
+	}
+
+	started := time.Now().Add(-24 * time.Hour)
+	attempt := func(userID uuid.UUID, answers map[uuid.UUID]bool) {
+		t.Helper()
+		started = started.Add(time.Minute)
+		var attemptID uuid.UUID
+		err := db.QueryRow(`
+			INSERT INTO user_exercise_attempts (user_id, exercise_id, total_questions, status, started_at)
+			VALUES ($1, $2, 2, 'completed', $3)
+			RETURNING id
+		`, userID, exerciseID, started).Scan(&attemptID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for bankID, correct := range answers {
+			points := 0
+			if correct {
+				points = 1
+			}
+			_, err := db.Exec(`
+				INSERT INTO user_answers (attempt_id, question_id, user_id, answer_text, is_correct, points_earned)
+				VALUES ($1, $2, $3, 'answer', $4, $5)
+			`, attemptID, questionIDs[bankID], userID, correct, points)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// First attempts: Q1 right once in four (hard); Q2 right twice and omitted twice (medium)
+	students := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	attempt(students[0], map[uuid.UUID]bool{bankQ1: false, bankQ2: true})
+	attempt(students[1], map[uuid.UUID]bool{bankQ1: false, bankQ2: true})
+	attempt(students[2], map[uuid.UUID]bool{bankQ1: false})
+	attempt(students[3], map[uuid.UUID]bool{bankQ1: true})
+	// Retakes do not count, or Q1 would look medium
+	attempt(students[0], map[uuid.UUID]bool{bankQ1: true, bankQ2: true})
+	attempt(students[1], map[uuid.UUID]bool{bankQ1: true, bankQ2: true})
+
+	changed, err := repo.RefreshBankDifficulty(4, 0.7, 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 2 {
+		t.Errorf("changed %d bank questions, want 2", changed)
+	}
+	for bankID, want := range map[uuid.UUID]string{bankQ1: "hard", bankQ2: "medium"} {
+		if got := bankDifficulty(t, db, bankID); got == nil || *got != want {
+			t.Errorf("bank question difficulty = %v, want %s", got, want)
+		}
+	}
+
+	// Omissions count as responses, but five are needed here
+	if changed, err := repo.RefreshBankDifficulty(5, 0.7, 0.3); err != nil || changed != 0 {
+		t.Errorf("RefreshBankDifficulty(5) = %d, %v; want 0", changed, err)
+	}
+}
+
+func TestSaveExercisePackageKeepsBankLinks(t *testing.T) {
+	repo, db := newDBTestRepository(t)
+	bankQ1, bankQ2 := insertBankQuestion(t, db), insertBankQuestion(t, db)
+
+	pkg := &exercisepkg.Package{
+		Exercise: exercisepkg.Exercise{
+			Title: "From the bank", Slug: "from-the-bank-" + uuid.NewString(),
+			ExerciseType: "practice", SkillType: "reading", Difficulty: "medium",
+		},
+		Sections: []exercisepkg.Section{{
+			SectionNumber: 1, Title: "Passage 1",
+			Questions: []exercisepkg.Question{
+				{QuestionNumber: 1, QuestionText: "What is kept in the library?", QuestionType: "short_answer", Points: 1},
+				{QuestionNumber: 2, QuestionText: "Where is the cafe?", QuestionType: "short_answer", Points: 1},
+			},
+		}},
+	}
+	exerciseID, err := repo.CreateExerciseFromBank(pkg, []uuid.UUID{bankQ1, bankQ2}, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Edit the text of question 1 and replace question 2 with a question of another type
+	pkg.Sections[0].Questions[0].QuestionText = "What is stored in the library?"
+	pkg.Sections[0].Questions[1] = exercisepkg.Question{
+		QuestionNumber: 2, QuestionText: "The cafe is open late.", QuestionType: "true_false_not_given", Points: 1,
+	}
+	if _, err := repo.SaveExercisePackage(pkg, &exerciseID, uuid.New(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := repo.GetQuestionBankLinks(exerciseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 1 {
+		t.Fatalf("%d questions linked to the bank, want 1", len(links))
+	}
+	for _, bankID := range links {
+		if bankID != bankQ1 {
+			t.Errorf("question linked to %s, want %s", bankID, bankQ1)
+		}
+	}
+}
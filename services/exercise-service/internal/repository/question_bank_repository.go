@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/exercisepkg"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const bankQuestionColumns = `
+	id, title, skill_type, question_type, difficulty, topic,
+	question_text, context_text, audio_url, image_url, answer_data,
+	tags, times_used, created_by, is_verified, is_published,
+	created_at, updated_at`
+
+// GetBankQuestionsByIDs returns the given bank questions; missing IDs are left out
+func (r *ExerciseRepository) GetBankQuestionsByIDs(ids []uuid.UUID) ([]models.QuestionBank, error) {
+	rows, err := r.db.Query(`SELECT`+bankQuestionColumns+` FROM question_bank WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	return scanBankQuestions(rows)
+}
+
+// DrawBankQuestions picks up to limit published bank questions matching the criteria,
+// least used first and at random among equally used ones
+func (r *ExerciseRepository) DrawBankQuestions(skillType string, criteria *models.BankQuestionCriteria, exclude []uuid.UUID, limit int) ([]models.QuestionBank, error) {
+	where := []string{"is_published = true", "skill_type = $1", "question_type = $2", "NOT (id = ANY($3))"}
+	args := []interface{}{skillType, criteria.QuestionType, pq.Array(exclude)}
+
+	if criteria.Difficulty != nil {
+		args = append(args, *criteria.Difficulty)
+		where = append(where, fmt.Sprintf("difficulty = $%d", len(args)))
+	}
+	if criteria.Topic != nil {
+		args = append(args, *criteria.Topic)
+		where = append(where, fmt.Sprintf("LOWER(topic) = LOWER($%d)", len(args)))
+	}
+	if len(criteria.Tags) > 0 {
+		args = append(args, pq.Array(criteria.Tags))
+		where = append(where, fmt.Sprintf("tags @> $%d", len(args)))
+	}
+	if criteria.VerifiedOnly {
+		where = append(where, "is_verified = true")
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.Query(fmt.Sprintf(`
+		SELECT%s
+		FROM question_bank
+		WHERE %s
+		ORDER BY times_used ASC, random()
+		LIMIT $%d
+	`, bankQuestionColumns, strings.Join(where, " AND "), len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanBankQuestions(rows)
+}
+
+func scanBankQuestions(rows *sql.Rows) ([]models.QuestionBank, error) {
+	defer rows.Close()
+
+	var questions []models.QuestionBank
+	for rows.Next() {
+		var q models.QuestionBank
+		var answerDataJSON []byte
+		var tagsArray pq.StringArray
+		err := rows.Scan(
+			&q.ID, &q.Title, &q.SkillType, &q.QuestionType, &q.Difficulty,
+			&q.Topic, &q.QuestionText, &q.ContextText, &q.AudioURL, &q.ImageURL,
+			&answerDataJSON, &tagsArray, &q.TimesUsed, &q.CreatedBy,
+			&q.IsVerified, &q.IsPublished, &q.CreatedAt, &q.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		q.AnswerData = string(answerDataJSON)
+		q.Tags = []string(tagsArray)
+		questions = append(questions, q)
+	}
+	return questions, rows.Err()
+}
+
+// CreateExerciseFromBank saves an assembled draft exercise, links its questions to the
+// bank questions they came from (bankIDs[i] is question i+1) and counts a use of each,
+// in one transaction
+func (r *ExerciseRepository) CreateExerciseFromBank(pkg *exercisepkg.Package, bankIDs []uuid.UUID, createdBy uuid.UUID) (uuid.UUID, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	exerciseID, err := writeExercisePackage(tx, pkg, nil, createdBy)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// Questions are numbered in bank order, which links each one to its bank question
+	_, err = tx.Exec(`
+		UPDATE questions q
+		SET bank_question_id = b.id
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS b(id, number)
+		WHERE q.exercise_id = $1 AND q.question_number = b.number
+	`, exerciseID, pq.Array(bankIDs))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to link bank questions: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE question_bank
+		SET times_used = COALESCE(times_used, 0) + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($1)
+	`, pq.Array(bankIDs))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to update bank usage: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+	return exerciseID, nil
+}
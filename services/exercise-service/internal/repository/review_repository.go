@@ -0,0 +1,376 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrReviewItemAnswered is returned when a review session item already has an answer
+var ErrReviewItemAnswered = errors.New("review item already answered in this session")
+
+// CollectReviewItems adds the objective questions answered wrongly in a completed attempt to the student's
+// review items. Questions already there start their schedule over, unless the student archived
+// them; collecting the same attempt twice changes nothing. Returns how many items changed.
+func (r *ExerciseRepository) CollectReviewItems(attemptID uuid.UUID) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO review_items (user_id, question_id, exercise_id, source_attempt_id)
+		SELECT a.user_id, ua.question_id, a.exercise_id, a.id
+		FROM user_answers ua
+		JOIN user_exercise_attempts a ON a.id = ua.attempt_id
+		JOIN exercises e ON e.id = a.exercise_id
+		WHERE ua.attempt_id = $1 AND a.status = 'completed' AND ua.is_correct = false
+		  AND e.skill_type IN ('listening', 'reading')
+		ON CONFLICT (user_id, question_id) DO UPDATE SET
+			source_attempt_id = EXCLUDED.source_attempt_id,
+			status = 'active',
+			repetitions = 0,
+			interval_days = 0,
+			lapses = review_items.lapses + 1,
+			due_at = CURRENT_TIMESTAMP
+		WHERE review_items.status <> 'archived'
+		  AND review_items.source_attempt_id IS DISTINCT FROM EXCLUDED.source_attempt_id
+	`, attemptID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const reviewItemColumns = `
+	ri.id, ri.user_id, ri.question_id, ri.exercise_id, ri.source_attempt_id, ri.status,
+	ri.ease_factor, ri.interval_days, ri.repetitions, ri.lapses, ri.due_at,
+	ri.review_count, ri.last_reviewed_at, ri.last_result, ri.created_at,
+	e.title, e.skill_type, q.question_number, q.question_type, q.question_text`
+
+func scanReviewItem(row interface{ Scan(...interface{}) error }) (*models.ReviewItem, error) {
+	var item models.ReviewItem
+	err := row.Scan(
+		&item.ID, &item.UserID, &item.QuestionID, &item.ExerciseID, &item.SourceAttemptID, &item.Status,
+		&item.EaseFactor, &item.IntervalDays, &item.Repetitions, &item.Lapses, &item.DueAt,
+		&item.ReviewCount, &item.LastReviewedAt, &item.LastResult, &item.CreatedAt,
+		&item.ExerciseTitle, &item.SkillType, &item.QuestionNumber, &item.QuestionType, &item.QuestionText,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetReviewItem returns one review item
+func (r *ExerciseRepository) GetReviewItem(itemID uuid.UUID) (*models.ReviewItem, error) {
+	return scanReviewItem(r.db.QueryRow(`
+		SELECT `+reviewItemColumns+`
+		FROM review_items ri
+		JOIN questions q ON q.id = ri.question_id
+		JOIN exercises e ON e.id = ri.exercise_id
+		WHERE ri.id = $1
+	`, itemID))
+}
+
+// GetQuestionExplanation returns the explanation shown once a question has been answered
+func (r *ExerciseRepository) GetQuestionExplanation(questionID uuid.UUID) (*string, error) {
+	var explanation *string
+	err := r.db.QueryRow(`SELECT explanation FROM questions WHERE id = $1`, questionID).Scan(&explanation)
+	return explanation, err
+}
+
+// GetReviewItems returns a page of the student's review items with the given status,
+// soonest due first
+func (r *ExerciseRepository) GetReviewItems(userID uuid.UUID, status string, limit, offset int) ([]models.ReviewItem, int, error) {
+	var total int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM review_items WHERE user_id = $1 AND status = $2
+	`, userID, status).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT `+reviewItemColumns+`
+		FROM review_items ri
+		JOIN questions q ON q.id = ri.question_id
+		JOIN exercises e ON e.id = ri.exercise_id
+		WHERE ri.user_id = $1 AND ri.status = $2
+		ORDER BY ri.due_at, ri.created_at
+		LIMIT $3 OFFSET $4
+	`, userID, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	items := []models.ReviewItem{}
+	for rows.Next() {
+		item, err := scanReviewItem(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, *item)
+	}
+	return items, total, rows.Err()
+}
+
+// GetReviewSummary counts the student's review items
+func (r *ExerciseRepository) GetReviewSummary(userID uuid.UUID, now time.Time) (*models.ReviewSummary, error) {
+	var summary models.ReviewSummary
+	err := r.db.QueryRow(`
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'active' AND due_at <= $2),
+			COUNT(*) FILTER (WHERE status = 'active'),
+			COUNT(*) FILTER (WHERE status = 'mastered'),
+			MIN(due_at) FILTER (WHERE status = 'active' AND due_at > $2)
+		FROM review_items
+		WHERE user_id = $1
+	`, userID, now).Scan(&summary.Due, &summary.Active, &summary.Mastered, &summary.NextDueAt)
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// ArchiveReviewItem takes an item out of the student's reviews
+func (r *ExerciseRepository) ArchiveReviewItem(userID, itemID uuid.UUID) error {
+	result, err := r.db.Exec(`
+		UPDATE review_items SET status = 'archived' WHERE id = $1 AND user_id = $2
+	`, itemID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetOpenReviewSessionID returns the student's review session in progress, if any
+func (r *ExerciseRepository) GetOpenReviewSessionID(userID uuid.UUID) (uuid.UUID, bool, error) {
+	var id uuid.UUID
+	err := r.db.QueryRow(`
+		SELECT id FROM review_sessions
+		WHERE user_id = $1 AND status = 'in_progress'
+		ORDER BY started_at DESC
+		LIMIT 1
+	`, userID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	return id, true, nil
+}
+
+// CreateReviewSession starts a session with up to limit of the student's most overdue items
+// in shuffled order, so questions from one exercise or of one type do not come in a run.
+// Returns false when nothing is due.
+func (r *ExerciseRepository) CreateReviewSession(userID uuid.UUID, skillType *string, limit int, now time.Time) (uuid.UUID, bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT ri.id
+		FROM review_items ri
+		JOIN exercises e ON e.id = ri.exercise_id
+		WHERE ri.user_id = $1 AND ri.status = 'active' AND ri.due_at <= $2
+		  AND ($3::text IS NULL OR e.skill_type = $3)
+		ORDER BY ri.due_at
+		LIMIT $4
+	`, userID, now, skillType, limit)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	var itemIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return uuid.Nil, false, err
+		}
+		itemIDs = append(itemIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return uuid.Nil, false, err
+	}
+	if len(itemIDs) == 0 {
+		return uuid.Nil, false, nil
+	}
+
+	rand.Shuffle(len(itemIDs), func(i, j int) { itemIDs[i], itemIDs[j] = itemIDs[j], itemIDs[i] })
+
+	sessionID := uuid.New()
+	_, err = tx.Exec(`
+		INSERT INTO review_sessions (id, user_id, status, started_at, created_at)
+		VALUES ($1, $2, 'in_progress', $3, $3)
+	`, sessionID, userID, now)
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to create review session: %w", err)
+	}
+	for i, id := range itemIDs {
+		_, err := tx.Exec(`
+			INSERT INTO review_session_items (session_id, review_item_id, position)
+			VALUES ($1, $2, $3)
+		`, sessionID, id, i+1)
+		if err != nil {
+			return uuid.Nil, false, fmt.Errorf("failed to add review session item: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, false, err
+	}
+	return sessionID, true, nil
+}
+
+// GetReviewSession returns a session with its items in order
+func (r *ExerciseRepository) GetReviewSession(sessionID uuid.UUID) (*models.ReviewSession, error) {
+	var session models.ReviewSession
+	err := r.db.QueryRow(`
+		SELECT id, user_id, status, started_at, completed_at, COALESCE(user_service_sync_status, 'pending')
+		FROM review_sessions
+		WHERE id = $1
+	`, sessionID).Scan(&session.ID, &session.UserID, &session.Status, &session.StartedAt,
+		&session.CompletedAt, &session.UserServiceSyncStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT si.review_item_id, si.position, ri.question_id, ri.exercise_id, e.title, e.skill_type,
+			si.is_correct, si.score, si.quality, si.time_spent_seconds, si.answered_at
+		FROM review_session_items si
+		JOIN review_items ri ON ri.id = si.review_item_id
+		JOIN exercises e ON e.id = ri.exercise_id
+		WHERE si.session_id = $1
+		ORDER BY si.position
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	session.Items = []models.ReviewSessionItem{}
+	for rows.Next() {
+		var item models.ReviewSessionItem
+		err := rows.Scan(
+			&item.ReviewItemID, &item.Position, &item.QuestionID, &item.ExerciseID, &item.ExerciseTitle,
+			&item.SkillType, &item.IsCorrect, &item.Score, &item.Quality, &item.TimeSpentSeconds, &item.AnsweredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		session.Items = append(session.Items, item)
+	}
+	return &session, rows.Err()
+}
+
+// RecordReviewAnswer stores the outcome of a session item and the item's new schedule.
+// The session is completed once every item has an answer. Returns whether it was.
+func (r *ExerciseRepository) RecordReviewAnswer(sessionID uuid.UUID, item *models.ReviewItem, answer *models.ReviewSessionItem) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE review_session_items
+		SET is_correct = $3, score = $4, quality = $5, time_spent_seconds = $6, answered_at = $7
+		WHERE session_id = $1 AND review_item_id = $2 AND answered_at IS NULL
+	`, sessionID, item.ID, answer.IsCorrect, answer.Score, answer.Quality, answer.TimeSpentSeconds, answer.AnsweredAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to record review answer: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, ErrReviewItemAnswered
+	}
+
+	_, err = tx.Exec(`
+		UPDATE review_items
+		SET ease_factor = $2, interval_days = $3, repetitions = $4, lapses = $5, due_at = $6,
+			status = $7, review_count = review_count + 1, last_reviewed_at = $8, last_result = $9
+		WHERE id = $1
+	`, item.ID, item.EaseFactor, item.IntervalDays, item.Repetitions, item.Lapses, item.DueAt,
+		item.Status, item.LastReviewedAt, item.LastResult)
+	if err != nil {
+		return false, fmt.Errorf("failed to reschedule review item: %w", err)
+	}
+
+	result, err = tx.Exec(`
+		UPDATE review_sessions
+		SET status = 'completed', completed_at = $2
+		WHERE id = $1 AND status = 'in_progress'
+		  AND NOT EXISTS (
+			SELECT 1 FROM review_session_items WHERE session_id = $1 AND answered_at IS NULL
+		  )
+	`, sessionID, answer.AnsweredAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to complete review session: %w", err)
+	}
+	completed, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return completed > 0, nil
+}
+
+// CompleteReviewSession finishes a session early; unanswered items stay due.
+// Returns false if the session was already completed.
+func (r *ExerciseRepository) CompleteReviewSession(sessionID uuid.UUID, now time.Time) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE review_sessions
+		SET status = 'completed', completed_at = $2
+		WHERE id = $1 AND status = 'in_progress'
+	`, sessionID, now)
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// GetPendingReviewSessionSyncs returns completed review sessions not yet recorded in User Service
+func (r *ExerciseRepository) GetPendingReviewSessionSyncs(limit int) ([]uuid.UUID, error) {
+	return r.queryIDs(`
+		SELECT id
+		FROM review_sessions
+		WHERE status = 'completed'
+		  AND user_service_sync_status IN ('pending', 'failed')
+		  AND user_service_sync_attempts < 5
+		ORDER BY completed_at
+		LIMIT $1
+	`, limit)
+}
+
+// MarkReviewSessionSync records the outcome of recording a review session in User Service
+func (r *ExerciseRepository) MarkReviewSessionSync(sessionID uuid.UUID, syncErr error) error {
+	if syncErr == nil {
+		_, err := r.db.Exec(`
+			UPDATE review_sessions
+			SET user_service_sync_status = 'synced',
+			    user_service_sync_attempts = user_service_sync_attempts + 1,
+			    user_service_sync_error = NULL
+			WHERE id = $1
+		`, sessionID)
+		return err
+	}
+
+	_, err := r.db.Exec(`
+		UPDATE review_sessions
+		SET user_service_sync_status = 'failed',
+		    user_service_sync_attempts = user_service_sync_attempts + 1,
+		    user_service_sync_error = $1
+		WHERE id = $2
+	`, syncErr.Error(), sessionID)
+	return err
+}
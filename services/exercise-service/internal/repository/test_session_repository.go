@@ -0,0 +1,342 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+const testSessionColumns = `
+	id, user_id, test_type, ielts_variant, status,
+	listening_band, reading_band, writing_band, speaking_band, overall_band,
+	started_at, last_activity_at, completed_at, COALESCE(user_service_sync_status, 'pending'),
+	created_at, updated_at
+`
+
+func scanTestSession(scanner interface{ Scan(...interface{}) error }) (*models.TestSession, error) {
+	var s models.TestSession
+	err := scanner.Scan(
+		&s.ID, &s.UserID, &s.TestType, &s.IELTSVariant, &s.Status,
+		&s.ListeningBand, &s.ReadingBand, &s.WritingBand, &s.SpeakingBand, &s.OverallBand,
+		&s.StartedAt, &s.LastActivityAt, &s.CompletedAt, &s.UserServiceSyncStatus,
+		&s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CreateTestSession creates a test session and its parts in one transaction
+func (r *ExerciseRepository) CreateTestSession(userID uuid.UUID, testType, ieltsVariant string, parts []models.TestSessionPart) (*models.TestSession, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	session, err := scanTestSession(tx.QueryRow(`
+		INSERT INTO test_sessions (id, user_id, test_type, ielts_variant, status, started_at, last_activity_at)
+		VALUES ($1, $2, $3, $4, 'in_progress', $5, $5)
+		RETURNING `+testSessionColumns,
+		uuid.New(), userID, testType, ieltsVariant, time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create test session: %w", err)
+	}
+
+	for _, part := range parts {
+		_, err := tx.Exec(`
+			INSERT INTO test_session_parts (session_id, part_number, skill_type, exercise_id)
+			VALUES ($1, $2, $3, $4)
+		`, session.ID, part.PartNumber, part.SkillType, part.ExerciseID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create test session part %d: %w", part.PartNumber, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	session.Parts = parts
+	return session, nil
+}
+
+// GetTestSession returns a test session with its parts and their attempts
+func (r *ExerciseRepository) GetTestSession(sessionID uuid.UUID) (*models.TestSession, error) {
+	session, err := scanTestSession(r.db.QueryRow(`
+		SELECT `+testSessionColumns+`
+		FROM test_sessions
+		WHERE id = $1
+	`, sessionID))
+	if err != nil {
+		return nil, err
+	}
+
+	session.Parts, err = r.getTestSessionParts(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *ExerciseRepository) getTestSessionParts(sessionID uuid.UUID) ([]models.TestSessionPart, error) {
+	rows, err := r.db.Query(`
+		SELECT p.part_number, p.skill_type, p.exercise_id, e.title, e.time_limit_minutes, e.writing_task_type,
+			p.attempt_id, COALESCE(p.skipped, false),
+			a.status, a.evaluation_status, a.band_score, a.correct_answers, a.raw_score, a.total_questions,
+			a.time_spent_seconds, a.started_at, a.completed_at, a.detailed_scores, a.ai_feedback
+		FROM test_session_parts p
+		JOIN exercises e ON e.id = p.exercise_id
+		LEFT JOIN user_exercise_attempts a ON a.id = p.attempt_id
+		WHERE p.session_id = $1
+		ORDER BY p.part_number
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get test session parts: %w", err)
+	}
+	defer rows.Close()
+
+	var parts []models.TestSessionPart
+	for rows.Next() {
+		var p models.TestSessionPart
+		var skipped bool
+		var attemptStatus *string
+		err := rows.Scan(
+			&p.PartNumber, &p.SkillType, &p.ExerciseID, &p.ExerciseTitle, &p.TimeLimitMinutes, &p.WritingTaskType,
+			&p.AttemptID, &skipped,
+			&attemptStatus, &p.EvaluationStatus, &p.BandScore, &p.CorrectAnswers, &p.RawScore, &p.TotalQuestions,
+			&p.TimeSpentSeconds, &p.StartedAt, &p.CompletedAt, &p.DetailedScores, &p.AIFeedback,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan test session part: %w", err)
+		}
+		p.Status = testSessionPartStatus(skipped, attemptStatus, p.EvaluationStatus)
+		parts = append(parts, p)
+	}
+	return parts, rows.Err()
+}
+
+// testSessionPartStatus derives a part's status from its attempt. A Writing/Speaking part
+// whose evaluation was given up on is final without a band, like a skipped part.
+func testSessionPartStatus(skipped bool, attemptStatus, evaluationStatus *string) string {
+	if attemptStatus == nil {
+		if skipped {
+			return "skipped"
+		}
+		return "pending"
+	}
+
+	switch *attemptStatus {
+	case "completed":
+		return "graded"
+	case "submitted":
+		if evaluationStatus != nil && *evaluationStatus == "failed" {
+			return "evaluation_failed"
+		}
+		return "submitted" // Writing/Speaking waiting for AI evaluation
+	case "abandoned":
+		return "skipped"
+	default:
+		return "in_progress"
+	}
+}
+
+// GetUserTestSessions returns the user's test sessions, newest first, without parts
+func (r *ExerciseRepository) GetUserTestSessions(userID uuid.UUID, limit, offset int) ([]models.TestSession, int, error) {
+	var total int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM test_sessions WHERE user_id = $1`, userID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count test sessions: %w", err)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT `+testSessionColumns+`
+		FROM test_sessions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get test sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.TestSession{}
+	for rows.Next() {
+		session, err := scanTestSession(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan test session: %w", err)
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, total, rows.Err()
+}
+
+// SetTestSessionPartAttempt links a started attempt to a part. Returns false if the part
+// was already started or skipped.
+func (r *ExerciseRepository) SetTestSessionPartAttempt(sessionID uuid.UUID, partNumber int, attemptID uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE test_session_parts
+		SET attempt_id = $1
+		WHERE session_id = $2 AND part_number = $3 AND attempt_id IS NULL AND NOT COALESCE(skipped, false)
+	`, attemptID, sessionID, partNumber)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE test_sessions SET last_activity_at = $1 WHERE id = $2
+	`, time.Now(), sessionID)
+	return true, err
+}
+
+// GetTestSessionIDForAttempt returns the session an attempt was taken in, if any
+func (r *ExerciseRepository) GetTestSessionIDForAttempt(attemptID uuid.UUID) (uuid.UUID, bool, error) {
+	var sessionID uuid.UUID
+	err := r.db.QueryRow(`
+		SELECT session_id FROM test_session_parts WHERE attempt_id = $1
+	`, attemptID).Scan(&sessionID)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	return sessionID, true, nil
+}
+
+// UpdateTestSessionStatus moves an open session between in_progress and awaiting_evaluation
+func (r *ExerciseRepository) UpdateTestSessionStatus(sessionID uuid.UUID, status string) error {
+	_, err := r.db.Exec(`
+		UPDATE test_sessions
+		SET status = $1
+		WHERE id = $2 AND status IN ('in_progress', 'awaiting_evaluation') AND status != $1
+	`, status, sessionID)
+	return err
+}
+
+// FinishTestSession stores the final scores of an open session. Returns false if the
+// session was already finished, so the result is only recorded once.
+func (r *ExerciseRepository) FinishTestSession(session *models.TestSession) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE test_sessions
+		SET status = $1,
+		    listening_band = $2,
+		    reading_band = $3,
+		    writing_band = $4,
+		    speaking_band = $5,
+		    overall_band = $6,
+		    completed_at = $7
+		WHERE id = $8 AND status IN ('in_progress', 'awaiting_evaluation')
+	`, session.Status, session.ListeningBand, session.ReadingBand, session.WritingBand, session.SpeakingBand,
+		session.OverallBand, session.CompletedAt, session.ID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// SkipPendingTestSessionParts marks parts that were never started as skipped
+func (r *ExerciseRepository) SkipPendingTestSessionParts(sessionID uuid.UUID) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE test_session_parts
+		SET skipped = true
+		WHERE session_id = $1 AND attempt_id IS NULL
+	`, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetIdleTestSessionIDs returns open sessions with no activity since idleBefore that can
+// be closed: in-progress sessions with no part being worked on, and sessions awaiting
+// evaluation whose evaluations have all finished or failed (e.g. when the hook that
+// closes them did not run)
+func (r *ExerciseRepository) GetIdleTestSessionIDs(idleBefore time.Time, limit int) ([]uuid.UUID, error) {
+	return r.queryIDs(`
+		SELECT s.id
+		FROM test_sessions s
+		WHERE s.last_activity_at < $1
+		  AND (
+			(s.status = 'in_progress' AND NOT EXISTS (
+				SELECT 1
+				FROM test_session_parts p
+				JOIN user_exercise_attempts a ON a.id = p.attempt_id
+				WHERE p.session_id = s.id AND a.status = 'in_progress'
+			))
+			OR (s.status = 'awaiting_evaluation' AND NOT EXISTS (
+				SELECT 1
+				FROM test_session_parts p
+				JOIN user_exercise_attempts a ON a.id = p.attempt_id
+				WHERE p.session_id = s.id AND a.status IN ('in_progress', 'submitted')
+				  AND a.evaluation_status IS DISTINCT FROM 'failed'
+			))
+		  )
+		ORDER BY s.last_activity_at
+		LIMIT $2
+	`, idleBefore, limit)
+}
+
+// GetPendingTestSessionSyncs returns finished sessions not yet recorded in User Service
+func (r *ExerciseRepository) GetPendingTestSessionSyncs(limit int) ([]uuid.UUID, error) {
+	return r.queryIDs(`
+		SELECT id
+		FROM test_sessions
+		WHERE status IN ('completed', 'incomplete')
+		  AND user_service_sync_status IN ('pending', 'failed')
+		  AND user_service_sync_attempts < 5
+		ORDER BY completed_at
+		LIMIT $1
+	`, limit)
+}
+
+// MarkTestSessionSync records the outcome of recording a session in User Service
+func (r *ExerciseRepository) MarkTestSessionSync(sessionID uuid.UUID, syncErr error) error {
+	if syncErr == nil {
+		_, err := r.db.Exec(`
+			UPDATE test_sessions
+			SET user_service_sync_status = 'synced',
+			    user_service_sync_attempts = user_service_sync_attempts + 1,
+			    user_service_sync_error = NULL
+			WHERE id = $1
+		`, sessionID)
+		return err
+	}
+
+	_, err := r.db.Exec(`
+		UPDATE test_sessions
+		SET user_service_sync_status = 'failed',
+		    user_service_sync_attempts = user_service_sync_attempts + 1,
+		    user_service_sync_error = $1
+		WHERE id = $2
+	`, syncErr.Error(), sessionID)
+	return err
+}
+
+func (r *ExerciseRepository) queryIDs(query string, args ...interface{}) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
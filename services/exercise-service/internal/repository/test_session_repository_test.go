@@ -0,0 +1,28 @@
+package repository
+
+import "testing"
+
+func TestTestSessionPartStatus(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name             string
+		skipped          bool
+		attemptStatus    *string
+		evaluationStatus *string
+		want             string
+	}{
+		{"not started", false, nil, nil, "pending"},
+		{"skipped", true, nil, nil, "skipped"},
+		{"being taken", false, str("in_progress"), nil, "in_progress"},
+		{"graded", false, str("completed"), str("completed"), "graded"},
+		{"waiting for evaluation", false, str("submitted"), str("pending"), "submitted"},
+		{"being evaluated", false, str("submitted"), str("processing"), "submitted"},
+		{"evaluation failed", false, str("submitted"), str("failed"), "evaluation_failed"},
+		{"abandoned", false, str("abandoned"), nil, "skipped"},
+	}
+	for _, tt := range tests {
+		if got := testSessionPartStatus(tt.skipped, tt.attemptStatus, tt.evaluationStatus); got != tt.want {
+			t.Errorf("%s: status = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -41,10 +41,35 @@ func SetupRoutes(router *gin.Engine, handler *handlers.ExerciseHandler, storageH
 			submissions.POST("", handler.StartExercise)                 // Start new exercise
 			submissions.POST("/:id/submit", handler.SubmitExercise)     // Unified submission (Phase 4)
 			submissions.PUT("/:id/answers", handler.SubmitAnswers)      // Submit answers (deprecated, use /submit)
+			submissions.PUT("/:id/autosave", handler.AutosaveAnswers)   // Save partial answers, get server time left
 			submissions.GET("/:id/result", handler.GetSubmissionResult) // Get result
 			submissions.GET("/my", handler.GetMySubmissions)            // Get my submissions
 		}
 
+		// Full mock tests: Listening, Reading, Writing and Speaking in one session
+		testSessions := api.Group("/test-sessions")
+		testSessions.Use(authMiddleware.AuthRequired())
+		{
+			testSessions.POST("", handler.CreateTestSession)                          // Create a session from exercises
+			testSessions.GET("", handler.ListTestSessions)                            // My test sessions
+			testSessions.GET("/:id", handler.GetTestSession)                          // Session with part progress and timers
+			testSessions.POST("/:id/parts/:part/start", handler.StartTestSessionPart) // Start the next part (returns a submission)
+			testSessions.GET("/:id/report", handler.GetTestSessionReport)             // Full score report
+		}
+
+		// Mistake notebook: missed questions come back on a spaced-repetition schedule
+		reviews := api.Group("/reviews")
+		reviews.Use(authMiddleware.AuthRequired())
+		{
+			reviews.GET("/summary", handler.GetReviewSummary)                             // Due, active and mastered counts
+			reviews.GET("/items", handler.ListReviewItems)                                // My review items by status
+			reviews.DELETE("/items/:id", handler.ArchiveReviewItem)                       // Stop reviewing a question
+			reviews.POST("/sessions", handler.CreateReviewSession)                        // Start a mixed session of due items (or resume the open one)
+			reviews.GET("/sessions/:id", handler.GetReviewSession)                        // Session with its questions
+			reviews.POST("/sessions/:id/items/:item_id/answer", handler.AnswerReviewItem) // Grade an answer and reschedule
+			reviews.POST("/sessions/:id/complete", handler.CompleteReviewSession)         // Finish early
+		}
+
 		// Tags routes (public)
 		tags := api.Group("/tags")
 		{
@@ -56,35 +81,44 @@ func SetupRoutes(router *gin.Engine, handler *handlers.ExerciseHandler, storageH
 			exerciseTags.GET("", handler.GetExerciseTags) // Get exercise tags
 		}
 
-		// Admin routes (instructor/admin only)
+		// Admin routes (per-endpoint permissions)
 		admin := api.Group("/admin")
 		admin.Use(authMiddleware.AuthRequired())
-		admin.Use(authMiddleware.RequireRole("instructor", "admin"))
 		{
 			// Exercise management
-			admin.POST("/exercises", handler.CreateExercise)                           // Create exercise
-			admin.PUT("/exercises/:id", handler.UpdateExercise)                        // Update exercise
-			admin.DELETE("/exercises/:id", handler.DeleteExercise)                     // Delete exercise
-			admin.POST("/exercises/:id/publish", handler.PublishExercise)              // Publish exercise
-			admin.POST("/exercises/:id/unpublish", handler.UnpublishExercise)          // Unpublish exercise
-			admin.POST("/exercises/:id/sections", handler.CreateSection)               // Create section
-			admin.GET("/exercises/:id/analytics", handler.GetExerciseAnalytics)        // Get analytics
-			admin.POST("/exercises/:id/tags", handler.AddTagToExercise)                // Add tag to exercise
-			admin.DELETE("/exercises/:id/tags/:tag_id", handler.RemoveTagFromExercise) // Remove tag
+			admin.POST("/exercises", authMiddleware.RequirePermission("exercise:create"), handler.CreateExercise)                             // Create exercise
+			admin.POST("/exercises/import", authMiddleware.RequirePermission("exercise:create"), handler.ImportExercise)                      // Import exercise package (?dry_run=true)
+			admin.GET("/exercises/:id", authMiddleware.RequirePermission("exercise:update"), handler.GetExerciseForAuthor)                    // Get exercise with answer key
+			admin.GET("/exercises/:id/export", authMiddleware.RequirePermission("exercise:update"), handler.ExportExercise)                   // Export exercise package (?format=json|zip)
+			admin.PUT("/exercises/:id", authMiddleware.RequirePermission("exercise:update"), handler.UpdateExercise)                          // Update exercise
+			admin.DELETE("/exercises/:id", authMiddleware.RequirePermission("exercise:delete"), handler.DeleteExercise)                       // Delete exercise
+			admin.POST("/exercises/:id/publish", authMiddleware.RequirePermission("exercise:publish"), handler.PublishExercise)               // Publish exercise
+			admin.POST("/exercises/:id/unpublish", authMiddleware.RequirePermission("exercise:publish"), handler.UnpublishExercise)           // Unpublish exercise
+			admin.POST("/exercises/:id/sections", authMiddleware.RequirePermission("exercise:create"), handler.CreateSection)                 // Create section
+			admin.GET("/exercises/:id/analytics", authMiddleware.RequirePermission("exercise:view_analytics"), handler.GetExerciseAnalytics)  // Get analytics
+			admin.GET("/exercises/:id/analytics/items", authMiddleware.RequirePermission("exercise:view_analytics"), handler.GetItemAnalysis) // Get item analysis
+			admin.POST("/exercises/:id/tags", authMiddleware.RequirePermission("exercise:update"), handler.AddTagToExercise)                  // Add tag to exercise
+			admin.DELETE("/exercises/:id/tags/:tag_id", authMiddleware.RequirePermission("exercise:update"), handler.RemoveTagFromExercise)   // Remove tag
 
 			// Question management
-			admin.POST("/questions", handler.CreateQuestion)                   // Create question
-			admin.POST("/questions/:id/options", handler.CreateQuestionOption) // Add option
-			admin.POST("/questions/:id/answer", handler.CreateQuestionAnswer)  // Add answer
+			admin.POST("/questions", authMiddleware.RequirePermission("exercise:create"), handler.CreateQuestion)                     // Create question
+			admin.POST("/questions/:id/options", authMiddleware.RequirePermission("exercise:create"), handler.CreateQuestionOption)   // Add option
+			admin.POST("/questions/:id/answer", authMiddleware.RequirePermission("exercise:create"), handler.CreateQuestionAnswer)    // Add answer
+			admin.POST("/questions/:id/preview-grading", authMiddleware.RequirePermission("exercise:update"), handler.PreviewGrading) // Score a candidate answer
 
 			// Tag management
-			admin.POST("/tags", handler.CreateTag) // Create tag
+			admin.POST("/tags", authMiddleware.RequirePermission("exercise:create"), handler.CreateTag) // Create tag
 
 			// Question Bank management
-			admin.GET("/question-bank", handler.GetBankQuestions)          // List bank questions
-			admin.POST("/question-bank", handler.CreateBankQuestion)       // Create bank question
-			admin.PUT("/question-bank/:id", handler.UpdateBankQuestion)    // Update bank question
-			admin.DELETE("/question-bank/:id", handler.DeleteBankQuestion) // Delete bank question
+			admin.GET("/question-bank", authMiddleware.RequirePermission("question_bank:manage"), handler.GetBankQuestions)           // List bank questions
+			admin.POST("/question-bank", authMiddleware.RequirePermission("question_bank:manage"), handler.CreateBankQuestion)        // Create bank question
+			admin.POST("/question-bank/assemble", authMiddleware.RequirePermission("question_bank:manage"), handler.AssembleExercise) // Build draft exercise from bank
+			admin.PUT("/question-bank/:id", authMiddleware.RequirePermission("question_bank:manage"), handler.UpdateBankQuestion)     // Update bank question
+			admin.DELETE("/question-bank/:id", authMiddleware.RequirePermission("question_bank:manage"), handler.DeleteBankQuestion)  // Delete bank question
+
+			// AI evaluation queue
+			admin.GET("/evaluation-jobs/dead", authMiddleware.RequirePermission("evaluation:manage"), handler.GetDeadEvaluationJobs)        // List dead-lettered evaluation jobs
+			admin.POST("/evaluation-jobs/:id/requeue", authMiddleware.RequirePermission("evaluation:manage"), handler.RequeueEvaluationJob) // Requeue dead evaluation job
 		}
 	}
 }
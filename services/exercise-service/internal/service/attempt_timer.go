@@ -0,0 +1,255 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/textanalysis"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// AttemptTimerConfig controls server-side exam timing and the in-progress attempt sweeper
+type AttemptTimerConfig struct {
+	GracePeriod   time.Duration // Answers are still accepted this long after the deadline (latency, slow autosave)
+	IdleTimeout   time.Duration // Untimed attempts with no activity for this long are abandoned
+	SweepInterval time.Duration // How often to look for expired and idle attempts
+	BatchSize     int           // Max attempts handled per sweep
+	ClaimTimeout  time.Duration // An auto-submit claimed this long ago without finishing is retried
+}
+
+// DefaultAttemptTimerConfig returns standard attempt timer configuration
+func DefaultAttemptTimerConfig() AttemptTimerConfig {
+	return AttemptTimerConfig{
+		GracePeriod:   30 * time.Second,
+		IdleTimeout:   24 * time.Hour,
+		SweepInterval: time.Minute,
+		BatchSize:     50,
+		ClaimTimeout:  5 * time.Minute,
+	}
+}
+
+// applyAttemptTimer fills in the server-side deadline and time left of an in-progress timed attempt
+func applyAttemptTimer(attempt *models.UserExerciseAttempt, now time.Time) {
+	deadline, ok := attempt.Deadline()
+	if !ok || attempt.Status != "in_progress" {
+		return
+	}
+
+	remaining := int(math.Ceil(deadline.Sub(now).Seconds()))
+	if remaining < 0 {
+		remaining = 0
+	}
+	attempt.DeadlineAt = &deadline
+	attempt.RemainingSeconds = &remaining
+}
+
+// checkAttemptOpen returns an error if the attempt can no longer take answers
+func (s *ExerciseService) checkAttemptOpen(attempt *models.UserExerciseAttempt, now time.Time) error {
+	if attempt.Status != "in_progress" {
+		return ErrAttemptNotInProgress
+	}
+	if deadline, ok := attempt.Deadline(); ok && now.After(deadline.Add(s.attemptTimerConfig.GracePeriod)) {
+		return ErrAttemptExpired
+	}
+	return nil
+}
+
+// openAttempt rejects answers to an attempt past its deadline and grace window. An expired
+// attempt is submitted with its saved answers so the student still gets a result.
+func (s *ExerciseService) openAttempt(attempt *models.UserExerciseAttempt) error {
+	err := s.checkAttemptOpen(attempt, time.Now())
+	if errors.Is(err, ErrAttemptExpired) {
+		log.Printf("⏰ Rejected late answers for submission %s, submitting saved answers", attempt.ID)
+		if expireErr := s.expireAttempt(attempt); expireErr != nil {
+			log.Printf("⚠️ Failed to submit expired attempt %s: %v", attempt.ID, expireErr)
+		}
+	}
+	return err
+}
+
+// AutosaveAnswers saves partial answers of the user's in-progress attempt without submitting it,
+// and returns the time left as computed by the server
+func (s *ExerciseService) AutosaveAnswers(submissionID, userID uuid.UUID, req *models.AutosaveRequest) (*models.AutosaveResponse, error) {
+	attempt, err := s.repo.GetSubmissionByID(submissionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSubmissionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get submission: %w", err)
+	}
+	if attempt.UserID != userID {
+		return nil, ErrSubmissionNotFound
+	}
+
+	if err := s.openAttempt(attempt); err != nil {
+		return nil, err
+	}
+
+	if len(req.Answers) > 0 {
+		if err := s.repo.SaveSubmissionAnswers(submissionID, req.Answers); err != nil {
+			return nil, fmt.Errorf("save answers: %w", err)
+		}
+	}
+	if req.WritingData != nil {
+		// Counted the way IELTS counts words, as on submit; the client's count is not trusted
+		wordCount := textanalysis.CountWords(req.WritingData.EssayText)
+		err := s.repo.UpdateSubmissionWritingData(submissionID, req.WritingData.EssayText, wordCount,
+			req.WritingData.TaskType, req.WritingData.PromptText)
+		if err != nil {
+			return nil, fmt.Errorf("save essay draft: %w", err)
+		}
+	}
+
+	if err := s.repo.TouchSubmissionActivity(submissionID); err != nil {
+		log.Printf("⚠️ Failed to record activity for submission %s: %v", submissionID, err)
+	}
+
+	saved, err := s.repo.CountSubmissionAnswers(submissionID)
+	if err != nil {
+		return nil, fmt.Errorf("count answers: %w", err)
+	}
+
+	now := time.Now()
+	applyAttemptTimer(attempt, now)
+	return &models.AutosaveResponse{
+		SubmissionID:     submissionID,
+		Status:           attempt.Status,
+		SavedAnswers:     saved,
+		ServerTime:       now,
+		DeadlineAt:       attempt.DeadlineAt,
+		RemainingSeconds: attempt.RemainingSeconds,
+		GraceSeconds:     int(s.attemptTimerConfig.GracePeriod.Seconds()),
+	}, nil
+}
+
+// expireAttempt submits an attempt whose time ran out with what was saved so far.
+// Attempts with nothing saved were never really taken and are marked abandoned instead.
+func (s *ExerciseService) expireAttempt(attempt *models.UserExerciseAttempt) error {
+	exercise, err := s.repo.GetExerciseByIDSimple(attempt.ExerciseID)
+	if err != nil {
+		return fmt.Errorf("get exercise: %w", err)
+	}
+
+	hasWork := false
+	switch exercise.SkillType {
+	case "listening", "reading":
+		saved, err := s.repo.CountSubmissionAnswers(attempt.ID)
+		if err != nil {
+			return fmt.Errorf("count answers: %w", err)
+		}
+		hasWork = saved > 0
+	case "writing":
+		hasWork = attempt.EssayText != nil && strings.TrimSpace(*attempt.EssayText) != ""
+	case "speaking":
+		hasWork = attempt.AudioURL != nil && *attempt.AudioURL != ""
+	}
+
+	if !hasWork {
+		abandoned, err := s.repo.AbandonSubmission(attempt.ID)
+		if err != nil {
+			return fmt.Errorf("abandon attempt: %w", err)
+		}
+		if abandoned {
+			log.Printf("⏰ Attempt %s expired with nothing saved, marked abandoned", attempt.ID)
+			go s.onAttemptFinished(attempt.ID)
+		}
+		return nil
+	}
+
+	claimed, err := s.repo.ClaimAutoSubmit(attempt.ID, time.Now().Add(-s.attemptTimerConfig.ClaimTimeout))
+	if err != nil {
+		return fmt.Errorf("claim attempt: %w", err)
+	}
+	if !claimed {
+		return nil
+	}
+
+	log.Printf("⏰ Auto-submitting expired %s attempt %s", exercise.SkillType, attempt.ID)
+	if err := s.autoSubmit(attempt.ID, exercise); err != nil {
+		if releaseErr := s.repo.ReleaseAutoSubmit(attempt.ID); releaseErr != nil {
+			log.Printf("⚠️ Failed to release auto-submit of attempt %s: %v", attempt.ID, releaseErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// autoSubmit submits an attempt the same way the student's own submit would
+func (s *ExerciseService) autoSubmit(submissionID uuid.UUID, exercise *models.Exercise) error {
+	if !exercise.RequiresAIEvaluation() {
+		return s.completeListeningReading(submissionID, exercise)
+	}
+
+	if err := s.repo.MarkSubmissionAsSubmitted(submissionID); err != nil {
+		return fmt.Errorf("mark submission as submitted: %w", err)
+	}
+	if err := s.repo.UpdateSubmissionEvaluationStatus(submissionID, "pending"); err != nil {
+		return fmt.Errorf("update evaluation status: %w", err)
+	}
+	if err := s.enqueueEvaluation(submissionID, exercise.SkillType); err != nil {
+		return err
+	}
+	go s.onAttemptFinished(submissionID)
+	return nil
+}
+
+// StartAttemptSweeper auto-submits expired attempts and abandons idle ones until the process exits
+func (s *ExerciseService) StartAttemptSweeper() {
+	cfg := s.attemptTimerConfig
+
+	ticker := time.NewTicker(cfg.SweepInterval)
+	defer ticker.Stop()
+
+	log.Printf("🔄 Started attempt sweeper (checking every %v, grace %v, idle timeout %v)",
+		cfg.SweepInterval, cfg.GracePeriod, cfg.IdleTimeout)
+
+	for {
+		s.sweepAttempts()
+		<-ticker.C
+	}
+}
+
+// sweepAttempts handles one batch of expired and idle in-progress attempts
+func (s *ExerciseService) sweepAttempts() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ PANIC in sweepAttempts: %v", r)
+		}
+	}()
+
+	cfg := s.attemptTimerConfig
+	now := time.Now()
+
+	ids, err := s.repo.GetExpiredAttemptIDs(now.Add(-cfg.GracePeriod), now.Add(-cfg.ClaimTimeout), cfg.BatchSize)
+	if err != nil {
+		log.Printf("⚠️ Failed to get expired attempts: %v", err)
+	}
+	for _, id := range ids {
+		attempt, err := s.repo.GetSubmissionByID(id)
+		if err != nil {
+			log.Printf("⚠️ Failed to get expired attempt %s: %v", id, err)
+			continue
+		}
+		if err := s.expireAttempt(attempt); err != nil {
+			log.Printf("❌ Failed to auto-submit expired attempt %s: %v", id, err)
+		}
+	}
+
+	abandoned, err := s.repo.AbandonIdleAttempts(now.Add(-cfg.IdleTimeout), cfg.BatchSize)
+	if err != nil {
+		log.Printf("⚠️ Failed to abandon idle attempts: %v", err)
+	}
+
+	idleSessions := s.closeIdleTestSessions(now.Add(-cfg.IdleTimeout), cfg.BatchSize)
+
+	if len(ids) > 0 || abandoned > 0 || idleSessions > 0 {
+		log.Printf("🔄 Attempt sweep: %d expired attempts handled, %d idle attempts abandoned, %d idle test sessions closed",
+			len(ids), abandoned, idleSessions)
+	}
+}
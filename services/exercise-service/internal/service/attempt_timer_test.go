@@ -0,0 +1,81 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+)
+
+func timedAttempt(status string, limitMinutes int, startedAt time.Time) *models.UserExerciseAttempt {
+	return &models.UserExerciseAttempt{Status: status, TimeLimitMinutes: &limitMinutes, StartedAt: startedAt}
+}
+
+func TestApplyAttemptTimer(t *testing.T) {
+	start := time.Date(2026, time.March, 10, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		attempt       *models.UserExerciseAttempt
+		now           time.Time
+		wantRemaining *int
+	}{
+		{"time left", timedAttempt("in_progress", 60, start), start.Add(45 * time.Minute), intPtr(15 * 60)},
+		{"partial second rounds up", timedAttempt("in_progress", 60, start), start.Add(59*time.Minute + 59*time.Second + 500*time.Millisecond), intPtr(1)},
+		{"past the deadline", timedAttempt("in_progress", 60, start), start.Add(2 * time.Hour), intPtr(0)},
+		{"untimed", timedAttempt("in_progress", 0, start), start, nil},
+		{"no time limit", &models.UserExerciseAttempt{Status: "in_progress", StartedAt: start}, start, nil},
+		{"already completed", timedAttempt("completed", 60, start), start, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applyAttemptTimer(tt.attempt, tt.now)
+			got := tt.attempt.RemainingSeconds
+			if (got == nil) != (tt.wantRemaining == nil) || (got != nil && *got != *tt.wantRemaining) {
+				t.Fatalf("remaining = %v, want %v", deref(got), deref(tt.wantRemaining))
+			}
+			if got != nil && !tt.attempt.DeadlineAt.Equal(start.Add(time.Hour)) {
+				t.Errorf("deadline = %v, want %v", tt.attempt.DeadlineAt, start.Add(time.Hour))
+			}
+			if got == nil && tt.attempt.DeadlineAt != nil {
+				t.Errorf("deadline = %v, want none", tt.attempt.DeadlineAt)
+			}
+		})
+	}
+}
+
+func TestCheckAttemptOpen(t *testing.T) {
+	s := &ExerciseService{attemptTimerConfig: DefaultAttemptTimerConfig()} // 30s grace
+	start := time.Date(2026, time.March, 10, 9, 0, 0, 0, time.UTC)
+	deadline := start.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		attempt *models.UserExerciseAttempt
+		now     time.Time
+		want    error
+	}{
+		{"before the deadline", timedAttempt("in_progress", 60, start), deadline.Add(-time.Minute), nil},
+		{"inside the grace period", timedAttempt("in_progress", 60, start), deadline.Add(30 * time.Second), nil},
+		{"after the grace period", timedAttempt("in_progress", 60, start), deadline.Add(31 * time.Second), ErrAttemptExpired},
+		{"untimed, much later", timedAttempt("in_progress", 0, start), start.Add(48 * time.Hour), nil},
+		{"submitted", timedAttempt("submitted", 60, start), start.Add(time.Minute), ErrAttemptNotInProgress},
+		{"abandoned", timedAttempt("abandoned", 60, start), deadline.Add(time.Hour), ErrAttemptNotInProgress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.checkAttemptOpen(tt.attempt, tt.now); got != tt.want {
+				t.Errorf("checkAttemptOpen = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func deref(v *int) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
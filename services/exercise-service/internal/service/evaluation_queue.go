@@ -0,0 +1,267 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+// EvaluationQueueConfig controls the Writing/Speaking evaluation worker
+type EvaluationQueueConfig struct {
+	PollInterval  time.Duration // How often to look for runnable jobs
+	LeaseDuration time.Duration // How long a worker owns a job before others may take it over
+	BatchSize     int           // Max jobs leased (and processed concurrently) per poll
+	MaxAttempts   int           // Job runs before the job is dead-lettered
+}
+
+// DefaultEvaluationQueueConfig returns standard evaluation queue configuration.
+// The lease must outlive a full run: transcription + evaluation, each with
+// AIServiceRetryConfig retries on a 60s HTTP timeout, then recording to User Service.
+func DefaultEvaluationQueueConfig() EvaluationQueueConfig {
+	return EvaluationQueueConfig{
+		PollInterval:  5 * time.Second,
+		LeaseDuration: 10 * time.Minute,
+		BatchSize:     4,
+		MaxAttempts:   5,
+	}
+}
+
+// evaluationOutcome is the result of one evaluation job run
+type evaluationOutcome struct {
+	result       *models.AIEvaluationResult
+	recordResult bool // Record to User Service and send notification after completion
+}
+
+// permanentError marks evaluation failures that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func permanentEvaluationError(err error) error {
+	return &permanentError{err: err}
+}
+
+// newEvaluationWorkerID returns a lease owner ID unique to this process
+func newEvaluationWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "exercise-service"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:8])
+}
+
+// enqueueEvaluation persists an evaluation job and wakes the worker
+func (s *ExerciseService) enqueueEvaluation(submissionID uuid.UUID, skillType string) error {
+	if err := s.repo.EnqueueEvaluationJob(submissionID, skillType, s.evalQueueConfig.MaxAttempts); err != nil {
+		return err
+	}
+
+	// Non-blocking: if a wake-up is already pending the worker will see this job too
+	select {
+	case s.evalWake <- struct{}{}:
+	default:
+	}
+
+	log.Printf("📥 Queued %s evaluation for submission %s", skillType, submissionID)
+	return nil
+}
+
+// StartEvaluationWorker runs the recovery sweep and then processes evaluation jobs until the process exits
+func (s *ExerciseService) StartEvaluationWorker() {
+	cfg := s.evalQueueConfig
+
+	requeued, backfilled, err := s.repo.RecoverEvaluationJobs(cfg.MaxAttempts)
+	if err != nil {
+		log.Printf("⚠️ Evaluation recovery sweep failed: %v", err)
+	} else if requeued > 0 || backfilled > 0 {
+		log.Printf("🔁 Evaluation recovery sweep: %d expired leases requeued, %d stuck submissions queued", requeued, backfilled)
+	}
+
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	log.Printf("🔄 Started evaluation worker %s (polling every %v)", s.evalWorkerID, cfg.PollInterval)
+
+	for {
+		s.processEvaluationBatch()
+
+		select {
+		case <-ticker.C:
+		case <-s.evalWake:
+		}
+	}
+}
+
+// processEvaluationBatch leases a batch of jobs and processes them concurrently
+func (s *ExerciseService) processEvaluationBatch() {
+	cfg := s.evalQueueConfig
+
+	jobs, err := s.repo.LeaseEvaluationJobs(s.evalWorkerID, cfg.BatchSize, cfg.LeaseDuration)
+	if err != nil {
+		log.Printf("⚠️ Failed to lease evaluation jobs: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job *models.EvaluationJob) {
+			defer wg.Done()
+			s.processEvaluationJob(job)
+		}(job)
+	}
+	wg.Wait()
+}
+
+// processEvaluationJob runs a single leased job and records its outcome
+func (s *ExerciseService) processEvaluationJob(job *models.EvaluationJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ PANIC in evaluation job %s: %v", job.ID, r)
+			s.failEvaluationJob(job, fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	log.Printf("🔄 Processing %s evaluation job %s (run %d/%d) for submission %s",
+		job.SkillType, job.ID, job.Attempts, job.MaxAttempts, job.AttemptID)
+
+	submission, err := s.repo.GetSubmissionByID(job.AttemptID)
+	if err != nil {
+		s.failEvaluationJob(job, fmt.Errorf("get submission: %w", err))
+		return
+	}
+
+	exercise, err := s.repo.GetExerciseByIDSimple(submission.ExerciseID)
+	if err != nil {
+		s.failEvaluationJob(job, fmt.Errorf("get exercise: %w", err))
+		return
+	}
+
+	// A worker stopped after saving the result: only the recording is left to do
+	if job.Status == "recording" {
+		bandScore := 0.0
+		if submission.BandScore != nil {
+			bandScore = *submission.BandScore
+		}
+		s.finishEvaluationJob(job, submission.ID, exercise, bandScore)
+		return
+	}
+
+	if err := s.repo.UpdateSubmissionEvaluationStatus(submission.ID, "processing"); err != nil {
+		log.Printf("⚠️ Failed to set evaluation status to processing: %v", err)
+	}
+
+	var outcome *evaluationOutcome
+	switch job.SkillType {
+	case "writing":
+		outcome, err = s.evaluateWriting(submission)
+	case "speaking":
+		outcome, err = s.evaluateSpeaking(submission, exercise)
+	default:
+		err = permanentEvaluationError(fmt.Errorf("unsupported skill type: %s", job.SkillType))
+	}
+	if err != nil {
+		s.failEvaluationJob(job, err)
+		return
+	}
+
+	// Store the result and complete the job atomically; only the lease holder may do this
+	err = s.repo.CompleteEvaluationJob(job.ID, s.evalWorkerID, outcome.result, outcome.recordResult)
+	if errors.Is(err, repository.ErrEvaluationLeaseLost) {
+		log.Printf("⚠️ Lease lost for evaluation job %s, discarding result", job.ID)
+		return
+	}
+	if err != nil {
+		s.failEvaluationJob(job, fmt.Errorf("save evaluation result: %w", err))
+		return
+	}
+
+	log.Printf("✅ %s evaluation completed for submission %s: %.1f band", job.SkillType, submission.ID, outcome.result.OverallBandScore)
+	job.RecordResult = outcome.recordResult
+	s.finishEvaluationJob(job, submission.ID, exercise, outcome.result.OverallBandScore)
+}
+
+// finishEvaluationJob records a saved evaluation and completes the job. It runs within the
+// lease, so if the worker stops first the next lease holder finishes the job again. The result
+// is recorded only by whoever claims the attempt's recording first, so it is sent once; if the
+// worker stops right after the claim, the User Service sync retry still sends it.
+func (s *ExerciseService) finishEvaluationJob(job *models.EvaluationJob, submissionID uuid.UUID, exercise *models.Exercise, bandScore float64) {
+	s.onAttemptFinished(submissionID)
+
+	if job.RecordResult {
+		claimed, err := s.repo.ClaimResultRecording(submissionID)
+		if err != nil {
+			s.failEvaluationJob(job, err)
+			return
+		}
+		if claimed {
+			// Record to user service
+			s.recordToUserService(submissionID, exercise, bandScore)
+
+			// Handle exercise completion (update user stats and send notification)
+			s.handleExerciseCompletion(submissionID)
+		} else {
+			log.Printf("ℹ️ Result of submission %s already recorded, skipping", submissionID)
+		}
+	}
+
+	if err := s.repo.FinishEvaluationJob(job.ID, s.evalWorkerID); err != nil {
+		log.Printf("⚠️ Failed to complete evaluation job %s: %v", job.ID, err)
+	}
+}
+
+// failEvaluationJob reschedules a failed job with backoff, or dead-letters it when
+// the error is permanent or the job has used up its attempts
+func (s *ExerciseService) failEvaluationJob(job *models.EvaluationJob, jobErr error) {
+	var permErr *permanentError
+	if errors.As(jobErr, &permErr) || job.Attempts >= job.MaxAttempts {
+		log.Printf("❌ Evaluation job %s dead-lettered after %d run(s): %v", job.ID, job.Attempts, jobErr)
+		if err := s.repo.DeadLetterEvaluationJob(job.ID, s.evalWorkerID, jobErr.Error()); err != nil {
+			log.Printf("⚠️ Failed to dead-letter evaluation job %s: %v", job.ID, err)
+			return
+		}
+		// The failed evaluation is final: a test session waiting for it can now be closed
+		s.onAttemptFinished(job.AttemptID)
+		return
+	}
+
+	delay := AIServiceRetryConfig().BackoffDelay(job.Attempts)
+	log.Printf("⚠️ Evaluation job %s failed (run %d/%d), retrying in %v: %v", job.ID, job.Attempts, job.MaxAttempts, delay, jobErr)
+	if err := s.repo.RetryEvaluationJob(job.ID, s.evalWorkerID, jobErr.Error(), time.Now().Add(delay)); err != nil {
+		log.Printf("⚠️ Failed to reschedule evaluation job %s: %v", job.ID, err)
+	}
+}
+
+// GetDeadEvaluationJobs returns dead-lettered evaluation jobs (admin only)
+func (s *ExerciseService) GetDeadEvaluationJobs(page, limit int) ([]*models.EvaluationJob, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return s.repo.GetDeadEvaluationJobs(limit, (page-1)*limit)
+}
+
+// RequeueEvaluationJob puts a dead-lettered evaluation job back on the queue (admin only)
+func (s *ExerciseService) RequeueEvaluationJob(jobID uuid.UUID) error {
+	if err := s.repo.RequeueDeadEvaluationJob(jobID); err != nil {
+		return err
+	}
+
+	select {
+	case s.evalWake <- struct{}{}:
+	default:
+	}
+	return nil
+}
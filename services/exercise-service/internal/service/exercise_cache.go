@@ -0,0 +1,65 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// Entries also expire on their own: invalidation is per process, so other replicas pick up
+// an edit after at most this long. Attempt counters in the exercise header lag by as much.
+const exerciseCacheTTL = 5 * time.Minute
+
+// exerciseCache keeps the student view of published exercises in memory
+type exerciseCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[uuid.UUID]exerciseCacheEntry
+}
+
+type exerciseCacheEntry struct {
+	detail    *models.StudentExerciseDetailResponse
+	expiresAt time.Time
+}
+
+func newExerciseCache(ttl time.Duration) *exerciseCache {
+	return &exerciseCache{
+		ttl:     ttl,
+		entries: make(map[uuid.UUID]exerciseCacheEntry),
+	}
+}
+
+// get returns the cached exercise; callers must not modify it
+func (c *exerciseCache) get(id uuid.UUID) (*models.StudentExerciseDetailResponse, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[id]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.detail, true
+}
+
+func (c *exerciseCache) set(id uuid.UUID, detail *models.StudentExerciseDetailResponse) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Drop expired entries as we go so exercises that are no longer viewed don't pile up
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[id] = exerciseCacheEntry{detail: detail, expiresAt: now.Add(c.ttl)}
+}
+
+func (c *exerciseCache) invalidate(id uuid.UUID) {
+	c.mu.Lock()
+	delete(c.entries, id)
+	c.mu.Unlock()
+}
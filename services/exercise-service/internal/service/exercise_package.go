@@ -0,0 +1,472 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/exercisepkg"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+const maxPackageMediaBytes = 150 << 20 // Media downloaded into one zip export
+
+// Redirects are not followed: they could lead away from the storage hosts
+var packageMediaClient = &http.Client{
+	Timeout: 60 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Media uploaded to storage on import; the storage service only takes audio
+var packageAudioExtensions = map[string]bool{
+	".mp3": true, ".wav": true, ".m4a": true, ".ogg": true, ".webm": true, ".aac": true, ".flac": true,
+}
+
+// ExportExercisePackage returns the exercise, published or not, as a portable package
+func (s *ExerciseService) ExportExercisePackage(id uuid.UUID) (*exercisepkg.Package, error) {
+	detail, err := s.repo.GetExerciseForAuthor(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExerciseNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get exercise: %w", err)
+	}
+
+	tags, err := s.repo.GetExerciseTags(id)
+	if err != nil {
+		return nil, fmt.Errorf("get tags: %w", err)
+	}
+
+	pkg := buildExercisePackage(detail, tags)
+	now := time.Now().UTC()
+	pkg.ExportedAt = &now
+	return pkg, nil
+}
+
+// ExportExercisePackageZip returns the package as a zip with the media it references.
+// Media that cannot be downloaded stays a plain URL reference.
+func (s *ExerciseService) ExportExercisePackageZip(id uuid.UUID) ([]byte, *exercisepkg.Package, error) {
+	pkg, err := s.ExportExercisePackage(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := make(map[string][]byte)
+	fileForURL := make(map[string]string)
+	total := 0
+	for _, ref := range pkg.MediaRefs() {
+		url := *ref.URL
+		if _, done := fileForURL[url]; done {
+			continue
+		}
+		fileForURL[url] = ""
+
+		content, contentType, err := s.downloadPackageMedia(url, maxPackageMediaBytes-total)
+		if err != nil {
+			log.Printf("⚠️ Exercise %s export: media %s not bundled: %v", id, ref.Path, err)
+			continue
+		}
+		total += len(content)
+
+		file := fmt.Sprintf("%s%03d-%s", exercisepkg.MediaDir, len(pkg.Media)+1, mediaFileName(url))
+		fileForURL[url] = file
+		files[file] = content
+		pkg.Media = append(pkg.Media, exercisepkg.Media{URL: url, File: file, ContentType: contentType})
+	}
+
+	var buf bytes.Buffer
+	if err := exercisepkg.WriteZip(&buf, pkg, files); err != nil {
+		return nil, nil, fmt.Errorf("write zip: %w", err)
+	}
+	return buf.Bytes(), pkg, nil
+}
+
+// buildExercisePackage converts the author view of an exercise to package form
+func buildExercisePackage(detail *models.ExerciseDetailResponse, tags []models.ExerciseTag) *exercisepkg.Package {
+	e := detail.Exercise
+	pkg := &exercisepkg.Package{
+		Format:  exercisepkg.Format,
+		Version: exercisepkg.Version,
+		Exercise: exercisepkg.Exercise{
+			Title:                     e.Title,
+			Slug:                      e.Slug,
+			Description:               e.Description,
+			ExerciseType:              e.ExerciseType,
+			SkillType:                 e.SkillType,
+			IELTSTestType:             e.IELTSTestType,
+			Difficulty:                e.Difficulty,
+			IELTSLevel:                e.IELTSLevel,
+			TimeLimitMinutes:          e.TimeLimitMinutes,
+			ThumbnailURL:              e.ThumbnailURL,
+			AudioURL:                  e.AudioURL,
+			AudioDurationSeconds:      e.AudioDurationSeconds,
+			AudioTranscript:           e.AudioTranscript,
+			PassageCount:              e.PassageCount,
+			PassingScore:              e.PassingScore,
+			IsFree:                    e.IsFree,
+			WritingTaskType:           e.WritingTaskType,
+			WritingPromptText:         e.WritingPromptText,
+			WritingVisualType:         e.WritingVisualType,
+			WritingVisualURL:          e.WritingVisualURL,
+			WritingWordRequirement:    e.WritingWordRequirement,
+			SpeakingPartNumber:        e.SpeakingPartNumber,
+			SpeakingPromptText:        e.SpeakingPromptText,
+			SpeakingCueCardTopic:      e.SpeakingCueCardTopic,
+			SpeakingCueCardPoints:     e.SpeakingCueCardPoints,
+			SpeakingPreparationTime:   e.SpeakingPreparationTime,
+			SpeakingResponseTime:      e.SpeakingResponseTime,
+			SpeakingFollowUpQuestions: e.SpeakingFollowUpQuestions,
+		},
+		Sections: []exercisepkg.Section{},
+	}
+
+	for _, tag := range tags {
+		pkg.Tags = append(pkg.Tags, exercisepkg.Tag{Name: tag.Name, Slug: tag.Slug})
+	}
+
+	for _, sw := range detail.Sections {
+		sec := sw.Section
+		section := exercisepkg.Section{
+			SectionNumber:    sec.SectionNumber,
+			Title:            sec.Title,
+			Description:      sec.Description,
+			AudioURL:         sec.AudioURL,
+			AudioStartTime:   sec.AudioStartTime,
+			AudioEndTime:     sec.AudioEndTime,
+			Transcript:       sec.Transcript,
+			PassageTitle:     sec.PassageTitle,
+			PassageContent:   sec.PassageContent,
+			PassageWordCount: sec.PassageWordCount,
+			Instructions:     sec.Instructions,
+			TimeLimitMinutes: sec.TimeLimitMinutes,
+			DisplayOrder:     sec.DisplayOrder,
+			Questions:        []exercisepkg.Question{},
+		}
+
+		for _, qw := range sw.Questions {
+			q := qw.Question
+			question := exercisepkg.Question{
+				QuestionNumber: q.QuestionNumber,
+				QuestionText:   q.QuestionText,
+				QuestionType:   q.QuestionType,
+				AudioURL:       q.AudioURL,
+				ImageURL:       q.ImageURL,
+				ContextText:    q.ContextText,
+				Points:         q.Points,
+				Difficulty:     q.Difficulty,
+				Explanation:    q.Explanation,
+				Tips:           q.Tips,
+				DisplayOrder:   q.DisplayOrder,
+			}
+			for _, o := range qw.Options {
+				question.Options = append(question.Options, exercisepkg.Option{
+					Label:        o.OptionLabel,
+					Text:         o.OptionText,
+					ImageURL:     o.OptionImageURL,
+					IsCorrect:    o.IsCorrect,
+					DisplayOrder: o.DisplayOrder,
+				})
+			}
+			for _, a := range qw.Answers {
+				answer := exercisepkg.Answer{Label: a.Label, Text: a.AnswerText}
+				if a.AlternativeAnswers != nil {
+					_ = json.Unmarshal([]byte(*a.AlternativeAnswers), &answer.Alternatives)
+				}
+				question.Answers = append(question.Answers, answer)
+			}
+			section.Questions = append(section.Questions, question)
+		}
+		pkg.Sections = append(pkg.Sections, section)
+	}
+
+	return pkg
+}
+
+// ImportExercisePackage validates a package (JSON or zip) and, unless dryRun, saves it.
+// Imports are idempotent by slug: a package identical to the stored exercise is a no-op,
+// a changed one replaces the exercise's content as long as no student has attempted it.
+// Bundled audio is uploaded to storage and the package's URLs point at the new copies;
+// the hash of each uploaded file is kept so that the same file is recognised next time.
+func (s *ExerciseService) ImportExercisePackage(data []byte, dryRun bool, userID uuid.UUID) (*models.ExercisePackageImportReport, error) {
+	report := &models.ExercisePackageImportReport{
+		DryRun:   dryRun,
+		Action:   "rejected",
+		Errors:   []exercisepkg.Issue{},
+		Warnings: []exercisepkg.Issue{},
+	}
+
+	pkg, files, err := exercisepkg.Read(data)
+	if err != nil {
+		report.Errors = append(report.Errors, exercisepkg.Issue{Path: "package", Message: err.Error()})
+		return report, nil
+	}
+	report.Slug = pkg.Exercise.Slug
+	report.Counts = pkg.Counts()
+
+	errs, warnings := pkg.Validate()
+	report.Errors = append(report.Errors, errs...)
+	report.Warnings = append(report.Warnings, warnings...)
+	checkPackageMedia(pkg, files, report)
+	if len(report.Errors) > 0 {
+		return report, nil
+	}
+
+	existingID, exists, err := s.repo.GetExerciseIDBySlug(pkg.Exercise.Slug)
+	if err != nil {
+		return nil, fmt.Errorf("look up slug: %w", err)
+	}
+	// Bundled audio is compared by content: the stored exercise points at uploaded copies
+	hashes := packageAudioHashes(pkg, files)
+	storedMedia := map[string]string{}
+
+	report.Action = "create"
+	if exists {
+		report.ExerciseID = &existingID
+
+		existing, err := s.ExportExercisePackage(existingID)
+		if err != nil {
+			return nil, fmt.Errorf("load existing exercise: %w", err)
+		}
+		if storedMedia, err = s.repo.GetExercisePackageMedia(existingID); err != nil {
+			return nil, fmt.Errorf("load package media: %w", err)
+		}
+		if samePackageContent(existing, storedMedia, pkg, hashes) {
+			report.Valid = true
+			report.Action = "unchanged"
+			return report, nil
+		}
+
+		attempts, err := s.repo.CountExerciseAttempts(existingID)
+		if err != nil {
+			return nil, fmt.Errorf("count attempts: %w", err)
+		}
+		if attempts > 0 {
+			report.Action = "rejected"
+			report.Errors = append(report.Errors, exercisepkg.Issue{
+				Path:    "exercise.slug",
+				Message: fmt.Sprintf("exercise %q already has %d student attempts and cannot be replaced; import it under a new slug", pkg.Exercise.Slug, attempts),
+			})
+			return report, nil
+		}
+		report.Action = "update"
+	}
+
+	report.Valid = true
+	if dryRun {
+		return report, nil
+	}
+
+	media := s.uploadPackageMedia(pkg, files, hashes, storedMedia, userID, report)
+
+	var existing *uuid.UUID
+	if exists {
+		existing = &existingID
+	}
+	exerciseID, err := s.repo.SaveExercisePackage(pkg, existing, userID, media)
+	if err != nil {
+		return nil, fmt.Errorf("save package: %w", err)
+	}
+	report.ExerciseID = &exerciseID
+	s.exerciseCache.invalidate(exerciseID)
+
+	log.Printf("📦 Imported exercise package %q (%s): %d sections, %d questions",
+		pkg.Exercise.Slug, report.Action, report.Counts.Sections, report.Counts.Questions)
+	return report, nil
+}
+
+// checkPackageMedia reports bundled files that are missing or not referenced
+func checkPackageMedia(pkg *exercisepkg.Package, files map[string][]byte, report *models.ExercisePackageImportReport) {
+	referenced := make(map[string]bool)
+	for _, ref := range pkg.MediaRefs() {
+		referenced[*ref.URL] = true
+	}
+
+	listed := make(map[string]bool)
+	for i, m := range pkg.Media {
+		listed[m.File] = true
+		path := fmt.Sprintf("media[%d]", i)
+		if _, ok := files[m.File]; !ok {
+			report.Errors = append(report.Errors, exercisepkg.Issue{Path: path + ".file", Message: fmt.Sprintf("%s is not in the package", m.File)})
+		}
+		if !referenced[m.URL] {
+			report.Warnings = append(report.Warnings, exercisepkg.Issue{Path: path + ".url", Message: "not used by the exercise"})
+		}
+	}
+	for name := range files {
+		if !listed[name] {
+			report.Warnings = append(report.Warnings, exercisepkg.Issue{Path: "media", Message: fmt.Sprintf("%s is not listed and will be ignored", name)})
+		}
+	}
+}
+
+// packageAudioHashes returns the SHA-256 of each bundled audio file, keyed by its package URL
+func packageAudioHashes(pkg *exercisepkg.Package, files map[string][]byte) map[string]string {
+	hashes := make(map[string]string)
+	for _, m := range pkg.Media {
+		content, ok := files[m.File]
+		if !ok || !packageAudioExtensions[strings.ToLower(path.Ext(m.File))] {
+			continue
+		}
+		sum := sha256.Sum256(content)
+		hashes[m.URL] = hex.EncodeToString(sum[:])
+	}
+	return hashes
+}
+
+// uploadPackageMedia uploads bundled audio and points the package at the uploaded copies.
+// Files the exercise already has a stored copy of (storedMedia, stored URL → hash) are
+// not uploaded again. Other media keeps its original URL. Returns the stored URL and
+// hash of every copy the package now uses.
+func (s *ExerciseService) uploadPackageMedia(pkg *exercisepkg.Package, files map[string][]byte, hashes, storedMedia map[string]string, userID uuid.UUID, report *models.ExercisePackageImportReport) map[string]string {
+	storedByHash := make(map[string]string)
+	for url, hash := range storedMedia {
+		storedByHash[hash] = url
+	}
+
+	newURLs := make(map[string]string)
+	media := make(map[string]string)
+	for i, m := range pkg.Media {
+		issuePath := fmt.Sprintf("media[%d]", i)
+		hash, ok := hashes[m.URL]
+		if !ok {
+			report.Warnings = append(report.Warnings, exercisepkg.Issue{Path: issuePath, Message: "only audio is uploaded; the original URL is kept"})
+			continue
+		}
+		if url, ok := storedByHash[hash]; ok {
+			newURLs[m.URL] = url
+			media[url] = hash
+			continue
+		}
+
+		header := &multipart.FileHeader{Filename: strings.TrimPrefix(m.File, exercisepkg.MediaDir)}
+		resp, err := s.storageServiceClient.UploadAudio(userID.String(), bytes.NewReader(files[m.File]), header)
+		if err != nil {
+			report.Warnings = append(report.Warnings, exercisepkg.Issue{Path: issuePath, Message: fmt.Sprintf("upload failed, the original URL is kept: %v", err)})
+			continue
+		}
+		newURLs[m.URL] = resp.Data.AudioURL
+		media[resp.Data.AudioURL] = hash
+		report.MediaUploaded++
+	}
+
+	for _, ref := range pkg.MediaRefs() {
+		if url, ok := newURLs[*ref.URL]; ok {
+			*ref.URL = url
+		}
+	}
+	return media
+}
+
+// samePackageContent reports whether two packages describe the same exercise content.
+// Media URLs listed in a package's hashes (URL → content hash) are compared by content.
+func samePackageContent(a *exercisepkg.Package, aHashes map[string]string, b *exercisepkg.Package, bHashes map[string]string) bool {
+	return bytes.Equal(packageContentJSON(a, aHashes), packageContentJSON(b, bHashes))
+}
+
+func packageContentJSON(p *exercisepkg.Package, hashes map[string]string) []byte {
+	// Deep copy, so media URLs can be replaced without touching p
+	var c exercisepkg.Package
+	raw, _ := json.Marshal(p)
+	_ = json.Unmarshal(raw, &c)
+	for _, ref := range c.MediaRefs() {
+		if hash, ok := hashes[*ref.URL]; ok {
+			*ref.URL = "sha256:" + hash
+		}
+	}
+	c.Format, c.Version, c.ExportedAt, c.Media = "", 0, nil, nil
+
+	c.Tags = append([]exercisepkg.Tag(nil), p.Tags...)
+	sort.Slice(c.Tags, func(i, j int) bool { return c.Tags[i].Slug < c.Tags[j].Slug })
+	if len(c.Tags) == 0 {
+		c.Tags = nil
+	}
+
+	for i := range c.Sections {
+		for j := range c.Sections[i].Questions {
+			q := &c.Sections[i].Questions[j]
+			q.QuestionType = strings.ToLower(strings.TrimSpace(q.QuestionType))
+		}
+	}
+
+	content, _ := json.Marshal(c)
+	return content
+}
+
+// downloadPackageMedia fetches a media file, trying the internal storage address for
+// URLs that only resolve from outside the Docker network. Only the storage hosts are
+// contacted: media URLs are author input and must not reach internal services.
+func (s *ExerciseService) downloadPackageMedia(url string, limit int) ([]byte, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("export media limit of %d MB reached", maxPackageMediaBytes>>20)
+	}
+
+	candidates := []string{url}
+	if internal := internalAudioURL(url); internal != url {
+		candidates = append(candidates, internal)
+	}
+
+	lastErr := fmt.Errorf("not served from a storage host")
+	for _, candidate := range candidates {
+		if !s.isStorageMediaURL(candidate) {
+			continue
+		}
+		resp, err := packageMediaClient.Get(candidate)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		content, err := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+		resp.Body.Close()
+		switch {
+		case err != nil:
+			lastErr = err
+		case resp.StatusCode != http.StatusOK:
+			lastErr = fmt.Errorf("HTTP %d", resp.StatusCode)
+		case len(content) > limit:
+			lastErr = fmt.Errorf("export media limit of %d MB reached", maxPackageMediaBytes>>20)
+		default:
+			return content, resp.Header.Get("Content-Type"), nil
+		}
+	}
+	return nil, "", lastErr
+}
+
+// isStorageMediaURL reports whether an http(s) URL points at one of the storage hosts
+func (s *ExerciseService) isStorageMediaURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User != nil {
+		return false
+	}
+	return s.storageMediaHosts[strings.ToLower(u.Host)]
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// mediaFileName derives a safe zip file name from a media URL
+func mediaFileName(url string) string {
+	if idx := strings.IndexAny(url, "?#"); idx != -1 {
+		url = url[:idx]
+	}
+	name := unsafeFileChars.ReplaceAllString(path.Base(url), "_")
+	if name == "" || name == "." || name == "_" || name == "/" {
+		return "media"
+	}
+	return name
+}
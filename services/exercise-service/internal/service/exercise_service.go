@@ -1,33 +1,82 @@
 package service
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
 	aiClient "github.com/bisosad1501/ielts-platform/exercise-service/internal/client"
 	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
 	"github.com/bisosad1501/ielts-platform/exercise-service/internal/repository"
 	"github.com/google/uuid"
 )
 
+var (
+	ErrExerciseNotFound     = errors.New("exercise not found")
+	ErrSubmissionNotFound   = errors.New("submission not found")
+	ErrQuestionNotFound     = errors.New("question not found")
+	ErrAttemptExpired       = errors.New("attempt time limit has expired")
+	ErrAttemptNotInProgress = errors.New("attempt is no longer in progress")
+
+	ErrTestSessionNotFound    = errors.New("test session not found")
+	ErrInvalidTestSession     = errors.New("invalid test session")
+	ErrTestSessionClosed      = errors.New("test session is no longer in progress")
+	ErrTestSessionPartLocked  = errors.New("earlier parts of the test must be finished first")
+	ErrTestSessionPartStarted = errors.New("test session part has already been started")
+
+	ErrInvalidAssembly = errors.New("exercise cannot be assembled from the question bank")
+
+	ErrReviewSessionNotFound = errors.New("review session not found")
+	ErrReviewSessionClosed   = errors.New("review session is already completed")
+	ErrReviewItemNotFound    = errors.New("review item not found")
+	ErrReviewItemAnswered    = errors.New("review item already answered in this session")
+	ErrInvalidReviewRequest  = errors.New("invalid review request")
+	ErrNoDueReviews          = errors.New("no review items are due")
+)
+
 type ExerciseService struct {
-	repo                *repository.ExerciseRepository
-	userServiceClient   *client.UserServiceClient
-	notificationClient  *client.NotificationServiceClient
-	aiServiceClient     *aiClient.AIServiceClient // Phase 4: AI service client
+	repo                 *repository.ExerciseRepository
+	userServiceClient    *client.UserServiceClient
+	notificationClient   *client.NotificationServiceClient
+	aiServiceClient      *aiClient.AIServiceClient      // Phase 4: AI service client
 	storageServiceClient *aiClient.StorageServiceClient // For generating presigned URLs
+	storageMediaHosts    map[string]bool                // Hosts package export may download media from
+
+	// Student view of published exercises, invalidated when authors edit them
+	exerciseCache *exerciseCache
+
+	// Durable Writing/Speaking evaluation queue
+	evalQueueConfig EvaluationQueueConfig
+	evalWorkerID    string
+	evalWake        chan struct{}
+
+	// Server-side exam timer and expired/idle attempt sweeper
+	attemptTimerConfig AttemptTimerConfig
 }
 
-func NewExerciseService(repo *repository.ExerciseRepository, userServiceClient *client.UserServiceClient, notificationClient *client.NotificationServiceClient, aiServiceClient *aiClient.AIServiceClient, storageServiceClient *aiClient.StorageServiceClient) *ExerciseService {
+func NewExerciseService(repo *repository.ExerciseRepository, userServiceClient *client.UserServiceClient, notificationClient *client.NotificationServiceClient, aiServiceClient *aiClient.AIServiceClient, storageServiceClient *aiClient.StorageServiceClient, storageMediaHosts []string) *ExerciseService {
+	mediaHosts := make(map[string]bool)
+	for _, host := range storageMediaHosts {
+		mediaHosts[strings.ToLower(host)] = true
+	}
+
 	return &ExerciseService{
-		repo:                repo,
-		userServiceClient:   userServiceClient,
-		notificationClient:  notificationClient,
-		aiServiceClient:     aiServiceClient,
+		repo:                 repo,
+		userServiceClient:    userServiceClient,
+		notificationClient:   notificationClient,
+		aiServiceClient:      aiServiceClient,
 		storageServiceClient: storageServiceClient,
+		storageMediaHosts:    mediaHosts,
+		exerciseCache:        newExerciseCache(exerciseCacheTTL),
+		evalQueueConfig:      DefaultEvaluationQueueConfig(),
+		evalWorkerID:         newEvaluationWorkerID(),
+		evalWake:             make(chan struct{}, 1),
+		attemptTimerConfig:   DefaultAttemptTimerConfig(),
 	}
 }
 
@@ -44,29 +93,66 @@ func (s *ExerciseService) GetExercises(query *models.ExerciseListQuery) ([]model
 	return s.repo.GetExercises(query)
 }
 
-// GetExerciseByID returns exercise with all details
-func (s *ExerciseService) GetExerciseByID(id uuid.UUID) (*models.ExerciseDetailResponse, error) {
-	return s.repo.GetExerciseByID(id)
+// GetExerciseByID returns the student view of a published exercise (no answer key)
+func (s *ExerciseService) GetExerciseByID(id uuid.UUID) (*models.StudentExerciseDetailResponse, error) {
+	if cached, ok := s.exerciseCache.get(id); ok {
+		return cached, nil
+	}
+
+	detail, err := s.repo.GetExerciseByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	view := detail.StudentView()
+	s.exerciseCache.set(id, view)
+	return view, nil
+}
+
+// GetExerciseForAuthor returns the full exercise, including drafts and the answer key
+func (s *ExerciseService) GetExerciseForAuthor(id uuid.UUID) (*models.ExerciseDetailResponse, error) {
+	return s.repo.GetExerciseForAuthor(id)
 }
 
 // StartExercise creates a new submission for user
 func (s *ExerciseService) StartExercise(userID, exerciseID uuid.UUID, deviceType *string) (*models.UserExerciseAttempt, error) {
-	return s.repo.CreateSubmission(userID, exerciseID, deviceType)
+	submission, err := s.repo.CreateSubmission(userID, exerciseID, deviceType)
+	if err != nil {
+		return nil, err
+	}
+
+	applyAttemptTimer(submission, time.Now())
+	return submission, nil
 }
 
 // SubmitAnswers saves answers and grades the submission
 func (s *ExerciseService) SubmitAnswers(submissionID uuid.UUID, answers []models.SubmitAnswerItem, frontendTimeSpent *int) error {
+	submission, err := s.repo.GetSubmissionByID(submissionID)
+	if err != nil {
+		return err
+	}
+
+	// Reject answers after the deadline (plus grace window)
+	if err := s.openAttempt(submission); err != nil {
+		return err
+	}
+
 	// Save and grade answers
-	err := s.repo.SaveSubmissionAnswers(submissionID, answers)
+	err = s.repo.SaveSubmissionAnswers(submissionID, answers)
 	if err != nil {
 		return err
 	}
 
-	// Complete submission and calculate final score (pass frontend time_spent)
+	// Complete submission and calculate final score (pass frontend time_spent).
+	// If a concurrent request completed it first, that request records the result.
 	err = s.repo.CompleteSubmissionWithTime(submissionID, frontendTimeSpent)
+	if errors.Is(err, repository.ErrAttemptAlreadyCompleted) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
+	go s.onAttemptFinished(submissionID)
 
 	// Service-to-service integration: Update user stats and send notification
 	go func() {
@@ -81,15 +167,23 @@ func (s *ExerciseService) SubmitAnswers(submissionID uuid.UUID, answers []models
 	return nil
 }
 
-// GetSubmissionResult returns detailed results
-func (s *ExerciseService) GetSubmissionResult(submissionID uuid.UUID) (*models.SubmissionResultResponse, error) {
+// GetSubmissionResult returns detailed results of the user's own attempt.
+// The answer key is only revealed once the attempt is completed.
+func (s *ExerciseService) GetSubmissionResult(submissionID, userID uuid.UUID) (*models.SubmissionResultResponse, error) {
 	result, err := s.repo.GetSubmissionResult(submissionID)
 	if err != nil {
 		return nil, err
 	}
+	if result.Submission == nil || result.Submission.UserID != userID {
+		return nil, ErrSubmissionNotFound
+	}
+	if result.Submission.Status != "completed" {
+		result.RedactAnswerKey()
+	}
+	applyAttemptTimer(result.Submission, time.Now())
 	
 	// If submission has audio_url, convert it to API Gateway URL for frontend access
-	if result.Submission != nil && result.Submission.AudioURL != nil && *result.Submission.AudioURL != "" {
+	if result.Submission.AudioURL != nil && *result.Submission.AudioURL != "" {
 		audioURL := *result.Submission.AudioURL
 		log.Printf("📎 [GetSubmissionResult] Audio URL from DB: %s", audioURL)
 		
@@ -165,12 +259,20 @@ func (s *ExerciseService) CreateExercise(req *models.CreateExerciseRequest, crea
 
 // UpdateExercise updates exercise details (admin only)
 func (s *ExerciseService) UpdateExercise(id uuid.UUID, req *models.UpdateExerciseRequest) error {
-	return s.repo.UpdateExercise(id, req)
+	if err := s.repo.UpdateExercise(id, req); err != nil {
+		return err
+	}
+	s.exerciseCache.invalidate(id)
+	return nil
 }
 
 // DeleteExercise soft deletes exercise (admin only)
 func (s *ExerciseService) DeleteExercise(id uuid.UUID) error {
-	return s.repo.DeleteExercise(id)
+	if err := s.repo.DeleteExercise(id); err != nil {
+		return err
+	}
+	s.exerciseCache.invalidate(id)
+	return nil
 }
 
 // CheckOwnership verifies if user owns the exercise
@@ -184,7 +286,12 @@ func (s *ExerciseService) CreateSection(exerciseID uuid.UUID, req *models.Create
 	if err := s.repo.CheckExerciseOwnership(exerciseID, userID); err != nil {
 		return nil, err
 	}
-	return s.repo.CreateSection(exerciseID, req)
+	section, err := s.repo.CreateSection(exerciseID, req)
+	if err != nil {
+		return nil, err
+	}
+	s.exerciseCache.invalidate(exerciseID)
+	return section, nil
 }
 
 // CreateQuestion creates a new question
@@ -193,14 +300,26 @@ func (s *ExerciseService) CreateQuestion(req *models.CreateQuestionRequest, user
 	if err := s.repo.CheckExerciseOwnership(req.ExerciseID, userID); err != nil {
 		return nil, err
 	}
-	return s.repo.CreateQuestion(req)
+	question, err := s.repo.CreateQuestion(req)
+	if err != nil {
+		return nil, err
+	}
+	s.exerciseCache.invalidate(req.ExerciseID)
+	return question, nil
 }
 
 // CreateQuestionOption creates an option for multiple choice question
 func (s *ExerciseService) CreateQuestionOption(questionID uuid.UUID, req *models.CreateQuestionOptionRequest, userID uuid.UUID) (*models.QuestionOption, error) {
 	// Get exercise ID from question and verify ownership
 	// TODO: Add method to get exercise ID from question ID
-	return s.repo.CreateQuestionOption(questionID, req)
+	option, err := s.repo.CreateQuestionOption(questionID, req)
+	if err != nil {
+		return nil, err
+	}
+	if exerciseID, err := s.repo.GetQuestionExerciseID(questionID); err == nil {
+		s.exerciseCache.invalidate(exerciseID)
+	}
+	return option, nil
 }
 
 // CreateQuestionAnswer creates answer for text-based question
@@ -210,13 +329,68 @@ func (s *ExerciseService) CreateQuestionAnswer(questionID uuid.UUID, req *models
 	return s.repo.CreateQuestionAnswer(questionID, req)
 }
 
+// PreviewGrading scores a candidate answer against a question's answer key without saving it,
+// so authors can check which answers the grader accepts
+func (s *ExerciseService) PreviewGrading(questionID uuid.UUID, req *models.GradingPreviewRequest) (*models.GradingPreviewResponse, error) {
+	key, _, err := s.repo.GetGradingKey(questionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrQuestionNotFound
+		}
+		return nil, err
+	}
+
+	result := s.repo.GradeAnswer(key, models.SubmitAnswerItem{
+		QuestionID:        questionID,
+		SelectedOptionID:  req.SelectedOptionID,
+		SelectedOptionIDs: req.SelectedOptionIDs,
+		TextAnswer:        req.TextAnswer,
+		Matches:           req.Matches,
+	})
+
+	preview := &models.GradingPreviewResponse{
+		QuestionType:    key.QuestionType,
+		Points:          key.Points,
+		WordLimit:       key.WordLimit,
+		AcceptedAnswers: []models.PreviewAcceptedAnswer{},
+		Result:          result,
+	}
+	if req.TextAnswer != nil {
+		normalized := ielts.NormalizeAnswer(*req.TextAnswer)
+		preview.NormalizedAnswer = &normalized
+	}
+	if len(req.Matches) > 0 {
+		preview.NormalizedMatches = make(map[string]string, len(req.Matches))
+		for label, answer := range req.Matches {
+			preview.NormalizedMatches[label] = ielts.NormalizeAnswer(answer)
+		}
+	}
+	for _, answer := range key.Answers {
+		for _, text := range append([]string{answer.Text}, answer.Variations...) {
+			for _, form := range ielts.ExpandOptionalWords(text) {
+				preview.AcceptedAnswers = append(preview.AcceptedAnswers, models.PreviewAcceptedAnswer{
+					Label:      answer.Label,
+					Answer:     form,
+					Normalized: ielts.NormalizeAnswer(form),
+				})
+			}
+		}
+	}
+
+	return preview, nil
+}
+
 // PublishExercise publishes an exercise
 func (s *ExerciseService) PublishExercise(exerciseID, userID uuid.UUID) error {
 	// Verify ownership
 	if err := s.repo.CheckExerciseOwnership(exerciseID, userID); err != nil {
 		return err
 	}
-	return s.repo.PublishExercise(exerciseID)
+	if err := s.repo.PublishExercise(exerciseID); err != nil {
+		return err
+	}
+	s.exerciseCache.invalidate(exerciseID)
+	return nil
 }
 
 // UnpublishExercise unpublishes an exercise
@@ -225,7 +399,11 @@ func (s *ExerciseService) UnpublishExercise(exerciseID, userID uuid.UUID) error
 	if err := s.repo.CheckExerciseOwnership(exerciseID, userID); err != nil {
 		return err
 	}
-	return s.repo.UnpublishExercise(exerciseID)
+	if err := s.repo.UnpublishExercise(exerciseID); err != nil {
+		return err
+	}
+	s.exerciseCache.invalidate(exerciseID)
+	return nil
 }
 
 // GetAllTags returns all available tags
@@ -444,6 +622,10 @@ func (s *ExerciseService) retryFailedSyncs() {
 		}
 	}()
 
+	// Full mock tests are recorded as one combined result
+	s.retryTestSessionSyncs()
+	s.retryReviewSessionSyncs()
+
 	// Get pending syncs (limit 50 per batch)
 	submissions, err := s.repo.GetPendingSyncs(50)
 	if err != nil {
@@ -513,7 +695,8 @@ func (s *ExerciseService) retrySingleSync(
 		}
 
 		if exercise.SkillType == "listening" || exercise.SkillType == "reading" {
-			req.RawScore = &submission.CorrectAnswers
+			rawScore := listeningReadingMarks(submission)
+			req.RawScore = &rawScore
 			req.TotalQuestions = &submission.TotalQuestions
 		} else {
 			req.BandScore = bandScore
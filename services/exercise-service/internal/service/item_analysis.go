@@ -0,0 +1,278 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	minItemAnalysisAttempts = 10   // Below this, facility and discrimination are noise
+	itemAnalysisGroupShare  = 0.27 // Upper and lower groups, as in classical item analysis
+
+	easyFacility      = 0.7 // Suggested difficulty: easy from here up
+	hardFacility      = 0.3 // and hard below this
+	tooEasyFacility   = 0.9
+	tooHardFacility   = 0.2
+	lowDiscrimination = 0.2
+
+	bankDifficultyMinResponses = 30
+	bankDifficultyInterval     = time.Hour
+)
+
+// GetItemAnalysis computes per-question statistics for an exercise from students' answers
+func (s *ExerciseService) GetItemAnalysis(exerciseID uuid.UUID) (*models.ItemAnalysis, error) {
+	detail, err := s.repo.GetExerciseForAuthor(exerciseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExerciseNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get exercise: %w", err)
+	}
+
+	attemptIDs, responses, err := s.repo.GetItemAnalysisResponses(exerciseID)
+	if err != nil {
+		return nil, fmt.Errorf("get responses: %w", err)
+	}
+	links, err := s.repo.GetQuestionBankLinks(exerciseID)
+	if err != nil {
+		return nil, fmt.Errorf("get bank links: %w", err)
+	}
+
+	var questions []models.QuestionWithOptions
+	for _, section := range detail.Sections {
+		questions = append(questions, section.Questions...)
+	}
+
+	items, groupSize := computeItemStatistics(questions, attemptIDs, responses)
+	for i := range items {
+		if bankID, ok := links[items[i].QuestionID]; ok {
+			items[i].BankQuestionID = &bankID
+		}
+	}
+
+	return &models.ItemAnalysis{
+		ExerciseID: exerciseID,
+		Attempts:   len(attemptIDs),
+		GroupSize:  groupSize,
+		Items:      items,
+		ComputedAt: time.Now(),
+	}, nil
+}
+
+// computeItemStatistics scores every question of every attempt (unanswered counts as zero),
+// ranks attempts by points earned and compares the upper and lower groups.
+// Returns the statistics in question order and the size of each group.
+func computeItemStatistics(questions []models.QuestionWithOptions, attemptIDs []uuid.UUID, responses []models.ItemResponse) ([]models.ItemStatistics, int) {
+	byAttempt := make(map[uuid.UUID]map[uuid.UUID]*models.ItemResponse, len(attemptIDs))
+	for _, id := range attemptIDs {
+		byAttempt[id] = make(map[uuid.UUID]*models.ItemResponse)
+	}
+	totals := make(map[uuid.UUID]float64, len(attemptIDs))
+	for i := range responses {
+		r := &responses[i]
+		answers, ok := byAttempt[r.AttemptID]
+		if !ok {
+			continue
+		}
+		answers[r.QuestionID] = r
+		totals[r.AttemptID] += r.PointsEarned
+	}
+
+	ranked := append([]uuid.UUID(nil), attemptIDs...)
+	sort.Slice(ranked, func(i, j int) bool {
+		if totals[ranked[i]] != totals[ranked[j]] {
+			return totals[ranked[i]] > totals[ranked[j]]
+		}
+		return ranked[i].String() < ranked[j].String()
+	})
+
+	n := len(ranked)
+	enough := n >= minItemAnalysisAttempts
+	groupSize := 0
+	if enough {
+		groupSize = int(math.Round(float64(n) * itemAnalysisGroupShare))
+	}
+	upper, lower := ranked[:groupSize], ranked[n-groupSize:]
+
+	items := make([]models.ItemStatistics, 0, len(questions))
+	for _, qw := range questions {
+		q := qw.Question
+		item := models.ItemStatistics{
+			QuestionID:     q.ID,
+			QuestionNumber: q.QuestionNumber,
+			QuestionType:   q.QuestionType,
+			Difficulty:     q.Difficulty,
+			Flags:          []string{},
+		}
+
+		var times []float64
+		for _, id := range ranked {
+			r := byAttempt[id][q.ID]
+			if r == nil {
+				continue
+			}
+			item.Responses++
+			if r.TimeSpentSeconds != nil && *r.TimeSpentSeconds > 0 {
+				times = append(times, float64(*r.TimeSpentSeconds))
+			}
+		}
+		item.Omitted = n - item.Responses
+		if len(times) > 0 {
+			item.AverageTimeSeconds = roundedPtr(average(times), 1)
+		}
+
+		score := func(group []uuid.UUID) float64 {
+			var sum float64
+			for _, id := range group {
+				sum += itemScore(q, byAttempt[id][q.ID])
+			}
+			return sum / float64(len(group))
+		}
+
+		var keyedUpper, distractorUpper float64
+		keyed := false
+		for _, o := range qw.Options {
+			count, upperCount, lowerCount := 0, 0, 0
+			for i, id := range ranked {
+				if !selectedOption(byAttempt[id][q.ID], o.ID) {
+					continue
+				}
+				count++
+				if i < groupSize {
+					upperCount++
+				}
+				if i >= n-groupSize {
+					lowerCount++
+				}
+			}
+			stat := models.OptionStatistics{OptionID: o.ID, Label: o.OptionLabel, IsCorrect: o.IsCorrect, Count: count}
+			if n > 0 {
+				stat.Rate = round(float64(count)/float64(n), 3)
+			}
+			if groupSize > 0 {
+				upperRate, lowerRate := float64(upperCount)/float64(groupSize), float64(lowerCount)/float64(groupSize)
+				stat.UpperRate, stat.LowerRate = roundedPtr(upperRate, 3), roundedPtr(lowerRate, 3)
+				if o.IsCorrect {
+					keyed = true
+					keyedUpper = math.Max(keyedUpper, upperRate)
+				} else {
+					distractorUpper = math.Max(distractorUpper, upperRate)
+				}
+			}
+			item.Options = append(item.Options, stat)
+		}
+
+		if !enough {
+			items = append(items, item)
+			continue
+		}
+
+		facility := score(ranked)
+		discrimination := score(upper) - score(lower)
+		item.Facility = roundedPtr(facility, 3)
+		item.Discrimination = roundedPtr(discrimination, 3)
+
+		suggested := "medium"
+		switch {
+		case facility >= easyFacility:
+			suggested = "easy"
+		case facility < hardFacility:
+			suggested = "hard"
+		}
+		item.SuggestedDifficulty = &suggested
+
+		// Strong students doing worse than weak ones, or preferring a distractor over
+		// the keyed option, usually means the key is wrong
+		if discrimination < 0 || (keyed && distractorUpper > keyedUpper) {
+			item.Flags = append(item.Flags, "possible_miskey")
+		} else if discrimination < lowDiscrimination {
+			item.Flags = append(item.Flags, "low_discrimination")
+		}
+		switch {
+		case facility > tooEasyFacility:
+			item.Flags = append(item.Flags, "too_easy")
+		case facility < tooHardFacility:
+			item.Flags = append(item.Flags, "too_hard")
+		}
+
+		items = append(items, item)
+	}
+	return items, groupSize
+}
+
+// itemScore is the share of the question's points earned, 0 when unanswered
+func itemScore(q *models.Question, r *models.ItemResponse) float64 {
+	switch {
+	case r == nil:
+		return 0
+	case q.Points > 0:
+		return math.Max(0, math.Min(r.PointsEarned/q.Points, 1))
+	case r.IsCorrect:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func selectedOption(r *models.ItemResponse, optionID uuid.UUID) bool {
+	if r == nil {
+		return false
+	}
+	if r.SelectedOptionID != nil && *r.SelectedOptionID == optionID {
+		return true
+	}
+	for _, id := range r.SelectedOptions {
+		if id == optionID {
+			return true
+		}
+	}
+	return false
+}
+
+func round(v float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(v*scale) / scale
+}
+
+func roundedPtr(v float64, places int) *float64 {
+	rounded := round(v, places)
+	return &rounded
+}
+
+// StartBankDifficultyWorker periodically feeds item facility back into question bank difficulty
+func (s *ExerciseService) StartBankDifficultyWorker() {
+	ticker := time.NewTicker(bankDifficultyInterval)
+	defer ticker.Stop()
+
+	log.Printf("🔄 Started question bank difficulty worker (checking every %v)", bankDifficultyInterval)
+
+	for {
+		s.refreshBankDifficulty()
+		<-ticker.C
+	}
+}
+
+func (s *ExerciseService) refreshBankDifficulty() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ PANIC in refreshBankDifficulty: %v", r)
+		}
+	}()
+
+	updated, err := s.repo.RefreshBankDifficulty(bankDifficultyMinResponses, easyFacility, hardFacility)
+	if err != nil {
+		log.Printf("⚠️ Failed to refresh question bank difficulty: %v", err)
+		return
+	}
+	if updated > 0 {
+		log.Printf("📊 Updated difficulty of %d question bank items from student answers", updated)
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestComputeItemStatistics(t *testing.T) {
+	choice := func(number int) (models.QuestionWithOptions, uuid.UUID, uuid.UUID) {
+		a, b := uuid.New(), uuid.New()
+		return models.QuestionWithOptions{
+			Question: &models.Question{ID: uuid.New(), QuestionNumber: number, QuestionType: "multiple_choice", Points: 1},
+			Options: []models.QuestionOption{
+				{ID: a, OptionLabel: "A", IsCorrect: true},
+				{ID: b, OptionLabel: "B"},
+			},
+		}, a, b
+	}
+	q1, q1A, q1B := choice(1)
+	q2, q2A, q2B := choice(2) // Keyed A, but the strongest students choose B
+	q3 := models.QuestionWithOptions{Question: &models.Question{ID: uuid.New(), QuestionNumber: 3, QuestionType: "short_answer", Points: 1}}
+	questions := []models.QuestionWithOptions{q1, q2, q3}
+
+	thirty := 30
+	answer := func(attempt uuid.UUID, q models.QuestionWithOptions, option *uuid.UUID, correct bool) models.ItemResponse {
+		r := models.ItemResponse{AttemptID: attempt, QuestionID: q.Question.ID, IsCorrect: correct, SelectedOptionID: option, TimeSpentSeconds: &thirty}
+		if correct {
+			r.PointsEarned = 1
+		}
+		return r
+	}
+
+	var attempts []uuid.UUID
+	var responses []models.ItemResponse
+	for i := 0; i < 10; i++ {
+		id := uuid.New()
+		attempts = append(attempts, id)
+		strong := i < 5
+		if strong {
+			responses = append(responses,
+				answer(id, q1, &q1A, true), answer(id, q2, &q2B, false), answer(id, q3, nil, true))
+		} else {
+			responses = append(responses, answer(id, q1, &q1B, false), answer(id, q2, &q2A, true))
+			if i != 9 {
+				responses = append(responses, answer(id, q3, nil, false))
+			}
+		}
+	}
+
+	items, groupSize := computeItemStatistics(questions, attempts, responses)
+	if groupSize != 3 || len(items) != 3 {
+		t.Fatalf("groupSize = %d, items = %d", groupSize, len(items))
+	}
+
+	check := func(item models.ItemStatistics, facility, discrimination float64, flags []string) {
+		t.Helper()
+		if item.Facility == nil || *item.Facility != facility {
+			t.Errorf("question %d facility = %v, want %v", item.QuestionNumber, item.Facility, facility)
+		}
+		if item.Discrimination == nil || *item.Discrimination != discrimination {
+			t.Errorf("question %d discrimination = %v, want %v", item.QuestionNumber, item.Discrimination, discrimination)
+		}
+		if !reflect.DeepEqual(item.Flags, flags) {
+			t.Errorf("question %d flags = %v, want %v", item.QuestionNumber, item.Flags, flags)
+		}
+	}
+	check(items[0], 0.5, 1, []string{})
+	check(items[1], 0.5, -1, []string{"possible_miskey"})
+	check(items[2], 0.5, 1, []string{})
+
+	if items[2].Omitted != 1 || items[2].Responses != 9 {
+		t.Errorf("q3 responses = %d, omitted = %d", items[2].Responses, items[2].Omitted)
+	}
+	if avg := items[0].AverageTimeSeconds; avg == nil || *avg != 30 {
+		t.Errorf("q1 average time = %v", avg)
+	}
+	a := items[0].Options[0]
+	if a.Count != 5 || a.Rate != 0.5 || *a.UpperRate != 1 || *a.LowerRate != 0 {
+		t.Errorf("q1 option A = %+v", a)
+	}
+	if s := items[0].SuggestedDifficulty; s == nil || *s != "medium" {
+		t.Errorf("q1 suggested difficulty = %v", s)
+	}
+
+	// Too few students: counts only
+	items, groupSize = computeItemStatistics(questions, attempts[:3], responses[:9])
+	if groupSize != 0 || items[0].Facility != nil || items[0].Discrimination != nil || items[0].Responses != 3 {
+		t.Errorf("small sample = %d, %+v", groupSize, items[0])
+	}
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/exercisepkg"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/grading"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// AssembleExercise builds an unpublished exercise from question bank items. Each section takes
+// its listed bank questions, then draws by criteria; a bank question is used at most once per
+// exercise and each one used has its times_used incremented.
+func (s *ExerciseService) AssembleExercise(req *models.AssembleExerciseRequest, userID uuid.UUID) (*models.AssembleExerciseResponse, error) {
+	if req.SkillType != "listening" && req.SkillType != "reading" {
+		return nil, fmt.Errorf("%w: only listening and reading exercises are assembled from bank questions", ErrInvalidAssembly)
+	}
+	if _, exists, err := s.repo.GetExerciseIDBySlug(req.Slug); err != nil {
+		return nil, fmt.Errorf("look up slug: %w", err)
+	} else if exists {
+		return nil, fmt.Errorf("%w: slug %q is already used", ErrInvalidAssembly, req.Slug)
+	}
+
+	picked, problems, err := s.pickBankQuestions(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAssembly, strings.Join(problems, "; "))
+	}
+
+	pkg, bankIDs, problems := buildAssembledPackage(req, picked)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAssembly, strings.Join(problems, "; "))
+	}
+
+	exerciseID, err := s.repo.CreateExerciseFromBank(pkg, bankIDs, userID)
+	if err != nil {
+		return nil, fmt.Errorf("save exercise: %w", err)
+	}
+
+	detail, err := s.repo.GetExerciseForAuthor(exerciseID)
+	if err != nil {
+		return nil, fmt.Errorf("load exercise: %w", err)
+	}
+	return &models.AssembleExerciseResponse{Exercise: detail, BankQuestionIDs: bankIDs}, nil
+}
+
+// pickBankQuestions resolves each section's bank questions, explicit IDs first.
+// Problems are reported for the request, err only for database failures.
+func (s *ExerciseService) pickBankQuestions(req *models.AssembleExerciseRequest) ([][]models.QuestionBank, []string, error) {
+	var problems []string
+	var explicitIDs []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for i, section := range req.Sections {
+		if len(section.QuestionIDs) == 0 && len(section.Draw) == 0 {
+			problems = append(problems, fmt.Sprintf("sections[%d]: needs question_ids or draw criteria", i))
+		}
+		for _, id := range section.QuestionIDs {
+			if seen[id] {
+				problems = append(problems, fmt.Sprintf("sections[%d]: bank question %s is listed twice", i, id))
+				continue
+			}
+			seen[id] = true
+			explicitIDs = append(explicitIDs, id)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, problems, nil
+	}
+
+	byID := make(map[uuid.UUID]models.QuestionBank)
+	if len(explicitIDs) > 0 {
+		found, err := s.repo.GetBankQuestionsByIDs(explicitIDs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get bank questions: %w", err)
+		}
+		for _, q := range found {
+			byID[q.ID] = q
+		}
+	}
+
+	used := append([]uuid.UUID{}, explicitIDs...)
+	picked := make([][]models.QuestionBank, len(req.Sections))
+	for i, section := range req.Sections {
+		for _, id := range section.QuestionIDs {
+			q, ok := byID[id]
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("sections[%d]: bank question %s not found", i, id))
+			case q.SkillType != req.SkillType:
+				problems = append(problems, fmt.Sprintf("sections[%d]: bank question %s is a %s question", i, id, q.SkillType))
+			default:
+				picked[i] = append(picked[i], q)
+			}
+		}
+
+		for j := range section.Draw {
+			criteria := &section.Draw[j]
+			drawn, err := s.repo.DrawBankQuestions(req.SkillType, criteria, used, criteria.Count)
+			if err != nil {
+				return nil, nil, fmt.Errorf("draw bank questions: %w", err)
+			}
+			if len(drawn) < criteria.Count {
+				problems = append(problems, fmt.Sprintf("sections[%d].draw[%d]: only %d of %d %s questions match",
+					i, j, len(drawn), criteria.Count, criteria.QuestionType))
+			}
+			for _, q := range drawn {
+				used = append(used, q.ID)
+			}
+			picked[i] = append(picked[i], drawn...)
+		}
+	}
+	return picked, problems, nil
+}
+
+// buildAssembledPackage lays the picked bank questions out as a package, numbering
+// questions across the whole exercise, and checks it like an imported package
+func buildAssembledPackage(req *models.AssembleExerciseRequest, picked [][]models.QuestionBank) (*exercisepkg.Package, []uuid.UUID, []string) {
+	exerciseType := req.ExerciseType
+	if exerciseType == "" {
+		exerciseType = "practice"
+	}
+	difficulty := req.Difficulty
+	if difficulty == "" {
+		difficulty = "medium"
+	}
+
+	pkg := &exercisepkg.Package{
+		Format:  exercisepkg.Format,
+		Version: exercisepkg.Version,
+		Exercise: exercisepkg.Exercise{
+			Title:            req.Title,
+			Slug:             req.Slug,
+			Description:      req.Description,
+			ExerciseType:     exerciseType,
+			SkillType:        req.SkillType,
+			IELTSTestType:    req.IELTSTestType,
+			Difficulty:       difficulty,
+			TimeLimitMinutes: req.TimeLimitMinutes,
+			AudioURL:         req.AudioURL,
+		},
+	}
+
+	var problems []string
+	var bankIDs []uuid.UUID
+	number := 0
+	for i, sectionReq := range req.Sections {
+		section := exercisepkg.Section{
+			SectionNumber:  i + 1,
+			Title:          sectionReq.Title,
+			Instructions:   sectionReq.Instructions,
+			PassageTitle:   sectionReq.PassageTitle,
+			PassageContent: sectionReq.PassageContent,
+			AudioURL:       sectionReq.AudioURL,
+			DisplayOrder:   i + 1,
+		}
+		for _, bq := range picked[i] {
+			number++
+			question, err := bankQuestionToPackage(&bq, number)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("bank question %s: %v", bq.ID, err))
+				continue
+			}
+			section.Questions = append(section.Questions, question)
+			bankIDs = append(bankIDs, bq.ID)
+		}
+		pkg.Sections = append(pkg.Sections, section)
+	}
+	if len(problems) > 0 {
+		return nil, nil, problems
+	}
+
+	errs, _ := pkg.Validate()
+	for _, issue := range errs {
+		problems = append(problems, fmt.Sprintf("%s: %s", issue.Path, issue.Message))
+	}
+	return pkg, bankIDs, problems
+}
+
+// bankAnswerData is the answer_data of a bank question. Options are strings (labelled A, B, C...)
+// or objects; correct answers are option labels or texts, accepted text answers, or "A-iv"
+// pairs for matching questions.
+type bankAnswerData struct {
+	Options          []bankOption `json:"options"`
+	CorrectAnswer    stringList   `json:"correct_answer"`
+	CorrectAnswers   stringList   `json:"correct_answers"`
+	Alternatives     stringList   `json:"alternatives"`
+	AnswerVariations stringList   `json:"answer_variations"`
+	Explanation      *string      `json:"explanation"`
+	Tips             *string      `json:"tips"`
+	Points           *float64     `json:"points"`
+}
+
+type bankOption struct {
+	Label     string  `json:"label"`
+	Text      string  `json:"text"`
+	ImageURL  *string `json:"image_url"`
+	IsCorrect bool    `json:"is_correct"`
+}
+
+func (o *bankOption) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*o = bankOption{Text: text}
+		return nil
+	}
+	type plain bankOption
+	return json.Unmarshal(data, (*plain)(o))
+}
+
+// stringList accepts a single value or a list; numbers and booleans become their text
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var values []interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		values = []interface{}{value}
+	}
+	*l = nil
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		if text := strings.TrimSpace(fmt.Sprint(v)); text != "" {
+			*l = append(*l, text)
+		}
+	}
+	return nil
+}
+
+var matchingTypes = map[string]bool{
+	grading.TypeMatching: true, grading.TypeMatchingHeadings: true, grading.TypeMatchingFeatures: true,
+	grading.TypeMatchingInformation: true, grading.TypeMatchingSentenceEnds: true,
+}
+
+var matchingPair = regexp.MustCompile(`^([A-Za-z0-9]+)\s*[-:=]\s*(\S.*)$`)
+
+// bankQuestionToPackage converts a bank question and its answer_data to an exercise question
+func bankQuestionToPackage(bq *models.QuestionBank, number int) (exercisepkg.Question, error) {
+	questionType := strings.ToLower(strings.TrimSpace(bq.QuestionType))
+	question := exercisepkg.Question{
+		QuestionNumber: number,
+		QuestionText:   bq.QuestionText,
+		QuestionType:   questionType,
+		AudioURL:       bq.AudioURL,
+		ImageURL:       bq.ImageURL,
+		ContextText:    bq.ContextText,
+		Points:         1,
+		Difficulty:     bq.Difficulty,
+		DisplayOrder:   number,
+	}
+
+	var data bankAnswerData
+	if strings.TrimSpace(bq.AnswerData) != "" {
+		if err := json.Unmarshal([]byte(bq.AnswerData), &data); err != nil {
+			return question, fmt.Errorf("answer_data: %v", err)
+		}
+	}
+	question.Explanation = data.Explanation
+	question.Tips = data.Tips
+	if data.Points != nil && *data.Points > 0 {
+		question.Points = *data.Points
+	}
+
+	correct := append(append(stringList{}, data.CorrectAnswer...), data.CorrectAnswers...)
+
+	markedCorrect := false
+	for i, o := range data.Options {
+		label := strings.TrimSpace(o.Label)
+		if label == "" {
+			label = string(rune('A' + i))
+		}
+		isCorrect := o.IsCorrect
+		for _, c := range correct {
+			if strings.EqualFold(c, label) || strings.EqualFold(c, strings.TrimSpace(o.Text)) {
+				isCorrect = true
+			}
+		}
+		markedCorrect = markedCorrect || isCorrect
+		question.Options = append(question.Options, exercisepkg.Option{
+			Label:        label,
+			Text:         o.Text,
+			ImageURL:     o.ImageURL,
+			IsCorrect:    isCorrect,
+			DisplayOrder: i + 1,
+		})
+	}
+
+	isChoice := questionType == grading.TypeMultipleChoice || questionType == grading.TypeMultipleChoiceMultiple
+	switch {
+	case isChoice && len(question.Options) == 0:
+		return question, fmt.Errorf("a %s question needs options in answer_data", questionType)
+	case isChoice || markedCorrect:
+		if !markedCorrect {
+			return question, fmt.Errorf("no option matches the correct answer")
+		}
+		return question, nil
+	case len(correct) == 0:
+		return question, fmt.Errorf("answer_data has no correct answer")
+	}
+
+	if matchingTypes[questionType] && allMatchingPairs(correct) {
+		for _, c := range correct {
+			pair := matchingPair.FindStringSubmatch(c)
+			label := pair[1]
+			question.Answers = append(question.Answers, exercisepkg.Answer{Label: &label, Text: strings.TrimSpace(pair[2])})
+		}
+		return question, nil
+	}
+
+	for _, c := range correct {
+		question.Answers = append(question.Answers, exercisepkg.Answer{Text: c})
+	}
+	question.Answers[0].Alternatives = append(append([]string(nil), data.Alternatives...), data.AnswerVariations...)
+	return question, nil
+}
+
+func allMatchingPairs(answers []string) bool {
+	for _, a := range answers {
+		if !matchingPair.MatchString(a) {
+			return false
+		}
+	}
+	return true
+}
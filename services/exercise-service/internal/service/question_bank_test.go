@@ -0,0 +1,113 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/exercisepkg"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+)
+
+func TestBankQuestionToPackage(t *testing.T) {
+	label := func(s string) *string { return &s }
+
+	tests := []struct {
+		name         string
+		questionType string
+		answerData   string
+		wantCorrect  []string // Labels of correct options
+		wantAnswers  []exercisepkg.Answer
+		wantPoints   float64
+		wantErr      string
+	}{
+		{
+			name:         "choice from string options",
+			questionType: "multiple_choice",
+			answerData:   `{"options":["Rooftops","Parks","Rivers"],"correct_answer":["A"],"points":2}`,
+			wantCorrect:  []string{"A"},
+			wantPoints:   2,
+		},
+		{
+			name:         "choice matched by option text",
+			questionType: "Multiple_Choice",
+			answerData:   `{"options":[{"label":"A","text":"Parks"},{"label":"B","text":"Rooftops"}],"correct_answer":"rooftops"}`,
+			wantCorrect:  []string{"B"},
+			wantPoints:   1,
+		},
+		{
+			name:         "choice without options",
+			questionType: "multiple_choice",
+			answerData:   `{"correct_answer":["A"]}`,
+			wantErr:      "needs options",
+		},
+		{
+			name:         "judgement as text answer",
+			questionType: "true_false_not_given",
+			answerData:   `{"correct_answer":["True"],"explanation":"Paragraph 2"}`,
+			wantAnswers:  []exercisepkg.Answer{{Text: "True"}},
+			wantPoints:   1,
+		},
+		{
+			name:         "matching pairs become labelled answers",
+			questionType: "matching",
+			answerData:   `{"correct_answer":["A-1","B-2","C - iv"]}`,
+			wantAnswers: []exercisepkg.Answer{
+				{Label: label("A"), Text: "1"}, {Label: label("B"), Text: "2"}, {Label: label("C"), Text: "iv"},
+			},
+			wantPoints: 1,
+		},
+		{
+			name:         "completion with variations",
+			questionType: "sentence_completion",
+			answerData:   `{"correct_answer":"recycled","answer_variations":["re-used","reused"]}`,
+			wantAnswers:  []exercisepkg.Answer{{Text: "recycled", Alternatives: []string{"re-used", "reused"}}},
+			wantPoints:   1,
+		},
+		{
+			name:         "hyphenated completion answer is not a pair",
+			questionType: "fill_in_blank",
+			answerData:   `{"correct_answer":["well-known"]}`,
+			wantAnswers:  []exercisepkg.Answer{{Text: "well-known"}},
+			wantPoints:   1,
+		},
+		{
+			name:         "missing key",
+			questionType: "short_answer",
+			answerData:   `{"explanation":"none"}`,
+			wantErr:      "no correct answer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bq := &models.QuestionBank{QuestionText: "Q", QuestionType: tt.questionType, AnswerData: tt.answerData}
+			q, err := bankQuestionToPackage(bq, 7)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var correct []string
+			for _, o := range q.Options {
+				if o.IsCorrect {
+					correct = append(correct, o.Label)
+				}
+			}
+			if !reflect.DeepEqual(correct, tt.wantCorrect) {
+				t.Errorf("correct options = %v, want %v", correct, tt.wantCorrect)
+			}
+			if !reflect.DeepEqual(q.Answers, tt.wantAnswers) {
+				t.Errorf("answers = %+v, want %+v", q.Answers, tt.wantAnswers)
+			}
+			if q.Points != tt.wantPoints || q.QuestionNumber != 7 || q.QuestionType != strings.ToLower(tt.questionType) {
+				t.Errorf("question = %+v", q)
+			}
+		})
+	}
+}
@@ -37,7 +37,6 @@ func AIServiceRetryConfig() RetryConfig {
 // RetryWithBackoff executes fn with exponential backoff retry
 func RetryWithBackoff(config RetryConfig, fn func() error) error {
 	var lastErr error
-	delay := config.InitialDelay
 
 	for attempt := 1; attempt <= config.MaxAttempts; attempt++ {
 		err := fn()
@@ -49,20 +48,28 @@ func RetryWithBackoff(config RetryConfig, fn func() error) error {
 		log.Printf("⚠️ Attempt %d/%d failed: %v", attempt, config.MaxAttempts, err)
 
 		if attempt < config.MaxAttempts {
+			delay := config.BackoffDelay(attempt)
 			log.Printf("⏳ Retrying in %v...", delay)
 			time.Sleep(delay)
-
-			// Calculate next delay with exponential backoff
-			delay = time.Duration(float64(delay) * config.Multiplier)
-			if delay > config.MaxDelay {
-				delay = config.MaxDelay
-			}
 		}
 	}
 
 	return fmt.Errorf("failed after %d attempts: %w", config.MaxAttempts, lastErr)
 }
 
+// BackoffDelay returns the delay to wait after the given failed attempt (1-based),
+// growing by Multiplier from InitialDelay and capped at MaxDelay
+func (c RetryConfig) BackoffDelay(attempt int) time.Duration {
+	delay := c.InitialDelay
+	for i := 1; i < attempt; i++ {
+		delay = time.Duration(float64(delay) * c.Multiplier)
+		if delay > c.MaxDelay {
+			return c.MaxDelay
+		}
+	}
+	return delay
+}
+
 // IsRetryableError checks if an error should trigger a retry
 func IsRetryableError(err error) bool {
 	if err == nil {
@@ -0,0 +1,432 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/grading"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultReviewSessionSize = 20
+	maxReviewSessionSize     = 50
+
+	// SM-2 (SuperMemo 2) parameters
+	minEaseFactor      = 1.3
+	passingQuality     = 3  // Below this the item is relearned from the start
+	masteredAfterDays  = 60 // An item this far apart is considered learned
+	partialCreditScore = 0.5
+)
+
+// reviewSchedule is the SM-2 state of a review item
+type reviewSchedule struct {
+	EaseFactor   float64
+	IntervalDays int
+	Repetitions  int
+	Lapses       int
+}
+
+// reviewQuality maps a graded answer to SM-2 response quality (0-5). A correct answer is 4,
+// or 5 when it came easily and 3 when it was hard; half or more of a multi-part question
+// is 2 and anything less is 1.
+func reviewQuality(result grading.Result, confidence string) int {
+	switch {
+	case result.Correct && confidence == "easy":
+		return 5
+	case result.Correct && confidence == "hard":
+		return 3
+	case result.Correct:
+		return 4
+	case result.Score >= partialCreditScore:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// nextReviewSchedule applies one SM-2 review of the given quality
+func nextReviewSchedule(s reviewSchedule, quality int) reviewSchedule {
+	if quality >= passingQuality {
+		switch s.Repetitions {
+		case 0:
+			s.IntervalDays = 1
+		case 1:
+			s.IntervalDays = 6
+		default:
+			s.IntervalDays = int(math.Round(float64(s.IntervalDays) * s.EaseFactor))
+		}
+		s.Repetitions++
+	} else {
+		s.Repetitions = 0
+		s.IntervalDays = 1
+		s.Lapses++
+	}
+
+	miss := float64(5 - quality)
+	s.EaseFactor = math.Max(minEaseFactor, round(s.EaseFactor+0.1-miss*(0.08+miss*0.02), 2))
+	return s
+}
+
+// collectMistakes adds the questions a student got wrong in a completed attempt to their reviews
+func (s *ExerciseService) collectMistakes(attemptID uuid.UUID) {
+	added, err := s.repo.CollectReviewItems(attemptID)
+	if err != nil {
+		log.Printf("⚠️ Failed to collect mistakes of attempt %s: %v", attemptID, err)
+		return
+	}
+	if added > 0 {
+		log.Printf("📒 Added %d missed questions of attempt %s to review", added, attemptID)
+	}
+}
+
+// GetReviewSummary returns how many of the student's review items are due
+func (s *ExerciseService) GetReviewSummary(userID uuid.UUID) (*models.ReviewSummary, error) {
+	return s.repo.GetReviewSummary(userID, time.Now())
+}
+
+// GetReviewItems returns a page of the student's review items
+func (s *ExerciseService) GetReviewItems(userID uuid.UUID, query *models.ReviewItemListQuery) ([]models.ReviewItem, int, error) {
+	if query.Status == "" {
+		query.Status = "active"
+	}
+	if query.Status != "active" && query.Status != "mastered" && query.Status != "archived" {
+		return nil, 0, fmt.Errorf("%w: status must be active, mastered or archived", ErrInvalidReviewRequest)
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	return s.repo.GetReviewItems(userID, query.Status, query.Limit, (query.Page-1)*query.Limit)
+}
+
+// ArchiveReviewItem stops reviewing an item; it is not collected again
+func (s *ExerciseService) ArchiveReviewItem(userID, itemID uuid.UUID) error {
+	err := s.repo.ArchiveReviewItem(userID, itemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrReviewItemNotFound
+	}
+	return err
+}
+
+// CreateReviewSession starts a review of the student's due items, or returns the session
+// they already have in progress
+func (s *ExerciseService) CreateReviewSession(userID uuid.UUID, req *models.CreateReviewSessionRequest) (*models.ReviewSession, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultReviewSessionSize
+	}
+	if limit < 1 || limit > maxReviewSessionSize {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidReviewRequest, maxReviewSessionSize)
+	}
+	if req.SkillType != nil && *req.SkillType != "listening" && *req.SkillType != "reading" {
+		return nil, fmt.Errorf("%w: skill_type must be listening or reading", ErrInvalidReviewRequest)
+	}
+
+	sessionID, ok, err := s.repo.GetOpenReviewSessionID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("get open review session: %w", err)
+	}
+	if !ok {
+		sessionID, ok, err = s.repo.CreateReviewSession(userID, req.SkillType, limit, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("create review session: %w", err)
+		}
+		if !ok {
+			return nil, ErrNoDueReviews
+		}
+	}
+
+	return s.GetReviewSession(userID, sessionID)
+}
+
+// GetReviewSession returns one of the student's review sessions with its questions
+func (s *ExerciseService) GetReviewSession(userID, sessionID uuid.UUID) (*models.ReviewSession, error) {
+	session, err := s.getOwnReviewSession(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Questions are shown as in their exercise, from the cached student view
+	exercises := make(map[uuid.UUID]*models.StudentExerciseDetailResponse)
+	for i := range session.Items {
+		item := &session.Items[i]
+		exercise, seen := exercises[item.ExerciseID]
+		if !seen {
+			exercise, err = s.GetExerciseByID(item.ExerciseID)
+			if err != nil {
+				log.Printf("⚠️ Failed to load exercise %s for review session %s: %v", item.ExerciseID, sessionID, err)
+			}
+			exercises[item.ExerciseID] = exercise
+		}
+		if exercise == nil {
+			continue
+		}
+		for _, section := range exercise.Sections {
+			for j := range section.Questions {
+				if section.Questions[j].Question.ID == item.QuestionID {
+					item.Section = section.Section
+					item.Question = &section.Questions[j]
+				}
+			}
+		}
+	}
+	return session, nil
+}
+
+func (s *ExerciseService) getOwnReviewSession(userID, sessionID uuid.UUID) (*models.ReviewSession, error) {
+	session, err := s.repo.GetReviewSession(sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReviewSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get review session: %w", err)
+	}
+	if session.UserID != userID {
+		return nil, ErrReviewSessionNotFound
+	}
+	return session, nil
+}
+
+// AnswerReviewItem grades an answer to one item of a review session and reschedules the item
+func (s *ExerciseService) AnswerReviewItem(userID, sessionID, itemID uuid.UUID, req *models.ReviewAnswerRequest) (*models.ReviewAnswerResponse, error) {
+	if req.Confidence != "" && req.Confidence != "easy" && req.Confidence != "good" && req.Confidence != "hard" {
+		return nil, fmt.Errorf("%w: confidence must be easy, good or hard", ErrInvalidReviewRequest)
+	}
+
+	session, err := s.getOwnReviewSession(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != "in_progress" {
+		return nil, ErrReviewSessionClosed
+	}
+	var sessionItem *models.ReviewSessionItem
+	for i := range session.Items {
+		if session.Items[i].ReviewItemID == itemID {
+			sessionItem = &session.Items[i]
+		}
+	}
+	if sessionItem == nil {
+		return nil, ErrReviewItemNotFound
+	}
+	if sessionItem.AnsweredAt != nil {
+		return nil, ErrReviewItemAnswered
+	}
+
+	item, err := s.repo.GetReviewItem(itemID)
+	if err != nil {
+		return nil, fmt.Errorf("get review item: %w", err)
+	}
+	key, _, err := s.repo.GetGradingKey(item.QuestionID)
+	if err != nil {
+		return nil, fmt.Errorf("get grading key: %w", err)
+	}
+
+	result := s.repo.GradeAnswer(key, models.SubmitAnswerItem{
+		QuestionID:        item.QuestionID,
+		SelectedOptionID:  req.SelectedOptionID,
+		SelectedOptionIDs: req.SelectedOptionIDs,
+		TextAnswer:        req.TextAnswer,
+		Matches:           req.Matches,
+	})
+	quality := reviewQuality(result, req.Confidence)
+
+	next := nextReviewSchedule(reviewSchedule{
+		EaseFactor:   item.EaseFactor,
+		IntervalDays: item.IntervalDays,
+		Repetitions:  item.Repetitions,
+		Lapses:       item.Lapses,
+	}, quality)
+
+	now := time.Now()
+	item.EaseFactor, item.IntervalDays, item.Repetitions, item.Lapses = next.EaseFactor, next.IntervalDays, next.Repetitions, next.Lapses
+	item.DueAt = now.AddDate(0, 0, next.IntervalDays)
+	item.LastReviewedAt = &now
+	item.LastResult = &result.Correct
+	item.Status = "active"
+	if next.IntervalDays >= masteredAfterDays {
+		item.Status = "mastered"
+	}
+
+	score := round(result.Score, 3)
+	completed, err := s.repo.RecordReviewAnswer(sessionID, item, &models.ReviewSessionItem{
+		IsCorrect:        &result.Correct,
+		Score:            &score,
+		Quality:          &quality,
+		TimeSpentSeconds: req.TimeSpentSeconds,
+		AnsweredAt:       &now,
+	})
+	if errors.Is(err, repository.ErrReviewItemAnswered) {
+		return nil, ErrReviewItemAnswered
+	}
+	if err != nil {
+		return nil, err
+	}
+	if completed {
+		go s.recordReviewSession(sessionID)
+	}
+
+	explanation, err := s.repo.GetQuestionExplanation(item.QuestionID)
+	if err != nil {
+		log.Printf("⚠️ Failed to get explanation of question %s: %v", item.QuestionID, err)
+	}
+
+	return &models.ReviewAnswerResponse{
+		ReviewItemID:     itemID,
+		Result:           result,
+		Quality:          quality,
+		Explanation:      explanation,
+		Status:           item.Status,
+		IntervalDays:     item.IntervalDays,
+		NextDueAt:        item.DueAt,
+		SessionCompleted: completed,
+	}, nil
+}
+
+// CompleteReviewSession ends a session before every item is answered; the rest stay due
+func (s *ExerciseService) CompleteReviewSession(userID, sessionID uuid.UUID) (*models.ReviewSession, error) {
+	if _, err := s.getOwnReviewSession(userID, sessionID); err != nil {
+		return nil, err
+	}
+
+	completed, err := s.repo.CompleteReviewSession(sessionID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("complete review session: %w", err)
+	}
+	if completed {
+		go s.recordReviewSession(sessionID)
+	}
+	return s.GetReviewSession(userID, sessionID)
+}
+
+// recordReviewSession records a finished review session in User Service as one practice
+// activity per skill
+func (s *ExerciseService) recordReviewSession(sessionID uuid.UUID) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ PANIC in recordReviewSession: %v", r)
+		}
+	}()
+
+	session, err := s.repo.GetReviewSession(sessionID)
+	if err != nil {
+		log.Printf("⚠️ Failed to get review session %s: %v", sessionID, err)
+		return
+	}
+
+	requests := reviewActivityRequests(session)
+	for _, req := range requests {
+		req := req
+		err = RetryWithBackoff(DefaultRetryConfig(), func() error {
+			return s.userServiceClient.RecordPracticeActivity(session.UserID.String(), req)
+		})
+		if err != nil {
+			break
+		}
+	}
+	if err != nil {
+		log.Printf("❌ Failed to record review session %s after retries: %v", sessionID, err)
+	} else if len(requests) > 0 {
+		log.Printf("✅ Recorded review session %s", sessionID)
+	}
+
+	if markErr := s.repo.MarkReviewSessionSync(sessionID, err); markErr != nil {
+		log.Printf("⚠️ Failed to update sync status of review session %s: %v", sessionID, markErr)
+	}
+}
+
+// reviewActivityRequests builds a practice activity for each skill answered in the session
+func reviewActivityRequests(session *models.ReviewSession) []client.RecordPracticeActivityRequest {
+	type tally struct {
+		answered, correct, skipped, timeSpent int
+		score                                 float64
+	}
+	tallies := make(map[string]*tally)
+	for _, item := range session.Items {
+		t := tallies[item.SkillType]
+		if t == nil {
+			t = &tally{}
+			tallies[item.SkillType] = t
+		}
+		if item.AnsweredAt == nil {
+			t.skipped++
+			continue
+		}
+		t.answered++
+		if item.IsCorrect != nil && *item.IsCorrect {
+			t.correct++
+		}
+		if item.Score != nil {
+			t.score += *item.Score
+		}
+		if item.TimeSpentSeconds != nil {
+			t.timeSpent += *item.TimeSpentSeconds
+		}
+	}
+
+	skills := make([]string, 0, len(tallies))
+	for skill := range tallies {
+		skills = append(skills, skill)
+	}
+	sort.Slice(skills, func(i, j int) bool { return skillRank(skills[i]) < skillRank(skills[j]) })
+
+	title := "Mistake review"
+	var requests []client.RecordPracticeActivityRequest
+	for _, skill := range skills {
+		t := tallies[skill]
+		if t.answered == 0 {
+			continue
+		}
+		total := t.answered
+		score := round(t.score, 2)
+		maxScore := float64(t.answered)
+		accuracy := round(float64(t.correct)/float64(t.answered)*100, 2)
+		timeSpent := t.timeSpent
+		notes := fmt.Sprintf("Spaced-repetition review of %d missed questions", t.answered)
+		status := "completed"
+		if t.skipped > 0 {
+			status = "incomplete"
+		}
+		requests = append(requests, client.RecordPracticeActivityRequest{
+			Skill:              skill,
+			ActivityType:       "question_set",
+			ExerciseTitle:      &title,
+			Score:              &score,
+			MaxScore:           &maxScore,
+			CorrectAnswers:     t.correct,
+			TotalQuestions:     &total,
+			AccuracyPercentage: &accuracy,
+			TimeSpentSeconds:   &timeSpent,
+			StartedAt:          &session.StartedAt,
+			CompletedAt:        session.CompletedAt,
+			CompletionStatus:   status,
+			Notes:              &notes,
+		})
+	}
+	return requests
+}
+
+// retryReviewSessionSyncs records finished review sessions whose earlier sync failed
+func (s *ExerciseService) retryReviewSessionSyncs() {
+	ids, err := s.repo.GetPendingReviewSessionSyncs(50)
+	if err != nil {
+		log.Printf("⚠️ Failed to get pending review session syncs: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		s.recordReviewSession(id)
+	}
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/grading"
+)
+
+func TestReviewQuality(t *testing.T) {
+	tests := []struct {
+		result     grading.Result
+		confidence string
+		want       int
+	}{
+		{grading.Result{Correct: true, Score: 1}, "", 4},
+		{grading.Result{Correct: true, Score: 1}, "good", 4},
+		{grading.Result{Correct: true, Score: 1}, "easy", 5},
+		{grading.Result{Correct: true, Score: 1}, "hard", 3},
+		{grading.Result{Score: 0.5}, "easy", 2},
+		{grading.Result{Score: 0.25}, "", 1},
+		{grading.Result{}, "", 1},
+	}
+	for _, tt := range tests {
+		if got := reviewQuality(tt.result, tt.confidence); got != tt.want {
+			t.Errorf("reviewQuality(%+v, %q) = %d, want %d", tt.result, tt.confidence, got, tt.want)
+		}
+	}
+}
+
+func TestNextReviewSchedule(t *testing.T) {
+	s := reviewSchedule{EaseFactor: 2.5}
+
+	// Correct answers space the item out: 1 day, 6 days, then interval times ease
+	s = nextReviewSchedule(s, 4)
+	if s.IntervalDays != 1 || s.Repetitions != 1 || s.EaseFactor != 2.5 {
+		t.Fatalf("first review = %+v", s)
+	}
+	s = nextReviewSchedule(s, 5)
+	if s.IntervalDays != 6 || s.Repetitions != 2 || s.EaseFactor != 2.6 {
+		t.Fatalf("second review = %+v", s)
+	}
+	s = nextReviewSchedule(s, 3)
+	if s.IntervalDays != 16 || s.Repetitions != 3 || s.EaseFactor != 2.46 {
+		t.Fatalf("third review = %+v", s)
+	}
+
+	// A miss starts the item over and makes it come back sooner from then on
+	s = nextReviewSchedule(s, 1)
+	if s.IntervalDays != 1 || s.Repetitions != 0 || s.Lapses != 1 || s.EaseFactor != 1.92 {
+		t.Fatalf("missed review = %+v", s)
+	}
+
+	// Ease never drops below the SM-2 floor
+	for i := 0; i < 5; i++ {
+		s = nextReviewSchedule(s, 1)
+	}
+	if s.EaseFactor != minEaseFactor || s.Lapses != 6 {
+		t.Fatalf("repeated misses = %+v", s)
+	}
+}
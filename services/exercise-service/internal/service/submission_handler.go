@@ -1,14 +1,17 @@
 package service
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 
 	sharedClient "github.com/bisosad1501/DATN/shared/pkg/client"
-	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+	"github.com/bisosad1501/DATN/shared/pkg/textanalysis"
 	aiClient "github.com/bisosad1501/ielts-platform/exercise-service/internal/client"
 	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/repository"
 	"github.com/google/uuid"
 )
 
@@ -25,6 +28,11 @@ func (s *ExerciseService) SubmitExercise(submissionID uuid.UUID, req *models.Sub
 		return fmt.Errorf("get exercise: %w", err)
 	}
 
+	// Attempts are submitted exactly once, and only until the deadline (plus grace window)
+	if err := s.openAttempt(submission); err != nil {
+		return err
+	}
+
 	// Route to appropriate handler based on skill type
 	switch exercise.SkillType {
 	case "listening", "reading":
@@ -54,44 +62,38 @@ func (s *ExerciseService) handleListeningReadingSubmission(
 		return fmt.Errorf("save answers: %w", err)
 	}
 
-	// 2. Grade immediately
-	err = s.repo.CompleteSubmission(submission.ID)
+	return s.completeListeningReading(submission.ID, exercise)
+}
+
+// completeListeningReading grades the saved answers of an L/R attempt and records the band score.
+// Also used to auto-submit attempts whose time ran out.
+func (s *ExerciseService) completeListeningReading(submissionID uuid.UUID, exercise *models.Exercise) error {
+	// 2. Grade immediately. An attempt completed by a concurrent request has been recorded
+	// by that request already.
+	err := s.repo.CompleteSubmission(submissionID)
+	if errors.Is(err, repository.ErrAttemptAlreadyCompleted) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("complete submission: %w", err)
 	}
 
-	// 3. Get updated submission with score
-	result, err := s.repo.GetSubmissionResult(submission.ID)
+	// 3. Get the band score, computed on completion from the raw score with partial credit
+	submission, err := s.repo.GetSubmissionByID(submissionID)
 	if err != nil {
 		return fmt.Errorf("get result: %w", err)
 	}
-
-	// 4. Convert to band score using shared library
-	correctAnswers := result.Submission.CorrectAnswers
-	totalQuestions := result.Submission.TotalQuestions
 	var bandScore float64
-
-	if exercise.SkillType == "listening" {
-		bandScore = ielts.ConvertListeningScore(correctAnswers, totalQuestions)
-	} else { // reading
-		testType := "academic"
-		if exercise.IELTSTestType != nil && *exercise.IELTSTestType == "general_training" {
-			testType = "general"
-		}
-		bandScore = ielts.ConvertReadingScore(correctAnswers, totalQuestions, testType)
-	}
-
-	// 5. Update submission with band score
-	err = s.repo.UpdateSubmissionBandScore(submission.ID, bandScore)
-	if err != nil {
-		log.Printf("⚠️ Failed to update band score: %v", err)
+	if submission.BandScore != nil {
+		bandScore = *submission.BandScore
 	}
 
-	// 6. Record to user service (async) - for practice activities and test results
-	go s.recordToUserService(submission.ID, exercise, bandScore)
+	// 4. Record to user service (async) - for practice activities and test results
+	go s.recordToUserService(submissionID, exercise, bandScore)
+	go s.onAttemptFinished(submissionID)
 
-	// 7. Handle exercise completion (update user stats and send notification)
-	go s.handleExerciseCompletion(submission.ID)
+	// 5. Handle exercise completion (update user stats and send notification)
+	go s.handleExerciseCompletion(submissionID)
 
 	return nil
 }
@@ -107,11 +109,9 @@ func (s *ExerciseService) handleWritingSubmission(
 		return fmt.Errorf("essay text is required for writing submission")
 	}
 
-	// 2. Save essay data
-	wordCount := req.WritingData.WordCount
-	if wordCount == 0 {
-		wordCount = len([]rune(req.WritingData.EssayText))
-	}
+	// 2. Save essay data. Words are counted here the way IELTS counts them, rather than
+	// trusting the count sent by the client.
+	wordCount := textanalysis.CountWords(req.WritingData.EssayText)
 
 	err := s.repo.UpdateSubmissionWritingData(
 		submission.ID,
@@ -139,10 +139,11 @@ func (s *ExerciseService) handleWritingSubmission(
 		return fmt.Errorf("update evaluation status: %w", err)
 	}
 
-	// 3. Start async evaluation
-	taskType := req.WritingData.TaskType
-	promptText := req.WritingData.PromptText
-	go s.evaluateWritingAsync(submission.ID, exercise, req.WritingData.EssayText, &taskType, &promptText)
+	// 3. Queue AI evaluation (picked up by the evaluation worker)
+	if err := s.enqueueEvaluation(submission.ID, exercise.SkillType); err != nil {
+		return fmt.Errorf("queue writing evaluation: %w", err)
+	}
+	go s.onAttemptFinished(submission.ID)
 
 	return nil
 }
@@ -192,77 +193,44 @@ func (s *ExerciseService) handleSpeakingSubmission(
 		return fmt.Errorf("update evaluation status: %w", err)
 	}
 
-	// 3. Start async processing (transcribe + evaluate)
-	// For AI service, we need internal URL (not presigned, and use minio:9000 instead of localhost:9000)
-	// Convert presigned URL to internal URL:
-	// 1. Remove query parameters (if presigned URL)
-	// 2. Replace localhost:9000 with minio:9000 (for Docker network access)
-	audioURLForAI := audioURL
-	
-	if strings.Contains(audioURL, "?") {
-		// Extract base URL (remove query parameters)
-		// Presigned URL format: http://minio:9000/bucket/object?X-Amz-Algorithm=...
-		// Internal URL format: http://minio:9000/bucket/object
-		parts := strings.Split(audioURL, "?")
-		if len(parts) > 0 {
-			audioURLForAI = parts[0]
-			log.Printf("📎 Removed query parameters from URL: %s", audioURLForAI)
-		}
+	// 3. Queue transcription + evaluation (picked up by the evaluation worker)
+	if err := s.enqueueEvaluation(submission.ID, exercise.SkillType); err != nil {
+		return fmt.Errorf("queue speaking evaluation: %w", err)
 	}
-	
-	// Replace localhost:9000 with minio:9000 for internal access
-	if strings.Contains(audioURLForAI, "localhost:9000") {
-		audioURLForAI = strings.Replace(audioURLForAI, "localhost:9000", "minio:9000", 1)
-		log.Printf("📎 Converted localhost to minio for internal access: %s", audioURLForAI)
-	}
-	
-	log.Printf("📎 Using audio URL for AI service: %s (original: %s)", audioURLForAI, audioURL)
-	speakingPart := req.SpeakingData.SpeakingPartNumber
-	go s.evaluateSpeakingAsync(submission.ID, exercise, audioURLForAI, &speakingPart)
+	go s.onAttemptFinished(submission.ID)
 
 	return nil
 }
 
-// evaluateWritingAsync performs async writing evaluation
-func (s *ExerciseService) evaluateWritingAsync(
-	submissionID uuid.UUID,
-	exercise *models.Exercise,
-	essayText string,
-	taskType *string,
-	promptText *string,
-) {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("❌ PANIC in evaluateWritingAsync: %v", r)
-			s.repo.UpdateSubmissionEvaluationStatus(submissionID, "failed")
-		}
-	}()
-
-	log.Printf("🔄 Starting writing evaluation for submission %s", submissionID)
+// evaluateWriting performs writing evaluation for a queued evaluation job
+func (s *ExerciseService) evaluateWriting(submission *models.UserExerciseAttempt) (*evaluationOutcome, error) {
+	log.Printf("🔄 Starting writing evaluation for submission %s", submission.ID)
 
 	// Check if AI client exists
 	if s.aiServiceClient == nil {
-		log.Printf("❌ AI service client not configured")
-		s.repo.UpdateSubmissionEvaluationStatus(submissionID, "failed")
-		return
+		return nil, permanentEvaluationError(fmt.Errorf("AI service client not configured"))
+	}
+
+	if submission.EssayText == nil || *submission.EssayText == "" {
+		return nil, permanentEvaluationError(fmt.Errorf("essay text is empty for submission %s", submission.ID))
 	}
 
 	// Call AI service with retry
 	taskTypeStr := "task2"
-	if taskType != nil {
-		taskTypeStr = *taskType
+	if submission.TaskType != nil && *submission.TaskType != "" {
+		taskTypeStr = *submission.TaskType
 	}
 
 	promptStr := ""
-	if promptText != nil {
-		promptStr = *promptText
+	if submission.PromptText != nil {
+		promptStr = *submission.PromptText
 	}
 
 	var result *aiClient.WritingEvaluationResponse
 	err := RetryWithBackoff(AIServiceRetryConfig(), func() error {
 		var evalErr error
 		result, evalErr = s.aiServiceClient.EvaluateWriting(aiClient.WritingEvaluationRequest{
-			EssayText:  essayText,
+			EssayText:  *submission.EssayText,
 			TaskType:   taskTypeStr,
 			PromptText: promptStr,
 		})
@@ -276,9 +244,7 @@ func (s *ExerciseService) evaluateWritingAsync(
 	})
 
 	if err != nil {
-		log.Printf("❌ AI evaluation failed after retries: %v", err)
-		s.repo.UpdateSubmissionEvaluationStatus(submissionID, "failed")
-		return
+		return nil, fmt.Errorf("AI evaluation failed after retries: %w", err)
 	}
 
 	// Use the overall band from AI service
@@ -295,135 +261,142 @@ func (s *ExerciseService) evaluateWritingAsync(
 		"weaknesses":         result.Data.AreasForImprovement,
 		"suggestions":        nil,
 	}
-
-	// Update submission with results
-	err = s.repo.UpdateSubmissionWithAIResult(submissionID, &models.AIEvaluationResult{
-		OverallBandScore: overallBand,
-		DetailedScores:   detailedScores,
-		Feedback:         result.Data.ExaminerFeedback,
-		CriteriaScores: map[string]float64{
-			"task_achievement":   result.Data.CriteriaScores.TaskAchievement,
-			"coherence_cohesion": result.Data.CriteriaScores.CoherenceCohesion,
-			"lexical_resource":   result.Data.CriteriaScores.LexicalResource,
-			"grammar_accuracy":   result.Data.CriteriaScores.GrammaticalRange,
-		},
-	})
-	if err != nil {
-		log.Printf("❌ Failed to update submission: %v", err)
-		return
+	addScoreConfidence(detailedScores, result.Data.Confidence, submission.ID)
+	addEvaluationProvider(detailedScores, result.Data.Provider, submission.ID)
+	if len(result.Data.TextAnalysis) > 0 {
+		detailedScores["text_analysis"] = result.Data.TextAnalysis
 	}
 
-	log.Printf("✅ Writing evaluation completed: %.1f band", overallBand)
-
-	// Record to user service
-	go s.recordToUserService(submissionID, exercise, overallBand)
-
-	// Handle exercise completion (update user stats and send notification)
-	go s.handleExerciseCompletion(submissionID)
+	return &evaluationOutcome{
+		result: &models.AIEvaluationResult{
+			OverallBandScore: overallBand,
+			DetailedScores:   detailedScores,
+			Feedback:         result.Data.ExaminerFeedback,
+			CriteriaScores: map[string]float64{
+				"task_achievement":   result.Data.CriteriaScores.TaskAchievement,
+				"coherence_cohesion": result.Data.CriteriaScores.CoherenceCohesion,
+				"lexical_resource":   result.Data.CriteriaScores.LexicalResource,
+				"grammar_accuracy":   result.Data.CriteriaScores.GrammaticalRange,
+			},
+		},
+		recordResult: true,
+	}, nil
 }
 
-// evaluateSpeakingAsync performs async speaking transcription + evaluation
-func (s *ExerciseService) evaluateSpeakingAsync(
-	submissionID uuid.UUID,
-	exercise *models.Exercise,
-	audioURL string,
-	partNumber *int,
-) {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("❌ PANIC in evaluateSpeakingAsync: %v", r)
-			s.repo.UpdateSubmissionEvaluationStatus(submissionID, "failed")
-		}
-	}()
-
-	log.Printf("🔄 Starting speaking evaluation for submission %s", submissionID)
-	log.Printf("📎 Audio URL: %s", audioURL)
-	log.Printf("📎 Part Number: %v", partNumber)
+// evaluateSpeaking performs speaking transcription + evaluation for a queued evaluation job
+func (s *ExerciseService) evaluateSpeaking(submission *models.UserExerciseAttempt, exercise *models.Exercise) (*evaluationOutcome, error) {
+	log.Printf("🔄 Starting speaking evaluation for submission %s", submission.ID)
 
 	// Check if AI client exists
 	if s.aiServiceClient == nil {
-		log.Printf("❌ AI service client not configured")
-		s.repo.UpdateSubmissionEvaluationStatus(submissionID, "failed")
-		return
+		return nil, permanentEvaluationError(fmt.Errorf("AI service client not configured"))
 	}
 
 	// Validate audio URL is not empty
-	if audioURL == "" {
-		log.Printf("❌ Audio URL is empty for submission %s", submissionID)
-		s.repo.UpdateSubmissionEvaluationStatus(submissionID, "failed")
-		return
+	if submission.AudioURL == nil || *submission.AudioURL == "" {
+		return nil, permanentEvaluationError(fmt.Errorf("audio URL is empty for submission %s", submission.ID))
 	}
 
-	// Step 1: Transcribe audio with retry
-	var transcriptResult *aiClient.SpeakingTranscriptionResponse
-	err := RetryWithBackoff(AIServiceRetryConfig(), func() error {
-		var transcribeErr error
-		log.Printf("🎤 Transcribing audio from URL: %s", audioURL)
-		transcriptResult, transcribeErr = s.aiServiceClient.TranscribeSpeaking(aiClient.SpeakingTranscriptionRequest{
-			AudioURL: audioURL,
-		})
+	// For AI service, we need internal URL (not presigned, and use minio:9000 instead of localhost:9000)
+	audioURL := internalAudioURL(*submission.AudioURL)
+	log.Printf("📎 Using audio URL for AI service: %s (original: %s)", audioURL, *submission.AudioURL)
 
-		if transcribeErr != nil && IsRetryableError(transcribeErr) {
-			log.Printf("⚠️ Retryable error in transcription: %v", transcribeErr)
-			return transcribeErr
-		}
+	// Step 1: Transcribe audio with retry.
+	// A transcript saved by a previous run of this job is reused instead of transcribing again,
+	// together with the speech metrics saved with it.
+	transcriptText := ""
+	if submission.TranscriptText != nil {
+		transcriptText = *submission.TranscriptText
+	}
 
-		return transcribeErr
-	})
+	// Get audio duration from submission (if available)
+	duration := 0.0
+	if submission.AudioDurationSeconds != nil {
+		duration = float64(*submission.AudioDurationSeconds)
+	}
 
-	if err != nil {
-		log.Printf("❌ Transcription failed after retries: %v", err)
-		s.repo.UpdateSubmissionEvaluationStatus(submissionID, "failed")
-		return
+	// Fluency metrics measured from the word timings. Without them (a transcript saved before
+	// metrics were kept) the AI service measures what it can from the transcript.
+	var speechMetrics json.RawMessage
+	if transcriptText != "" && submission.SpeechMetrics != nil {
+		speechMetrics = json.RawMessage(*submission.SpeechMetrics)
 	}
 
-	// Log transcript result
-	if transcriptResult != nil && transcriptResult.Data.TranscriptText != "" {
-		transcriptPreview := transcriptResult.Data.TranscriptText
-		if len(transcriptPreview) > 200 {
-			transcriptPreview = transcriptPreview[:200] + "..."
+	if transcriptText == "" {
+		var transcriptResult *aiClient.SpeakingTranscriptionResponse
+		err := RetryWithBackoff(AIServiceRetryConfig(), func() error {
+			var transcribeErr error
+			log.Printf("🎤 Transcribing audio from URL: %s", audioURL)
+			transcriptResult, transcribeErr = s.aiServiceClient.TranscribeSpeaking(aiClient.SpeakingTranscriptionRequest{
+				AudioURL: audioURL,
+			})
+
+			if transcribeErr != nil && IsRetryableError(transcribeErr) {
+				log.Printf("⚠️ Retryable error in transcription: %v", transcribeErr)
+				return transcribeErr
+			}
+
+			return transcribeErr
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("transcription failed after retries: %w", err)
 		}
-		log.Printf("✅ Transcription successful. Transcript preview: %s", transcriptPreview)
-		log.Printf("📊 Transcript length: %d characters", len(transcriptResult.Data.TranscriptText))
-	} else {
-		log.Printf("⚠️ Transcription returned empty transcript")
-	}
 
-	// Update submission with transcript
-	err = s.repo.UpdateSubmissionTranscript(submissionID, transcriptResult.Data.TranscriptText)
-	if err != nil {
-		log.Printf("⚠️ Failed to save transcript: %v", err)
-		// Continue even if transcript save fails
+		transcriptText = transcriptResult.Data.TranscriptText
+		speechMetrics = transcriptResult.Data.SpeechMetrics
+		if duration == 0 {
+			duration = float64(transcriptResult.Data.AudioDuration)
+		}
+
+		// Log transcript result
+		if transcriptText != "" {
+			transcriptPreview := transcriptText
+			if len(transcriptPreview) > 200 {
+				transcriptPreview = transcriptPreview[:200] + "..."
+			}
+			log.Printf("✅ Transcription successful. Transcript preview: %s", transcriptPreview)
+			log.Printf("📊 Transcript length: %d characters", len(transcriptText))
+		} else {
+			log.Printf("⚠️ Transcription returned empty transcript")
+		}
+
+		// Update submission with transcript
+		if err := s.repo.UpdateSubmissionTranscript(submission.ID, transcriptText, speechMetrics); err != nil {
+			log.Printf("⚠️ Failed to save transcript: %v", err)
+			// Continue even if transcript save fails
+		}
 	}
 
 	// Step 2: Evaluate speaking with retry
 	partNum := 1
-	if partNumber != nil {
-		partNum = *partNumber
+	if submission.SpeakingPartNumber != nil && *submission.SpeakingPartNumber > 0 {
+		partNum = *submission.SpeakingPartNumber
 	}
 
 	// Validate transcript is not empty
-	if transcriptResult.Data.TranscriptText == "" || len(transcriptResult.Data.TranscriptText) < 10 {
-		log.Printf("❌ Transcript is empty or too short (%d chars) for submission %s", len(transcriptResult.Data.TranscriptText), submissionID)
-		s.repo.UpdateSubmissionEvaluationStatus(submissionID, "failed")
-		// Update with error feedback
-		s.repo.UpdateSubmissionWithAIResult(submissionID, &models.AIEvaluationResult{
-			OverallBandScore: 0.0,
-			DetailedScores: map[string]interface{}{
-				"fluency":          0.0,
-				"lexical_resource": 0.0,
-				"grammar":          0.0,
-				"pronunciation":    0.0,
+	if len(transcriptText) < 10 {
+		log.Printf("❌ Transcript is empty or too short (%d chars) for submission %s", len(transcriptText), submission.ID)
+		// Complete with error feedback; nothing is recorded to User Service
+		return &evaluationOutcome{
+			result: &models.AIEvaluationResult{
+				OverallBandScore: 0.0,
+				DetailedScores: map[string]interface{}{
+					"fluency":          0.0,
+					"lexical_resource": 0.0,
+					"grammar":          0.0,
+					"pronunciation":    0.0,
+				},
+				Feedback: "Không có câu trả lời nào được cung cấp, do đó không thể đánh giá khả năng nói của thí sinh. Để cải thiện, hãy cố gắng trả lời đầy đủ và rõ ràng các câu hỏi trong bài thi. Điều này sẽ giúp bạn có cơ hội thể hiện khả năng ngôn ngữ của mình tốt hơn.",
+				CriteriaScores: map[string]float64{
+					"fluency":          0.0,
+					"lexical_resource": 0.0,
+					"grammar":          0.0,
+					"pronunciation":    0.0,
+				},
 			},
-			Feedback: "Không có câu trả lời nào được cung cấp, do đó không thể đánh giá khả năng nói của thí sinh. Để cải thiện, hãy cố gắng trả lời đầy đủ và rõ ràng các câu hỏi trong bài thi. Điều này sẽ giúp bạn có cơ hội thể hiện khả năng ngôn ngữ của mình tốt hơn.",
-			CriteriaScores: map[string]float64{
-				"fluency":          0.0,
-				"lexical_resource": 0.0,
-				"grammar":          0.0,
-				"pronunciation":    0.0,
-			},
-		})
-		return
+			recordResult: false,
+		}, nil
 	}
 
 	// Get prompt text from exercise
@@ -433,26 +406,20 @@ func (s *ExerciseService) evaluateSpeakingAsync(
 	}
 
 	// Calculate word count from transcript
-	wordCount := len(strings.Fields(transcriptResult.Data.TranscriptText))
-
-	// Get audio duration from submission (if available)
-	submission, err := s.repo.GetSubmissionByID(submissionID)
-	duration := 0.0
-	if err == nil && submission.AudioDurationSeconds != nil {
-		duration = float64(*submission.AudioDurationSeconds)
-	}
+	wordCount := len(strings.Fields(transcriptText))
 
 	var evalResult *aiClient.SpeakingEvaluationResponse
-	err = RetryWithBackoff(AIServiceRetryConfig(), func() error {
+	err := RetryWithBackoff(AIServiceRetryConfig(), func() error {
 		var evalErr error
-		log.Printf("📝 Evaluating speaking with transcript length: %d, part: %d, word count: %d, duration: %.1fs", len(transcriptResult.Data.TranscriptText), partNum, wordCount, duration)
+		log.Printf("📝 Evaluating speaking with transcript length: %d, part: %d, word count: %d, duration: %.1fs", len(transcriptText), partNum, wordCount, duration)
 		evalResult, evalErr = s.aiServiceClient.EvaluateSpeaking(aiClient.SpeakingEvaluationRequest{
 			AudioURL:       audioURL,
-			TranscriptText: transcriptResult.Data.TranscriptText,
+			TranscriptText: transcriptText,
 			PromptText:     promptText,
 			PartNumber:     partNum,
 			WordCount:      wordCount,
 			Duration:       duration,
+			SpeechMetrics:  speechMetrics,
 		})
 
 		if evalErr != nil && IsRetryableError(evalErr) {
@@ -464,9 +431,7 @@ func (s *ExerciseService) evaluateSpeakingAsync(
 	})
 
 	if err != nil {
-		log.Printf("❌ Speaking evaluation failed after retries: %v", err)
-		s.repo.UpdateSubmissionEvaluationStatus(submissionID, "failed")
-		return
+		return nil, fmt.Errorf("speaking evaluation failed after retries: %w", err)
 	}
 
 	// Use the overall band from AI service
@@ -489,31 +454,77 @@ func (s *ExerciseService) evaluateSpeakingAsync(
 		"weaknesses":       evalResult.Data.AreasForImprovement,
 		"suggestions":      nil,
 	}
+	addScoreConfidence(detailedScores, evalResult.Data.Confidence, submission.ID)
+	addEvaluationProvider(detailedScores, evalResult.Data.Provider, submission.ID)
+	if len(evalResult.Data.SpeechMetrics) > 0 {
+		detailedScores["speech_metrics"] = evalResult.Data.SpeechMetrics
+	}
 
-	// Update submission with results
-	err = s.repo.UpdateSubmissionWithAIResult(submissionID, &models.AIEvaluationResult{
-		OverallBandScore: overallBand,
-		DetailedScores:   detailedScores,
-		Feedback:         evalResult.Data.ExaminerFeedback,
-		CriteriaScores: map[string]float64{
-			"fluency":          evalResult.Data.CriteriaScores.FluencyCoherence,
-			"lexical_resource": evalResult.Data.CriteriaScores.LexicalResource,
-			"grammar":          evalResult.Data.CriteriaScores.GrammaticalRange,
-			"pronunciation":    evalResult.Data.CriteriaScores.Pronunciation,
+	return &evaluationOutcome{
+		result: &models.AIEvaluationResult{
+			OverallBandScore: overallBand,
+			DetailedScores:   detailedScores,
+			Feedback:         evalResult.Data.ExaminerFeedback,
+			CriteriaScores: map[string]float64{
+				"fluency":          evalResult.Data.CriteriaScores.FluencyCoherence,
+				"lexical_resource": evalResult.Data.CriteriaScores.LexicalResource,
+				"grammar":          evalResult.Data.CriteriaScores.GrammaticalRange,
+				"pronunciation":    evalResult.Data.CriteriaScores.Pronunciation,
+			},
 		},
-	})
-	if err != nil {
-		log.Printf("❌ Failed to update submission: %v", err)
+		recordResult: true,
+	}, nil
+}
+
+// addScoreConfidence stores how far the AI's samples agreed next to the criteria scores,
+// so the result page can show it and teachers can find results flagged for review
+func addScoreConfidence(detailedScores map[string]interface{}, confidence *aiClient.ScoreConfidence, submissionID uuid.UUID) {
+	if confidence == nil {
+		return
+	}
+	detailedScores["confidence"] = confidence
+	if confidence.NeedsReview {
+		log.Printf("🚩 AI samples for submission %s differ by %.1f bands, flagged for human review", submissionID, confidence.OverallSpread)
+	}
+}
+
+// addEvaluationProvider records which evaluator scored the submission. Heuristic scores are
+// estimates made while no model was reachable, so they are marked for the result page.
+func addEvaluationProvider(detailedScores map[string]interface{}, provider string, submissionID uuid.UUID) {
+	if provider == "" {
 		return
 	}
+	detailedScores["provider"] = provider
+	if provider == "heuristic" {
+		detailedScores["estimated"] = true
+		log.Printf("🚩 Submission %s was scored by the heuristic evaluator, band is an estimate", submissionID)
+	}
+}
 
-	log.Printf("✅ Speaking evaluation completed: %.1f band", overallBand)
+// internalAudioURL converts the stored audio URL into one the AI service can fetch:
+// 1. Remove query parameters (if presigned URL)
+// 2. Replace localhost:9000 with minio:9000 (for Docker network access)
+func internalAudioURL(audioURL string) string {
+	if idx := strings.Index(audioURL, "?"); idx != -1 {
+		// Presigned URL format: http://minio:9000/bucket/object?X-Amz-Algorithm=...
+		// Internal URL format: http://minio:9000/bucket/object
+		audioURL = audioURL[:idx]
+	}
 
-	// Record to user service
-	go s.recordToUserService(submissionID, exercise, overallBand)
+	if strings.Contains(audioURL, "localhost:9000") {
+		audioURL = strings.Replace(audioURL, "localhost:9000", "minio:9000", 1)
+	}
 
-	// Handle exercise completion (update user stats and send notification)
-	go s.handleExerciseCompletion(submissionID)
+	return audioURL
+}
+
+// listeningReadingMarks is the raw score of an L/R attempt, the marks its band was computed
+// from. Attempts completed before raw scores were stored fall back to their correct answers.
+func listeningReadingMarks(submission *models.UserExerciseAttempt) int {
+	if submission.RawScore != nil {
+		return *submission.RawScore
+	}
+	return submission.CorrectAnswers
 }
 
 // recordToUserService records results to user service (for all 4 skills)
@@ -535,6 +546,14 @@ func (s *ExerciseService) recordToUserService(
 		return
 	}
 
+	// Parts of a full mock test are recorded once, as the session's overall result
+	if _, inSession, err := s.repo.GetTestSessionIDForAttempt(submissionID); err != nil {
+		log.Printf("⚠️ Failed to look up test session of submission %s: %v", submissionID, err)
+	} else if inSession {
+		s.repo.MarkUserServiceSyncNotRequired(submissionID)
+		return
+	}
+
 	// Determine if this is official test or practice
 	isOfficialTest := exercise.IsOfficialTest()
 
@@ -565,9 +584,9 @@ func (s *ExerciseService) recordToUserService(
 
 		// For Listening/Reading, send raw score (User Service calculates band_score)
 		if exercise.SkillType == "listening" || exercise.SkillType == "reading" {
-			correctAnswers := submission.CorrectAnswers
+			rawScore := listeningReadingMarks(submission)
 			totalQuestions := submission.TotalQuestions
-			req.RawScore = &correctAnswers
+			req.RawScore = &rawScore
 			req.TotalQuestions = &totalQuestions
 			// Do NOT send BandScore - let User Service be single source of truth
 		} else {
@@ -0,0 +1,535 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// testSessionSkillOrder is the order of the four skills in an IELTS test
+var testSessionSkillOrder = []string{"listening", "reading", "writing", "speaking"}
+
+func skillRank(skillType string) int {
+	for i, skill := range testSessionSkillOrder {
+		if skill == skillType {
+			return i
+		}
+	}
+	return len(testSessionSkillOrder)
+}
+
+// CreateTestSession creates a full mock test from published exercises covering all four skills
+func (s *ExerciseService) CreateTestSession(userID uuid.UUID, req *models.CreateTestSessionRequest) (*models.TestSession, error) {
+	testType := req.TestType
+	if testType == "" {
+		testType = "mock_test"
+	}
+	if testType != "mock_test" && testType != "full_test" {
+		return nil, fmt.Errorf("%w: test_type must be mock_test or full_test", ErrInvalidTestSession)
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	exercises := make([]*models.Exercise, 0, len(req.ExerciseIDs))
+	for _, id := range req.ExerciseIDs {
+		if seen[id] {
+			return nil, fmt.Errorf("%w: exercise %s is listed twice", ErrInvalidTestSession, id)
+		}
+		seen[id] = true
+
+		exercise, err := s.repo.GetExerciseByIDSimple(id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: exercise %s not found", ErrInvalidTestSession, id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get exercise %s: %w", id, err)
+		}
+		if !exercise.IsPublished {
+			return nil, fmt.Errorf("%w: exercise %s is not published", ErrInvalidTestSession, id)
+		}
+		exercises = append(exercises, exercise)
+	}
+
+	// Listening, Reading, Writing, Speaking; the given order is kept within a skill
+	sort.SliceStable(exercises, func(i, j int) bool {
+		return skillRank(exercises[i].SkillType) < skillRank(exercises[j].SkillType)
+	})
+
+	ieltsVariant := "academic"
+	skills := make(map[string]bool)
+	parts := make([]models.TestSessionPart, 0, len(exercises))
+	for i, exercise := range exercises {
+		skills[exercise.SkillType] = true
+		if exercise.SkillType == "reading" && exercise.IELTSTestType != nil {
+			ieltsVariant = *exercise.IELTSTestType
+		}
+		parts = append(parts, models.TestSessionPart{
+			PartNumber:       i + 1,
+			SkillType:        exercise.SkillType,
+			ExerciseID:       exercise.ID,
+			ExerciseTitle:    exercise.Title,
+			TimeLimitMinutes: exercise.TimeLimitMinutes,
+			WritingTaskType:  exercise.WritingTaskType,
+			Status:           "pending",
+		})
+	}
+	for _, skill := range testSessionSkillOrder {
+		if !skills[skill] {
+			return nil, fmt.Errorf("%w: a %s exercise is required", ErrInvalidTestSession, skill)
+		}
+	}
+
+	session, err := s.repo.CreateTestSession(userID, testType, ieltsVariant, parts)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("📝 Created %s session %s for user %s with %d parts", testType, session.ID, userID, len(parts))
+	return session, nil
+}
+
+// GetTestSession returns the user's test session with the progress of each part
+func (s *ExerciseService) GetTestSession(sessionID, userID uuid.UUID) (*models.TestSession, error) {
+	session, err := s.getUserTestSession(sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range session.Parts {
+		applyTestSessionPartTimer(&session.Parts[i], now)
+	}
+	return session, nil
+}
+
+func (s *ExerciseService) getUserTestSession(sessionID, userID uuid.UUID) (*models.TestSession, error) {
+	session, err := s.repo.GetTestSession(sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTestSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get test session: %w", err)
+	}
+	if session.UserID != userID {
+		return nil, ErrTestSessionNotFound
+	}
+	return session, nil
+}
+
+// applyTestSessionPartTimer fills in the deadline and time left of a part being taken
+func applyTestSessionPartTimer(part *models.TestSessionPart, now time.Time) {
+	if part.Status != "in_progress" || part.StartedAt == nil || part.TimeLimitMinutes == nil || *part.TimeLimitMinutes <= 0 {
+		return
+	}
+
+	attempt := models.UserExerciseAttempt{
+		Status:           "in_progress",
+		StartedAt:        *part.StartedAt,
+		TimeLimitMinutes: part.TimeLimitMinutes,
+	}
+	applyAttemptTimer(&attempt, now)
+	part.DeadlineAt = attempt.DeadlineAt
+	part.RemainingSeconds = attempt.RemainingSeconds
+}
+
+// ListTestSessions returns the user's test sessions, newest first
+func (s *ExerciseService) ListTestSessions(userID uuid.UUID, query *models.TestSessionListQuery) ([]models.TestSession, int, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	return s.repo.GetUserTestSessions(userID, query.Limit, (query.Page-1)*query.Limit)
+}
+
+// StartTestSessionPart starts the attempt for one part of a test session. Parts are taken
+// in order: every earlier part must be submitted or skipped first. The returned attempt
+// is then answered and submitted like any other submission, with its own timer.
+func (s *ExerciseService) StartTestSessionPart(sessionID, userID uuid.UUID, partNumber int) (*models.UserExerciseAttempt, error) {
+	session, err := s.getUserTestSession(sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != "in_progress" {
+		return nil, ErrTestSessionClosed
+	}
+
+	var part *models.TestSessionPart
+	for i := range session.Parts {
+		p := &session.Parts[i]
+		if p.PartNumber == partNumber {
+			part = p
+			break
+		}
+		if !p.IsDone() {
+			return nil, ErrTestSessionPartLocked
+		}
+	}
+	if part == nil {
+		return nil, ErrTestSessionNotFound
+	}
+	if part.Status != "pending" {
+		return nil, ErrTestSessionPartStarted
+	}
+
+	attempt, err := s.repo.CreateSubmission(userID, part.ExerciseID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create attempt: %w", err)
+	}
+
+	linked, err := s.repo.SetTestSessionPartAttempt(sessionID, partNumber, attempt.ID)
+	if err != nil || !linked {
+		// Another request started this part first; drop the attempt we just created
+		if _, abandonErr := s.repo.AbandonSubmission(attempt.ID); abandonErr != nil {
+			log.Printf("⚠️ Failed to abandon orphan attempt %s: %v", attempt.ID, abandonErr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("link attempt to test session: %w", err)
+		}
+		return nil, ErrTestSessionPartStarted
+	}
+
+	applyAttemptTimer(attempt, time.Now())
+	return attempt, nil
+}
+
+// GetTestSessionReport returns the score report of the user's test session
+func (s *ExerciseService) GetTestSessionReport(sessionID, userID uuid.UUID) (*models.TestSessionReport, error) {
+	session, err := s.getUserTestSession(sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return buildTestSessionReport(session), nil
+}
+
+// buildTestSessionReport summarizes a session per skill. Bands are only final once the
+// session is completed or incomplete; until then they reflect the parts graded so far.
+func buildTestSessionReport(session *models.TestSession) *models.TestSessionReport {
+	if !session.IsFinished() {
+		computeTestSessionBands(session)
+	}
+
+	report := &models.TestSessionReport{
+		Session:          session,
+		OverallBand:      session.OverallBand,
+		CompletionStatus: "pending",
+	}
+	if session.IsFinished() {
+		report.CompletionStatus = session.Status
+	}
+
+	bands := map[string]*float64{
+		"listening": session.ListeningBand,
+		"reading":   session.ReadingBand,
+		"writing":   session.WritingBand,
+		"speaking":  session.SpeakingBand,
+	}
+	for _, skill := range testSessionSkillOrder {
+		skillReport := models.SkillScoreReport{
+			SkillType: skill,
+			BandScore: bands[skill],
+		}
+		correct, total := 0, 0
+		for _, part := range session.Parts {
+			if part.SkillType != skill {
+				continue
+			}
+			skillReport.Parts++
+			switch part.Status {
+			case "graded":
+				skillReport.PartsGraded++
+				if part.CorrectAnswers != nil && part.TotalQuestions != nil {
+					correct += *part.CorrectAnswers
+					total += *part.TotalQuestions
+				}
+			case "skipped":
+				skillReport.PartsSkipped++
+			}
+			if part.TimeSpentSeconds != nil {
+				skillReport.TimeSpentSeconds += *part.TimeSpentSeconds
+			}
+		}
+		if skill == "listening" || skill == "reading" {
+			skillReport.CorrectAnswers = &correct
+			skillReport.TotalQuestions = &total
+		}
+		report.TimeSpentSeconds += skillReport.TimeSpentSeconds
+		report.Skills = append(report.Skills, skillReport)
+	}
+
+	return report
+}
+
+// computeTestSessionBands sets the skill and overall bands of a session from its graded parts.
+// Listening/Reading pool the raw scores (with partial credit) of all their parts, Writing weights Task 2 twice as
+// much as Task 1, and Speaking averages its parts. Skills with no graded part get no band.
+// The overall band is only given when every part was graded: a result averaged over fewer
+// than the four skills is not an IELTS overall band.
+func computeTestSessionBands(session *models.TestSession) {
+	var lMarks, lTotal, rMarks, rTotal int
+	var task1, task2, speaking []float64
+
+	allGraded := len(session.Parts) > 0
+	for _, part := range session.Parts {
+		if part.Status != "graded" {
+			allGraded = false
+			continue
+		}
+		switch part.SkillType {
+		case "listening", "reading":
+			if part.CorrectAnswers == nil || part.TotalQuestions == nil {
+				continue
+			}
+			marks := *part.CorrectAnswers
+			if part.RawScore != nil {
+				marks = *part.RawScore
+			}
+			if part.SkillType == "listening" {
+				lMarks += marks
+				lTotal += *part.TotalQuestions
+			} else {
+				rMarks += marks
+				rTotal += *part.TotalQuestions
+			}
+		case "writing":
+			if part.BandScore == nil {
+				continue
+			}
+			if part.WritingTaskType != nil && *part.WritingTaskType == "task1" {
+				task1 = append(task1, *part.BandScore)
+			} else {
+				task2 = append(task2, *part.BandScore)
+			}
+		case "speaking":
+			if part.BandScore != nil {
+				speaking = append(speaking, *part.BandScore)
+			}
+		}
+	}
+
+	session.ListeningBand, session.ReadingBand, session.WritingBand, session.SpeakingBand = nil, nil, nil, nil
+	if lTotal > 0 {
+		session.ListeningBand = bandPtr(ielts.ConvertListeningScore(lMarks, lTotal))
+	}
+	if rTotal > 0 {
+		session.ReadingBand = bandPtr(ielts.ConvertReadingScore(rMarks, rTotal, session.IELTSVariant))
+	}
+	if len(task1) > 0 || len(task2) > 0 {
+		session.WritingBand = bandPtr(ielts.CalculateWritingBandFromTasks(average(task1), average(task2)))
+	}
+	if len(speaking) > 0 {
+		session.SpeakingBand = bandPtr(ielts.RoundToIELTSBand(average(speaking)))
+	}
+
+	// Averaged directly rather than with ielts.CalculateOverallBand, which leaves out zero
+	// bands as "not taken": here a zero is a real score
+	session.OverallBand = nil
+	if allGraded && session.ListeningBand != nil && session.ReadingBand != nil &&
+		session.WritingBand != nil && session.SpeakingBand != nil {
+		overall := ielts.RoundToIELTSBand((*session.ListeningBand + *session.ReadingBand +
+			*session.WritingBand + *session.SpeakingBand) / 4)
+		session.OverallBand = &overall
+	}
+}
+
+func average(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func bandPtr(band float64) *float64 {
+	return &band
+}
+
+func bandValue(band *float64) float64 {
+	if band == nil {
+		return 0
+	}
+	return *band
+}
+
+// onAttemptFinished collects the attempt's mistakes for review and updates the test session
+// it belongs to, if any, after the attempt was submitted, graded or abandoned
+func (s *ExerciseService) onAttemptFinished(attemptID uuid.UUID) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ PANIC in onAttemptFinished: %v", r)
+		}
+	}()
+
+	s.collectMistakes(attemptID)
+
+	sessionID, ok, err := s.repo.GetTestSessionIDForAttempt(attemptID)
+	if err != nil {
+		log.Printf("⚠️ Failed to look up test session of attempt %s: %v", attemptID, err)
+		return
+	}
+	if !ok {
+		return
+	}
+	if err := s.refreshTestSession(sessionID); err != nil {
+		log.Printf("⚠️ Failed to update test session %s: %v", sessionID, err)
+	}
+}
+
+// refreshTestSession moves a session forward from the state of its parts. Once every part
+// is graded or skipped, the final bands are stored and the combined result is recorded.
+func (s *ExerciseService) refreshTestSession(sessionID uuid.UUID) error {
+	session, err := s.repo.GetTestSession(sessionID)
+	if err != nil {
+		return fmt.Errorf("get test session: %w", err)
+	}
+	if session.IsFinished() {
+		return nil
+	}
+
+	awaitingEvaluation, skipped := false, false
+	for _, part := range session.Parts {
+		switch part.Status {
+		case "pending", "in_progress":
+			return nil // Still being taken
+		case "submitted":
+			awaitingEvaluation = true
+		case "skipped", "evaluation_failed":
+			skipped = true
+		}
+	}
+	if awaitingEvaluation {
+		return s.repo.UpdateTestSessionStatus(sessionID, "awaiting_evaluation")
+	}
+
+	computeTestSessionBands(session)
+	now := time.Now()
+	session.CompletedAt = &now
+	session.Status = "completed"
+	if skipped {
+		session.Status = "incomplete"
+	}
+
+	finished, err := s.repo.FinishTestSession(session)
+	if err != nil {
+		return fmt.Errorf("finish test session: %w", err)
+	}
+	if !finished {
+		return nil // Finished concurrently
+	}
+
+	log.Printf("✅ Test session %s %s: overall band %.1f", session.ID, session.Status, bandValue(session.OverallBand))
+	go s.recordTestSessionToUserService(session)
+	return nil
+}
+
+// recordTestSessionToUserService records a finished session as one overall test result
+func (s *ExerciseService) recordTestSessionToUserService(session *models.TestSession) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ PANIC in recordTestSessionToUserService: %v", r)
+		}
+	}()
+
+	if session.OverallBand == nil {
+		// Nothing was graded; there is no result to record
+		if err := s.repo.MarkTestSessionSync(session.ID, nil); err != nil {
+			log.Printf("⚠️ Failed to mark test session %s as synced: %v", session.ID, err)
+		}
+		return
+	}
+
+	err := RetryWithBackoff(DefaultRetryConfig(), func() error {
+		return s.userServiceClient.RecordTestResult(session.UserID.String(), testSessionResultRequest(session))
+	})
+	if err != nil {
+		log.Printf("❌ Failed to record test session %s after retries: %v", session.ID, err)
+	} else {
+		log.Printf("✅ Recorded test session %s: %.1f overall band", session.ID, *session.OverallBand)
+	}
+
+	if markErr := s.repo.MarkTestSessionSync(session.ID, err); markErr != nil {
+		log.Printf("⚠️ Failed to update sync status of test session %s: %v", session.ID, markErr)
+	}
+}
+
+func testSessionResultRequest(session *models.TestSession) client.RecordTestResultRequest {
+	sessionID := session.ID.String()
+	variant := session.IELTSVariant
+
+	timeSpent := 0
+	for _, part := range session.Parts {
+		if part.TimeSpentSeconds != nil {
+			timeSpent += *part.TimeSpentSeconds
+		}
+	}
+	durationMinutes := (timeSpent + 59) / 60
+
+	return client.RecordTestResultRequest{
+		TestType:            session.TestType,
+		SkillType:           "overall",
+		IELTSVariant:        &variant,
+		BandScore:           *session.OverallBand,
+		SourceService:       "exercise_service",
+		SourceTable:         "test_sessions",
+		SourceID:            &sessionID,
+		TestDate:            session.CompletedAt,
+		TestSource:          "platform",
+		ListeningBandScore:  session.ListeningBand,
+		ReadingBandScore:    session.ReadingBand,
+		WritingBandScore:    session.WritingBand,
+		SpeakingBandScore:   session.SpeakingBand,
+		TestDurationMinutes: &durationMinutes,
+		CompletionStatus:    session.Status,
+	}
+}
+
+// retryTestSessionSyncs records finished sessions whose earlier sync failed
+func (s *ExerciseService) retryTestSessionSyncs() {
+	ids, err := s.repo.GetPendingTestSessionSyncs(50)
+	if err != nil {
+		log.Printf("⚠️ Failed to get pending test session syncs: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		session, err := s.repo.GetTestSession(id)
+		if err != nil {
+			log.Printf("⚠️ Failed to get test session %s: %v", id, err)
+			continue
+		}
+		s.recordTestSessionToUserService(session)
+	}
+}
+
+// closeIdleTestSessions skips the unstarted parts of sessions left without activity,
+// so they are finished with the parts that were taken
+func (s *ExerciseService) closeIdleTestSessions(idleBefore time.Time, limit int) int {
+	ids, err := s.repo.GetIdleTestSessionIDs(idleBefore, limit)
+	if err != nil {
+		log.Printf("⚠️ Failed to get idle test sessions: %v", err)
+		return 0
+	}
+
+	for _, id := range ids {
+		if _, err := s.repo.SkipPendingTestSessionParts(id); err != nil {
+			log.Printf("⚠️ Failed to skip parts of idle test session %s: %v", id, err)
+			continue
+		}
+		if err := s.refreshTestSession(id); err != nil {
+			log.Printf("⚠️ Failed to close idle test session %s: %v", id, err)
+		}
+	}
+	return len(ids)
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+)
+
+func intPtr(v int) *int { return &v }
+
+func lrPart(skill, status string, correct, total int) models.TestSessionPart {
+	return models.TestSessionPart{SkillType: skill, Status: status, CorrectAnswers: intPtr(correct), TotalQuestions: intPtr(total)}
+}
+
+func withRawScore(part models.TestSessionPart, marks int) models.TestSessionPart {
+	part.RawScore = intPtr(marks)
+	return part
+}
+
+func bandPart(skill, status string, band float64, taskType string) models.TestSessionPart {
+	part := models.TestSessionPart{SkillType: skill, Status: status, BandScore: bandPtr(band)}
+	if taskType != "" {
+		part.WritingTaskType = &taskType
+	}
+	return part
+}
+
+func TestComputeTestSessionBands(t *testing.T) {
+	tests := []struct {
+		name                                           string
+		variant                                        string
+		parts                                          []models.TestSessionPart
+		listening, reading, writing, speaking, overall float64 // 0 = no band
+	}{
+		{
+			name:    "full test, sections pooled and Task 2 weighted twice",
+			variant: "academic",
+			parts: []models.TestSessionPart{
+				lrPart("listening", "graded", 15, 20),
+				lrPart("listening", "graded", 15, 20),
+				lrPart("reading", "graded", 30, 40),
+				bandPart("writing", "graded", 6.0, "task1"),
+				bandPart("writing", "graded", 7.0, "task2"),
+				bandPart("speaking", "graded", 7.0, ""),
+				bandPart("speaking", "graded", 6.0, ""),
+			},
+			listening: 7.0, reading: 7.0, writing: 6.5, speaking: 6.5, overall: 7.0,
+		},
+		{
+			name:    "general training reading table",
+			variant: "general_training",
+			parts: []models.TestSessionPart{
+				lrPart("listening", "graded", 30, 40),
+				lrPart("reading", "graded", 30, 40),
+				bandPart("writing", "graded", 6.0, "task2"),
+				bandPart("speaking", "graded", 6.0, ""),
+			},
+			listening: 7.0, reading: 6.0, writing: 6.0, speaking: 6.0, overall: 6.5,
+		},
+		{
+			name:    "skipped and ungraded parts are left out, with no overall band",
+			variant: "academic",
+			parts: []models.TestSessionPart{
+				lrPart("listening", "graded", 30, 40),
+				lrPart("reading", "graded", 30, 40),
+				bandPart("writing", "graded", 6.0, "task1"),
+				bandPart("writing", "submitted", 9.0, "task2"),
+				{SkillType: "speaking", Status: "skipped"},
+			},
+			listening: 7.0, reading: 7.0, writing: 6.0, speaking: 0, overall: 0,
+		},
+		{
+			name:    "failed evaluation leaves no overall band",
+			variant: "academic",
+			parts: []models.TestSessionPart{
+				lrPart("listening", "graded", 30, 40),
+				lrPart("reading", "graded", 30, 40),
+				bandPart("writing", "graded", 6.0, "task2"),
+				{SkillType: "speaking", Status: "evaluation_failed"},
+			},
+			listening: 7.0, reading: 7.0, writing: 6.0, speaking: 0, overall: 0,
+		},
+		{
+			// 29 fully correct questions, plus two multi-part answers with half their parts right:
+			// 30 marks is band 7.0, where counting only fully correct questions would give 6.5
+			name:    "partial credit on multi-part answers counts towards the band",
+			variant: "academic",
+			parts: []models.TestSessionPart{
+				withRawScore(lrPart("listening", "graded", 29, 40), 30),
+				lrPart("reading", "graded", 30, 40),
+				bandPart("writing", "graded", 7.0, "task2"),
+				bandPart("speaking", "graded", 7.0, ""),
+			},
+			listening: 7.0, reading: 7.0, writing: 7.0, speaking: 7.0, overall: 7.0,
+		},
+		{
+			name:    "nothing graded",
+			variant: "academic",
+			parts: []models.TestSessionPart{
+				{SkillType: "listening", Status: "skipped"},
+				{SkillType: "reading", Status: "in_progress"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &models.TestSession{IELTSVariant: tt.variant, Parts: tt.parts}
+			computeTestSessionBands(session)
+
+			check := func(name string, got *float64, want float64) {
+				if want == 0 {
+					if got != nil {
+						t.Errorf("%s band = %.1f, want none", name, *got)
+					}
+					return
+				}
+				if got == nil || *got != want {
+					t.Errorf("%s band = %v, want %.1f", name, got, want)
+				}
+			}
+			check("listening", session.ListeningBand, tt.listening)
+			check("reading", session.ReadingBand, tt.reading)
+			check("writing", session.WritingBand, tt.writing)
+			check("speaking", session.SpeakingBand, tt.speaking)
+			check("overall", session.OverallBand, tt.overall)
+		})
+	}
+}
+
+func TestComputeTestSessionBandsCountsZeroBand(t *testing.T) {
+	// A real 0 band is averaged in, not left out as "not taken"
+	session := &models.TestSession{IELTSVariant: "academic", Parts: []models.TestSessionPart{
+		lrPart("listening", "graded", 30, 40),
+		lrPart("reading", "graded", 30, 40),
+		bandPart("writing", "graded", 0, "task2"),
+		bandPart("speaking", "graded", 6.0, ""),
+	}}
+	computeTestSessionBands(session)
+
+	if session.WritingBand == nil || *session.WritingBand != 0 {
+		t.Errorf("writing band = %v, want 0", session.WritingBand)
+	}
+	if session.OverallBand == nil || *session.OverallBand != 5.0 {
+		t.Errorf("overall band = %v, want 5.0", session.OverallBand)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/bisosad1501/ielts-platform/notification-service/internal/config"
 	"github.com/bisosad1501/ielts-platform/notification-service/internal/database"
@@ -42,6 +43,41 @@ func main() {
 	internalHandler := handlers.NewInternalHandler(notificationService)
 	authMiddleware := middleware.NewAuthMiddleware(cfg.JWTSecret, cfg.InternalAPIKey)
 
+	// Start scheduled notification dispatcher
+	scheduledDispatcher := service.NewScheduledDispatcher(
+		notificationRepo,
+		notificationService,
+		time.Duration(cfg.Scheduler.PollIntervalSeconds)*time.Second,
+		cfg.Scheduler.BatchSize,
+		time.Duration(cfg.Scheduler.ClaimTimeoutSeconds)*time.Second,
+	)
+	go scheduledDispatcher.Start()
+
+	// Start email delivery worker
+	var emailSender service.EmailSender
+	if cfg.Email.Provider == "fake" {
+		log.Println("📧 Email provider: fake (emails are logged, not sent)")
+		emailSender = service.NewFakeEmailSender()
+	} else {
+		emailSender = service.NewSMTPEmailSender(
+			cfg.Email.SMTPHost,
+			cfg.Email.SMTPPort,
+			cfg.Email.SMTPUsername,
+			cfg.Email.SMTPPassword,
+			cfg.Email.FromEmail,
+			cfg.Email.FromName,
+		)
+	}
+	emailWorker := service.NewEmailWorker(notificationRepo, emailSender, service.EmailWorkerConfig{
+		PollInterval: time.Duration(cfg.Email.PollIntervalSeconds) * time.Second,
+		BatchSize:    cfg.Email.BatchSize,
+		ClaimTimeout: 5 * time.Minute,
+		MaxAttempts:  cfg.Email.MaxAttempts,
+		RetryBase:    time.Minute,
+		RetryMax:     time.Hour,
+	})
+	go emailWorker.Start()
+
 	// Setup Gin
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
@@ -65,4 +101,6 @@ func main() {
 
 	<-quit
 	log.Println("🛑 Shutting down Notification Service...")
+	scheduledDispatcher.Stop()
+	emailWorker.Stop()
 }
@@ -11,6 +11,8 @@ type Config struct {
 	JWTSecret      string
 	InternalAPIKey string
 	Database       DatabaseConfig
+	Scheduler      SchedulerConfig
+	Email          EmailConfig
 }
 
 type DatabaseConfig struct {
@@ -21,6 +23,27 @@ type DatabaseConfig struct {
 	DBName   string
 }
 
+// SchedulerConfig controls the scheduled notification dispatcher
+type SchedulerConfig struct {
+	PollIntervalSeconds int // How often to look for due schedules
+	BatchSize           int // Max schedules claimed per poll
+	ClaimTimeoutSeconds int // How long a claim blocks other replicas before it can be retried
+}
+
+// EmailConfig controls the email channel
+type EmailConfig struct {
+	Provider            string // smtp or fake (records emails without sending)
+	SMTPHost            string
+	SMTPPort            string
+	SMTPUsername        string
+	SMTPPassword        string
+	FromEmail           string
+	FromName            string
+	PollIntervalSeconds int
+	BatchSize           int
+	MaxAttempts         int
+}
+
 func LoadConfig() (*Config, error) {
 	config := &Config{
 		ServerPort:     getEnv("SERVER_PORT", "8085"),
@@ -33,6 +56,23 @@ func LoadConfig() (*Config, error) {
 			Password: getEnv("DB_PASSWORD", "postgres"),
 			DBName:   getEnv("DB_NAME", "notification_db"),
 		},
+		Scheduler: SchedulerConfig{
+			PollIntervalSeconds: getEnvAsInt("SCHEDULER_POLL_INTERVAL_SECONDS", 30),
+			BatchSize:           getEnvAsInt("SCHEDULER_BATCH_SIZE", 100),
+			ClaimTimeoutSeconds: getEnvAsInt("SCHEDULER_CLAIM_TIMEOUT_SECONDS", 300),
+		},
+		Email: EmailConfig{
+			Provider:            getEnv("EMAIL_PROVIDER", "smtp"),
+			SMTPHost:            getEnv("SMTP_HOST", "smtp.gmail.com"),
+			SMTPPort:            getEnv("SMTP_PORT", "587"),
+			SMTPUsername:        getEnv("SMTP_USERNAME", ""),
+			SMTPPassword:        getEnv("SMTP_PASSWORD", ""),
+			FromEmail:           getEnv("SMTP_FROM_EMAIL", "noreply@ieltsplatform.com"),
+			FromName:            getEnv("SMTP_FROM_NAME", "IELTS Learning Platform"),
+			PollIntervalSeconds: getEnvAsInt("EMAIL_POLL_INTERVAL_SECONDS", 15),
+			BatchSize:           getEnvAsInt("EMAIL_BATCH_SIZE", 50),
+			MaxAttempts:         getEnvAsInt("EMAIL_MAX_ATTEMPTS", 5),
+		},
 	}
 
 	if config.JWTSecret == "" {
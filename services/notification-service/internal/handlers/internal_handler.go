@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
@@ -25,14 +26,20 @@ func NewInternalHandler(notificationService *service.NotificationService) *Inter
 // SendNotificationInternalRequest represents request to send notification from other services
 type SendNotificationInternalRequest struct {
 	UserID     uuid.UUID              `json:"user_id" binding:"required"`
-	Title      string                 `json:"title" binding:"required"`
-	Message    string                 `json:"message" binding:"required"`
+	Title      string                 `json:"title" binding:"required_without=TemplateCode"`
+	Message    string                 `json:"message" binding:"required_without=TemplateCode"`
 	Type       string                 `json:"type" binding:"required,oneof=achievement reminder course_update exercise_graded system social"`
 	Category   string                 `json:"category" binding:"required,oneof=info success warning alert"`
 	ActionType *string                `json:"action_type,omitempty"`
 	ActionData map[string]interface{} `json:"action_data,omitempty"`
 	IconURL    *string                `json:"icon_url,omitempty"`
 	ImageURL   *string                `json:"image_url,omitempty"`
+
+	// Email delivery (optional)
+	Channel      string            `json:"channel,omitempty" binding:"omitempty,oneof=in_app email both"`
+	Email        *string           `json:"email,omitempty" binding:"omitempty,email"`
+	TemplateCode *string           `json:"template_code,omitempty"`
+	TemplateData map[string]string `json:"template_data,omitempty"`
 }
 
 // SendBulkNotificationInternalRequest represents request to send bulk notification
@@ -72,12 +79,17 @@ func (h *InternalHandler) SendNotificationInternal(c *gin.Context) {
 		ActionData: req.ActionData,
 		IconURL:    req.IconURL,
 		ImageURL:   req.ImageURL,
+
+		Channel:      req.Channel,
+		Email:        req.Email,
+		TemplateCode: req.TemplateCode,
+		TemplateData: req.TemplateData,
 	}
 
 	notification, err := h.notificationService.CreateNotification(createReq)
 	if err != nil {
 		// Check if error is due to user preferences blocking
-		if err.Error() == "notification blocked by user preferences" {
+		if errors.Is(err, service.ErrNotificationBlocked) {
 			// Return 200 OK when blocked by preferences - this is expected behavior, not an error
 			// Other services should treat this as success (notification not needed)
 			log.Printf("[Internal] Notification blocked by user preferences for user %s (type: %s)", req.UserID, req.Type)
@@ -88,6 +100,13 @@ func (h *InternalHandler) SendNotificationInternal(c *gin.Context) {
 			})
 			return
 		}
+		if errors.Is(err, service.ErrMissingRecipientEmail) || errors.Is(err, service.ErrInvalidEmailHeader) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "validation_error",
+				Message: err.Error(),
+			})
+			return
+		}
 		log.Printf("[Internal] Failed to create notification for user %s: %v", req.UserID, err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "creation_failed",
@@ -96,6 +115,16 @@ func (h *InternalHandler) SendNotificationInternal(c *gin.Context) {
 		return
 	}
 
+	// Email-only delivery has no in-app notification
+	if notification == nil {
+		log.Printf("[Internal] Queued email for user %s (type: %s)", req.UserID, req.Type)
+		c.JSON(http.StatusAccepted, gin.H{
+			"success": true,
+			"message": "Email queued for delivery",
+		})
+		return
+	}
+
 	log.Printf("[Internal] Successfully created notification %s for user %s (type: %s, category: %s)",
 		notification.ID, req.UserID, req.Type, req.Category)
 
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -470,7 +471,7 @@ func (h *NotificationHandler) CreateNotification(c *gin.Context) {
 	// Create notification
 	notification, err := h.service.CreateNotification(&req)
 	if err != nil {
-		if err.Error() == "notification blocked by user preferences" {
+		if errors.Is(err, service.ErrNotificationBlocked) {
 			// Return 200 OK when blocked by preferences - this is expected behavior, not an error
 			// The notification was not created, which is the desired outcome when user blocks it
 			c.JSON(http.StatusOK, models.SuccessResponse{
@@ -481,6 +482,13 @@ func (h *NotificationHandler) CreateNotification(c *gin.Context) {
 			})
 			return
 		}
+		if errors.Is(err, service.ErrMissingRecipientEmail) || errors.Is(err, service.ErrInvalidEmailHeader) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "validation_error",
+				Message: err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "internal_error",
 			Message: "Failed to create notification: " + err.Error(),
@@ -488,6 +496,14 @@ func (h *NotificationHandler) CreateNotification(c *gin.Context) {
 		return
 	}
 
+	// Email-only delivery has no in-app notification
+	if notification == nil {
+		c.JSON(http.StatusAccepted, models.SuccessResponse{
+			Message: "Email queued for delivery",
+		})
+		return
+	}
+
 	resp := h.convertToNotificationResponse(notification)
 	c.JSON(http.StatusCreated, resp)
 }
@@ -25,9 +25,10 @@ func NewAuthMiddleware(jwtSecret, internalAPIKey string) *AuthMiddleware {
 
 // Claims represents JWT claims structure
 type Claims struct {
-	UserID uuid.UUID `json:"user_id"`
-	Email  string    `json:"email"`
-	Role   string    `json:"role"`
+	UserID      uuid.UUID `json:"user_id"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	Permissions []string  `json:"permissions,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -47,6 +48,9 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 				if role := c.GetHeader("X-User-Role"); role != "" {
 					c.Set("role", role)
 				}
+				if permissions := c.GetHeader("X-User-Permissions"); permissions != "" {
+					c.Set("permissions", strings.Split(permissions, ","))
+				}
 				c.Next()
 				return
 			}
@@ -113,6 +117,7 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
 		c.Set("role", claims.Role)
+		c.Set("permissions", claims.Permissions)
 
 		c.Next()
 	}
@@ -157,6 +162,30 @@ func (m *AuthMiddleware) RequireRole(roles ...string) gin.HandlerFunc {
 	}
 }
 
+// RequirePermission checks that the user holds at least one of the given permissions
+func (m *AuthMiddleware) RequirePermission(requiredPermissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, _ := c.Get("permissions")
+		permissions, _ := granted.([]string)
+
+		for _, required := range requiredPermissions {
+			for _, p := range permissions {
+				if p == required {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "forbidden",
+			Message: fmt.Sprintf("Access denied. Required permission: %v", requiredPermissions),
+			Code:    "AUTH_006",
+		})
+		c.Abort()
+	}
+}
+
 // InternalAuth validates internal API key for service-to-service communication
 func (m *AuthMiddleware) InternalAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
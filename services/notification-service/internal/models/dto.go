@@ -17,6 +17,13 @@ type CreateNotificationRequest struct {
 	ImageURL     *string                `json:"image_url,omitempty"`
 	ScheduledFor *string                `json:"scheduled_for,omitempty"` // ISO8601 timestamp
 	ExpiresAt    *string                `json:"expires_at,omitempty"`    // ISO8601 timestamp
+
+	// Delivery channel: in_app, email or both. Defaults to the template's
+	// notification_type, or in_app when no template is used.
+	Channel      string            `json:"channel,omitempty" binding:"omitempty,oneof=in_app email both"`
+	Email        *string           `json:"email,omitempty" binding:"omitempty,email"` // Recipient address for the email channel
+	TemplateCode *string           `json:"template_code,omitempty"`                   // notification_templates.template_code
+	TemplateData map[string]string `json:"template_data,omitempty"`                   // Variables for the template
 }
 
 // NotificationResponse represents notification response
@@ -168,7 +175,7 @@ type SuccessResponse struct {
 type CreateScheduledNotificationRequest struct {
 	Title         string `json:"title" binding:"required,max=200"`
 	Message       string `json:"message" binding:"required,max=1000"`
-	ScheduleType  string `json:"schedule_type" binding:"required,oneof=daily weekly monthly custom"`
+	ScheduleType  string `json:"schedule_type" binding:"required,oneof=once daily weekly monthly custom"`
 	ScheduledTime string `json:"scheduled_time" binding:"required"` // "09:00:00"
 	DaysOfWeek    []int  `json:"days_of_week,omitempty"`            // [1,2,3,4,5] for Mon-Fri
 	Timezone      string `json:"timezone"`                          // default: "Asia/Ho_Chi_Minh"
@@ -27,6 +27,17 @@ type Notification struct {
 	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// DeferredReason is set when delivery was held back by user preferences
+	// (quiet_hours, daily_limit). Internal only.
+	DeferredReason *string `json:"-"`
+}
+
+// DeliveryDecision is the result of checking a notification against user preferences
+type DeliveryDecision struct {
+	Allowed     bool       // false when the user turned this kind of notification off
+	DeferUntil  *time.Time // when set, hold the notification until this time (UTC)
+	DeferReason string     // quiet_hours or daily_limit
 }
 
 // DeviceToken represents a user's device for push notifications
@@ -97,7 +108,7 @@ type EmailNotification struct {
 	BodyText       *string    `json:"body_text,omitempty"`
 	TemplateName   *string    `json:"template_name,omitempty"`
 	TemplateData   *string    `json:"template_data,omitempty"` // JSON string
-	Status         string     `json:"status"`                  // pending, sent, delivered, bounced, failed
+	Status         string     `json:"status"`                  // pending, sending, sent, delivered, bounced, failed
 	SentAt         *time.Time `json:"sent_at,omitempty"`
 	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
 	OpenedAt       *time.Time `json:"opened_at,omitempty"`
@@ -105,7 +116,9 @@ type EmailNotification struct {
 	ExternalID     *string    `json:"external_id,omitempty"`
 	ErrorMessage   *string    `json:"error_message,omitempty"`
 	RetryCount     int        `json:"retry_count"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
 	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
 // NotificationTemplate represents a reusable notification template
@@ -135,7 +148,7 @@ type ScheduledNotification struct {
 	UserID        uuid.UUID  `json:"user_id"`
 	Title         string     `json:"title"`
 	Message       string     `json:"message"`
-	ScheduleType  string     `json:"schedule_type"`  // daily, weekly, monthly, custom
+	ScheduleType  string     `json:"schedule_type"`  // once, daily, weekly, monthly, custom
 	ScheduledTime string     `json:"scheduled_time"` // TIME string "09:00:00"
 	DaysOfWeek    IntArray   `json:"days_of_week"`   // [1,2,3,4,5] for Mon-Fri
 	Timezone      string     `json:"timezone"`
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ReleaseDeferredNotifications marks up to limit held-back notifications as sent once their
+// scheduled_for time has passed (quiet hours over, or an explicit scheduled_for), and returns
+// them so they can be pushed to the user. The user's daily cap is checked again on release:
+// notifications beyond it stay deferred as daily-limit overflow for the next digest.
+// Daily-limit overflow itself is handled by ReleaseOverflowNotifications.
+func (r *NotificationRepository) ReleaseDeferredNotifications(now time.Time, limit int) ([]models.Notification, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id, user_id, type, category, title, message,
+			action_type, action_data, icon_url, image_url,
+			is_read, read_at, is_sent, sent_at,
+			scheduled_for, expires_at, created_at, updated_at
+		FROM notifications
+		WHERE is_sent = false
+		  AND scheduled_for <= $1
+		  AND deferred_reason IS DISTINCT FROM $3
+		ORDER BY scheduled_for ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, now, limit, DeferReasonDailyLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release deferred notifications: %w", err)
+	}
+
+	var due []models.Notification
+	var userIDs []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var n models.Notification
+		err := rows.Scan(
+			&n.ID, &n.UserID, &n.Type, &n.Category, &n.Title, &n.Message,
+			&n.ActionType, &n.ActionData, &n.IconURL, &n.ImageURL,
+			&n.IsRead, &n.ReadAt, &n.IsSent, &n.SentAt,
+			&n.ScheduledFor, &n.ExpiresAt, &n.CreatedAt, &n.UpdatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		due = append(due, n)
+		if !seen[n.UserID] {
+			seen[n.UserID] = true
+			userIDs = append(userIDs, n.UserID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read deferred notifications: %w", err)
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+
+	prefsByUser, sentToday, err := r.loadDeliveryState(userIDs, now)
+	if err != nil {
+		return nil, err
+	}
+	release, overflow := capDeferredRelease(due, prefsByUser, sentToday, now)
+
+	if len(release) > 0 {
+		ids := make([]uuid.UUID, len(release))
+		for i := range release {
+			ids[i] = release[i].ID
+			release[i].IsSent = true
+			release[i].SentAt = &now
+			release[i].UpdatedAt = now
+		}
+		if _, err := tx.Exec(`
+			UPDATE notifications
+			SET is_sent = true, sent_at = $1, updated_at = $1
+			WHERE id = ANY($2)
+		`, now, pq.Array(ids)); err != nil {
+			return nil, fmt.Errorf("failed to mark deferred notifications sent: %w", err)
+		}
+	}
+
+	for id, digestAt := range overflow {
+		if _, err := tx.Exec(`
+			UPDATE notifications
+			SET deferred_reason = $1, scheduled_for = $2, updated_at = $3
+			WHERE id = $4
+		`, DeferReasonDailyLimit, digestAt, now, id); err != nil {
+			return nil, fmt.Errorf("failed to hold back notification over the daily limit: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return release, nil
+}
+
+// ReleaseOverflowNotifications delivers notifications held back by the daily limit as one
+// digest per user. The held-back notifications become visible in the user's list without
+// being pushed individually; buildDigest creates the single notification that is pushed.
+// Marking and digest creation happen in one transaction. Released items keep their
+// deferred_reason, which leaves them out of the daily count of the day they are released.
+func (r *NotificationRepository) ReleaseOverflowNotifications(now time.Time, limit int, buildDigest func(userID uuid.UUID, items []models.Notification) *models.Notification) ([]*models.Notification, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		UPDATE notifications
+		SET is_sent = true, sent_at = $1, updated_at = $1
+		WHERE id IN (
+			SELECT id FROM notifications
+			WHERE is_sent = false
+			  AND deferred_reason = $3
+			  AND scheduled_for <= $1
+			ORDER BY user_id, created_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, type, category, title, message, created_at
+	`, now, limit, DeferReasonDailyLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release overflow notifications: %w", err)
+	}
+
+	var userOrder []uuid.UUID
+	itemsByUser := make(map[uuid.UUID][]models.Notification)
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Category, &n.Title, &n.Message, &n.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan overflow notification: %w", err)
+		}
+		if _, seen := itemsByUser[n.UserID]; !seen {
+			userOrder = append(userOrder, n.UserID)
+		}
+		itemsByUser[n.UserID] = append(itemsByUser[n.UserID], n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read overflow notifications: %w", err)
+	}
+
+	var digests []*models.Notification
+	for _, userID := range userOrder {
+		digest := buildDigest(userID, itemsByUser[userID])
+		_, err := tx.Exec(`
+			INSERT INTO notifications (
+				id, user_id, type, category, title, message,
+				action_type, action_data, icon_url, image_url,
+				is_read, is_sent, sent_at, scheduled_for, expires_at,
+				created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		`,
+			digest.ID, digest.UserID, digest.Type, digest.Category,
+			digest.Title, digest.Message, digest.ActionType, digest.ActionData,
+			digest.IconURL, digest.ImageURL, digest.IsRead, digest.IsSent,
+			digest.SentAt, digest.ScheduledFor, digest.ExpiresAt,
+			digest.CreatedAt, digest.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create digest notification: %w", err)
+		}
+		digests = append(digests, digest)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return digests, nil
+}
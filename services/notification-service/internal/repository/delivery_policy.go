@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	DeferReasonQuietHours = "quiet_hours"
+	DeferReasonDailyLimit = "daily_limit"
+
+	defaultUserTimezone = "Asia/Ho_Chi_Minh"
+
+	// WeeklyReportTemplateCode is the template used by user-service's weekly progress email
+	WeeklyReportTemplateCode = "weekly_progress_report"
+)
+
+// isUrgentNotification reports whether a notification bypasses quiet hours and the daily cap
+func isUrgentNotification(notifType, category string) bool {
+	return category == "alert" || notifType == "system"
+}
+
+// typeAllowed applies the channel and per-type switches from the user's preferences
+func typeAllowed(prefs *models.NotificationPreferences, notifType string) bool {
+	// push_enabled is the master switch: when off, block all notifications (both push and in-app)
+	if !prefs.PushEnabled || !prefs.InAppEnabled {
+		return false
+	}
+
+	switch notifType {
+	case "achievement":
+		return prefs.PushAchievements
+	case "reminder":
+		return prefs.PushReminders
+	case "course_update":
+		return prefs.PushCourseUpdates
+	case "exercise_graded":
+		return prefs.PushExerciseGraded
+	}
+
+	// social and system have no dedicated switch yet
+	return true
+}
+
+// emailAllowed applies the email switches from the user's preferences.
+// Transactional types (system, exercise_graded, ...) only need email_enabled.
+func emailAllowed(prefs *models.NotificationPreferences, notifType, templateCode string) bool {
+	if !prefs.EmailEnabled {
+		return false
+	}
+
+	if templateCode == WeeklyReportTemplateCode {
+		return prefs.EmailWeeklyReport
+	}
+	if notifType == "course_update" {
+		return prefs.EmailCourseUpdates
+	}
+
+	return true
+}
+
+// userLocation resolves the user's timezone, falling back to the platform default
+func userLocation(timezone string) *time.Location {
+	if timezone != "" {
+		if loc, err := time.LoadLocation(timezone); err == nil {
+			return loc
+		}
+	}
+	if loc, err := time.LoadLocation(defaultUserTimezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// parseClock converts "HH:MM" or "HH:MM:SS" to seconds since midnight
+func parseClock(value string) (int, bool) {
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	limits := []int{23, 59, 59}
+	seconds := 0
+	for i, unit := range []int{3600, 60, 1} {
+		v := 0
+		if i < len(parts) {
+			n, err := strconv.Atoi(parts[i])
+			if err != nil || n < 0 || n > limits[i] {
+				return 0, false
+			}
+			v = n
+		}
+		seconds += v * unit
+	}
+	return seconds, true
+}
+
+// localDayStart returns midnight of t's day in loc
+func localDayStart(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+}
+
+// quietHoursEnd returns when the user's quiet window ends if t falls inside it, or nil.
+// Windows may span midnight (e.g. 22:00 - 07:00).
+func quietHoursEnd(prefs *models.NotificationPreferences, t time.Time, loc *time.Location) *time.Time {
+	if !prefs.QuietHoursEnabled || prefs.QuietHoursStart == nil || prefs.QuietHoursEnd == nil {
+		return nil
+	}
+	start, ok1 := parseClock(*prefs.QuietHoursStart)
+	end, ok2 := parseClock(*prefs.QuietHoursEnd)
+	if !ok1 || !ok2 || start == end {
+		return nil
+	}
+
+	dayStart := localDayStart(t, loc)
+	local := t.In(loc)
+	now := local.Hour()*3600 + local.Minute()*60 + local.Second()
+
+	var endsAt time.Time
+	switch {
+	case start < end && now >= start && now < end:
+		endsAt = dayStart.Add(time.Duration(end) * time.Second)
+	case start > end && now >= start:
+		endsAt = dayStart.AddDate(0, 0, 1).Add(time.Duration(end) * time.Second)
+	case start > end && now < end:
+		endsAt = dayStart.Add(time.Duration(end) * time.Second)
+	default:
+		return nil
+	}
+
+	endsAt = endsAt.UTC()
+	return &endsAt
+}
+
+// decideDelivery applies quiet hours and the daily cap to a notification the user accepts.
+// sentToday is the number of notifications already delivered in the user's local day.
+// Deferred notifications are released at the end of the quiet window; over-limit ones
+// are rolled into a digest at the start of the next local day (after quiet hours).
+func decideDelivery(prefs *models.NotificationPreferences, notifType, category string, sentToday int, now time.Time) *models.DeliveryDecision {
+	if !typeAllowed(prefs, notifType) {
+		return &models.DeliveryDecision{Allowed: false}
+	}
+	if isUrgentNotification(notifType, category) {
+		return &models.DeliveryDecision{Allowed: true}
+	}
+
+	loc := userLocation(prefs.Timezone)
+
+	if until := quietHoursEnd(prefs, now, loc); until != nil {
+		return &models.DeliveryDecision{Allowed: true, DeferUntil: until, DeferReason: DeferReasonQuietHours}
+	}
+
+	if overLimit := dailyLimitDeferral(prefs, sentToday, now, loc); overLimit != nil {
+		return overLimit
+	}
+
+	return &models.DeliveryDecision{Allowed: true}
+}
+
+// dailyLimitDeferral holds a notification back for the overflow digest once the user has
+// reached their daily cap. The digest goes out at the start of the next local day, or when
+// quiet hours end if the day starts inside them. Returns nil while under the cap.
+func dailyLimitDeferral(prefs *models.NotificationPreferences, sentToday int, now time.Time, loc *time.Location) *models.DeliveryDecision {
+	if prefs.MaxNotificationsPerDay <= 0 || sentToday < prefs.MaxNotificationsPerDay {
+		return nil
+	}
+
+	digestAt := localDayStart(now, loc).AddDate(0, 0, 1)
+	if until := quietHoursEnd(prefs, digestAt, loc); until != nil {
+		digestAt = *until
+	}
+	digestAt = digestAt.UTC()
+	return &models.DeliveryDecision{Allowed: true, DeferUntil: &digestAt, DeferReason: DeferReasonDailyLimit}
+}
+
+// capDeferredRelease applies the daily cap to notifications that are due for release after
+// quiet hours (or an explicit scheduled_for). They were not counted when they were created,
+// so each user's remaining quota is spent in release order. The excess stays deferred as
+// daily-limit overflow and is returned with the time its digest is due. Urgent notifications
+// and users without preferences are not capped.
+func capDeferredRelease(due []models.Notification, prefsByUser map[uuid.UUID]*models.NotificationPreferences, sentToday map[uuid.UUID]int, now time.Time) (release []models.Notification, overflow map[uuid.UUID]time.Time) {
+	overflow = make(map[uuid.UUID]time.Time)
+	counted := make(map[uuid.UUID]int, len(sentToday))
+	for userID, count := range sentToday {
+		counted[userID] = count
+	}
+
+	for _, n := range due {
+		prefs, exists := prefsByUser[n.UserID]
+		if !exists || isUrgentNotification(n.Type, n.Category) {
+			release = append(release, n)
+			continue
+		}
+
+		if overLimit := dailyLimitDeferral(prefs, counted[n.UserID], now, userLocation(prefs.Timezone)); overLimit != nil {
+			overflow[n.ID] = *overLimit.DeferUntil
+			continue
+		}
+		counted[n.UserID]++
+		release = append(release, n)
+	}
+
+	return release, overflow
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/models"
+	"github.com/google/uuid"
+)
+
+func quietPrefs(start, end string) *models.NotificationPreferences {
+	return &models.NotificationPreferences{
+		PushEnabled:       true,
+		PushReminders:     true,
+		InAppEnabled:      true,
+		QuietHoursEnabled: true,
+		QuietHoursStart:   &start,
+		QuietHoursEnd:     &end,
+		Timezone:          "UTC",
+	}
+}
+
+func utc(day, hour, minute int) time.Time {
+	return time.Date(2026, time.March, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestQuietHoursEnd(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end string
+		now        time.Time
+		want       *time.Time
+	}{
+		{"same-day window, inside", "13:00", "15:00", utc(10, 14, 0), ptr(utc(10, 15, 0))},
+		{"same-day window, before", "13:00", "15:00", utc(10, 12, 59), nil},
+		{"same-day window, end is exclusive", "13:00", "15:00", utc(10, 15, 0), nil},
+		{"overnight window, evening", "22:00", "07:00", utc(10, 23, 30), ptr(utc(11, 7, 0))},
+		{"overnight window, at start", "22:00", "07:00", utc(10, 22, 0), ptr(utc(11, 7, 0))},
+		{"overnight window, after midnight", "22:00", "07:00", utc(11, 2, 0), ptr(utc(11, 7, 0))},
+		{"overnight window, daytime", "22:00", "07:00", utc(10, 12, 0), nil},
+		{"overnight window, at end", "22:00", "07:00", utc(11, 7, 0), nil},
+		{"seconds in the clock", "22:00:00", "06:30:00", utc(10, 23, 0), ptr(utc(11, 6, 30))},
+		{"empty window", "22:00", "22:00", utc(10, 22, 30), nil},
+		{"invalid clock", "25:00", "07:00", utc(10, 23, 0), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := quietHoursEnd(quietPrefs(tt.start, tt.end), tt.now, time.UTC)
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("quietHoursEnd(%s-%s, %s) = %v, want %v", tt.start, tt.end, tt.now.Format("02 15:04"), got, tt.want)
+			}
+		})
+	}
+
+	disabled := quietPrefs("22:00", "07:00")
+	disabled.QuietHoursEnabled = false
+	if got := quietHoursEnd(disabled, utc(10, 23, 0), time.UTC); got != nil {
+		t.Errorf("disabled quiet hours = %v", got)
+	}
+}
+
+func TestQuietHoursEndInUserTimezone(t *testing.T) {
+	// 16:00 UTC is 23:00 at UTC+7: inside a 22:00-07:00 window that ends at 00:00 UTC
+	loc := time.FixedZone("UTC+7", 7*3600)
+	got := quietHoursEnd(quietPrefs("22:00", "07:00"), utc(10, 16, 0), loc)
+	if want := utc(11, 0, 0); got == nil || !got.Equal(want) {
+		t.Errorf("quietHoursEnd = %v, want %v", got, want)
+	}
+}
+
+func TestDecideDelivery(t *testing.T) {
+	night := utc(10, 23, 0)
+	day := utc(10, 12, 0)
+
+	tests := []struct {
+		name      string
+		notifType string
+		category  string
+		sentToday int
+		limit     int
+		now       time.Time
+		want      models.DeliveryDecision
+	}{
+		{"daytime under the limit", "reminder", "info", 2, 5, day, models.DeliveryDecision{Allowed: true}},
+		{"no limit", "reminder", "info", 100, 0, day, models.DeliveryDecision{Allowed: true}},
+		{"quiet hours", "reminder", "info", 0, 5, night,
+			models.DeliveryDecision{Allowed: true, DeferUntil: ptr(utc(11, 7, 0)), DeferReason: DeferReasonQuietHours}},
+		// The digest goes out at midnight, which is inside quiet hours, so it waits until 07:00
+		{"daily limit reached", "reminder", "info", 5, 5, day,
+			models.DeliveryDecision{Allowed: true, DeferUntil: ptr(utc(11, 7, 0)), DeferReason: DeferReasonDailyLimit}},
+		{"urgent alert in quiet hours", "reminder", "alert", 0, 5, night, models.DeliveryDecision{Allowed: true}},
+		{"system over the limit", "system", "info", 9, 5, day, models.DeliveryDecision{Allowed: true}},
+		{"type switched off", "achievement", "info", 0, 5, day, models.DeliveryDecision{Allowed: false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := quietPrefs("22:00", "07:00")
+			prefs.MaxNotificationsPerDay = tt.limit
+			got := decideDelivery(prefs, tt.notifType, tt.category, tt.sentToday, tt.now)
+
+			sameUntil := (got.DeferUntil == nil) == (tt.want.DeferUntil == nil) &&
+				(got.DeferUntil == nil || got.DeferUntil.Equal(*tt.want.DeferUntil))
+			if got.Allowed != tt.want.Allowed || got.DeferReason != tt.want.DeferReason || !sameUntil {
+				t.Errorf("decideDelivery = {%v %v %q}, want {%v %v %q}",
+					got.Allowed, got.DeferUntil, got.DeferReason, tt.want.Allowed, tt.want.DeferUntil, tt.want.DeferReason)
+			}
+		})
+	}
+
+	// Without quiet hours the digest goes out at the user's next local midnight
+	prefs := quietPrefs("22:00", "07:00")
+	prefs.QuietHoursEnabled = false
+	prefs.MaxNotificationsPerDay = 3
+	got := decideDelivery(prefs, "reminder", "info", 3, day)
+	if got.DeferUntil == nil || !got.DeferUntil.Equal(utc(11, 0, 0)) {
+		t.Errorf("digest time = %v, want next midnight", got.DeferUntil)
+	}
+}
+
+func TestCapDeferredRelease(t *testing.T) {
+	// Quiet hours (22:00-07:00) just ended; the capped user has 1 of 3 slots used today
+	now := utc(11, 7, 0)
+	capped, uncapped, noPrefs := uuid.New(), uuid.New(), uuid.New()
+
+	cappedPrefs := quietPrefs("22:00", "07:00")
+	cappedPrefs.MaxNotificationsPerDay = 3
+	prefsByUser := map[uuid.UUID]*models.NotificationPreferences{
+		capped:   cappedPrefs,
+		uncapped: quietPrefs("22:00", "07:00"),
+	}
+	sentToday := map[uuid.UUID]int{capped: 1}
+
+	notification := func(userID uuid.UUID, notifType, category string) models.Notification {
+		return models.Notification{ID: uuid.New(), UserID: userID, Type: notifType, Category: category}
+	}
+	due := []models.Notification{
+		notification(capped, "reminder", "info"),
+		notification(capped, "reminder", "info"),
+		notification(capped, "system", "info"), // urgent: not capped and not counted
+		notification(capped, "reminder", "info"),
+		notification(capped, "achievement", "info"),
+		notification(uncapped, "reminder", "info"),
+		notification(noPrefs, "reminder", "info"),
+	}
+
+	release, overflow := capDeferredRelease(due, prefsByUser, sentToday, now)
+
+	wantReleased := []int{0, 1, 2, 5, 6}
+	if len(release) != len(wantReleased) {
+		t.Fatalf("released %d notifications, want %d", len(release), len(wantReleased))
+	}
+	for i, idx := range wantReleased {
+		if release[i].ID != due[idx].ID {
+			t.Errorf("release[%d] is due[%d]'s notification, want due[%d]", i, indexOf(due, release[i].ID), idx)
+		}
+	}
+
+	// The excess waits for tomorrow's digest, after quiet hours
+	for _, idx := range []int{3, 4} {
+		digestAt, held := overflow[due[idx].ID]
+		if !held {
+			t.Errorf("due[%d] was not held back", idx)
+			continue
+		}
+		if want := utc(12, 7, 0); !digestAt.Equal(want) {
+			t.Errorf("due[%d] digest at %v, want %v", idx, digestAt, want)
+		}
+	}
+	if len(overflow) != 2 {
+		t.Errorf("held back %d notifications, want 2", len(overflow))
+	}
+	if sentToday[capped] != 1 {
+		t.Errorf("capDeferredRelease modified the caller's counts: %d", sentToday[capped])
+	}
+}
+
+func indexOf(notifications []models.Notification, id uuid.UUID) int {
+	for i := range notifications {
+		if notifications[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
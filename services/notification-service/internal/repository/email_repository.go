@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// CreateEmailNotification queues an email for delivery
+func (r *NotificationRepository) CreateEmailNotification(email *models.EmailNotification) error {
+	query := `
+		INSERT INTO email_notifications (
+			id, notification_id, user_id, to_email, subject, body_html, body_text,
+			template_name, template_data, status, retry_count, next_attempt_at,
+			created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err := r.db.Exec(query,
+		email.ID,
+		email.NotificationID,
+		email.UserID,
+		email.ToEmail,
+		email.Subject,
+		email.BodyHTML,
+		email.BodyText,
+		email.TemplateName,
+		email.TemplateData,
+		email.Status,
+		email.RetryCount,
+		email.NextAttemptAt,
+		email.CreatedAt,
+		email.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create email notification: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimPendingEmails claims up to limit emails that are due for a (re)try. Claimed rows move
+// to 'sending' with next_attempt_at pushed out by claimTimeout; if the sender crashes the
+// claim lapses and the email is picked up again. SKIP LOCKED keeps replicas apart.
+func (r *NotificationRepository) ClaimPendingEmails(now time.Time, limit int, claimTimeout time.Duration) ([]models.EmailNotification, error) {
+	query := `
+		UPDATE email_notifications
+		SET status = 'sending',
+			next_attempt_at = $1::timestamp + ($2 * INTERVAL '1 second'),
+			updated_at = $1
+		WHERE id IN (
+			SELECT id FROM email_notifications
+			WHERE status IN ('pending', 'sending')
+			  AND next_attempt_at <= $1
+			ORDER BY next_attempt_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, notification_id, user_id, to_email, subject, body_html, body_text,
+			template_name, template_data::TEXT, status, sent_at, error_message, retry_count,
+			next_attempt_at, created_at, updated_at
+	`
+
+	rows, err := r.db.Query(query, now, int(claimTimeout.Seconds()), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pending emails: %w", err)
+	}
+	defer rows.Close()
+
+	var emails []models.EmailNotification
+	for rows.Next() {
+		var e models.EmailNotification
+		err := rows.Scan(
+			&e.ID, &e.NotificationID, &e.UserID, &e.ToEmail, &e.Subject, &e.BodyHTML, &e.BodyText,
+			&e.TemplateName, &e.TemplateData, &e.Status, &e.SentAt, &e.ErrorMessage, &e.RetryCount,
+			&e.NextAttemptAt, &e.CreatedAt, &e.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan email notification: %w", err)
+		}
+		emails = append(emails, e)
+	}
+
+	return emails, rows.Err()
+}
+
+// MarkEmailSent records a successful delivery
+func (r *NotificationRepository) MarkEmailSent(id uuid.UUID, sentAt time.Time) error {
+	query := `
+		UPDATE email_notifications
+		SET status = 'sent', sent_at = $2, error_message = NULL, updated_at = $2
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(query, id, sentAt); err != nil {
+		return fmt.Errorf("failed to mark email sent: %w", err)
+	}
+	return nil
+}
+
+// MarkEmailRetry records a failed attempt and schedules the next one
+func (r *NotificationRepository) MarkEmailRetry(id uuid.UUID, errorMsg string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE email_notifications
+		SET status = 'pending', error_message = $2, retry_count = retry_count + 1,
+			next_attempt_at = $3, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(query, id, errorMsg, nextAttemptAt); err != nil {
+		return fmt.Errorf("failed to schedule email retry: %w", err)
+	}
+	return nil
+}
+
+// MarkEmailFailed gives up on an email after its last attempt
+func (r *NotificationRepository) MarkEmailFailed(id uuid.UUID, errorMsg string) error {
+	query := `
+		UPDATE email_notifications
+		SET status = 'failed', error_message = $2, retry_count = retry_count + 1, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(query, id, errorMsg); err != nil {
+		return fmt.Errorf("failed to mark email failed: %w", err)
+	}
+	return nil
+}
+
+// CanSendEmail checks the user's email preferences for a notification type and template
+func (r *NotificationRepository) CanSendEmail(userID uuid.UUID, notifType, templateCode string) (bool, error) {
+	prefs, err := r.GetNotificationPreferences(userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get preferences: %w", err)
+	}
+	return emailAllowed(prefs, notifType, templateCode), nil
+}
@@ -27,8 +27,8 @@ func (r *NotificationRepository) CreateNotification(notification *models.Notific
 			id, user_id, type, category, title, message,
 			action_type, action_data, icon_url, image_url,
 			is_read, is_sent, sent_at,
-			scheduled_for, expires_at, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+			scheduled_for, expires_at, created_at, updated_at, deferred_reason
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 	`
 
 	_, err := r.db.Exec(query,
@@ -49,6 +49,7 @@ func (r *NotificationRepository) CreateNotification(notification *models.Notific
 		notification.ExpiresAt,
 		notification.CreatedAt,
 		notification.UpdatedAt,
+		notification.DeferredReason,
 	)
 
 	if err != nil {
@@ -60,7 +61,7 @@ func (r *NotificationRepository) CreateNotification(notification *models.Notific
 
 // GetNotifications retrieves notifications with pagination and optional filtering
 func (r *NotificationRepository) GetNotifications(userID uuid.UUID, query *models.NotificationListQuery) ([]models.Notification, int, error) {
-	where := []string{"user_id = $1", "(expires_at IS NULL OR expires_at > NOW())"}
+	where := []string{"user_id = $1", "is_sent = true", "(expires_at IS NULL OR expires_at > NOW())"}
 	args := []interface{}{userID}
 	argCount := 1
 
@@ -253,7 +254,7 @@ func (r *NotificationRepository) MarkAsRead(id uuid.UUID) error {
 func (r *NotificationRepository) MarkAllAsRead(userID uuid.UUID) (int, error) {
 	// Check if there are any unread notifications first (idempotency)
 	var unreadCount int
-	checkQuery := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND is_read = false`
+	checkQuery := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND is_read = false AND is_sent = true`
 	err := r.db.QueryRow(checkQuery, userID).Scan(&unreadCount)
 	if err != nil {
 		return 0, fmt.Errorf("failed to check unread count: %w", err)
@@ -266,7 +267,7 @@ func (r *NotificationRepository) MarkAllAsRead(userID uuid.UUID) (int, error) {
 	query := `
 		UPDATE notifications
 		SET is_read = true, read_at = NOW(), updated_at = NOW()
-		WHERE user_id = $1 AND is_read = false
+		WHERE user_id = $1 AND is_read = false AND is_sent = true
 	`
 
 	result, err := r.db.Exec(query, userID)
@@ -304,7 +305,7 @@ func (r *NotificationRepository) GetUnreadCount(userID uuid.UUID) (int, error) {
     query := `
         SELECT COUNT(*) 
         FROM notifications 
-        WHERE user_id = $1 AND is_read = false 
+        WHERE user_id = $1 AND is_read = false AND is_sent = true
           AND (expires_at IS NULL OR expires_at > NOW())
     `
 
@@ -516,98 +517,36 @@ func (r *NotificationRepository) UpdateNotificationPreferences(prefs *models.Not
 	return nil
 }
 
-// CanSendNotification checks if a notification can be sent based on user preferences
-func (r *NotificationRepository) CanSendNotification(userID uuid.UUID, notifType, category string) (bool, error) {
+// CanSendNotification checks a notification against the user's preferences: per-type
+// switches, quiet hours and the daily limit, all evaluated in the user's timezone
+func (r *NotificationRepository) CanSendNotification(userID uuid.UUID, notifType, category string) (*models.DeliveryDecision, error) {
 	// Get user preferences
 	prefs, err := r.GetNotificationPreferences(userID)
 	if err != nil {
-		return false, fmt.Errorf("failed to get preferences: %w", err)
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
 	}
 
-	// Check if push notifications are enabled (master switch)
-	// If push_enabled=false, block all notifications (both push and in-app)
-	if !prefs.PushEnabled {
-		return false, nil
-	}
-
-	// Check if in-app notifications are enabled
-	if !prefs.InAppEnabled {
-		return false, nil
-	}
-
-	// Check type-specific preferences
-	switch notifType {
-	case "achievement":
-		if !prefs.PushAchievements {
-			return false, nil
-		}
-	case "reminder":
-		if !prefs.PushReminders {
-			return false, nil
-		}
-	case "course_update":
-		if !prefs.PushCourseUpdates {
-			return false, nil
-		}
-	case "exercise_graded":
-		if !prefs.PushExerciseGraded {
-			return false, nil
-		}
-	case "social":
-		// Social notifications (follow, etc.) - use course_updates preference or default to true
-		// If user wants course updates, they likely want social updates too
-		// But we don't block if preference doesn't exist yet
-		// Default to allow social notifications
-		// Future: Add specific PushSocial preference field
-	case "system":
-		// System notifications are always allowed if push_enabled and in_app_enabled
-		// (no additional check needed)
-	}
-
-	// Check quiet hours with user's timezone
-	// FIX #24: Use user's timezone instead of server timezone
-	if prefs.QuietHoursEnabled && prefs.QuietHoursStart != nil && prefs.QuietHoursEnd != nil {
-		// Load user's timezone (use from prefs.Timezone field added in migration 007)
-		timezone := "Asia/Ho_Chi_Minh" // Default
-		// TODO: Get prefs.Timezone from database when field is available
-
-		loc, err := time.LoadLocation(timezone)
-		if err != nil {
-			loc, _ = time.LoadLocation("Asia/Ho_Chi_Minh") // Fallback
-		}
-
-		// Get current time in user's timezone
-		nowInUserTZ := time.Now().In(loc)
-		currentTime := nowInUserTZ.Format("15:04:05")
+	now := time.Now().UTC()
 
-		// Quiet hours span midnight (e.g., 22:00 - 08:00)
-		// Block if: currentTime >= start (after 22:00) OR currentTime <= end (before 08:00)
-		if currentTime >= *prefs.QuietHoursStart || currentTime <= *prefs.QuietHoursEnd {
-			return false, nil
-		}
-	}
-
-	// Check daily limit (only count sent notifications, not scheduled or failed ones)
+	// Count today's delivered notifications (user's local day, not the server's). Overflow
+	// released from an earlier day belongs to that day's quota and is not counted again.
+	sentToday := 0
 	if prefs.MaxNotificationsPerDay > 0 {
+		dayStart := localDayStart(now, userLocation(prefs.Timezone)).UTC()
 		countQuery := `
-			SELECT COUNT(*) 
-			FROM notifications 
-			WHERE user_id = $1 
-			  AND created_at >= CURRENT_DATE
+			SELECT COUNT(*)
+			FROM notifications
+			WHERE user_id = $1
 			  AND is_sent = true
+			  AND sent_at >= $2
+			  AND deferred_reason IS DISTINCT FROM $3
 		`
-		var todayCount int
-		err := r.db.QueryRow(countQuery, userID).Scan(&todayCount)
-		if err != nil {
-			return false, fmt.Errorf("failed to check daily count: %w", err)
-		}
-
-		if todayCount >= prefs.MaxNotificationsPerDay {
-			return false, nil
+		if err := r.db.QueryRow(countQuery, userID, dayStart, DeferReasonDailyLimit).Scan(&sentToday); err != nil {
+			return nil, fmt.Errorf("failed to check daily count: %w", err)
 		}
 	}
 
-	return true, nil
+	return decideDelivery(prefs, notifType, category, sentToday, now), nil
 }
 
 // GetTemplateByCode retrieves a notification template by code
@@ -786,22 +725,21 @@ func (r *NotificationRepository) CreateBulkNotifications(notifications []*models
 			id, user_id, type, category, title, message,
 			action_type, action_data, icon_url, image_url,
 			is_read, is_sent, sent_at, scheduled_for, expires_at,
-			created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+			created_at, updated_at, deferred_reason
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
-	now := time.Now()
 	for _, notif := range notifications {
 		_, err = stmt.Exec(
 			notif.ID, notif.UserID, notif.Type, notif.Category,
 			notif.Title, notif.Message, notif.ActionType, notif.ActionData,
 			notif.IconURL, notif.ImageURL, notif.IsRead, notif.IsSent,
-			&now, notif.ScheduledFor, notif.ExpiresAt,
-			notif.CreatedAt, notif.UpdatedAt,
+			notif.SentAt, notif.ScheduledFor, notif.ExpiresAt,
+			notif.CreatedAt, notif.UpdatedAt, notif.DeferredReason,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to insert notification: %w", err)
@@ -816,51 +754,113 @@ func (r *NotificationRepository) CreateBulkNotifications(notifications []*models
 	return nil
 }
 
-// GetBulkNotificationPreferences checks preferences for multiple users at once
-// FIX #21: Single query instead of N queries for better performance
-func (r *NotificationRepository) GetBulkNotificationPreferences(userIDs []uuid.UUID, notifType, category string) (map[uuid.UUID]bool, error) {
+// GetBulkDeliveryDecisions checks preferences for multiple users at once
+// FIX #21: Batched queries instead of N queries for better performance
+func (r *NotificationRepository) GetBulkDeliveryDecisions(userIDs []uuid.UUID, notifType, category string) (map[uuid.UUID]*models.DeliveryDecision, error) {
+	result := make(map[uuid.UUID]*models.DeliveryDecision)
 	if len(userIDs) == 0 {
-		return make(map[uuid.UUID]bool), nil
+		return result, nil
+	}
+
+	now := time.Now().UTC()
+	prefsByUser, sentToday, err := r.loadDeliveryState(userIDs, now)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, userID := range userIDs {
+		prefs, exists := prefsByUser[userID]
+		if !exists {
+			// For users without preferences, default: allow
+			result[userID] = &models.DeliveryDecision{Allowed: true}
+			continue
+		}
+		result[userID] = decideDelivery(prefs, notifType, category, sentToday[userID], now)
 	}
 
+	return result, nil
+}
+
+// loadDeliveryState loads the preferences of the given users and, for those with a daily
+// limit, how many notifications they have been delivered since their local midnight.
+// Users without a preferences row are absent from the map.
+func (r *NotificationRepository) loadDeliveryState(userIDs []uuid.UUID, now time.Time) (map[uuid.UUID]*models.NotificationPreferences, map[uuid.UUID]int, error) {
 	query := `
-		SELECT user_id,
-			   CASE
-				   WHEN NOT in_app_enabled THEN false
-				   WHEN $2 = 'achievement' AND NOT push_achievements THEN false
-				   WHEN $2 = 'reminder' AND NOT push_reminders THEN false
-				   WHEN $2 = 'course_update' AND NOT push_course_updates THEN false
-				   WHEN $2 = 'exercise_graded' AND NOT push_exercise_graded THEN false
-				   ELSE true
-			   END as can_send
+		SELECT user_id, push_enabled, push_achievements, push_reminders,
+			   push_course_updates, push_exercise_graded, email_enabled,
+			   email_weekly_report, email_course_updates, email_marketing,
+			   in_app_enabled, quiet_hours_enabled,
+			   quiet_hours_start::TEXT, quiet_hours_end::TEXT,
+			   max_notifications_per_day, timezone, updated_at
 		FROM notification_preferences
 		WHERE user_id = ANY($1)
 	`
 
-	rows, err := r.db.Query(query, pq.Array(userIDs), notifType)
+	rows, err := r.db.Query(query, pq.Array(userIDs))
 	if err != nil {
-		return nil, fmt.Errorf("failed to query bulk preferences: %w", err)
+		return nil, nil, fmt.Errorf("failed to query bulk preferences: %w", err)
 	}
 	defer rows.Close()
 
-	result := make(map[uuid.UUID]bool)
+	prefsByUser := make(map[uuid.UUID]*models.NotificationPreferences)
 	for rows.Next() {
-		var userID uuid.UUID
-		var canSend bool
-		if err := rows.Scan(&userID, &canSend); err != nil {
-			return nil, fmt.Errorf("failed to scan preference: %w", err)
+		var prefs models.NotificationPreferences
+		if err := rows.Scan(
+			&prefs.UserID, &prefs.PushEnabled, &prefs.PushAchievements, &prefs.PushReminders,
+			&prefs.PushCourseUpdates, &prefs.PushExerciseGraded, &prefs.EmailEnabled,
+			&prefs.EmailWeeklyReport, &prefs.EmailCourseUpdates, &prefs.EmailMarketing,
+			&prefs.InAppEnabled, &prefs.QuietHoursEnabled, &prefs.QuietHoursStart,
+			&prefs.QuietHoursEnd, &prefs.MaxNotificationsPerDay, &prefs.Timezone, &prefs.UpdatedAt,
+		); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan preference: %w", err)
 		}
-		result[userID] = canSend
+		prefsByUser[prefs.UserID] = &prefs
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read preferences: %w", err)
 	}
 
-	// For users without preferences, create defaults and allow
-	for _, userID := range userIDs {
-		if _, exists := result[userID]; !exists {
-			result[userID] = true // Default: allow
+	// Count today's deliveries for capped users; "today" starts at each user's local midnight.
+	// Released daily-limit overflow is left out, as in CanSendNotification.
+	var cappedUsers []uuid.UUID
+	var dayStarts []string
+	for userID, prefs := range prefsByUser {
+		if prefs.MaxNotificationsPerDay > 0 {
+			cappedUsers = append(cappedUsers, userID)
+			dayStarts = append(dayStarts, localDayStart(now, userLocation(prefs.Timezone)).UTC().Format("2006-01-02 15:04:05"))
 		}
 	}
 
-	return result, nil
+	sentToday := make(map[uuid.UUID]int)
+	if len(cappedUsers) > 0 {
+		countRows, err := r.db.Query(`
+			SELECT u.user_id, COUNT(n.id)
+			FROM unnest($1::uuid[], $2::timestamp[]) AS u(user_id, day_start)
+			JOIN notifications n ON n.user_id = u.user_id
+				AND n.is_sent = true
+				AND n.sent_at >= u.day_start
+				AND n.deferred_reason IS DISTINCT FROM $3
+			GROUP BY u.user_id
+		`, pq.Array(cappedUsers), pq.Array(dayStarts), DeferReasonDailyLimit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to count daily notifications: %w", err)
+		}
+		defer countRows.Close()
+
+		for countRows.Next() {
+			var userID uuid.UUID
+			var count int
+			if err := countRows.Scan(&userID, &count); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan daily count: %w", err)
+			}
+			sentToday[userID] = count
+		}
+		if err := countRows.Err(); err != nil {
+			return nil, nil, fmt.Errorf("failed to read daily counts: %w", err)
+		}
+	}
+
+	return prefsByUser, sentToday, nil
 }
 
 // GetScheduledNotificationByID retrieves a scheduled notification by ID
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const scheduledNotificationColumns = `
+	id, user_id, title, message, schedule_type, scheduled_time,
+	days_of_week, timezone, is_active, last_sent_at, next_send_at,
+	created_at, updated_at
+`
+
+func scanScheduledNotifications(rows *sql.Rows) ([]models.ScheduledNotification, error) {
+	var schedules []models.ScheduledNotification
+	for rows.Next() {
+		var schedule models.ScheduledNotification
+		var daysOfWeek []int32 // PostgreSQL INT is 32-bit
+
+		err := rows.Scan(
+			&schedule.ID,
+			&schedule.UserID,
+			&schedule.Title,
+			&schedule.Message,
+			&schedule.ScheduleType,
+			&schedule.ScheduledTime,
+			pq.Array(&daysOfWeek),
+			&schedule.Timezone,
+			&schedule.IsActive,
+			&schedule.LastSentAt,
+			&schedule.NextSendAt,
+			&schedule.CreatedAt,
+			&schedule.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled notification: %w", err)
+		}
+
+		schedule.DaysOfWeek = make([]int, len(daysOfWeek))
+		for i, day := range daysOfWeek {
+			schedule.DaysOfWeek[i] = int(day)
+		}
+
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, rows.Err()
+}
+
+// ClaimDueScheduledNotifications claims up to limit active schedules whose next_send_at
+// has passed. Claiming pushes next_send_at forward by claimTimeout, so other replicas skip
+// the rows (SKIP LOCKED) and a crashed dispatcher's claim is picked up again once it lapses.
+// The returned rows carry the claim time in NextSendAt.
+func (r *NotificationRepository) ClaimDueScheduledNotifications(now time.Time, limit int, claimTimeout time.Duration) ([]models.ScheduledNotification, error) {
+	query := `
+		UPDATE scheduled_notifications
+		SET next_send_at = $1::timestamp + ($2 * INTERVAL '1 second'),
+			updated_at = $1
+		WHERE id IN (
+			SELECT id FROM scheduled_notifications
+			WHERE is_active = true
+			AND next_send_at IS NOT NULL
+			AND next_send_at <= $1
+			ORDER BY next_send_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + scheduledNotificationColumns
+
+	rows, err := r.db.Query(query, now, int(claimTimeout.Seconds()), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due scheduled notifications: %w", err)
+	}
+	defer rows.Close()
+
+	return scanScheduledNotifications(rows)
+}
+
+// CompleteScheduledNotificationRun records the outcome of a dispatch. lastSentAt is left
+// unchanged when nil (the notification was not delivered); a nil nextSendAt means the
+// schedule has no further occurrences. The update only applies while the row still holds
+// our claim, so an edit made by the user during dispatch is not overwritten.
+func (r *NotificationRepository) CompleteScheduledNotificationRun(id uuid.UUID, claimedUntil time.Time, lastSentAt, nextSendAt *time.Time, isActive bool) error {
+	query := `
+		UPDATE scheduled_notifications
+		SET last_sent_at = COALESCE($3, last_sent_at),
+			next_send_at = $4,
+			is_active = $5,
+			updated_at = $6
+		WHERE id = $1 AND next_send_at = $2
+	`
+
+	_, err := r.db.Exec(query, id, claimedUntil, lastSentAt, nextSendAt, isActive, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to complete scheduled notification run: %w", err)
+	}
+
+	return nil
+}
+
+// GetUnscheduledActiveNotifications returns active schedules that have no next_send_at
+// yet (e.g. monthly/custom rows created before these types were supported)
+func (r *NotificationRepository) GetUnscheduledActiveNotifications(limit int) ([]models.ScheduledNotification, error) {
+	query := `
+		SELECT ` + scheduledNotificationColumns + `
+		FROM scheduled_notifications
+		WHERE is_active = true AND next_send_at IS NULL
+		LIMIT $1
+	`
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unscheduled notifications: %w", err)
+	}
+	defer rows.Close()
+
+	return scanScheduledNotifications(rows)
+}
+
+// SetScheduledNotificationNextSend sets next_send_at for a schedule that has none
+func (r *NotificationRepository) SetScheduledNotificationNextSend(id uuid.UUID, nextSendAt *time.Time, isActive bool) error {
+	query := `
+		UPDATE scheduled_notifications
+		SET next_send_at = $2, is_active = $3, updated_at = $4
+		WHERE id = $1 AND next_send_at IS NULL
+	`
+
+	_, err := r.db.Exec(query, id, nextSendAt, isActive, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to set next send time: %w", err)
+	}
+
+	return nil
+}
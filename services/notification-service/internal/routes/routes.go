@@ -53,10 +53,9 @@ func SetupRoutes(r *gin.Engine, handler *handlers.NotificationHandler, internalH
 	// Admin routes
 	admin := v1.Group("/admin/notifications")
 	admin.Use(authMiddleware.Authenticate())
-	admin.Use(authMiddleware.RequireRole("admin", "instructor"))
 	{
-		admin.POST("", handler.CreateNotification)         // Create notification for a user
-		admin.POST("/bulk", handler.SendBulkNotifications) // Send bulk notifications
+		admin.POST("", authMiddleware.RequirePermission("notification:send"), handler.CreateNotification)                // Create notification for a user
+		admin.POST("/bulk", authMiddleware.RequirePermission("notification:bulk_send"), handler.SendBulkNotifications) // Send bulk notifications
 	}
 
 	// Internal routes (service-to-service)
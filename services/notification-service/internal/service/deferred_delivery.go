@@ -0,0 +1,80 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// maxDigestTitles is how many held-back titles are listed in an overflow digest
+const maxDigestTitles = 3
+
+// ReleaseDeferredNotifications delivers notifications whose quiet-hours deferral (or
+// scheduled_for time) has passed and that fit in the user's daily limit, then sends one
+// digest per user for notifications that were held back by the daily limit. Returns how
+// many notifications were pushed.
+func (s *NotificationService) ReleaseDeferredNotifications(batchSize int) (int, error) {
+	now := time.Now().UTC()
+
+	released, err := s.repo.ReleaseDeferredNotifications(now, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i := range released {
+		notification := &released[i]
+		s.logNotificationEvent(&notification.ID, notification.UserID, "sent", "success", &notification.Type, nil)
+		if s.broadcaster != nil {
+			s.broadcaster.Broadcast(notification.UserID, notification)
+		}
+	}
+
+	digests, err := s.repo.ReleaseOverflowNotifications(now, batchSize, func(userID uuid.UUID, items []models.Notification) *models.Notification {
+		return buildOverflowDigest(userID, items, now)
+	})
+	if err != nil {
+		return len(released), err
+	}
+	for _, digest := range digests {
+		s.logNotificationEvent(&digest.ID, digest.UserID, "digest_sent", "success", &digest.Type, nil)
+		if s.broadcaster != nil {
+			s.broadcaster.Broadcast(digest.UserID, digest)
+		}
+	}
+
+	return len(released) + len(digests), nil
+}
+
+// buildOverflowDigest summarizes notifications held back by the daily limit
+func buildOverflowDigest(userID uuid.UUID, items []models.Notification, now time.Time) *models.Notification {
+	titles := make([]string, 0, maxDigestTitles)
+	for i := 0; i < len(items) && i < maxDigestTitles; i++ {
+		titles = append(titles, items[i].Title)
+	}
+
+	message := strings.Join(titles, "; ")
+	if extra := len(items) - len(titles); extra > 0 {
+		message = fmt.Sprintf("%s and %d more", message, extra)
+	}
+
+	title := "You have 1 notification you missed"
+	if len(items) > 1 {
+		title = fmt.Sprintf("You have %d notifications you missed", len(items))
+	}
+
+	return &models.Notification{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Type:      "system",
+		Category:  "info",
+		Title:     title,
+		Message:   message,
+		IsRead:    false,
+		IsSent:    true,
+		SentAt:    &now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
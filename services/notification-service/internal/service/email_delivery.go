@@ -0,0 +1,312 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// Delivery channels for a notification
+const (
+	ChannelInApp = "in_app"
+	ChannelEmail = "email"
+	ChannelBoth  = "both"
+)
+
+// ErrMissingRecipientEmail is returned when the email channel is requested without an address
+var ErrMissingRecipientEmail = errors.New("email channel requires a recipient email")
+
+// notificationChannel resolves the delivery channel: an explicit channel wins, otherwise
+// the template's notification_type ('push', 'email', 'both'), otherwise in-app only
+func notificationChannel(requested string, tmpl *models.NotificationTemplate) string {
+	if requested != "" {
+		return requested
+	}
+	if tmpl != nil {
+		switch tmpl.NotificationType {
+		case ChannelEmail, ChannelBoth:
+			return tmpl.NotificationType
+		}
+	}
+	return ChannelInApp
+}
+
+// renderTemplateText replaces {{key}} placeholders; values are HTML-escaped when escape is set
+func renderTemplateText(text string, variables map[string]string, escape bool) string {
+	for key, value := range variables {
+		if escape {
+			value = html.EscapeString(value)
+		}
+		text = strings.ReplaceAll(text, fmt.Sprintf("{{%s}}", key), value)
+	}
+	return text
+}
+
+// emailLayout wraps plain content in the platform's email layout
+func emailLayout(title, contentHTML string) string {
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"></head>
+<body style="margin:0;padding:24px;background:#FFF7F5;font-family:Arial,Helvetica,sans-serif;">
+  <table role="presentation" width="100%%" cellspacing="0" cellpadding="0" style="max-width:600px;margin:0 auto;background:#FFFFFF;border-radius:10px;border:1px solid #FAD8D6;">
+    <tr>
+      <td style="padding:24px;border-bottom:1px solid #FAD8D6;">
+        <div style="font-size:22px;line-height:1.2;color:#111827;font-weight:700;letter-spacing:-0.3px">
+          <span>IELTS</span><span style="color:#E53935">Go</span>
+        </div>
+      </td>
+    </tr>
+    <tr>
+      <td style="padding:24px;">
+        <h1 style="margin:0 0 12px 0;font-size:18px;color:#111827">%s</h1>
+        <div style="font-size:14px;color:#374151;line-height:1.7">%s</div>
+      </td>
+    </tr>
+    <tr>
+      <td style="padding:20px 24px 24px 24px;color:#9CA3AF;font-size:12px;border-top:1px solid #FAD8D6;">
+        You can change which emails you receive in your notification preferences.
+      </td>
+    </tr>
+  </table>
+</body>
+</html>`, html.EscapeString(title), contentHTML)
+}
+
+// textToHTML escapes plain text and keeps its line breaks
+func textToHTML(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// applyTemplate loads req.TemplateCode (if any), checks required variables and fills in
+// an empty title/message from the template
+func (s *NotificationService) applyTemplate(req *models.CreateNotificationRequest) (*models.NotificationTemplate, error) {
+	if req.TemplateCode == nil || *req.TemplateCode == "" {
+		return nil, nil
+	}
+
+	tmpl, err := s.repo.GetTemplateByCode(*req.TemplateCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	for _, reqVar := range tmpl.RequiredVariables {
+		if _, ok := req.TemplateData[reqVar]; !ok {
+			return nil, fmt.Errorf("missing required variable: %s", reqVar)
+		}
+	}
+
+	if req.Title == "" {
+		if tmpl.TitleTemplate != nil {
+			req.Title = renderTemplateText(*tmpl.TitleTemplate, req.TemplateData, false)
+		} else {
+			req.Title = tmpl.Name
+		}
+	}
+	if req.Message == "" {
+		req.Message = renderTemplateText(tmpl.BodyTemplate, req.TemplateData, false)
+	}
+
+	return tmpl, nil
+}
+
+// queueEmail renders the email for req and stores it in email_notifications for the email worker
+func (s *NotificationService) queueEmail(req *models.CreateNotificationRequest, tmpl *models.NotificationTemplate, notificationID *uuid.UUID) (*models.EmailNotification, error) {
+	if req.Email == nil || *req.Email == "" {
+		return nil, ErrMissingRecipientEmail
+	}
+	if _, err := mail.ParseAddress(*req.Email); err != nil || strings.ContainsAny(*req.Email, "\r\n") {
+		return nil, fmt.Errorf("%w: invalid recipient %q", ErrInvalidEmailHeader, *req.Email)
+	}
+
+	templateCode := ""
+	if tmpl != nil {
+		templateCode = tmpl.TemplateCode
+	}
+	allowed, err := s.repo.CanSendEmail(req.UserID, req.Type, templateCode)
+	if err != nil {
+		// Same fail-open policy as in-app preference checks
+		log.Printf("[Notification-Service] WARNING: Failed to check email preferences: %v. Allowing email by default.", err)
+		allowed = true
+	}
+	if !allowed {
+		return nil, ErrNotificationBlocked
+	}
+
+	subject := req.Title
+	bodyHTML := emailLayout(req.Title, textToHTML(req.Message))
+	var templateName, templateData *string
+	if tmpl != nil {
+		if tmpl.SubjectTemplate != nil {
+			subject = renderTemplateText(*tmpl.SubjectTemplate, req.TemplateData, false)
+		}
+		if tmpl.HTMLTemplate != nil {
+			bodyHTML = renderTemplateText(*tmpl.HTMLTemplate, req.TemplateData, true)
+		}
+		templateName = &tmpl.TemplateCode
+		if len(req.TemplateData) > 0 {
+			dataBytes, err := json.Marshal(req.TemplateData)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal template_data: %w", err)
+			}
+			data := string(dataBytes)
+			templateData = &data
+		}
+	}
+
+	// A subject is a single header line
+	subject = strings.Join(strings.Fields(subject), " ")
+
+	now := time.Now().UTC()
+	bodyText := req.Message
+	email := &models.EmailNotification{
+		ID:             uuid.New(),
+		NotificationID: notificationID,
+		UserID:         req.UserID,
+		ToEmail:        *req.Email,
+		Subject:        subject,
+		BodyHTML:       bodyHTML,
+		BodyText:       &bodyText,
+		TemplateName:   templateName,
+		TemplateData:   templateData,
+		Status:         "pending",
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if err := s.repo.CreateEmailNotification(email); err != nil {
+		return nil, err
+	}
+
+	s.logNotificationEvent(notificationID, req.UserID, "email_queued", "success", &req.Type, nil)
+	return email, nil
+}
+
+// emailStore is the part of the repository the email worker needs
+type emailStore interface {
+	ClaimPendingEmails(now time.Time, limit int, claimTimeout time.Duration) ([]models.EmailNotification, error)
+	MarkEmailSent(id uuid.UUID, sentAt time.Time) error
+	MarkEmailRetry(id uuid.UUID, errorMsg string, nextAttemptAt time.Time) error
+	MarkEmailFailed(id uuid.UUID, errorMsg string) error
+}
+
+// EmailWorkerConfig controls email delivery
+type EmailWorkerConfig struct {
+	PollInterval time.Duration // How often to look for pending emails
+	BatchSize    int           // Max emails claimed per poll
+	ClaimTimeout time.Duration // How long a claimed email is reserved for this worker
+	MaxAttempts  int           // Send attempts before an email is marked failed
+	RetryBase    time.Duration // Delay after the first failure; doubles per attempt
+	RetryMax     time.Duration // Upper bound for the retry delay
+}
+
+// EmailWorker sends queued emails and tracks their status in email_notifications
+type EmailWorker struct {
+	store  emailStore
+	sender EmailSender
+	config EmailWorkerConfig
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewEmailWorker creates an email worker
+func NewEmailWorker(store emailStore, sender EmailSender, config EmailWorkerConfig) *EmailWorker {
+	return &EmailWorker{
+		store:  store,
+		sender: sender,
+		config: config,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start runs the delivery loop until Stop is called
+func (w *EmailWorker) Start() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.config.PollInterval)
+	defer ticker.Stop()
+
+	log.Printf("[Email-Worker] 🔄 Started (polling every %v)", w.config.PollInterval)
+
+	for {
+		w.processBatch()
+
+		select {
+		case <-ticker.C:
+		case <-w.stop:
+			log.Println("[Email-Worker] 🛑 Stopped")
+			return
+		}
+	}
+}
+
+// Stop signals the loop to exit and waits for the current batch to finish
+func (w *EmailWorker) Stop() {
+	close(w.stop)
+	<-w.done
+}
+
+// processBatch claims due emails and sends them one by one
+func (w *EmailWorker) processBatch() int {
+	emails, err := w.store.ClaimPendingEmails(time.Now().UTC(), w.config.BatchSize, w.config.ClaimTimeout)
+	if err != nil {
+		log.Printf("[Email-Worker] ⚠️ Failed to claim pending emails: %v", err)
+		return 0
+	}
+
+	for i := range emails {
+		w.deliver(&emails[i])
+	}
+	return len(emails)
+}
+
+// deliver sends one email and records the outcome
+func (w *EmailWorker) deliver(email *models.EmailNotification) {
+	sendErr := w.sender.Send(email.ToEmail, email.Subject, email.BodyHTML)
+	now := time.Now().UTC()
+
+	if sendErr == nil {
+		if err := w.store.MarkEmailSent(email.ID, now); err != nil {
+			log.Printf("[Email-Worker] ⚠️ Email %s sent but status update failed: %v", email.ID, err)
+		}
+		log.Printf("[Email-Worker] ✅ Sent email %s to %s", email.ID, email.ToEmail)
+		return
+	}
+
+	// A malformed header fails the same way on every attempt
+	attempt := email.RetryCount + 1
+	if attempt >= w.config.MaxAttempts || errors.Is(sendErr, ErrInvalidEmailHeader) {
+		log.Printf("[Email-Worker] ❌ Email %s failed after %d attempt(s): %v", email.ID, attempt, sendErr)
+		if err := w.store.MarkEmailFailed(email.ID, sendErr.Error()); err != nil {
+			log.Printf("[Email-Worker] ⚠️ Failed to mark email %s failed: %v", email.ID, err)
+		}
+		return
+	}
+
+	delay := w.retryDelay(attempt)
+	log.Printf("[Email-Worker] ⚠️ Email %s failed (attempt %d/%d), retrying in %v: %v", email.ID, attempt, w.config.MaxAttempts, delay, sendErr)
+	if err := w.store.MarkEmailRetry(email.ID, sendErr.Error(), now.Add(delay)); err != nil {
+		log.Printf("[Email-Worker] ⚠️ Failed to schedule retry for email %s: %v", email.ID, err)
+	}
+}
+
+// retryDelay returns the backoff after the given failed attempt (1-based)
+func (w *EmailWorker) retryDelay(attempt int) time.Duration {
+	delay := w.config.RetryBase
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= w.config.RetryMax {
+			return w.config.RetryMax
+		}
+	}
+	return delay
+}
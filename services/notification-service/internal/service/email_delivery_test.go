@@ -0,0 +1,230 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// memoryEmailStore is an in-memory emailStore that hands out every pending email
+type memoryEmailStore struct {
+	emails map[uuid.UUID]*models.EmailNotification
+}
+
+func newMemoryEmailStore(emails ...*models.EmailNotification) *memoryEmailStore {
+	store := &memoryEmailStore{emails: make(map[uuid.UUID]*models.EmailNotification)}
+	for _, e := range emails {
+		store.emails[e.ID] = e
+	}
+	return store
+}
+
+func (m *memoryEmailStore) ClaimPendingEmails(now time.Time, limit int, claimTimeout time.Duration) ([]models.EmailNotification, error) {
+	var claimed []models.EmailNotification
+	for _, e := range m.emails {
+		if e.Status == "pending" && len(claimed) < limit {
+			e.Status = "sending"
+			claimed = append(claimed, *e)
+		}
+	}
+	return claimed, nil
+}
+
+func (m *memoryEmailStore) MarkEmailSent(id uuid.UUID, sentAt time.Time) error {
+	m.emails[id].Status = "sent"
+	m.emails[id].SentAt = &sentAt
+	return nil
+}
+
+func (m *memoryEmailStore) MarkEmailRetry(id uuid.UUID, errorMsg string, nextAttemptAt time.Time) error {
+	e := m.emails[id]
+	e.Status = "pending"
+	e.ErrorMessage = &errorMsg
+	e.RetryCount++
+	e.NextAttemptAt = nextAttemptAt
+	return nil
+}
+
+func (m *memoryEmailStore) MarkEmailFailed(id uuid.UUID, errorMsg string) error {
+	e := m.emails[id]
+	e.Status = "failed"
+	e.ErrorMessage = &errorMsg
+	e.RetryCount++
+	return nil
+}
+
+func testEmailWorkerConfig() EmailWorkerConfig {
+	return EmailWorkerConfig{
+		PollInterval: time.Second,
+		BatchSize:    10,
+		ClaimTimeout: time.Minute,
+		MaxAttempts:  3,
+		RetryBase:    time.Minute,
+		RetryMax:     5 * time.Minute,
+	}
+}
+
+func pendingEmail(to string) *models.EmailNotification {
+	return &models.EmailNotification{
+		ID:       uuid.New(),
+		UserID:   uuid.New(),
+		ToEmail:  to,
+		Subject:  "Weekly progress",
+		BodyHTML: "<p>Hello</p>",
+		Status:   "pending",
+	}
+}
+
+// TestEmailWorkerDelivery tests status tracking across send attempts
+func TestEmailWorkerDelivery(t *testing.T) {
+	tests := []struct {
+		name           string
+		failTimes      int
+		alwaysFail     bool
+		runs           int
+		wantStatus     string
+		wantRetryCount int
+		wantSent       int
+	}{
+		{"sent on first attempt", 0, false, 1, "sent", 0, 1},
+		{"retried then sent", 2, false, 3, "sent", 2, 1},
+		{"failed after max attempts", 0, true, 3, "failed", 3, 0},
+		{"pending between attempts", 1, false, 1, "pending", 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email := pendingEmail("learner@example.com")
+			store := newMemoryEmailStore(email)
+			sender := NewFakeEmailSender()
+			sender.FailTimes = tt.failTimes
+			if tt.alwaysFail {
+				sender.Err = errors.New("smtp unavailable")
+			}
+
+			worker := NewEmailWorker(store, sender, testEmailWorkerConfig())
+			for i := 0; i < tt.runs; i++ {
+				worker.processBatch()
+			}
+
+			if email.Status != tt.wantStatus {
+				t.Errorf("status = %q; want %q", email.Status, tt.wantStatus)
+			}
+			if email.RetryCount != tt.wantRetryCount {
+				t.Errorf("retry_count = %d; want %d", email.RetryCount, tt.wantRetryCount)
+			}
+			if got := len(sender.SentEmails()); got != tt.wantSent {
+				t.Errorf("sent emails = %d; want %d", got, tt.wantSent)
+			}
+		})
+	}
+}
+
+// TestEmailRetryDelay tests exponential backoff with an upper bound
+func TestEmailRetryDelay(t *testing.T) {
+	worker := NewEmailWorker(newMemoryEmailStore(), NewFakeEmailSender(), testEmailWorkerConfig())
+
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 5 * time.Minute}, // capped
+		{10, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := worker.retryDelay(tt.attempt); got != tt.expected {
+			t.Errorf("retryDelay(%d) = %v; want %v", tt.attempt, got, tt.expected)
+		}
+	}
+}
+
+// TestNotificationChannel tests channel resolution from request and template
+func TestNotificationChannel(t *testing.T) {
+	emailTemplate := &models.NotificationTemplate{NotificationType: "email"}
+	pushTemplate := &models.NotificationTemplate{NotificationType: "push"}
+
+	tests := []struct {
+		name      string
+		requested string
+		tmpl      *models.NotificationTemplate
+		expected  string
+	}{
+		{"default", "", nil, ChannelInApp},
+		{"explicit both", ChannelBoth, nil, ChannelBoth},
+		{"from email template", "", emailTemplate, ChannelEmail},
+		{"push template is in-app", "", pushTemplate, ChannelInApp},
+		{"explicit overrides template", ChannelInApp, emailTemplate, ChannelInApp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notificationChannel(tt.requested, tt.tmpl); got != tt.expected {
+				t.Errorf("notificationChannel(%q) = %q; want %q", tt.requested, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestRenderTemplateTextEscapesHTML tests that variables are escaped in HTML templates only
+func TestRenderTemplateTextEscapesHTML(t *testing.T) {
+	vars := map[string]string{"name": "<b>Lan</b>"}
+
+	if got := renderTemplateText("Hi {{name}}", vars, false); got != "Hi <b>Lan</b>" {
+		t.Errorf("plain render = %q", got)
+	}
+	if got := renderTemplateText("<p>Hi {{name}}</p>", vars, true); got != "<p>Hi &lt;b&gt;Lan&lt;/b&gt;</p>" {
+		t.Errorf("html render = %q", got)
+	}
+}
+
+// TestEmailWorkerInvalidHeaderNotRetried tests that a header the sender rejects fails at once
+func TestEmailWorkerInvalidHeaderNotRetried(t *testing.T) {
+	email := pendingEmail("learner@example.com")
+	store := newMemoryEmailStore(email)
+	sender := NewFakeEmailSender()
+	sender.Err = fmt.Errorf("%w: Subject header contains a line break", ErrInvalidEmailHeader)
+
+	NewEmailWorker(store, sender, testEmailWorkerConfig()).processBatch()
+
+	if email.Status != "failed" || email.RetryCount != 1 {
+		t.Errorf("status = %q, retry_count = %d; want failed after one attempt", email.Status, email.RetryCount)
+	}
+}
+
+// TestBuildEmailMessage tests header encoding and header injection checks
+func TestBuildEmailMessage(t *testing.T) {
+	msg, err := buildEmailMessage("IELTS <no-reply@ielts.example>", "learner@example.com", "Tiến độ tuần này", "<p>Hello</p>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(msg), "\r\nSubject: =?utf-8?q?Ti=E1=BA=BFn_=C4=91=E1=BB=99_tu=E1=BA=A7n_n=C3=A0y?=\r\n") {
+		t.Errorf("subject not RFC 2047 encoded:\n%s", msg)
+	}
+	if !strings.HasSuffix(string(msg), "\r\n\r\n<p>Hello</p>") {
+		t.Errorf("body not after a blank line:\n%s", msg)
+	}
+
+	tests := []struct {
+		name, to, subject string
+	}{
+		{"CRLF in subject", "learner@example.com", "Hi\r\nBcc: victim@example.com"},
+		{"LF in recipient", "learner@example.com\nBcc: victim@example.com", "Hi"},
+		{"not an address", "learner", "Hi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := buildEmailMessage("IELTS <no-reply@ielts.example>", tt.to, tt.subject, ""); !errors.Is(err, ErrInvalidEmailHeader) {
+				t.Errorf("err = %v; want ErrInvalidEmailHeader", err)
+			}
+		})
+	}
+}
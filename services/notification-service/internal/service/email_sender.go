@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidEmailHeader is returned for recipients or subjects that cannot be put in a header
+var ErrInvalidEmailHeader = errors.New("invalid email header")
+
+// EmailSender delivers a single email. Implementations: SMTP for production,
+// FakeEmailSender for tests and local development.
+type EmailSender interface {
+	Send(to, subject, htmlBody string) error
+}
+
+type smtpEmailSender struct {
+	smtpHost     string
+	smtpPort     string
+	smtpUsername string
+	smtpPassword string
+	fromEmail    string
+	fromName     string
+}
+
+// NewSMTPEmailSender creates a sender that delivers through an SMTP server
+func NewSMTPEmailSender(host, port, username, password, fromEmail, fromName string) EmailSender {
+	return &smtpEmailSender{
+		smtpHost:     host,
+		smtpPort:     port,
+		smtpUsername: username,
+		smtpPassword: password,
+		fromEmail:    fromEmail,
+		fromName:     fromName,
+	}
+}
+
+func (s *smtpEmailSender) Send(to, subject, htmlBody string) error {
+	from := fmt.Sprintf("%s <%s>", s.fromName, s.fromEmail)
+	msg, err := buildEmailMessage(from, to, subject, htmlBody)
+	if err != nil {
+		return err
+	}
+
+	auth := smtp.PlainAuth("", s.smtpUsername, s.smtpPassword, s.smtpHost)
+	addr := fmt.Sprintf("%s:%s", s.smtpHost, s.smtpPort)
+	if err := smtp.SendMail(addr, auth, s.fromEmail, []string{to}, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// buildEmailMessage renders the headers and body of an HTML email. Header values come
+// from user data, so line breaks (header injection) are rejected and the subject is
+// RFC 2047 encoded.
+func buildEmailMessage(from, to, subject, htmlBody string) ([]byte, error) {
+	for name, value := range map[string]string{"From": from, "To": to, "Subject": subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("%w: %s header contains a line break", ErrInvalidEmailHeader, name)
+		}
+	}
+	if _, err := mail.ParseAddress(to); err != nil {
+		return nil, fmt.Errorf("%w: invalid recipient %q: %v", ErrInvalidEmailHeader, to, err)
+	}
+
+	headers := []string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"MIME-Version: 1.0",
+		`Content-Type: text/html; charset="UTF-8"`,
+	}
+
+	var msg strings.Builder
+	for _, h := range headers {
+		msg.WriteString(h + "\r\n")
+	}
+	msg.WriteString("\r\n" + htmlBody)
+	return []byte(msg.String()), nil
+}
+
+// SentEmail is an email captured by FakeEmailSender
+type SentEmail struct {
+	To       string
+	Subject  string
+	HTMLBody string
+}
+
+// FakeEmailSender records emails instead of sending them.
+// Set Err to make every send fail, or FailTimes to fail only the first N sends.
+type FakeEmailSender struct {
+	mu        sync.Mutex
+	Sent      []SentEmail
+	Err       error
+	FailTimes int
+	attempts  int
+}
+
+// NewFakeEmailSender creates a sender that only records emails
+func NewFakeEmailSender() *FakeEmailSender {
+	return &FakeEmailSender{}
+}
+
+func (f *FakeEmailSender) Send(to, subject, htmlBody string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.attempts++
+	if f.Err != nil {
+		return f.Err
+	}
+	if f.attempts <= f.FailTimes {
+		return fmt.Errorf("fake send failure %d/%d", f.attempts, f.FailTimes)
+	}
+
+	f.Sent = append(f.Sent, SentEmail{To: to, Subject: subject, HTMLBody: htmlBody})
+	log.Printf("[Email-Fake] 📧 %s -> %s", subject, to)
+	return nil
+}
+
+// SentEmails returns a copy of the emails recorded so far
+func (f *FakeEmailSender) SentEmails() []SentEmail {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sent := make([]SentEmail, len(f.Sent))
+	copy(sent, f.Sent)
+	return sent
+}
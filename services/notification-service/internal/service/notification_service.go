@@ -2,8 +2,10 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,6 +14,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrNotificationBlocked is returned when the user's preferences block a notification
+var ErrNotificationBlocked = errors.New("notification blocked by user preferences")
+
 type NotificationService struct {
 	repo        *repository.NotificationRepository
 	broadcaster *NotificationBroadcaster
@@ -24,20 +29,63 @@ func NewNotificationService(repo *repository.NotificationRepository, broadcaster
 	}
 }
 
-// CreateNotification creates a new notification after checking permissions
-// FIX #23: Use retry mechanism for preference checks
+// CreateNotification delivers a notification on its channel (in-app, email or both).
+// Returns the in-app notification; it is nil when none was created (email-only delivery,
+// or in-app blocked while the email was queued). Fails only if no channel delivered.
 func (s *NotificationService) CreateNotification(req *models.CreateNotificationRequest) (*models.Notification, error) {
+	tmpl, err := s.applyTemplate(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Title == "" || req.Message == "" {
+		return nil, fmt.Errorf("title and message are required")
+	}
+
+	channel := notificationChannel(req.Channel, tmpl)
+
+	var notification *models.Notification
+	var inAppErr error
+	if channel != ChannelEmail {
+		notification, inAppErr = s.createInAppNotification(req)
+		if inAppErr != nil && !errors.Is(inAppErr, ErrNotificationBlocked) {
+			return nil, inAppErr
+		}
+	}
+
+	if channel != ChannelInApp {
+		var notificationID *uuid.UUID
+		if notification != nil {
+			notificationID = &notification.ID
+		}
+		_, err := s.queueEmail(req, tmpl, notificationID)
+		if err == nil {
+			// Delivered on at least one channel, even if the in-app part was blocked
+			return notification, nil
+		}
+		if channel == ChannelEmail {
+			return nil, err
+		}
+		// The in-app part succeeded (or was blocked on its own), so don't fail the request
+		log.Printf("[Notification-Service] WARNING: Email not queued for user %s: %v", req.UserID, err)
+	}
+
+	return notification, inAppErr
+}
+
+// createInAppNotification creates an in-app notification after checking permissions
+// FIX #23: Use retry mechanism for preference checks
+func (s *NotificationService) createInAppNotification(req *models.CreateNotificationRequest) (*models.Notification, error) {
 	// Check if notification can be sent with retry
-	canSend, err := s.checkNotificationPermissionsWithRetry(req.UserID, req.Type, req.Category)
+	decision, err := s.checkNotificationPermissionsWithRetry(req.UserID, req.Type, req.Category)
 	if err != nil {
 		// Log error but continue with default behavior (allow)
 		// Better to send than miss important notification
 		log.Printf("[Notification-Service] WARNING: Failed to check preferences after retry: %v. Allowing notification by default.", err)
-		canSend = true // Fail open
+		decision = &models.DeliveryDecision{Allowed: true} // Fail open
 	}
 
-	if !canSend {
-		return nil, fmt.Errorf("notification blocked by user preferences")
+	if !decision.Allowed {
+		return nil, ErrNotificationBlocked
 	}
 
 	// Marshal action_data to JSON string if provided
@@ -58,9 +106,17 @@ func (s *NotificationService) CreateNotification(req *models.CreateNotificationR
 		if err != nil {
 			return nil, fmt.Errorf("invalid scheduled_for format: %w", err)
 		}
+		t = t.UTC() // TIMESTAMP columns hold UTC
 		scheduledFor = &t
 	}
 
+	// Quiet hours / daily limit: hold the notification back unless it is already scheduled later
+	var deferredReason *string
+	if decision.DeferUntil != nil && (scheduledFor == nil || scheduledFor.Before(*decision.DeferUntil)) {
+		scheduledFor = decision.DeferUntil
+		deferredReason = &decision.DeferReason
+	}
+
 	var expiresAt *time.Time
 	if req.ExpiresAt != nil {
 		t, err := time.Parse(time.RFC3339, *req.ExpiresAt)
@@ -89,6 +145,8 @@ func (s *NotificationService) CreateNotification(req *models.CreateNotificationR
 		ExpiresAt:    expiresAt,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
+
+		DeferredReason: deferredReason,
 	}
 
 	if notification.IsSent {
@@ -104,6 +162,9 @@ func (s *NotificationService) CreateNotification(req *models.CreateNotificationR
 
 	// Log the creation
 	s.logNotificationEvent(&notification.ID, notification.UserID, "created", "success", &notification.Type, nil)
+	if deferredReason != nil {
+		s.logNotificationEvent(&notification.ID, notification.UserID, "deferred", "success", &notification.Type, deferredReason)
+	}
 
 	// Broadcast to connected clients (realtime)
 	if notification.IsSent && s.broadcaster != nil {
@@ -341,8 +402,8 @@ func (s *NotificationService) SendBulkNotifications(req *models.SendBulkNotifica
 		return 0, 0, nil
 	}
 
-	// Pre-fetch all users' preferences in single query (optimization)
-	prefsMap, err := s.repo.GetBulkNotificationPreferences(req.UserIDs, req.Type, req.Category)
+	// Pre-fetch all users' delivery decisions in batched queries (optimization)
+	decisions, err := s.repo.GetBulkDeliveryDecisions(req.UserIDs, req.Type, req.Category)
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to get bulk preferences: %w", err)
 	}
@@ -365,8 +426,8 @@ func (s *NotificationService) SendBulkNotifications(req *models.SendBulkNotifica
 	now := time.Now()
 
 	for _, userID := range req.UserIDs {
-		canSend, exists := prefsMap[userID]
-		if !exists || !canSend {
+		decision, exists := decisions[userID]
+		if !exists || !decision.Allowed {
 			failedCount++
 			errMsg := "blocked by user preferences"
 			s.logNotificationEvent(nil, userID, "bulk_send", "blocked", &req.Type, &errMsg)
@@ -391,6 +452,14 @@ func (s *NotificationService) SendBulkNotifications(req *models.SendBulkNotifica
 			UpdatedAt:  now,
 		}
 
+		// Held back by quiet hours or the daily limit; released later by the dispatcher
+		if decision.DeferUntil != nil {
+			notification.IsSent = false
+			notification.SentAt = nil
+			notification.ScheduledFor = decision.DeferUntil
+			notification.DeferredReason = &decision.DeferReason
+		}
+
 		notifications = append(notifications, notification)
 		successCount++
 	}
@@ -428,37 +497,29 @@ func (s *NotificationService) RenderTemplate(templateCode string, variables map[
 	// Render title
 	title := ""
 	if tmpl.TitleTemplate != nil {
-		title = *tmpl.TitleTemplate
-		for key, value := range variables {
-			placeholder := fmt.Sprintf("{{%s}}", key)
-			title = strings.ReplaceAll(title, placeholder, value)
-		}
+		title = renderTemplateText(*tmpl.TitleTemplate, variables, false)
 	}
 
 	// Render body
-	body := tmpl.BodyTemplate
-	for key, value := range variables {
-		placeholder := fmt.Sprintf("{{%s}}", key)
-		body = strings.ReplaceAll(body, placeholder, value)
-	}
+	body := renderTemplateText(tmpl.BodyTemplate, variables, false)
 
 	return title, body, nil
 }
 
 // checkNotificationPermissionsWithRetry checks notification permissions with retry
 // FIX #23: Retry mechanism for preference checks with fallback
-func (s *NotificationService) checkNotificationPermissionsWithRetry(userID uuid.UUID, notifType, category string) (bool, error) {
+func (s *NotificationService) checkNotificationPermissionsWithRetry(userID uuid.UUID, notifType, category string) (*models.DeliveryDecision, error) {
 	maxRetries := 3
 	baseDelay := 100 * time.Millisecond
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		canSend, err := s.repo.CanSendNotification(userID, notifType, category)
+		decision, err := s.repo.CanSendNotification(userID, notifType, category)
 
 		if err == nil {
 			if attempt > 1 {
 				log.Printf("[Notification-Service] SUCCESS: Preferences check succeeded on attempt %d", attempt)
 			}
-			return canSend, nil
+			return decision, nil
 		}
 
 		log.Printf("[Notification-Service] WARNING: Preferences check failed (attempt %d/%d): %v", attempt, maxRetries, err)
@@ -469,7 +530,7 @@ func (s *NotificationService) checkNotificationPermissionsWithRetry(userID uuid.
 		}
 	}
 
-	return false, fmt.Errorf("failed after %d attempts", maxRetries)
+	return nil, fmt.Errorf("failed after %d attempts", maxRetries)
 }
 
 // logNotificationEvent creates a log entry for notification events
@@ -520,9 +581,11 @@ func (s *NotificationService) CreateScheduledNotification(userID uuid.UUID, req
 		UpdatedAt:     time.Now(),
 	}
 
-	// Calculate next_send_at based on schedule type
-	// This is a simplified version - in production, use proper timezone handling
-	nextSend := calculateNextSendTime(schedule.ScheduleType, schedule.ScheduledTime, schedule.DaysOfWeek)
+	// Calculate next_send_at in the schedule's timezone
+	nextSend := calculateNextSendTime(schedule.ScheduleType, schedule.ScheduledTime, schedule.DaysOfWeek, schedule.Timezone, schedule.CreatedAt, time.Now())
+	if nextSend == nil {
+		return nil, fmt.Errorf("invalid schedule: could not compute next send time")
+	}
 	schedule.NextSendAt = nextSend
 
 	err := s.repo.CreateScheduledNotification(schedule)
@@ -594,7 +657,10 @@ func (s *NotificationService) UpdateScheduledNotification(id uuid.UUID, userID u
 	}
 
 	// Recalculate next_send_at
-	nextSend := calculateNextSendTime(schedule.ScheduleType, schedule.ScheduledTime, schedule.DaysOfWeek)
+	nextSend := calculateNextSendTime(schedule.ScheduleType, schedule.ScheduledTime, schedule.DaysOfWeek, schedule.Timezone, schedule.CreatedAt, time.Now())
+	if nextSend == nil && schedule.IsActive {
+		return nil, fmt.Errorf("invalid schedule: could not compute next send time")
+	}
 	schedule.NextSendAt = nextSend
 	schedule.UpdatedAt = time.Now()
 
@@ -626,68 +692,105 @@ func (s *NotificationService) DeleteScheduledNotification(id uuid.UUID, userID u
 	return nil
 }
 
-// calculateNextSendTime calculates the next send time for a scheduled notification
-// This is a simplified version - in production, use proper timezone handling
-func calculateNextSendTime(scheduleType, scheduledTime string, daysOfWeek []int) *time.Time {
-	now := time.Now()
+// defaultScheduleTimezone is used when a schedule has no (or an unknown) timezone
+const defaultScheduleTimezone = "Asia/Ho_Chi_Minh"
 
-	// Parse scheduled time (HH:MM:SS)
+// loadScheduleLocation resolves a schedule timezone, falling back to the default
+func loadScheduleLocation(timezone string) *time.Location {
+	if timezone != "" {
+		if loc, err := time.LoadLocation(timezone); err == nil {
+			return loc
+		}
+		log.Printf("⚠️ Unknown timezone %q, using %s", timezone, defaultScheduleTimezone)
+	}
+	loc, err := time.LoadLocation(defaultScheduleTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// calculateNextSendTime returns the first occurrence of a schedule strictly after `after`.
+// scheduledTime ("HH:MM" or "HH:MM:SS") and daysOfWeek (1=Monday ... 7=Sunday) are
+// interpreted in the schedule's timezone; the result is returned in UTC. Monthly schedules
+// repeat on the day of month they were created (createdAt), or on the last day of shorter months.
+// Returns nil if the schedule is invalid.
+func calculateNextSendTime(scheduleType, scheduledTime string, daysOfWeek []int, timezone string, createdAt, after time.Time) *time.Time {
 	timeParts := strings.Split(scheduledTime, ":")
-	if len(timeParts) != 3 {
+	if len(timeParts) < 2 || len(timeParts) > 3 {
+		return nil
+	}
+	clock := make([]int, 3)
+	for i, part := range timeParts {
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return nil
+		}
+		clock[i] = v
+	}
+	if clock[0] > 23 || clock[1] > 59 || clock[2] > 59 {
 		return nil
 	}
 
-	hour, _ := time.Parse("15", timeParts[0])
-	minute, _ := time.Parse("04", timeParts[1])
+	loc := loadScheduleLocation(timezone)
+	local := after.In(loc)
+	candidate := time.Date(local.Year(), local.Month(), local.Day(), clock[0], clock[1], clock[2], 0, loc)
 
+	var nextSend time.Time
 	switch scheduleType {
-	case "daily":
-		// Next occurrence at specified time
-		nextSend := time.Date(now.Year(), now.Month(), now.Day(),
-			hour.Hour(), minute.Minute(), 0, 0, now.Location())
-
-		// If time has passed today, schedule for tomorrow
-		if nextSend.Before(now) {
-			nextSend = nextSend.Add(24 * time.Hour)
-		}
-		return &nextSend
-
-	case "weekly":
-		// For weekly, use days_of_week
-		// Find next matching day
-		currentDay := int(now.Weekday())
-		if currentDay == 0 { // Sunday is 0, but we use 7
-			currentDay = 7
+	case "once", "daily":
+		nextSend = candidate
+		if !nextSend.After(local) {
+			nextSend = candidate.AddDate(0, 0, 1)
 		}
 
-		// Find next day in daysOfWeek
-		var daysUntilNext int
+	case "weekly", "custom":
+		// No days selected behaves like daily
+		if len(daysOfWeek) == 0 {
+			daysOfWeek = []int{1, 2, 3, 4, 5, 6, 7}
+		}
 		found := false
-		for i := 0; i < 7; i++ {
-			testDay := (currentDay+i-1)%7 + 1
-			for _, day := range daysOfWeek {
-				if testDay == day {
-					daysUntilNext = i
+		for i := 0; i <= 7 && !found; i++ {
+			day := candidate.AddDate(0, 0, i)
+			if !day.After(local) {
+				continue
+			}
+			weekday := int(day.Weekday())
+			if weekday == 0 { // Sunday is 0, but we use 7
+				weekday = 7
+			}
+			for _, d := range daysOfWeek {
+				if d == weekday {
+					nextSend = day
 					found = true
 					break
 				}
 			}
-			if found {
-				break
-			}
+		}
+		if !found {
+			return nil
 		}
 
-		nextSend := time.Date(now.Year(), now.Month(), now.Day()+daysUntilNext,
-			hour.Hour(), minute.Minute(), 0, 0, now.Location())
-
-		// If same day but time passed, go to next week occurrence
-		if daysUntilNext == 0 && nextSend.Before(now) {
-			nextSend = nextSend.Add(7 * 24 * time.Hour)
+	case "monthly":
+		// Counted from the creation day every time, so a schedule from the 31st runs on
+		// 28 February and then 31 March instead of drifting to the 28th
+		day := createdAt.In(loc).Day()
+		nextSend = monthlyOccurrence(local.Year(), local.Month(), day, clock, loc)
+		if !nextSend.After(local) {
+			nextSend = monthlyOccurrence(local.Year(), local.Month()+1, day, clock, loc)
 		}
-		return &nextSend
 
 	default:
-		// For monthly and custom, return nil (need more complex logic)
 		return nil
 	}
+
+	nextSend = nextSend.UTC()
+	return &nextSend
+}
+
+// monthlyOccurrence is day of the month at clock, moved back to the month's last day when
+// the month is shorter
+func monthlyOccurrence(year int, month time.Month, day int, clock []int, loc *time.Location) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	return time.Date(year, month, min(day, lastDay), clock[0], clock[1], clock[2], 0, loc)
 }
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalculateNextSendTime(t *testing.T) {
+	at := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name         string
+		scheduleType string
+		clock        string
+		daysOfWeek   []int
+		timezone     string
+		createdAt    time.Time
+		after        time.Time
+		want         *time.Time
+	}{
+		{"daily, later today", "daily", "09:00", nil, "UTC", at(2026, 3, 1, 8), at(2026, 3, 10, 8), ptrTime(at(2026, 3, 10, 9))},
+		{"daily, time passed", "daily", "09:00", nil, "UTC", at(2026, 3, 1, 8), at(2026, 3, 10, 9), ptrTime(at(2026, 3, 11, 9))},
+		{"weekly, next Monday", "weekly", "09:00:00", []int{1}, "UTC", at(2026, 3, 1, 8), at(2026, 3, 7, 12), ptrTime(at(2026, 3, 9, 9))},
+		{"monthly, later this month", "monthly", "09:00", nil, "UTC", at(2026, 1, 15, 8), at(2026, 3, 15, 8), ptrTime(at(2026, 3, 15, 9))},
+		{"monthly, 31st into February", "monthly", "09:00", nil, "UTC", at(2026, 1, 31, 8), at(2026, 1, 31, 9), ptrTime(at(2026, 2, 28, 9))},
+		{"monthly, back to the 31st after February", "monthly", "09:00", nil, "UTC", at(2026, 1, 31, 8), at(2026, 2, 28, 9), ptrTime(at(2026, 3, 31, 9))},
+		{"monthly, 31st into April", "monthly", "09:00", nil, "UTC", at(2026, 1, 31, 8), at(2026, 3, 31, 10), ptrTime(at(2026, 4, 30, 9))},
+		{"monthly, 30th in a leap February", "monthly", "09:00", nil, "UTC", at(2028, 1, 30, 8), at(2028, 1, 30, 9), ptrTime(at(2028, 2, 29, 9))},
+		{"monthly, into the next year", "monthly", "09:00", nil, "UTC", at(2025, 12, 31, 8), at(2025, 12, 31, 9), ptrTime(at(2026, 1, 31, 9))},
+		// Created at 01:00 on the 31st in UTC+7, which is still the 30th in UTC
+		{"monthly, creation day in the schedule's timezone", "monthly", "09:00", nil, "Asia/Ho_Chi_Minh", at(2026, 1, 30, 18), at(2026, 3, 1, 0), ptrTime(at(2026, 3, 31, 2))},
+		{"invalid time", "daily", "25:00", nil, "UTC", at(2026, 3, 1, 8), at(2026, 3, 10, 8), nil},
+		{"unknown type", "yearly", "09:00", nil, "UTC", at(2026, 3, 1, 8), at(2026, 3, 10, 8), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calculateNextSendTime(tt.scheduleType, tt.clock, tt.daysOfWeek, tt.timezone, tt.createdAt, tt.after)
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("calculateNextSendTime = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/models"
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/repository"
+)
+
+// ScheduledDispatcher polls scheduled_notifications and sends the ones that are due, and
+// releases notifications held back by quiet hours or the daily limit.
+// Rows are claimed with FOR UPDATE SKIP LOCKED, so several replicas can run it at once.
+type ScheduledDispatcher struct {
+	repo         *repository.NotificationRepository
+	service      *NotificationService
+	pollInterval time.Duration
+	batchSize    int
+	claimTimeout time.Duration
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+// NewScheduledDispatcher creates a dispatcher that sends through service
+func NewScheduledDispatcher(repo *repository.NotificationRepository, service *NotificationService, pollInterval time.Duration, batchSize int, claimTimeout time.Duration) *ScheduledDispatcher {
+	return &ScheduledDispatcher{
+		repo:         repo,
+		service:      service,
+		pollInterval: pollInterval,
+		batchSize:    batchSize,
+		claimTimeout: claimTimeout,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Start runs the dispatch loop until Stop is called
+func (d *ScheduledDispatcher) Start() {
+	defer close(d.done)
+
+	d.scheduleMissing()
+
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	log.Printf("[Scheduled-Dispatcher] 🔄 Started (polling every %v)", d.pollInterval)
+
+	for {
+		d.dispatchDue()
+		d.releaseDeferred()
+
+		select {
+		case <-ticker.C:
+		case <-d.stop:
+			log.Println("[Scheduled-Dispatcher] 🛑 Stopped")
+			return
+		}
+	}
+}
+
+// Stop signals the loop to exit and waits for the current batch to finish
+func (d *ScheduledDispatcher) Stop() {
+	close(d.stop)
+	<-d.done
+}
+
+// scheduleMissing computes next_send_at for active schedules that never got one,
+// otherwise they would never become due
+func (d *ScheduledDispatcher) scheduleMissing() {
+	schedules, err := d.repo.GetUnscheduledActiveNotifications(d.batchSize)
+	if err != nil {
+		log.Printf("[Scheduled-Dispatcher] ⚠️ Failed to load unscheduled notifications: %v", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		nextSend := calculateNextSendTime(schedule.ScheduleType, schedule.ScheduledTime, schedule.DaysOfWeek, schedule.Timezone, schedule.CreatedAt, time.Now())
+		if err := d.repo.SetScheduledNotificationNextSend(schedule.ID, nextSend, nextSend != nil); err != nil {
+			log.Printf("[Scheduled-Dispatcher] ⚠️ Failed to schedule %s: %v", schedule.ID, err)
+			continue
+		}
+		if nextSend == nil {
+			log.Printf("[Scheduled-Dispatcher] ⚠️ Deactivated invalid schedule %s (type=%s, time=%s)", schedule.ID, schedule.ScheduleType, schedule.ScheduledTime)
+		}
+	}
+}
+
+// dispatchDue claims one batch of due schedules and sends them
+func (d *ScheduledDispatcher) dispatchDue() {
+	schedules, err := d.repo.ClaimDueScheduledNotifications(time.Now().UTC(), d.batchSize, d.claimTimeout)
+	if err != nil {
+		log.Printf("[Scheduled-Dispatcher] ⚠️ Failed to claim due notifications: %v", err)
+		return
+	}
+
+	if len(schedules) > 0 {
+		log.Printf("[Scheduled-Dispatcher] 📬 Dispatching %d scheduled notification(s)", len(schedules))
+	}
+
+	for i := range schedules {
+		d.dispatch(&schedules[i])
+	}
+}
+
+// releaseDeferred delivers held-back notifications whose deferral has ended
+func (d *ScheduledDispatcher) releaseDeferred() {
+	released, err := d.service.ReleaseDeferredNotifications(d.batchSize)
+	if err != nil {
+		log.Printf("[Scheduled-Dispatcher] ⚠️ Failed to release deferred notifications: %v", err)
+	}
+	if released > 0 {
+		log.Printf("[Scheduled-Dispatcher] 📬 Released %d deferred notification(s)", released)
+	}
+}
+
+// dispatch sends one claimed schedule and advances it to its next occurrence.
+// If sending fails for a reason other than user preferences the claim is left in
+// place, so the schedule is retried once the claim times out.
+func (d *ScheduledDispatcher) dispatch(schedule *models.ScheduledNotification) {
+	claimedUntil := *schedule.NextSendAt
+	now := time.Now().UTC()
+
+	req := &models.CreateNotificationRequest{
+		UserID:   schedule.UserID,
+		Type:     "reminder",
+		Category: "info",
+		Title:    schedule.Title,
+		Message:  schedule.Message,
+		ActionData: map[string]interface{}{
+			"scheduled_notification_id": schedule.ID.String(),
+			"schedule_type":             schedule.ScheduleType,
+		},
+	}
+
+	var sentAt *time.Time
+	notification, err := d.service.CreateNotification(req)
+	switch {
+	case err == nil:
+		sentAt = &now
+		if notification != nil {
+			log.Printf("[Scheduled-Dispatcher] ✅ Sent scheduled notification %s to user %s (notification %s)", schedule.ID, schedule.UserID, notification.ID)
+		} else {
+			log.Printf("[Scheduled-Dispatcher] ✅ Sent scheduled notification %s to user %s by email", schedule.ID, schedule.UserID)
+		}
+	case errors.Is(err, ErrNotificationBlocked):
+		log.Printf("[Scheduled-Dispatcher] 🔕 Scheduled notification %s skipped: %v", schedule.ID, err)
+	default:
+		log.Printf("[Scheduled-Dispatcher] ❌ Failed to send scheduled notification %s, will retry: %v", schedule.ID, err)
+		return
+	}
+
+	// "once" schedules are done after their single run
+	isActive := schedule.ScheduleType != "once"
+	var nextSend *time.Time
+	if isActive {
+		nextSend = calculateNextSendTime(schedule.ScheduleType, schedule.ScheduledTime, schedule.DaysOfWeek, schedule.Timezone, schedule.CreatedAt, now)
+		isActive = nextSend != nil
+	}
+
+	if err := d.repo.CompleteScheduledNotificationRun(schedule.ID, claimedUntil, sentAt, nextSend, isActive); err != nil {
+		log.Printf("[Scheduled-Dispatcher] ⚠️ Failed to advance scheduled notification %s: %v", schedule.ID, err)
+	}
+}
@@ -35,6 +35,10 @@ func main() {
 	// Initialize service
 	userService := service.NewUserService(userRepo, cfg)
 
+	// Start weekly progress report job
+	weeklyReportJob := service.NewWeeklyReportJob(userRepo, cfg)
+	go weeklyReportJob.Start()
+
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(cfg)
 
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 )
 
 type Config struct {
@@ -25,6 +26,10 @@ type Config struct {
 
 	// Service URLs
 	NotificationServiceURL string
+
+	// Weekly progress report email
+	WeeklyReportSendHour    int // Local hour on Monday when last week's report is sent
+	WeeklyReportPollMinutes int
 }
 
 func LoadConfig() *Config {
@@ -47,6 +52,10 @@ func LoadConfig() *Config {
 
 		// Service URLs
 		NotificationServiceURL: getEnv("NOTIFICATION_SERVICE_URL", "http://notification-service:8085"),
+
+		// Weekly progress report
+		WeeklyReportSendHour:    getEnvAsInt("WEEKLY_REPORT_SEND_HOUR", 8),
+		WeeklyReportPollMinutes: getEnvAsInt("WEEKLY_REPORT_POLL_MINUTES", 15),
 	}
 
 	log.Printf("✅ Configuration loaded successfully")
@@ -64,3 +73,11 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+func getEnvAsInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -27,9 +27,10 @@ func NewScoringHandler(service *service.UserService) *ScoringHandler {
 // FIX #10: BandScore validation updated
 // - Accept 0 to allow calculation from raw_score
 // - Logic validates: must have EITHER (band_score >= 1) OR (raw_score + total_questions)
+// A full four-skill test is recorded once with skill_type "overall" and the four skill bands
 type RecordTestResultRequest struct {
 	TestType       string     `json:"test_type" validate:"required,oneof=full_test mock_test sectional_test practice"`
-	SkillType      string     `json:"skill_type" validate:"required,oneof=listening reading writing speaking"`
+	SkillType      string     `json:"skill_type" validate:"required,oneof=listening reading writing speaking overall"`
 	IELTSVariant   *string    `json:"ielts_variant,omitempty" validate:"omitempty,oneof=academic general_training"`
 	BandScore      float64    `json:"band_score" validate:"min=0,max=9"` // Allow 0 for calculation from raw_score
 	RawScore       *int       `json:"raw_score,omitempty"`
@@ -40,6 +41,14 @@ type RecordTestResultRequest struct {
 	TestDate       time.Time  `json:"test_date"`
 	TestSource     string     `json:"test_source,omitempty"`
 	Notes          *string    `json:"notes,omitempty"`
+
+	// Full tests (skill_type "overall") only
+	ListeningBandScore  *float64 `json:"listening_band_score,omitempty"`
+	ReadingBandScore    *float64 `json:"reading_band_score,omitempty"`
+	WritingBandScore    *float64 `json:"writing_band_score,omitempty"`
+	SpeakingBandScore   *float64 `json:"speaking_band_score,omitempty"`
+	TestDurationMinutes *int     `json:"test_duration_minutes,omitempty"`
+	CompletionStatus    string   `json:"completion_status,omitempty"` // completed (default), incomplete
 }
 
 type RecordPracticeActivityRequest struct {
@@ -97,11 +106,79 @@ func (h *ScoringHandler) RecordTestResultInternal(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "ielts_variant is required for reading tests"})
 		return
 	}
-	if req.SkillType != "reading" && req.IELTSVariant != nil {
+	if req.SkillType != "reading" && req.SkillType != "overall" && req.IELTSVariant != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "ielts_variant should only be set for reading tests"})
 		return
 	}
 
+	completionStatus := req.CompletionStatus
+	if completionStatus == "" {
+		completionStatus = "completed"
+	}
+	if completionStatus != "completed" && completionStatus != "incomplete" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "completion_status must be completed or incomplete"})
+		return
+	}
+
+	// Full test: one combined record with the band of each skill
+	if req.SkillType == "overall" {
+		skillBands := []*float64{req.ListeningBandScore, req.ReadingBandScore, req.WritingBandScore, req.SpeakingBandScore}
+		scores := make([]float64, len(skillBands))
+		for i, band := range skillBands {
+			if band == nil {
+				continue
+			}
+			if !ielts.IsValidBandScore(*band) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "skill band scores must be 0-9 in 0.5 steps"})
+				return
+			}
+			scores[i] = *band
+		}
+		// The overall band is always derived from the skill bands
+		overall := ielts.CalculateOverallBand(scores[0], scores[1], scores[2], scores[3])
+		if overall == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "full test results need at least one skill band score"})
+			return
+		}
+
+		result := &models.OfficialTestResult{
+			UserID:              userID,
+			TestType:            req.TestType,
+			SkillType:           req.SkillType,
+			IELTSVariant:        req.IELTSVariant,
+			BandScore:           overall,
+			ListeningBandScore:  req.ListeningBandScore,
+			ReadingBandScore:    req.ReadingBandScore,
+			WritingBandScore:    req.WritingBandScore,
+			SpeakingBandScore:   req.SpeakingBandScore,
+			SourceService:       &sourceService,
+			SourceTable:         &sourceTable,
+			SourceID:            req.SourceID,
+			TestDate:            req.TestDate,
+			TestDurationMinutes: req.TestDurationMinutes,
+			CompletionStatus:    completionStatus,
+			TestSource:          &req.TestSource,
+			Notes:               req.Notes,
+		}
+		if req.TestDate.IsZero() {
+			result.TestDate = time.Now()
+		}
+
+		if err := h.service.RecordOfficialTestResult(result); err != nil {
+			log.Printf("❌ Error recording full test result: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record test result"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"success":        true,
+			"test_result_id": result.ID,
+			"band_score":     overall,
+			"message":        "Test result recorded successfully",
+		})
+		return
+	}
+
 	// FIX #10: Validate that EITHER band_score OR raw_score is provided
 	// Valid IELTS bands: 1.0, 1.5, 2.0, ..., 8.5, 9.0 (0 is NOT valid)
 	bandScore := req.BandScore
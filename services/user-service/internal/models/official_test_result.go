@@ -7,7 +7,8 @@ import (
 )
 
 // OfficialTestResult represents an official IELTS test result for ONE skill
-// Per-skill model: Each record represents one skill test (listening, reading, writing, or speaking)
+// Per-skill model: Each record represents one skill test (listening, reading, writing, or speaking),
+// or one full four-skill test (skill_type "overall") with the band of each skill
 // This is the SOURCE OF TRUTH for user's official band scores
 type OfficialTestResult struct {
 	ID     uuid.UUID `json:"id" db:"id"`
@@ -16,10 +17,10 @@ type OfficialTestResult struct {
 	// Test classification: full_test, mock_test, sectional_test, practice
 	TestType string `json:"test_type" db:"test_type" validate:"required,oneof=full_test mock_test sectional_test practice"`
 
-	// Which skill was tested
-	SkillType string `json:"skill_type" db:"skill_type" validate:"required,oneof=listening reading writing speaking"`
+	// Which skill was tested ("overall" for a full test)
+	SkillType string `json:"skill_type" db:"skill_type" validate:"required,oneof=listening reading writing speaking overall"`
 
-	// IELTS test variant (academic/general_training) - ONLY for Reading skill
+	// IELTS test variant (academic/general_training) - ONLY for Reading skill and full tests
 	// For Listening/Writing/Speaking, this should be NULL
 	IELTSVariant *string `json:"ielts_variant,omitempty" db:"ielts_variant" validate:"omitempty,oneof=academic general_training"`
 
@@ -32,6 +33,12 @@ type OfficialTestResult struct {
 	// Total questions for Listening/Reading (usually 40)
 	TotalQuestions *int `json:"total_questions,omitempty" db:"total_questions" validate:"omitempty,min=0"`
 
+	// Skill bands of a full test (skill_type "overall" only); BandScore is the overall band
+	ListeningBandScore *float64 `json:"listening_band_score,omitempty" db:"listening_band_score" validate:"omitempty,min=0,max=9"`
+	ReadingBandScore   *float64 `json:"reading_band_score,omitempty" db:"reading_band_score" validate:"omitempty,min=0,max=9"`
+	WritingBandScore   *float64 `json:"writing_band_score,omitempty" db:"writing_band_score" validate:"omitempty,min=0,max=9"`
+	SpeakingBandScore  *float64 `json:"speaking_band_score,omitempty" db:"speaking_band_score" validate:"omitempty,min=0,max=9"`
+
 	// Source tracking (audit trail)
 	SourceService *string    `json:"source_service,omitempty" db:"source_service"` // exercise_service
 	SourceTable   *string    `json:"source_table,omitempty" db:"source_table"`     // user_exercise_attempts
@@ -54,6 +61,27 @@ func (OfficialTestResult) TableName() string {
 	return "official_test_results"
 }
 
+// IsFullTest returns true for a combined four-skill result
+func (o *OfficialTestResult) IsFullTest() bool {
+	return o.SkillType == "overall"
+}
+
+// SkillBands returns the skill bands of a full test, keyed by skill. Skills without a band are left out.
+func (o *OfficialTestResult) SkillBands() map[string]float64 {
+	bands := make(map[string]float64)
+	for skill, score := range map[string]*float64{
+		"listening": o.ListeningBandScore,
+		"reading":   o.ReadingBandScore,
+		"writing":   o.WritingBandScore,
+		"speaking":  o.SpeakingBandScore,
+	} {
+		if score != nil && *score > 0 {
+			bands[skill] = *score
+		}
+	}
+	return bands
+}
+
 // Validate performs business logic validation
 func (o *OfficialTestResult) Validate() error {
 	// Additional business logic validation beyond struct tags
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WeeklyReportRecipient is a user who has the weekly progress email enabled
+type WeeklyReportRecipient struct {
+	UserID   uuid.UUID `json:"user_id" db:"user_id"`
+	FullName *string   `json:"full_name,omitempty" db:"full_name"`
+	Timezone *string   `json:"timezone,omitempty" db:"timezone"`
+}
+
+// WeeklySkillActivity summarizes one skill's practice in the reported week
+type WeeklySkillActivity struct {
+	Skill               string   `json:"skill"`
+	Exercises           int      `json:"exercises"`
+	AverageBand         *float64 `json:"average_band,omitempty"`
+	PreviousAverageBand *float64 `json:"previous_average_band,omitempty"` // Same skill, the week before
+}
+
+// WeeklyReport is the data behind a user's weekly progress email.
+// WeekStart/WeekEnd are local midnights in the user's timezone (end exclusive).
+type WeeklyReport struct {
+	UserID               uuid.UUID                       `json:"user_id"`
+	WeekStart            time.Time                       `json:"week_start"`
+	WeekEnd              time.Time                       `json:"week_end"`
+	StudyMinutes         int                             `json:"study_minutes"`
+	PreviousStudyMinutes int                             `json:"previous_study_minutes"`
+	ExercisesTotal       int                             `json:"exercises_total"`
+	Skills               map[string]*WeeklySkillActivity `json:"skills"`
+	CurrentStreakDays    int                             `json:"current_streak_days"`
+	LongestStreakDays    int                             `json:"longest_streak_days"`
+	Goals                []StudyGoal                     `json:"goals"`
+}
+
+// IsEmpty reports whether the user did nothing worth reporting this week
+func (r *WeeklyReport) IsEmpty() bool {
+	return r.StudyMinutes == 0 && r.ExercisesTotal == 0 && len(r.Goals) == 0
+}
@@ -62,9 +62,10 @@ func (r *UserRepository) CreateOfficialTestResultTx(tx *sql.Tx, result *models.O
 			raw_score, total_questions,
 			source_service, source_table, source_id,
 			test_date, test_duration_minutes, completion_status,
-			test_source, notes
+			test_source, notes,
+			listening_band_score, reading_band_score, writing_band_score, speaking_band_score
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19
 		) RETURNING id, created_at, updated_at`
 
 	err := tx.QueryRow(
@@ -84,6 +85,10 @@ func (r *UserRepository) CreateOfficialTestResultTx(tx *sql.Tx, result *models.O
 		result.CompletionStatus,
 		result.TestSource,
 		result.Notes,
+		result.ListeningBandScore,
+		result.ReadingBandScore,
+		result.WritingBandScore,
+		result.SpeakingBandScore,
 	).Scan(&result.ID, &result.CreatedAt, &result.UpdatedAt)
 
 	if err != nil {
@@ -1943,9 +1948,10 @@ func (r *UserRepository) CreateOfficialTestResult(result *models.OfficialTestRes
 			raw_score, total_questions,
 			source_service, source_table, source_id,
 			test_date, test_duration_minutes, completion_status,
-			test_source, notes
+			test_source, notes,
+			listening_band_score, reading_band_score, writing_band_score, speaking_band_score
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19
 		) RETURNING id, created_at, updated_at`
 
 	err := r.db.DB.QueryRow(
@@ -1965,6 +1971,10 @@ func (r *UserRepository) CreateOfficialTestResult(result *models.OfficialTestRes
 		result.CompletionStatus,
 		result.TestSource,
 		result.Notes,
+		result.ListeningBandScore,
+		result.ReadingBandScore,
+		result.WritingBandScore,
+		result.SpeakingBandScore,
 	).Scan(&result.ID, &result.CreatedAt, &result.UpdatedAt)
 
 	if err != nil {
@@ -2003,8 +2013,9 @@ func (r *UserRepository) GetUserTestHistory(userID uuid.UUID, skillType *string,
 
 	// Get paginated results
 	query := fmt.Sprintf(`
-		SELECT id, user_id, test_type, skill_type, band_score,
+		SELECT id, user_id, test_type, skill_type, ielts_variant, band_score,
 			   raw_score, total_questions,
+			   listening_band_score, reading_band_score, writing_band_score, speaking_band_score,
 			   source_service, source_table, source_id,
 			   test_date, test_duration_minutes, completion_status,
 			   test_source, notes, created_at, updated_at
@@ -2029,9 +2040,14 @@ func (r *UserRepository) GetUserTestHistory(userID uuid.UUID, skillType *string,
 			&result.UserID,
 			&result.TestType,
 			&result.SkillType,
+			&result.IELTSVariant,
 			&result.BandScore,
 			&result.RawScore,
 			&result.TotalQuestions,
+			&result.ListeningBandScore,
+			&result.ReadingBandScore,
+			&result.WritingBandScore,
+			&result.SpeakingBandScore,
 			&result.SourceService,
 			&result.SourceTable,
 			&result.SourceID,
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/user-service/internal/models"
+	"github.com/google/uuid"
+)
+
+var reportSkills = []string{"listening", "reading", "writing", "speaking"}
+
+// GetWeeklyReportRecipients pages through users with the weekly report enabled, ordered by user_id.
+// Users without a preferences row get the defaults (weekly_report and email_notifications on).
+func (r *UserRepository) GetWeeklyReportRecipients(afterUserID uuid.UUID, limit int) ([]models.WeeklyReportRecipient, error) {
+	query := `
+		SELECT up.user_id, up.full_name, up.timezone
+		FROM user_profiles up
+		LEFT JOIN user_preferences pref ON pref.user_id = up.user_id
+		WHERE up.deleted_at IS NULL
+		  AND up.user_id > $1
+		  AND COALESCE(pref.weekly_report, true) = true
+		  AND COALESCE(pref.email_notifications, true) = true
+		ORDER BY up.user_id
+		LIMIT $2
+	`
+
+	rows, err := r.db.DB.Query(query, afterUserID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get weekly report recipients: %w", err)
+	}
+	defer rows.Close()
+
+	recipients := []models.WeeklyReportRecipient{}
+	for rows.Next() {
+		var recipient models.WeeklyReportRecipient
+		if err := rows.Scan(&recipient.UserID, &recipient.FullName, &recipient.Timezone); err != nil {
+			return nil, fmt.Errorf("failed to scan weekly report recipient: %w", err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, rows.Err()
+}
+
+// ClaimWeeklyReport records that the report for weekStart is being sent to the user.
+// Returns false when another run (or replica) already claimed it.
+func (r *UserRepository) ClaimWeeklyReport(userID uuid.UUID, weekStart time.Time) (bool, error) {
+	query := `
+		INSERT INTO weekly_report_deliveries (user_id, week_start, sent_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, week_start) DO NOTHING
+	`
+
+	result, err := r.db.DB.Exec(query, userID, weekStart.Format("2006-01-02"), time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim weekly report: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim weekly report: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// ReleaseWeeklyReport drops a claim so the report is retried on the next run
+func (r *UserRepository) ReleaseWeeklyReport(userID uuid.UUID, weekStart time.Time) error {
+	query := `DELETE FROM weekly_report_deliveries WHERE user_id = $1 AND week_start = $2`
+	if _, err := r.db.DB.Exec(query, userID, weekStart.Format("2006-01-02")); err != nil {
+		return fmt.Errorf("failed to release weekly report: %w", err)
+	}
+	return nil
+}
+
+// GetWeeklyReport aggregates study time, practice per skill, streaks and goals for
+// [weekStart, weekEnd), comparing with the week before
+func (r *UserRepository) GetWeeklyReport(userID uuid.UUID, weekStart, weekEnd time.Time) (*models.WeeklyReport, error) {
+	from := weekStart.UTC()
+	to := weekEnd.UTC()
+	prevFrom := weekStart.AddDate(0, 0, -7).UTC()
+
+	report := &models.WeeklyReport{
+		UserID:    userID,
+		WeekStart: weekStart,
+		WeekEnd:   weekEnd,
+		Skills:    make(map[string]*models.WeeklySkillActivity),
+		Goals:     []models.StudyGoal{},
+	}
+	for _, skill := range reportSkills {
+		report.Skills[skill] = &models.WeeklySkillActivity{Skill: skill}
+	}
+
+	// Study time (source of truth: study_sessions)
+	minutesQuery := `
+		SELECT
+			COALESCE(SUM(duration_minutes) FILTER (WHERE started_at >= $2), 0),
+			COALESCE(SUM(duration_minutes) FILTER (WHERE started_at < $2), 0)
+		FROM study_sessions
+		WHERE user_id = $1 AND started_at >= $3 AND started_at < $4
+	`
+	err := r.db.DB.QueryRow(minutesQuery, userID, from, prevFrom, to).Scan(&report.StudyMinutes, &report.PreviousStudyMinutes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get weekly study minutes: %w", err)
+	}
+
+	// Practice per skill with band averages for this week and the previous one
+	skillsQuery := `
+		SELECT skill,
+			COUNT(*) FILTER (WHERE completed_at >= $2),
+			ROUND(AVG(band_score) FILTER (WHERE completed_at >= $2), 1),
+			ROUND(AVG(band_score) FILTER (WHERE completed_at < $2), 1)
+		FROM practice_activities
+		WHERE user_id = $1
+		  AND completion_status = 'completed'
+		  AND completed_at >= $3 AND completed_at < $4
+		GROUP BY skill
+	`
+	rows, err := r.db.DB.Query(skillsQuery, userID, from, prevFrom, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get weekly practice activities: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var skill string
+		var exercises int
+		var avgBand, prevAvgBand sql.NullFloat64
+		if err := rows.Scan(&skill, &exercises, &avgBand, &prevAvgBand); err != nil {
+			return nil, fmt.Errorf("failed to scan weekly practice activity: %w", err)
+		}
+		activity, ok := report.Skills[skill]
+		if !ok {
+			continue
+		}
+		activity.Exercises = exercises
+		if avgBand.Valid {
+			activity.AverageBand = &avgBand.Float64
+		}
+		if prevAvgBand.Valid {
+			activity.PreviousAverageBand = &prevAvgBand.Float64
+		}
+		report.ExercisesTotal += exercises
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read weekly practice activities: %w", err)
+	}
+
+	// Streaks
+	streakQuery := `
+		SELECT COALESCE(current_streak_days, 0), COALESCE(longest_streak_days, 0)
+		FROM learning_progress
+		WHERE user_id = $1
+	`
+	err = r.db.DB.QueryRow(streakQuery, userID).Scan(&report.CurrentStreakDays, &report.LongestStreakDays)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get streaks: %w", err)
+	}
+
+	// Goals still running, plus those completed during the week
+	goalsQuery := `
+		SELECT id, user_id, goal_type, title, description, target_value, target_unit, current_value, skill_type, start_date, end_date,
+		       status, completed_at, reminder_enabled, reminder_time, created_at, updated_at
+		FROM study_goals
+		WHERE user_id = $1
+		  AND (status = 'active' OR (status = 'completed' AND completed_at >= $2 AND completed_at < $3))
+		ORDER BY status, end_date
+	`
+	goalRows, err := r.db.DB.Query(goalsQuery, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get weekly goals: %w", err)
+	}
+	defer goalRows.Close()
+
+	for goalRows.Next() {
+		goal := models.StudyGoal{}
+		err := goalRows.Scan(&goal.ID, &goal.UserID, &goal.GoalType, &goal.Title, &goal.Description, &goal.TargetValue, &goal.TargetUnit, &goal.CurrentValue,
+			&goal.SkillType, &goal.StartDate, &goal.EndDate, &goal.Status, &goal.CompletedAt,
+			&goal.ReminderEnabled, &goal.ReminderTime, &goal.CreatedAt, &goal.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan goal: %w", err)
+		}
+		report.Goals = append(report.Goals, goal)
+	}
+	if err := goalRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read weekly goals: %w", err)
+	}
+
+	return report, nil
+}
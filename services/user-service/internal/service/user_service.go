@@ -1366,6 +1366,26 @@ func (s *UserService) RecordOfficialTestResult(result *models.OfficialTestResult
 		return fmt.Errorf("failed to create test result: %w", err)
 	}
 
+	// A full test updates every skill it scored, and its overall band is the test's own
+	if result.IsFullTest() {
+		for skill, band := range result.SkillBands() {
+			if err := s.repo.UpdateLearningProgressWithTestScoreTx(tx, result.UserID, skill, band, true); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to update learning progress for %s: %w", skill, err)
+			}
+		}
+		if err := s.repo.UpdateLearningProgressWithTestScoreTx(tx, result.UserID, "overall", result.BandScore, false); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to update overall score: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		log.Printf("✅ Recorded full test result for user %s (overall: %.1f)", result.UserID, result.BandScore)
+		return nil
+	}
+
 	// 2. Update learning_progress for THIS skill only
 	if result.BandScore > 0 {
 		if err := s.repo.UpdateLearningProgressWithTestScoreTx(
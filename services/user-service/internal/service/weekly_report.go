@@ -0,0 +1,278 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/user-service/internal/config"
+	"github.com/bisosad1501/DATN/services/user-service/internal/models"
+	"github.com/bisosad1501/DATN/services/user-service/internal/repository"
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/google/uuid"
+)
+
+const (
+	// weeklyReportTemplateCode must match the notification_templates entry in notification-service
+	weeklyReportTemplateCode = "weekly_progress_report"
+	weeklyReportBatchSize    = 200
+	defaultReportTimezone    = "Asia/Ho_Chi_Minh"
+)
+
+// WeeklyReportJob emails every opted-in user a summary of last week's study every Monday
+// morning in their own timezone. Deliveries are tracked per (user, week) so a report is sent
+// at most once even with several user-service replicas.
+type WeeklyReportJob struct {
+	repo               *repository.UserRepository
+	authClient         *client.AuthServiceClient
+	notificationClient *client.NotificationServiceClient
+	sendHour           int
+	pollInterval       time.Duration
+	stop               chan struct{}
+	done               chan struct{}
+}
+
+// NewWeeklyReportJob creates the weekly report job
+func NewWeeklyReportJob(repo *repository.UserRepository, cfg *config.Config) *WeeklyReportJob {
+	return &WeeklyReportJob{
+		repo:               repo,
+		authClient:         client.NewAuthServiceClient(cfg.AuthServiceURL, cfg.InternalAPIKey),
+		notificationClient: client.NewNotificationServiceClient(cfg.NotificationServiceURL, cfg.InternalAPIKey),
+		sendHour:           cfg.WeeklyReportSendHour,
+		pollInterval:       time.Duration(cfg.WeeklyReportPollMinutes) * time.Minute,
+		stop:               make(chan struct{}),
+		done:               make(chan struct{}),
+	}
+}
+
+// Start runs the job until Stop is called
+func (j *WeeklyReportJob) Start() {
+	defer close(j.done)
+
+	ticker := time.NewTicker(j.pollInterval)
+	defer ticker.Stop()
+
+	log.Printf("📊 Weekly report job started (Monday %02d:00 local time, polling every %v)", j.sendHour, j.pollInterval)
+
+	for {
+		j.run(time.Now())
+
+		select {
+		case <-ticker.C:
+		case <-j.stop:
+			log.Println("🛑 Weekly report job stopped")
+			return
+		}
+	}
+}
+
+// Stop signals the job to exit and waits for the current run to finish
+func (j *WeeklyReportJob) Stop() {
+	close(j.stop)
+	<-j.done
+}
+
+// run sends the reports that are due at now
+func (j *WeeklyReportJob) run(now time.Time) {
+	sent := 0
+	after := uuid.Nil
+	for {
+		recipients, err := j.repo.GetWeeklyReportRecipients(after, weeklyReportBatchSize)
+		if err != nil {
+			log.Printf("⚠️  Weekly report: failed to load recipients: %v", err)
+			return
+		}
+
+		for _, recipient := range recipients {
+			if j.sendIfDue(recipient, now) {
+				sent++
+			}
+		}
+
+		if len(recipients) < weeklyReportBatchSize {
+			break
+		}
+		after = recipients[len(recipients)-1].UserID
+	}
+
+	if sent > 0 {
+		log.Printf("📊 Weekly report: sent %d report(s)", sent)
+	}
+}
+
+// sendIfDue sends last week's report to one user when it is due in their timezone
+func (j *WeeklyReportJob) sendIfDue(recipient models.WeeklyReportRecipient, now time.Time) bool {
+	timezone := ""
+	if recipient.Timezone != nil {
+		timezone = *recipient.Timezone
+	}
+	weekStart, due := reportWeek(now, reportLocation(timezone), j.sendHour)
+	if !due {
+		return false
+	}
+
+	claimed, err := j.repo.ClaimWeeklyReport(recipient.UserID, weekStart)
+	if err != nil {
+		log.Printf("⚠️  Weekly report: %v", err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+
+	if err := j.send(recipient, weekStart); err != nil {
+		log.Printf("⚠️  Weekly report for user %s failed, will retry: %v", recipient.UserID, err)
+		if err := j.repo.ReleaseWeeklyReport(recipient.UserID, weekStart); err != nil {
+			log.Printf("⚠️  Weekly report: %v", err)
+		}
+		return false
+	}
+	return true
+}
+
+// send builds the report and hands it to notification-service as an email.
+// Users with nothing to report, or whose account is inactive, are skipped (the claim is kept).
+func (j *WeeklyReportJob) send(recipient models.WeeklyReportRecipient, weekStart time.Time) error {
+	report, err := j.repo.GetWeeklyReport(recipient.UserID, weekStart, weekStart.AddDate(0, 0, 7))
+	if err != nil {
+		return err
+	}
+	if report.IsEmpty() {
+		return nil
+	}
+
+	// Email addresses live in auth-service
+	contact, err := j.authClient.GetUserContact(recipient.UserID.String())
+	if err != nil {
+		return err
+	}
+	if !contact.IsActive || contact.Email == "" {
+		return nil
+	}
+
+	fullName := ""
+	if recipient.FullName != nil {
+		fullName = *recipient.FullName
+	}
+
+	return j.notificationClient.SendNotification(client.SendNotificationRequest{
+		UserID:       recipient.UserID.String(),
+		Type:         "system",
+		Category:     "info",
+		Channel:      "email",
+		Email:        contact.Email,
+		TemplateCode: weeklyReportTemplateCode,
+		TemplateData: weeklyReportTemplateData(report, fullName),
+	})
+}
+
+// reportLocation resolves the user's timezone, falling back to the platform default
+func reportLocation(timezone string) *time.Location {
+	if timezone != "" {
+		if loc, err := time.LoadLocation(timezone); err == nil {
+			return loc
+		}
+	}
+	if loc, err := time.LoadLocation(defaultReportTimezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// reportWeek returns the Monday (local midnight) of the week before now and whether its
+// report is due: from sendHour on Monday until sendHour on Tuesday in loc, so a run missed
+// on Monday morning still catches up later that day.
+func reportWeek(now time.Time, loc *time.Location, sendHour int) (time.Time, bool) {
+	local := now.In(loc)
+	daysSinceMonday := (int(local.Weekday()) + 6) % 7
+	thisMonday := time.Date(local.Year(), local.Month(), local.Day()-daysSinceMonday, 0, 0, 0, 0, loc)
+
+	sendAt := thisMonday.Add(time.Duration(sendHour) * time.Hour)
+	due := !local.Before(sendAt) && local.Before(sendAt.AddDate(0, 0, 1))
+
+	return thisMonday.AddDate(0, 0, -7), due
+}
+
+// weeklyReportTemplateData flattens a report into the weekly_progress_report template variables
+func weeklyReportTemplateData(report *models.WeeklyReport, fullName string) map[string]string {
+	if fullName == "" {
+		fullName = "bạn"
+	}
+
+	lastDay := report.WeekEnd.AddDate(0, 0, -1)
+	data := map[string]string{
+		"full_name":            fullName,
+		"week_range":           fmt.Sprintf("%s - %s", report.WeekStart.Format("02/01"), lastDay.Format("02/01/2006")),
+		"study_minutes":        fmt.Sprintf("%d", report.StudyMinutes),
+		"study_minutes_change": studyMinutesChange(report.StudyMinutes, report.PreviousStudyMinutes),
+		"exercises_total":      fmt.Sprintf("%d", report.ExercisesTotal),
+		"current_streak":       fmt.Sprintf("%d", report.CurrentStreakDays),
+		"longest_streak":       fmt.Sprintf("%d", report.LongestStreakDays),
+		"goals_summary":        goalsSummary(report.Goals),
+	}
+
+	for skill, activity := range report.Skills {
+		data[skill+"_exercises"] = fmt.Sprintf("%d", activity.Exercises)
+		data[skill+"_band"] = formatBand(activity.AverageBand)
+		data[skill+"_trend"] = bandTrend(activity.AverageBand, activity.PreviousAverageBand)
+	}
+
+	return data
+}
+
+func studyMinutesChange(current, previous int) string {
+	diff := current - previous
+	switch {
+	case diff > 0:
+		return fmt.Sprintf("+%d phút so với tuần trước", diff)
+	case diff < 0:
+		return fmt.Sprintf("%d phút so với tuần trước", diff)
+	default:
+		return "bằng tuần trước"
+	}
+}
+
+func formatBand(band *float64) string {
+	if band == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", *band)
+}
+
+// bandTrend describes the change of a skill's average band versus the previous week
+func bandTrend(current, previous *float64) string {
+	if current == nil {
+		return "-"
+	}
+	if previous == nil {
+		return "mới"
+	}
+
+	diff := *current - *previous
+	switch {
+	case diff >= 0.05:
+		return fmt.Sprintf("▲ +%.1f", diff)
+	case diff <= -0.05:
+		return fmt.Sprintf("▼ %.1f", diff)
+	default:
+		return "không đổi"
+	}
+}
+
+// goalsSummary renders one line per goal (the template keeps line breaks)
+func goalsSummary(goals []models.StudyGoal) string {
+	if len(goals) == 0 {
+		return "Bạn chưa có mục tiêu nào đang thực hiện."
+	}
+
+	lines := make([]string, 0, len(goals))
+	for _, goal := range goals {
+		if goal.Status == "completed" {
+			lines = append(lines, fmt.Sprintf("✓ %s: đã hoàn thành", goal.Title))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("• %s: %d/%d %s", goal.Title, goal.CurrentValue, goal.TargetValue, goal.TargetUnit))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportWeek(t *testing.T) {
+	hcm, err := time.LoadLocation("Asia/Ho_Chi_Minh")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		wantWeek string
+		wantDue  bool
+	}{
+		// 2026-10-19 is a Monday
+		{"before send hour", time.Date(2026, 10, 19, 7, 59, 0, 0, hcm), "2026-10-12", false},
+		{"at send hour", time.Date(2026, 10, 19, 8, 0, 0, 0, hcm), "2026-10-12", true},
+		{"monday evening", time.Date(2026, 10, 19, 22, 0, 0, 0, hcm), "2026-10-12", true},
+		{"tuesday catch-up", time.Date(2026, 10, 20, 7, 0, 0, 0, hcm), "2026-10-12", true},
+		{"tuesday after window", time.Date(2026, 10, 20, 8, 0, 0, 0, hcm), "2026-10-12", false},
+		{"sunday", time.Date(2026, 10, 25, 9, 0, 0, 0, hcm), "2026-10-12", false},
+		// Sunday 23:30 UTC is already Monday 06:30 in Ho Chi Minh
+		{"utc clock on previous day", time.Date(2026, 10, 25, 23, 30, 0, 0, time.UTC), "2026-10-19", false},
+		{"utc clock after local send hour", time.Date(2026, 10, 26, 1, 0, 0, 0, time.UTC), "2026-10-19", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			week, due := reportWeek(tt.now, hcm, 8)
+			assert.Equal(t, tt.wantWeek, week.Format("2006-01-02"))
+			assert.Equal(t, tt.wantDue, due)
+		})
+	}
+}
+
+func TestBandTrend(t *testing.T) {
+	band := func(v float64) *float64 { return &v }
+
+	assert.Equal(t, "-", bandTrend(nil, band(6.0)))
+	assert.Equal(t, "mới", bandTrend(band(6.0), nil))
+	assert.Equal(t, "▲ +0.5", bandTrend(band(6.5), band(6.0)))
+	assert.Equal(t, "▼ -1.0", bandTrend(band(5.5), band(6.5)))
+	assert.Equal(t, "không đổi", bandTrend(band(6.0), band(6.0)))
+}
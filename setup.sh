@@ -78,6 +78,7 @@ if [ ! -f .env ]; then
 # JWT Configuration
 JWT_SECRET=your-super-secret-jwt-key-change-this-in-production-2025
 JWT_EXPIRATION=86400
+TWO_FACTOR_ENCRYPTION_KEY=your-two-factor-encryption-key-change-this-in-production-2025
 
 # Database Configuration
 POSTGRES_USER=ielts_admin
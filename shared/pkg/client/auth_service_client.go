@@ -0,0 +1,45 @@
+package client
+
+import (
+	"fmt"
+)
+
+// AuthServiceClient handles communication with Auth Service
+type AuthServiceClient struct {
+	*ServiceClient
+}
+
+// NewAuthServiceClient creates a new auth service client
+func NewAuthServiceClient(baseURL, apiKey string) *AuthServiceClient {
+	return &AuthServiceClient{
+		ServiceClient: NewServiceClient(baseURL, apiKey),
+	}
+}
+
+// UserContact is the account contact info owned by Auth Service
+type UserContact struct {
+	UserID     string `json:"user_id"`
+	Email      string `json:"email"`
+	IsActive   bool   `json:"is_active"`
+	IsVerified bool   `json:"is_verified"`
+}
+
+// GetUserContact retrieves a user's email address and account status
+func (c *AuthServiceClient) GetUserContact(userID string) (*UserContact, error) {
+	endpoint := fmt.Sprintf("/api/v1/auth/internal/users/%s", userID)
+
+	resp, err := c.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("get user contact: %w", err)
+	}
+
+	var result struct {
+		Success bool        `json:"success"`
+		Data    UserContact `json:"data"`
+	}
+	if err := DecodeResponse(resp, &result); err != nil {
+		return nil, fmt.Errorf("get user contact: %w", err)
+	}
+
+	return &result.Data, nil
+}
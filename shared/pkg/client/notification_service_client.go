@@ -27,6 +27,12 @@ type SendNotificationRequest struct {
 	ActionData map[string]interface{} `json:"action_data,omitempty"`    // {course_id: "...", lesson_id: "...", url: "..."}
 	Priority   string                 `json:"priority,omitempty"`       // low, normal, high
 	Data       map[string]string      `json:"data,omitempty"`          // Deprecated, use ActionData instead
+
+	// Email delivery: Channel is in_app (default), email or both; Email is the recipient address
+	Channel      string            `json:"channel,omitempty"`
+	Email        string            `json:"email,omitempty"`
+	TemplateCode string            `json:"template_code,omitempty"` // Renders title/message (and email subject/HTML) from notification_templates
+	TemplateData map[string]string `json:"template_data,omitempty"`
 }
 
 // SendNotification sends a notification to a user
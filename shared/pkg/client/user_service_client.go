@@ -173,20 +173,28 @@ func (c *UserServiceClient) RecordCompletedSession(userID, sessionType, skillTyp
 // ============= Scoring System Methods =============
 
 // RecordTestResultRequest represents official test result recording request (per-skill model)
-// Each request records ONE skill test
+// Each request records ONE skill test, or one full four-skill test with skill_type "overall"
 type RecordTestResultRequest struct {
 	TestType       string     `json:"test_type"`                 // full_test, mock_test, sectional_test, practice
-	SkillType      string     `json:"skill_type"`                // listening, reading, writing, speaking
-	IELTSVariant   *string    `json:"ielts_variant,omitempty"`   // academic, general_training (ONLY for Reading)
-	BandScore      float64    `json:"band_score"`                // Final band score for THIS skill
+	SkillType      string     `json:"skill_type"`                // listening, reading, writing, speaking, overall
+	IELTSVariant   *string    `json:"ielts_variant,omitempty"`   // academic, general_training (Reading and overall)
+	BandScore      float64    `json:"band_score"`                // Final band score for THIS skill (overall band for full tests)
 	RawScore       *int       `json:"raw_score,omitempty"`       // For L/R only
 	TotalQuestions *int       `json:"total_questions,omitempty"` // For L/R only
 	SourceService  string     `json:"source_service,omitempty"`  // exercise_service
-	SourceTable    string     `json:"source_table,omitempty"`    // user_exercise_attempts
-	SourceID       *string    `json:"source_id,omitempty"`       // submission_id
+	SourceTable    string     `json:"source_table,omitempty"`    // user_exercise_attempts, test_sessions
+	SourceID       *string    `json:"source_id,omitempty"`       // submission_id or test session id
 	TestDate       *time.Time `json:"test_date,omitempty"`
 	TestSource     string     `json:"test_source,omitempty"` // platform, imported, manual_entry
 	Notes          *string    `json:"notes,omitempty"`
+
+	// Full tests (skill_type "overall") only
+	ListeningBandScore  *float64 `json:"listening_band_score,omitempty"`
+	ReadingBandScore    *float64 `json:"reading_band_score,omitempty"`
+	WritingBandScore    *float64 `json:"writing_band_score,omitempty"`
+	SpeakingBandScore   *float64 `json:"speaking_band_score,omitempty"`
+	TestDurationMinutes *int     `json:"test_duration_minutes,omitempty"`
+	CompletionStatus    string   `json:"completion_status,omitempty"` // completed (default), incomplete
 }
 
 // RecordPracticeActivityRequest represents practice activity recording request
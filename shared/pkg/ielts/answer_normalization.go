@@ -0,0 +1,443 @@
+package ielts
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NormalizeAnswer returns the canonical form of a Listening or Reading text answer, so that
+// answers IELTS markers accept as the same compare equal:
+//
+//   - case, spacing, surrounding punctuation, dash variants and hyphenation ("part-time" = "part time")
+//   - numbers: "three" = "3", "twenty-one" = "21", "1,500" = "1500", "5.50" = "5.5", "15th" = "15"
+//   - dates: "15th March" = "March 15" = "the 15th of March" (canonical "15 march")
+//   - times: "3pm" = "3 p.m." = "3:00 PM" = "15:00"
+//   - fractions stay fractions: "2/3"
+//   - currency: "£50" = "50 pounds" (canonical "50 gbp")
+//   - UK and US spellings: "colour" = "color", "organise" = "organize"
+//   - a leading article: "the library" = "library" (but "vitamin a" keeps its letter)
+//
+// A dotted number is a decimal ("17.50" = "17.5"). AnswerMatches reads it as a time
+// ("10.30" = "10:30") when the other answer is clearly a time.
+//
+// Answers that differ after normalization are different answers.
+func NormalizeAnswer(answer string) string {
+	return normalizeAnswer(answer, false)
+}
+
+// normalizeAnswer is NormalizeAnswer; with dottedTimes, "10.30" and "15.00" are times
+func normalizeAnswer(answer string, dottedTimes bool) string {
+	tokens := tokenizeAnswer(answer)
+	tokens = splitGluedTokens(tokens)
+	tokens = canonicalNumbers(tokens)
+	tokens = canonicalTimes(tokens, dottedTimes)
+	tokens = trimDecimals(tokens)
+	tokens = canonicalCurrency(tokens)
+	tokens = canonicalDates(tokens)
+	for i, token := range tokens {
+		tokens[i] = AmericanSpelling(token)
+	}
+	tokens = dropArticles(tokens)
+	return strings.Join(tokens, " ")
+}
+
+// AnswerMatches reports whether a candidate's answer matches any accepted answer after
+// normalization. Words in parentheses in an accepted answer are optional:
+// "(the) city centre" accepts "city centre" and "the city center".
+// When either side is clearly a time (a colon, am/pm or o'clock), dotted numbers on both
+// sides are read as times: "10.30" matches "10:30", while "17.50" still matches "17.5".
+func AnswerMatches(given string, accepted ...string) bool {
+	normalized := NormalizeAnswer(given)
+	if normalized == "" {
+		return false
+	}
+	givenIsTime := hasClockTime(normalized)
+	for _, answer := range accepted {
+		for _, form := range ExpandOptionalWords(answer) {
+			expected := NormalizeAnswer(form)
+			if givenIsTime || hasClockTime(expected) {
+				if normalizeAnswer(given, true) == normalizeAnswer(form, true) {
+					return true
+				}
+				continue
+			}
+			if expected == normalized {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var optionalWords = regexp.MustCompile(`\(([^()]*)\)`)
+
+// ExpandOptionalWords returns an answer with and without the words in parentheses
+func ExpandOptionalWords(answer string) []string {
+	if !optionalWords.MatchString(answer) {
+		return []string{answer}
+	}
+	return []string{
+		optionalWords.ReplaceAllString(answer, "$1"),
+		optionalWords.ReplaceAllString(answer, ""),
+	}
+}
+
+// ---- Tokenizing ----
+
+var (
+	thousandsSeparator = regexp.MustCompile(`(\d),(\d{3})\b`)
+	characterVariants  = strings.NewReplacer(
+		"‐", "-", "‑", "-", "–", "-", "—", "-", // dashes
+		"’", "'", "‘", "'", "“", `"`, "”", `"`, // quotes
+		"a.m.", "am", "p.m.", "pm", "a.m", "am", "p.m", "pm",
+	)
+	tokenSeparators = strings.NewReplacer("-", " ", ",", " ", ";", " ", "!", " ", "?", " ", `"`, " ", "(", " ", ")", " ", "/", " / ")
+	tokenEdges      = ".:'`"
+
+	// A slash between numbers is a fraction ("2/3"), not a separator
+	fraction        = regexp.MustCompile(`(\d)\s*/\s*(\d)`)
+	fractionSlash   = "\x00"
+	restoreFraction = strings.NewReplacer(fractionSlash, "/")
+)
+
+func tokenizeAnswer(answer string) []string {
+	s := characterVariants.Replace(strings.ToLower(answer))
+	for thousandsSeparator.MatchString(s) {
+		s = thousandsSeparator.ReplaceAllString(s, "$1$2")
+	}
+	s = fraction.ReplaceAllString(s, "$1"+fractionSlash+"$2")
+	s = restoreFraction.Replace(tokenSeparators.Replace(s))
+
+	var tokens []string
+	for _, token := range strings.Fields(s) {
+		if token = strings.Trim(token, tokenEdges); token != "" && token != "/" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+var (
+	gluedMeridiem = regexp.MustCompile(`^(\d{1,2}(?:[:.]\d{2})?)(am|pm)$`)
+	gluedCurrency = regexp.MustCompile(`^([£$€])(\d+(?:\.\d+)?)$`)
+	gluedOrdinal  = regexp.MustCompile(`^(\d+)(st|nd|rd|th)$`)
+)
+
+// splitGluedTokens separates "3pm", "£50" and "15th" into their parts
+func splitGluedTokens(tokens []string) []string {
+	var result []string
+	for _, token := range tokens {
+		switch {
+		case gluedMeridiem.MatchString(token):
+			m := gluedMeridiem.FindStringSubmatch(token)
+			result = append(result, m[1], m[2])
+		case gluedCurrency.MatchString(token):
+			m := gluedCurrency.FindStringSubmatch(token)
+			result = append(result, m[1], m[2])
+		case gluedOrdinal.MatchString(token):
+			result = append(result, gluedOrdinal.FindStringSubmatch(token)[1])
+		default:
+			result = append(result, token)
+		}
+	}
+	return result
+}
+
+// ---- Numbers ----
+
+var cardinalWords = map[string]int{
+	"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7,
+	"eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12, "thirteen": 13, "fourteen": 14,
+	"fifteen": 15, "sixteen": 16, "seventeen": 17, "eighteen": 18, "nineteen": 19,
+	"twenty": 20, "thirty": 30, "forty": 40, "fifty": 50, "sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90,
+}
+
+var ordinalWords = map[string]int{
+	"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5, "sixth": 6, "seventh": 7,
+	"eighth": 8, "ninth": 9, "tenth": 10, "eleventh": 11, "twelfth": 12, "thirteenth": 13,
+	"fourteenth": 14, "fifteenth": 15, "sixteenth": 16, "seventeenth": 17, "eighteenth": 18,
+	"nineteenth": 19, "twentieth": 20, "thirtieth": 30,
+}
+
+var scaleWords = map[string]int{"hundred": 100, "thousand": 1000, "million": 1000000}
+
+var decimalNumber = regexp.MustCompile(`^\d+\.\d+$`)
+
+// canonicalNumbers writes numbers in words, cardinal or ordinal, as digits
+func canonicalNumbers(tokens []string) []string {
+	var result []string
+	for i := 0; i < len(tokens); {
+		if value, consumed := parseNumberWords(tokens[i:]); consumed > 0 {
+			result = append(result, strconv.Itoa(value))
+			i += consumed
+			continue
+		}
+		result = append(result, tokens[i])
+		i++
+	}
+	return result
+}
+
+// trimDecimals drops trailing zeros from decimals: "5.50" is "5.5" and "5.00" is "5"
+func trimDecimals(tokens []string) []string {
+	for i, token := range tokens {
+		if decimalNumber.MatchString(token) {
+			tokens[i] = strings.TrimRight(strings.TrimRight(token, "0"), ".")
+		}
+	}
+	return tokens
+}
+
+// parseNumberWords reads a number written in words ("two hundred and fifty", "twenty first")
+// from the start of tokens, returning its value and how many tokens it used
+func parseNumberWords(tokens []string) (value, consumed int) {
+	const (
+		none = iota
+		units
+		tens
+		hundreds
+		thousands
+	)
+	total, current, last := 0, 0, none
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		n, cardinal := cardinalWords[token]
+		ordinal := false
+		if !cardinal {
+			n, ordinal = ordinalWords[token]
+		}
+		if cardinal || ordinal {
+			switch {
+			case last == none, last == hundreds, last == thousands:
+				current += n
+			case last == tens && n > 0 && n < 10:
+				current += n
+			default:
+				return total + current, consumed // "one two" is two numbers
+			}
+			consumed = i + 1
+			if ordinal {
+				break // An ordinal ends the number
+			}
+			last = units
+			if n >= 20 && n%10 == 0 {
+				last = tens
+			}
+			continue
+		}
+
+		if scale, ok := scaleWords[token]; ok && last != none && last != thousands {
+			if scale == 100 && last == hundreds {
+				break
+			}
+			if scale == 100 {
+				current *= 100
+				last = hundreds
+			} else {
+				total += current * scale
+				current = 0
+				last = thousands
+			}
+			consumed = i + 1
+			continue
+		}
+
+		// "two hundred and fifty"
+		if token == "and" && (last == hundreds || last == thousands) && i+1 < len(tokens) {
+			if _, ok := cardinalWords[tokens[i+1]]; ok {
+				continue
+			}
+			if _, ok := ordinalWords[tokens[i+1]]; ok {
+				continue
+			}
+		}
+		break
+	}
+
+	if consumed == 0 {
+		return 0, 0
+	}
+	return total + current, consumed
+}
+
+// ---- Times ----
+
+var (
+	clockTime       = regexp.MustCompile(`^(\d{1,2})(?:[:.](\d{2}))?$`)
+	integerOnly     = regexp.MustCompile(`^\d+$`)
+	normalizedClock = regexp.MustCompile(`(?:^| )\d{2}:\d{2}(?: |$)`)
+)
+
+// canonicalTimes writes times on the 24-hour clock: "3 pm", "3:00 pm" and "15:00" become
+// "15:00". A dotted number ("15.00", "9.30") stays a decimal, as it could be a price or a
+// measurement, unless dottedTimes is set.
+func canonicalTimes(tokens []string, dottedTimes bool) []string {
+	var result []string
+	for i := 0; i < len(tokens); i++ {
+		m := clockTime.FindStringSubmatch(tokens[i])
+		if m == nil {
+			result = append(result, tokens[i])
+			continue
+		}
+
+		hour, _ := strconv.Atoi(m[1])
+		minute := 0
+		if m[2] != "" {
+			minute, _ = strconv.Atoi(m[2])
+		}
+		hasColon := strings.Contains(tokens[i], ":")
+
+		next := ""
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+		dottedTime := dottedTimes && m[2] != "" && !hasColon && !nextToMoney(tokens, i)
+
+		switch {
+		case (next == "am" || next == "pm") && hour >= 1 && hour <= 12 && minute < 60:
+			if next == "pm" && hour != 12 {
+				hour += 12
+			} else if next == "am" && hour == 12 {
+				hour = 0
+			}
+			i++
+		case next == "o'clock" && m[2] == "" && hour <= 12:
+			i++
+		case (hasColon || dottedTime) && hour < 24 && minute < 60:
+		default:
+			result = append(result, tokens[i])
+			continue
+		}
+		result = append(result, formatClock(hour, minute))
+	}
+	return result
+}
+
+// hasClockTime reports whether a normalized answer contains a time ("15:00")
+func hasClockTime(normalized string) bool {
+	return normalizedClock.MatchString(normalized)
+}
+
+// nextToMoney reports whether the number at tokens[i] is an amount: "£15.00", "15.00 pounds"
+func nextToMoney(tokens []string, i int) bool {
+	if i > 0 {
+		if _, ok := currencySymbols[tokens[i-1]]; ok {
+			return true
+		}
+	}
+	if i+1 < len(tokens) {
+		if _, ok := currencyWords[tokens[i+1]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func formatClock(hour, minute int) string {
+	return twoDigits(hour) + ":" + twoDigits(minute)
+}
+
+func twoDigits(n int) string {
+	if n < 10 {
+		return "0" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+// ---- Currency ----
+
+var currencySymbols = map[string]string{"£": "gbp", "$": "usd", "€": "eur"}
+
+var currencyWords = map[string]string{
+	"pound": "gbp", "pounds": "gbp", "gbp": "gbp",
+	"dollar": "usd", "dollars": "usd", "usd": "usd",
+	"euro": "eur", "euros": "eur", "eur": "eur",
+}
+
+var amount = regexp.MustCompile(`^\d+(?:\.\d+)?$`)
+
+// canonicalCurrency writes amounts as "<number> <code>": "£50" and "50 pounds" become "50 gbp"
+func canonicalCurrency(tokens []string) []string {
+	var result []string
+	for i := 0; i < len(tokens); i++ {
+		if code, ok := currencySymbols[tokens[i]]; ok && i+1 < len(tokens) && amount.MatchString(tokens[i+1]) {
+			result = append(result, tokens[i+1], code)
+			i++
+			continue
+		}
+		if amount.MatchString(tokens[i]) && i+1 < len(tokens) {
+			if code, ok := currencyWords[tokens[i+1]]; ok {
+				result = append(result, tokens[i], code)
+				i++
+				continue
+			}
+		}
+		result = append(result, tokens[i])
+	}
+	return result
+}
+
+// ---- Dates ----
+
+var months = map[string]string{
+	"january": "january", "jan": "january", "february": "february", "feb": "february",
+	"march": "march", "mar": "march", "april": "april", "apr": "april", "may": "may",
+	"june": "june", "jun": "june", "july": "july", "jul": "july", "august": "august", "aug": "august",
+	"september": "september", "sep": "september", "sept": "september", "october": "october", "oct": "october",
+	"november": "november", "nov": "november", "december": "december", "dec": "december",
+}
+
+// canonicalDates writes dates day first: "March 15", "15 March" and "15 of March" become "15 march"
+func canonicalDates(tokens []string) []string {
+	var result []string
+	for i := 0; i < len(tokens); i++ {
+		// day [of] month
+		if isDay(tokens[i]) {
+			j := i + 1
+			if j < len(tokens) && tokens[j] == "of" {
+				j++
+			}
+			if j < len(tokens) {
+				if month, ok := months[tokens[j]]; ok {
+					result = append(result, strings.TrimLeft(tokens[i], "0"), month)
+					i = j
+					continue
+				}
+			}
+		}
+		// month day
+		if month, ok := months[tokens[i]]; ok && i+1 < len(tokens) && isDay(tokens[i+1]) {
+			result = append(result, strings.TrimLeft(tokens[i+1], "0"), month)
+			i++
+			continue
+		}
+		if month, ok := months[tokens[i]]; ok {
+			result = append(result, month)
+			continue
+		}
+		result = append(result, tokens[i])
+	}
+	return result
+}
+
+func isDay(token string) bool {
+	if !integerOnly.MatchString(token) {
+		return false
+	}
+	day, _ := strconv.Atoi(token)
+	return day >= 1 && day <= 31
+}
+
+// ---- Articles ----
+
+// dropArticles removes a leading "a", "an" or "the" ("the library"). Articles elsewhere
+// stay, as does an answer that is only a letter ("A", "vitamin A").
+func dropArticles(tokens []string) []string {
+	if len(tokens) > 1 && (tokens[0] == "a" || tokens[0] == "an" || tokens[0] == "the") {
+		return tokens[1:]
+	}
+	return tokens
+}
@@ -0,0 +1,189 @@
+package ielts
+
+import (
+	"testing"
+)
+
+// TestNormalizeAnswer tests that accepted variants of an answer normalize to the same form
+func TestNormalizeAnswer(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected bool
+	}{
+		// Case, spacing and punctuation
+		{"case and spacing", "  Marine   Biology ", "marine biology", true},
+		{"trailing full stop", "library.", "library", true},
+		{"hyphen vs space", "part-time", "part time", true},
+		{"en dash", "north–east", "north-east", true},
+
+		// Numbers
+		{"number word", "three", "3", true},
+		{"compound number", "twenty-one", "21", true},
+		{"hundreds", "two hundred and fifty", "250", true},
+		{"thousands", "one thousand five hundred", "1,500", true},
+		{"thousands separator", "1,500", "1500", true},
+		{"decimal zeros", "5.50", "5.5", true},
+		{"fraction", "2/3", "2 3", false},
+		{"fraction spacing", "2 / 3", "2/3", true},
+		{"different fractions", "2/3", "3/2", false},
+		{"ordinal", "15th", "15", true},
+		{"ordinal word", "twenty-first", "21st", true},
+		{"separate numbers", "one two", "12", false},
+		{"different numbers", "13", "30", false},
+
+		// Dates
+		{"day month vs month day", "15th March", "March 15", true},
+		{"day of month", "the 15th of March", "15 March", true},
+		{"month abbreviation", "15 Mar", "March 15th", true},
+		{"with year", "March 15, 2024", "15 March 2024", true},
+		{"different day", "14 March", "15 March", false},
+
+		// Times
+		{"pm glued", "3pm", "3 p.m.", true},
+		{"pm with minutes", "3:30 PM", "15:30", true},
+		{"dot minutes", "3.30 pm", "15:30", true},
+		{"dot on its own is a decimal", "17.50", "17.5", true},
+		{"short dot stays a decimal", "9.30", "9.3", true},
+		{"dot amount is not a time", "£15.00", "15 pounds", true},
+		{"midnight", "12am", "00:00", true},
+		{"o'clock", "nine o'clock", "9:00", true},
+		{"am vs pm", "9 am", "9 pm", false},
+
+		// Currency
+		{"pound symbol vs word", "£50", "50 pounds", true},
+		{"dollar decimals", "$5.50", "5.5 dollars", true},
+		{"different currency", "£50", "$50", false},
+
+		// Spelling
+		{"our", "colour", "color", true},
+		{"re", "city centre", "city center", true},
+		{"ise", "organisation", "organization", true},
+		{"ised", "specialised", "specialized", true},
+		{"ise exception", "exercise", "exercize", false},
+		{"yse", "analyse", "analyze", true},
+		{"doubled l", "travelling", "traveling", true},
+		{"programme", "programme", "program", true},
+
+		// Articles
+		{"leading article", "the library", "library", true},
+		{"indefinite article", "an umbrella", "umbrella", true},
+		{"letter answer kept", "A", "B", false},
+		{"letter answer", "a", "A", true},
+		{"trailing letter kept", "Vitamin A", "vitamin", false},
+		{"trailing letter", "vitamin A", "Vitamin a", true},
+		{"article inside the answer kept", "vitamin A and the mineral", "vitamin and mineral", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := NormalizeAnswer(tt.a), NormalizeAnswer(tt.b)
+			if (a == b) != tt.expected {
+				t.Errorf("NormalizeAnswer(%q) = %q, NormalizeAnswer(%q) = %q; equal = %v, want %v",
+					tt.a, a, tt.b, b, a == b, tt.expected)
+			}
+		})
+	}
+}
+
+// TestAnswerMatches tests matching against accepted answers with optional words
+func TestAnswerMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		given    string
+		accepted []string
+		expected bool
+	}{
+		{"exact", "library", []string{"library"}, true},
+		{"variation", "car park", []string{"parking lot", "car park"}, true},
+		{"optional word given", "the city centre", []string{"(the) city centre"}, true},
+		{"optional word left out", "city center", []string{"(the) city centre"}, true},
+		{"optional adjective", "fresh fruit", []string{"(fresh) fruit"}, true},
+		{"wrong", "museum", []string{"library"}, false},
+		{"empty", "  ", []string{"library"}, false},
+
+		// A dotted number is a time only when the other side is clearly a time
+		{"decimal", "17.5", []string{"17.50"}, true},
+		{"decimal with unit", "15.5 km", []string{"15.50 km"}, true},
+		{"dotted time vs colon", "10.30", []string{"10:30"}, true},
+		{"colon vs dotted time", "10:30", []string{"10.30"}, true},
+		{"24-hour dot vs colon", "15.00", []string{"15:00"}, true},
+		{"24-hour dot vs pm", "15.00", []string{"3pm"}, true},
+		{"dotted time vs pm", "10.30", []string{"10.30 am"}, true},
+		{"dotted time vs other time", "10.30", []string{"10:03"}, false},
+		{"dotted time in a phrase", "at 9.15", []string{"at 9:15"}, true},
+		{"dot amount vs time", "£15.00", []string{"15:00"}, false},
+		{"decimal vs decimal", "10.3", []string{"10.30"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AnswerMatches(tt.given, tt.accepted...); got != tt.expected {
+				t.Errorf("AnswerMatches(%q, %q) = %v; want %v", tt.given, tt.accepted, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestParseWordLimit tests reading word limits from instructions
+func TestParseWordLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		texts    []string
+		expected *WordLimit
+	}{
+		{"words and/or a number", []string{"Write NO MORE THAN TWO WORDS AND/OR A NUMBER for each answer."}, &WordLimit{MaxWords: 2, AllowNumber: true}},
+		{"words only", []string{"Write NO MORE THAN THREE WORDS from the passage"}, &WordLimit{MaxWords: 3}},
+		{"one word only", []string{"Write ONE WORD ONLY for each answer."}, &WordLimit{MaxWords: 1}},
+		{"one word and/or a number", []string{"Write ONE WORD AND/OR A NUMBER"}, &WordLimit{MaxWords: 1, AllowNumber: true}},
+		{"a number", []string{"Write A NUMBER for each answer."}, &WordLimit{NumberOnly: true}},
+		{"from section instructions", []string{"What is the name of the hotel?", "Write no more than two words"}, &WordLimit{MaxWords: 2}},
+		{"none", []string{"Choose the correct letter, A, B or C."}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseWordLimit(tt.texts...)
+			if (got == nil) != (tt.expected == nil) || (got != nil && *got != *tt.expected) {
+				t.Errorf("ParseWordLimit(%q) = %+v; want %+v", tt.texts, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestWordLimitAllows tests word and number counting against limits
+func TestWordLimitAllows(t *testing.T) {
+	twoWordsAndNumber := &WordLimit{MaxWords: 2, AllowNumber: true}
+	twoWords := &WordLimit{MaxWords: 2}
+	numberOnly := &WordLimit{NumberOnly: true}
+
+	tests := []struct {
+		name     string
+		limit    *WordLimit
+		answer   string
+		expected bool
+	}{
+		{"two words", twoWordsAndNumber, "solar panels", true},
+		{"three words", twoWordsAndNumber, "the solar panels", false},
+		{"two words and a number", twoWordsAndNumber, "3 large bedrooms", true},
+		{"two numbers", twoWordsAndNumber, "3 or 4", false},
+		{"date is a word and a number", twoWordsAndNumber, "15th March", true},
+		{"time is one number", twoWordsAndNumber, "3 p.m. Friday", true},
+		{"number in words", twoWordsAndNumber, "twenty-one days", true},
+		{"hyphenated word", twoWords, "part-time job", true},
+		{"number counts as a word", twoWords, "2 large gardens", false},
+		{"number only", numberOnly, "1,500", true},
+		{"currency number", numberOnly, "£50", true},
+		{"number with a word", numberOnly, "1500 pounds", false},
+		{"no limit", nil, "any number of words at all", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limit.Allows(tt.answer); got != tt.expected {
+				words, numbers := CountWords(tt.answer)
+				t.Errorf("Allows(%q) = %v (words %d, numbers %d); want %v", tt.answer, got, words, numbers, tt.expected)
+			}
+		})
+	}
+}
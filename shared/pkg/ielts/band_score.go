@@ -106,6 +106,32 @@ func CalculateSpeakingBand(fluencyCoherence, lexicalResource, grammaticalAccurac
 	return RoundToIELTSBand(average)
 }
 
+// CalculateWritingBandFromTasks combines Writing Task 1 and Task 2 bands into the Writing band
+//
+// Parameters:
+//   - task1: Task 1 band score (0.0-9.0), 0 if not taken
+//   - task2: Task 2 band score (0.0-9.0), 0 if not taken
+//
+// Returns:
+//   - Writing band score (0.0-9.0), rounded to nearest 0.5
+//
+// Formula: Task 2 counts twice as much as Task 1, as in the test.
+// If only one task was taken, its band is the Writing band.
+func CalculateWritingBandFromTasks(task1, task2 float64) float64 {
+	if !isValidBandScore(task1) || !isValidBandScore(task2) {
+		return 0.0
+	}
+
+	switch {
+	case task1 > 0 && task2 > 0:
+		return RoundToIELTSBand((task1 + 2*task2) / 3.0)
+	case task2 > 0:
+		return RoundToIELTSBand(task2)
+	default:
+		return RoundToIELTSBand(task1)
+	}
+}
+
 // CalculateOverallBand calculates overall IELTS band score from 4 skill scores
 //
 // Parameters:
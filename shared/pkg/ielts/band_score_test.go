@@ -215,6 +215,64 @@ func TestCalculateSpeakingBand(t *testing.T) {
 	}
 }
 
+// TestCalculateWritingBandFromTasks tests Task 1/Task 2 weighting
+func TestCalculateWritingBandFromTasks(t *testing.T) {
+	tests := []struct {
+		name  string
+		task1 float64
+		task2 float64
+		want  float64
+	}{
+		{"Equal tasks", 6.5, 6.5, 6.5},
+		{"Task 2 weighs double", 6.0, 7.0, 6.5}, // 6.67 → 6.5
+		{"Task 1 higher", 8.0, 6.0, 6.5},        // 6.67 → 6.5
+		{"Whole band", 5.0, 6.5, 6.0},           // 6.0
+		{"Only Task 2", 0.0, 7.0, 7.0},
+		{"Only Task 1", 6.5, 0.0, 6.5},
+		{"Neither", 0.0, 0.0, 0.0},
+		{"Invalid", 10.0, 6.0, 0.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CalculateWritingBandFromTasks(tt.task1, tt.task2)
+			if result != tt.want {
+				t.Errorf("CalculateWritingBandFromTasks(%.1f, %.1f) = %.1f; want %.1f",
+					tt.task1, tt.task2, result, tt.want)
+			}
+		})
+	}
+}
+
+// TestMaxTaskBandForLength tests the under-length cap on Task Achievement / Task Response
+func TestMaxTaskBandForLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		taskType  string
+		wordCount int
+		want      float64
+	}{
+		{"Task 2 at minimum", "task2", 250, 9.0},
+		{"Task 2 at 80%", "task2", 200, 6.0},
+		{"Task 2 just under 80%", "task2", 199, 5.0},
+		{"Task 2 at 40%", "task2", 100, 4.0},
+		{"Task 2 very short", "task2", 50, 3.0},
+		{"Task 1 at minimum", "task1", 150, 9.0},
+		{"Task 1 at 80%", "task1", 120, 6.0},
+		{"Unknown task as Task 2", "", 200, 6.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := MaxTaskBandForLength(tt.taskType, tt.wordCount)
+			if result != tt.want {
+				t.Errorf("MaxTaskBandForLength(%q, %d) = %.1f; want %.1f",
+					tt.taskType, tt.wordCount, result, tt.want)
+			}
+		})
+	}
+}
+
 // TestCalculateOverallBand tests overall band calculation
 func TestCalculateOverallBand(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,91 @@
+package ielts
+
+import "strings"
+
+// IELTS accepts British and American spellings alike. Answers are compared in the American
+// form: common pairs are listed here, and -ise/-isation words are converted by rule.
+var britishToAmerican = map[string]string{
+	// -our
+	"colour": "color", "colours": "colors", "coloured": "colored", "colourful": "colorful",
+	"favour": "favor", "favourite": "favorite", "favourites": "favorites", "favourable": "favorable",
+	"honour": "honor", "honours": "honors", "labour": "labor", "neighbour": "neighbor",
+	"neighbours": "neighbors", "neighbourhood": "neighborhood", "behaviour": "behavior",
+	"behaviours": "behaviors", "harbour": "harbor", "harbours": "harbors", "humour": "humor",
+	"flavour": "flavor", "flavours": "flavors", "rumour": "rumor", "vapour": "vapor",
+	"odour": "odor", "armour": "armor", "endeavour": "endeavor", "glamour": "glamor",
+	"parlour": "parlor", "saviour": "savior", "vigour": "vigor", "tumour": "tumor",
+
+	// -re
+	"centre": "center", "centres": "centers", "theatre": "theater", "theatres": "theaters",
+	"metre": "meter", "metres": "meters", "litre": "liter", "litres": "liters",
+	"kilometre": "kilometer", "kilometres": "kilometers", "centimetre": "centimeter",
+	"centimetres": "centimeters", "millimetre": "millimeter", "millimetres": "millimeters",
+	"fibre": "fiber", "fibres": "fibers", "calibre": "caliber", "sombre": "somber",
+	"spectre": "specter", "lustre": "luster", "meagre": "meager",
+
+	// -ce / -se
+	"defence": "defense", "offence": "offense", "licence": "license", "pretence": "pretense",
+	"practise": "practice", "practised": "practiced", "practising": "practicing",
+
+	// -yse
+	"analyse": "analyze", "analysed": "analyzed", "analysing": "analyzing",
+	"paralyse": "paralyze", "catalyse": "catalyze",
+
+	// -ogue
+	"catalogue": "catalog", "catalogues": "catalogs", "dialogue": "dialog", "dialogues": "dialogs",
+	"analogue": "analog", "monologue": "monolog", "prologue": "prolog",
+
+	// Doubled l
+	"travelled": "traveled", "travelling": "traveling", "traveller": "traveler", "travellers": "travelers",
+	"cancelled": "canceled", "cancelling": "canceling", "modelling": "modeling", "modelled": "modeled",
+	"labelled": "labeled", "labelling": "labeling", "levelled": "leveled", "fuelled": "fueled",
+	"jewellery": "jewelry", "woollen": "woolen", "counsellor": "counselor", "marvellous": "marvelous",
+	"enrolment": "enrollment", "enrol": "enroll", "fulfil": "fulfill", "skilful": "skillful",
+	"instalment": "installment", "wilful": "willful",
+
+	// Others
+	"programme": "program", "programmes": "programs", "grey": "gray", "tyre": "tire", "tyres": "tires",
+	"cheque": "check", "cheques": "checks", "aluminium": "aluminum", "pyjamas": "pajamas",
+	"plough": "plow", "mould": "mold", "moustache": "mustache", "ageing": "aging",
+	"judgement": "judgment", "acknowledgement": "acknowledgment", "aeroplane": "airplane",
+	"aeroplanes": "airplanes", "cosy": "cozy", "sceptical": "skeptical", "manoeuvre": "maneuver",
+	"paediatric": "pediatric", "encyclopaedia": "encyclopedia", "oestrogen": "estrogen",
+	"anaemia": "anemia", "anaesthetic": "anesthetic", "diarrhoea": "diarrhea", "foetus": "fetus",
+	"orthopaedic": "orthopedic", "archaeology": "archeology", "draughty": "drafty",
+}
+
+// Words ending in -ise that are spelled that way in American English too
+var iseExceptions = map[string]bool{
+	"advertise": true, "advise": true, "apprise": true, "arise": true, "chastise": true,
+	"circumcise": true, "comprise": true, "compromise": true, "concise": true, "cruise": true,
+	"bruise": true, "demise": true, "despise": true, "devise": true, "disguise": true,
+	"enterprise": true, "excise": true, "exercise": true, "expertise": true, "franchise": true,
+	"improvise": true, "incise": true, "merchandise": true, "otherwise": true, "paradise": true,
+	"praise": true, "precise": true, "premise": true, "promise": true, "reprise": true,
+	"revise": true, "supervise": true, "surmise": true, "surprise": true, "televise": true,
+	"treatise": true, "noise": true, "raise": true,
+	"poise": true, "rise": true, "guise": true, "anise": true, "valise": true, "sunrise": true,
+	"moonrise": true, "chemise": true,
+}
+
+var iseSuffixes = []string{"isations", "isation", "ising", "ised", "ises", "isers", "iser", "ise"}
+
+// AmericanSpelling returns the American spelling of a lower-case British word,
+// or the word unchanged
+func AmericanSpelling(word string) string {
+	if american, ok := britishToAmerican[word]; ok {
+		return american
+	}
+
+	for _, suffix := range iseSuffixes {
+		if !strings.HasSuffix(word, suffix) {
+			continue
+		}
+		stem := strings.TrimSuffix(word, suffix)
+		if base := stem + "ise"; len(base) >= 6 && !iseExceptions[base] && !strings.HasSuffix(base, "wise") {
+			return stem + "iz" + strings.TrimPrefix(suffix, "is")
+		}
+		break
+	}
+	return word
+}
@@ -0,0 +1,103 @@
+package ielts
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// WordLimit is a completion instruction such as "NO MORE THAN TWO WORDS AND/OR A NUMBER"
+type WordLimit struct {
+	MaxWords    int  `json:"max_words"`
+	AllowNumber bool `json:"allow_number"` // "AND/OR A NUMBER": one number on top of the words
+	NumberOnly  bool `json:"number_only"`  // "Write A NUMBER"
+}
+
+var (
+	wordLimitPattern  = regexp.MustCompile(`\b(ONE|TWO|THREE|FOUR|FIVE|SIX|\d+)\s+WORDS?(?:\s+ONLY)?(\s+AND\s*/\s*OR\s+A\s+NUMBER)?`)
+	numberOnlyPattern = regexp.MustCompile(`\bA NUMBER\b|\bNUMBERS? ONLY\b`)
+	numberToken       = regexp.MustCompile(`^[£$€]?\d[\d,.:/]*(%|st|nd|rd|th|s|am|pm|a\.m|p\.m)?$`)
+	instructionSpace  = regexp.MustCompile(`\s+`)
+)
+
+var limitWords = map[string]int{"ONE": 1, "TWO": 2, "THREE": 3, "FOUR": 4, "FIVE": 5, "SIX": 6}
+
+// ParseWordLimit finds the word limit in instruction texts, checked in order
+// (for example the question, then its section). Returns nil when none states one.
+func ParseWordLimit(texts ...string) *WordLimit {
+	for _, text := range texts {
+		upper := instructionSpace.ReplaceAllString(strings.ToUpper(text), " ")
+
+		if match := wordLimitPattern.FindStringSubmatch(upper); match != nil {
+			max, ok := limitWords[match[1]]
+			if !ok {
+				max, _ = strconv.Atoi(match[1])
+			}
+			if max > 0 {
+				return &WordLimit{MaxWords: max, AllowNumber: match[2] != ""}
+			}
+		}
+		if numberOnlyPattern.MatchString(upper) {
+			return &WordLimit{NumberOnly: true}
+		}
+	}
+	return nil
+}
+
+// CountWords counts the words and numbers in an answer the way the word limit does:
+// hyphenated words are one word, and numbers in digits or words ("3", "15th", "3 pm", "twenty-one") are numbers
+func CountWords(answer string) (words, numbers int) {
+	var fields []string
+	for _, field := range strings.Fields(characterVariants.Replace(strings.ToLower(answer))) {
+		if field = strings.Trim(field, `.,;:!?"'()`); field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	for i := 0; i < len(fields); i++ {
+		if numberToken.MatchString(fields[i]) {
+			numbers++
+			continue
+		}
+		if (fields[i] == "am" || fields[i] == "pm") && i > 0 && numberToken.MatchString(fields[i-1]) {
+			continue // "3 pm" is one number, like "3pm"
+		}
+
+		// A number in words is one number however many words it takes ("two hundred and five")
+		var parts []string
+		for _, field := range fields[i:] {
+			parts = append(parts, strings.Split(field, "-")...)
+		}
+		if _, consumed := parseNumberWords(parts); consumed > 0 {
+			used := 0
+			for used < len(fields)-i && consumed > 0 {
+				consumed -= len(strings.Split(fields[i+used], "-"))
+				used++
+			}
+			if consumed == 0 {
+				numbers++
+				i += used - 1
+				continue
+			}
+		}
+		words++
+	}
+	return words, numbers
+}
+
+// Allows reports whether an answer keeps to the limit. A nil limit allows any answer.
+func (l *WordLimit) Allows(answer string) bool {
+	if l == nil {
+		return true
+	}
+
+	words, numbers := CountWords(answer)
+	switch {
+	case l.NumberOnly:
+		return numbers == 1 && words == 0
+	case l.AllowNumber:
+		return words <= l.MaxWords && numbers <= 1
+	default:
+		return words+numbers <= l.MaxWords
+	}
+}
@@ -0,0 +1,43 @@
+package ielts
+
+// Minimum essay lengths: examiners penalise Task Achievement / Task Response below them
+const (
+	MinWordsWritingTask1 = 150
+	MinWordsWritingTask2 = 250
+)
+
+// writingLengthCaps are the highest Task Achievement / Task Response bands for an essay
+// reaching each share of the minimum length, longest first. An under-length essay cannot
+// fully address the task, and a very short one cannot show a developed response.
+var writingLengthCaps = []struct {
+	ratio float64
+	band  float64
+}{
+	{1.0, 9.0},
+	{0.8, 6.0},
+	{0.6, 5.0},
+	{0.4, 4.0},
+}
+
+// MinimumWritingWords returns the minimum word count for a writing task ("task1" or "task2");
+// anything else is treated as Task 2
+func MinimumWritingWords(taskType string) int {
+	if taskType == "task1" {
+		return MinWordsWritingTask1
+	}
+	return MinWordsWritingTask2
+}
+
+// MaxTaskBandForLength returns the highest Task Achievement / Task Response band an essay of
+// wordCount words can receive: 9.0 at or above the minimum, down to 3.0 below 40% of it
+//
+// Example: a 200-word Task 2 essay (80% of 250) is capped at 6.0
+func MaxTaskBandForLength(taskType string, wordCount int) float64 {
+	ratio := float64(wordCount) / float64(MinimumWritingWords(taskType))
+	for _, c := range writingLengthCaps {
+		if ratio >= c.ratio {
+			return c.band
+		}
+	}
+	return 3.0
+}
@@ -0,0 +1,304 @@
+// Package textanalysis measures essays the way IELTS examiners and teachers count them:
+// word, sentence and paragraph counts, vocabulary range, academic vocabulary, repetition,
+// linking devices and sentence variety. Everything is computed from the text alone, so the
+// same essay always gets the same figures.
+package textanalysis
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxRepeatedPhrases bounds the phrases reported, most repeated first
+const maxRepeatedPhrases = 10
+
+// Analysis is what an essay's text shows about its length and language
+type Analysis struct {
+	WordCount      int `json:"word_count"`
+	SentenceCount  int `json:"sentence_count"`
+	ParagraphCount int `json:"paragraph_count"`
+
+	TypeTokenRatio       float64  `json:"type_token_ratio"`       // Distinct words / words
+	LexicalDensity       float64  `json:"lexical_density"`        // Content words / words
+	AcademicWordCoverage float64  `json:"academic_word_coverage"` // Share of words from the Academic Word List
+	AcademicWords        []string `json:"academic_words"`         // AWL headwords used, alphabetically
+
+	RepeatedPhrases    []RepeatedPhrase `json:"repeated_phrases"`
+	LinkingDevices     map[string]int   `json:"linking_devices"` // Device → uses
+	LinkingDeviceCount int              `json:"linking_device_count"`
+
+	SentenceLength SentenceLength `json:"sentence_length"`
+}
+
+// RepeatedPhrase is a run of three or more words used more than once
+type RepeatedPhrase struct {
+	Phrase string `json:"phrase"`
+	Count  int    `json:"count"`
+}
+
+// SentenceLength describes how much sentence lengths (in words) vary
+type SentenceLength struct {
+	Mean      float64 `json:"mean"`
+	StdDev    float64 `json:"std_dev"`
+	Variation float64 `json:"variation"` // StdDev / Mean: higher means more varied sentences
+	Shortest  int     `json:"shortest"`
+	Longest   int     `json:"longest"`
+}
+
+// separators split words that run into each other without spaces
+var separators = strings.NewReplacer("—", " ", "–", " ", "…", " ", "...", " ", "’", "'", "‘", "'")
+
+// CountWords counts words as IELTS does: anything between spaces that contains a letter
+// or digit is one word, so "well-known", "don't", "2,500", "25%" and "e.g." count once
+// and stray punctuation does not count at all
+func CountWords(text string) int {
+	count := 0
+	for _, field := range strings.Fields(separators.Replace(text)) {
+		if hasAlphanumeric(field) {
+			count++
+		}
+	}
+	return count
+}
+
+// Words returns the text's words as the vocabulary measures see them: lower case, without
+// surrounding punctuation, numbers left out
+func Words(text string) []string {
+	var paragraphs int
+	var words []string
+	for _, s := range splitSentences(text, &paragraphs) {
+		words = append(words, s.words...)
+	}
+	return words
+}
+
+// Analyze measures the essay
+func Analyze(text string) *Analysis {
+	a := &Analysis{
+		WordCount:       CountWords(text),
+		AcademicWords:   []string{},
+		RepeatedPhrases: []RepeatedPhrase{},
+		LinkingDevices:  map[string]int{},
+	}
+
+	sentences := splitSentences(text, &a.ParagraphCount)
+	a.SentenceCount = len(sentences)
+	a.SentenceLength = sentenceLength(sentences)
+
+	var words []string
+	for _, s := range sentences {
+		words = append(words, s.words...)
+	}
+	if len(words) == 0 {
+		return a
+	}
+
+	distinct := make(map[string]bool)
+	academic := make(map[string]bool)
+	content, academicTokens := 0, 0
+	for _, w := range words {
+		distinct[w] = true
+		if !functionWords[w] {
+			content++
+		}
+		if head := academicHeadword(w); head != "" {
+			academicTokens++
+			academic[head] = true
+		}
+	}
+	a.TypeTokenRatio = round(float64(len(distinct))/float64(len(words)), 2)
+	a.LexicalDensity = round(float64(content)/float64(len(words)), 2)
+	a.AcademicWordCoverage = round(float64(academicTokens)/float64(len(words)), 3)
+	for head := range academic {
+		a.AcademicWords = append(a.AcademicWords, head)
+	}
+	sort.Strings(a.AcademicWords)
+
+	a.LinkingDevices, a.LinkingDeviceCount = countLinkingDevices(sentences)
+	a.RepeatedPhrases = repeatedPhrases(sentences)
+	return a
+}
+
+// sentence is the lower-case words of a sentence, numbers and punctuation left out, and
+// its length in words as CountWords counts them
+type sentence struct {
+	words  []string
+	length int
+}
+
+// splitSentences splits paragraphs (non-empty lines) into sentences ending in . ! or ?,
+// except after abbreviations such as "e.g."
+func splitSentences(text string, paragraphs *int) []sentence {
+	var sentences []sentence
+	for _, line := range strings.Split(separators.Replace(text), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		*paragraphs++
+
+		var current sentence
+		for _, field := range fields {
+			if !hasAlphanumeric(field) {
+				continue
+			}
+			current.length++
+			if w := normalizeWord(field); w != "" {
+				current.words = append(current.words, w)
+			}
+			if endsSentence(field) {
+				sentences = append(sentences, current)
+				current = sentence{}
+			}
+		}
+		if current.length > 0 {
+			sentences = append(sentences, current)
+		}
+	}
+	return sentences
+}
+
+func endsSentence(field string) bool {
+	field = strings.TrimRight(field, `"')]”»`)
+	if !strings.ContainsAny(field[max(0, len(field)-1):], ".!?") {
+		return false
+	}
+	word := strings.ToLower(strings.TrimRight(strings.TrimLeft(field, `"'([“«`), ".!?"))
+	return !abbreviations[word]
+}
+
+// normalizeWord lower-cases a word and trims surrounding punctuation, keeping inner hyphens
+// and apostrophes. Numbers are not words for vocabulary measures and give "".
+func normalizeWord(field string) string {
+	word := strings.ToLower(strings.TrimFunc(field, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}))
+	if !strings.ContainsFunc(word, unicode.IsLetter) {
+		return ""
+	}
+	return word
+}
+
+func hasAlphanumeric(s string) bool {
+	return strings.ContainsFunc(s, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) })
+}
+
+func sentenceLength(sentences []sentence) SentenceLength {
+	var sl SentenceLength
+	if len(sentences) == 0 {
+		return sl
+	}
+
+	sl.Shortest = sentences[0].length
+	total := 0
+	for _, s := range sentences {
+		total += s.length
+		sl.Shortest = min(sl.Shortest, s.length)
+		sl.Longest = max(sl.Longest, s.length)
+	}
+	mean := float64(total) / float64(len(sentences))
+
+	variance := 0.0
+	for _, s := range sentences {
+		variance += math.Pow(float64(s.length)-mean, 2)
+	}
+	stdDev := math.Sqrt(variance / float64(len(sentences)))
+
+	sl.Mean, sl.StdDev = round(mean, 1), round(stdDev, 1)
+	if mean > 0 {
+		sl.Variation = round(stdDev/mean, 2)
+	}
+	return sl
+}
+
+// countLinkingDevices counts each device once per use, longest devices first so that
+// "in addition to" is not also counted as "in addition"
+func countLinkingDevices(sentences []sentence) (map[string]int, int) {
+	devices := make(map[string]int)
+	total := 0
+	for _, s := range sentences {
+		padded := " " + strings.Join(s.words, " ") + " "
+		for _, device := range linkingDevices {
+			n := strings.Count(padded, " "+device+" ")
+			if n == 0 {
+				continue
+			}
+			devices[device] += n
+			total += n
+			padded = strings.ReplaceAll(padded, " "+device+" ", " | ")
+		}
+	}
+	return devices, total
+}
+
+// repeatedPhrases finds runs of 3-5 words used more than once within sentences. Runs made
+// only of function words ("one of the") and linking devices or parts of them ("the other
+// hand") are ordinary and left out, as are shorter runs that only repeat as part of a longer one.
+func repeatedPhrases(sentences []sentence) []RepeatedPhrase {
+	var found []RepeatedPhrase
+	for n := 5; n >= 3; n-- {
+		counts := make(map[string]int)
+		var order []string
+		for _, s := range sentences {
+			for i := 0; i+n <= len(s.words); i++ {
+				gram := s.words[i : i+n]
+				if !hasContentWord(gram) {
+					continue
+				}
+				phrase := strings.Join(gram, " ")
+				if counts[phrase] == 0 {
+					order = append(order, phrase)
+				}
+				counts[phrase]++
+			}
+		}
+
+		for _, phrase := range order {
+			count := counts[phrase]
+			if count < 2 || partOfLinkingDevice(phrase) || coveredBy(found, phrase, count) {
+				continue
+			}
+			found = append(found, RepeatedPhrase{Phrase: phrase, Count: count})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Count > found[j].Count })
+	if len(found) > maxRepeatedPhrases {
+		found = found[:maxRepeatedPhrases]
+	}
+	return found
+}
+
+func hasContentWord(words []string) bool {
+	for _, w := range words {
+		if !functionWords[w] {
+			return true
+		}
+	}
+	return false
+}
+
+func partOfLinkingDevice(phrase string) bool {
+	for _, device := range linkingDevices {
+		if strings.Contains(" "+device+" ", " "+phrase+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func coveredBy(found []RepeatedPhrase, phrase string, count int) bool {
+	for _, longer := range found {
+		if longer.Count >= count && strings.Contains(" "+longer.Phrase+" ", " "+phrase+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func round(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
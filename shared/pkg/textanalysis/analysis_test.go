@@ -0,0 +1,99 @@
+package textanalysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCountWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"The well-known city", 3},
+		{"I don't think it's true", 5},
+		{"It rose by 2,500 units (25%) in 2010.", 8},
+		{"Cars, e.g. taxis - are common", 5},
+		{"Prices rose—sharply…then fell", 5},
+		{"Line one.\n\nLine two!", 4},
+	}
+	for _, tt := range tests {
+		if got := CountWords(tt.text); got != tt.want {
+			t.Errorf("CountWords(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	got := Words("The well-known city grew by 25% (2010-2020).\nIt's \"huge\"!")
+	want := []string{"the", "well-known", "city", "grew", "by", "it's", "huge"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Words = %q, want %q", got, want)
+	}
+}
+
+func TestSentencesAndParagraphs(t *testing.T) {
+	text := "Many people, e.g. students, live in cities. Is that good?\n\n" +
+		"Some say \"yes.\" Others disagree\n" +
+		"Finally, it depends"
+	a := Analyze(text)
+	if a.ParagraphCount != 3 || a.SentenceCount != 5 {
+		t.Errorf("paragraphs %d, sentences %d, want 3 and 5", a.ParagraphCount, a.SentenceCount)
+	}
+	want := SentenceLength{Mean: 3.6, StdDev: 1.7, Variation: 0.48, Shortest: 2, Longest: 7}
+	if a.SentenceLength != want {
+		t.Errorf("sentence length = %+v, want %+v", a.SentenceLength, want)
+	}
+}
+
+func TestAcademicWords(t *testing.T) {
+	tests := map[string]string{
+		"economic": "economy", "economies": "economy", "analysis": "analyse", "analyzed": "analyse",
+		"significance": "significant", "various": "vary", "variation": "vary", "labor": "labour",
+		"fees": "fee", "theories": "theory", "environmental": "environment", "so-called": "so-called",
+		"feed": "", "them": "", "sits": "", "house": "",
+	}
+	for word, want := range tests {
+		if got := academicHeadword(word); got != want {
+			t.Errorf("academicHeadword(%q) = %q, want %q", word, got, want)
+		}
+	}
+
+	a := Analyze("The economic analysis was significant. The house was big.")
+	if want := []string{"analyse", "economy", "significant"}; !reflect.DeepEqual(a.AcademicWords, want) {
+		t.Errorf("academic words = %v, want %v", a.AcademicWords, want)
+	}
+	if a.AcademicWordCoverage != 0.333 || a.TypeTokenRatio != 0.78 || a.LexicalDensity != 0.56 {
+		t.Errorf("coverage %.3f, TTR %.2f, density %.2f", a.AcademicWordCoverage, a.TypeTokenRatio, a.LexicalDensity)
+	}
+}
+
+func TestRepeatedPhrases(t *testing.T) {
+	a := Analyze("Public transport is very important. I think public transport is very cheap. " +
+		"In my opinion public transport is good. It is one of the best. It is one of the worst. " +
+		"On the other hand, cars are fast. On the other hand, bikes are slow.")
+	want := []RepeatedPhrase{
+		{Phrase: "public transport is", Count: 3},
+		{Phrase: "public transport is very", Count: 2},
+	}
+	if !reflect.DeepEqual(a.RepeatedPhrases, want) {
+		t.Errorf("repeated phrases = %+v, want %+v", a.RepeatedPhrases, want)
+	}
+}
+
+func TestLinkingDevices(t *testing.T) {
+	a := Analyze("In addition to cost, time matters. However, in addition, safety counts. " +
+		"On the other hand, it is cheap; however it is slow.")
+	want := map[string]int{"in addition to": 1, "in addition": 1, "however": 2, "on the other hand": 1}
+	if !reflect.DeepEqual(a.LinkingDevices, want) || a.LinkingDeviceCount != 5 {
+		t.Errorf("linking devices = %v (%d), want %v", a.LinkingDevices, a.LinkingDeviceCount, want)
+	}
+}
+
+func TestAnalyzeEmpty(t *testing.T) {
+	a := Analyze("  \n ")
+	if a.WordCount != 0 || a.SentenceCount != 0 || a.ParagraphCount != 0 || a.AcademicWords == nil || a.RepeatedPhrases == nil || a.LinkingDevices == nil {
+		t.Errorf("empty analysis = %+v", a)
+	}
+}
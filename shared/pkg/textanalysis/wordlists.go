@@ -0,0 +1,192 @@
+package textanalysis
+
+import "strings"
+
+// academicHeadwords is the Academic Word List (Coxhead, 2000): 570 word families that are
+// frequent in academic texts but outside the 2,000 most common English words
+var academicHeadwords = strings.Fields(`
+analyse approach area assess assume authority available benefit concept consist constitute
+context contract create data define derive distribute economy environment establish estimate
+evident export factor finance formula function identify income indicate individual interpret
+involve issue labour legal legislate major method occur percent period policy principle proceed
+process require research respond role section sector significant similar source specific
+structure theory vary
+
+achieve acquire administrate affect appropriate aspect assist category chapter commission
+community complex compute conclude conduct consequent construct consume credit culture design
+distinct element equate evaluate feature final focus impact injure institute invest item journal
+maintain normal obtain participate perceive positive potential previous primary purchase range
+region regulate relevant reside resource restrict secure seek select site strategy survey text
+tradition transfer
+
+alternative circumstance comment compensate component consent considerable constant constrain
+contribute convene coordinate core corporate correspond criteria deduce demonstrate document
+dominate emphasis ensure exclude framework fund illustrate immigrate imply initial instance
+interact justify layer link locate maximise minor negate outcome partner philosophy physical
+proportion publish react register rely remove scheme sequence sex shift specify sufficient task
+technical technique technology valid volume
+
+access adequate annual apparent approximate attitude attribute civil code commit communicate
+concentrate confer contrast cycle debate despite dimension domestic emerge error ethnic goal grant
+hence hypothesis implement implicate impose integrate internal investigate job label mechanism
+obvious occupy option output overall parallel parameter phase predict principal prior
+professional project promote regime resolve retain series statistic status stress subsequent sum
+summary undertake
+
+academy adjust alter amend aware capacity challenge clause compound conflict consult contact
+decline discrete draft enable energy enforce entity equivalent evolve expand expose external
+facilitate fundamental generate generation image liberal licence logic margin medical mental
+modify monitor network notion objective orient perspective precise prime psychology pursue ratio
+reject revenue stable style substitute sustain symbol target transit trend version welfare whereas
+
+abstract accurate acknowledge aggregate allocate assign attach author bond brief capable cite
+cooperate discriminate display diverse domain edit enhance estate exceed expert explicit federal
+fee flexible furthermore gender ignorance incentive incidence incorporate index inhibit initiate
+input instruct intelligence interval lecture migrate minimum ministry motive neutral nevertheless
+overseas precede presume rational recover reveal scope subsidy tape trace transform transport
+underlie utilise
+
+adapt adult advocate aid channel chemical classic comprehensive comprise confirm contrary convert
+couple decade definite deny differentiate dispose dynamic eliminate empirical equip extract file
+finite foundation globe grade guarantee hierarchy identical ideology infer innovate insert
+intervene isolate media mode paradigm phenomenon priority prohibit publication quote release
+reverse simulate sole somewhat submit successor survive thesis topic transmit ultimate unique
+visible voluntary
+
+abandon accompany accumulate ambiguous append appreciate arbitrary automate bias chart clarify
+commodity complement conform contemporary contradict crucial currency denote detect deviate
+displace drama eventual exhibit exploit fluctuate guideline highlight implicit induce inevitable
+infrastructure inspect intense manipulate minimise nuclear offset paragraph plus practitioner
+predominant prospect radical random reinforce restore revise schedule tension terminate theme
+thereby uniform vehicle via virtual visual widespread
+
+accommodate analogy anticipate assure attain behalf bulk cease coherent coincide commence
+compatible concurrent confine controversy converse device devote diminish distort duration erode
+ethic format found inherent insight integral intermediate manual mature mediate medium military
+minimal mutual norm overlap passive portion preliminary protocol qualitative refine relax restrain
+revolution rigid route scenario sphere subordinate supplement suspend team temporary trigger unify
+violate vision
+
+adjacent albeit assemble collapse colleague compile conceive convince depress encounter enormous
+forthcoming incline integrity intrinsic invoke levy likewise nonetheless notwithstanding odd
+ongoing panel persist pose reluctance so-called straightforward undergo whereby
+`)
+
+// familySuffixes turn a headword or stem into the regular members of its family
+// ("economy" → "economic", "economically"; "analyse" → "analysis")
+var familySuffixes = []string{
+	"", "s", "es", "d", "ed", "ing", "ings", "er", "ers", "or", "ors", "ly", "ally", "ically",
+	"al", "ial", "ic", "ics", "ical", "ion", "ions", "ation", "ations", "ive", "ively", "ivity",
+	"ity", "ities", "ment", "ments", "ness", "ous", "able", "ability", "ible", "ance", "ence",
+	"ant", "ent", "antly", "ently", "ist", "ists", "ise", "ised", "ize", "ized", "isation",
+	"ization", "is", "ies", "ied", "ure", "ures", "ary", "age",
+}
+
+// americanSpellings of headwords whose families are otherwise matched by the rules below
+var americanSpellings = map[string]string{"labour": "labor", "licence": "license"}
+
+// academicForms are headwords in either spelling; academicStems are the shortened stems
+// family members are built on, which only count with a suffix ("theme" → "them" is no match)
+var academicForms, academicStems = func() (map[string]string, map[string]string) {
+	forms := make(map[string]string, len(academicHeadwords)*2)
+	stems := make(map[string]string, len(academicHeadwords)*2)
+	for _, head := range academicHeadwords {
+		spellings := []string{head}
+		if us, ok := americanSpellings[head]; ok {
+			spellings = append(spellings, us)
+		}
+		for _, ending := range []string{"ise", "yse"} {
+			if strings.HasSuffix(head, ending) {
+				spellings = append(spellings, strings.TrimSuffix(head, ending)+ending[:len(ending)-2]+"ze")
+			}
+		}
+
+		for _, form := range spellings {
+			forms[form] = head
+			// Only longer headwords are shortened, so "site" does not claim "sits"
+			if len(form) < 5 {
+				continue
+			}
+			switch {
+			case strings.HasSuffix(form, "e"):
+				stems[form[:len(form)-1]] = head
+			case strings.HasSuffix(form, "y"):
+				stems[form[:len(form)-1]] = head
+				stems[form[:len(form)-1]+"i"] = head // "economy" → "economies"
+			case strings.HasSuffix(form, "ant"), strings.HasSuffix(form, "ent"):
+				stems[form[:len(form)-3]] = head // "significant" → "significance"
+			}
+		}
+		if head == "vary" {
+			stems["vari"] = head // "various", "variation"
+		}
+	}
+	return forms, stems
+}()
+
+// academicHeadword returns the Academic Word List family of a lower-case word, or ""
+func academicHeadword(word string) string {
+	for _, suffix := range familySuffixes {
+		if !strings.HasSuffix(word, suffix) {
+			continue
+		}
+		stem := word[:len(word)-len(suffix)]
+		// Three-letter headwords only take a plural: "fees", but not "feed"
+		if len(stem) < 4 && suffix != "" && suffix != "s" {
+			continue
+		}
+		if head, ok := academicForms[stem]; ok {
+			return head
+		}
+		if head, ok := academicStems[stem]; ok && suffix != "" {
+			return head
+		}
+	}
+	return ""
+}
+
+// functionWords carry grammar rather than content; everything else counts towards lexical density
+var functionWords = toSet(strings.Fields(`
+a an the this that these those some any each every either neither no all both half several
+many much more most few fewer less least other another such what which whose whatever whichever
+i me my mine myself you your yours yourself yourselves he him his himself she her hers herself
+it its itself we us our ours ourselves they them their theirs themselves one ones who whom
+someone somebody something anyone anybody anything everyone everybody everything nobody nothing
+be am is are was were been being have has had having do does did doing done
+will would shall should can could may might must ought
+and but or nor so yet for because although though while whereas if unless until since as than
+whether when whenever where wherever why how that
+of in on at by to from with without within into onto upon about above below over under between
+among through throughout during before after behind beside besides beyond near off out up down
+around across along against toward towards via per like unlike despite except inside outside
+not never also too very just only even still already quite rather really then there here
+yes oh well
+don't doesn't didn't isn't aren't wasn't weren't won't wouldn't can't cannot couldn't shouldn't
+haven't hasn't hadn't i'm you're he's she's it's we're they're i've you've we've they've
+i'll you'll he'll she'll we'll they'll i'd you'd he'd she'd we'd they'd that's there's
+`)...)
+
+// linkingDevices are the cohesive devices counted for Coherence and Cohesion, longest first
+// so that "on the other hand" is not also counted as "other"
+var linkingDevices = []string{
+	"on the other hand", "in addition to", "as a result of", "in conclusion", "to conclude",
+	"to sum up", "in summary", "in addition", "as a result", "for example", "for instance",
+	"in contrast", "by contrast", "in other words", "as well as", "due to", "in particular",
+	"in fact", "on the contrary", "as long as", "even though", "in order to", "such as",
+	"however", "moreover", "furthermore", "therefore", "thus", "hence", "consequently",
+	"nevertheless", "nonetheless", "although", "whereas", "meanwhile", "similarly", "likewise",
+	"additionally", "besides", "firstly", "secondly", "thirdly", "finally", "lastly", "overall",
+	"instead", "otherwise", "accordingly", "subsequently", "namely", "indeed", "because",
+	"since", "unless", "despite",
+}
+
+// abbreviations end in a full stop without ending the sentence
+var abbreviations = toSet("e.g", "i.e", "etc", "mr", "mrs", "ms", "dr", "prof", "vs", "approx", "u.s", "u.k")
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}